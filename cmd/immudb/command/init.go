@@ -62,6 +62,7 @@ func (cl *Commandline) setupFlags(cmd *cobra.Command, options *server.Options) {
 	cmd.Flags().Bool("force-admin-password", false, "if true, reset the admin password to the one passed through admin-password option upon startup")
 	cmd.Flags().Bool("maintenance", options.GetMaintenance(), "override the authentication flag")
 	cmd.Flags().String("signingKey", options.SigningKey, "signature private key path. If a valid one is provided, it enables the cryptographic signature of the root. e.g. \"./../test/signer/ec3.key\"")
+	cmd.Flags().String("field-encryption-key-file", options.FieldEncryptionKeyFile, "path of the file holding the hex-encoded AES key (16, 24 or 32 bytes) used to encrypt the document fields marked as encrypted")
 	cmd.Flags().Bool("synced", true, "synced mode prevents data lost under unexpected crashes but affects performance")
 	cmd.Flags().Int("token-expiry-time", options.TokenExpiryTimeMin, "client authentication token expiration time. Minutes")
 	cmd.Flags().Bool("metrics-server", options.MetricsServer, "enable or disable Prometheus endpoint")
@@ -129,6 +130,7 @@ func setupDefaults(options *server.Options) {
 	viper.SetDefault("force-admin-password", options.ForceAdminPassword)
 	viper.SetDefault("maintenance", options.GetMaintenance())
 	viper.SetDefault("synced", true)
+	viper.SetDefault("field-encryption-key-file", "")
 	viper.SetDefault("token-expiry-time", options.TokenExpiryTimeMin)
 	viper.SetDefault("metrics-server", options.MetricsServer)
 	viper.SetDefault("metrics-server-port", options.MetricsServerPort)
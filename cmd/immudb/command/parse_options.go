@@ -68,6 +68,7 @@ func parseOptions() (options *server.Options, err error) {
 	forceAdminPassword := viper.GetBool("force-admin-password")
 	maintenance := viper.GetBool("maintenance")
 	signingKey := viper.GetString("signingKey")
+	fieldEncryptionKeyFile := viper.GetString("field-encryption-key-file")
 	synced := viper.GetBool("synced")
 	tokenExpTime := viper.GetInt("token-expiry-time")
 
@@ -141,6 +142,7 @@ func parseOptions() (options *server.Options, err error) {
 		WithForceAdminPassword(forceAdminPassword).
 		WithMaintenance(maintenance).
 		WithSigningKey(signingKey).
+		WithFieldEncryptionKeyFile(fieldEncryptionKeyFile).
 		WithSynced(synced).
 		WithRemoteStorageOptions(remoteStorageOptions).
 		WithTokenExpiryTime(tokenExpTime).
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+)
+
+// CollectionChanges returns the changes made by the transaction to the documents of the collection,
+// in the order they are stored in the transaction. The transaction is expected to be indexed.
+func (e *Engine) CollectionChanges(ctx context.Context, collectionName string, tx *store.Tx) ([]*protomodel.CollectionWatchResponse, error) {
+	sqlTx, err := e.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithReadOnly(true))
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	table, err := getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	// documents are written as rows, while they are read through the entries of the primary index
+	rowPrefix := sql.MapKey(
+		e.sqlEngine.GetPrefix(),
+		sql.RowPrefix,
+		sql.EncodeID(sql.DatabaseID),
+		sql.EncodeID(table.ID()),
+		sql.EncodeID(table.PrimaryIndex().ID()),
+	)
+
+	var changes []*protomodel.CollectionWatchResponse
+
+	for _, entry := range tx.Entries() {
+		if !bytes.HasPrefix(entry.Key(), rowPrefix) {
+			continue
+		}
+
+		pkEncVals := entry.Key()[len(rowPrefix):]
+
+		docID, err := documentIDFromEncodedPK(pkEncVals)
+		if err != nil {
+			return nil, err
+		}
+
+		key := sql.MapKey(
+			e.sqlEngine.GetPrefix(),
+			sql.MappedPrefix,
+			sql.EncodeID(table.ID()),
+			sql.EncodeID(table.PrimaryIndex().ID()),
+			pkEncVals,
+			pkEncVals,
+		)
+
+		valRef, err := e.sqlEngine.GetStore().GetBetween(ctx, key, tx.Header().ID, tx.Header().ID)
+		if err != nil {
+			return nil, err
+		}
+
+		change := &protomodel.CollectionWatchResponse{
+			DocumentId:    docID.EncodeToHexString(),
+			TransactionId: tx.Header().ID,
+			Revision:      valRef.HC(),
+		}
+
+		if entry.Metadata() != nil && entry.Metadata().Deleted() {
+			change.Operation = protomodel.ChangeOperation_DELETE
+			changes = append(changes, change)
+			continue
+		}
+
+		if valRef.HC() > 1 {
+			change.Operation = protomodel.ChangeOperation_UPDATE
+		}
+
+		docAtRevision, err := e.getDocument(key, valRef, true)
+		if err != nil {
+			return nil, err
+		}
+
+		err = e.decryptDocument(table, docAtRevision.Document)
+		if err != nil {
+			return nil, err
+		}
+
+		err = e.valueCodecs.decodeDocument(collectionName, docAtRevision.Document)
+		if err != nil {
+			return nil, err
+		}
+
+		err = e.assembleBlobs(ctx, docAtRevision.Document)
+		if err != nil {
+			return nil, err
+		}
+
+		change.Document = docAtRevision.Document
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// documentIDFromEncodedPK decodes the document id from the encoded primary key of a document,
+// made of the not null prefix, the value padded up to the maximum length and the length of the value
+func documentIDFromEncodedPK(encID []byte) (DocumentID, error) {
+	if len(encID) != 1+MaxDocumentIDLength+sql.EncLenLen {
+		return nil, fmt.Errorf("%w: invalid document key", ErrUnexpectedValue)
+	}
+
+	idLen := int(binary.BigEndian.Uint32(encID[len(encID)-sql.EncLenLen:]))
+	if idLen > MaxDocumentIDLength {
+		return nil, fmt.Errorf("%w: invalid document key", ErrUnexpectedValue)
+	}
+
+	return DocumentID(encID[1 : 1+idLen]), nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/sql"
+)
+
+// deletedAtProperty is the table property holding the time, in unix nanoseconds, a collection was soft-deleted at
+const deletedAtProperty = "deletedAt"
+
+func isDeletedCollection(table *sql.Table) bool {
+	_, deleted := table.Property(deletedAtProperty)
+	return deleted
+}
+
+func collectionDeletedAt(table *sql.Table) (time.Time, error) {
+	deletedAt, _ := table.Property(deletedAtProperty)
+
+	nanos, err := strconv.ParseInt(deletedAt, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid deletion time of collection '%s'", ErrUnexpectedValue, table.Name())
+	}
+
+	return time.Unix(0, nanos), nil
+}
+
+// SoftDeleteCollection marks a collection as deleted. The collection is hidden and can not be queried nor altered,
+// but neither its documents nor its indexes are dropped until it gets purged, thus it can be restored
+// within the retention period.
+func (e *Engine) SoftDeleteCollection(ctx context.Context, username, collectionName string) error {
+	err := validateCollectionName(collectionName)
+	if err != nil {
+		return err
+	}
+
+	opts := sql.DefaultTxOptions().
+		WithUnsafeMVCC(true).
+		WithExtra([]byte(username)).
+		WithSnapshotMustIncludeTxID(func(lastPrecommittedTxID uint64) uint64 { return 0 }).
+		WithSnapshotRenewalPeriod(0).
+		WithExplicitClose(true)
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, opts)
+	if err != nil {
+		return mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	_, err = getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	_, _, err = e.sqlEngine.ExecPreparedStmts(
+		ctx,
+		sqlTx,
+		[]sql.SQLStmt{sql.NewSetTablePropertyStmt(collectionName, deletedAtProperty, deletedAt)},
+		nil,
+	)
+	if err != nil {
+		return mayTranslateError(err)
+	}
+
+	err = sqlTx.Commit(ctx)
+	return mayTranslateError(err)
+}
+
+// RestoreCollection makes a soft-deleted collection available again, as long as its retention period did not expire
+func (e *Engine) RestoreCollection(ctx context.Context, username, collectionName string) error {
+	err := validateCollectionName(collectionName)
+	if err != nil {
+		return err
+	}
+
+	opts := sql.DefaultTxOptions().
+		WithUnsafeMVCC(true).
+		WithExtra([]byte(username)).
+		WithSnapshotMustIncludeTxID(func(lastPrecommittedTxID uint64) uint64 { return 0 }).
+		WithSnapshotRenewalPeriod(0).
+		WithExplicitClose(true)
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, opts)
+	if err != nil {
+		return mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	table, err := sqlTx.Catalog().GetTableByName(collectionName)
+	if err != nil {
+		return fmt.Errorf("%w (%s)", mayTranslateError(err), collectionName)
+	}
+
+	if !isDeletedCollection(table) {
+		return fmt.Errorf("%w (%s)", ErrCollectionNotDeleted, collectionName)
+	}
+
+	deletedAt, err := collectionDeletedAt(table)
+	if err != nil {
+		return err
+	}
+
+	if time.Since(deletedAt) >= e.deletedCollectionRetention {
+		return fmt.Errorf("%w (%s)", ErrRetentionPeriodExpired, collectionName)
+	}
+
+	_, _, err = e.sqlEngine.ExecPreparedStmts(
+		ctx,
+		sqlTx,
+		[]sql.SQLStmt{sql.NewSetTablePropertyStmt(collectionName, deletedAtProperty, "")},
+		nil,
+	)
+	if err != nil {
+		return mayTranslateError(err)
+	}
+
+	err = sqlTx.Commit(ctx)
+	return mayTranslateError(err)
+}
+
+// PurgeCollections drops the soft-deleted collections whose retention period expired,
+// the names of the purged collections are returned
+func (e *Engine) PurgeCollections(ctx context.Context, username string) ([]string, error) {
+	opts := sql.DefaultTxOptions().
+		WithUnsafeMVCC(true).
+		WithExtra([]byte(username)).
+		WithSnapshotMustIncludeTxID(func(lastPrecommittedTxID uint64) uint64 { return 0 }).
+		WithSnapshotRenewalPeriod(0).
+		WithExplicitClose(true)
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, opts)
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	var purged []string
+	var stmts []sql.SQLStmt
+
+	for _, table := range sqlTx.Catalog().GetTables() {
+		if !isDeletedCollection(table) {
+			continue
+		}
+
+		deletedAt, err := collectionDeletedAt(table)
+		if err != nil {
+			return nil, err
+		}
+
+		if time.Since(deletedAt) < e.deletedCollectionRetention {
+			continue
+		}
+
+		purged = append(purged, table.Name())
+		stmts = append(stmts, sql.NewDropTableStmt(table.Name()))
+	}
+
+	if len(stmts) == 0 {
+		return nil, nil
+	}
+
+	_, _, err = e.sqlEngine.ExecPreparedStmts(ctx, sqlTx, stmts, nil)
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+
+	err = sqlTx.Commit(ctx)
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSoftDeleteCollection(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	err := engine.CreateCollection(ctx, "admin", "people", "", []*protomodel.Field{
+		{Name: "name", Type: protomodel.FieldType_STRING},
+	}, []*protomodel.Index{{Fields: []string{"name"}}})
+	require.NoError(t, err)
+
+	_, _, err = engine.InsertDocument(ctx, "admin", "people", &structpb.Struct{Fields: map[string]*structpb.Value{
+		"name": structpb.NewStringValue("alice"),
+	}})
+	require.NoError(t, err)
+
+	query := &protomodel.Query{CollectionName: "people"}
+
+	t.Run("only deleted collections can be restored", func(t *testing.T) {
+		err := engine.RestoreCollection(ctx, "admin", "people")
+		require.ErrorIs(t, err, ErrCollectionNotDeleted)
+
+		err = engine.RestoreCollection(ctx, "admin", "unexistent")
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+
+		err = engine.SoftDeleteCollection(ctx, "admin", "unexistent")
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+	})
+
+	err = engine.SoftDeleteCollection(ctx, "admin", "people")
+	require.NoError(t, err)
+
+	t.Run("soft-deleted collections should be hidden", func(t *testing.T) {
+		collections, err := engine.GetCollections(ctx)
+		require.NoError(t, err)
+		require.Empty(t, collections)
+
+		_, err = engine.GetCollection(ctx, "people")
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+
+		_, err = engine.CountDocuments(ctx, query, 0)
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+
+		_, _, err = engine.InsertDocument(ctx, "admin", "people", &structpb.Struct{Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("bob"),
+		}})
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+
+		err = engine.CreateIndex(ctx, "admin", "people", []string{"_id", "name"}, false)
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+
+		err = engine.DeleteCollection(ctx, "admin", "people")
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+
+		err = engine.SoftDeleteCollection(ctx, "admin", "people")
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+	})
+
+	t.Run("collections should not be purged within the retention period", func(t *testing.T) {
+		purged, err := engine.PurgeCollections(ctx, "admin")
+		require.NoError(t, err)
+		require.Empty(t, purged)
+	})
+
+	t.Run("soft-deleted collections should be restored with their documents", func(t *testing.T) {
+		err := engine.RestoreCollection(ctx, "admin", "people")
+		require.NoError(t, err)
+
+		collection, err := engine.GetCollection(ctx, "people")
+		require.NoError(t, err)
+		require.Len(t, collection.Indexes, 2)
+
+		count, err := engine.CountDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, count)
+	})
+
+	t.Run("collections should be purged once the retention period expires", func(t *testing.T) {
+		err := engine.SoftDeleteCollection(ctx, "admin", "people")
+		require.NoError(t, err)
+
+		engine.deletedCollectionRetention = time.Millisecond
+		time.Sleep(2 * time.Millisecond)
+
+		err = engine.RestoreCollection(ctx, "admin", "people")
+		require.ErrorIs(t, err, ErrRetentionPeriodExpired)
+
+		purged, err := engine.PurgeCollections(ctx, "admin")
+		require.NoError(t, err)
+		require.Equal(t, []string{"people"}, purged)
+
+		err = engine.RestoreCollection(ctx, "admin", "people")
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+
+		// the name can be taken again once the collection is purged
+		err = engine.CreateCollection(ctx, "admin", "people", "", nil, nil)
+		require.NoError(t, err)
+
+		count, err := engine.CountDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+}
@@ -18,6 +18,7 @@ package document
 import (
 	"context"
 	"errors"
+	"regexp"
 
 	"github.com/codenotary/immudb/embedded/sql"
 	"github.com/codenotary/immudb/pkg/api/protomodel"
@@ -34,14 +35,39 @@ type DocumentReader interface {
 	Close() error
 }
 
+// DocumentCounter is optionally implemented by document readers able to count the documents
+// satisfying their query on the same snapshot the documents are read from
+type DocumentCounter interface {
+	Count(ctx context.Context) (int64, error)
+}
+
 type documentReader struct {
 	rowReader       sql.RowReader
+	decodeDoc       func(doc *structpb.Struct) error
 	onCloseCallback func(reader DocumentReader)
+	// count is set by the engine to count the matching documents within the transaction of the reader
+	count func(ctx context.Context) (int64, error)
+	// highlights describe the field comparisons evaluated for each document, if any
+	highlights []*fieldHighlight
+}
+
+// fieldHighlight locates within each row read the evaluation of a field comparison of the query
+type fieldHighlight struct {
+	expressionIndex uint32
+	comparisonIndex uint32
+	field           string
+	operator        protomodel.ComparisonOperator
+	// matchPos is the position of the result of the comparison
+	matchPos int
+	// pattern and valuePos are only set for comparisons matching a pattern, to locate the matched substring in the field value
+	pattern  *regexp.Regexp
+	valuePos int
 }
 
-func newDocumentReader(rowReader sql.RowReader, onCloseCallback func(reader DocumentReader)) DocumentReader {
+func newDocumentReader(rowReader sql.RowReader, decodeDoc func(doc *structpb.Struct) error, onCloseCallback func(reader DocumentReader)) DocumentReader {
 	return &documentReader{
 		rowReader:       rowReader,
+		decodeDoc:       decodeDoc,
 		onCloseCallback: onCloseCallback,
 	}
 }
@@ -75,16 +101,30 @@ func (r *documentReader) ReadN(ctx context.Context, count int) ([]*protomodel.Do
 			return nil, err
 		}
 
+		err = r.decode(doc)
+		if err != nil {
+			return nil, err
+		}
+
 		revisions = append(revisions, &protomodel.DocumentAtRevision{
 			TransactionId: 0, // TODO: not yet available
 			Revision:      0, // TODO: not yet available
 			Document:      doc,
+			Matches:       r.matches(row),
 		})
 	}
 
 	return revisions, err
 }
 
+func (r *documentReader) Count(ctx context.Context) (int64, error) {
+	if r.count == nil {
+		return 0, ErrIllegalState
+	}
+
+	return r.count(ctx)
+}
+
 func (r *documentReader) Close() error {
 	if r.onCloseCallback != nil {
 		defer r.onCloseCallback(r)
@@ -112,11 +152,59 @@ func (r *documentReader) Read(ctx context.Context) (*protomodel.DocumentAtRevisi
 		return nil, err
 	}
 
+	err = r.decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
 	revision := &protomodel.DocumentAtRevision{
 		TransactionId: 0, // TODO: not yet available
 		Revision:      0, // TODO: not yet available
 		Document:      doc,
+		Matches:       r.matches(row),
 	}
 
 	return revision, err
 }
+
+func (r *documentReader) decode(doc *structpb.Struct) error {
+	if r.decodeDoc == nil {
+		return nil
+	}
+
+	return r.decodeDoc(doc)
+}
+
+// matches returns the field comparisons satisfied by the row
+func (r *documentReader) matches(row *sql.Row) []*protomodel.FieldMatch {
+	var matches []*protomodel.FieldMatch
+
+	for _, h := range r.highlights {
+		matched, _ := row.ValuesByPosition[h.matchPos].RawValue().(bool)
+		if !matched {
+			continue
+		}
+
+		match := &protomodel.FieldMatch{
+			ExpressionIndex: h.expressionIndex,
+			ComparisonIndex: h.comparisonIndex,
+			Field:           h.field,
+			Operator:        h.operator,
+		}
+
+		if h.pattern != nil {
+			value, _ := row.ValuesByPosition[h.valuePos].RawValue().(string)
+
+			if loc := h.pattern.FindStringIndex(value); loc != nil {
+				match.Substring = &protomodel.SubstringMatch{
+					Start: uint32(loc[0]),
+					End:   uint32(loc[1]),
+				}
+			}
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches
+}
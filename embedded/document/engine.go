@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/codenotary/immudb/embedded/sql"
 	"github.com/codenotary/immudb/embedded/store"
@@ -35,6 +37,12 @@ const (
 	DefaultDocumentIDField     = "_id"
 	DocumentBLOBField          = "_doc"
 	documentFieldPathSeparator = "."
+
+	// defaultOrderByProperty is the table property holding the default sort of a collection
+	defaultOrderByProperty = "defaultOrderBy"
+
+	MaxCollectionNameLength = 128
+	MaxFieldNameLength      = 128
 )
 
 var reservedWords = map[string]struct{}{
@@ -44,6 +52,9 @@ var reservedWords = map[string]struct{}{
 	"document":   {},
 }
 
+// reservedCollectionNamePrefixes are used by tables of the underlying sql catalog
+var reservedCollectionNamePrefixes = []string{"pg_"}
+
 var collectionNameValidation = regexp.MustCompile(`^[a-zA-Z_]+[a-zA-Z0-9_\-]*$`)
 var documentIDFieldNameValidation = regexp.MustCompile(`^[a-zA-Z_]+[a-zA-Z0-9_\-]*$`)
 var fieldNameValidation = regexp.MustCompile(`^[a-zA-Z_]+[a-zA-Z0-9_\-.]*$`)
@@ -52,6 +63,12 @@ type Engine struct {
 	sqlEngine *sql.Engine
 
 	maxNestedFields int
+
+	valueCodecs *ValueCodecRegistry
+
+	fieldEncryptor *fieldEncryptor
+
+	deletedCollectionRetention time.Duration
 }
 
 type EncodedDocument struct {
@@ -71,65 +88,141 @@ func NewEngine(store *store.ImmuStore, opts *Options) (*Engine, error) {
 		WithPrefix(opts.prefix).
 		WithLazyIndexConstraintValidation(true)
 
+	var encryptor *fieldEncryptor
+
+	if opts.fieldEncryptionKey != nil {
+		encryptor, err = newFieldEncryptor(opts.fieldEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	engine, err := sql.NewEngine(store, sqlOpts)
 	if err != nil {
 		return nil, err
 	}
 
+	err = initBlobChunkIndexing(store, opts.prefix)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Engine{
 		sqlEngine:       engine,
 		maxNestedFields: opts.maxNestedFields,
+		valueCodecs:     opts.valueCodecs,
+		fieldEncryptor:  encryptor,
+
+		deletedCollectionRetention: opts.deletedCollectionRetention,
 	}, nil
 }
 
-func validateCollectionName(collectionName string) error {
-	_, isReservedWord := reservedWords[strings.ToLower(collectionName)]
+// validateName checks the name is non-empty valid UTF-8 within maxLen bytes and not a reserved word
+// before matching it against the allowed charset
+func validateName(kind, name string, maxLen int, validation *regexp.Regexp) error {
+	if name == "" || !utf8.ValidString(name) {
+		return &InvalidNameError{Kind: kind, Name: name, Reason: ErrIllegalArguments}
+	}
+
+	if len(name) > maxLen {
+		return &InvalidNameError{Kind: kind, Name: name, Reason: ErrMaxLengthExceeded}
+	}
+
+	_, isReservedWord := reservedWords[strings.ToLower(name)]
 	if isReservedWord {
-		return fmt.Errorf("%w: invalid collection name '%s'", ErrReservedName, collectionName)
+		return &InvalidNameError{Kind: kind, Name: name, Reason: ErrReservedName}
 	}
 
-	if !collectionNameValidation.MatchString(collectionName) {
-		return fmt.Errorf("%w: invalid collection name '%s'", ErrIllegalArguments, collectionName)
+	if !validation.MatchString(name) {
+		return &InvalidNameError{Kind: kind, Name: name, Reason: ErrIllegalArguments}
 	}
 
 	return nil
 }
 
-func validateDocumentIdFieldName(documentIdFieldName string) error {
-	_, isReservedWord := reservedWords[strings.ToLower(documentIdFieldName)]
-	if isReservedWord {
-		return fmt.Errorf("%w: invalid id field name '%s'", ErrReservedName, documentIdFieldName)
-	}
-
-	if documentIdFieldName == DocumentBLOBField {
-		return fmt.Errorf("%w: invalid id field name '%s'", ErrReservedName, documentIdFieldName)
+func validateCollectionName(collectionName string) error {
+	err := validateName("collection", collectionName, MaxCollectionNameLength, collectionNameValidation)
+	if err != nil {
+		return err
 	}
 
-	if !documentIDFieldNameValidation.MatchString(documentIdFieldName) {
-		return fmt.Errorf("%w: invalid id field name '%s'", ErrIllegalArguments, documentIdFieldName)
+	for _, prefix := range reservedCollectionNamePrefixes {
+		if strings.HasPrefix(strings.ToLower(collectionName), prefix) {
+			return &InvalidNameError{Kind: "collection", Name: collectionName, Reason: ErrReservedName}
+		}
 	}
 
 	return nil
 }
 
-func validateFieldName(fieldName string) error {
-	_, isReservedWord := reservedWords[strings.ToLower(fieldName)]
-	if isReservedWord {
-		return fmt.Errorf("%w: invalid field name '%s'", ErrReservedName, fieldName)
+func validateDocumentIdFieldName(documentIdFieldName string) error {
+	if documentIdFieldName == DocumentBLOBField {
+		return &InvalidNameError{Kind: "id field", Name: documentIdFieldName, Reason: ErrReservedName}
 	}
 
+	return validateName("id field", documentIdFieldName, MaxFieldNameLength, documentIDFieldNameValidation)
+}
+
+func validateFieldName(fieldName string) error {
 	if fieldName == DocumentBLOBField {
-		return fmt.Errorf("%w: invalid field name '%s'", ErrReservedName, fieldName)
+		return &InvalidNameError{Kind: "field", Name: fieldName, Reason: ErrReservedName}
 	}
 
-	if !fieldNameValidation.MatchString(fieldName) {
-		return fmt.Errorf("%w: invalid field name '%s'", ErrIllegalArguments, fieldName)
+	return validateName("field", fieldName, MaxFieldNameLength, fieldNameValidation)
+}
+
+// CollectionOption sets an optional property of a collection when it's created
+type CollectionOption func(*collectionOptions)
+
+type collectionOptions struct {
+	defaultOrderBy []*protomodel.OrderByClause
+}
+
+// WithDefaultOrderBy sets the order used to sort the results of the queries not specifying any order
+func WithDefaultOrderBy(orderBy []*protomodel.OrderByClause) CollectionOption {
+	return func(opts *collectionOptions) {
+		opts.defaultOrderBy = orderBy
 	}
+}
 
-	return nil
+// CreateCollection creates a collection with the given fields and indexes
+func (e *Engine) CreateCollection(
+	ctx context.Context,
+	username, name, documentIdFieldName string,
+	fields []*protomodel.Field,
+	indexes []*protomodel.Index,
+	opts ...CollectionOption,
+) error {
+	return e.createCollection(ctx, username, name, documentIdFieldName, fields, indexes, false, opts)
+}
+
+// CreateStrictCollection creates a collection as CreateCollection does, documents holding fields not declared
+// by the collection are rejected when written to it
+func (e *Engine) CreateStrictCollection(
+	ctx context.Context,
+	username, name, documentIdFieldName string,
+	fields []*protomodel.Field,
+	indexes []*protomodel.Index,
+	opts ...CollectionOption,
+) error {
+	return e.createCollection(ctx, username, name, documentIdFieldName, fields, indexes, true, opts)
 }
 
-func (e *Engine) CreateCollection(ctx context.Context, username, name, documentIdFieldName string, fields []*protomodel.Field, indexes []*protomodel.Index) error {
+func (e *Engine) createCollection(
+	ctx context.Context,
+	username, name, documentIdFieldName string,
+	fields []*protomodel.Field,
+	indexes []*protomodel.Index,
+	strict bool,
+	collOpts []CollectionOption,
+) error {
+	var options collectionOptions
+	for _, opt := range collOpts {
+		opt(&options)
+	}
+
+	defaultOrderBy := options.defaultOrderBy
+
 	err := validateCollectionName(name)
 	if err != nil {
 		return err
@@ -144,6 +237,59 @@ func (e *Engine) CreateCollection(ctx context.Context, username, name, documentI
 		return err
 	}
 
+	err = validateDefaultOrderBy(documentIdFieldName, fields, defaultOrderBy)
+	if err != nil {
+		return err
+	}
+
+	// encrypted fields are only kept within the document, thus they are neither columns nor indexable
+	var encFields []string
+	var columnFields []*protomodel.Field
+
+	for _, field := range fields {
+		if field == nil {
+			return fmt.Errorf("%w: no field specified", ErrIllegalArguments)
+		}
+
+		if !field.Encrypted {
+			columnFields = append(columnFields, field)
+			continue
+		}
+
+		err = validateFieldName(field.Name)
+		if err != nil {
+			return err
+		}
+
+		if field.Name == documentIdFieldName {
+			return fmt.Errorf("%w: id field '%s' can not be encrypted", ErrIllegalArguments, field.Name)
+		}
+
+		encFields = append(encFields, field.Name)
+	}
+
+	if len(encFields) > 0 && e.fieldEncryptor == nil {
+		return fmt.Errorf("%w: collection '%s' holds encrypted fields", ErrFieldEncryptionKeyNotSet, name)
+	}
+
+	for _, field := range encFields {
+		for _, index := range indexes {
+			for _, indexField := range index.Fields {
+				if indexField == field {
+					return fmt.Errorf("%w: encrypted field '%s' can not be indexed", ErrIllegalArguments, field)
+				}
+			}
+		}
+
+		for _, clause := range defaultOrderBy {
+			if clause.Field == field {
+				return fmt.Errorf("%w: default order on encrypted field '%s'", ErrIllegalArguments, field)
+			}
+		}
+	}
+
+	fields = columnFields
+
 	// only catalog needs to be up to date
 	opts := sql.DefaultTxOptions().
 		WithUnsafeMVCC(true).
@@ -242,10 +388,207 @@ func (e *Engine) CreateCollection(ctx context.Context, username, name, documentI
 		}
 	}
 
+	if len(defaultOrderBy) > 0 {
+		_, _, err = e.sqlEngine.ExecPreparedStmts(
+			ctx,
+			sqlTx,
+			[]sql.SQLStmt{sql.NewSetTablePropertyStmt(name, defaultOrderByProperty, encodeOrderBy(defaultOrderBy))},
+			nil,
+		)
+		if err != nil {
+			return mayTranslateError(err)
+		}
+	}
+
+	if len(encFields) > 0 {
+		_, _, err = e.sqlEngine.ExecPreparedStmts(
+			ctx,
+			sqlTx,
+			[]sql.SQLStmt{sql.NewSetTablePropertyStmt(name, encryptedFieldsProperty, strings.Join(encFields, ","))},
+			nil,
+		)
+		if err != nil {
+			return mayTranslateError(err)
+		}
+	}
+
+	if strict {
+		_, _, err = e.sqlEngine.ExecPreparedStmts(
+			ctx,
+			sqlTx,
+			[]sql.SQLStmt{sql.NewSetTablePropertyStmt(name, strictProperty, "true")},
+			nil,
+		)
+		if err != nil {
+			return mayTranslateError(err)
+		}
+	}
+
 	err = sqlTx.Commit(ctx)
 	return mayTranslateError(err)
 }
 
+func validateDefaultOrderBy(documentIdFieldName string, fields []*protomodel.Field, defaultOrderBy []*protomodel.OrderByClause) error {
+	for _, clause := range defaultOrderBy {
+		if clause == nil {
+			return fmt.Errorf("%w: invalid default order", ErrIllegalArguments)
+		}
+
+		if clause.Field == documentIdFieldName {
+			continue
+		}
+
+		err := validateFieldName(clause.Field)
+		if err != nil {
+			return err
+		}
+
+		found := false
+
+		for _, field := range fields {
+			if field.Name == clause.Field {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%w: default order on undefined field '%s'", ErrIllegalArguments, clause.Field)
+		}
+	}
+
+	return nil
+}
+
+// encodeOrderBy encodes order clauses as comma-separated "field ASC|DESC" terms
+func encodeOrderBy(orderBy []*protomodel.OrderByClause) string {
+	terms := make([]string, len(orderBy))
+
+	for i, clause := range orderBy {
+		direction := "ASC"
+		if clause.Desc {
+			direction = "DESC"
+		}
+
+		terms[i] = clause.Field + " " + direction
+	}
+
+	return strings.Join(terms, ",")
+}
+
+func decodeOrderBy(encOrderBy string) []*protomodel.OrderByClause {
+	if encOrderBy == "" {
+		return nil
+	}
+
+	terms := strings.Split(encOrderBy, ",")
+
+	orderBy := make([]*protomodel.OrderByClause, len(terms))
+
+	for i, term := range terms {
+		field, direction, _ := strings.Cut(term, " ")
+
+		orderBy[i] = &protomodel.OrderByClause{
+			Field: field,
+			Desc:  direction == "DESC",
+		}
+	}
+
+	return orderBy
+}
+
+func defaultOrderBy(table *sql.Table) []*protomodel.OrderByClause {
+	encOrderBy, _ := table.Property(defaultOrderByProperty)
+	return decodeOrderBy(encOrderBy)
+}
+
+// matchingIndex returns the index whose leading fields are compared for equality by the query, a range
+// comparison being also accepted on the last one. Composite indexes are thus used when the query filters
+// a prefix of their fields. The index covering the most fields is chosen, nil is returned when no index matches.
+// Only queries made of a single expression are considered.
+func matchingIndex(table *sql.Table, expressions []*protomodel.QueryExpression) *sql.Index {
+	if len(expressions) != 1 {
+		return nil
+	}
+
+	var bestIndex *sql.Index
+	var bestMatchedFields int
+
+	for _, index := range table.GetIndexes() {
+		if index.IsPrimary() {
+			continue
+		}
+
+		matchedFields := matchedIndexFields(index, expressions[0].FieldComparisons)
+		if matchedFields > bestMatchedFields {
+			bestIndex = index
+			bestMatchedFields = matchedFields
+		}
+	}
+
+	return bestIndex
+}
+
+func matchedIndexFields(index *sql.Index, comparisons []*protomodel.FieldComparison) int {
+	matchedFields := 0
+
+	for _, col := range index.Cols() {
+		var eq, inRange bool
+
+		for _, cmp := range comparisons {
+			if cmp.Field != col.Name() {
+				continue
+			}
+
+			switch cmp.Operator {
+			case protomodel.ComparisonOperator_EQ:
+				eq = true
+			case protomodel.ComparisonOperator_LT,
+				protomodel.ComparisonOperator_LE,
+				protomodel.ComparisonOperator_GT,
+				protomodel.ComparisonOperator_GE,
+				protomodel.ComparisonOperator_IN:
+				inRange = true
+			}
+		}
+
+		if eq {
+			matchedFields++
+			continue
+		}
+
+		if inRange {
+			// a range comparison can only be used on the last matched field
+			matchedFields++
+		}
+
+		break
+	}
+
+	return matchedFields
+}
+
+// queryIndexFields returns the fields of the index matching the query, which is scanned instead of the
+// primary one when no order is specified, nil is returned otherwise
+func queryIndexFields(table *sql.Table, query *protomodel.Query) []string {
+	if len(query.OrderBy) > 0 || len(defaultOrderBy(table)) > 0 {
+		return nil
+	}
+
+	index := matchingIndex(table, query.Expressions)
+	if index == nil {
+		return nil
+	}
+
+	fields := make([]string, len(index.Cols()))
+
+	for i, col := range index.Cols() {
+		fields[i] = col.Name()
+	}
+
+	return fields
+}
+
 func (e *Engine) GetCollection(ctx context.Context, collectionName string) (*protomodel.Collection, error) {
 	opts := sql.DefaultTxOptions().
 		WithReadOnly(true).
@@ -278,10 +621,14 @@ func (e *Engine) GetCollections(ctx context.Context) ([]*protomodel.Collection,
 
 	tables := sqlTx.Catalog().GetTables()
 
-	collections := make([]*protomodel.Collection, len(tables))
+	collections := make([]*protomodel.Collection, 0, len(tables))
+
+	for _, table := range tables {
+		if isDeletedCollection(table) {
+			continue
+		}
 
-	for i, table := range tables {
-		collections[i] = collectionFromTable(table)
+		collections = append(collections, collectionFromTable(table))
 	}
 
 	return collections, nil
@@ -301,8 +648,16 @@ func getTableForCollection(sqlTx *sql.SQLTx, collectionName string) (*sql.Table,
 	if errors.Is(err, sql.ErrTableDoesNotExist) {
 		return nil, fmt.Errorf("%w (%s)", mayTranslateError(err), collectionName)
 	}
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+
+	if isDeletedCollection(table) {
+		// soft-deleted collections are hidden until restored
+		return nil, fmt.Errorf("%w (%s)", ErrCollectionDoesNotExist, collectionName)
+	}
 
-	return table, mayTranslateError(err)
+	return table, nil
 }
 
 func getColumnForField(table *sql.Table, field string) (*sql.Column, error) {
@@ -328,6 +683,8 @@ func collectionFromTable(table *sql.Table) *protomodel.Collection {
 		Name:                table.Name(),
 		DocumentIdFieldName: documentIdFieldName,
 		Indexes:             make([]*protomodel.Index, len(indexes)),
+		DefaultOrderBy:      defaultOrderBy(table),
+		Strict:              isStrictCollection(table),
 	}
 
 	for _, col := range table.Cols() {
@@ -360,6 +717,13 @@ func collectionFromTable(table *sql.Table) *protomodel.Collection {
 		})
 	}
 
+	for _, field := range encryptedFields(table) {
+		collection.Fields = append(collection.Fields, &protomodel.Field{
+			Name:      field,
+			Encrypted: true,
+		})
+	}
+
 	for i, index := range indexes {
 		fields := make([]string, len(index.Cols()))
 
@@ -451,6 +815,12 @@ func (e *Engine) DeleteCollection(ctx context.Context, username, collectionName
 	}
 	defer sqlTx.Cancel()
 
+	// soft-deleted collections are only dropped when purged
+	_, err = getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return err
+	}
+
 	_, _, err = e.sqlEngine.ExecPreparedStmts(
 		ctx,
 		sqlTx,
@@ -482,6 +852,10 @@ func (e *Engine) AddField(ctx context.Context, username, collectionName string,
 		return err
 	}
 
+	if field.Encrypted {
+		return fmt.Errorf("%w: fields can only be encrypted at collection creation", ErrIllegalArguments)
+	}
+
 	sqlType, err := protomodelValueTypeToSQLValueType(field.Type)
 	if err != nil {
 		return err
@@ -505,6 +879,15 @@ func (e *Engine) AddField(ctx context.Context, username, collectionName string,
 	}
 	defer sqlTx.Cancel()
 
+	table, err := getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return err
+	}
+
+	if isEncryptedField(table, field.Name) {
+		return fmt.Errorf("%w: field '%s' is encrypted", ErrFieldAlreadyExists, field.Name)
+	}
+
 	colSpec := sql.NewColSpec(field.Name, sqlType, colLen, false, false)
 
 	addColumnStmt := sql.NewAddColumnStmt(collectionName, colSpec)
@@ -547,6 +930,11 @@ func (e *Engine) RemoveField(ctx context.Context, username, collectionName strin
 	}
 	defer sqlTx.Cancel()
 
+	_, err = getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return err
+	}
+
 	dropColumnStmt := sql.NewDropColumnStmt(collectionName, fieldName)
 
 	_, _, err = e.sqlEngine.ExecPreparedStmts(
@@ -593,6 +981,11 @@ func (e *Engine) CreateIndex(ctx context.Context, username, collectionName strin
 		}
 	}
 
+	_, err = getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return err
+	}
+
 	createIndexStmt := sql.NewCreateIndexStmt(collectionName, fields, isUnique)
 
 	_, _, err = e.sqlEngine.ExecPreparedStmts(
@@ -639,6 +1032,11 @@ func (e *Engine) DeleteIndex(ctx context.Context, username, collectionName strin
 		}
 	}
 
+	_, err = getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return err
+	}
+
 	dropIndexStmt := sql.NewDropIndexStmt(collectionName, fields)
 
 	_, _, err = e.sqlEngine.ExecPreparedStmts(
@@ -664,22 +1062,46 @@ func (e *Engine) InsertDocument(ctx context.Context, username, collectionName st
 	return txID, docIDs[0], nil
 }
 
-func (e *Engine) InsertDocuments(ctx context.Context, username, collectionName string, docs []*structpb.Struct) (txID uint64, docIDs []DocumentID, err error) {
+// InsertDocuments inserts the documents within a single transaction, which is only committed if all
+// the given preconditions are met
+func (e *Engine) InsertDocuments(ctx context.Context, username, collectionName string, docs []*structpb.Struct, preconditions ...store.Precondition) (txID uint64, docIDs []DocumentID, err error) {
 	opts := sql.DefaultTxOptions().
 		WithUnsafeMVCC(true).
 		WithExtra([]byte(username)).
 		WithSnapshotMustIncludeTxID(func(lastPrecommittedTxID uint64) uint64 { return 0 }).
 		WithSnapshotRenewalPeriod(0)
 
+	for _, doc := range docs {
+		err = checkNoBlobReferences(doc)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
 	sqlTx, err := e.sqlEngine.NewTx(ctx, opts)
 	if err != nil {
 		return 0, nil, mayTranslateError(err)
 	}
 	defer sqlTx.Cancel()
 
+	err = addPreconditions(sqlTx, preconditions)
+	if err != nil {
+		return 0, nil, err
+	}
+
 	return e.upsertDocuments(ctx, sqlTx, collectionName, docs, true)
 }
 
+func addPreconditions(sqlTx *sql.SQLTx, preconditions []store.Precondition) error {
+	for _, c := range preconditions {
+		err := sqlTx.AddPrecondition(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (e *Engine) upsertDocuments(ctx context.Context, sqlTx *sql.SQLTx, collectionName string, docs []*structpb.Struct, isInsert bool) (txID uint64, docIDs []DocumentID, err error) {
 	if len(docs) == 0 {
 		return 0, nil, fmt.Errorf("%w: no document specified", ErrIllegalArguments)
@@ -712,16 +1134,32 @@ func (e *Engine) upsertDocuments(ctx context.Context, sqlTx *sql.SQLTx, collecti
 			return 0, nil, fmt.Errorf("%w(%s)", ErrReservedName, DocumentBLOBField)
 		}
 
+		err = checkDeclaredFields(table, doc)
+		if err != nil {
+			return 0, nil, err
+		}
+
 		var docID DocumentID
 
 		provisionedDocID, docIDProvisioned := doc.Fields[docIDFieldName]
 		if docIDProvisioned {
+			docID, err = NewDocumentIDFromHexEncodedString(provisionedDocID.GetStringValue())
+
 			if isInsert {
-				return 0, nil, fmt.Errorf("%w: field (%s) should NOT be specified when inserting a document", ErrIllegalArguments, docIDFieldName)
-			}
+				// the id can only be specified to revive a deleted document
+				deleted := false
 
-			docID, err = NewDocumentIDFromHexEncodedString(provisionedDocID.GetStringValue())
-			if err != nil {
+				if err == nil {
+					deleted, err = e.isDeletedDocument(ctx, sqlTx, collectionName, docID)
+					if err != nil {
+						return 0, nil, err
+					}
+				}
+
+				if !deleted {
+					return 0, nil, fmt.Errorf("%w: field (%s) should NOT be specified when inserting a document", ErrIllegalArguments, docIDFieldName)
+				}
+			} else if err != nil {
 				return 0, nil, err
 			}
 		} else {
@@ -734,12 +1172,22 @@ func (e *Engine) upsertDocuments(ctx context.Context, sqlTx *sql.SQLTx, collecti
 			doc.Fields[docIDFieldName] = structpb.NewStringValue(docID.EncodeToHexString())
 		}
 
-		rowSpec, err := e.generateRowSpecForDocument(table, doc)
+		doc, err = e.valueCodecs.encodeDocument(collectionName, doc)
 		if err != nil {
 			return 0, nil, err
 		}
 
-		docIDs[i] = docID
+		doc, err = e.encryptDocument(table, doc)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		rowSpec, err := e.generateRowSpecForDocument(table, doc)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		docIDs[i] = docID
 		rows[i] = rowSpec
 	}
 
@@ -767,6 +1215,47 @@ func (e *Engine) upsertDocuments(ctx context.Context, sqlTx *sql.SQLTx, collecti
 	return txID, docIDs, nil
 }
 
+// isDeletedDocument returns whether the latest revision of the document is a tombstone, in which case the
+// transaction is conditioned on the document not being modified after the deletion. Documents are inserted
+// without MVCC checks, so this is what makes concurrent revivals of the same document fail but one.
+func (e *Engine) isDeletedDocument(ctx context.Context, sqlTx *sql.SQLTx, collectionName string, docID DocumentID) (bool, error) {
+	searchKey, err := e.getKeyForDocument(ctx, sqlTx, collectionName, docID)
+	if err != nil {
+		return false, err
+	}
+
+	st := e.sqlEngine.GetStore()
+
+	// documents are committed without waiting for indexing, the deletion may not be indexed yet
+	err = st.WaitForIndexingUpto(ctx, st.LastCommittedTxID())
+	if err != nil {
+		return false, err
+	}
+
+	valRefs, _, err := st.History(searchKey, 0, true, 1)
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	md := valRefs[0].KVMetadata()
+	if md == nil || !md.Deleted() {
+		return false, nil
+	}
+
+	err = sqlTx.AddPrecondition(&store.PreconditionKeyNotModifiedAfterTx{
+		Key:  searchKey,
+		TxID: valRefs[0].Tx(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 func (e *Engine) generateRowSpecForDocument(table *sql.Table, doc *structpb.Struct) (*sql.RowSpec, error) {
 	values := make([]sql.ValueExp, len(table.Cols()))
 
@@ -823,7 +1312,109 @@ func (e *Engine) structValueFromFieldPath(doc *structpb.Struct, fieldPath string
 	return nil, fmt.Errorf("%w('%s')", ErrFieldDoesNotExist, fieldPath)
 }
 
-func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *protomodel.Query, doc *structpb.Struct) (revisions []*protomodel.DocumentAtRevision, err error) {
+// ReplaceDocuments replaces the documents matching the query within a single transaction, which is only committed
+// if all the given preconditions are met
+func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *protomodel.Query, doc *structpb.Struct, preconditions ...store.Precondition) (revisions []*protomodel.DocumentAtRevision, err error) {
+	return e.replaceDocuments(ctx, username, query, doc, nil, preconditions)
+}
+
+// ReplaceDocumentIfRevMatch replaces the document matching the query only if its current revision is the provided one,
+// a RevisionConflictError holding the current revision is returned otherwise
+func (e *Engine) ReplaceDocumentIfRevMatch(ctx context.Context, username string, query *protomodel.Query, doc *structpb.Struct, rev uint64, preconditions ...store.Precondition) (revisions []*protomodel.DocumentAtRevision, err error) {
+	if rev == 0 {
+		return nil, fmt.Errorf("%w: invalid revision", ErrIllegalArguments)
+	}
+
+	return e.replaceDocuments(ctx, username, query, doc, revisionMatches(rev), preconditions)
+}
+
+// UpdateDocumentIfTxMatch replaces the fields of the document with the given id only if its latest version
+// was written by the provided transaction, a TxConflictError holding the transaction of the latest version is
+// returned otherwise. The revision of the updated document holds the id of the transaction it was written by.
+func (e *Engine) UpdateDocumentIfTxMatch(ctx context.Context, username, collectionName string, docID DocumentID, doc *structpb.Struct, txID uint64) (*protomodel.DocumentAtRevision, error) {
+	if len(docID) == 0 {
+		return nil, fmt.Errorf("%w: no document id specified", ErrIllegalArguments)
+	}
+
+	if txID == 0 {
+		return nil, fmt.Errorf("%w: invalid transaction id", ErrIllegalArguments)
+	}
+
+	idFieldName, err := e.getDocumentIDFieldName(ctx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &protomodel.Query{
+		CollectionName: collectionName,
+		Expressions: []*protomodel.QueryExpression{{
+			FieldComparisons: []*protomodel.FieldComparison{{
+				Field:    idFieldName,
+				Operator: protomodel.ComparisonOperator_EQ,
+				Value:    structpb.NewStringValue(docID.EncodeToHexString()),
+			}},
+		}},
+		Limit: 1,
+	}
+
+	revisions, err := e.replaceDocuments(ctx, username, query, doc, txMatches(txID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("%w (%s)", ErrDocumentNotFound, docID.EncodeToHexString())
+	}
+
+	return revisions[0], nil
+}
+
+func (e *Engine) getDocumentIDFieldName(ctx context.Context, collectionName string) (string, error) {
+	sqlTx, err := e.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithReadOnly(true))
+	if err != nil {
+		return "", mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	table, err := getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return "", err
+	}
+
+	return docIDFieldName(table), nil
+}
+
+// documentCondition is checked against the latest version of the single document matched by a conditional
+// replacement, which is only performed if no error is returned
+type documentCondition func(docID DocumentID, encDoc *EncodedDocument) error
+
+func revisionMatches(rev uint64) documentCondition {
+	return func(docID DocumentID, encDoc *EncodedDocument) error {
+		if encDoc.Revision != rev {
+			return &RevisionConflictError{
+				DocumentID:      docID.EncodeToHexString(),
+				CurrentRevision: encDoc.Revision,
+			}
+		}
+
+		return nil
+	}
+}
+
+func txMatches(txID uint64) documentCondition {
+	return func(docID DocumentID, encDoc *EncodedDocument) error {
+		if encDoc.TxID != txID {
+			return &TxConflictError{
+				DocumentID:  docID.EncodeToHexString(),
+				CurrentTxID: encDoc.TxID,
+			}
+		}
+
+		return nil
+	}
+}
+
+func (e *Engine) replaceDocuments(ctx context.Context, username string, query *protomodel.Query, doc *structpb.Struct, cond documentCondition, preconditions []store.Precondition) (revisions []*protomodel.DocumentAtRevision, err error) {
 	if query == nil {
 		return nil, ErrIllegalArguments
 	}
@@ -834,12 +1425,22 @@ func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *p
 		}
 	}
 
+	err = checkNoBlobReferences(doc)
+	if err != nil {
+		return nil, err
+	}
+
 	sqlTx, err := e.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithExtra([]byte(username)))
 	if err != nil {
 		return nil, mayTranslateError(err)
 	}
 	defer sqlTx.Cancel()
 
+	err = addPreconditions(sqlTx, preconditions)
+	if err != nil {
+		return nil, err
+	}
+
 	table, err := getTableForCollection(sqlTx, query.CollectionName)
 	if err != nil {
 		return nil, err
@@ -873,7 +1474,7 @@ func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *p
 		}
 	}
 
-	queryCondition, err := generateSQLFilteringExpression(query.Expressions, table)
+	queryCondition, err := e.generateSQLFilteringExpression(query.Expressions, table)
 	if err != nil {
 		return nil, err
 	}
@@ -882,10 +1483,10 @@ func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *p
 		[]sql.TargetEntry{{Exp: sql.NewColSelector(query.CollectionName, documentIdFieldName)}},
 		sql.NewTableRef(query.CollectionName, ""),
 		queryCondition,
-		generateSQLOrderByClauses(table, query.OrderBy),
+		generateSQLOrderByClauses(table, effectiveOrderBy(table, query)),
 		sql.NewInteger(int64(query.Limit)),
 		nil,
-	)
+	).WithIndexOn(queryIndexFields(table, query))
 
 	r, err := e.sqlEngine.QueryPreparedStmt(ctx, sqlTx, queryStmt, nil)
 	if err != nil {
@@ -893,6 +1494,7 @@ func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *p
 	}
 
 	var docs []*structpb.Struct
+	var matchedDocIDs []DocumentID
 
 	for {
 		row, err := r.Read(ctx)
@@ -923,6 +1525,7 @@ func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *p
 		}
 
 		docs = append(docs, newDoc)
+		matchedDocIDs = append(matchedDocIDs, docID)
 	}
 
 	r.Close()
@@ -931,6 +1534,17 @@ func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *p
 		return nil, nil
 	}
 
+	if cond != nil {
+		if len(matchedDocIDs) > 1 {
+			return nil, ErrMultipleDocumentsFound
+		}
+
+		err = e.checkDocumentCondition(ctx, sqlTx, query.CollectionName, matchedDocIDs[0], cond)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	txID, docIDs, err := e.upsertDocuments(ctx, sqlTx, query.CollectionName, docs, false)
 	if err != nil {
 		return nil, err
@@ -959,7 +1573,31 @@ func (e *Engine) ReplaceDocuments(ctx context.Context, username string, query *p
 	return revisions, nil
 }
 
+func (e *Engine) checkDocumentCondition(ctx context.Context, sqlTx *sql.SQLTx, collectionName string, docID DocumentID, cond documentCondition) error {
+	searchKey, err := e.getKeyForDocument(ctx, sqlTx, collectionName, docID)
+	if err != nil {
+		return err
+	}
+
+	encDoc, err := e.getEncodedDocument(ctx, searchKey, 0)
+	if err != nil {
+		return err
+	}
+
+	return cond(docID, encDoc)
+}
+
 func (e *Engine) GetDocuments(ctx context.Context, query *protomodel.Query, offset int64) (DocumentReader, error) {
+	return e.getDocuments(ctx, query, offset, false)
+}
+
+// GetDocumentsWithHighlights behaves as GetDocuments but each document read also includes the field
+// comparisons of the query it satisfies and, for LIKE comparisons, the offsets of the matched substring
+func (e *Engine) GetDocumentsWithHighlights(ctx context.Context, query *protomodel.Query, offset int64) (DocumentReader, error) {
+	return e.getDocuments(ctx, query, offset, true)
+}
+
+func (e *Engine) getDocuments(ctx context.Context, query *protomodel.Query, offset int64, highlight bool) (DocumentReader, error) {
 	if query == nil {
 		return nil, ErrIllegalArguments
 	}
@@ -975,29 +1613,260 @@ func (e *Engine) GetDocuments(ctx context.Context, query *protomodel.Query, offs
 		return nil, err
 	}
 
-	queryCondition, err := generateSQLFilteringExpression(query.Expressions, table)
+	queryCondition, err := e.generateSQLFilteringExpression(query.Expressions, table)
+	if err != nil {
+		defer sqlTx.Cancel()
+		return nil, err
+	}
+
+	reader, err := e.newDocumentReader(ctx, sqlTx, table, query, queryCondition, effectiveOrderBy(table, query), offset, highlight)
 	if err != nil {
 		defer sqlTx.Cancel()
 		return nil, err
 	}
 
+	reader.(*documentReader).count = func(ctx context.Context) (int64, error) {
+		return e.countDocuments(ctx, sqlTx, table, query, queryCondition, 0)
+	}
+
+	return reader, nil
+}
+
+// newDocumentReader returns a reader over the documents satisfying the condition, sorted as specified.
+// The transaction is cancelled when the reader is closed
+func (e *Engine) newDocumentReader(
+	ctx context.Context,
+	sqlTx *sql.SQLTx,
+	table *sql.Table,
+	query *protomodel.Query,
+	condition sql.ValueExp,
+	orderBy []*protomodel.OrderByClause,
+	offset int64,
+	highlight bool,
+) (DocumentReader, error) {
+	targets := []sql.TargetEntry{{Exp: sql.NewColSelector(query.CollectionName, DocumentBLOBField)}}
+
+	var highlights []*fieldHighlight
+	var err error
+
+	if highlight {
+		targets, highlights, err = e.generateHighlightTargets(query.Expressions, table, targets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	op := sql.NewSelectStmt(
-		[]sql.TargetEntry{{Exp: sql.NewColSelector(query.CollectionName, DocumentBLOBField)}},
+		targets,
 		sql.NewTableRef(query.CollectionName, ""),
-		queryCondition,
-		generateSQLOrderByClauses(table, query.OrderBy),
+		condition,
+		generateSQLOrderByClauses(table, orderBy),
 		sql.NewInteger(int64(query.Limit)),
 		sql.NewInteger(offset),
-	)
+	).WithIndexOn(queryIndexFields(table, query))
 
 	// returning an open reader here, so the caller HAS to close it
 	r, err := e.sqlEngine.QueryPreparedStmt(ctx, sqlTx, op, nil)
 	if err != nil {
-		defer sqlTx.Cancel()
 		return nil, err
 	}
 
-	return newDocumentReader(r, func(_ DocumentReader) { sqlTx.Cancel() }), nil
+	decodeDoc := func(doc *structpb.Struct) error {
+		err := e.decryptDocument(table, doc)
+		if err != nil {
+			return err
+		}
+
+		err = e.valueCodecs.decodeDocument(query.CollectionName, doc)
+		if err != nil {
+			return err
+		}
+
+		return e.assembleBlobs(ctx, doc)
+	}
+
+	reader := newDocumentReader(r, decodeDoc, func(_ DocumentReader) { sqlTx.Cancel() })
+	reader.(*documentReader).highlights = highlights
+
+	return reader, nil
+}
+
+// generateHighlightTargets appends to the targets the evaluation of each field comparison of the query,
+// followed by the value of the field for comparisons matching a pattern so the matched substring can be located
+func (e *Engine) generateHighlightTargets(
+	expressions []*protomodel.QueryExpression,
+	table *sql.Table,
+	targets []sql.TargetEntry,
+) ([]sql.TargetEntry, []*fieldHighlight, error) {
+	var highlights []*fieldHighlight
+
+	for i, exp := range expressions {
+		for j, cmp := range exp.FieldComparisons {
+			fieldExp, err := e.generateSQLFieldComparison(cmp, table)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			h := &fieldHighlight{
+				expressionIndex: uint32(i),
+				comparisonIndex: uint32(j),
+				field:           cmp.Field,
+				operator:        cmp.Operator,
+				matchPos:        len(targets),
+			}
+
+			targets = append(targets, sql.TargetEntry{Exp: fieldExp, As: fmt.Sprintf("_match%d", len(highlights))})
+
+			pattern, ok := highlightPattern(cmp)
+			if ok {
+				h.pattern, err = regexp.Compile(pattern)
+				if err != nil {
+					return nil, nil, fmt.Errorf("%w: invalid pattern for field '%s': %v", ErrIllegalArguments, cmp.Field, err)
+				}
+
+				h.valuePos = len(targets)
+
+				targets = append(targets, sql.TargetEntry{
+					Exp: sql.NewColSelector(table.Name(), cmp.Field),
+					As:  fmt.Sprintf("_value%d", len(highlights)),
+				})
+			}
+
+			highlights = append(highlights, h)
+		}
+	}
+
+	return targets, highlights, nil
+}
+
+// highlightPattern returns the pattern locating the matched substring of the field value, only comparisons
+// evaluated by matching a pattern have one
+func highlightPattern(cmp *protomodel.FieldComparison) (string, bool) {
+	if cmp.CaseInsensitive {
+		pattern, negated, err := caseInsensitivePattern(cmp)
+		return pattern, err == nil && !negated
+	}
+
+	switch cmp.Operator {
+	case protomodel.ComparisonOperator_LIKE:
+		return cmp.Value.GetStringValue(), true
+	case protomodel.ComparisonOperator_IEQ:
+		return caseInsensitiveEqualityPattern(cmp.Value.GetStringValue()), true
+	}
+
+	return "", false
+}
+
+// ExplainQuery describes how the documents matching the query are retrieved: the index being used,
+// whether the whole collection needs to be scanned and the number of examined and matched documents.
+// Examined documents are estimated as the ones within the scanned index range, as the scan itself
+// may stop earlier, and both numbers are counted on their own, so explaining a query has a cost
+// comparable to running it.
+func (e *Engine) ExplainQuery(ctx context.Context, query *protomodel.Query) (*protomodel.QueryPlan, error) {
+	if query == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithReadOnly(true))
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	table, err := getTableForCollection(sqlTx, query.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	queryCondition, err := e.generateSQLFilteringExpression(query.Expressions, table)
+	if err != nil {
+		return nil, err
+	}
+
+	op := sql.NewSelectStmt(
+		[]sql.TargetEntry{{Exp: sql.NewColSelector(query.CollectionName, DocumentBLOBField)}},
+		sql.NewTableRef(query.CollectionName, ""),
+		queryCondition,
+		generateSQLOrderByClauses(table, effectiveOrderBy(table, query)),
+		sql.NewInteger(int64(query.Limit)),
+		nil,
+	).WithIndexOn(queryIndexFields(table, query))
+
+	r, err := e.sqlEngine.QueryPreparedStmt(ctx, sqlTx, op, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scanSpecs := r.ScanSpecs()
+
+	err = r.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	rangeScanCols := scanSpecs.RangeScanCols()
+
+	plan := &protomodel.QueryPlan{
+		Index:    scanSpecs.Index.Name(),
+		FullScan: rangeScanCols == 0,
+	}
+
+	rangeFields := make(map[string]struct{}, rangeScanCols)
+
+	for i, col := range scanSpecs.Index.Cols() {
+		plan.IndexFields = append(plan.IndexFields, col.Name())
+
+		if i < rangeScanCols {
+			rangeFields[col.Name()] = struct{}{}
+		}
+	}
+
+	filteredFields := make(map[string]struct{})
+
+	// examined documents are the ones within the index range i.e. matching the comparisons on range fields
+	rangeExpressions := make([]*protomodel.QueryExpression, 0, len(query.Expressions))
+
+	for _, exp := range query.Expressions {
+		rangeExp := &protomodel.QueryExpression{}
+
+		for _, cmp := range exp.FieldComparisons {
+			if _, ok := filteredFields[cmp.Field]; !ok {
+				filteredFields[cmp.Field] = struct{}{}
+				plan.FilteredFields = append(plan.FilteredFields, cmp.Field)
+			}
+
+			if _, ok := rangeFields[cmp.Field]; ok {
+				rangeExp.FieldComparisons = append(rangeExp.FieldComparisons, cmp)
+			}
+		}
+
+		if len(rangeExp.FieldComparisons) > 0 {
+			rangeExpressions = append(rangeExpressions, rangeExp)
+		}
+	}
+
+	if plan.FullScan {
+		rangeExpressions = nil
+	}
+
+	plan.DocumentsExamined, err = e.CountDocuments(ctx, &protomodel.Query{
+		CollectionName: query.CollectionName,
+		Expressions:    rangeExpressions,
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.DocumentsMatched, err = e.CountDocuments(ctx, &protomodel.Query{
+		CollectionName: query.CollectionName,
+		Expressions:    query.Expressions,
+		Limit:          query.Limit,
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
 }
 
 func (e *Engine) CountDocuments(ctx context.Context, query *protomodel.Query, offset int64) (int64, error) {
@@ -1017,19 +1886,24 @@ func (e *Engine) CountDocuments(ctx context.Context, query *protomodel.Query, of
 		return 0, err
 	}
 
-	queryCondition, err := generateSQLFilteringExpression(query.Expressions, table)
+	queryCondition, err := e.generateSQLFilteringExpression(query.Expressions, table)
 	if err != nil {
 		return 0, err
 	}
 
+	return e.countDocuments(ctx, sqlTx, table, query, queryCondition, offset)
+}
+
+// countDocuments counts within the given transaction the documents satisfying the condition of the query
+func (e *Engine) countDocuments(ctx context.Context, sqlTx *sql.SQLTx, table *sql.Table, query *protomodel.Query, queryCondition sql.ValueExp, offset int64) (int64, error) {
 	ds := sql.NewSelectStmt(
 		[]sql.TargetEntry{{Exp: sql.NewColSelector(query.CollectionName, table.Cols()[0].Name())}},
 		sql.NewTableRef(query.CollectionName, ""),
 		queryCondition,
-		generateSQLOrderByClauses(table, query.OrderBy),
+		generateSQLOrderByClauses(table, effectiveOrderBy(table, query)),
 		sql.NewInteger(int64(query.Limit)),
 		sql.NewInteger(offset),
-	)
+	).WithIndexOn(queryIndexFields(table, query))
 
 	op := sql.NewSelectStmt(
 		[]sql.TargetEntry{{Exp: sql.NewAggColSelector(sql.COUNT, query.CollectionName, "*")}},
@@ -1093,6 +1967,11 @@ func (e *Engine) AuditDocument(ctx context.Context, collectionName string, docID
 	}
 	defer sqlTx.Cancel()
 
+	table, err := getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
 	searchKey, err := e.getKeyForDocument(ctx, sqlTx, collectionName, docID)
 	if err != nil {
 		return nil, err
@@ -1111,6 +1990,23 @@ func (e *Engine) AuditDocument(ctx context.Context, collectionName string, docID
 			return nil, err
 		}
 
+		if docAtRevision.Document != nil {
+			err = e.decryptDocument(table, docAtRevision.Document)
+			if err != nil {
+				return nil, err
+			}
+
+			err = e.valueCodecs.decodeDocument(collectionName, docAtRevision.Document)
+			if err != nil {
+				return nil, err
+			}
+
+			err = e.assembleBlobs(ctx, docAtRevision.Document)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		hdr, err := e.sqlEngine.GetStore().ReadTxHeader(valRef.Tx(), false, false)
 		if err != nil {
 			return nil, err
@@ -1127,7 +2023,7 @@ func (e *Engine) AuditDocument(ctx context.Context, collectionName string, docID
 }
 
 // generateSQLFilteringExpression generates a boolean expression in Disjunctive Normal Form from a list of expressions
-func generateSQLFilteringExpression(expressions []*protomodel.QueryExpression, table *sql.Table) (sql.ValueExp, error) {
+func (e *Engine) generateSQLFilteringExpression(expressions []*protomodel.QueryExpression, table *sql.Table) (sql.ValueExp, error) {
 	var outerExp sql.ValueExp
 
 	for i, exp := range expressions {
@@ -1138,40 +2034,11 @@ func generateSQLFilteringExpression(expressions []*protomodel.QueryExpression, t
 		var innerExp sql.ValueExp
 
 		for i, exp := range exp.FieldComparisons {
-			column, err := getColumnForField(table, exp.Field)
+			fieldExp, err := e.generateSQLFieldComparison(exp, table)
 			if err != nil {
 				return nil, err
 			}
 
-			value, err := structValueToSqlValue(exp.Value, column.Type())
-			if err != nil {
-				return nil, err
-			}
-
-			colSelector := sql.NewColSelector(table.Name(), exp.Field)
-
-			var fieldExp sql.ValueExp
-
-			switch exp.Operator {
-			case protomodel.ComparisonOperator_LIKE:
-				{
-					fieldExp = sql.NewLikeBoolExp(colSelector, false, value)
-				}
-			case protomodel.ComparisonOperator_NOT_LIKE:
-				{
-					fieldExp = sql.NewLikeBoolExp(colSelector, true, value)
-				}
-			default:
-				{
-					sqlCmpOp, err := sqlCmpOperatorFor(exp.Operator)
-					if err != nil {
-						return nil, err
-					}
-
-					fieldExp = sql.NewCmpBoolExp(sqlCmpOp, colSelector, value)
-				}
-			}
-
 			if i == 0 {
 				innerExp = fieldExp
 			} else {
@@ -1189,6 +2056,188 @@ func generateSQLFilteringExpression(expressions []*protomodel.QueryExpression, t
 	return outerExp, nil
 }
 
+// generateSQLFieldComparison generates the boolean expression evaluating a single field comparison
+func (e *Engine) generateSQLFieldComparison(exp *protomodel.FieldComparison, table *sql.Table) (sql.ValueExp, error) {
+	if isEncryptedField(table, exp.Field) {
+		return nil, fmt.Errorf("%w: encrypted field '%s' can not be queried", ErrIllegalArguments, exp.Field)
+	}
+
+	column, err := getColumnForField(table, exp.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	if exp.CaseInsensitive {
+		return generateSQLCaseInsensitiveComparison(exp, table, column)
+	}
+
+	if exp.Operator == protomodel.ComparisonOperator_IN {
+		return e.generateSQLInComparison(exp, table, column)
+	}
+
+	cmpValue := exp.Value
+
+	if exp.Operator != protomodel.ComparisonOperator_LIKE &&
+		exp.Operator != protomodel.ComparisonOperator_NOT_LIKE &&
+		exp.Operator != protomodel.ComparisonOperator_IEQ {
+		// stored values are compared in their encoded form
+		cmpValue, err = e.valueCodecs.encodeValue(table.Name(), exp.Field, exp.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := structValueToSqlValue(cmpValue, column.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	colSelector := sql.NewColSelector(table.Name(), exp.Field)
+
+	switch exp.Operator {
+	case protomodel.ComparisonOperator_LIKE:
+		{
+			return sql.NewLikeBoolExp(colSelector, false, value), nil
+		}
+	case protomodel.ComparisonOperator_NOT_LIKE:
+		{
+			return sql.NewLikeBoolExp(colSelector, true, value), nil
+		}
+	case protomodel.ComparisonOperator_IEQ:
+		{
+			if column.Type() != sql.VarcharType {
+				return nil, fmt.Errorf("%w: operator '%s' can only be used on string fields", ErrIllegalArguments, exp.Operator)
+			}
+
+			pattern := sql.NewVarchar(caseInsensitiveEqualityPattern(exp.Value.GetStringValue()))
+
+			return sql.NewLikeBoolExp(colSelector, false, pattern), nil
+		}
+	default:
+		{
+			sqlCmpOp, err := sqlCmpOperatorFor(exp.Operator)
+			if err != nil {
+				return nil, err
+			}
+
+			return sql.NewCmpBoolExp(sqlCmpOp, colSelector, value), nil
+		}
+	}
+}
+
+// generateSQLInComparison generates the disjunction of the equality comparisons with each of the listed values.
+// The index range derived from such disjunction spans from the smallest to the biggest value, thus an index
+// on the field is scanned only within such bounds.
+func (e *Engine) generateSQLInComparison(exp *protomodel.FieldComparison, table *sql.Table, column *sql.Column) (sql.ValueExp, error) {
+	if len(exp.Values) == 0 {
+		return nil, fmt.Errorf("%w: operator '%s' requires at least one value", ErrIllegalArguments, exp.Operator)
+	}
+
+	colSelector := sql.NewColSelector(table.Name(), exp.Field)
+
+	var inExp sql.ValueExp
+
+	for i, v := range exp.Values {
+		// stored values are compared in their encoded form
+		cmpValue, err := e.valueCodecs.encodeValue(table.Name(), exp.Field, v)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := structValueToSqlValue(cmpValue, column.Type())
+		if err != nil {
+			return nil, err
+		}
+
+		eqExp := sql.NewCmpBoolExp(sql.EQ, colSelector, value)
+
+		if i == 0 {
+			inExp = eqExp
+		} else {
+			inExp = sql.NewBinBoolExp(sql.OR, inExp, eqExp)
+		}
+	}
+
+	return inExp, nil
+}
+
+// generateSQLCaseInsensitiveComparison generates the pattern matching of the field against the case-insensitive
+// pattern of the comparison, stored values are matched in their decoded form as done for LIKE comparisons
+func generateSQLCaseInsensitiveComparison(exp *protomodel.FieldComparison, table *sql.Table, column *sql.Column) (sql.ValueExp, error) {
+	if column.Type() != sql.VarcharType {
+		return nil, fmt.Errorf("%w: case-insensitive comparisons can only be used on string fields", ErrIllegalArguments)
+	}
+
+	pattern, negated, err := caseInsensitivePattern(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.NewLikeBoolExp(sql.NewColSelector(table.Name(), exp.Field), negated, sql.NewVarchar(pattern)), nil
+}
+
+// caseInsensitivePattern returns the pattern a case-insensitive comparison is evaluated with and whether
+// matching values are the ones not matching the pattern. Ordering operators are not supported
+// as values are not sorted by their case-folded form.
+func caseInsensitivePattern(exp *protomodel.FieldComparison) (pattern string, negated bool, err error) {
+	switch exp.Operator {
+	case protomodel.ComparisonOperator_EQ, protomodel.ComparisonOperator_IEQ, protomodel.ComparisonOperator_NE:
+		{
+			value, err := stringComparisonValue(exp.Value)
+			if err != nil {
+				return "", false, err
+			}
+
+			return caseInsensitiveEqualityPattern(value), exp.Operator == protomodel.ComparisonOperator_NE, nil
+		}
+	case protomodel.ComparisonOperator_LIKE, protomodel.ComparisonOperator_NOT_LIKE:
+		{
+			value, err := stringComparisonValue(exp.Value)
+			if err != nil {
+				return "", false, err
+			}
+
+			return "(?i)" + value, exp.Operator == protomodel.ComparisonOperator_NOT_LIKE, nil
+		}
+	case protomodel.ComparisonOperator_IN:
+		{
+			if len(exp.Values) == 0 {
+				return "", false, fmt.Errorf("%w: operator '%s' requires at least one value", ErrIllegalArguments, exp.Operator)
+			}
+
+			alternatives := make([]string, len(exp.Values))
+
+			for i, v := range exp.Values {
+				value, err := stringComparisonValue(v)
+				if err != nil {
+					return "", false, err
+				}
+
+				alternatives[i] = regexp.QuoteMeta(value)
+			}
+
+			return "(?i)^(?:" + strings.Join(alternatives, "|") + ")$", false, nil
+		}
+	}
+
+	return "", false, fmt.Errorf("%w: operator '%s' can not be case-insensitive", ErrIllegalArguments, exp.Operator)
+}
+
+func stringComparisonValue(value *structpb.Value) (string, error) {
+	s, ok := value.GetKind().(*structpb.Value_StringValue)
+	if !ok {
+		return "", fmt.Errorf("%w: case-insensitive comparisons require string values", ErrIllegalArguments)
+	}
+
+	return s.StringValue, nil
+}
+
+// caseInsensitiveEqualityPattern returns the pattern matching the whole value under Unicode simple case folding,
+// as defined by strings.EqualFold. The pattern is evaluated for every row, thus IEQ comparisons are never served by an index.
+func caseInsensitiveEqualityPattern(value string) string {
+	return "(?i)^" + regexp.QuoteMeta(value) + "$"
+}
+
 func sqlCmpOperatorFor(op protomodel.ComparisonOperator) (sql.CmpOperator, error) {
 	switch op {
 	case protomodel.ComparisonOperator_EQ:
@@ -1361,46 +2410,118 @@ func (e *Engine) getEncodedDocument(ctx context.Context, key []byte, atTx uint64
 }
 
 // DeleteDocuments deletes documents matching the query
-func (e *Engine) DeleteDocuments(ctx context.Context, username string, query *protomodel.Query) error {
+// Documents are deleted in bounded transactions, thus a failure may leave part of the documents deleted.
+// The limit of the query caps the number of deleted documents, no cap is applied when it's not set.
+// The number of deleted documents is returned.
+func (e *Engine) DeleteDocuments(ctx context.Context, username string, query *protomodel.Query) (deleted int, err error) {
 	if query == nil {
-		return ErrIllegalArguments
+		return 0, ErrIllegalArguments
+	}
+
+	batchSize := e.sqlEngine.GetStore().MaxTxEntries()
+
+	for query.Limit == 0 || deleted < int(query.Limit) {
+		limit := batchSize
+		if query.Limit > 0 && int(query.Limit)-deleted < limit {
+			limit = int(query.Limit) - deleted
+		}
+
+		n, err := e.deleteDocuments(ctx, username, query, limit)
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted += n
+
+		if n < limit {
+			break
+		}
 	}
 
+	return deleted, nil
+}
+
+// deleteDocuments deletes up to limit documents matching the query in a single transaction
+func (e *Engine) deleteDocuments(ctx context.Context, username string, query *protomodel.Query, limit int) (int, error) {
 	sqlTx, err := e.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithExtra([]byte(username)))
 	if err != nil {
-		return mayTranslateError(err)
+		return 0, mayTranslateError(err)
 	}
 	defer sqlTx.Cancel()
 
 	table, err := getTableForCollection(sqlTx, query.CollectionName)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	queryCondition, err := generateSQLFilteringExpression(query.Expressions, table)
+	queryCondition, err := e.generateSQLFilteringExpression(query.Expressions, table)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Delete a single document matching the query
 	deleteStmt := sql.NewDeleteFromStmt(
 		table.Name(),
 		queryCondition,
-		generateSQLOrderByClauses(table, query.OrderBy),
-		sql.NewInteger(int64(query.Limit)),
-	)
+		generateSQLOrderByClauses(table, effectiveOrderBy(table, query)),
+		sql.NewInteger(int64(limit)),
+	).WithIndexOn(queryIndexFields(table, query))
 
-	_, _, err = e.sqlEngine.ExecPreparedStmts(
+	_, ctxs, err := e.sqlEngine.ExecPreparedStmts(
 		ctx,
 		sqlTx,
 		[]sql.SQLStmt{deleteStmt},
 		nil,
 	)
 	if err != nil {
-		return mayTranslateError(err)
+		return 0, mayTranslateError(err)
 	}
 
-	return nil
+	return ctxs[0].UpdatedRows(), nil
+}
+
+// DeleteDocument deletes the document with the given id, whose latest revision becomes a tombstone.
+// The deleted document is no longer returned by searches while its previous revisions can still be audited,
+// inserting a document with the same id revives it as a new revision.
+func (e *Engine) DeleteDocument(ctx context.Context, username, collectionName string, docID DocumentID) (txID uint64, err error) {
+	if len(docID) == 0 {
+		return 0, fmt.Errorf("%w: no document id specified", ErrIllegalArguments)
+	}
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithExtra([]byte(username)))
+	if err != nil {
+		return 0, mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	table, err := getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return 0, err
+	}
+
+	idCondition, err := e.generateSQLFieldComparison(&protomodel.FieldComparison{
+		Field:    docIDFieldName(table),
+		Operator: protomodel.ComparisonOperator_EQ,
+		Value:    structpb.NewStringValue(docID.EncodeToHexString()),
+	}, table)
+	if err != nil {
+		return 0, err
+	}
+
+	_, ctxs, err := e.sqlEngine.ExecPreparedStmts(
+		ctx,
+		sqlTx,
+		[]sql.SQLStmt{sql.NewDeleteFromStmt(table.Name(), idCondition, nil, sql.NewInteger(1))},
+		nil,
+	)
+	if err != nil {
+		return 0, mayTranslateError(err)
+	}
+
+	if ctxs[0].UpdatedRows() == 0 {
+		return 0, fmt.Errorf("%w (%s)", ErrDocumentNotFound, docID.EncodeToHexString())
+	}
+
+	return ctxs[0].TxHeader().ID, nil
 }
 
 // CopyCatalogToTx copies the current sql catalog to the ongoing transaction.
@@ -1408,6 +2529,23 @@ func (e *Engine) CopyCatalogToTx(ctx context.Context, tx *store.OngoingTx) error
 	return e.sqlEngine.CopyCatalogToTx(ctx, tx)
 }
 
+// effectiveOrderBy falls back to the default order of the collection when no order is specified.
+// When the collection has no default order either, documents are sorted by id. Such order is made
+// explicit when the scan is narrowed to the index matching the query, as it's not sorted by id.
+func effectiveOrderBy(table *sql.Table, query *protomodel.Query) []*protomodel.OrderByClause {
+	orderBy := query.OrderBy
+
+	if len(orderBy) == 0 {
+		orderBy = defaultOrderBy(table)
+	}
+
+	if len(orderBy) == 0 && len(queryIndexFields(table, query)) > 0 {
+		orderBy = []*protomodel.OrderByClause{{Field: docIDFieldName(table)}}
+	}
+
+	return orderBy
+}
+
 func generateSQLOrderByClauses(table *sql.Table, orderBy []*protomodel.OrderByClause) (ordCols []*sql.OrdCol) {
 	for _, col := range orderBy {
 		ordCols = append(ordCols, sql.NewOrdCol(table.Name(), col.Field, col.Desc))
@@ -17,8 +17,10 @@ package document
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"strings"
 	"sync"
 	"testing"
 
@@ -60,6 +62,68 @@ func TestEngineWithInvalidOptions(t *testing.T) {
 	require.ErrorIs(t, err, ErrIllegalArguments)
 }
 
+func TestCollectionAndFieldNameValidation(t *testing.T) {
+	engine := makeEngine(t)
+
+	fields := []*protomodel.Field{{Name: "name", Type: protomodel.FieldType_STRING}}
+
+	for _, tc := range []struct {
+		name   string
+		reason error
+	}{
+		{"", ErrIllegalArguments},
+		{strings.Repeat("c", MaxCollectionNameLength+1), ErrMaxLengthExceeded},
+		{"pg_collection", ErrReservedName},
+		{"PG_TYPE", ErrReservedName},
+		{"path/collection", ErrIllegalArguments},
+		{"coll\xff", ErrIllegalArguments},
+	} {
+		err := engine.CreateCollection(context.Background(), "admin", tc.name, "", fields, nil)
+		require.ErrorIs(t, err, tc.reason)
+
+		var nameErr *InvalidNameError
+		require.ErrorAs(t, err, &nameErr)
+		require.Equal(t, "collection", nameErr.Kind)
+		require.Equal(t, tc.name, nameErr.Name)
+	}
+
+	err := engine.CreateCollection(
+		context.Background(),
+		"admin",
+		strings.Repeat("c", MaxCollectionNameLength),
+		"",
+		[]*protomodel.Field{{Name: strings.Repeat("f", MaxFieldNameLength), Type: protomodel.FieldType_STRING}},
+		nil,
+	)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		reason error
+	}{
+		{"", ErrIllegalArguments},
+		{strings.Repeat("f", MaxFieldNameLength+1), ErrMaxLengthExceeded},
+		{DocumentBLOBField, ErrReservedName},
+	} {
+		err := engine.CreateCollection(
+			context.Background(),
+			"admin",
+			"my_collection",
+			"",
+			[]*protomodel.Field{{Name: tc.name, Type: protomodel.FieldType_STRING}},
+			nil,
+		)
+		require.ErrorIs(t, err, tc.reason)
+
+		var nameErr *InvalidNameError
+		require.ErrorAs(t, err, &nameErr)
+		require.Equal(t, "field", nameErr.Kind)
+	}
+
+	err = engine.CreateCollection(context.Background(), "admin", "my_collection", strings.Repeat("i", MaxFieldNameLength+1), fields, nil)
+	require.ErrorIs(t, err, ErrMaxLengthExceeded)
+}
+
 func TestCreateCollection(t *testing.T) {
 	engine := makeEngine(t)
 
@@ -592,7 +656,7 @@ func TestDocumentAudit(t *testing.T) {
 		require.Equal(t, uint64(i+1), docAudit.Revision)
 	}
 
-	err = engine.DeleteDocuments(context.Background(), "admin", &protomodel.Query{
+	_, err = engine.DeleteDocuments(context.Background(), "admin", &protomodel.Query{
 		CollectionName: collectionName,
 		Expressions: []*protomodel.QueryExpression{
 			{
@@ -1284,6 +1348,162 @@ func TestDocumentUpdate(t *testing.T) {
 	})
 }
 
+func TestReplaceDocumentIfRevMatch(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		ctx,
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "name", Type: protomodel.FieldType_STRING},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	_, docID, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("Alice"),
+		},
+	})
+	require.NoError(t, err)
+
+	query := &protomodel.Query{CollectionName: collectionName}
+
+	toUpdateDoc := func(name string) *structpb.Struct {
+		return &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				DefaultDocumentIDField: structpb.NewStringValue(docID.EncodeToHexString()),
+				"name":                 structpb.NewStringValue(name),
+			},
+		}
+	}
+
+	t.Run("update with matching revision should succeed", func(t *testing.T) {
+		revisions, err := engine.ReplaceDocumentIfRevMatch(ctx, "admin", query, toUpdateDoc("Bob"), 1)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		require.EqualValues(t, 2, revisions[0].Revision)
+	})
+
+	t.Run("update with stale revision should fail with a conflict", func(t *testing.T) {
+		_, err := engine.ReplaceDocumentIfRevMatch(ctx, "admin", query, toUpdateDoc("Carol"), 1)
+		require.ErrorIs(t, err, ErrRevisionConflict)
+
+		var conflictErr *RevisionConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Equal(t, docID.EncodeToHexString(), conflictErr.DocumentID)
+		require.EqualValues(t, 2, conflictErr.CurrentRevision)
+
+		_, _, encDoc, err := engine.GetEncodedDocument(ctx, collectionName, docID, 0)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, encDoc.Revision)
+	})
+
+	t.Run("update with invalid revision should fail", func(t *testing.T) {
+		_, err := engine.ReplaceDocumentIfRevMatch(ctx, "admin", query, toUpdateDoc("Carol"), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("update of multiple documents should fail", func(t *testing.T) {
+		_, _, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"name": structpb.NewStringValue("Dave"),
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = engine.ReplaceDocumentIfRevMatch(ctx, "admin", &protomodel.Query{CollectionName: collectionName}, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"name": structpb.NewStringValue("Eve"),
+			},
+		}, 1)
+		require.ErrorIs(t, err, ErrMultipleDocumentsFound)
+	})
+}
+
+func TestUpdateDocumentIfTxMatch(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	// the document id field is renamed so the update must not assume its default name
+	err := engine.CreateCollection(ctx, "admin", collectionName, "doc_id", []*protomodel.Field{
+		{Name: "name", Type: protomodel.FieldType_STRING},
+	}, nil)
+	require.NoError(t, err)
+
+	txID, docID, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("Alice"),
+		},
+	})
+	require.NoError(t, err)
+
+	toUpdateDoc := func(name string) *structpb.Struct {
+		return &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"name": structpb.NewStringValue(name),
+			},
+		}
+	}
+
+	t.Run("update with matching transaction should succeed", func(t *testing.T) {
+		revision, err := engine.UpdateDocumentIfTxMatch(ctx, "admin", collectionName, docID, toUpdateDoc("Bob"), txID)
+		require.NoError(t, err)
+		require.Greater(t, revision.TransactionId, txID)
+		require.Equal(t, docID.EncodeToHexString(), revision.DocumentId)
+		require.EqualValues(t, 2, revision.Revision)
+
+		// updates are chained by the transaction returned by the previous one
+		revision, err = engine.UpdateDocumentIfTxMatch(ctx, "admin", collectionName, docID, toUpdateDoc("Carol"), revision.TransactionId)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, revision.Revision)
+
+		txID = revision.TransactionId
+
+		_, _, encDoc, err := engine.GetEncodedDocument(ctx, collectionName, docID, 0)
+		require.NoError(t, err)
+		require.Equal(t, txID, encDoc.TxID)
+	})
+
+	t.Run("update with stale transaction should fail with a conflict", func(t *testing.T) {
+		_, err := engine.UpdateDocumentIfTxMatch(ctx, "admin", collectionName, docID, toUpdateDoc("Dave"), txID-1)
+		require.ErrorIs(t, err, ErrTxConflict)
+
+		var conflictErr *TxConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Equal(t, docID.EncodeToHexString(), conflictErr.DocumentID)
+		require.Equal(t, txID, conflictErr.CurrentTxID)
+
+		_, _, encDoc, err := engine.GetEncodedDocument(ctx, collectionName, docID, 0)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, encDoc.Revision)
+	})
+
+	t.Run("update of an unexistent document should fail", func(t *testing.T) {
+		_, err := engine.UpdateDocumentIfTxMatch(ctx, "admin", collectionName, NewDocumentIDFromTx(0), toUpdateDoc("Dave"), txID)
+		require.ErrorIs(t, err, ErrDocumentNotFound)
+
+		_, err = engine.UpdateDocumentIfTxMatch(ctx, "admin", "unexistentCollection", docID, toUpdateDoc("Dave"), txID)
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+	})
+
+	t.Run("update with invalid arguments should fail", func(t *testing.T) {
+		_, err := engine.UpdateDocumentIfTxMatch(ctx, "admin", collectionName, nil, toUpdateDoc("Dave"), txID)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = engine.UpdateDocumentIfTxMatch(ctx, "admin", collectionName, docID, toUpdateDoc("Dave"), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
 func TestFloatSupport(t *testing.T) {
 	ctx := context.Background()
 	engine := makeEngine(t)
@@ -1925,6 +2145,51 @@ func TestPaginationOnReader(t *testing.T) {
 			require.Equal(t, i, doc.Document.Fields["pincode"].GetNumberValue())
 		}
 	})
+
+	t.Run("test reader count on the snapshot of the reader", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    "pincode",
+							Operator: protomodel.ComparisonOperator_GT,
+							Value:    structpb.NewNumberValue(10),
+						},
+					},
+				},
+			},
+		}
+
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		docs, err := reader.ReadN(ctx, 5)
+		require.NoError(t, err)
+		require.Len(t, docs, 5)
+
+		_, _, err = engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"country": structpb.NewStringValue("country-21"),
+				"pincode": structpb.NewNumberValue(21),
+			},
+		})
+		require.NoError(t, err)
+
+		count, err := reader.(DocumentCounter).Count(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 10, count)
+
+		count, err = engine.CountDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		require.EqualValues(t, 11, count)
+
+		docs, err = reader.ReadN(ctx, 10)
+		require.ErrorIs(t, err, ErrNoMoreDocuments)
+		require.Len(t, docs, 5)
+	})
 }
 
 func TestDeleteDocument(t *testing.T) {
@@ -1976,11 +2241,12 @@ func TestDeleteDocument(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, docs, 1)
 
-	err = engine.DeleteDocuments(ctx, "admin", nil)
+	_, err = engine.DeleteDocuments(ctx, "admin", nil)
 	require.ErrorIs(t, err, ErrIllegalArguments)
 
-	err = engine.DeleteDocuments(ctx, "admin", query)
+	deleted, err := engine.DeleteDocuments(ctx, "admin", query)
 	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
 
 	reader, err = engine.GetDocuments(ctx, query, 0)
 	require.NoError(t, err)
@@ -1990,140 +2256,294 @@ func TestDeleteDocument(t *testing.T) {
 	require.ErrorIs(t, ErrNoMoreDocuments, err)
 }
 
-func TestGetCollection(t *testing.T) {
+func TestDeleteDocumentByID(t *testing.T) {
+	ctx := context.Background()
 	engine := makeEngine(t)
-	collectionName := "mycollection1"
 
-	err := engine.CreateCollection(
-		context.Background(),
-		"admin",
-		collectionName,
-		"",
-		[]*protomodel.Field{
-			{Name: "number", Type: protomodel.FieldType_INTEGER},
-			{Name: "name", Type: protomodel.FieldType_STRING},
-			{Name: "pin", Type: protomodel.FieldType_INTEGER},
-			{Name: "country", Type: protomodel.FieldType_STRING},
-		},
-		[]*protomodel.Index{
-			{Fields: []string{"number"}},
-			{Fields: []string{"name"}},
-			{Fields: []string{"pin"}},
-			{Fields: []string{"country"}},
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(ctx, "admin", collectionName, "", []*protomodel.Field{
+		{Name: "country", Type: protomodel.FieldType_STRING},
+	}, nil)
+	require.NoError(t, err)
+
+	_, docID, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"country": structpb.NewStringValue("wonderland"),
 		},
-	)
+	})
 	require.NoError(t, err)
 
-	collection, err := engine.GetCollection(context.Background(), collectionName)
+	_, otherDocID, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"country": structpb.NewStringValue("neverland"),
+		},
+	})
 	require.NoError(t, err)
-	require.Equal(t, collectionName, collection.Name)
-	require.Len(t, collection.Fields, 5)
-	require.Len(t, collection.Indexes, 5)
 
-	expectedIndexKeys := []*protomodel.Field{
-		{Name: "_id", Type: protomodel.FieldType_STRING},
-		{Name: "number", Type: protomodel.FieldType_INTEGER},
-		{Name: "name", Type: protomodel.FieldType_STRING},
-		{Name: "pin", Type: protomodel.FieldType_INTEGER},
-		{Name: "country", Type: protomodel.FieldType_STRING},
+	countDocuments := func() int64 {
+		count, err := engine.CountDocuments(ctx, &protomodel.Query{CollectionName: collectionName}, 0)
+		require.NoError(t, err)
+		return count
 	}
 
-	for i, idxType := range expectedIndexKeys {
-		require.Equal(t, idxType.Name, collection.Fields[i].Name)
-		require.Equal(t, idxType.Type, collection.Fields[i].Type)
-	}
-}
+	_, err = engine.DeleteDocument(ctx, "admin", collectionName, nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
 
-func TestGetDocuments_WithOrderBy(t *testing.T) {
-	ctx := context.Background()
-	engine := makeEngine(t)
+	_, err = engine.DeleteDocument(ctx, "admin", "unexistentCollection", docID)
+	require.ErrorIs(t, err, ErrCollectionDoesNotExist)
 
-	collectionName := "mycollection"
+	_, err = engine.DeleteDocument(ctx, "admin", collectionName, NewDocumentIDFromTx(0))
+	require.ErrorIs(t, err, ErrDocumentNotFound)
 
-	err := engine.CreateCollection(
-		context.Background(),
-		"admin",
-		collectionName,
-		"",
-		[]*protomodel.Field{
-			{Name: "number", Type: protomodel.FieldType_DOUBLE},
-			{Name: "age", Type: protomodel.FieldType_DOUBLE},
-		},
-		[]*protomodel.Index{
-			{Fields: []string{"number", "age"}},
+	_, _, err = engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			DefaultDocumentIDField: structpb.NewStringValue(docID.EncodeToHexString()),
 		},
-	)
+	})
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	txID, err := engine.DeleteDocument(ctx, "admin", collectionName, docID)
 	require.NoError(t, err)
+	require.NotZero(t, txID)
+	require.EqualValues(t, 1, countDocuments())
 
-	noOfDocs := 5
+	_, err = engine.DeleteDocument(ctx, "admin", collectionName, docID)
+	require.ErrorIs(t, err, ErrDocumentNotFound)
 
-	for i := 1; i <= noOfDocs; i++ {
-		_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+	err = engine.sqlEngine.GetStore().WaitForIndexingUpto(ctx, txID)
+	require.NoError(t, err)
+
+	revisions, err := engine.AuditDocument(ctx, collectionName, docID, false, 0, 10, true)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, "wonderland", revisions[0].Document.Fields["country"].GetStringValue())
+	require.True(t, revisions[1].Metadata.Deleted)
+	require.Nil(t, revisions[1].Document)
+
+	t.Run("a deleted document should be revived by inserting it again", func(t *testing.T) {
+		txID, revivedDocID, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
 			Fields: map[string]*structpb.Value{
-				"number": structpb.NewNumberValue(float64(i)),
+				DefaultDocumentIDField: structpb.NewStringValue(docID.EncodeToHexString()),
+				"country":              structpb.NewStringValue("wonderland revisited"),
 			},
 		})
 		require.NoError(t, err)
-	}
+		require.Equal(t, docID, revivedDocID)
+		require.EqualValues(t, 2, countDocuments())
 
-	t.Run("order by single field", func(t *testing.T) {
-		query := &protomodel.Query{
-			CollectionName: collectionName,
-			Expressions: []*protomodel.QueryExpression{
-				{
-					FieldComparisons: []*protomodel.FieldComparison{
-						{
-							Field:    "number",
-							Operator: protomodel.ComparisonOperator_LE,
-							Value:    structpb.NewNumberValue(5),
-						},
-					},
-				},
-			},
-			OrderBy: []*protomodel.OrderByClause{{
-				Field: "number",
-				Desc:  true,
-			}},
-		}
+		err = engine.sqlEngine.GetStore().WaitForIndexingUpto(ctx, txID)
+		require.NoError(t, err)
 
-		reader, err := engine.GetDocuments(ctx, query, 0)
+		revisions, err := engine.AuditDocument(ctx, collectionName, docID, false, 0, 10, true)
+		require.NoError(t, err)
+		require.Len(t, revisions, 3)
+		require.EqualValues(t, 3, revisions[2].Revision)
+		require.Equal(t, "wonderland revisited", revisions[2].Document.Fields["country"].GetStringValue())
+
+		_, _, err = engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				DefaultDocumentIDField: structpb.NewStringValue(docID.EncodeToHexString()),
+			},
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("a deleted document should not be revived concurrently", func(t *testing.T) {
+		_, revivedDocID, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"country": structpb.NewStringValue("to be revived"),
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = engine.DeleteDocument(ctx, "admin", collectionName, revivedDocID)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, countDocuments())
+
+		sqlTx, err := engine.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithUnsafeMVCC(true))
+		require.NoError(t, err)
+		defer sqlTx.Cancel()
+
+		deleted, err := engine.isDeletedDocument(ctx, sqlTx, collectionName, revivedDocID)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		_, _, err = engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				DefaultDocumentIDField: structpb.NewStringValue(revivedDocID.EncodeToHexString()),
+				"country":              structpb.NewStringValue("first revival"),
+			},
+		})
+		require.NoError(t, err)
+
+		_, _, err = engine.upsertDocuments(ctx, sqlTx, collectionName, []*structpb.Struct{{
+			Fields: map[string]*structpb.Value{
+				DefaultDocumentIDField: structpb.NewStringValue(revivedDocID.EncodeToHexString()),
+				"country":              structpb.NewStringValue("second revival"),
+			},
+		}}, false)
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+
+		revisions, err := engine.AuditDocument(ctx, collectionName, revivedDocID, true, 0, 1, true)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		require.Equal(t, "first revival", revisions[0].Document.Fields["country"].GetStringValue())
+
+		_, err = engine.DeleteDocument(ctx, "admin", collectionName, revivedDocID)
+		require.NoError(t, err)
+	})
+
+	t.Run("only the document with the given id should be deleted", func(t *testing.T) {
+		_, err := engine.DeleteDocument(ctx, "admin", collectionName, otherDocID)
+		require.NoError(t, err)
+
+		reader, err := engine.GetDocuments(ctx, &protomodel.Query{CollectionName: collectionName}, 0)
 		require.NoError(t, err)
 		defer reader.Close()
 
-		docs, err := reader.ReadN(ctx, noOfDocs)
+		docs, err := reader.ReadN(ctx, 10)
+		require.ErrorIs(t, err, ErrNoMoreDocuments)
+		require.Len(t, docs, 1)
+		require.Equal(t, docID.EncodeToHexString(), docs[0].Document.Fields[DefaultDocumentIDField].GetStringValue())
+	})
+}
+
+func TestDeleteDocumentsInBatches(t *testing.T) {
+	ctx := context.Background()
+
+	st, err := store.Open(t.TempDir(), store.DefaultOptions().WithMultiIndexing(true).WithMaxTxEntries(8))
+	require.NoError(t, err)
+	defer st.Close()
+
+	engine, err := NewEngine(st, DefaultOptions().WithPrefix(docPrefix))
+	require.NoError(t, err)
+
+	collectionName := "mycollection"
+
+	err = engine.CreateCollection(
+		ctx,
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "country", Type: protomodel.FieldType_STRING},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"country"}},
+		},
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		country := "wonderland"
+		if i%4 == 0 {
+			country = "neverland"
+		}
+
+		_, _, err = engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"country": structpb.NewStringValue(country),
+			},
+		})
 		require.NoError(t, err)
-		require.Len(t, docs, 5)
+	}
 
-		i := noOfDocs
-		for _, doc := range docs {
-			require.Equal(t, float64(i), doc.Document.Fields["number"].GetNumberValue())
-			i--
+	query := func(country string, limit uint32) *protomodel.Query {
+		return &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "country", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue(country)},
+					},
+				},
+			},
+			Limit: limit,
 		}
+	}
+
+	countDocuments := func(country string) int64 {
+		count, err := engine.CountDocuments(ctx, query(country, 0), 0)
+		require.NoError(t, err)
+		return count
+	}
+
+	t.Run("deletion capped by the query limit should span multiple transactions", func(t *testing.T) {
+		txID := st.LastCommittedTxID()
+
+		deleted, err := engine.DeleteDocuments(ctx, "admin", query("wonderland", 10))
+		require.NoError(t, err)
+		require.Equal(t, 10, deleted)
+
+		require.Equal(t, txID+2, st.LastCommittedTxID())
+		require.EqualValues(t, 5, countDocuments("wonderland"))
+	})
+
+	t.Run("deletion should delete all the matching documents", func(t *testing.T) {
+		deleted, err := engine.DeleteDocuments(ctx, "admin", query("wonderland", 0))
+		require.NoError(t, err)
+		require.Equal(t, 5, deleted)
+
+		require.Zero(t, countDocuments("wonderland"))
+	})
+
+	t.Run("documents not matching the query should survive", func(t *testing.T) {
+		require.EqualValues(t, 5, countDocuments("neverland"))
 	})
 }
 
-func BenchmarkInsertion(b *testing.B) {
-	stOpts := store.DefaultOptions().
-		WithMultiIndexing(true).
-		WithMaxConcurrency(100)
+func TestGetCollection(t *testing.T) {
+	engine := makeEngine(t)
+	collectionName := "mycollection1"
 
-	st, err := store.Open(b.TempDir(), stOpts)
-	require.NoError(b, err)
+	err := engine.CreateCollection(
+		context.Background(),
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "number", Type: protomodel.FieldType_INTEGER},
+			{Name: "name", Type: protomodel.FieldType_STRING},
+			{Name: "pin", Type: protomodel.FieldType_INTEGER},
+			{Name: "country", Type: protomodel.FieldType_STRING},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"number"}},
+			{Fields: []string{"name"}},
+			{Fields: []string{"pin"}},
+			{Fields: []string{"country"}},
+		},
+	)
+	require.NoError(t, err)
 
-	defer func() {
-		err := st.Close()
-		if !b.Failed() {
-			// Do not pollute error output if test has already failed
-			require.NoError(b, err)
-		}
-	}()
+	collection, err := engine.GetCollection(context.Background(), collectionName)
+	require.NoError(t, err)
+	require.Equal(t, collectionName, collection.Name)
+	require.Len(t, collection.Fields, 5)
+	require.Len(t, collection.Indexes, 5)
 
-	engine, err := NewEngine(st, DefaultOptions().WithPrefix(docPrefix))
-	require.NoError(b, err)
+	expectedIndexKeys := []*protomodel.Field{
+		{Name: "_id", Type: protomodel.FieldType_STRING},
+		{Name: "number", Type: protomodel.FieldType_INTEGER},
+		{Name: "name", Type: protomodel.FieldType_STRING},
+		{Name: "pin", Type: protomodel.FieldType_INTEGER},
+		{Name: "country", Type: protomodel.FieldType_STRING},
+	}
+
+	for i, idxType := range expectedIndexKeys {
+		require.Equal(t, idxType.Name, collection.Fields[i].Name)
+		require.Equal(t, idxType.Type, collection.Fields[i].Type)
+	}
+}
+
+func TestGetDocuments_WithOrderBy(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
 
 	collectionName := "mycollection"
 
-	err = engine.CreateCollection(
+	err := engine.CreateCollection(
 		context.Background(),
 		"admin",
 		collectionName,
@@ -2136,33 +2556,1023 @@ func BenchmarkInsertion(b *testing.B) {
 			{Fields: []string{"number", "age"}},
 		},
 	)
-	require.NoError(b, err)
-
-	b.ResetTimer()
+	require.NoError(t, err)
 
-	noOfWorkers := 100
-	noOfDocs := 10
+	noOfDocs := 5
 
-	for it := 0; it < 1; it++ {
-		var wg sync.WaitGroup
-		wg.Add(noOfWorkers)
+	for i := 1; i <= noOfDocs; i++ {
+		_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"number": structpb.NewNumberValue(float64(i)),
+			},
+		})
+		require.NoError(t, err)
+	}
 
-		for w := 0; w < noOfWorkers; w++ {
-			go func(w int) {
-				for i := 1; i <= noOfDocs; i++ {
-					_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
-						Fields: map[string]*structpb.Value{
-							"number": structpb.NewNumberValue(float64(w*noOfDocs + i)),
+	t.Run("order by single field", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    "number",
+							Operator: protomodel.ComparisonOperator_LE,
+							Value:    structpb.NewNumberValue(5),
 						},
-					})
-					if err != nil {
-						b.Fail()
-					}
-				}
-				wg.Done()
-			}(w)
+					},
+				},
+			},
+			OrderBy: []*protomodel.OrderByClause{{
+				Field: "number",
+				Desc:  true,
+			}},
 		}
 
-		wg.Wait()
-	}
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		docs, err := reader.ReadN(ctx, noOfDocs)
+		require.NoError(t, err)
+		require.Len(t, docs, 5)
+
+		i := noOfDocs
+		for _, doc := range docs {
+			require.Equal(t, float64(i), doc.Document.Fields["number"].GetNumberValue())
+			i--
+		}
+	})
+}
+
+func TestGetDocuments_WithDefaultOrderBy(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	t.Run("default order on undefined fields should fail", func(t *testing.T) {
+		err := engine.CreateCollection(
+			ctx,
+			"admin",
+			collectionName,
+			"",
+			[]*protomodel.Field{
+				{Name: "number", Type: protomodel.FieldType_DOUBLE},
+			},
+			nil,
+			WithDefaultOrderBy([]*protomodel.OrderByClause{{Field: "age"}}),
+		)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	err := engine.CreateCollection(
+		ctx,
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "number", Type: protomodel.FieldType_DOUBLE},
+			{Name: "age", Type: protomodel.FieldType_DOUBLE},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"number"}},
+		},
+		WithDefaultOrderBy([]*protomodel.OrderByClause{{Field: "number", Desc: true}}),
+	)
+	require.NoError(t, err)
+
+	collection, err := engine.GetCollection(ctx, collectionName)
+	require.NoError(t, err)
+	require.Len(t, collection.DefaultOrderBy, 1)
+	require.Equal(t, "number", collection.DefaultOrderBy[0].Field)
+	require.True(t, collection.DefaultOrderBy[0].Desc)
+
+	for _, i := range []int{3, 1, 5, 2, 4} {
+		_, _, err = engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"number": structpb.NewNumberValue(float64(i)),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	readNumbers := func(t *testing.T, query *protomodel.Query) []float64 {
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		docs, err := reader.ReadN(ctx, 10)
+		require.ErrorIs(t, err, ErrNoMoreDocuments)
+
+		numbers := make([]float64, len(docs))
+		for i, doc := range docs {
+			numbers[i] = doc.Document.Fields["number"].GetNumberValue()
+		}
+
+		return numbers
+	}
+
+	t.Run("queries without order should use the default order", func(t *testing.T) {
+		numbers := readNumbers(t, &protomodel.Query{CollectionName: collectionName})
+		require.Equal(t, []float64{5, 4, 3, 2, 1}, numbers)
+	})
+
+	t.Run("explicit order should take precedence over the default order", func(t *testing.T) {
+		numbers := readNumbers(t, &protomodel.Query{
+			CollectionName: collectionName,
+			OrderBy:        []*protomodel.OrderByClause{{Field: "number"}},
+		})
+		require.Equal(t, []float64{1, 2, 3, 4, 5}, numbers)
+	})
+}
+
+func TestExplainQuery(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		context.Background(),
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "pincode", Type: protomodel.FieldType_INTEGER},
+			{Name: "city", Type: protomodel.FieldType_STRING},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"pincode"}},
+		},
+	)
+	require.NoError(t, err)
+
+	for i := 1; i <= 10; i++ {
+		_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"pincode": structpb.NewNumberValue(float64(i)),
+				"city":    structpb.NewStringValue(fmt.Sprintf("city-%d", i%2)),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("explain should fail on invalid queries", func(t *testing.T) {
+		_, err := engine.ExplainQuery(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = engine.ExplainQuery(ctx, &protomodel.Query{CollectionName: "unexistent"})
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+	})
+
+	t.Run("explain should report index usage on an indexed field", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    "pincode",
+							Operator: protomodel.ComparisonOperator_GE,
+							Value:    structpb.NewNumberValue(7),
+						},
+						{
+							Field:    "city",
+							Operator: protomodel.ComparisonOperator_EQ,
+							Value:    structpb.NewStringValue("city-0"),
+						},
+					},
+				},
+			},
+			OrderBy: []*protomodel.OrderByClause{{Field: "pincode"}},
+		})
+		require.NoError(t, err)
+		require.False(t, plan.FullScan)
+		require.Equal(t, []string{"pincode"}, plan.IndexFields)
+		require.Equal(t, []string{"pincode", "city"}, plan.FilteredFields)
+		require.EqualValues(t, 4, plan.DocumentsExamined)
+		require.EqualValues(t, 2, plan.DocumentsMatched)
+	})
+
+	t.Run("explain should report a full scan on a non-indexed field", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    "city",
+							Operator: protomodel.ComparisonOperator_EQ,
+							Value:    structpb.NewStringValue("city-1"),
+						},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, plan.FullScan)
+		require.Equal(t, []string{DefaultDocumentIDField}, plan.IndexFields)
+		require.Equal(t, []string{"city"}, plan.FilteredFields)
+		require.EqualValues(t, 10, plan.DocumentsExamined)
+		require.EqualValues(t, 5, plan.DocumentsMatched)
+	})
+}
+
+func BenchmarkInsertion(b *testing.B) {
+	stOpts := store.DefaultOptions().
+		WithMultiIndexing(true).
+		WithMaxConcurrency(100)
+
+	st, err := store.Open(b.TempDir(), stOpts)
+	require.NoError(b, err)
+
+	defer func() {
+		err := st.Close()
+		if !b.Failed() {
+			// Do not pollute error output if test has already failed
+			require.NoError(b, err)
+		}
+	}()
+
+	engine, err := NewEngine(st, DefaultOptions().WithPrefix(docPrefix))
+	require.NoError(b, err)
+
+	collectionName := "mycollection"
+
+	err = engine.CreateCollection(
+		context.Background(),
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "number", Type: protomodel.FieldType_DOUBLE},
+			{Name: "age", Type: protomodel.FieldType_DOUBLE},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"number", "age"}},
+		},
+	)
+	require.NoError(b, err)
+
+	b.ResetTimer()
+
+	noOfWorkers := 100
+	noOfDocs := 10
+
+	for it := 0; it < 1; it++ {
+		var wg sync.WaitGroup
+		wg.Add(noOfWorkers)
+
+		for w := 0; w < noOfWorkers; w++ {
+			go func(w int) {
+				for i := 1; i <= noOfDocs; i++ {
+					_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+						Fields: map[string]*structpb.Value{
+							"number": structpb.NewNumberValue(float64(w*noOfDocs + i)),
+						},
+					})
+					if err != nil {
+						b.Fail()
+					}
+				}
+				wg.Done()
+			}(w)
+		}
+
+		wg.Wait()
+	}
+}
+
+func TestGetDocumentsWithHighlights(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		context.Background(),
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "pincode", Type: protomodel.FieldType_INTEGER},
+			{Name: "city", Type: protomodel.FieldType_STRING},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	for i, city := range []string{"newark", "boston", "newport"} {
+		_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"pincode": structpb.NewNumberValue(float64(i + 1)),
+				"city":    structpb.NewStringValue(city),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	query := &protomodel.Query{
+		CollectionName: collectionName,
+		Expressions: []*protomodel.QueryExpression{
+			{
+				FieldComparisons: []*protomodel.FieldComparison{
+					{
+						Field:    "city",
+						Operator: protomodel.ComparisonOperator_LIKE,
+						Value:    structpb.NewStringValue("^new"),
+					},
+				},
+			},
+			{
+				FieldComparisons: []*protomodel.FieldComparison{
+					{
+						Field:    "pincode",
+						Operator: protomodel.ComparisonOperator_EQ,
+						Value:    structpb.NewNumberValue(2),
+					},
+				},
+			},
+		},
+		OrderBy: []*protomodel.OrderByClause{{Field: "pincode"}},
+	}
+
+	t.Run("documents should not be highlighted unless requested", func(t *testing.T) {
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		revisions, err := reader.ReadN(ctx, 3)
+		require.NoError(t, err)
+		require.Len(t, revisions, 3)
+
+		for _, rev := range revisions {
+			require.Empty(t, rev.Matches)
+		}
+	})
+
+	t.Run("highlights should identify the matching field and substring", func(t *testing.T) {
+		reader, err := engine.GetDocumentsWithHighlights(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		revisions, err := reader.ReadN(ctx, 3)
+		require.NoError(t, err)
+		require.Len(t, revisions, 3)
+
+		require.Len(t, revisions[0].Matches, 1)
+		require.Equal(t, "city", revisions[0].Matches[0].Field)
+		require.Equal(t, protomodel.ComparisonOperator_LIKE, revisions[0].Matches[0].Operator)
+		require.EqualValues(t, 0, revisions[0].Matches[0].ExpressionIndex)
+		require.Equal(t, &protomodel.SubstringMatch{Start: 0, End: 3}, revisions[0].Matches[0].Substring)
+
+		require.Len(t, revisions[1].Matches, 1)
+		require.Equal(t, "pincode", revisions[1].Matches[0].Field)
+		require.EqualValues(t, 1, revisions[1].Matches[0].ExpressionIndex)
+		require.Nil(t, revisions[1].Matches[0].Substring)
+
+		require.Len(t, revisions[2].Matches, 1)
+		require.Equal(t, "city", revisions[2].Matches[0].Field)
+		require.Equal(t, &protomodel.SubstringMatch{Start: 0, End: 3}, revisions[2].Matches[0].Substring)
+
+		rev, err := reader.Read(ctx)
+		require.ErrorIs(t, err, ErrNoMoreDocuments)
+		require.Nil(t, rev)
+	})
+
+	t.Run("highlighting should fail with an invalid pattern", func(t *testing.T) {
+		_, err := engine.GetDocumentsWithHighlights(ctx, &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    "city",
+							Operator: protomodel.ComparisonOperator_LIKE,
+							Value:    structpb.NewStringValue("new("),
+						},
+					},
+				},
+			},
+		}, 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
+func TestGetDocumentsWithCaseInsensitiveEquality(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		context.Background(),
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "pincode", Type: protomodel.FieldType_INTEGER},
+			{Name: "name", Type: protomodel.FieldType_STRING},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"name"}},
+		},
+	)
+	require.NoError(t, err)
+
+	for i, name := range []string{"john", "JOHN", "Johnny", "jon", "ÉMILE"} {
+		_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"pincode": structpb.NewNumberValue(float64(i + 1)),
+				"name":    structpb.NewStringValue(name),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	queryFor := func(field string, value *structpb.Value) *protomodel.Query {
+		return &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    field,
+							Operator: protomodel.ComparisonOperator_IEQ,
+							Value:    value,
+						},
+					},
+				},
+			},
+			OrderBy: []*protomodel.OrderByClause{{Field: "pincode"}},
+		}
+	}
+
+	readNames := func(t *testing.T, query *protomodel.Query) []string {
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		var names []string
+
+		for {
+			rev, err := reader.Read(ctx)
+			if errors.Is(err, ErrNoMoreDocuments) {
+				break
+			}
+			require.NoError(t, err)
+
+			names = append(names, rev.Document.Fields["name"].GetStringValue())
+		}
+
+		return names
+	}
+
+	t.Run("values should be matched regardless of their case", func(t *testing.T) {
+		require.Equal(t, []string{"john", "JOHN"}, readNames(t, queryFor("name", structpb.NewStringValue("John"))))
+		require.Equal(t, []string{"ÉMILE"}, readNames(t, queryFor("name", structpb.NewStringValue("émile"))))
+	})
+
+	t.Run("the value should be matched literally and as a whole", func(t *testing.T) {
+		require.Empty(t, readNames(t, queryFor("name", structpb.NewStringValue("j.hn"))))
+		require.Empty(t, readNames(t, queryFor("name", structpb.NewStringValue("joh"))))
+	})
+
+	t.Run("case-insensitive equality should not be served by the case-sensitive index", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, queryFor("name", structpb.NewStringValue("John")))
+		require.NoError(t, err)
+		require.True(t, plan.FullScan)
+	})
+
+	t.Run("case-insensitive equality should only be supported on string fields", func(t *testing.T) {
+		_, err := engine.GetDocuments(ctx, queryFor("pincode", structpb.NewNumberValue(1)), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("highlights should cover the whole value", func(t *testing.T) {
+		reader, err := engine.GetDocumentsWithHighlights(ctx, queryFor("name", structpb.NewStringValue("John")), 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		rev, err := reader.Read(ctx)
+		require.NoError(t, err)
+		require.Len(t, rev.Matches, 1)
+		require.Equal(t, &protomodel.SubstringMatch{Start: 0, End: 4}, rev.Matches[0].Substring)
+	})
+}
+
+func TestGetDocumentsWithCaseInsensitiveComparisons(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		context.Background(),
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "pincode", Type: protomodel.FieldType_INTEGER},
+			{Name: "name", Type: protomodel.FieldType_STRING},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"name"}},
+		},
+	)
+	require.NoError(t, err)
+
+	for i, name := range []string{"john", "JOHN", "Johnny", "jon", "ÉMILE", "Straße"} {
+		_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"pincode": structpb.NewNumberValue(float64(i + 1)),
+				"name":    structpb.NewStringValue(name),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	queryFor := func(cmp *protomodel.FieldComparison) *protomodel.Query {
+		cmp.CaseInsensitive = true
+
+		return &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{FieldComparisons: []*protomodel.FieldComparison{cmp}},
+			},
+			OrderBy: []*protomodel.OrderByClause{{Field: "pincode"}},
+		}
+	}
+
+	readNames := func(t *testing.T, query *protomodel.Query) []string {
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		var names []string
+
+		for {
+			rev, err := reader.Read(ctx)
+			if errors.Is(err, ErrNoMoreDocuments) {
+				break
+			}
+			require.NoError(t, err)
+
+			names = append(names, rev.Document.Fields["name"].GetStringValue())
+		}
+
+		return names
+	}
+
+	t.Run("equality should match values regardless of their case", func(t *testing.T) {
+		names := readNames(t, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_EQ,
+			Value:    structpb.NewStringValue("John"),
+		}))
+		require.Equal(t, []string{"john", "JOHN"}, names)
+
+		names = readNames(t, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_EQ,
+			Value:    structpb.NewStringValue("émile"),
+		}))
+		require.Equal(t, []string{"ÉMILE"}, names)
+
+		names = readNames(t, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_EQ,
+			Value:    structpb.NewStringValue("STRASSE"),
+		}))
+		require.Empty(t, names, "simple case folding does not expand characters")
+	})
+
+	t.Run("inequality should exclude values regardless of their case", func(t *testing.T) {
+		names := readNames(t, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_NE,
+			Value:    structpb.NewStringValue("John"),
+		}))
+		require.Equal(t, []string{"Johnny", "jon", "ÉMILE", "Straße"}, names)
+	})
+
+	t.Run("patterns should match regardless of their case", func(t *testing.T) {
+		names := readNames(t, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_LIKE,
+			Value:    structpb.NewStringValue("^JOHN"),
+		}))
+		require.Equal(t, []string{"john", "JOHN", "Johnny"}, names)
+
+		names = readNames(t, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_NOT_LIKE,
+			Value:    structpb.NewStringValue("^JO"),
+		}))
+		require.Equal(t, []string{"ÉMILE", "Straße"}, names)
+	})
+
+	t.Run("values in the list should be matched regardless of their case", func(t *testing.T) {
+		names := readNames(t, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_IN,
+			Values:   []*structpb.Value{structpb.NewStringValue("JON"), structpb.NewStringValue("straSSe"), structpb.NewStringValue("straße")},
+		}))
+		require.Equal(t, []string{"jon", "Straße"}, names)
+	})
+
+	t.Run("case-insensitive comparisons should not be served by the case-sensitive index", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_EQ,
+			Value:    structpb.NewStringValue("John"),
+		}))
+		require.NoError(t, err)
+		require.True(t, plan.FullScan)
+	})
+
+	t.Run("highlights should locate the case-insensitive match", func(t *testing.T) {
+		reader, err := engine.GetDocumentsWithHighlights(ctx, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_LIKE,
+			Value:    structpb.NewStringValue("NN"),
+		}), 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		rev, err := reader.Read(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Johnny", rev.Document.Fields["name"].GetStringValue())
+		require.Len(t, rev.Matches, 1)
+		require.Equal(t, &protomodel.SubstringMatch{Start: 3, End: 5}, rev.Matches[0].Substring)
+	})
+
+	t.Run("unsupported case-insensitive comparisons should fail", func(t *testing.T) {
+		_, err := engine.GetDocuments(ctx, queryFor(&protomodel.FieldComparison{
+			Field:    "pincode",
+			Operator: protomodel.ComparisonOperator_EQ,
+			Value:    structpb.NewNumberValue(1),
+		}), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = engine.GetDocuments(ctx, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_GT,
+			Value:    structpb.NewStringValue("john"),
+		}), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = engine.GetDocuments(ctx, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_EQ,
+			Value:    structpb.NewNumberValue(1),
+		}), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = engine.GetDocuments(ctx, queryFor(&protomodel.FieldComparison{
+			Field:    "name",
+			Operator: protomodel.ComparisonOperator_IN,
+		}), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
+func TestGetDocumentsWithInOperator(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		context.Background(),
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "age", Type: protomodel.FieldType_INTEGER},
+			{Name: "name", Type: protomodel.FieldType_STRING},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"age"}},
+		},
+	)
+	require.NoError(t, err)
+
+	for i := 1; i <= 50; i++ {
+		_, _, err = engine.InsertDocument(context.Background(), "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"age":  structpb.NewNumberValue(float64(i)),
+				"name": structpb.NewStringValue(fmt.Sprintf("name-%d", i)),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	queryFor := func(field string, values ...*structpb.Value) *protomodel.Query {
+		return &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    field,
+							Operator: protomodel.ComparisonOperator_IN,
+							Values:   values,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	readAges := func(t *testing.T, query *protomodel.Query) []float64 {
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		var ages []float64
+
+		for {
+			rev, err := reader.Read(ctx)
+			if errors.Is(err, ErrNoMoreDocuments) {
+				break
+			}
+			require.NoError(t, err)
+
+			ages = append(ages, rev.Document.Fields["age"].GetNumberValue())
+		}
+
+		return ages
+	}
+
+	t.Run("documents equal to any of the values should be matched", func(t *testing.T) {
+		ages := readAges(t, queryFor("age", structpb.NewNumberValue(32), structpb.NewNumberValue(30), structpb.NewNumberValue(31), structpb.NewNumberValue(99)))
+		require.Equal(t, []float64{30, 31, 32}, ages)
+
+		ages = readAges(t, queryFor("name", structpb.NewStringValue("name-7"), structpb.NewStringValue("name-3")))
+		require.ElementsMatch(t, []float64{3, 7}, ages)
+	})
+
+	t.Run("the index should be scanned within the bounds of the values", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, queryFor("age", structpb.NewNumberValue(30), structpb.NewNumberValue(32)))
+		require.NoError(t, err)
+		require.False(t, plan.FullScan)
+		require.Equal(t, []string{"age"}, plan.IndexFields)
+		require.EqualValues(t, 2, plan.DocumentsMatched)
+	})
+
+	t.Run("at least one value should be provided", func(t *testing.T) {
+		_, err := engine.GetDocuments(ctx, queryFor("age"), 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("values should match the field type", func(t *testing.T) {
+		_, err := engine.GetDocuments(ctx, queryFor("age", structpb.NewNumberValue(30), structpb.NewStringValue("thirty")), 0)
+		require.Error(t, err)
+	})
+}
+
+func TestQueryWithCompositeIndex(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		ctx,
+		"admin",
+		collectionName,
+		"",
+		[]*protomodel.Field{
+			{Name: "status", Type: protomodel.FieldType_STRING},
+			{Name: "createdAt", Type: protomodel.FieldType_INTEGER},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"status"}},
+			{Fields: []string{"status", "createdAt"}},
+		},
+	)
+	require.NoError(t, err)
+
+	for i := 1; i <= 10; i++ {
+		status := "open"
+		if i%2 == 0 {
+			status = "closed"
+		}
+
+		_, _, err = engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"status":    structpb.NewStringValue(status),
+				"createdAt": structpb.NewNumberValue(float64(i)),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	query := &protomodel.Query{
+		CollectionName: collectionName,
+		Expressions: []*protomodel.QueryExpression{
+			{
+				FieldComparisons: []*protomodel.FieldComparison{
+					{Field: "status", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue("open")},
+					{Field: "createdAt", Operator: protomodel.ComparisonOperator_GT, Value: structpb.NewNumberValue(4)},
+				},
+			},
+		},
+	}
+
+	t.Run("query on a prefix of the composite index should return the matching documents", func(t *testing.T) {
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		revisions, err := reader.ReadN(ctx, 10)
+		require.ErrorIs(t, err, ErrNoMoreDocuments)
+		require.Len(t, revisions, 3)
+
+		for i, rev := range revisions {
+			require.Equal(t, "open", rev.Document.Fields["status"].GetStringValue())
+			require.EqualValues(t, 5+2*i, rev.Document.Fields["createdAt"].GetNumberValue())
+		}
+	})
+
+	t.Run("explain should report the usage of the composite index", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, query)
+		require.NoError(t, err)
+		require.False(t, plan.FullScan)
+		require.Equal(t, []string{"status", "createdAt"}, plan.IndexFields)
+		require.EqualValues(t, 3, plan.DocumentsExamined)
+		require.EqualValues(t, 3, plan.DocumentsMatched)
+	})
+
+	t.Run("documents should be returned in id order unless an order is specified", func(t *testing.T) {
+		_, _, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"status":    structpb.NewStringValue("open"),
+				"createdAt": structpb.NewNumberValue(6),
+			},
+		})
+		require.NoError(t, err)
+
+		readCreatedAt := func(t *testing.T, query *protomodel.Query) []float64 {
+			reader, err := engine.GetDocuments(ctx, query, 0)
+			require.NoError(t, err)
+			defer reader.Close()
+
+			revisions, err := reader.ReadN(ctx, 10)
+			require.ErrorIs(t, err, ErrNoMoreDocuments)
+
+			createdAt := make([]float64, len(revisions))
+			for i, rev := range revisions {
+				createdAt[i] = rev.Document.Fields["createdAt"].GetNumberValue()
+			}
+			return createdAt
+		}
+
+		require.Equal(t, []float64{5, 7, 9, 6}, readCreatedAt(t, query))
+
+		plan, err := engine.ExplainQuery(ctx, query)
+		require.NoError(t, err)
+		require.Equal(t, []string{"status", "createdAt"}, plan.IndexFields)
+
+		require.Equal(t, []float64{5, 6, 7, 9}, readCreatedAt(t, &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions:    query.Expressions,
+			OrderBy:        []*protomodel.OrderByClause{{Field: "createdAt"}},
+		}))
+	})
+
+	t.Run("query on the leading field should use the single-field index", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "status", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue("closed")},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.False(t, plan.FullScan)
+		require.Equal(t, []string{"status"}, plan.IndexFields)
+		require.EqualValues(t, 5, plan.DocumentsMatched)
+	})
+
+	t.Run("query not filtering the leading field should not use the composite index", func(t *testing.T) {
+		plan, err := engine.ExplainQuery(ctx, &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "createdAt", Operator: protomodel.ComparisonOperator_GT, Value: structpb.NewNumberValue(4)},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, plan.FullScan)
+		require.Equal(t, []string{DefaultDocumentIDField}, plan.IndexFields)
+	})
+}
+
+func TestCollectionWithCustomDocumentIDField(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	collectionName := "mycollection"
+
+	err := engine.CreateCollection(
+		ctx,
+		"admin",
+		collectionName,
+		"uuid",
+		[]*protomodel.Field{
+			{Name: "name", Type: protomodel.FieldType_STRING},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	collection, err := engine.GetCollection(ctx, collectionName)
+	require.NoError(t, err)
+	require.Equal(t, "uuid", collection.DocumentIdFieldName)
+
+	t.Run("the id field should not be provided on insertion", func(t *testing.T) {
+		_, _, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"uuid": structpb.NewStringValue(NewDocumentIDFromTx(1).EncodeToHexString()),
+				"name": structpb.NewStringValue("alice"),
+			},
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	_, docID, err := engine.InsertDocument(ctx, "admin", collectionName, &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("alice"),
+		},
+	})
+	require.NoError(t, err)
+
+	query := func() *protomodel.Query {
+		return &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "uuid", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue(docID.EncodeToHexString())},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("documents should be searchable by the custom id field", func(t *testing.T) {
+		reader, err := engine.GetDocuments(ctx, query(), 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		revisions, err := reader.ReadN(ctx, 10)
+		require.ErrorIs(t, err, ErrNoMoreDocuments)
+		require.Len(t, revisions, 1)
+		require.Equal(t, docID.EncodeToHexString(), revisions[0].Document.Fields["uuid"].GetStringValue())
+		require.Equal(t, "alice", revisions[0].Document.Fields["name"].GetStringValue())
+		require.NotContains(t, revisions[0].Document.Fields, DefaultDocumentIDField)
+	})
+
+	t.Run("the default id field should not be queryable", func(t *testing.T) {
+		_, err := engine.GetDocuments(ctx, &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: DefaultDocumentIDField, Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue(docID.EncodeToHexString())},
+					},
+				},
+			},
+		}, 0)
+		require.ErrorIs(t, err, ErrFieldDoesNotExist)
+	})
+
+	t.Run("documents should be replaced by the custom id field", func(t *testing.T) {
+		revisions, err := engine.ReplaceDocuments(ctx, "admin", query(), &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"name": structpb.NewStringValue("bob"),
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		require.Equal(t, docID.EncodeToHexString(), revisions[0].DocumentId)
+		require.EqualValues(t, 2, revisions[0].Revision)
+
+		reader, err := engine.GetDocuments(ctx, query(), 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		rev, err := reader.Read(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "bob", rev.Document.Fields["name"].GetStringValue())
+	})
 }
@@ -17,28 +17,84 @@ package document
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/codenotary/immudb/embedded/sql"
 	"github.com/codenotary/immudb/embedded/store"
 )
 
 var (
-	ErrIllegalArguments        = store.ErrIllegalArguments
-	ErrUnsupportedType         = errors.New("unsupported type")
-	ErrUnexpectedValue         = errors.New("unexpected value")
-	ErrCollectionAlreadyExists = errors.New("collection already exists")
-	ErrCollectionDoesNotExist  = errors.New("collection does not exist")
-	ErrMaxLengthExceeded       = errors.New("max length exceeded")
-	ErrMultipleDocumentsFound  = errors.New("multiple documents found")
-	ErrDocumentNotFound        = errors.New("document not found")
-	ErrNoMoreDocuments         = errors.New("no more documents")
-	ErrFieldAlreadyExists      = errors.New("field already exists")
-	ErrFieldDoesNotExist       = errors.New("field does not exist")
-	ErrReservedName            = errors.New("reserved name")
-	ErrLimitedIndexCreation    = errors.New("unique index creation is only supported on empty collections")
-	ErrConflict                = errors.New("conflict due to uniqueness contraint violation or read document was updated by another transaction")
+	ErrIllegalArguments         = store.ErrIllegalArguments
+	ErrIllegalState             = store.ErrIllegalState
+	ErrUnsupportedType          = errors.New("unsupported type")
+	ErrUnexpectedValue          = errors.New("unexpected value")
+	ErrCollectionAlreadyExists  = errors.New("collection already exists")
+	ErrCollectionDoesNotExist   = errors.New("collection does not exist")
+	ErrMaxLengthExceeded        = errors.New("max length exceeded")
+	ErrMultipleDocumentsFound   = errors.New("multiple documents found")
+	ErrDocumentNotFound         = errors.New("document not found")
+	ErrNoMoreDocuments          = errors.New("no more documents")
+	ErrFieldAlreadyExists       = errors.New("field already exists")
+	ErrFieldDoesNotExist        = errors.New("field does not exist")
+	ErrReservedName             = errors.New("reserved name")
+	ErrLimitedIndexCreation     = errors.New("unique index creation is only supported on empty collections")
+	ErrConflict                 = errors.New("conflict due to uniqueness contraint violation or read document was updated by another transaction")
+	ErrValueCodecFailed         = errors.New("value codec failed")
+	ErrRevisionConflict         = errors.New("document revision does not match the current one")
+	ErrTxConflict               = errors.New("document was last updated by a transaction other than the expected one")
+	ErrFieldEncryptionKeyNotSet = errors.New("field encryption key not set")
+	ErrUndeclaredField          = errors.New("field not declared by the collection")
+	ErrCollectionNotDeleted     = errors.New("collection is not deleted")
+	ErrRetentionPeriodExpired   = errors.New("retention period of the deleted collection expired")
 )
 
+// RevisionConflictError is returned when a conditional update finds a revision other than the expected one,
+// CurrentRevision holds the revision the document is at
+type RevisionConflictError struct {
+	DocumentID      string
+	CurrentRevision uint64
+}
+
+func (e *RevisionConflictError) Error() string {
+	return fmt.Sprintf("%s, document '%s' is at revision %d", ErrRevisionConflict.Error(), e.DocumentID, e.CurrentRevision)
+}
+
+func (e *RevisionConflictError) Is(target error) bool {
+	return target == ErrRevisionConflict
+}
+
+// TxConflictError is returned when a conditional update finds the document was last updated by a transaction
+// other than the expected one, CurrentTxID holds the transaction the latest version was written by
+type TxConflictError struct {
+	DocumentID  string
+	CurrentTxID uint64
+}
+
+func (e *TxConflictError) Error() string {
+	return fmt.Sprintf("%s, document '%s' was last updated by transaction %d", ErrTxConflict.Error(), e.DocumentID, e.CurrentTxID)
+}
+
+func (e *TxConflictError) Is(target error) bool {
+	return target == ErrTxConflict
+}
+
+// InvalidNameError is returned when a collection or field name is rejected, Reason tells apart
+// names not matching the allowed charset (ErrIllegalArguments), too long (ErrMaxLengthExceeded) or
+// reserved (ErrReservedName)
+type InvalidNameError struct {
+	Kind   string
+	Name   string
+	Reason error
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("%s: invalid %s name '%s'", e.Reason.Error(), e.Kind, e.Name)
+}
+
+func (e *InvalidNameError) Is(target error) bool {
+	return errors.Is(e.Reason, target)
+}
+
 func mayTranslateError(err error) error {
 	if err == nil {
 		return nil
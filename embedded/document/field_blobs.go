@@ -0,0 +1,610 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/embedded/store"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultBlobChunkSize is the maximum size of each chunk the value of a field uploaded in parts is stored in,
+// it's further limited by the maximum value length of the store
+const DefaultBlobChunkSize = 512 << 10 // 512Kb
+
+// blobChunkPrefix is the prefix, following the one of the engine, of the keys chunks are stored under,
+// chunks are content-addressed thus their keys end with the sha256 digest of their content
+const blobChunkPrefix = "BLOB."
+
+// blobReferenceField is the only field of the struct the value of a field uploaded in parts is replaced with
+// within the stored document, which holds the digest and size of the whole value and the root of its chunk tree.
+// The digests of the chunks are themselves stored in manifest chunks, as many levels of them as needed for
+// the root to be a single chunk, so the size of the document does not depend on the size of the value.
+const blobReferenceField = "_blob"
+
+// BlobUpload receives the values of the fields of a document uploaded in parts. The chunks of all the values
+// are kept in memory until the document is inserted, so they are committed within the same transaction as
+// the document. Their number is thus bounded while receiving them by the maximum number of entries of a
+// transaction, minus the entries taken by the document itself.
+type BlobUpload struct {
+	e *Engine
+
+	collectionName string
+	table          *sql.Table
+
+	maxChunks int
+
+	// writers of the values, by field path
+	writers map[string]*BlobWriter
+
+	// content of the distinct data and manifest chunks of all the values
+	contents map[[sha256.Size]byte][]byte
+}
+
+// BlobWriter receives the value of a document field in parts, splitting it in content-addressed chunks kept
+// by the upload it belongs to. Values are assembled as strings, thus the data written must be valid UTF-8.
+type BlobWriter struct {
+	u *BlobUpload
+
+	chunkSize int
+	buf       []byte
+
+	// trailing bytes of an incomplete UTF-8 encoded rune
+	pendingRune []byte
+
+	digest hash.Hash
+	size   int64
+
+	// digests of the data chunks, in order
+	chunks [][sha256.Size]byte
+}
+
+// NewBlobUpload returns an upload of the values of fields of a document to be inserted into the collection
+// with InsertDocumentWithBlobs
+func (e *Engine) NewBlobUpload(ctx context.Context, collectionName string) (*BlobUpload, error) {
+	opts := sql.DefaultTxOptions().
+		WithReadOnly(true).
+		WithExplicitClose(true)
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, opts)
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	table, err := getTableForCollection(sqlTx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	// the document takes an entry for each index of the collection
+	maxChunks := e.sqlEngine.GetStore().MaxTxEntries() - len(table.GetIndexes())
+	if maxChunks < 1 {
+		return nil, fmt.Errorf("%w: collection '%s' has too many indexes for its documents to hold values uploaded in parts",
+			store.ErrMaxTxEntriesLimitExceeded, collectionName)
+	}
+
+	return &BlobUpload{
+		e:              e,
+		collectionName: collectionName,
+		table:          table,
+		maxChunks:      maxChunks,
+		writers:        make(map[string]*BlobWriter),
+		contents:       make(map[[sha256.Size]byte][]byte),
+	}, nil
+}
+
+// Writer returns the writer of the value of the field, encrypted fields can not be uploaded in parts
+// as chunks are stored in plain
+func (u *BlobUpload) Writer(fieldPath string) (*BlobWriter, error) {
+	if w, ok := u.writers[fieldPath]; ok {
+		return w, nil
+	}
+
+	if fieldPath == "" {
+		return nil, fmt.Errorf("%w: empty field name", ErrIllegalArguments)
+	}
+
+	if overlapsEncryptedField(u.table, fieldPath) {
+		return nil, fmt.Errorf("%w: field '%s' is encrypted and can not be uploaded in parts", ErrIllegalArguments, fieldPath)
+	}
+
+	chunkSize := DefaultBlobChunkSize
+
+	if maxValueLen := u.e.sqlEngine.GetStore().MaxValueLen(); maxValueLen < chunkSize {
+		chunkSize = maxValueLen
+	}
+
+	w := &BlobWriter{
+		u:         u,
+		chunkSize: chunkSize,
+		digest:    sha256.New(),
+	}
+
+	u.writers[fieldPath] = w
+
+	return w, nil
+}
+
+// Size returns the number of bytes written so far to all the values
+func (u *BlobUpload) Size() int64 {
+	var size int64
+
+	for _, w := range u.writers {
+		size += w.size
+	}
+
+	return size
+}
+
+// Size returns the number of bytes written so far
+func (w *BlobWriter) Size() int64 {
+	return w.size
+}
+
+// Write appends the data to the value, splitting it in chunks as they get filled up
+func (w *BlobWriter) Write(data []byte) error {
+	err := w.validateUTF8(data)
+	if err != nil {
+		return err
+	}
+
+	w.digest.Write(data)
+	w.size += int64(len(data))
+
+	w.buf = append(w.buf, data...)
+
+	for len(w.buf) >= w.chunkSize {
+		err := w.addDataChunk(w.buf[:w.chunkSize])
+		if err != nil {
+			return err
+		}
+
+		w.buf = w.buf[w.chunkSize:]
+	}
+
+	return nil
+}
+
+// validateUTF8 checks the data is valid UTF-8, a rune may be split across consecutive writes
+func (w *BlobWriter) validateUTF8(data []byte) error {
+	data = append(w.pendingRune, data...)
+
+	complete := len(data)
+
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				complete = i
+			}
+			break
+		}
+	}
+
+	if !utf8.Valid(data[:complete]) {
+		return fmt.Errorf("%w: field value is not valid UTF-8", ErrIllegalArguments)
+	}
+
+	w.pendingRune = append([]byte(nil), data[complete:]...)
+
+	return nil
+}
+
+func (w *BlobWriter) addDataChunk(chunk []byte) error {
+	digest, err := w.addChunk(chunk)
+	if err != nil {
+		return err
+	}
+
+	w.chunks = append(w.chunks, digest)
+
+	return nil
+}
+
+// addChunk keeps a copy of the chunk, chunks with the same content are kept once across all the values
+func (w *BlobWriter) addChunk(chunk []byte) (digest [sha256.Size]byte, err error) {
+	digest = sha256.Sum256(chunk)
+
+	if _, ok := w.u.contents[digest]; ok {
+		return digest, nil
+	}
+
+	if len(w.u.contents) == w.u.maxChunks {
+		return digest, fmt.Errorf("%w: uploaded values are too large to be stored within a single transaction", store.ErrMaxTxEntriesLimitExceeded)
+	}
+
+	w.u.contents[digest] = append([]byte(nil), chunk...)
+
+	return digest, nil
+}
+
+// addChunkTree adds the digests of the chunks in manifest chunks, level after level, until a single
+// root chunk remains. The depth is the number of manifest levels below the root, zero meaning the root is the only data chunk.
+func (w *BlobWriter) addChunkTree() (root [sha256.Size]byte, depth int, err error) {
+	level := w.chunks
+	digestsPerManifest := w.chunkSize / sha256.Size
+
+	for len(level) > 1 {
+		var nextLevel [][sha256.Size]byte
+
+		for i := 0; i < len(level); i += digestsPerManifest {
+			end := i + digestsPerManifest
+			if end > len(level) {
+				end = len(level)
+			}
+
+			manifest := make([]byte, 0, (end-i)*sha256.Size)
+			for _, digest := range level[i:end] {
+				manifest = append(manifest, digest[:]...)
+			}
+
+			digest, err := w.addChunk(manifest)
+			if err != nil {
+				return root, 0, err
+			}
+
+			nextLevel = append(nextLevel, digest)
+		}
+
+		level = nextLevel
+		depth++
+	}
+
+	return level[0], depth, nil
+}
+
+// close adds the remaining data and returns the reference to the whole value
+func (w *BlobWriter) close() (*structpb.Value, error) {
+	if len(w.pendingRune) > 0 {
+		return nil, fmt.Errorf("%w: field value is not valid UTF-8", ErrIllegalArguments)
+	}
+
+	// an empty value is stored as a single empty chunk
+	if len(w.buf) > 0 || len(w.chunks) == 0 {
+		err := w.addDataChunk(w.buf)
+		if err != nil {
+			return nil, err
+		}
+
+		w.buf = nil
+	}
+
+	root, depth, err := w.addChunkTree()
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"digest": structpb.NewStringValue(hex.EncodeToString(w.digest.Sum(nil))),
+		"size":   structpb.NewNumberValue(float64(w.size)),
+		"root":   structpb.NewStringValue(hex.EncodeToString(root[:])),
+		"depth":  structpb.NewNumberValue(float64(depth)),
+	}}
+
+	return structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+		blobReferenceField: structpb.NewStructValue(ref),
+	}}), nil
+}
+
+// initBlobChunkIndexing indexes the chunks of the field values uploaded in parts, so they can be read by their digest
+func initBlobChunkIndexing(st *store.ImmuStore, prefix []byte) error {
+	chunkPrefix := append(append([]byte(nil), prefix...), blobChunkPrefix...)
+
+	err := st.InitIndexing(&store.IndexSpec{
+		SourcePrefix:     chunkPrefix,
+		TargetPrefix:     chunkPrefix,
+		InjectiveMapping: true,
+	})
+	if err != nil && !errors.Is(err, store.ErrIndexAlreadyInitialized) {
+		return err
+	}
+
+	return nil
+}
+
+func (e *Engine) blobChunkKey(digest [sha256.Size]byte) []byte {
+	return append(append(append([]byte(nil), e.sqlEngine.GetPrefix()...), blobChunkPrefix...), digest[:]...)
+}
+
+// setBlobChunks adds to the transaction the chunks not already stored
+func (e *Engine) setBlobChunks(ctx context.Context, sqlTx *sql.SQLTx, chunks map[[sha256.Size]byte][]byte) error {
+	st := e.sqlEngine.GetStore()
+
+	for digest, chunk := range chunks {
+		key := e.blobChunkKey(digest)
+
+		_, err := st.Get(ctx, key)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, store.ErrKeyNotFound) {
+			return err
+		}
+
+		err = sqlTx.Set(key, nil, chunk)
+		if err != nil {
+			return mayTranslateError(err)
+		}
+	}
+
+	return nil
+}
+
+// InsertDocumentWithBlobs inserts the document with the value of each of the fields uploaded in parts being
+// the one received by its writer. The document only holds a reference to the chunks the values are stored in,
+// values are assembled back when the document is read. The chunks are committed within the same transaction
+// as the document.
+func (e *Engine) InsertDocumentWithBlobs(
+	ctx context.Context,
+	username string,
+	doc *structpb.Struct,
+	upload *BlobUpload,
+) (txID uint64, docID DocumentID, err error) {
+	if upload == nil || upload.e != e {
+		return 0, nil, fmt.Errorf("%w: invalid upload", ErrIllegalArguments)
+	}
+
+	err = checkNoBlobReferences(doc)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if doc == nil {
+		doc = &structpb.Struct{}
+	}
+
+	doc = cloneStruct(doc)
+
+	opts := sql.DefaultTxOptions().
+		WithUnsafeMVCC(true).
+		WithExtra([]byte(username)).
+		WithSnapshotMustIncludeTxID(func(lastPrecommittedTxID uint64) uint64 { return 0 }).
+		WithSnapshotRenewalPeriod(0)
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, opts)
+	if err != nil {
+		return 0, nil, mayTranslateError(err)
+	}
+	defer sqlTx.Cancel()
+
+	// the collection may have been altered while receiving the values
+	table, err := getTableForCollection(sqlTx, upload.collectionName)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for fieldPath, w := range upload.writers {
+		if overlapsEncryptedField(table, fieldPath) {
+			return 0, nil, fmt.Errorf("%w: field '%s' is encrypted and can not be uploaded in parts", ErrIllegalArguments, fieldPath)
+		}
+
+		ref, err := w.close()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		err = setStructValueAtFieldPath(doc, fieldPath, ref)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	err = e.setBlobChunks(ctx, sqlTx, upload.contents)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	txID, docIDs, err := e.upsertDocuments(ctx, sqlTx, upload.collectionName, []*structpb.Struct{doc}, true)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// chunks are read from their own index, which must include them as soon as the document is inserted
+	err = e.sqlEngine.GetStore().WaitForIndexingUpto(ctx, txID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return txID, docIDs[0], nil
+}
+
+func cloneStruct(s *structpb.Struct) *structpb.Struct {
+	fields := make(map[string]*structpb.Value, len(s.Fields))
+
+	for k, v := range s.Fields {
+		fields[k] = v
+	}
+
+	return &structpb.Struct{Fields: fields}
+}
+
+// setStructValueAtFieldPath sets the value of a field not yet present in the document,
+// nested documents along the path are created when missing
+func setStructValueAtFieldPath(doc *structpb.Struct, fieldPath string, value *structpb.Value) error {
+	if fieldPath == "" {
+		return fmt.Errorf("%w: empty field name", ErrIllegalArguments)
+	}
+
+	fields := strings.Split(fieldPath, documentFieldPathSeparator)
+
+	for i, field := range fields {
+		if i == len(fields)-1 {
+			if _, ok := doc.Fields[field]; ok {
+				return fmt.Errorf("%w: field '%s' is both in the document and uploaded in parts", ErrIllegalArguments, fieldPath)
+			}
+
+			doc.Fields[field] = value
+			return nil
+		}
+
+		nested, ok := doc.Fields[field]
+		if !ok {
+			nestedDoc := &structpb.Struct{Fields: make(map[string]*structpb.Value)}
+			doc.Fields[field] = structpb.NewStructValue(nestedDoc)
+			doc = nestedDoc
+			continue
+		}
+
+		if nested.GetStructValue() == nil {
+			return fmt.Errorf("%w: field '%s' is not a nested document", ErrIllegalArguments, strings.Join(fields[:i+1], documentFieldPathSeparator))
+		}
+
+		// nested documents are copied so the given document is not modified
+		nestedDoc := cloneStruct(nested.GetStructValue())
+		doc.Fields[field] = structpb.NewStructValue(nestedDoc)
+		doc = nestedDoc
+	}
+
+	return nil
+}
+
+// checkNoBlobReferences prevents documents from including blob references other than
+// the ones resulting from values uploaded in parts
+func checkNoBlobReferences(doc *structpb.Struct) error {
+	for _, v := range doc.GetFields() {
+		nested := v.GetStructValue()
+		if nested == nil {
+			continue
+		}
+
+		if _, ok := nested.Fields[blobReferenceField]; ok {
+			return fmt.Errorf("%w(%s)", ErrReservedName, blobReferenceField)
+		}
+
+		err := checkNoBlobReferences(nested)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// assembleBlobs replaces in place the blob references of the document with the values they refer to
+func (e *Engine) assembleBlobs(ctx context.Context, doc *structpb.Struct) error {
+	for field, v := range doc.GetFields() {
+		nested := v.GetStructValue()
+		if nested == nil {
+			continue
+		}
+
+		ref, ok := nested.Fields[blobReferenceField]
+		if !ok {
+			err := e.assembleBlobs(ctx, nested)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		value, err := e.readBlob(ctx, ref.GetStructValue())
+		if err != nil {
+			return err
+		}
+
+		doc.Fields[field] = structpb.NewStringValue(value)
+	}
+
+	return nil
+}
+
+func (e *Engine) readBlob(ctx context.Context, ref *structpb.Struct) (string, error) {
+	if ref == nil {
+		return "", fmt.Errorf("%w: invalid blob reference", ErrUnexpectedValue)
+	}
+
+	var root [sha256.Size]byte
+
+	n, err := hex.Decode(root[:], []byte(ref.Fields["root"].GetStringValue()))
+	if err != nil || n != sha256.Size {
+		return "", fmt.Errorf("%w: invalid blob reference", ErrUnexpectedValue)
+	}
+
+	var value bytes.Buffer
+	value.Grow(int(ref.Fields["size"].GetNumberValue()))
+
+	err = e.readChunkTree(ctx, root, int(ref.Fields["depth"].GetNumberValue()), &value)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(value.Bytes())
+
+	if hex.EncodeToString(digest[:]) != ref.Fields["digest"].GetStringValue() {
+		return "", fmt.Errorf("%w: blob does not match its digest", store.ErrCorruptedData)
+	}
+
+	return value.String(), nil
+}
+
+// readChunkTree appends to the value the data chunks below the given chunk, in order
+func (e *Engine) readChunkTree(ctx context.Context, digest [sha256.Size]byte, depth int, value *bytes.Buffer) error {
+	chunk, err := e.readBlobChunk(ctx, digest)
+	if err != nil {
+		return err
+	}
+
+	if depth == 0 {
+		value.Write(chunk)
+		return nil
+	}
+
+	if len(chunk)%sha256.Size != 0 {
+		return fmt.Errorf("%w: invalid blob manifest", store.ErrCorruptedData)
+	}
+
+	for i := 0; i < len(chunk); i += sha256.Size {
+		var childDigest [sha256.Size]byte
+		copy(childDigest[:], chunk[i:])
+
+		err = e.readChunkTree(ctx, childDigest, depth-1, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) readBlobChunk(ctx context.Context, digest [sha256.Size]byte) ([]byte, error) {
+	valRef, err := e.sqlEngine.GetStore().Get(ctx, e.blobChunkKey(digest))
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+
+	chunk, err := valRef.Resolve()
+	if err != nil {
+		return nil, mayTranslateError(err)
+	}
+
+	if sha256.Sum256(chunk) != digest {
+		return nil, fmt.Errorf("%w: blob chunk does not match its digest", store.ErrCorruptedData)
+	}
+
+	return chunk, nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestInsertDocumentWithBlobs(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	err := engine.CreateCollection(ctx, "admin", "files", "", []*protomodel.Field{
+		{Name: "name", Type: protomodel.FieldType_STRING},
+	}, nil)
+	require.NoError(t, err)
+
+	// multi-byte runes get split across parts and chunks
+	var sb strings.Builder
+	for i := 0; sb.Len() < 3<<20; i++ {
+		fmt.Fprintf(&sb, "line %d: immudb ñandú 文档\n", i)
+	}
+	content := sb.String()
+
+	write := func(t *testing.T, upload *BlobUpload, fieldPath, content string, partSize int) *BlobWriter {
+		w, err := upload.Writer(fieldPath)
+		require.NoError(t, err)
+
+		for i := 0; i < len(content); i += partSize {
+			end := i + partSize
+			if end > len(content) {
+				end = len(content)
+			}
+
+			err := w.Write([]byte(content[i:end]))
+			require.NoError(t, err)
+		}
+
+		return w
+	}
+
+	doc := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"name": structpb.NewStringValue("manual.txt"),
+	}}
+
+	newUpload := func(t *testing.T) *BlobUpload {
+		upload, err := engine.NewBlobUpload(ctx, "files")
+		require.NoError(t, err)
+		return upload
+	}
+
+	upload := newUpload(t)
+	write(t, upload, "content", content, 65_537)
+	write(t, upload, "preview.content", content[:1000], 7)
+	write(t, upload, "empty", "", 1)
+
+	txID, docID, err := engine.InsertDocumentWithBlobs(ctx, "admin", doc, upload)
+	require.NoError(t, err)
+	require.NotZero(t, txID)
+
+	require.NotContains(t, doc.Fields, "content", "the given document should not be modified")
+
+	t.Run("documents should be read with their values assembled", func(t *testing.T) {
+		reader, err := engine.GetDocuments(ctx, &protomodel.Query{CollectionName: "files"}, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		rev, err := reader.Read(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "manual.txt", rev.Document.Fields["name"].GetStringValue())
+		require.Equal(t, content, rev.Document.Fields["content"].GetStringValue())
+		require.Equal(t, content[:1000], rev.Document.Fields["preview"].GetStructValue().Fields["content"].GetStringValue())
+		require.Equal(t, "", rev.Document.Fields["empty"].GetStringValue())
+
+		revisions, err := engine.AuditDocument(ctx, "files", docID, false, 0, 1, true)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		require.Equal(t, content, revisions[0].Document.Fields["content"].GetStringValue())
+	})
+
+	t.Run("identical values should share their chunks", func(t *testing.T) {
+		w1 := write(t, newUpload(t), "content", content, 1<<20)
+		w2 := write(t, newUpload(t), "content", content, 4096)
+
+		ref1, err := w1.close()
+		require.NoError(t, err)
+
+		ref2, err := w2.close()
+		require.NoError(t, err)
+
+		require.Equal(t, ref1.String(), ref2.String())
+	})
+
+	t.Run("invalid uploads should fail", func(t *testing.T) {
+		w, err := newUpload(t).Writer("content")
+		require.NoError(t, err)
+
+		err = w.Write([]byte{0xff, 0xfe})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		upload := newUpload(t)
+		write(t, upload, "content", "ñ"[:1], 1)
+
+		_, _, err = engine.InsertDocumentWithBlobs(ctx, "admin", nil, upload)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		for _, fieldPath := range []string{"name", "name.content"} {
+			upload := newUpload(t)
+			write(t, upload, fieldPath, "", 1)
+
+			_, _, err = engine.InsertDocumentWithBlobs(ctx, "admin", doc, upload)
+			require.ErrorIs(t, err, ErrIllegalArguments)
+		}
+
+		_, err = newUpload(t).Writer("")
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, _, err = engine.InsertDocumentWithBlobs(ctx, "admin", doc, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = engine.NewBlobUpload(ctx, "missing")
+		require.ErrorIs(t, err, ErrCollectionDoesNotExist)
+	})
+
+	t.Run("chunks of failed uploads should not be stored", func(t *testing.T) {
+		upload := newUpload(t)
+		write(t, upload, "name", "orphan", 1)
+
+		_, _, err = engine.InsertDocumentWithBlobs(ctx, "admin", doc, upload)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = engine.readBlobChunk(ctx, sha256.Sum256([]byte("orphan")))
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("blob references should not be provided within documents", func(t *testing.T) {
+		forged := &structpb.Struct{Fields: map[string]*structpb.Value{
+			"content": structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+				blobReferenceField: structpb.NewStringValue("forged"),
+			}}),
+		}}
+
+		_, _, err := engine.InsertDocument(ctx, "admin", "files", forged)
+		require.ErrorIs(t, err, ErrReservedName)
+
+		_, _, err = engine.InsertDocumentWithBlobs(ctx, "admin", forged, newUpload(t))
+		require.ErrorIs(t, err, ErrReservedName)
+	})
+}
+
+func TestInsertDocumentWithBlobsLimits(t *testing.T) {
+	ctx := context.Background()
+
+	st, err := store.Open(t.TempDir(), store.DefaultOptions().WithMultiIndexing(true).WithMaxTxEntries(8))
+	require.NoError(t, err)
+	defer st.Close()
+
+	engine, err := NewEngine(st, DefaultOptions().WithPrefix(docPrefix).WithFieldEncryptionKey([]byte("0123456789abcdef0123456789abcdef")))
+	require.NoError(t, err)
+
+	err = engine.CreateCollection(ctx, "admin", "files", "", []*protomodel.Field{
+		{Name: "secret", Encrypted: true},
+		{Name: "card.number", Encrypted: true},
+	}, nil)
+	require.NoError(t, err)
+
+	t.Run("encrypted fields should not be uploaded in parts", func(t *testing.T) {
+		upload, err := engine.NewBlobUpload(ctx, "files")
+		require.NoError(t, err)
+
+		for _, field := range []string{"secret", "secret.content", "card"} {
+			_, err := upload.Writer(field)
+			require.ErrorIs(t, err, ErrIllegalArguments)
+		}
+	})
+
+	t.Run("values should fit within a single transaction along with the document", func(t *testing.T) {
+		upload, err := engine.NewBlobUpload(ctx, "files")
+		require.NoError(t, err)
+
+		chunk := func(i int) []byte {
+			return []byte(strings.Repeat(fmt.Sprintf("%c", 'a'+i), st.MaxValueLen()))
+		}
+
+		w1, err := upload.Writer("content")
+		require.NoError(t, err)
+
+		w2, err := upload.Writer("attachment")
+		require.NoError(t, err)
+
+		// the collection has a single index, so the document takes a single entry
+		for i := 0; i < st.MaxTxEntries()-1; i++ {
+			w := w1
+			if i%2 == 1 {
+				w = w2
+			}
+
+			err = w.Write(chunk(i))
+			require.NoError(t, err)
+		}
+
+		// chunks already received do not count twice
+		err = w2.Write(chunk(0))
+		require.NoError(t, err)
+
+		err = w1.Write(chunk(st.MaxTxEntries()))
+		require.ErrorIs(t, err, store.ErrMaxTxEntriesLimitExceeded)
+	})
+}
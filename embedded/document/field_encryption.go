@@ -0,0 +1,180 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// encryptedFieldsProperty is the table property holding the comma-separated paths of the encrypted fields
+const encryptedFieldsProperty = "encryptedFields"
+
+// fieldEncryptor encrypts the values of the fields marked as encrypted at collection creation.
+// Values are sealed with AES-GCM and stored as base64 encoded strings, the collection name and
+// field path are authenticated so a value can not be moved to another field.
+type fieldEncryptor struct {
+	aead cipher.AEAD
+}
+
+func newFieldEncryptor(key []byte) (*fieldEncryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid field encryption key: %v", ErrIllegalArguments, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fieldEncryptor{aead: aead}, nil
+}
+
+func fieldAdditionalData(collectionName, fieldPath string) []byte {
+	return []byte(collectionName + "/" + fieldPath)
+}
+
+func (f *fieldEncryptor) encrypt(collectionName, fieldPath string, value *structpb.Value) (*structpb.Value, error) {
+	plaintext, err := proto.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, f.aead.NonceSize(), f.aead.NonceSize()+len(plaintext)+f.aead.Overhead())
+
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := f.aead.Seal(nonce, nonce, plaintext, fieldAdditionalData(collectionName, fieldPath))
+
+	return structpb.NewStringValue(base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+func (f *fieldEncryptor) decrypt(collectionName, fieldPath string, value *structpb.Value) (*structpb.Value, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value.GetStringValue())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < f.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: encrypted value is too short", ErrUnexpectedValue)
+	}
+
+	plaintext, err := f.aead.Open(nil, sealed[:f.aead.NonceSize()], sealed[f.aead.NonceSize():], fieldAdditionalData(collectionName, fieldPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var decrypted structpb.Value
+
+	err = proto.Unmarshal(plaintext, &decrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decrypted, nil
+}
+
+func encryptedFields(table *sql.Table) []string {
+	encFields, _ := table.Property(encryptedFieldsProperty)
+	if encFields == "" {
+		return nil
+	}
+	return strings.Split(encFields, ",")
+}
+
+func isEncryptedField(table *sql.Table, fieldPath string) bool {
+	for _, encField := range encryptedFields(table) {
+		if encField == fieldPath {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsEncryptedField returns whether the field is encrypted, nested within an encrypted field or holds one
+func overlapsEncryptedField(table *sql.Table, fieldPath string) bool {
+	for _, encField := range encryptedFields(table) {
+		if encField == fieldPath ||
+			strings.HasPrefix(fieldPath, encField+documentFieldPathSeparator) ||
+			strings.HasPrefix(encField, fieldPath+documentFieldPathSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptDocument returns a copy of the document with the values of the encrypted fields sealed,
+// the same document is returned when the collection holds no encrypted field
+func (e *Engine) encryptDocument(table *sql.Table, doc *structpb.Struct) (*structpb.Struct, error) {
+	encFields := encryptedFields(table)
+	if len(encFields) == 0 {
+		return doc, nil
+	}
+
+	if e.fieldEncryptor == nil {
+		return nil, fmt.Errorf("%w: collection '%s' holds encrypted fields", ErrFieldEncryptionKeyNotSet, table.Name())
+	}
+
+	encryptedDoc := proto.Clone(doc).(*structpb.Struct)
+
+	for _, fieldPath := range encFields {
+		err := transformFieldValue(encryptedDoc, fieldPath, func(value *structpb.Value) (*structpb.Value, error) {
+			return e.fieldEncryptor.encrypt(table.Name(), fieldPath, value)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return encryptedDoc, nil
+}
+
+// decryptDocument decrypts in place the values of the encrypted fields
+func (e *Engine) decryptDocument(table *sql.Table, doc *structpb.Struct) error {
+	encFields := encryptedFields(table)
+	if len(encFields) == 0 {
+		return nil
+	}
+
+	if e.fieldEncryptor == nil {
+		return fmt.Errorf("%w: collection '%s' holds encrypted fields", ErrFieldEncryptionKeyNotSet, table.Name())
+	}
+
+	for _, fieldPath := range encFields {
+		err := transformFieldValue(doc, fieldPath, func(value *structpb.Value) (*structpb.Value, error) {
+			return e.fieldEncryptor.decrypt(table.Name(), fieldPath, value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
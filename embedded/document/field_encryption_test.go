@@ -0,0 +1,158 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFieldEncryption(t *testing.T) {
+	ctx := context.Background()
+
+	st, err := store.Open(t.TempDir(), store.DefaultOptions().WithMultiIndexing(true))
+	require.NoError(t, err)
+	defer st.Close()
+
+	_, err = NewEngine(st, DefaultOptions().WithPrefix(docPrefix).WithFieldEncryptionKey([]byte("short")))
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	plainEngine, err := NewEngine(st, DefaultOptions().WithPrefix(docPrefix))
+	require.NoError(t, err)
+
+	engine, err := NewEngine(st, DefaultOptions().WithPrefix(docPrefix).WithFieldEncryptionKey([]byte("0123456789abcdef0123456789abcdef")))
+	require.NoError(t, err)
+
+	fields := []*protomodel.Field{
+		{Name: "name", Type: protomodel.FieldType_STRING},
+		{Name: "ssn", Encrypted: true},
+		{Name: "card.number", Encrypted: true},
+	}
+
+	t.Run("collections with encrypted fields require a key", func(t *testing.T) {
+		err := plainEngine.CreateCollection(ctx, "admin", "people", "", fields, nil)
+		require.ErrorIs(t, err, ErrFieldEncryptionKeyNotSet)
+	})
+
+	t.Run("encrypted fields should not be indexed", func(t *testing.T) {
+		err := engine.CreateCollection(ctx, "admin", "people", "", fields, []*protomodel.Index{{Fields: []string{"ssn"}}})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		err = engine.CreateCollection(ctx, "admin", "people", "", fields, nil, WithDefaultOrderBy([]*protomodel.OrderByClause{{Field: "ssn"}}))
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	err = engine.CreateCollection(ctx, "admin", "people", "", fields, []*protomodel.Index{{Fields: []string{"name"}}})
+	require.NoError(t, err)
+
+	collection, err := engine.GetCollection(ctx, "people")
+	require.NoError(t, err)
+	require.Contains(t, collection.Fields, &protomodel.Field{Name: "ssn", Encrypted: true})
+	require.Contains(t, collection.Fields, &protomodel.Field{Name: "card.number", Encrypted: true})
+
+	txID, docID, err := engine.InsertDocument(ctx, "admin", "people", &structpb.Struct{Fields: map[string]*structpb.Value{
+		"name": structpb.NewStringValue("alice"),
+		"ssn":  structpb.NewStringValue("123-45-6789"),
+		"card": structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+			"number": structpb.NewNumberValue(4111111111111111),
+			"holder": structpb.NewStringValue("Alice Smith"),
+		}}),
+	}})
+	require.NoError(t, err)
+
+	t.Run("encrypted values should not be stored in plain", func(t *testing.T) {
+		_, _, encodedDoc, err := engine.GetEncodedDocument(ctx, "people", docID, txID)
+		require.NoError(t, err)
+		require.NotContains(t, string(encodedDoc.EncodedDocument), "123-45-6789")
+		require.Contains(t, string(encodedDoc.EncodedDocument), "alice")
+		require.Contains(t, string(encodedDoc.EncodedDocument), "Alice Smith")
+	})
+
+	t.Run("encrypted values should be decrypted on read", func(t *testing.T) {
+		reader, err := engine.GetDocuments(ctx, &protomodel.Query{
+			CollectionName: "people",
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "name", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue("alice")},
+					},
+				},
+			},
+		}, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		revision, err := reader.Read(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "123-45-6789", revision.Document.Fields["ssn"].GetStringValue())
+
+		card := revision.Document.Fields["card"].GetStructValue()
+		require.EqualValues(t, 4111111111111111, card.Fields["number"].GetNumberValue())
+		require.Equal(t, "Alice Smith", card.Fields["holder"].GetStringValue())
+
+		revisions, err := engine.AuditDocument(ctx, "people", docID, false, 0, 10, true)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		require.Equal(t, "123-45-6789", revisions[0].Document.Fields["ssn"].GetStringValue())
+	})
+
+	t.Run("encrypted fields should not be queryable", func(t *testing.T) {
+		_, err := engine.GetDocuments(ctx, &protomodel.Query{
+			CollectionName: "people",
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "ssn", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue("123-45-6789")},
+					},
+				},
+			},
+		}, 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("encrypted fields should not be used to paginate by token", func(t *testing.T) {
+		_, _, err := engine.GetDocumentsPage(ctx, &protomodel.Query{
+			CollectionName: "people",
+			OrderBy:        []*protomodel.OrderByClause{{Field: "ssn"}},
+		}, "", 1, false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+		require.Contains(t, err.Error(), "encrypted field 'ssn'")
+	})
+
+	t.Run("encrypted fields should not be added as regular ones", func(t *testing.T) {
+		err := engine.AddField(ctx, "admin", "people", &protomodel.Field{Name: "ssn", Type: protomodel.FieldType_STRING})
+		require.ErrorIs(t, err, ErrFieldAlreadyExists)
+
+		err = engine.AddField(ctx, "admin", "people", &protomodel.Field{Name: "pin", Encrypted: true})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("documents should not be read without the key", func(t *testing.T) {
+		_, err := plainEngine.AuditDocument(ctx, "people", docID, false, 0, 10, true)
+		require.ErrorIs(t, err, ErrFieldEncryptionKeyNotSet)
+
+		_, _, err = plainEngine.InsertDocument(ctx, "admin", "people", &structpb.Struct{Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("bob"),
+		}})
+		require.ErrorIs(t, err, ErrFieldEncryptionKeyNotSet)
+	})
+}
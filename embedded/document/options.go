@@ -17,20 +17,30 @@ package document
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
 )
 
 const DefaultDocumentMaxNestedFields = 3
+const DefaultDeletedCollectionRetention = 7 * 24 * time.Hour
 
 type Options struct {
 	prefix          []byte
 	maxNestedFields int
+	valueCodecs     *ValueCodecRegistry
+
+	// key used to encrypt the fields marked as encrypted, an AES key of 16, 24 or 32 bytes
+	fieldEncryptionKey []byte
+
+	// period soft-deleted collections can be restored within, they are purged afterwards
+	deletedCollectionRetention time.Duration
 }
 
 func DefaultOptions() *Options {
 	return &Options{
-		maxNestedFields: DefaultDocumentMaxNestedFields,
+		maxNestedFields:            DefaultDocumentMaxNestedFields,
+		deletedCollectionRetention: DefaultDeletedCollectionRetention,
 	}
 }
 
@@ -39,6 +49,10 @@ func (opts *Options) Validate() error {
 		return fmt.Errorf("%w: nil options", store.ErrInvalidOptions)
 	}
 
+	if opts.deletedCollectionRetention < 0 {
+		return fmt.Errorf("%w: invalid deleted collection retention", store.ErrInvalidOptions)
+	}
+
 	return nil
 }
 
@@ -51,3 +65,21 @@ func (opts *Options) WithMaxNestedFields(maxNestedFields int) *Options {
 	opts.maxNestedFields = maxNestedFields
 	return opts
 }
+
+// WithValueCodecs sets the registry of codecs applied to field values on insert and read
+func (opts *Options) WithValueCodecs(valueCodecs *ValueCodecRegistry) *Options {
+	opts.valueCodecs = valueCodecs
+	return opts
+}
+
+// WithFieldEncryptionKey sets the key used to encrypt the values of the fields marked as encrypted
+func (opts *Options) WithFieldEncryptionKey(key []byte) *Options {
+	opts.fieldEncryptionKey = key
+	return opts
+}
+
+// WithDeletedCollectionRetention sets the period soft-deleted collections can be restored within
+func (opts *Options) WithDeletedCollectionRetention(retention time.Duration) *Options {
+	opts.deletedCollectionRetention = retention
+	return opts
+}
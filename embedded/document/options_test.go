@@ -17,6 +17,7 @@ package document
 
 import (
 	"testing"
+	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/stretchr/testify/require"
@@ -30,6 +31,7 @@ func TestDefaultOptions(t *testing.T) {
 	require.NotNil(t, opts)
 
 	require.Equal(t, DefaultDocumentMaxNestedFields, opts.maxNestedFields)
+	require.Equal(t, DefaultDeletedCollectionRetention, opts.deletedCollectionRetention)
 }
 
 func TestOptionsValidate(t *testing.T) {
@@ -63,3 +65,11 @@ func TestOptionsWithMaxNestedFields(t *testing.T) {
 
 	require.Equal(t, 20, opts.maxNestedFields)
 }
+
+func TestOptionsWithDeletedCollectionRetention(t *testing.T) {
+	opts := DefaultOptions().WithDeletedCollectionRetention(time.Hour)
+	require.Equal(t, time.Hour, opts.deletedCollectionRetention)
+
+	err := opts.WithDeletedCollectionRetention(-time.Hour).Validate()
+	require.ErrorIs(t, err, store.ErrInvalidOptions)
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GetDocumentsPage returns up to pageSize documents matching the query, starting right after the position
+// encoded in the page token, or from the first document when no token is given.
+// Documents are sorted as in GetDocuments, ties being broken by the document id, so the position of the last
+// document read is encoded into the returned token, which is empty when there are no more documents.
+// As the page is read from the position in the index rather than skipping the preceding documents,
+// the cost of reading a page does not grow with the number of pages read before it.
+func (e *Engine) GetDocumentsPage(
+	ctx context.Context,
+	query *protomodel.Query,
+	pageToken string,
+	pageSize int,
+	highlight bool,
+) (revisions []*protomodel.DocumentAtRevision, nextPageToken string, err error) {
+	if query == nil || pageSize < 1 {
+		return nil, "", ErrIllegalArguments
+	}
+
+	if query.Limit > 0 {
+		return nil, "", fmt.Errorf("%w: query limit can not be used when paginating by token", ErrIllegalArguments)
+	}
+
+	sqlTx, err := e.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithReadOnly(true))
+	if err != nil {
+		return nil, "", mayTranslateError(err)
+	}
+
+	table, err := getTableForCollection(sqlTx, query.CollectionName)
+	if err != nil {
+		defer sqlTx.Cancel()
+		return nil, "", err
+	}
+
+	orderBy := pageOrderBy(table, query)
+
+	for _, clause := range orderBy {
+		// page tokens hold the values of the sorted fields as read from the decrypted documents
+		if isEncryptedField(table, clause.Field) {
+			defer sqlTx.Cancel()
+			return nil, "", fmt.Errorf("%w: encrypted field '%s' can not be used to paginate by token", ErrIllegalArguments, clause.Field)
+		}
+	}
+
+	queryCondition, err := e.generateSQLFilteringExpression(query.Expressions, table)
+	if err != nil {
+		defer sqlTx.Cancel()
+		return nil, "", err
+	}
+
+	if pageToken != "" {
+		position, err := decodePageToken(query.CollectionName, orderBy, pageToken)
+		if err != nil {
+			defer sqlTx.Cancel()
+			return nil, "", err
+		}
+
+		positionCondition, err := e.generateSQLPositionExpression(table, orderBy, position)
+		if err != nil {
+			defer sqlTx.Cancel()
+			return nil, "", err
+		}
+
+		if queryCondition == nil {
+			queryCondition = positionCondition
+		} else {
+			queryCondition = sql.NewBinBoolExp(sql.AND, queryCondition, positionCondition)
+		}
+	}
+
+	reader, err := e.newDocumentReader(ctx, sqlTx, table, query, queryCondition, orderBy, 0, highlight)
+	if err != nil {
+		defer sqlTx.Cancel()
+		return nil, "", err
+	}
+	defer reader.Close()
+
+	// an extra document is read to know whether there is a next page
+	revisions, err = reader.ReadN(ctx, pageSize+1)
+	if err != nil && !errors.Is(err, ErrNoMoreDocuments) {
+		return nil, "", err
+	}
+
+	if len(revisions) <= pageSize {
+		return revisions, "", nil
+	}
+
+	revisions = revisions[:pageSize]
+
+	nextPageToken, err = e.encodePageToken(query.CollectionName, orderBy, revisions[pageSize-1].Document)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return revisions, nextPageToken, nil
+}
+
+// pageOrderBy returns the order documents are paginated by, which is the one used by GetDocuments with
+// the document id appended, so the position of every document is unique.
+// The document id is sorted as the last field, thus an index on the sorted fields still serves the order.
+func pageOrderBy(table *sql.Table, query *protomodel.Query) []*protomodel.OrderByClause {
+	orderBy := effectiveOrderBy(table, query)
+	idField := docIDFieldName(table)
+
+	for _, clause := range orderBy {
+		if clause.Field == idField {
+			return orderBy
+		}
+	}
+
+	desc := len(orderBy) > 0 && orderBy[len(orderBy)-1].Desc
+
+	pageOrderBy := make([]*protomodel.OrderByClause, len(orderBy), len(orderBy)+1)
+	copy(pageOrderBy, orderBy)
+
+	return append(pageOrderBy, &protomodel.OrderByClause{Field: idField, Desc: desc})
+}
+
+// encodePageToken encodes the collection name followed by each sorted field, its direction and the value
+// it has in the document
+func (e *Engine) encodePageToken(collectionName string, orderBy []*protomodel.OrderByClause, doc *structpb.Struct) (string, error) {
+	values := make([]*structpb.Value, 0, 1+3*len(orderBy))
+	values = append(values, structpb.NewStringValue(collectionName))
+
+	for _, clause := range orderBy {
+		value, err := e.structValueFromFieldPath(doc, clause.Field)
+		if errors.Is(err, ErrFieldDoesNotExist) {
+			// documents lacking the field are sorted as if its value was null
+			value = structpb.NewNullValue()
+		} else if err != nil {
+			return "", err
+		}
+
+		values = append(values,
+			structpb.NewStringValue(clause.Field),
+			structpb.NewBoolValue(clause.Desc),
+			value,
+		)
+	}
+
+	bs, err := proto.Marshal(&structpb.ListValue{Values: values})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+// decodePageToken returns the values of the sorted fields encoded in the page token, which must have been
+// issued for the same collection and order
+func decodePageToken(collectionName string, orderBy []*protomodel.OrderByClause, pageToken string) ([]*structpb.Value, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid page token", ErrIllegalArguments)
+	}
+
+	var token structpb.ListValue
+
+	err = proto.Unmarshal(bs, &token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid page token", ErrIllegalArguments)
+	}
+
+	values := token.GetValues()
+
+	if len(values) != 1+3*len(orderBy) || values[0].GetStringValue() != collectionName {
+		return nil, fmt.Errorf("%w: page token does not match the query", ErrIllegalArguments)
+	}
+
+	position := make([]*structpb.Value, len(orderBy))
+
+	for i, clause := range orderBy {
+		field := values[1+3*i]
+		desc := values[2+3*i]
+
+		if field.GetStringValue() != clause.Field || desc.GetBoolValue() != clause.Desc {
+			return nil, fmt.Errorf("%w: page token does not match the query", ErrIllegalArguments)
+		}
+
+		position[i] = values[3+3*i]
+	}
+
+	return position, nil
+}
+
+// generateSQLPositionExpression generates the boolean expression satisfied by the documents sorted after
+// the given position i.e. those whose sorted fields equal the ones of the position up to a field which
+// is beyond the one of the position
+func (e *Engine) generateSQLPositionExpression(table *sql.Table, orderBy []*protomodel.OrderByClause, position []*structpb.Value) (sql.ValueExp, error) {
+	var outerExp sql.ValueExp
+
+	for i, clause := range orderBy {
+		op := protomodel.ComparisonOperator_GT
+		if clause.Desc {
+			op = protomodel.ComparisonOperator_LT
+		}
+
+		innerExp, err := e.generateSQLFieldComparison(&protomodel.FieldComparison{
+			Field:    clause.Field,
+			Operator: op,
+			Value:    position[i],
+		}, table)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := i - 1; j >= 0; j-- {
+			eqExp, err := e.generateSQLFieldComparison(&protomodel.FieldComparison{
+				Field:    orderBy[j].Field,
+				Operator: protomodel.ComparisonOperator_EQ,
+				Value:    position[j],
+			}, table)
+			if err != nil {
+				return nil, err
+			}
+
+			innerExp = sql.NewBinBoolExp(sql.AND, eqExp, innerExp)
+		}
+
+		if i == 0 {
+			outerExp = innerExp
+		} else {
+			outerExp = sql.NewBinBoolExp(sql.OR, outerExp, innerExp)
+		}
+	}
+
+	return outerExp, nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestGetDocumentsPage(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	err := engine.CreateCollection(ctx, "admin", "people", "", []*protomodel.Field{
+		{Name: "age", Type: protomodel.FieldType_INTEGER},
+		{Name: "name", Type: protomodel.FieldType_STRING},
+	}, []*protomodel.Index{{Fields: []string{"age"}}})
+	require.NoError(t, err)
+
+	// many documents share the same age so pages end in the middle of ties
+	docs := make([]*structpb.Struct, 23)
+
+	for i := range docs {
+		docs[i] = &structpb.Struct{Fields: map[string]*structpb.Value{
+			"age":  structpb.NewNumberValue(float64(i % 4)),
+			"name": structpb.NewStringValue("person"),
+		}}
+	}
+
+	_, _, err = engine.InsertDocuments(ctx, "admin", "people", docs)
+	require.NoError(t, err)
+
+	readAllPages := func(t *testing.T, query *protomodel.Query, pageSize int) []*protomodel.DocumentAtRevision {
+		var results []*protomodel.DocumentAtRevision
+		var pageToken string
+
+		for {
+			revisions, nextPageToken, err := engine.GetDocumentsPage(ctx, query, pageToken, pageSize, false)
+			require.NoError(t, err)
+
+			results = append(results, revisions...)
+
+			if nextPageToken == "" {
+				return results
+			}
+
+			require.Len(t, revisions, pageSize)
+			pageToken = nextPageToken
+		}
+	}
+
+	for _, desc := range []bool{false, true} {
+		query := &protomodel.Query{
+			CollectionName: "people",
+			Expressions: []*protomodel.QueryExpression{{
+				FieldComparisons: []*protomodel.FieldComparison{
+					{Field: "age", Operator: protomodel.ComparisonOperator_GE, Value: structpb.NewNumberValue(1)},
+				},
+			}},
+			OrderBy: []*protomodel.OrderByClause{{Field: "age", Desc: desc}},
+		}
+
+		for _, pageSize := range []int{1, 4, 5, 17, 100} {
+			results := readAllPages(t, query, pageSize)
+			require.Len(t, results, 17)
+
+			seen := make(map[string]struct{}, len(results))
+
+			for i, rev := range results {
+				docID := rev.Document.Fields[DefaultDocumentIDField].GetStringValue()
+
+				_, duplicated := seen[docID]
+				require.False(t, duplicated)
+				seen[docID] = struct{}{}
+
+				if i > 0 {
+					prevAge := results[i-1].Document.Fields["age"].GetNumberValue()
+					age := rev.Document.Fields["age"].GetNumberValue()
+
+					if desc {
+						require.LessOrEqual(t, age, prevAge)
+					} else {
+						require.GreaterOrEqual(t, age, prevAge)
+					}
+				}
+			}
+		}
+	}
+
+	t.Run("documents should be paginated by id when no order is specified", func(t *testing.T) {
+		results := readAllPages(t, &protomodel.Query{CollectionName: "people"}, 6)
+		require.Len(t, results, len(docs))
+
+		for i := 1; i < len(results); i++ {
+			require.Less(t, results[i-1].Document.Fields[DefaultDocumentIDField].GetStringValue(), results[i].Document.Fields[DefaultDocumentIDField].GetStringValue())
+		}
+	})
+
+	t.Run("pages should be read from the index range following the page token", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: "people",
+			OrderBy:        []*protomodel.OrderByClause{{Field: "age"}},
+		}
+
+		revisions, pageToken, err := engine.GetDocumentsPage(ctx, query, "", 10, false)
+		require.NoError(t, err)
+		require.Len(t, revisions, 10)
+		require.NotEmpty(t, pageToken)
+
+		sqlTx, err := engine.sqlEngine.NewTx(ctx, sql.DefaultTxOptions().WithReadOnly(true))
+		require.NoError(t, err)
+		defer sqlTx.Cancel()
+
+		table, err := getTableForCollection(sqlTx, "people")
+		require.NoError(t, err)
+
+		orderBy := pageOrderBy(table, query)
+
+		position, err := decodePageToken("people", orderBy, pageToken)
+		require.NoError(t, err)
+
+		condition, err := engine.generateSQLPositionExpression(table, orderBy, position)
+		require.NoError(t, err)
+
+		r, err := engine.sqlEngine.QueryPreparedStmt(ctx, sqlTx, sql.NewSelectStmt(
+			[]sql.TargetEntry{{Exp: sql.NewColSelector("people", DocumentBLOBField)}},
+			sql.NewTableRef("people", ""),
+			condition,
+			generateSQLOrderByClauses(table, orderBy),
+			nil,
+			nil,
+		), nil)
+		require.NoError(t, err)
+
+		scanSpecs := r.ScanSpecs()
+		require.NoError(t, r.Close())
+
+		require.Equal(t, "age", scanSpecs.Index.Cols()[0].Name())
+		require.Equal(t, 1, scanSpecs.RangeScanCols())
+	})
+
+	t.Run("invalid page tokens should be rejected", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: "people",
+			OrderBy:        []*protomodel.OrderByClause{{Field: "age"}},
+		}
+
+		_, pageToken, err := engine.GetDocumentsPage(ctx, query, "", 1, false)
+		require.NoError(t, err)
+
+		_, _, err = engine.GetDocumentsPage(ctx, query, "not a token", 1, false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, _, err = engine.GetDocumentsPage(ctx, &protomodel.Query{
+			CollectionName: "people",
+			OrderBy:        []*protomodel.OrderByClause{{Field: "age", Desc: true}},
+		}, pageToken, 1, false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, _, err = engine.GetDocumentsPage(ctx, &protomodel.Query{
+			CollectionName: "people",
+			OrderBy:        []*protomodel.OrderByClause{{Field: "name"}},
+		}, pageToken, 1, false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, _, err = engine.GetDocumentsPage(ctx, &protomodel.Query{CollectionName: "people", Limit: 1}, "", 1, false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, _, err = engine.GetDocumentsPage(ctx, query, "", 0, false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, _, err = engine.GetDocumentsPage(ctx, nil, "", 1, false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// strictProperty is the table property set on collections rejecting documents with undeclared fields
+const strictProperty = "strict"
+
+func isStrictCollection(table *sql.Table) bool {
+	strict, _ := table.Property(strictProperty)
+	return strict == "true"
+}
+
+// checkDeclaredFields returns an error listing the fields of the document not declared by the collection,
+// nothing is checked unless the collection was created as strict. Declared fields may be omitted.
+func checkDeclaredFields(table *sql.Table, doc *structpb.Struct) error {
+	if !isStrictCollection(table) {
+		return nil
+	}
+
+	declared := make(map[string]struct{})
+	parents := make(map[string]struct{})
+
+	addDeclared := func(fieldPath string) {
+		declared[fieldPath] = struct{}{}
+
+		for i := strings.LastIndex(fieldPath, "."); i > 0; i = strings.LastIndex(fieldPath[:i], ".") {
+			parents[fieldPath[:i]] = struct{}{}
+		}
+	}
+
+	for _, col := range table.Cols() {
+		if col.Name() != DocumentBLOBField {
+			addDeclared(col.Name())
+		}
+	}
+
+	for _, field := range encryptedFields(table) {
+		addDeclared(field)
+	}
+
+	var undeclared []string
+
+	var walk func(prefix string, s *structpb.Struct)
+	walk = func(prefix string, s *structpb.Struct) {
+		names := make([]string, 0, len(s.Fields))
+		for name := range s.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fieldPath := prefix + name
+
+			if _, ok := declared[fieldPath]; ok {
+				continue
+			}
+
+			nested := s.Fields[name].GetStructValue()
+
+			if _, ok := parents[fieldPath]; ok && nested != nil {
+				walk(fieldPath+".", nested)
+				continue
+			}
+
+			undeclared = append(undeclared, fmt.Sprintf("'%s'", fieldPath))
+		}
+	}
+
+	walk("", doc)
+
+	if len(undeclared) > 0 {
+		return fmt.Errorf("%w: collection '%s' does not declare %s", ErrUndeclaredField, table.Name(), strings.Join(undeclared, ", "))
+	}
+
+	return nil
+}
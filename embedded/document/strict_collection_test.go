@@ -0,0 +1,109 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestStrictCollection(t *testing.T) {
+	ctx := context.Background()
+	engine := makeEngine(t)
+
+	fields := []*protomodel.Field{
+		{Name: "name", Type: protomodel.FieldType_STRING},
+		{Name: "age", Type: protomodel.FieldType_INTEGER},
+		{Name: "address.city", Type: protomodel.FieldType_STRING},
+	}
+
+	err := engine.CreateStrictCollection(ctx, "admin", "people", "", fields, nil)
+	require.NoError(t, err)
+
+	err = engine.CreateCollection(ctx, "admin", "loose_people", "", fields, nil)
+	require.NoError(t, err)
+
+	collection, err := engine.GetCollection(ctx, "people")
+	require.NoError(t, err)
+	require.True(t, collection.Strict)
+
+	collection, err = engine.GetCollection(ctx, "loose_people")
+	require.NoError(t, err)
+	require.False(t, collection.Strict)
+
+	t.Run("declared fields may be omitted", func(t *testing.T) {
+		_, _, err := engine.InsertDocument(ctx, "admin", "people", &structpb.Struct{Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("alice"),
+			"address": structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+				"city": structpb.NewStringValue("Rome"),
+			}}),
+		}})
+		require.NoError(t, err)
+	})
+
+	t.Run("undeclared fields should be rejected", func(t *testing.T) {
+		_, _, err := engine.InsertDocument(ctx, "admin", "people", &structpb.Struct{Fields: map[string]*structpb.Value{
+			"name":     structpb.NewStringValue("bob"),
+			"nickname": structpb.NewStringValue("bobby"),
+			"address": structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+				"city": structpb.NewStringValue("Rome"),
+				"zip":  structpb.NewStringValue("00100"),
+			}}),
+		}})
+		require.ErrorIs(t, err, ErrUndeclaredField)
+		require.Contains(t, err.Error(), "'address.zip', 'nickname'")
+
+		_, _, err = engine.InsertDocument(ctx, "admin", "people", &structpb.Struct{Fields: map[string]*structpb.Value{
+			"address": structpb.NewStringValue("Rome"),
+		}})
+		require.ErrorIs(t, err, ErrUndeclaredField)
+		require.Contains(t, err.Error(), "'address'")
+
+		_, _, err = engine.InsertDocument(ctx, "admin", "loose_people", &structpb.Struct{Fields: map[string]*structpb.Value{
+			"nickname": structpb.NewStringValue("bobby"),
+		}})
+		require.NoError(t, err)
+	})
+
+	t.Run("undeclared fields should be rejected on replacement", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: "people",
+			Expressions: []*protomodel.QueryExpression{{
+				FieldComparisons: []*protomodel.FieldComparison{
+					{Field: "name", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue("alice")},
+				},
+			}},
+		}
+
+		_, err := engine.ReplaceDocuments(ctx, "admin", query, &structpb.Struct{Fields: map[string]*structpb.Value{
+			"name":     structpb.NewStringValue("alice"),
+			"nickname": structpb.NewStringValue("ali"),
+		}})
+		require.ErrorIs(t, err, ErrUndeclaredField)
+
+		revisions, err := engine.ReplaceDocuments(ctx, "admin", query, &structpb.Struct{Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("alice"),
+			"age":  structpb.NewNumberValue(30),
+		}})
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+	})
+}
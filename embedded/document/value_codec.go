@@ -0,0 +1,185 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ValueCodec translates the value of a document field between the representation
+// used by clients and the one being stored
+type ValueCodec interface {
+	// Encode is applied to the field value before the document is stored,
+	// and to comparison values when the field is used in a query
+	Encode(value *structpb.Value) (*structpb.Value, error)
+	// Decode is applied to the stored field value when the document is read
+	Decode(value *structpb.Value) (*structpb.Value, error)
+}
+
+// ValueCodecRegistry holds the value codecs registered for the fields of each collection.
+// Fields without a registered codec are left untouched.
+type ValueCodecRegistry struct {
+	mutex  sync.RWMutex
+	codecs map[string]map[string]ValueCodec
+}
+
+func NewValueCodecRegistry() *ValueCodecRegistry {
+	return &ValueCodecRegistry{
+		codecs: make(map[string]map[string]ValueCodec),
+	}
+}
+
+// Register sets the codec of a collection field, nested fields are specified using their path e.g. "address.street"
+func (r *ValueCodecRegistry) Register(collectionName, fieldPath string, codec ValueCodec) error {
+	err := validateCollectionName(collectionName)
+	if err != nil {
+		return err
+	}
+
+	err = validateFieldName(fieldPath)
+	if err != nil {
+		return err
+	}
+
+	if codec == nil {
+		return fmt.Errorf("%w: nil codec", ErrIllegalArguments)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fieldCodecs, ok := r.codecs[collectionName]
+	if !ok {
+		fieldCodecs = make(map[string]ValueCodec)
+		r.codecs[collectionName] = fieldCodecs
+	}
+
+	fieldCodecs[fieldPath] = codec
+
+	return nil
+}
+
+// Unregister removes the codec of a collection field
+func (r *ValueCodecRegistry) Unregister(collectionName, fieldPath string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.codecs[collectionName], fieldPath)
+
+	if len(r.codecs[collectionName]) == 0 {
+		delete(r.codecs, collectionName)
+	}
+}
+
+func (r *ValueCodecRegistry) fieldCodecs(collectionName string) map[string]ValueCodec {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	fieldCodecs := make(map[string]ValueCodec, len(r.codecs[collectionName]))
+
+	for fieldPath, codec := range r.codecs[collectionName] {
+		fieldCodecs[fieldPath] = codec
+	}
+
+	return fieldCodecs
+}
+
+// encodeDocument returns a copy of the document with the values of the fields holding a codec encoded,
+// the same document is returned when no codec is registered for the collection
+func (r *ValueCodecRegistry) encodeDocument(collectionName string, doc *structpb.Struct) (*structpb.Struct, error) {
+	fieldCodecs := r.fieldCodecs(collectionName)
+	if len(fieldCodecs) == 0 {
+		return doc, nil
+	}
+
+	encodedDoc := proto.Clone(doc).(*structpb.Struct)
+
+	for fieldPath, codec := range fieldCodecs {
+		err := transformFieldValue(encodedDoc, fieldPath, codec.Encode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return encodedDoc, nil
+}
+
+// decodeDocument decodes in place the values of the fields holding a codec
+func (r *ValueCodecRegistry) decodeDocument(collectionName string, doc *structpb.Struct) error {
+	for fieldPath, codec := range r.fieldCodecs(collectionName) {
+		err := transformFieldValue(doc, fieldPath, codec.Decode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeValue encodes a value to be compared with the stored values of the field
+func (r *ValueCodecRegistry) encodeValue(collectionName, fieldPath string, value *structpb.Value) (*structpb.Value, error) {
+	codec, ok := r.fieldCodecs(collectionName)[fieldPath]
+	if !ok {
+		return value, nil
+	}
+
+	encodedValue, err := codec.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error encoding value of field '%s': %v", ErrValueCodecFailed, fieldPath, err)
+	}
+
+	return encodedValue, nil
+}
+
+func transformFieldValue(doc *structpb.Struct, fieldPath string, transform func(*structpb.Value) (*structpb.Value, error)) error {
+	nestedStruct := doc
+	nestedFields := strings.Split(fieldPath, documentFieldPathSeparator)
+
+	for i, field := range nestedFields {
+		if nestedStruct == nil {
+			return nil
+		}
+
+		rval, ok := nestedStruct.Fields[field]
+		if !ok {
+			return nil
+		}
+
+		if i < len(nestedFields)-1 {
+			nestedStruct = rval.GetStructValue()
+			continue
+		}
+
+		tval, err := transform(rval)
+		if err != nil {
+			return fmt.Errorf("%w: error transforming value of field '%s': %v", ErrValueCodecFailed, fieldPath, err)
+		}
+
+		nestedStruct.Fields[field] = tval
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package document
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/protomodel"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// protoBlobCodec stores struct values as base64 encoded protobuf blobs
+type protoBlobCodec struct{}
+
+func (c *protoBlobCodec) Encode(value *structpb.Value) (*structpb.Value, error) {
+	bs, err := proto.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return structpb.NewStringValue(base64.StdEncoding.EncodeToString(bs)), nil
+}
+
+func (c *protoBlobCodec) Decode(value *structpb.Value) (*structpb.Value, error) {
+	bs, err := base64.StdEncoding.DecodeString(value.GetStringValue())
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := &structpb.Value{}
+
+	err = proto.Unmarshal(bs, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+type failingCodec struct{}
+
+func (c *failingCodec) Encode(value *structpb.Value) (*structpb.Value, error) {
+	return nil, errors.New("encoding failure")
+}
+
+func (c *failingCodec) Decode(value *structpb.Value) (*structpb.Value, error) {
+	return nil, errors.New("decoding failure")
+}
+
+func TestValueCodecRegistry(t *testing.T) {
+	registry := NewValueCodecRegistry()
+
+	err := registry.Register("", "blob", &protoBlobCodec{})
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	err = registry.Register("mycollection", "", &protoBlobCodec{})
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	err = registry.Register("mycollection", "blob", nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	err = registry.Register("mycollection", "payload.blob", &protoBlobCodec{})
+	require.NoError(t, err)
+
+	doc, err := structpb.NewStruct(map[string]interface{}{
+		"name": "doc1",
+		"payload": map[string]interface{}{
+			"blob": map[string]interface{}{"amount": 10.0},
+		},
+	})
+	require.NoError(t, err)
+
+	encodedDoc, err := registry.encodeDocument("mycollection", doc)
+	require.NoError(t, err)
+	require.NotNil(t, encodedDoc.Fields["payload"].GetStructValue().Fields["blob"].GetStringValue())
+	require.Equal(t, "doc1", encodedDoc.Fields["name"].GetStringValue())
+
+	// the provided document is not modified
+	require.NotNil(t, doc.Fields["payload"].GetStructValue().Fields["blob"].GetStructValue())
+
+	sameDoc, err := registry.encodeDocument("othercollection", doc)
+	require.NoError(t, err)
+	require.Same(t, doc, sameDoc)
+
+	err = registry.decodeDocument("mycollection", encodedDoc)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(doc, encodedDoc))
+
+	registry.Unregister("mycollection", "payload.blob")
+
+	sameDoc, err = registry.encodeDocument("mycollection", doc)
+	require.NoError(t, err)
+	require.Same(t, doc, sameDoc)
+}
+
+func TestDocumentsWithValueCodecs(t *testing.T) {
+	ctx := context.Background()
+
+	registry := NewValueCodecRegistry()
+
+	err := registry.Register("mycollection", "blob", &protoBlobCodec{})
+	require.NoError(t, err)
+
+	st, err := store.Open(t.TempDir(), store.DefaultOptions().WithMultiIndexing(true))
+	require.NoError(t, err)
+	defer st.Close()
+
+	engine, err := NewEngine(st, DefaultOptions().WithPrefix(docPrefix).WithValueCodecs(registry))
+	require.NoError(t, err)
+
+	err = engine.CreateCollection(
+		ctx,
+		"admin",
+		"mycollection",
+		"",
+		[]*protomodel.Field{
+			{Name: "blob", Type: protomodel.FieldType_STRING},
+		},
+		[]*protomodel.Index{
+			{Fields: []string{"blob"}},
+		},
+	)
+	require.NoError(t, err)
+
+	blob := structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+		"amount": structpb.NewNumberValue(10),
+	}})
+
+	txID, docID, err := engine.InsertDocument(ctx, "admin", "mycollection", &structpb.Struct{Fields: map[string]*structpb.Value{
+		"blob":  blob,
+		"other": structpb.NewStringValue("untouched"),
+	}})
+	require.NoError(t, err)
+
+	t.Run("stored value should be encoded", func(t *testing.T) {
+		_, _, encodedDoc, err := engine.GetEncodedDocument(ctx, "mycollection", docID, txID)
+		require.NoError(t, err)
+		require.NotContains(t, string(encodedDoc.EncodedDocument), "amount")
+	})
+
+	t.Run("searched documents should be decoded", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: "mycollection",
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "blob", Operator: protomodel.ComparisonOperator_EQ, Value: blob},
+					},
+				},
+			},
+		}
+
+		reader, err := engine.GetDocuments(ctx, query, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		revision, err := reader.Read(ctx)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(blob, revision.Document.Fields["blob"]))
+		require.Equal(t, "untouched", revision.Document.Fields["other"].GetStringValue())
+
+		_, err = reader.Read(ctx)
+		require.ErrorIs(t, err, ErrNoMoreDocuments)
+	})
+
+	t.Run("audited documents should be decoded", func(t *testing.T) {
+		revisions, err := engine.AuditDocument(ctx, "mycollection", docID, false, 0, 10, true)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		require.True(t, proto.Equal(blob, revisions[0].Document.Fields["blob"]))
+	})
+
+	t.Run("codec failures should be reported", func(t *testing.T) {
+		err := registry.Register("mycollection", "other", &failingCodec{})
+		require.NoError(t, err)
+		defer registry.Unregister("mycollection", "other")
+
+		_, _, err = engine.InsertDocument(ctx, "admin", "mycollection", &structpb.Struct{Fields: map[string]*structpb.Value{
+			"other": structpb.NewStringValue("value"),
+		}})
+		require.ErrorIs(t, err, ErrValueCodecFailed)
+
+		reader, err := engine.GetDocuments(ctx, &protomodel.Query{CollectionName: "mycollection"}, 0)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		_, err = reader.ReadN(ctx, 1)
+		require.ErrorIs(t, err, ErrValueCodecFailed)
+	})
+}
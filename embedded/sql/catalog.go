@@ -59,6 +59,7 @@ type Table struct {
 	indexesByName    map[string]*Index
 	indexesByColID   map[uint32][]*Index
 	checkConstraints map[string]CheckConstraint
+	properties       map[string]string
 	primaryIndex     *Index
 	autoIncrementPK  bool
 	maxPK            int64
@@ -245,6 +246,12 @@ func (t *Table) GetMaxColID() uint32 {
 	return t.maxColID
 }
 
+// Property returns the value of a table property and whether it is set
+func (t *Table) Property(name string) (string, bool) {
+	value, ok := t.properties[name]
+	return value, ok
+}
+
 func (i *Index) IsPrimary() bool {
 	return i.id == PKIndexID
 }
@@ -267,10 +274,28 @@ func (i *Index) enginePrefix() []byte {
 }
 
 func (i *Index) coversOrdCols(ordCols []*OrdCol, rangesByColID map[uint32]*typedValueRange) bool {
+	return i.coversOrdColsUsing(i.sortingCols(), ordCols, rangesByColID)
+}
+
+// coversOrdColsUsing returns true if scanning the index, whose entries are sorted by the given columns, yields
+// the entries sorted as required by ordCols
+func (i *Index) coversOrdColsUsing(sortingCols []*Column, ordCols []*OrdCol, rangesByColID map[uint32]*typedValueRange) bool {
 	if !ordColumnsHaveSameDirection(ordCols) {
 		return false
 	}
-	return i.hasPrefix(i.cols, ordCols) || i.sortableUsing(ordCols, rangesByColID)
+	return i.hasPrefix(sortingCols, ordCols) || i.sortableUsing(sortingCols, ordCols, rangesByColID)
+}
+
+// sortingCols returns the columns entries are sorted by when scanning the index. Entries of secondary
+// indexes are suffixed with the primary key, thus entries sharing the same indexed values are sorted by it.
+func (i *Index) sortingCols() []*Column {
+	if i.IsPrimary() {
+		return i.cols
+	}
+
+	cols := make([]*Column, 0, len(i.cols)+len(i.table.primaryIndex.cols))
+	cols = append(cols, i.cols...)
+	return append(cols, i.table.primaryIndex.cols...)
 }
 
 func ordColumnsHaveSameDirection(cols []*OrdCol) bool {
@@ -306,7 +331,7 @@ func (i *Index) hasPrefix(columns []*Column, ordCols []*OrdCol) bool {
 	return true
 }
 
-func (i *Index) sortableUsing(columns []*OrdCol, rangesByColID map[uint32]*typedValueRange) bool {
+func (i *Index) sortableUsing(sortingCols []*Column, columns []*OrdCol, rangesByColID map[uint32]*typedValueRange) bool {
 	// all columns before colID must be fixedValues otherwise the index can not be used
 	aggFn, _, colName := columns[0].sel.resolve(i.table.Name())
 	if len(aggFn) > 0 {
@@ -318,9 +343,9 @@ func (i *Index) sortableUsing(columns []*OrdCol, rangesByColID map[uint32]*typed
 		return false
 	}
 
-	for j, col := range i.cols {
+	for j, col := range sortingCols {
 		if col.id == firstCol.id {
-			return i.hasPrefix(i.cols[j:], columns)
+			return i.hasPrefix(sortingCols[j:], columns)
 		}
 
 		colRange, ok := rangesByColID[col.id]
@@ -406,6 +431,7 @@ func (catlg *Catalog) newTable(name string, colsSpec map[uint32]*ColSpec, checkC
 		indexesByName:    make(map[string]*Index),
 		indexesByColID:   make(map[uint32][]*Index),
 		checkConstraints: checkConstraints,
+		properties:       make(map[string]string),
 		maxColID:         maxColID,
 	}
 
@@ -780,6 +806,11 @@ func (catlg *Catalog) loadCatalog(ctx context.Context, tx *store.OngoingTx, copy
 			return ErrCorruptedData
 		}
 
+		err = table.loadProperties(ctx, catlg.enginePrefix, tx, copyToTx)
+		if err != nil {
+			return err
+		}
+
 		if copyToTx {
 			if err := tx.Set(key, nil, value); err != nil {
 				return err
@@ -884,6 +915,32 @@ func loadCheckConstraints(ctx context.Context, dbID, tableID uint32, tx *store.O
 	return checks, err
 }
 
+func (table *Table) loadProperties(ctx context.Context, sqlPrefix []byte, tx *store.OngoingTx, copyToTx bool) error {
+	prefix := MapKey(sqlPrefix, catalogPropertyPrefix, EncodeID(DatabaseID), EncodeID(table.id))
+
+	return iteratePrefix(ctx, tx, prefix, func(key, value []byte, deleted bool) error {
+		if deleted {
+			return nil
+		}
+
+		name, err := trimPrefix(prefix, key, nil)
+		if err != nil {
+			return err
+		}
+
+		if len(name) == 0 {
+			return ErrCorruptedData
+		}
+
+		table.properties[string(name)] = string(value)
+
+		if copyToTx {
+			return tx.Set(key, nil, value)
+		}
+		return nil
+	})
+}
+
 func (table *Table) loadIndexes(ctx context.Context, sqlPrefix []byte, tx *store.OngoingTx, copyToTx bool) error {
 	prefix := MapKey(sqlPrefix, catalogIndexPrefix, EncodeID(1), EncodeID(table.id))
 
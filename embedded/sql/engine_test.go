@@ -3597,6 +3597,24 @@ func TestIndexSelection(t *testing.T) {
 			expectedIndex: []string{"v3", "v4"},
 			desc:          true,
 		},
+		{
+			query:         "SELECT * FROM table1 ORDER BY v3 DESC, v4 DESC, v0 DESC, v1 DESC",
+			expectedIndex: []string{"v3", "v4"},
+			desc:          true,
+		},
+		{
+			query:         "SELECT * FROM table1 WHERE v3 = 0 AND v4 = 1 ORDER BY v0, v1",
+			expectedIndex: []string{"v0", "v1"},
+		},
+		{
+			query:         "SELECT * FROM table1 USE INDEX ON (v3, v4) WHERE v3 = 0 AND v4 = 1 ORDER BY v0, v1",
+			expectedIndex: []string{"v3", "v4"},
+		},
+		{
+			query:                   "SELECT * FROM table1 ORDER BY v3, v4, v1",
+			expectedIndex:           []string{"v0", "v1"},
+			expectedOrderBySortCols: []string{EncodeSelector("", "table1", "v3"), EncodeSelector("", "table1", "v4"), EncodeSelector("", "table1", "v1")},
+		},
 		{
 			query:                   "SELECT * FROM table1 ORDER BY v3 DESC, v4 ASC",
 			expectedIndex:           []string{"v0", "v1"},
@@ -9152,3 +9170,55 @@ func TestFunctions(t *testing.T) {
 		require.Equal(t, "OBJECT", rows[0].ValuesByPosition[0].RawValue().(string))
 	})
 }
+
+func TestTableProperties(t *testing.T) {
+	engine := setupCommonTest(t)
+
+	_, _, err := engine.Exec(context.Background(), nil, "CREATE TABLE mytable(id INTEGER, title VARCHAR, PRIMARY KEY id)", nil)
+	require.NoError(t, err)
+
+	getProperty := func(name string) (string, bool) {
+		catalog, err := engine.Catalog(context.Background(), nil)
+		require.NoError(t, err)
+
+		table, err := catalog.GetTableByName("mytable")
+		require.NoError(t, err)
+
+		return table.Property(name)
+	}
+
+	_, _, err = engine.ExecPreparedStmts(context.Background(), nil, []SQLStmt{NewSetTablePropertyStmt("mytable", "", "value")}, nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	_, _, err = engine.ExecPreparedStmts(context.Background(), nil, []SQLStmt{NewSetTablePropertyStmt("unknown", "prop", "value")}, nil)
+	require.ErrorIs(t, err, ErrTableDoesNotExist)
+
+	_, _, err = engine.ExecPreparedStmts(context.Background(), nil, []SQLStmt{
+		NewSetTablePropertyStmt("mytable", "prop1", "value1"),
+		NewSetTablePropertyStmt("mytable", "prop2", "value2"),
+	}, nil)
+	require.NoError(t, err)
+
+	value, ok := getProperty("prop1")
+	require.True(t, ok)
+	require.Equal(t, "value1", value)
+
+	_, _, err = engine.ExecPreparedStmts(context.Background(), nil, []SQLStmt{NewSetTablePropertyStmt("mytable", "prop1", "")}, nil)
+	require.NoError(t, err)
+
+	_, ok = getProperty("prop1")
+	require.False(t, ok)
+
+	value, ok = getProperty("prop2")
+	require.True(t, ok)
+	require.Equal(t, "value2", value)
+
+	_, _, err = engine.Exec(context.Background(), nil, "DROP TABLE mytable", nil)
+	require.NoError(t, err)
+
+	_, _, err = engine.Exec(context.Background(), nil, "CREATE TABLE mytable(id INTEGER, PRIMARY KEY id)", nil)
+	require.NoError(t, err)
+
+	_, ok = getProperty("prop2")
+	require.False(t, ok)
+}
@@ -51,6 +51,27 @@ type ScanSpecs struct {
 	orderBySortCols    []*OrdCol
 }
 
+// RangeScanCols returns the number of leading index columns whose values are bounded
+// by the query conditions, the whole index is scanned when zero
+func (s *ScanSpecs) RangeScanCols() int {
+	if s.Index == nil {
+		return 0
+	}
+
+	n := 0
+
+	for _, col := range s.Index.cols {
+		colRange, ok := s.rangesByColID[col.id]
+		if !ok || (colRange.lRange == nil && colRange.hRange == nil) {
+			break
+		}
+
+		n++
+	}
+
+	return n
+}
+
 func (s *ScanSpecs) extraCols() int {
 	n := 0
 	if s.IncludeHistory {
@@ -456,7 +477,7 @@ func (r *rawRowReader) Read(ctx context.Context) (*Row, error) {
 		return nil, err
 	}
 
-	v, err := vref.Resolve()
+	v, err := vref.ResolveContext(ctx)
 	if err != nil {
 		return nil, err
 	}
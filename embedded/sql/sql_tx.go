@@ -108,6 +108,11 @@ func (sqlTx *SQLTx) set(key []byte, metadata *store.KVMetadata, value []byte) er
 	return sqlTx.tx.Set(key, metadata, value)
 }
 
+// Set adds the entry to the transaction as is, the key must not collide with the ones managed by the engine
+func (sqlTx *SQLTx) Set(key []byte, metadata *store.KVMetadata, value []byte) error {
+	return sqlTx.set(key, metadata, value)
+}
+
 func (sqlTx *SQLTx) setTransient(key []byte, metadata *store.KVMetadata, value []byte) error {
 	return sqlTx.tx.SetTransient(key, metadata, value)
 }
@@ -146,6 +151,11 @@ func (sqlTx *SQLTx) Commit(ctx context.Context) error {
 	return merr.Reduce()
 }
 
+// AddPrecondition adds a precondition checked by the store when the transaction is committed
+func (sqlTx *SQLTx) AddPrecondition(c store.Precondition) error {
+	return sqlTx.tx.AddPrecondition(c)
+}
+
 func (sqlTx *SQLTx) Closed() bool {
 	return sqlTx.tx.Closed()
 }
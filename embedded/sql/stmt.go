@@ -39,6 +39,7 @@ const (
 	catalogColumnPrefix    = "CTL.COLUMN."    // (key=CTL.COLUMN.{1}{tableID}{colID}{colTYPE}, value={(auto_incremental | nullable){maxLen}{colNAME}})
 	catalogIndexPrefix     = "CTL.INDEX."     // (key=CTL.INDEX.{1}{tableID}{indexID}, value={unique {colID1}(ASC|DESC)...{colIDN}(ASC|DESC)})
 	catalogCheckPrefix     = "CTL.CHECK."     // (key=CTL.CHECK.{1}{tableID}{checkID}, value={nameLen}{name}{expText})
+	catalogPropertyPrefix  = "CTL.PROPERTY."  // (key=CTL.PROPERTY.{1}{tableID}{propertyNAME}, value={propertyVALUE})
 	catalogPrivilegePrefix = "CTL.PRIVILEGE." // (key=CTL.COLUMN.{1}{tableID}{colID}{colTYPE}, value={(auto_incremental | nullable){maxLen}{colNAME}})
 
 	RowPrefix    = "R." // (key=R.{1}{tableID}{0}({null}({pkVal}{padding}{pkValLen})?)+, value={count (colID valLen val)+})
@@ -882,6 +883,74 @@ func (stmt *RenameColumnStmt) execAt(ctx context.Context, tx *SQLTx, params map[
 	return tx, nil
 }
 
+// SetTablePropertyStmt sets a property of a table, properties are not interpreted by the engine
+// but persisted as part of the catalog. An empty value removes the property.
+type SetTablePropertyStmt struct {
+	table string
+	name  string
+	value string
+}
+
+func NewSetTablePropertyStmt(table, name, value string) *SetTablePropertyStmt {
+	return &SetTablePropertyStmt{table: table, name: name, value: value}
+}
+
+func (stmt *SetTablePropertyStmt) readOnly() bool {
+	return false
+}
+
+func (stmt *SetTablePropertyStmt) requiredPrivileges() []SQLPrivilege {
+	return []SQLPrivilege{SQLPrivilegeAlter}
+}
+
+func (stmt *SetTablePropertyStmt) inferParameters(ctx context.Context, tx *SQLTx, params map[string]SQLValueType) error {
+	return nil
+}
+
+func (stmt *SetTablePropertyStmt) execAt(ctx context.Context, tx *SQLTx, params map[string]interface{}) (*SQLTx, error) {
+	if stmt.name == "" {
+		return nil, fmt.Errorf("%w: empty property name", ErrIllegalArguments)
+	}
+
+	table, err := tx.catalog.GetTableByName(stmt.table)
+	if err != nil {
+		return nil, err
+	}
+
+	mappedKey := MapKey(
+		tx.sqlPrefix(),
+		catalogPropertyPrefix,
+		EncodeID(DatabaseID),
+		EncodeID(table.id),
+		[]byte(stmt.name),
+	)
+
+	if stmt.value == "" {
+		_, exists := table.properties[stmt.name]
+		if !exists {
+			return tx, nil
+		}
+
+		err = tx.delete(ctx, mappedKey)
+		if err != nil {
+			return nil, err
+		}
+
+		delete(table.properties, stmt.name)
+	} else {
+		err = tx.set(mappedKey, nil, []byte(stmt.value))
+		if err != nil {
+			return nil, err
+		}
+
+		table.properties[stmt.name] = stmt.value
+	}
+
+	tx.mutatedCatalog = true
+
+	return tx, nil
+}
+
 type DropColumnStmt struct {
 	table   string
 	colName string
@@ -1776,6 +1845,12 @@ func NewDeleteFromStmt(table string, where ValueExp, orderBy []*OrdCol, limit Va
 	}
 }
 
+// WithIndexOn makes the statement scan the index over the given columns, as USE INDEX ON does
+func (stmt *DeleteFromStmt) WithIndexOn(cols []string) *DeleteFromStmt {
+	stmt.indexOn = cols
+	return stmt
+}
+
 func (stmt *DeleteFromStmt) readOnly() bool {
 	return false
 }
@@ -2996,6 +3071,12 @@ func NewSelectStmt(
 	}
 }
 
+// WithIndexOn makes the statement scan the index over the given columns, as USE INDEX ON does
+func (stmt *SelectStmt) WithIndexOn(cols []string) *SelectStmt {
+	stmt.indexOn = cols
+	return stmt
+}
+
 func (stmt *SelectStmt) readOnly() bool {
 	return true
 }
@@ -3391,6 +3472,13 @@ func (stmt *SelectStmt) selectSortingIndex(groupByCols, orderByCols []*OrdCol, t
 		return nil
 	}
 
+	for _, idx := range table.indexes {
+		if idx.coversOrdColsUsing(idx.cols, sortCols, rangesByColId) {
+			return idx
+		}
+	}
+
+	// entries of secondary indexes sharing the same indexed values are sorted by primary key
 	for _, idx := range table.indexes {
 		if idx.coversOrdCols(sortCols, rangesByColId) {
 			return idx
@@ -5480,6 +5568,21 @@ func (stmt *DropTableStmt) execAt(ctx context.Context, tx *SQLTx, params map[str
 		}
 	}
 
+	// delete properties
+	for name := range table.properties {
+		key := MapKey(
+			tx.sqlPrefix(),
+			catalogPropertyPrefix,
+			EncodeID(DatabaseID),
+			EncodeID(table.id),
+			[]byte(name),
+		)
+
+		if err := tx.delete(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
 	// delete indexes
 	for _, index := range table.indexes {
 		mappedKey := MapKey(
@@ -148,6 +148,7 @@ type ImmuStore struct {
 	path string
 
 	logger           logger.Logger
+	tracer           Tracer
 	lastNotification time.Time
 	notifyMutex      sync.Mutex
 
@@ -611,6 +612,7 @@ func OpenWith(path string, vLogs []appendable.Appendable, txLog, cLog appendable
 	store := &ImmuStore{
 		path:             path,
 		logger:           opts.logger,
+		tracer:           opts.tracer,
 		txLog:            txLog,
 		txLogCache:       txLogCache,
 		vLogs:            vLogsMap,
@@ -1246,6 +1248,9 @@ func (s *ImmuStore) WaitForTx(ctx context.Context, txID uint64, allowPrecommitte
 }
 
 func (s *ImmuStore) WaitForIndexingUpto(ctx context.Context, txID uint64) error {
+	ctx, span := s.startSpan(ctx, SpanWaitForIndexing)
+	defer span.End()
+
 	s.waiteesMutex.Lock()
 
 	if s.waiteesCount == s.maxWaitees {
@@ -1273,6 +1278,21 @@ func (s *ImmuStore) WaitForIndexingUpto(ctx context.Context, txID uint64) error
 	return nil
 }
 
+// IsIndexedUpto returns true if every index already includes the transaction with the given id,
+// unlike WaitForIndexingUpto it does not wait for indexing to progress
+func (s *ImmuStore) IsIndexedUpto(txID uint64) bool {
+	s.indexersMux.RLock()
+	defer s.indexersMux.RUnlock()
+
+	for _, indexer := range s.indexers {
+		if indexer.Ts() < txID {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (s *ImmuStore) CompactIndexes() error {
 	if s.compactionDisabled {
 		return ErrCompactionDisabled
@@ -1515,6 +1535,9 @@ func (s *ImmuStore) NewTx(ctx context.Context, opts *TxOptions) (*OngoingTx, err
 }
 
 func (s *ImmuStore) commit(ctx context.Context, otx *OngoingTx, expectedHeader *TxHeader, skipIntegrityCheck bool, waitForIndexing bool) (*TxHeader, error) {
+	ctx, span := s.startSpan(ctx, SpanCommit)
+	defer span.End()
+
 	hdr, err := s.precommit(ctx, otx, expectedHeader, skipIntegrityCheck)
 	if err != nil {
 		return nil, err
@@ -3387,6 +3387,48 @@ func TestImmudbPreconditionIndexing(t *testing.T) {
 	})
 }
 
+func TestImmudbStoreIsIndexedUpto(t *testing.T) {
+	immuStore, err := Open(t.TempDir(), DefaultOptions())
+	require.NoError(t, err)
+
+	defer immuStore.Close()
+
+	require.True(t, immuStore.IsIndexedUpto(0))
+
+	tx, err := immuStore.NewWriteOnlyTx(context.Background())
+	require.NoError(t, err)
+
+	err = tx.Set([]byte("key1"), nil, []byte("value1"))
+	require.NoError(t, err)
+
+	hdr1, err := tx.Commit(context.Background())
+	require.NoError(t, err)
+	require.True(t, immuStore.IsIndexedUpto(hdr1.ID))
+
+	indexer, err := immuStore.getIndexerFor(nil)
+	require.NoError(t, err)
+
+	indexer.Pause()
+
+	tx, err = immuStore.NewWriteOnlyTx(context.Background())
+	require.NoError(t, err)
+
+	err = tx.Set([]byte("key2"), nil, []byte("value2"))
+	require.NoError(t, err)
+
+	hdr2, err := tx.AsyncCommit(context.Background())
+	require.NoError(t, err)
+
+	require.True(t, immuStore.IsIndexedUpto(hdr1.ID))
+	require.False(t, immuStore.IsIndexedUpto(hdr2.ID))
+
+	indexer.Resume()
+
+	err = immuStore.WaitForIndexingUpto(context.Background(), hdr2.ID)
+	require.NoError(t, err)
+	require.True(t, immuStore.IsIndexedUpto(hdr2.ID))
+}
+
 func TestTimeBasedTxLookup(t *testing.T) {
 	immuStore, err := Open(t.TempDir(), DefaultOptions())
 	require.NoError(t, err)
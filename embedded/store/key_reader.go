@@ -238,6 +238,8 @@ func (s *Snapshot) NewKeyReader(spec KeyReaderSpec) (KeyReader, error) {
 
 type ValueRef interface {
 	Resolve() (val []byte, err error)
+	// ResolveContext resolves the value as Resolve does, the context is used to trace the read
+	ResolveContext(ctx context.Context) (val []byte, err error)
 	Tx() uint64
 	HC() uint64
 	TxMetadata() *TxMetadata
@@ -364,6 +366,14 @@ func (v *valueRef) Resolve() (val []byte, err error) {
 	return refVal, nil
 }
 
+// ResolveContext resolves the value as Resolve does, the read is traced as a child of the span of the context
+func (v *valueRef) ResolveContext(ctx context.Context) (val []byte, err error) {
+	_, span := v.st.startSpan(ctx, SpanReadValue)
+	defer span.End()
+
+	return v.Resolve()
+}
+
 func (v *valueRef) Tx() uint64 {
 	return v.tx
 }
@@ -140,6 +140,10 @@ func (oref *ongoingValRef) Resolve() (val []byte, err error) {
 	return oref.value, nil
 }
 
+func (oref *ongoingValRef) ResolveContext(ctx context.Context) (val []byte, err error) {
+	return oref.value, nil
+}
+
 func (oref *ongoingValRef) Tx() uint64 {
 	return 0
 }
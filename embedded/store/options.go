@@ -84,6 +84,9 @@ type Options struct {
 
 	logger logger.Logger
 
+	// tracer creating the spans of store operations, no spans are created when not set
+	tracer Tracer
+
 	appFactory AppFactoryFunc
 
 	appRemove AppRemoveFunc
@@ -458,6 +461,12 @@ func (opts *Options) WithLogger(logger logger.Logger) *Options {
 	return opts
 }
 
+// WithTracer sets the tracer creating the spans of commits, index waits and value reads
+func (opts *Options) WithTracer(tracer Tracer) *Options {
+	opts.tracer = tracer
+	return opts
+}
+
 func (opts *Options) WithAppFactory(appFactory AppFactoryFunc) *Options {
 	opts.appFactory = appFactory
 	return opts
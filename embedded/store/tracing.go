@@ -0,0 +1,51 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import "context"
+
+// Names of the spans created by the store
+const (
+	SpanCommit          = "store.Commit"
+	SpanWaitForIndexing = "store.WaitForIndexing"
+	SpanReadValue       = "store.ReadValue"
+)
+
+// Tracer creates the spans of store operations. It's meant to be backed by a tracing library,
+// e.g. OpenTelemetry, so store operations are recorded as children of the spans of the context.
+type Tracer interface {
+	// Start creates a span as a child of the one in the context, if any, and returns the context holding it
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Span is ended once the traced operation completes
+type Span interface {
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End() {}
+
+// startSpan creates a span when a tracer is configured, no allocations are made otherwise
+func (s *ImmuStore) startSpan(ctx context.Context, spanName string) (context.Context, Span) {
+	if s.tracer == nil || ctx == nil {
+		return ctx, noopSpan{}
+	}
+
+	return s.tracer.Start(ctx, spanName)
+}
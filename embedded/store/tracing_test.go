@@ -0,0 +1,125 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+type recordedSpan struct {
+	name   string
+	parent string
+	ended  bool
+}
+
+// memTracer records the spans in memory, parent spans are tracked through the context
+type memTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *memTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	span := &recordedSpan{name: spanName}
+
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.parent = parent.name
+	}
+
+	t.spans = append(t.spans, span)
+
+	return context.WithValue(ctx, spanKey{}, span), &memSpan{tracer: t, span: span}
+}
+
+func (t *memTracer) childrenOf(parent string) map[string]bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	children := make(map[string]bool)
+
+	for _, span := range t.spans {
+		if span.parent == parent {
+			children[span.name] = span.ended
+		}
+	}
+
+	return children
+}
+
+type memSpan struct {
+	tracer *memTracer
+	span   *recordedSpan
+}
+
+func (s *memSpan) End() {
+	s.tracer.mutex.Lock()
+	defer s.tracer.mutex.Unlock()
+
+	s.span.ended = true
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &memTracer{}
+
+	st, err := Open(t.TempDir(), DefaultOptions().WithEmbeddedValues(false).WithTracer(tracer))
+	require.NoError(t, err)
+	defer immustoreClose(t, st)
+
+	requestCtx, requestSpan := tracer.Start(context.Background(), "request")
+
+	tx, err := st.NewWriteOnlyTx(requestCtx)
+	require.NoError(t, err)
+
+	err = tx.Set([]byte("key"), nil, []byte("value"))
+	require.NoError(t, err)
+
+	_, err = tx.Commit(requestCtx)
+	require.NoError(t, err)
+
+	valRef, err := st.Get(requestCtx, []byte("key"))
+	require.NoError(t, err)
+
+	val, err := valRef.ResolveContext(requestCtx)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), val)
+
+	requestSpan.End()
+
+	children := tracer.childrenOf("request")
+	require.Equal(t, map[string]bool{SpanCommit: true, SpanReadValue: true}, children)
+
+	// waiting for indexing is done as part of the commit
+	require.Equal(t, map[string]bool{SpanWaitForIndexing: true}, tracer.childrenOf(SpanCommit))
+}
+
+func TestTracingDisabled(t *testing.T) {
+	st, err := Open(t.TempDir(), DefaultOptions())
+	require.NoError(t, err)
+	defer immustoreClose(t, st)
+
+	ctx, span := st.startSpan(context.Background(), SpanCommit)
+	require.Equal(t, context.Background(), ctx)
+	require.Equal(t, noopSpan{}, span)
+}
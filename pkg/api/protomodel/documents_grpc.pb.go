@@ -23,6 +23,8 @@ type DocumentServiceClient interface {
 	GetCollection(ctx context.Context, in *GetCollectionRequest, opts ...grpc.CallOption) (*GetCollectionResponse, error)
 	UpdateCollection(ctx context.Context, in *UpdateCollectionRequest, opts ...grpc.CallOption) (*UpdateCollectionResponse, error)
 	DeleteCollection(ctx context.Context, in *DeleteCollectionRequest, opts ...grpc.CallOption) (*DeleteCollectionResponse, error)
+	RestoreCollection(ctx context.Context, in *RestoreCollectionRequest, opts ...grpc.CallOption) (*RestoreCollectionResponse, error)
+	PurgeCollections(ctx context.Context, in *PurgeCollectionsRequest, opts ...grpc.CallOption) (*PurgeCollectionsResponse, error)
 	AddField(ctx context.Context, in *AddFieldRequest, opts ...grpc.CallOption) (*AddFieldResponse, error)
 	RemoveField(ctx context.Context, in *RemoveFieldRequest, opts ...grpc.CallOption) (*RemoveFieldResponse, error)
 	CreateIndex(ctx context.Context, in *CreateIndexRequest, opts ...grpc.CallOption) (*CreateIndexResponse, error)
@@ -30,10 +32,14 @@ type DocumentServiceClient interface {
 	InsertDocuments(ctx context.Context, in *InsertDocumentsRequest, opts ...grpc.CallOption) (*InsertDocumentsResponse, error)
 	ReplaceDocuments(ctx context.Context, in *ReplaceDocumentsRequest, opts ...grpc.CallOption) (*ReplaceDocumentsResponse, error)
 	DeleteDocuments(ctx context.Context, in *DeleteDocumentsRequest, opts ...grpc.CallOption) (*DeleteDocumentsResponse, error)
+	DeleteDocument(ctx context.Context, in *DeleteDocumentRequest, opts ...grpc.CallOption) (*DeleteDocumentResponse, error)
+	UpdateDocument(ctx context.Context, in *UpdateDocumentRequest, opts ...grpc.CallOption) (*UpdateDocumentResponse, error)
 	SearchDocuments(ctx context.Context, in *SearchDocumentsRequest, opts ...grpc.CallOption) (*SearchDocumentsResponse, error)
 	CountDocuments(ctx context.Context, in *CountDocumentsRequest, opts ...grpc.CallOption) (*CountDocumentsResponse, error)
 	AuditDocument(ctx context.Context, in *AuditDocumentRequest, opts ...grpc.CallOption) (*AuditDocumentResponse, error)
 	ProofDocument(ctx context.Context, in *ProofDocumentRequest, opts ...grpc.CallOption) (*ProofDocumentResponse, error)
+	UploadDocument(ctx context.Context, opts ...grpc.CallOption) (DocumentService_UploadDocumentClient, error)
+	CollectionWatch(ctx context.Context, in *CollectionWatchRequest, opts ...grpc.CallOption) (DocumentService_CollectionWatchClient, error)
 }
 
 type documentServiceClient struct {
@@ -89,6 +95,24 @@ func (c *documentServiceClient) DeleteCollection(ctx context.Context, in *Delete
 	return out, nil
 }
 
+func (c *documentServiceClient) RestoreCollection(ctx context.Context, in *RestoreCollectionRequest, opts ...grpc.CallOption) (*RestoreCollectionResponse, error) {
+	out := new(RestoreCollectionResponse)
+	err := c.cc.Invoke(ctx, "/immudb.model.DocumentService/RestoreCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *documentServiceClient) PurgeCollections(ctx context.Context, in *PurgeCollectionsRequest, opts ...grpc.CallOption) (*PurgeCollectionsResponse, error) {
+	out := new(PurgeCollectionsResponse)
+	err := c.cc.Invoke(ctx, "/immudb.model.DocumentService/PurgeCollections", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *documentServiceClient) AddField(ctx context.Context, in *AddFieldRequest, opts ...grpc.CallOption) (*AddFieldResponse, error) {
 	out := new(AddFieldResponse)
 	err := c.cc.Invoke(ctx, "/immudb.model.DocumentService/AddField", in, out, opts...)
@@ -152,6 +176,24 @@ func (c *documentServiceClient) DeleteDocuments(ctx context.Context, in *DeleteD
 	return out, nil
 }
 
+func (c *documentServiceClient) DeleteDocument(ctx context.Context, in *DeleteDocumentRequest, opts ...grpc.CallOption) (*DeleteDocumentResponse, error) {
+	out := new(DeleteDocumentResponse)
+	err := c.cc.Invoke(ctx, "/immudb.model.DocumentService/DeleteDocument", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *documentServiceClient) UpdateDocument(ctx context.Context, in *UpdateDocumentRequest, opts ...grpc.CallOption) (*UpdateDocumentResponse, error) {
+	out := new(UpdateDocumentResponse)
+	err := c.cc.Invoke(ctx, "/immudb.model.DocumentService/UpdateDocument", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *documentServiceClient) SearchDocuments(ctx context.Context, in *SearchDocumentsRequest, opts ...grpc.CallOption) (*SearchDocumentsResponse, error) {
 	out := new(SearchDocumentsResponse)
 	err := c.cc.Invoke(ctx, "/immudb.model.DocumentService/SearchDocuments", in, out, opts...)
@@ -188,6 +230,72 @@ func (c *documentServiceClient) ProofDocument(ctx context.Context, in *ProofDocu
 	return out, nil
 }
 
+func (c *documentServiceClient) UploadDocument(ctx context.Context, opts ...grpc.CallOption) (DocumentService_UploadDocumentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DocumentService_ServiceDesc.Streams[0], "/immudb.model.DocumentService/UploadDocument", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &documentServiceUploadDocumentClient{stream}
+	return x, nil
+}
+
+type DocumentService_UploadDocumentClient interface {
+	Send(*UploadDocumentChunk) error
+	CloseAndRecv() (*UploadDocumentResponse, error)
+	grpc.ClientStream
+}
+
+type documentServiceUploadDocumentClient struct {
+	grpc.ClientStream
+}
+
+func (x *documentServiceUploadDocumentClient) Send(m *UploadDocumentChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *documentServiceUploadDocumentClient) CloseAndRecv() (*UploadDocumentResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadDocumentResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *documentServiceClient) CollectionWatch(ctx context.Context, in *CollectionWatchRequest, opts ...grpc.CallOption) (DocumentService_CollectionWatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DocumentService_ServiceDesc.Streams[1], "/immudb.model.DocumentService/CollectionWatch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &documentServiceCollectionWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DocumentService_CollectionWatchClient interface {
+	Recv() (*CollectionWatchResponse, error)
+	grpc.ClientStream
+}
+
+type documentServiceCollectionWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *documentServiceCollectionWatchClient) Recv() (*CollectionWatchResponse, error) {
+	m := new(CollectionWatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DocumentServiceServer is the server API for DocumentService service.
 // All implementations should embed UnimplementedDocumentServiceServer
 // for forward compatibility
@@ -197,6 +305,8 @@ type DocumentServiceServer interface {
 	GetCollection(context.Context, *GetCollectionRequest) (*GetCollectionResponse, error)
 	UpdateCollection(context.Context, *UpdateCollectionRequest) (*UpdateCollectionResponse, error)
 	DeleteCollection(context.Context, *DeleteCollectionRequest) (*DeleteCollectionResponse, error)
+	RestoreCollection(context.Context, *RestoreCollectionRequest) (*RestoreCollectionResponse, error)
+	PurgeCollections(context.Context, *PurgeCollectionsRequest) (*PurgeCollectionsResponse, error)
 	AddField(context.Context, *AddFieldRequest) (*AddFieldResponse, error)
 	RemoveField(context.Context, *RemoveFieldRequest) (*RemoveFieldResponse, error)
 	CreateIndex(context.Context, *CreateIndexRequest) (*CreateIndexResponse, error)
@@ -204,10 +314,14 @@ type DocumentServiceServer interface {
 	InsertDocuments(context.Context, *InsertDocumentsRequest) (*InsertDocumentsResponse, error)
 	ReplaceDocuments(context.Context, *ReplaceDocumentsRequest) (*ReplaceDocumentsResponse, error)
 	DeleteDocuments(context.Context, *DeleteDocumentsRequest) (*DeleteDocumentsResponse, error)
+	DeleteDocument(context.Context, *DeleteDocumentRequest) (*DeleteDocumentResponse, error)
+	UpdateDocument(context.Context, *UpdateDocumentRequest) (*UpdateDocumentResponse, error)
 	SearchDocuments(context.Context, *SearchDocumentsRequest) (*SearchDocumentsResponse, error)
 	CountDocuments(context.Context, *CountDocumentsRequest) (*CountDocumentsResponse, error)
 	AuditDocument(context.Context, *AuditDocumentRequest) (*AuditDocumentResponse, error)
 	ProofDocument(context.Context, *ProofDocumentRequest) (*ProofDocumentResponse, error)
+	UploadDocument(DocumentService_UploadDocumentServer) error
+	CollectionWatch(*CollectionWatchRequest, DocumentService_CollectionWatchServer) error
 }
 
 // UnimplementedDocumentServiceServer should be embedded to have forward compatible implementations.
@@ -229,6 +343,12 @@ func (UnimplementedDocumentServiceServer) UpdateCollection(context.Context, *Upd
 func (UnimplementedDocumentServiceServer) DeleteCollection(context.Context, *DeleteCollectionRequest) (*DeleteCollectionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteCollection not implemented")
 }
+func (UnimplementedDocumentServiceServer) RestoreCollection(context.Context, *RestoreCollectionRequest) (*RestoreCollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreCollection not implemented")
+}
+func (UnimplementedDocumentServiceServer) PurgeCollections(context.Context, *PurgeCollectionsRequest) (*PurgeCollectionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeCollections not implemented")
+}
 func (UnimplementedDocumentServiceServer) AddField(context.Context, *AddFieldRequest) (*AddFieldResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddField not implemented")
 }
@@ -250,6 +370,12 @@ func (UnimplementedDocumentServiceServer) ReplaceDocuments(context.Context, *Rep
 func (UnimplementedDocumentServiceServer) DeleteDocuments(context.Context, *DeleteDocumentsRequest) (*DeleteDocumentsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteDocuments not implemented")
 }
+func (UnimplementedDocumentServiceServer) DeleteDocument(context.Context, *DeleteDocumentRequest) (*DeleteDocumentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDocument not implemented")
+}
+func (UnimplementedDocumentServiceServer) UpdateDocument(context.Context, *UpdateDocumentRequest) (*UpdateDocumentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDocument not implemented")
+}
 func (UnimplementedDocumentServiceServer) SearchDocuments(context.Context, *SearchDocumentsRequest) (*SearchDocumentsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SearchDocuments not implemented")
 }
@@ -262,6 +388,12 @@ func (UnimplementedDocumentServiceServer) AuditDocument(context.Context, *AuditD
 func (UnimplementedDocumentServiceServer) ProofDocument(context.Context, *ProofDocumentRequest) (*ProofDocumentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ProofDocument not implemented")
 }
+func (UnimplementedDocumentServiceServer) UploadDocument(DocumentService_UploadDocumentServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadDocument not implemented")
+}
+func (UnimplementedDocumentServiceServer) CollectionWatch(*CollectionWatchRequest, DocumentService_CollectionWatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method CollectionWatch not implemented")
+}
 
 // UnsafeDocumentServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to DocumentServiceServer will
@@ -364,6 +496,42 @@ func _DocumentService_DeleteCollection_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DocumentService_RestoreCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocumentServiceServer).RestoreCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immudb.model.DocumentService/RestoreCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocumentServiceServer).RestoreCollection(ctx, req.(*RestoreCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocumentService_PurgeCollections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeCollectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocumentServiceServer).PurgeCollections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immudb.model.DocumentService/PurgeCollections",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocumentServiceServer).PurgeCollections(ctx, req.(*PurgeCollectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DocumentService_AddField_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AddFieldRequest)
 	if err := dec(in); err != nil {
@@ -490,6 +658,42 @@ func _DocumentService_DeleteDocuments_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DocumentService_DeleteDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocumentServiceServer).DeleteDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immudb.model.DocumentService/DeleteDocument",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocumentServiceServer).DeleteDocument(ctx, req.(*DeleteDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DocumentService_UpdateDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DocumentServiceServer).UpdateDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/immudb.model.DocumentService/UpdateDocument",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DocumentServiceServer).UpdateDocument(ctx, req.(*UpdateDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DocumentService_SearchDocuments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SearchDocumentsRequest)
 	if err := dec(in); err != nil {
@@ -562,6 +766,53 @@ func _DocumentService_ProofDocument_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DocumentService_UploadDocument_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DocumentServiceServer).UploadDocument(&documentServiceUploadDocumentServer{stream})
+}
+
+type DocumentService_UploadDocumentServer interface {
+	SendAndClose(*UploadDocumentResponse) error
+	Recv() (*UploadDocumentChunk, error)
+	grpc.ServerStream
+}
+
+type documentServiceUploadDocumentServer struct {
+	grpc.ServerStream
+}
+
+func (x *documentServiceUploadDocumentServer) SendAndClose(m *UploadDocumentResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *documentServiceUploadDocumentServer) Recv() (*UploadDocumentChunk, error) {
+	m := new(UploadDocumentChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _DocumentService_CollectionWatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CollectionWatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DocumentServiceServer).CollectionWatch(m, &documentServiceCollectionWatchServer{stream})
+}
+
+type DocumentService_CollectionWatchServer interface {
+	Send(*CollectionWatchResponse) error
+	grpc.ServerStream
+}
+
+type documentServiceCollectionWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *documentServiceCollectionWatchServer) Send(m *CollectionWatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // DocumentService_ServiceDesc is the grpc.ServiceDesc for DocumentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -589,6 +840,14 @@ var DocumentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteCollection",
 			Handler:    _DocumentService_DeleteCollection_Handler,
 		},
+		{
+			MethodName: "RestoreCollection",
+			Handler:    _DocumentService_RestoreCollection_Handler,
+		},
+		{
+			MethodName: "PurgeCollections",
+			Handler:    _DocumentService_PurgeCollections_Handler,
+		},
 		{
 			MethodName: "AddField",
 			Handler:    _DocumentService_AddField_Handler,
@@ -617,6 +876,14 @@ var DocumentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteDocuments",
 			Handler:    _DocumentService_DeleteDocuments_Handler,
 		},
+		{
+			MethodName: "DeleteDocument",
+			Handler:    _DocumentService_DeleteDocument_Handler,
+		},
+		{
+			MethodName: "UpdateDocument",
+			Handler:    _DocumentService_UpdateDocument_Handler,
+		},
 		{
 			MethodName: "SearchDocuments",
 			Handler:    _DocumentService_SearchDocuments_Handler,
@@ -634,6 +901,17 @@ var DocumentService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _DocumentService_ProofDocument_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadDocument",
+			Handler:       _DocumentService_UploadDocument_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "CollectionWatch",
+			Handler:       _DocumentService_CollectionWatch_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "documents.proto",
 }
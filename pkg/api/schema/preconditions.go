@@ -46,3 +46,34 @@ func PreconditionKeyNotModifiedAfterTX(key []byte, txID uint64) *Precondition {
 		},
 	}
 }
+
+func PreconditionKeyMustBeReference(key []byte) *Precondition {
+	return &Precondition{
+		Precondition: &Precondition_KeyMustBeReference{
+			KeyMustBeReference: &Precondition_KeyMustBeReferencePrecondition{
+				Key: key,
+			},
+		},
+	}
+}
+
+func PreconditionKeyMustNotBeReference(key []byte) *Precondition {
+	return &Precondition{
+		Precondition: &Precondition_KeyMustNotBeReference{
+			KeyMustNotBeReference: &Precondition_KeyMustNotBeReferencePrecondition{
+				Key: key,
+			},
+		},
+	}
+}
+
+func PreconditionKeyMustHaveValue(key, value []byte) *Precondition {
+	return &Precondition{
+		Precondition: &Precondition_KeyMustHaveValue{
+			KeyMustHaveValue: &Precondition_KeyMustHaveValuePrecondition{
+				Key:   key,
+				Value: value,
+			},
+		},
+	}
+}
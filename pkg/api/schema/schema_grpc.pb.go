@@ -49,6 +49,9 @@ type ImmuServiceClient interface {
 	GetAll(ctx context.Context, in *KeyListRequest, opts ...grpc.CallOption) (*Entries, error)
 	ExecAll(ctx context.Context, in *ExecAllRequest, opts ...grpc.CallOption) (*TxHeader, error)
 	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*Entries, error)
+	// ScanByTx streams the scanned entries grouped by the transaction that last wrote each key, one message per
+	// transaction in ascending transaction order. The limit applies to the number of entries.
+	ScanByTx(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (ImmuService_ScanByTxClient, error)
 	// NOT YET SUPPORTED
 	Count(ctx context.Context, in *KeyPrefix, opts ...grpc.CallOption) (*EntryCount, error)
 	// NOT YET SUPPORTED
@@ -57,6 +60,9 @@ type ImmuServiceClient interface {
 	VerifiableTxById(ctx context.Context, in *VerifiableTxRequest, opts ...grpc.CallOption) (*VerifiableTx, error)
 	TxScan(ctx context.Context, in *TxScanRequest, opts ...grpc.CallOption) (*TxList, error)
 	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*Entries, error)
+	// VerifiableHistory streams the revisions of a key together with the proofs needed to verify each of them.
+	// The first message holds the proof of the target state all revisions are proven against.
+	VerifiableHistory(ctx context.Context, in *VerifiableHistoryRequest, opts ...grpc.CallOption) (ImmuService_VerifiableHistoryClient, error)
 	// ServerInfo returns information about the server instance.
 	// ServerInfoRequest is defined for future extensions.
 	ServerInfo(ctx context.Context, in *ServerInfoRequest, opts ...grpc.CallOption) (*ServerInfoResponse, error)
@@ -386,6 +392,38 @@ func (c *immuServiceClient) Scan(ctx context.Context, in *ScanRequest, opts ...g
 	return out, nil
 }
 
+func (c *immuServiceClient) ScanByTx(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (ImmuService_ScanByTxClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[1], "/immudb.schema.ImmuService/ScanByTx", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &immuServiceScanByTxClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImmuService_ScanByTxClient interface {
+	Recv() (*TxGroup, error)
+	grpc.ClientStream
+}
+
+type immuServiceScanByTxClient struct {
+	grpc.ClientStream
+}
+
+func (x *immuServiceScanByTxClient) Recv() (*TxGroup, error) {
+	m := new(TxGroup)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *immuServiceClient) Count(ctx context.Context, in *KeyPrefix, opts ...grpc.CallOption) (*EntryCount, error) {
 	out := new(EntryCount)
 	err := c.cc.Invoke(ctx, "/immudb.schema.ImmuService/Count", in, out, opts...)
@@ -440,6 +478,38 @@ func (c *immuServiceClient) History(ctx context.Context, in *HistoryRequest, opt
 	return out, nil
 }
 
+func (c *immuServiceClient) VerifiableHistory(ctx context.Context, in *VerifiableHistoryRequest, opts ...grpc.CallOption) (ImmuService_VerifiableHistoryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[2], "/immudb.schema.ImmuService/VerifiableHistory", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &immuServiceVerifiableHistoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImmuService_VerifiableHistoryClient interface {
+	Recv() (*VerifiableHistoryResponse, error)
+	grpc.ClientStream
+}
+
+type immuServiceVerifiableHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *immuServiceVerifiableHistoryClient) Recv() (*VerifiableHistoryResponse, error) {
+	m := new(VerifiableHistoryResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *immuServiceClient) ServerInfo(ctx context.Context, in *ServerInfoRequest, opts ...grpc.CallOption) (*ServerInfoResponse, error) {
 	out := new(ServerInfoResponse)
 	err := c.cc.Invoke(ctx, "/immudb.schema.ImmuService/ServerInfo", in, out, opts...)
@@ -662,7 +732,7 @@ func (c *immuServiceClient) CompactIndex(ctx context.Context, in *emptypb.Empty,
 }
 
 func (c *immuServiceClient) StreamGet(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (ImmuService_StreamGetClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[1], "/immudb.schema.ImmuService/streamGet", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[3], "/immudb.schema.ImmuService/streamGet", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -694,7 +764,7 @@ func (x *immuServiceStreamGetClient) Recv() (*Chunk, error) {
 }
 
 func (c *immuServiceClient) StreamSet(ctx context.Context, opts ...grpc.CallOption) (ImmuService_StreamSetClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[2], "/immudb.schema.ImmuService/streamSet", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[4], "/immudb.schema.ImmuService/streamSet", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -728,7 +798,7 @@ func (x *immuServiceStreamSetClient) CloseAndRecv() (*TxHeader, error) {
 }
 
 func (c *immuServiceClient) StreamVerifiableGet(ctx context.Context, in *VerifiableGetRequest, opts ...grpc.CallOption) (ImmuService_StreamVerifiableGetClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[3], "/immudb.schema.ImmuService/streamVerifiableGet", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[5], "/immudb.schema.ImmuService/streamVerifiableGet", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -760,7 +830,7 @@ func (x *immuServiceStreamVerifiableGetClient) Recv() (*Chunk, error) {
 }
 
 func (c *immuServiceClient) StreamVerifiableSet(ctx context.Context, opts ...grpc.CallOption) (ImmuService_StreamVerifiableSetClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[4], "/immudb.schema.ImmuService/streamVerifiableSet", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[6], "/immudb.schema.ImmuService/streamVerifiableSet", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -794,7 +864,7 @@ func (x *immuServiceStreamVerifiableSetClient) CloseAndRecv() (*VerifiableTx, er
 }
 
 func (c *immuServiceClient) StreamScan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (ImmuService_StreamScanClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[5], "/immudb.schema.ImmuService/streamScan", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[7], "/immudb.schema.ImmuService/streamScan", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -826,7 +896,7 @@ func (x *immuServiceStreamScanClient) Recv() (*Chunk, error) {
 }
 
 func (c *immuServiceClient) StreamZScan(ctx context.Context, in *ZScanRequest, opts ...grpc.CallOption) (ImmuService_StreamZScanClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[6], "/immudb.schema.ImmuService/streamZScan", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[8], "/immudb.schema.ImmuService/streamZScan", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -858,7 +928,7 @@ func (x *immuServiceStreamZScanClient) Recv() (*Chunk, error) {
 }
 
 func (c *immuServiceClient) StreamHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (ImmuService_StreamHistoryClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[7], "/immudb.schema.ImmuService/streamHistory", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[9], "/immudb.schema.ImmuService/streamHistory", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -890,7 +960,7 @@ func (x *immuServiceStreamHistoryClient) Recv() (*Chunk, error) {
 }
 
 func (c *immuServiceClient) StreamExecAll(ctx context.Context, opts ...grpc.CallOption) (ImmuService_StreamExecAllClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[8], "/immudb.schema.ImmuService/streamExecAll", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[10], "/immudb.schema.ImmuService/streamExecAll", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -924,7 +994,7 @@ func (x *immuServiceStreamExecAllClient) CloseAndRecv() (*TxHeader, error) {
 }
 
 func (c *immuServiceClient) ExportTx(ctx context.Context, in *ExportTxRequest, opts ...grpc.CallOption) (ImmuService_ExportTxClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[9], "/immudb.schema.ImmuService/exportTx", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[11], "/immudb.schema.ImmuService/exportTx", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -956,7 +1026,7 @@ func (x *immuServiceExportTxClient) Recv() (*Chunk, error) {
 }
 
 func (c *immuServiceClient) ReplicateTx(ctx context.Context, opts ...grpc.CallOption) (ImmuService_ReplicateTxClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[10], "/immudb.schema.ImmuService/replicateTx", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[12], "/immudb.schema.ImmuService/replicateTx", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -990,7 +1060,7 @@ func (x *immuServiceReplicateTxClient) CloseAndRecv() (*TxHeader, error) {
 }
 
 func (c *immuServiceClient) StreamExportTx(ctx context.Context, opts ...grpc.CallOption) (ImmuService_StreamExportTxClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[11], "/immudb.schema.ImmuService/streamExportTx", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[13], "/immudb.schema.ImmuService/streamExportTx", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1039,7 +1109,7 @@ func (c *immuServiceClient) UnarySQLQuery(ctx context.Context, in *SQLQueryReque
 }
 
 func (c *immuServiceClient) SQLQuery(ctx context.Context, in *SQLQueryRequest, opts ...grpc.CallOption) (ImmuService_SQLQueryClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[12], "/immudb.schema.ImmuService/SQLQuery", opts...)
+	stream, err := c.cc.NewStream(ctx, &ImmuService_ServiceDesc.Streams[14], "/immudb.schema.ImmuService/SQLQuery", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1140,6 +1210,9 @@ type ImmuServiceServer interface {
 	GetAll(context.Context, *KeyListRequest) (*Entries, error)
 	ExecAll(context.Context, *ExecAllRequest) (*TxHeader, error)
 	Scan(context.Context, *ScanRequest) (*Entries, error)
+	// ScanByTx streams the scanned entries grouped by the transaction that last wrote each key, one message per
+	// transaction in ascending transaction order. The limit applies to the number of entries.
+	ScanByTx(*ScanRequest, ImmuService_ScanByTxServer) error
 	// NOT YET SUPPORTED
 	Count(context.Context, *KeyPrefix) (*EntryCount, error)
 	// NOT YET SUPPORTED
@@ -1148,6 +1221,9 @@ type ImmuServiceServer interface {
 	VerifiableTxById(context.Context, *VerifiableTxRequest) (*VerifiableTx, error)
 	TxScan(context.Context, *TxScanRequest) (*TxList, error)
 	History(context.Context, *HistoryRequest) (*Entries, error)
+	// VerifiableHistory streams the revisions of a key together with the proofs needed to verify each of them.
+	// The first message holds the proof of the target state all revisions are proven against.
+	VerifiableHistory(*VerifiableHistoryRequest, ImmuService_VerifiableHistoryServer) error
 	// ServerInfo returns information about the server instance.
 	// ServerInfoRequest is defined for future extensions.
 	ServerInfo(context.Context, *ServerInfoRequest) (*ServerInfoResponse, error)
@@ -1290,6 +1366,9 @@ func (UnimplementedImmuServiceServer) ExecAll(context.Context, *ExecAllRequest)
 func (UnimplementedImmuServiceServer) Scan(context.Context, *ScanRequest) (*Entries, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
 }
+func (UnimplementedImmuServiceServer) ScanByTx(*ScanRequest, ImmuService_ScanByTxServer) error {
+	return status.Errorf(codes.Unimplemented, "method ScanByTx not implemented")
+}
 func (UnimplementedImmuServiceServer) Count(context.Context, *KeyPrefix) (*EntryCount, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Count not implemented")
 }
@@ -1308,6 +1387,9 @@ func (UnimplementedImmuServiceServer) TxScan(context.Context, *TxScanRequest) (*
 func (UnimplementedImmuServiceServer) History(context.Context, *HistoryRequest) (*Entries, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
 }
+func (UnimplementedImmuServiceServer) VerifiableHistory(*VerifiableHistoryRequest, ImmuService_VerifiableHistoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method VerifiableHistory not implemented")
+}
 func (UnimplementedImmuServiceServer) ServerInfo(context.Context, *ServerInfoRequest) (*ServerInfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ServerInfo not implemented")
 }
@@ -1917,6 +1999,27 @@ func _ImmuService_Scan_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ImmuService_ScanByTx_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImmuServiceServer).ScanByTx(m, &immuServiceScanByTxServer{stream})
+}
+
+type ImmuService_ScanByTxServer interface {
+	Send(*TxGroup) error
+	grpc.ServerStream
+}
+
+type immuServiceScanByTxServer struct {
+	grpc.ServerStream
+}
+
+func (x *immuServiceScanByTxServer) Send(m *TxGroup) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _ImmuService_Count_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(KeyPrefix)
 	if err := dec(in); err != nil {
@@ -2025,6 +2128,27 @@ func _ImmuService_History_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ImmuService_VerifiableHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VerifiableHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImmuServiceServer).VerifiableHistory(m, &immuServiceVerifiableHistoryServer{stream})
+}
+
+type ImmuService_VerifiableHistoryServer interface {
+	Send(*VerifiableHistoryResponse) error
+	grpc.ServerStream
+}
+
+type immuServiceVerifiableHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *immuServiceVerifiableHistoryServer) Send(m *VerifiableHistoryResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _ImmuService_ServerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ServerInfoRequest)
 	if err := dec(in); err != nil {
@@ -3100,6 +3224,16 @@ var ImmuService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ImmuService_TxSQLQuery_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ScanByTx",
+			Handler:       _ImmuService_ScanByTx_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "VerifiableHistory",
+			Handler:       _ImmuService_VerifiableHistory_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "streamGet",
 			Handler:       _ImmuService_StreamGet_Handler,
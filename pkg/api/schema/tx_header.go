@@ -0,0 +1,27 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import "time"
+
+// Time returns the commit timestamp of the transaction, with a precision of seconds.
+// Timestamps are assigned by the server when the transaction is committed, using the clock of the
+// host running the primary database; replicas keep the timestamps assigned by the primary.
+// As the wall clock is used, timestamps are not guaranteed to increase if the clock is set back.
+func (hdr *TxHeader) Time() time.Time {
+	return time.Unix(hdr.GetTs(), 0)
+}
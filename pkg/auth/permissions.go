@@ -27,9 +27,11 @@ var maintenanceMethods = map[string]struct{}{
 	"VerifiableTxByID":    {},
 	"IScan":               {},
 	"Scan":                {},
+	"ScanByTx":            {},
 	"StreamScan":          {},
 	"History":             {},
 	"StreamHistory":       {},
+	"VerifiableHistory":   {},
 	"TxByID":              {},
 	"TxScan":              {},
 	"ExportTx":            {},
@@ -48,17 +50,23 @@ var maintenanceMethods = map[string]struct{}{
 	"GetCollections":      {},
 	"UpdateCollection":    {},
 	"DeleteCollection":    {},
+	"RestoreCollection":   {},
+	"PurgeCollections":    {},
 	"AddField":            {},
 	"RemoveField":         {},
 	"CreateIndex":         {},
 	"DeleteIndex":         {},
 	"InsertDocuments":     {},
+	"UploadDocument":      {},
 	"ReplaceDocuments":    {},
+	"UpdateDocument":      {},
 	"DeleteDocuments":     {},
+	"DeleteDocument":      {},
 	"SearchDocuments":     {},
 	"CountDocuments":      {},
 	"AuditDocument":       {},
 	"ProofDocument":       {},
+	"CollectionWatch":     {},
 
 	// admin methods
 	"ListUsers":    {},
@@ -103,9 +111,11 @@ var methodsPermissions = map[string][]uint32{
 	"VerifiableTxByID":       {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"IScan":                  {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"Scan":                   {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"ScanByTx":               {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"StreamScan":             {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"History":                {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"StreamHistory":          {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"VerifiableHistory":      {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"TxByID":                 {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"TxScan":                 {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"Count":                  {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
@@ -121,22 +131,28 @@ var methodsPermissions = map[string][]uint32{
 	"DescribeTable":          {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 	"VerifiableSQLGet":       {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 
-	"CreateCollection": {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"GetCollection":    {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
-	"GetCollections":   {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
-	"UpdateCollection": {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"DeleteCollection": {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"AddField":         {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"RemoveField":      {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"CreateIndex":      {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"DeleteIndex":      {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"InsertDocuments":  {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"ReplaceDocuments": {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"DeleteDocuments":  {PermissionSysAdmin, PermissionAdmin, PermissionRW},
-	"SearchDocuments":  {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
-	"CountDocuments":   {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
-	"AuditDocument":    {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
-	"ProofDocument":    {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"CreateCollection":  {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"GetCollection":     {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"GetCollections":    {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"UpdateCollection":  {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"DeleteCollection":  {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"RestoreCollection": {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"PurgeCollections":  {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"AddField":          {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"RemoveField":       {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"CreateIndex":       {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"DeleteIndex":       {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"InsertDocuments":   {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"UploadDocument":    {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"ReplaceDocuments":  {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"UpdateDocument":    {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"DeleteDocuments":   {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"DeleteDocument":    {PermissionSysAdmin, PermissionAdmin, PermissionRW},
+	"SearchDocuments":   {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"CountDocuments":    {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"AuditDocument":     {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"ProofDocument":     {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
+	"CollectionWatch":   {PermissionSysAdmin, PermissionAdmin, PermissionRW, PermissionR},
 
 	// admin methods
 	"ListUsers":        {PermissionSysAdmin, PermissionAdmin},
@@ -30,6 +30,10 @@ var ErrServerIdentityValidationFailed = errors.New("failed to validate the ident
 type Cache interface {
 	Get(serverUUID, db string) (*schema.ImmutableState, error)
 	Set(serverUUID, db string, state *schema.ImmutableState) error
+	// CompareAndSet atomically stores the new state only if the cached one matches expectedPrev, a nil
+	// expectedPrev means no state is expected to be cached. ErrStateRolledBack is returned if the cached
+	// state is newer than the new one. It's not required to hold the cache lock.
+	CompareAndSet(expectedPrev, new *schema.ImmutableState, serverUUID, db string) error
 	Lock(serverUUID string) error
 	Unlock() error
 
@@ -46,3 +50,36 @@ type HistoryCache interface {
 	Cache
 	Walk(serverUUID string, db string, f func(*schema.ImmutableState) interface{}) ([]interface{}, error)
 }
+
+// HistoryWalker is optionally implemented by history caches able to visit the states in other orders than Walk does
+type HistoryWalker interface {
+	// WalkReverse visits the states from the newest to the oldest one, until f returns ErrStopWalk
+	WalkReverse(serverUUID string, db string, f func(*schema.ImmutableState) interface{}) ([]interface{}, error)
+	// WalkBatch visits the states from the oldest to the newest one in batches of up to batchSize states,
+	// until f returns an error. ErrStopWalk stops the walk without failing it
+	WalkBatch(serverUUID string, db string, batchSize int, f func([]*schema.ImmutableState) error) error
+	// WalkAllLatest visits the latest state of each database once, in database name order, and returns
+	// the names of the databases for which f returned true
+	WalkAllLatest(serverUUID string, f func(db string, latest *schema.ImmutableState) bool) ([]string, error)
+}
+
+// BatchSetter is optionally implemented by caches able to store the states of multiple databases at once
+type BatchSetter interface {
+	// SetAll stores the states of multiple databases at once, no state is stored if any of them is older than the cached one
+	SetAll(states map[string]*schema.ImmutableState, serverUUID string) error
+}
+
+// Preloader is optionally implemented by caches able to serve the latest states from memory
+type Preloader interface {
+	// Preload loads the latest state of each database in memory, subsequent Get calls are served from it
+	Preload(serverUUID string) error
+}
+
+// StateExporter is optionally implemented by caches able to move their states to another cache
+type StateExporter interface {
+	// Export encodes every state stored for the server into a single portable blob, preserving database names
+	Export(serverUUID string) ([]byte, error)
+	// Import stores the states of a blob returned by Export, ErrStateRolledBack is returned and no state is
+	// stored if the newest imported state of any database precedes the cached one
+	Import(serverUUID string, blob []byte) error
+}
@@ -17,13 +17,19 @@ limitations under the License.
 package cache
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/golang/protobuf/proto"
 	"github.com/rogpeppe/go-internal/lockedfile"
 )
 
@@ -107,3 +113,149 @@ func validateServerIdentityInFile(serverIdentity string, serverUUID string, iden
 
 	return nil
 }
+
+// compareAndSet is the default CompareAndSet implementation, it wraps the given get and set of the state
+// of the database, which the caller must invoke while holding the same lock taken by Set
+func compareAndSet(
+	get func() (*schema.ImmutableState, error),
+	set func(*schema.ImmutableState) error,
+	expectedPrev, new *schema.ImmutableState,
+	db string,
+) error {
+	if new == nil {
+		return proto.ErrNil
+	}
+
+	current, err := get()
+	if errors.Is(err, ErrPrevStateNotFound) {
+		current = nil
+	} else if err != nil {
+		return err
+	}
+
+	if current != nil && current.TxId > new.TxId {
+		return fmt.Errorf("%w: state %d of database %s precedes the cached state %d", ErrStateRolledBack, new.TxId, db, current.TxId)
+	}
+
+	if !sameState(current, expectedPrev) {
+		return fmt.Errorf("%w: database %s", ErrUnexpectedPrevState, db)
+	}
+
+	return set(new)
+}
+
+func sameState(s1, s2 *schema.ImmutableState) bool {
+	if s1 == nil || s2 == nil {
+		return s1 == s2
+	}
+
+	return s1.TxId == s2.TxId && bytes.Equal(s1.TxHash, s2.TxHash)
+}
+
+// walkBatch passes the states to f in batches of up to batchSize states, preserving their order
+func walkBatch(states []*schema.ImmutableState, batchSize int, f func([]*schema.ImmutableState) error) error {
+	if batchSize <= 0 {
+		return ErrInvalidBatchSize
+	}
+
+	for start := 0; start < len(states); start += batchSize {
+		end := start + batchSize
+		if end > len(states) {
+			end = len(states)
+		}
+
+		err := f(states[start:end:end])
+		if errors.Is(err, ErrStopWalk) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// marshalExportedStates encodes the states of each database in database name and tx order, one state per line.
+// States are exported with the json codec whatever the codec of the cache, so any database name is preserved
+// and the export can be imported by any cache.
+func marshalExportedStates(states map[string][]*schema.ImmutableState) ([]byte, error) {
+	codec := NewJSONStateCodec()
+
+	dbs := make([]string, 0, len(states))
+	for db := range states {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+
+	var b bytes.Buffer
+
+	for _, db := range dbs {
+		for _, state := range states[db] {
+			encoded, err := codec.Marshal(db, state)
+			if err != nil {
+				return nil, err
+			}
+
+			b.Write(encoded)
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// unmarshalExportedStates decodes the states encoded by marshalExportedStates, the states of each database
+// are returned in tx order. Legacy lines are decoded as well, as states used to be exported in that format.
+func unmarshalExportedStates(blob []byte) (map[string][]*schema.ImmutableState, error) {
+	codec := NewJSONStateCodec()
+
+	states := make(map[string][]*schema.ImmutableState)
+
+	for i, line := range strings.Split(string(blob), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		db, state, err := decodeStateLine(codec, line)
+		if db == "" {
+			return nil, fmt.Errorf("%w: line %d is not a state", ErrInvalidExport, i+1)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: state of database %s at line %d: %v", ErrInvalidExport, db, i+1, err)
+		}
+
+		states[db] = append(states[db], state)
+	}
+
+	for _, dbStates := range states {
+		sort.SliceStable(dbStates, func(i, j int) bool {
+			return dbStates[i].TxId < dbStates[j].TxId
+		})
+	}
+
+	return states, nil
+}
+
+// checkImportedStates returns ErrStateRolledBack if the newest imported state of any database precedes the cached
+// one, and ErrUnexpectedPrevState if it's the cached one but its hash differs
+func checkImportedStates(imported map[string][]*schema.ImmutableState, latest map[string]*schema.ImmutableState) error {
+	for db, states := range imported {
+		current := latest[db]
+		newest := states[len(states)-1]
+
+		if current == nil {
+			continue
+		}
+
+		if newest.TxId < current.TxId {
+			return fmt.Errorf("%w: imported state %d of database %s precedes the cached state %d", ErrStateRolledBack, newest.TxId, db, current.TxId)
+		}
+
+		if newest.TxId == current.TxId && !sameState(newest, current) {
+			return fmt.Errorf("%w: imported state %d of database %s differs from the cached one", ErrUnexpectedPrevState, newest.TxId, db)
+		}
+	}
+
+	return nil
+}
@@ -18,9 +18,12 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"sync"
 	"testing"
 
+	"github.com/codenotary/immudb/pkg/api/schema"
 	"github.com/stretchr/testify/require"
 )
 
@@ -77,3 +80,163 @@ func TestValidateServerIdentityInFileCornerCases(t *testing.T) {
 		require.NotErrorIs(t, err, ErrServerIdentityValidationFailed)
 	})
 }
+
+func TestCompareAndSet(t *testing.T) {
+	caches := map[string]func(t *testing.T) Cache{
+		"file":         func(t *testing.T) Cache { return NewFileCache(t.TempDir()) },
+		"history file": func(t *testing.T) Cache { return NewHistoryFileCache(t.TempDir()) },
+		"history mem":  func(t *testing.T) Cache { return NewHistoryMemCache() },
+		"in memory":    func(t *testing.T) Cache { return NewInMemoryCache() },
+	}
+
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(t)
+
+			state1 := &schema.ImmutableState{Db: "db", TxId: 1, TxHash: []byte{1}}
+			state2 := &schema.ImmutableState{Db: "db", TxId: 2, TxHash: []byte{2}}
+
+			err := c.CompareAndSet(nil, nil, "uuid", "db")
+			require.Error(t, err)
+
+			err = c.CompareAndSet(state1, state2, "uuid", "db")
+			require.ErrorIs(t, err, ErrUnexpectedPrevState)
+
+			err = c.CompareAndSet(nil, state2, "uuid", "db")
+			require.NoError(t, err)
+
+			err = c.CompareAndSet(nil, state2, "uuid", "db")
+			require.ErrorIs(t, err, ErrUnexpectedPrevState)
+
+			err = c.CompareAndSet(state2, state1, "uuid", "db")
+			require.ErrorIs(t, err, ErrStateRolledBack)
+
+			err = c.CompareAndSet(state2, state2, "uuid", "db")
+			require.NoError(t, err)
+
+			state3 := &schema.ImmutableState{Db: "db", TxId: 3, TxHash: []byte{3}}
+
+			err = c.CompareAndSet(state2, state3, "uuid", "db")
+			require.NoError(t, err)
+
+			if fc, ok := c.(*fileCache); ok {
+				require.NoError(t, fc.Lock("uuid"))
+				defer fc.Unlock()
+			}
+
+			cached, err := c.Get("uuid", "db")
+			require.NoError(t, err)
+			require.Equal(t, state3.TxId, cached.TxId)
+			require.Equal(t, state3.TxHash, cached.TxHash)
+		})
+	}
+}
+
+func TestCompareAndSetConcurrentlyWithSet(t *testing.T) {
+	caches := map[string]func(t *testing.T) Cache{
+		"history file": func(t *testing.T) Cache { return NewHistoryFileCache(t.TempDir()) },
+		"history mem":  func(t *testing.T) Cache { return NewHistoryMemCache() },
+		"in memory":    func(t *testing.T) Cache { return NewInMemoryCache() },
+	}
+
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(t)
+
+			err := c.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: 1, TxHash: []byte{1}})
+			require.NoError(t, err)
+
+			latest := &schema.ImmutableState{Db: "db", TxId: 1000, TxHash: []byte{0xff}}
+
+			var wg sync.WaitGroup
+
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					for j := 0; j < 20; j++ {
+						current, err := c.Get("uuid", "db")
+						require.NoError(t, err)
+
+						next := &schema.ImmutableState{Db: "db", TxId: current.TxId + 1, TxHash: []byte{byte(current.TxId + 1)}}
+
+						err = c.CompareAndSet(current, next, "uuid", "db")
+						if errors.Is(err, ErrUnexpectedPrevState) || errors.Is(err, ErrStateRolledBack) {
+							continue
+						}
+						require.NoError(t, err)
+					}
+				}()
+			}
+
+			err = c.Set("uuid", "db", latest)
+			require.NoError(t, err)
+
+			wg.Wait()
+
+			// states set by CompareAndSet never precede the one stored by Set
+			cached, err := c.Get("uuid", "db")
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, cached.TxId, latest.TxId)
+		})
+	}
+}
+
+func TestWalkBatch(t *testing.T) {
+	caches := map[string]func(t *testing.T) HistoryCache{
+		"history file": func(t *testing.T) HistoryCache { return NewHistoryFileCacheWithRotation(t.TempDir(), 10) },
+		"history mem":  func(t *testing.T) HistoryCache { return NewHistoryMemCache() },
+	}
+
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(t)
+
+			err := c.(HistoryWalker).WalkBatch("uuid", "db", 3, func(states []*schema.ImmutableState) error {
+				require.Fail(t, "no states should be visited")
+				return nil
+			})
+			require.NoError(t, err)
+
+			for txID := uint64(1); txID <= 7; txID++ {
+				err := c.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: txID, TxHash: []byte{byte(txID)}})
+				require.NoError(t, err)
+			}
+
+			err = c.(HistoryWalker).WalkBatch("uuid", "db", 0, func(states []*schema.ImmutableState) error { return nil })
+			require.ErrorIs(t, err, ErrInvalidBatchSize)
+
+			var batches [][]uint64
+
+			err = c.(HistoryWalker).WalkBatch("uuid", "db", 3, func(states []*schema.ImmutableState) error {
+				var txIDs []uint64
+				for _, state := range states {
+					txIDs = append(txIDs, state.TxId)
+				}
+				batches = append(batches, txIDs)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, [][]uint64{{1, 2, 3}, {4, 5, 6}, {7}}, batches)
+
+			visited := 0
+
+			err = c.(HistoryWalker).WalkBatch("uuid", "db", 2, func(states []*schema.ImmutableState) error {
+				visited++
+				return ErrStopWalk
+			})
+			require.NoError(t, err)
+			require.Equal(t, 1, visited)
+
+			errWalk := errors.New("walk error")
+
+			err = c.(HistoryWalker).WalkBatch("uuid", "db", 10, func(states []*schema.ImmutableState) error {
+				require.Len(t, states, 7)
+				return errWalk
+			})
+			require.ErrorIs(t, err, errWalk)
+		})
+	}
+}
@@ -22,4 +22,12 @@ var (
 	ErrPrevStateNotFound   = errors.New("could not find previous state")
 	ErrLocalStateCorrupted = errors.New("local state is corrupted")
 	ErrNotImplemented      = errors.New("no implemented")
+	ErrStateRolledBack     = errors.New("state is older than the cached one")
+	ErrOlderState          = ErrStateRolledBack
+	ErrStopWalk            = errors.New("stop walking states")
+	ErrUnexpectedPrevState = errors.New("cached state does not match the expected one")
+	ErrInvalidBatchSize    = errors.New("batch size must be greater than zero")
+	ErrInvalidExport       = errors.New("invalid exported states")
+	ErrInvalidStateLine    = errors.New("invalid state line")
+	ErrInvalidDatabaseName = errors.New("invalid database name")
 )
@@ -127,6 +127,31 @@ func (w *fileCache) Set(serverUUID string, db string, state *schema.ImmutableSta
 	return nil
 }
 
+// CompareAndSet locks the state file of the server when it's not already locked by the caller,
+// thus the state is not concurrently updated by other processes sharing the same directory
+func (w *fileCache) CompareAndSet(expectedPrev, new *schema.ImmutableState, serverUUID, db string) (err error) {
+	if w.stateFile == nil {
+		if err := w.Lock(serverUUID); err != nil {
+			return err
+		}
+
+		defer func() {
+			unlockErr := w.Unlock()
+			w.stateFile = nil
+
+			if err == nil {
+				err = unlockErr
+			}
+		}()
+	}
+
+	return compareAndSet(
+		func() (*schema.ImmutableState, error) { return w.Get(serverUUID, db) },
+		func(state *schema.ImmutableState) error { return w.Set(serverUUID, db, state) },
+		expectedPrev, new, db,
+	)
+}
+
 func (w *fileCache) Lock(serverUUID string) (err error) {
 	w.stateFile, err = lockedfile.OpenFile(w.getStateFilePath(serverUUID), os.O_RDWR|os.O_CREATE, 0655)
 	return err
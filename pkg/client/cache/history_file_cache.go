@@ -17,29 +17,157 @@ limitations under the License.
 package cache
 
 import (
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/codenotary/immudb/pkg/api/schema"
 	"github.com/golang/protobuf/proto"
 )
 
+const (
+	rotatedStateFilePrefix    = ".state-"
+	rotatedStateTmpFilePrefix = ".tmp-state-"
+	sharedStateTmpFileName    = ".tmp-state"
+	rotatedStateTxIDLen       = 20
+)
+
 type historyFileCache struct {
 	dir string
+	// maxStates is the number of states kept per database, zero means only the latest
+	// state is kept in a single file shared by all databases
+	maxStates int
+	// readDir lists the files of a directory, ioutil.ReadDir is used when not set
+	readDir func(dirname string) ([]os.FileInfo, error)
+	// readFile reads the content of a state file, ioutil.ReadFile is used when not set
+	readFile func(filename string) ([]byte, error)
+	// codec encodes the states written to the state files, the legacy line format is used when not set
+	codec StateCodec
+
+	// preloaded holds the latest states loaded by Preload, by server and database. States are
+	// dropped when a newer one is stored, thus they are read from disk again
+	preloaded      map[string]map[string]*schema.ImmutableState
+	preloadedMutex sync.Mutex
+
+	// serverMutexes serialize the writes of the states of each server with any other access to them,
+	// states of different servers are accessed in parallel
+	serverMutexes      map[string]*sync.RWMutex
+	serverMutexesMutex sync.Mutex
 }
 
+var (
+	_ HistoryWalker = (*historyFileCache)(nil)
+	_ BatchSetter   = (*historyFileCache)(nil)
+	_ Preloader     = (*historyFileCache)(nil)
+	_ StateExporter = (*historyFileCache)(nil)
+)
+
 // NewHistoryFileCache returns a new history file cache
 func NewHistoryFileCache(dir string) HistoryCache {
 	return &historyFileCache{dir: dir}
 }
 
+// NewHistoryFileCacheWithRotation returns a new history file cache keeping up to maxStates states per database.
+// The first (genesis) state is always kept together with the newest maxStates-1 states, thus maxStates
+// values lower than 2 are raised to 2.
+func NewHistoryFileCacheWithRotation(dir string, maxStates int) HistoryCache {
+	if maxStates < 2 {
+		maxStates = 2
+	}
+
+	return &historyFileCache{dir: dir, maxStates: maxStates}
+}
+
+// NewHistoryFileCacheWithCodec returns a new history file cache writing states with the given codec, states
+// written in the legacy line format are still read. A zero maxStates keeps only the latest state in a single
+// file shared by all databases, otherwise states are rotated as in NewHistoryFileCacheWithRotation.
+func NewHistoryFileCacheWithCodec(dir string, maxStates int, codec StateCodec) HistoryCache {
+	if maxStates > 0 && maxStates < 2 {
+		maxStates = 2
+	}
+
+	return &historyFileCache{dir: dir, maxStates: maxStates, codec: codec}
+}
+
+func (history *historyFileCache) stateCodec() StateCodec {
+	if history.codec == nil {
+		return legacyStateCodec{}
+	}
+	return history.codec
+}
+
+// validateDatabaseName ensures the name of the database can be stored in the state files, states are stored
+// one per line and the legacy line format separates the name of the database from the state with a colon.
+// When states are rotated the name is part of the state file names, thus it must not be usable as a path.
+func (history *historyFileCache) validateDatabaseName(db string) error {
+	if strings.Contains(db, "\n") {
+		return fmt.Errorf("%w: %q contains a newline", ErrInvalidDatabaseName, db)
+	}
+
+	if history.maxStates > 0 && (strings.ContainsAny(db, `/\`) || strings.Contains(db, "..")) {
+		return fmt.Errorf("%w: %q contains a path separator or '..', which is not supported when rotating states", ErrInvalidDatabaseName, db)
+	}
+
+	if _, legacy := history.stateCodec().(legacyStateCodec); legacy && strings.Contains(db, ":") {
+		return fmt.Errorf("%w: %q contains a colon, which is not supported by the legacy state codec", ErrInvalidDatabaseName, db)
+	}
+
+	return nil
+}
+
+// encodeStateLine encodes the state of the database as a line of a state file
+func (history *historyFileCache) encodeStateLine(db string, state *schema.ImmutableState) (string, error) {
+	encoded, err := history.stateCodec().Marshal(db, state)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded) + "\n", nil
+}
+
+// serverMutex returns the mutex guarding the states of the server
+func (history *historyFileCache) serverMutex(serverUUID string) *sync.RWMutex {
+	history.serverMutexesMutex.Lock()
+	defer history.serverMutexesMutex.Unlock()
+
+	if history.serverMutexes == nil {
+		history.serverMutexes = make(map[string]*sync.RWMutex)
+	}
+
+	mutex, ok := history.serverMutexes[serverUUID]
+	if !ok {
+		mutex = &sync.RWMutex{}
+		history.serverMutexes[serverUUID] = mutex
+	}
+
+	return mutex
+}
+
 func (history *historyFileCache) Get(serverUUID, db string) (*schema.ImmutableState, error) {
+	if err := history.validateDatabaseName(db); err != nil {
+		return nil, err
+	}
+
+	mutex := history.serverMutex(serverUUID)
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return history.get(serverUUID, db)
+}
+
+// get returns the latest state of the database, the caller must hold the mutex of the server
+func (history *historyFileCache) get(serverUUID, db string) (*schema.ImmutableState, error) {
+	if state := history.preloadedState(serverUUID, db); state != nil {
+		return state, nil
+	}
+
 	statesDir := filepath.Join(history.dir, serverUUID)
-	statesFileInfos, err := history.getStatesFileInfos(statesDir)
+	statesFileInfos, err := history.getDBStatesFileInfos(statesDir, db)
 	if err != nil {
 		return nil, err
 	}
@@ -57,8 +185,79 @@ func (history *historyFileCache) Walk(
 	serverUUID string, databasename string,
 	f func(*schema.ImmutableState) interface{},
 ) ([]interface{}, error) {
-	statesDir := filepath.Join(history.dir, serverUUID)
-	statesFileInfos, err := history.getStatesFileInfos(statesDir)
+	states, err := history.dbStates(serverUUID, databasename)
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(states))
+
+	for _, state := range states {
+		results = append(results, f(state))
+	}
+
+	return results, nil
+}
+
+// WalkReverse visits the states of the database from the newest to the oldest one. The walk is
+// stopped as soon as f returns ErrStopWalk, which is not included in the results
+func (history *historyFileCache) WalkReverse(
+	serverUUID string, databasename string,
+	f func(*schema.ImmutableState) interface{},
+) ([]interface{}, error) {
+	states, err := history.dbStates(serverUUID, databasename)
+	if err != nil || len(states) == 0 {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(states))
+
+	for i := len(states) - 1; i >= 0; i-- {
+		res := f(states[i])
+
+		if err, ok := res.(error); ok && errors.Is(err, ErrStopWalk) {
+			break
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// WalkBatch visits the states of the database in tx order, passing them to f in batches of up to batchSize states
+func (history *historyFileCache) WalkBatch(
+	serverUUID string, databasename string, batchSize int,
+	f func([]*schema.ImmutableState) error,
+) error {
+	if batchSize <= 0 {
+		return ErrInvalidBatchSize
+	}
+
+	states, err := history.dbStates(serverUUID, databasename)
+	if err != nil {
+		return err
+	}
+
+	return walkBatch(states, batchSize, f)
+}
+
+// dbStates returns the states of the database sorted in tx order
+func (history *historyFileCache) dbStates(serverUUID string, databasename string) ([]*schema.ImmutableState, error) {
+	if err := history.validateDatabaseName(databasename); err != nil {
+		return nil, err
+	}
+
+	mutex := history.serverMutex(serverUUID)
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return history.readDBStates(filepath.Join(history.dir, serverUUID), databasename)
+}
+
+// readDBStates reads the states of the database sorted in tx order, the server mutex must be held
+func (history *historyFileCache) readDBStates(statesDir string, databasename string) ([]*schema.ImmutableState, error) {
+	statesFileInfos, err := history.getDBStatesFileInfos(statesDir, databasename)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +266,7 @@ func (history *historyFileCache) Walk(
 		return nil, nil
 	}
 
-	results := make([]interface{}, 0, len(statesFileInfos))
+	states := make([]*schema.ImmutableState, 0, len(statesFileInfos))
 
 	for _, stateFileInfo := range statesFileInfos {
 		stateFilePath := filepath.Join(statesDir, stateFileInfo.Name())
@@ -75,32 +274,218 @@ func (history *historyFileCache) Walk(
 		if err != nil {
 			return nil, err
 		}
-		results = append(results, f(state))
+		states = append(states, state)
 	}
 
-	return results, nil
+	// states are visited in tx order regardless of the order files are listed by the filesystem
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].GetTxId() < states[j].GetTxId()
+	})
+
+	return states, nil
+}
+
+// Preload loads the latest state of each database of the server in memory, subsequent Get calls
+// return them without reading from disk until a newer state is stored
+func (history *historyFileCache) Preload(serverUUID string) error {
+	mutex := history.serverMutex(serverUUID)
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	states, err := history.latestStates(serverUUID)
+	if err != nil {
+		return err
+	}
+
+	history.preloadedMutex.Lock()
+	defer history.preloadedMutex.Unlock()
+
+	if history.preloaded == nil {
+		history.preloaded = make(map[string]map[string]*schema.ImmutableState)
+	}
+
+	history.preloaded[serverUUID] = states
+
+	return nil
+}
+
+func (history *historyFileCache) preloadedState(serverUUID, db string) *schema.ImmutableState {
+	history.preloadedMutex.Lock()
+	defer history.preloadedMutex.Unlock()
+
+	state, ok := history.preloaded[serverUUID][db]
+	if !ok {
+		return nil
+	}
+
+	return proto.Clone(state).(*schema.ImmutableState)
+}
+
+func (history *historyFileCache) invalidatePreloaded(serverUUID string, dbs ...string) {
+	history.preloadedMutex.Lock()
+	defer history.preloadedMutex.Unlock()
+
+	for _, db := range dbs {
+		delete(history.preloaded[serverUUID], db)
+	}
+}
+
+func (history *historyFileCache) WalkAllLatest(
+	serverUUID string,
+	f func(db string, latest *schema.ImmutableState) bool,
+) ([]string, error) {
+	mutex := history.serverMutex(serverUUID)
+	mutex.RLock()
+	states, err := history.latestStates(serverUUID)
+	mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	dbs := make([]string, 0, len(states))
+	for db := range states {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+
+	var matches []string
+
+	for _, db := range dbs {
+		if f(db, states[db]) {
+			matches = append(matches, db)
+		}
+	}
+
+	return matches, nil
+}
+
+// latestStates reads the latest state of each database of the server
+func (history *historyFileCache) latestStates(serverUUID string) (map[string]*schema.ImmutableState, error) {
+	statesDir := filepath.Join(history.dir, serverUUID)
+
+	if history.maxStates > 0 {
+		return history.latestRotatedStates(statesDir)
+	}
+
+	return history.latestSharedStates(filepath.Join(statesDir, ".state"))
+}
+
+// latestSharedStates reads the states of all the databases from the file shared by them
+func (history *historyFileCache) latestSharedStates(stateFilePath string) (map[string]*schema.ImmutableState, error) {
+	input, err := history.readStateFile(stateFilePath)
+	if os.IsNotExist(err) {
+		return map[string]*schema.ImmutableState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading states from %s: %v", stateFilePath, err)
+	}
+
+	states := make(map[string]*schema.ImmutableState)
+
+	for _, line := range strings.Split(string(input), "\n") {
+		db, state, err := decodeStateLine(history.stateCodec(), line)
+		if db == "" {
+			continue
+		}
+
+		// the first line of each database is the one states are read from
+		if _, dup := states[db]; dup {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading state of database %s from file %s: %w", db, stateFilePath, err)
+		}
+
+		states[db] = state
+	}
+
+	return states, nil
+}
+
+// latestRotatedStates reads the newest state file of each database
+func (history *historyFileCache) latestRotatedStates(statesDir string) (map[string]*schema.ImmutableState, error) {
+	statesFileInfos, err := history.getStatesFileInfos(statesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	latestFiles := make(map[string]string)
+
+	for _, stateFileInfo := range statesFileInfos {
+		name := stateFileInfo.Name()
+
+		sep := len(name) - rotatedStateTxIDLen - 1
+
+		if stateFileInfo.IsDir() || !strings.HasPrefix(name, rotatedStateFilePrefix) ||
+			sep <= len(rotatedStateFilePrefix) || name[sep] != '-' {
+			continue
+		}
+
+		db := name[len(rotatedStateFilePrefix):sep]
+
+		// tx ids are zero padded, thus the greatest name holds the newest state
+		if name > latestFiles[db] {
+			latestFiles[db] = name
+		}
+	}
+
+	states := make(map[string]*schema.ImmutableState, len(latestFiles))
+
+	for db, name := range latestFiles {
+		state, err := history.unmarshalRoot(filepath.Join(statesDir, name), db)
+		if err != nil {
+			return nil, err
+		}
+
+		if state != nil {
+			states[db] = state
+		}
+	}
+
+	return states, nil
 }
 
 func (history *historyFileCache) Set(serverUUID, db string, state *schema.ImmutableState) error {
+	if err := history.validateDatabaseName(db); err != nil {
+		return err
+	}
+
+	mutex := history.serverMutex(serverUUID)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return history.set(serverUUID, db, state)
+}
+
+// set stores the state of the database, the caller must hold the mutex of the server
+func (history *historyFileCache) set(serverUUID, db string, state *schema.ImmutableState) error {
+	history.invalidatePreloaded(serverUUID, db)
+
 	statesDir := filepath.Join(history.dir, serverUUID)
 	if err := os.MkdirAll(statesDir, os.ModePerm); err != nil {
 		return fmt.Errorf("error ensuring states dir %s exists: %v", statesDir, err)
 	}
+
+	if history.maxStates > 0 {
+		return history.setRotated(statesDir, db, state)
+	}
+
 	stateFilePath := filepath.Join(statesDir, ".state")
 
 	//at run first the file does not exist
 	input, _ := ioutil.ReadFile(stateFilePath)
 
-	lines := strings.Split(string(input), "\n")
-	raw, err := proto.Marshal(state)
+	lines := history.uniqueStateLines(strings.Split(string(input), "\n"))
+
+	newState, err := history.encodeStateLine(db, state)
 	if err != nil {
 		return err
 	}
 
-	newState := db + ":" + base64.StdEncoding.EncodeToString(raw) + "\n"
 	var exists bool
 	for i, line := range lines {
-		if strings.Contains(line, db+":") {
+		if lineDB, ok := history.stateLineDB(line); ok && lineDB == db {
 			exists = true
 			lines[i] = newState
 		}
@@ -111,19 +496,424 @@ func (history *historyFileCache) Set(serverUUID, db string, state *schema.Immuta
 
 	output := strings.Join(lines, "\n")
 
-	if err = ioutil.WriteFile(stateFilePath, []byte(output), 0644); err != nil {
+	if err = writeFileAtomic(statesDir, stateFilePath, []byte(output)); err != nil {
+		return fmt.Errorf("error writing state %d to file %s: %v", state.TxId, stateFilePath, err)
+	}
+
+	return nil
+}
+
+// SetAll stores the states of multiple databases. States are checked not to be older than the cached ones
+// before storing any of them. When a single file is shared by all databases, it's read and rewritten just
+// once and the new content atomically replaces the previous one.
+func (history *historyFileCache) SetAll(states map[string]*schema.ImmutableState, serverUUID string) error {
+	mutex := history.serverMutex(serverUUID)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	statesDir := filepath.Join(history.dir, serverUUID)
+	if err := os.MkdirAll(statesDir, os.ModePerm); err != nil {
+		return fmt.Errorf("error ensuring states dir %s exists: %v", statesDir, err)
+	}
+
+	dbs := make([]string, 0, len(states))
+	for db := range states {
+		if err := history.validateDatabaseName(db); err != nil {
+			return err
+		}
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+
+	history.invalidatePreloaded(serverUUID, dbs...)
+
+	if history.maxStates > 0 {
+		return history.setAllRotated(statesDir, dbs, states)
+	}
+
+	return history.setAllShared(statesDir, dbs, states)
+}
+
+// setAllShared stores the states of the databases in the file shared by all of them, which is rewritten just once
+func (history *historyFileCache) setAllShared(statesDir string, dbs []string, states map[string]*schema.ImmutableState) error {
+	stateFilePath := filepath.Join(statesDir, ".state")
+
+	//at run first the file does not exist
+	input, _ := ioutil.ReadFile(stateFilePath)
+
+	lines := history.uniqueStateLines(strings.Split(string(input), "\n"))
+
+	dbLines := make(map[string]int, len(lines))
+	for i, line := range lines {
+		if lineDB, ok := history.stateLineDB(line); ok {
+			dbLines[lineDB] = i
+		}
+	}
+
+	for _, db := range dbs {
+		state := states[db]
+
+		newState, err := history.encodeStateLine(db, state)
+		if err != nil {
+			return err
+		}
+
+		i, exists := dbLines[db]
+		if !exists {
+			lines = append(lines, newState)
+			continue
+		}
+
+		prevState, err := history.unmarshalStateLine(lines[i])
+		if err != nil {
+			return fmt.Errorf("error reading state of database %s from file %s: %w", db, stateFilePath, err)
+		}
+
+		if state.TxId < prevState.TxId {
+			return fmt.Errorf("%w: state %d of database %s precedes the cached state %d", ErrStateRolledBack, state.TxId, db, prevState.TxId)
+		}
+
+		lines[i] = newState
+	}
+
+	output := strings.Join(lines, "\n")
+
+	err := writeFileAtomic(statesDir, stateFilePath, []byte(output))
+	if err != nil {
+		return fmt.Errorf("error writing states to file %s: %v", stateFilePath, err)
+	}
+
+	return nil
+}
+
+// Export encodes every state stored for the server, the states of each database are exported in tx order
+// together with the name of the database, so they can be loaded by Import into another cache
+func (history *historyFileCache) Export(serverUUID string) ([]byte, error) {
+	mutex := history.serverMutex(serverUUID)
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	latest, err := history.latestStates(serverUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string][]*schema.ImmutableState, len(latest))
+
+	for db, state := range latest {
+		if history.maxStates == 0 {
+			// only the latest state is kept when a single file is shared by all databases
+			states[db] = []*schema.ImmutableState{state}
+			continue
+		}
+
+		states[db], err = history.readDBStates(filepath.Join(history.dir, serverUUID), db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return marshalExportedStates(states)
+}
+
+// Import stores the states encoded by Export. No state is stored if the newest imported state of any database
+// precedes the cached one, in which case ErrStateRolledBack is returned. When a single file is shared by all
+// databases, only the newest imported state of each database is kept.
+func (history *historyFileCache) Import(serverUUID string, blob []byte) error {
+	imported, err := unmarshalExportedStates(blob)
+	if err != nil {
+		return err
+	}
+
+	for db := range imported {
+		if err := history.validateDatabaseName(db); err != nil {
+			return err
+		}
+	}
+
+	mutex := history.serverMutex(serverUUID)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	latest, err := history.latestStates(serverUUID)
+	if err != nil {
+		return err
+	}
+
+	err = checkImportedStates(imported, latest)
+	if err != nil || len(imported) == 0 {
+		return err
+	}
+
+	dbs := make([]string, 0, len(imported))
+	newest := make(map[string]*schema.ImmutableState, len(imported))
+
+	for db, states := range imported {
+		dbs = append(dbs, db)
+		newest[db] = states[len(states)-1]
+	}
+	sort.Strings(dbs)
+
+	history.invalidatePreloaded(serverUUID, dbs...)
+
+	statesDir := filepath.Join(history.dir, serverUUID)
+	if err := os.MkdirAll(statesDir, os.ModePerm); err != nil {
+		return fmt.Errorf("error ensuring states dir %s exists: %v", statesDir, err)
+	}
+
+	if history.maxStates == 0 {
+		return history.setAllShared(statesDir, dbs, newest)
+	}
+
+	for _, db := range dbs {
+		for _, state := range imported[db] {
+			err := history.setRotated(statesDir, db, state)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (history *historyFileCache) setAllRotated(statesDir string, dbs []string, states map[string]*schema.ImmutableState) error {
+	for _, db := range dbs {
+		if states[db] == nil {
+			return proto.ErrNil
+		}
+
+		statesFileInfos, err := history.getDBStatesFileInfos(statesDir, db)
+		if err != nil {
+			return err
+		}
+
+		if len(statesFileInfos) == 0 {
+			continue
+		}
+
+		prevStateFilePath := filepath.Join(statesDir, statesFileInfos[len(statesFileInfos)-1].Name())
+
+		prevState, err := history.unmarshalRoot(prevStateFilePath, db)
+		if err != nil {
+			return err
+		}
+
+		if prevState != nil && states[db].TxId < prevState.TxId {
+			return fmt.Errorf("%w: state %d of database %s precedes the cached state %d", ErrStateRolledBack, states[db].TxId, db, prevState.TxId)
+		}
+	}
+
+	for _, db := range dbs {
+		err := history.setRotated(statesDir, db, states[db])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stateLineDB returns the name of the database the state line belongs to
+func (history *historyFileCache) stateLineDB(line string) (string, bool) {
+	db, _, _ := decodeStateLine(history.stateCodec(), line)
+	return db, db != ""
+}
+
+// uniqueStateLines drops the state lines of databases already found in a previous line, the first one
+// is kept as it's the one states are read from
+func (history *historyFileCache) uniqueStateLines(lines []string) []string {
+	dbs := make(map[string]struct{}, len(lines))
+	unique := lines[:0]
+
+	for _, line := range lines {
+		if db, ok := history.stateLineDB(line); ok {
+			if _, dup := dbs[db]; dup {
+				continue
+			}
+			dbs[db] = struct{}{}
+		}
+
+		unique = append(unique, line)
+	}
+
+	return unique
+}
+
+func (history *historyFileCache) unmarshalStateLine(line string) (*schema.ImmutableState, error) {
+	_, state, err := decodeStateLine(history.stateCodec(), line)
+	return state, err
+}
+
+// setRotated stores the state in its own file and drops the oldest states but the genesis one when
+// exceeding the maximum number of states. States are written to a temporary file which is then
+// atomically renamed, so an interrupted write never corrupts already stored states and extra
+// states left behind by an interrupted rotation are dropped on the next write.
+func (history *historyFileCache) setRotated(statesDir, db string, state *schema.ImmutableState) error {
+	content, err := history.encodeStateLine(db, state)
+	if err != nil {
+		return err
+	}
+
+	stateFileName := rotatedStateFileName(db, state.TxId)
+	stateFilePath := filepath.Join(statesDir, stateFileName)
+	tmpFilePath := filepath.Join(statesDir, rotatedStateTmpFilePrefix+stateFileName[len(rotatedStateFilePrefix):])
+
+	err = writeFileSync(tmpFilePath, []byte(content))
+	if err != nil {
+		return fmt.Errorf("error writing state %d to file %s: %v", state.TxId, tmpFilePath, err)
+	}
+
+	err = os.Rename(tmpFilePath, stateFilePath)
+	if err != nil {
+		return fmt.Errorf("error writing state %d to file %s: %v", state.TxId, stateFilePath, err)
+	}
+
+	err = syncDir(statesDir)
+	if err != nil {
 		return fmt.Errorf("error writing state %d to file %s: %v", state.TxId, stateFilePath, err)
 	}
 
+	statesFileInfos, err := history.getDBStatesFileInfos(statesDir, db)
+	if err != nil {
+		return err
+	}
+
+	if len(statesFileInfos) <= history.maxStates {
+		return nil
+	}
+
+	// the genesis state is kept, oldest states are removed first
+	for _, stateFileInfo := range statesFileInfos[1 : len(statesFileInfos)-history.maxStates+1] {
+		err = os.Remove(filepath.Join(statesDir, stateFileInfo.Name()))
+		if err != nil {
+			return fmt.Errorf("error rotating states of database %s: %v", db, err)
+		}
+	}
+
 	return nil
 }
 
+func rotatedStateFileName(db string, txID uint64) string {
+	return fmt.Sprintf("%s%s-%0*d", rotatedStateFilePrefix, db, rotatedStateTxIDLen, txID)
+}
+
+// writeFileAtomic replaces the content of the shared states file by renaming a synced temporary file
+// into place, the directory is synced as well so the update survives a crash. The temporary file is
+// uniquely named so concurrent writers never write into each other's file.
+func writeFileAtomic(statesDir, path string, data []byte) error {
+	f, err := os.CreateTemp(statesDir, sharedStateTmpFileName+"-*")
+	if err != nil {
+		return err
+	}
+
+	tmpFilePath := f.Name()
+
+	err = f.Chmod(0644)
+	if err == nil {
+		err = writeSync(f, data)
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmpFilePath, path)
+	}
+	if err != nil {
+		os.Remove(tmpFilePath)
+		return err
+	}
+
+	return syncDir(statesDir)
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	return writeSync(f, data)
+}
+
+// writeSync writes the data into the file, syncs it and closes it
+func writeSync(f *os.File, data []byte) error {
+	_, err := f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// getDBStatesFileInfos returns the files holding states of the database sorted from the oldest to the newest one
+func (history *historyFileCache) getDBStatesFileInfos(dir string, db string) ([]os.FileInfo, error) {
+	statesFileInfos, err := history.getStatesFileInfos(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if history.maxStates == 0 {
+		// temporary files may be left behind by interrupted writes, they must never be read
+		dbStatesFileInfos := make([]os.FileInfo, 0, len(statesFileInfos))
+
+		for _, stateFileInfo := range statesFileInfos {
+			if !strings.HasPrefix(stateFileInfo.Name(), sharedStateTmpFileName) {
+				dbStatesFileInfos = append(dbStatesFileInfos, stateFileInfo)
+			}
+		}
+
+		return dbStatesFileInfos, nil
+	}
+
+	dbPrefix := rotatedStateFilePrefix + db + "-"
+
+	dbStatesFileInfos := make([]os.FileInfo, 0, len(statesFileInfos))
+
+	for _, stateFileInfo := range statesFileInfos {
+		name := stateFileInfo.Name()
+
+		// the fixed length of the tx suffix tells apart databases sharing a name prefix
+		if !stateFileInfo.IsDir() && strings.HasPrefix(name, dbPrefix) && len(name) == len(dbPrefix)+rotatedStateTxIDLen {
+			dbStatesFileInfos = append(dbStatesFileInfos, stateFileInfo)
+		}
+	}
+
+	// tx ids are zero padded, thus sorting by name sorts by tx
+	sort.Slice(dbStatesFileInfos, func(i, j int) bool {
+		return dbStatesFileInfos[i].Name() < dbStatesFileInfos[j].Name()
+	})
+
+	return dbStatesFileInfos, nil
+}
+
 func (history *historyFileCache) getStatesFileInfos(dir string) ([]os.FileInfo, error) {
 	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		return nil, fmt.Errorf("error ensuring states dir %s exists: %v", dir, err)
 	}
 
-	statesFileInfos, err := ioutil.ReadDir(dir)
+	readDir := history.readDir
+	if readDir == nil {
+		readDir = ioutil.ReadDir
+	}
+
+	statesFileInfos, err := readDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("error reading states dir %s: %v", dir, err)
 	}
@@ -131,37 +921,54 @@ func (history *historyFileCache) getStatesFileInfos(dir string) ([]os.FileInfo,
 	return statesFileInfos, nil
 }
 
+func (history *historyFileCache) readStateFile(fpath string) ([]byte, error) {
+	if history.readFile == nil {
+		return ioutil.ReadFile(fpath)
+	}
+	return history.readFile(fpath)
+}
+
 func (history *historyFileCache) unmarshalRoot(fpath string, db string) (*schema.ImmutableState, error) {
-	state := &schema.ImmutableState{}
-	raw, err := ioutil.ReadFile(fpath)
+	raw, err := history.readStateFile(fpath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading state from %s: %v", fpath, err)
 	}
 
 	lines := strings.Split(string(raw), "\n")
 	for _, line := range lines {
-		if strings.Contains(line, db+":") {
-			r := strings.Split(line, ":")
-
-			if r[1] == "" {
-				return nil, ErrPrevStateNotFound
-			}
-
-			oldRoot, err := base64.StdEncoding.DecodeString(r[1])
-			if err != nil {
-				return nil, ErrPrevStateNotFound
-			}
+		lineDB, state, err := decodeStateLine(history.stateCodec(), line)
+		if lineDB != db || db == "" {
+			continue
+		}
 
-			if err = proto.Unmarshal(oldRoot, state); err != nil {
-				return nil, fmt.Errorf("error unmarshaling state from %s: %v", fpath, err)
-			}
-			return state, nil
+		if errors.Is(err, ErrPrevStateNotFound) {
+			return nil, err
 		}
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling state from %s: %v", fpath, err)
+		}
+		return state, nil
 	}
 
 	return nil, nil
 }
 
+func (history *historyFileCache) CompareAndSet(expectedPrev, new *schema.ImmutableState, serverUUID, db string) error {
+	if err := history.validateDatabaseName(db); err != nil {
+		return err
+	}
+
+	mutex := history.serverMutex(serverUUID)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return compareAndSet(
+		func() (*schema.ImmutableState, error) { return history.get(serverUUID, db) },
+		func(state *schema.ImmutableState) error { return history.set(serverUUID, db, state) },
+		expectedPrev, new, db,
+	)
+}
+
 func (history *historyFileCache) Lock(serverUUID string) (err error) {
 	return fmt.Errorf("not implemented")
 }
@@ -18,10 +18,13 @@ package cache
 
 import (
 	"encoding/base64"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
@@ -207,3 +210,692 @@ func TestHistoryFileCache_unmarshalRootEmptyFile(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, state)
 }
+
+func TestHistoryFileCacheWithRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	fc := NewHistoryFileCacheWithRotation(dir, 4)
+
+	for i := uint64(1); i <= 10; i++ {
+		err := fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: i, TxHash: []byte{byte(i)}})
+		require.NoError(t, err)
+
+		err = fc.Set("uuid", "db-1", &schema.ImmutableState{Db: "db-1", TxId: i * 100, TxHash: []byte{byte(i)}})
+		require.NoError(t, err)
+	}
+
+	// leftovers of an interrupted write should be ignored
+	err := ioutil.WriteFile(filepath.Join(dir, "uuid", rotatedStateTmpFilePrefix+"db-00000000000000000011"), []byte("db:"), 0644)
+	require.NoError(t, err)
+
+	walkTxIDs := func(db string) []uint64 {
+		txIDs, err := fc.Walk("uuid", db, func(state *schema.ImmutableState) interface{} {
+			return state.TxId
+		})
+		require.NoError(t, err)
+
+		res := make([]uint64, len(txIDs))
+		for i, txID := range txIDs {
+			res[i] = txID.(uint64)
+		}
+
+		return res
+	}
+
+	t.Run("genesis and newest states should be retained", func(t *testing.T) {
+		require.Equal(t, []uint64{1, 8, 9, 10}, walkTxIDs("db"))
+		require.Equal(t, []uint64{100, 800, 900, 1000}, walkTxIDs("db-1"))
+
+		state, err := fc.Get("uuid", "db")
+		require.NoError(t, err)
+		require.EqualValues(t, 10, state.TxId)
+	})
+
+	t.Run("setting an already stored state should not rotate states", func(t *testing.T) {
+		err := fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: 10, TxHash: []byte{10}})
+		require.NoError(t, err)
+
+		require.Equal(t, []uint64{1, 8, 9, 10}, walkTxIDs("db"))
+	})
+
+	t.Run("missing states should not be reported", func(t *testing.T) {
+		state, err := fc.Get("uuid", "db2")
+		require.NoError(t, err)
+		require.Nil(t, state)
+
+		require.Empty(t, walkTxIDs("db2"))
+	})
+
+	t.Run("states should be retained across instances", func(t *testing.T) {
+		fc = NewHistoryFileCacheWithRotation(dir, 1)
+
+		err := fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: 11, TxHash: []byte{11}})
+		require.NoError(t, err)
+
+		require.Equal(t, []uint64{1, 11}, walkTxIDs("db"))
+	})
+}
+
+func TestHistoryFileCacheWalkInTxOrder(t *testing.T) {
+	reverseReadDir := func(dirname string) ([]os.FileInfo, error) {
+		fileInfos, err := ioutil.ReadDir(dirname)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, j := 0, len(fileInfos)-1; i < j; i, j = i+1, j-1 {
+			fileInfos[i], fileInfos[j] = fileInfos[j], fileInfos[i]
+		}
+
+		return fileInfos, nil
+	}
+
+	walkTxIDs := func(t *testing.T, fc HistoryCache) []uint64 {
+		txIDs, err := fc.Walk("uuid", "db", func(state *schema.ImmutableState) interface{} {
+			return state.TxId
+		})
+		require.NoError(t, err)
+
+		res := make([]uint64, len(txIDs))
+		for i, txID := range txIDs {
+			res[i] = txID.(uint64)
+		}
+
+		return res
+	}
+
+	t.Run("rotated states should be visited in tx order", func(t *testing.T) {
+		fc := &historyFileCache{dir: t.TempDir(), maxStates: 10, readDir: reverseReadDir}
+
+		for _, txID := range []uint64{1, 9, 10, 100} {
+			err := fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: txID, TxHash: []byte{byte(txID)}})
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []uint64{1, 9, 10, 100}, walkTxIDs(t, fc))
+
+		state, err := fc.Get("uuid", "db")
+		require.NoError(t, err)
+		require.EqualValues(t, 100, state.TxId)
+	})
+
+	t.Run("states of multiple files should be visited in tx order", func(t *testing.T) {
+		dir := t.TempDir()
+
+		for i, txID := range []uint64{3, 1, 2} {
+			raw, err := proto.Marshal(&schema.ImmutableState{Db: "db", TxId: txID})
+			require.NoError(t, err)
+
+			err = os.MkdirAll(filepath.Join(dir, "uuid"), os.ModePerm)
+			require.NoError(t, err)
+
+			err = ioutil.WriteFile(
+				filepath.Join(dir, "uuid", ".state"+string(rune('a'+i))),
+				[]byte("db:"+base64.StdEncoding.EncodeToString(raw)+"\n"),
+				0644,
+			)
+			require.NoError(t, err)
+		}
+
+		fc := &historyFileCache{dir: dir, readDir: reverseReadDir}
+		require.Equal(t, []uint64{1, 2, 3}, walkTxIDs(t, fc))
+	})
+}
+
+func TestHistoryFileCacheAtomicSet(t *testing.T) {
+	dir := t.TempDir()
+	fc := NewHistoryFileCache(dir)
+
+	err := fc.Set("uuid", "db1", &schema.ImmutableState{Db: "db1", TxId: 1, TxHash: []byte{1}})
+	require.NoError(t, err)
+
+	// a temporary file left behind by an interrupted write
+	err = ioutil.WriteFile(filepath.Join(dir, "uuid", sharedStateTmpFileName+"-123"), []byte("db1:corrupted"), 0644)
+	require.NoError(t, err)
+
+	state, err := fc.Get("uuid", "db1")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, state.TxId)
+
+	txIDs, err := fc.Walk("uuid", "db1", func(state *schema.ImmutableState) interface{} {
+		return state.TxId
+	})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{uint64(1)}, txIDs)
+
+	err = fc.Set("uuid", "db2", &schema.ImmutableState{Db: "db2", TxId: 2, TxHash: []byte{2}})
+	require.NoError(t, err)
+
+	// the write does not leave its own temporary file behind
+	tmpFiles, err := filepath.Glob(filepath.Join(dir, "uuid", sharedStateTmpFileName+"-*"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "uuid", sharedStateTmpFileName+"-123")}, tmpFiles)
+
+	for db, txID := range map[string]uint64{"db1": 1, "db2": 2} {
+		state, err := fc.Get("uuid", db)
+		require.NoError(t, err)
+		require.Equal(t, txID, state.TxId)
+	}
+}
+
+func TestHistoryFileCacheConcurrentWriters(t *testing.T) {
+	dir := t.TempDir()
+
+	// caches sharing the folder but not their locks, as different processes would
+	caches := []Cache{NewHistoryFileCache(dir), NewHistoryFileCache(dir)}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*50)
+
+	for i, fc := range caches {
+		wg.Add(1)
+
+		go func(fc Cache, db string) {
+			defer wg.Done()
+
+			for txID := uint64(1); txID <= 50; txID++ {
+				err := fc.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: txID, TxHash: []byte{byte(txID)}})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(fc, fmt.Sprintf("db%d", i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	tmpFiles, err := filepath.Glob(filepath.Join(dir, "uuid", sharedStateTmpFileName+"-*"))
+	require.NoError(t, err)
+	require.Empty(t, tmpFiles)
+}
+
+func TestHistoryFileCacheExactDBMatching(t *testing.T) {
+	dir := t.TempDir()
+	fc := NewHistoryFileCache(dir)
+
+	err := fc.Set("uuid", "db2", &schema.ImmutableState{Db: "db2", TxId: 20, TxHash: []byte{20}})
+	require.NoError(t, err)
+
+	// a database name ending with the name of another one
+	err = fc.Set("uuid", "mydb", &schema.ImmutableState{Db: "mydb", TxId: 10, TxHash: []byte{10}})
+	require.NoError(t, err)
+
+	state, err := fc.Get("uuid", "db")
+	require.NoError(t, err)
+	require.Nil(t, state)
+
+	err = fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: 1, TxHash: []byte{1}})
+	require.NoError(t, err)
+
+	err = fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: 2, TxHash: []byte{2}})
+	require.NoError(t, err)
+
+	for db, txID := range map[string]uint64{"db": 2, "db2": 20, "mydb": 10} {
+		state, err := fc.Get("uuid", db)
+		require.NoError(t, err)
+		require.Equal(t, db, state.Db)
+		require.Equal(t, txID, state.TxId)
+	}
+
+	t.Run("duplicate lines should be dropped by Set", func(t *testing.T) {
+		stateFilePath := filepath.Join(dir, "uuid", ".state")
+
+		content, err := ioutil.ReadFile(stateFilePath)
+		require.NoError(t, err)
+
+		raw, err := proto.Marshal(&schema.ImmutableState{Db: "db", TxId: 1})
+		require.NoError(t, err)
+
+		stale := "db:" + base64.StdEncoding.EncodeToString(raw) + "\n"
+
+		err = ioutil.WriteFile(stateFilePath, append(content, stale...), 0644)
+		require.NoError(t, err)
+
+		err = fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: 3, TxHash: []byte{3}})
+		require.NoError(t, err)
+
+		content, err = ioutil.ReadFile(stateFilePath)
+		require.NoError(t, err)
+
+		var dbLines int
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(line, "db:") {
+				dbLines++
+			}
+		}
+		require.Equal(t, 1, dbLines)
+
+		state, err := fc.Get("uuid", "db")
+		require.NoError(t, err)
+		require.EqualValues(t, 3, state.TxId)
+
+		state, err = fc.Get("uuid", "db2")
+		require.NoError(t, err)
+		require.EqualValues(t, 20, state.TxId)
+	})
+}
+
+func TestHistoryFileCacheConcurrentSet(t *testing.T) {
+	dir := t.TempDir()
+	fc := NewHistoryFileCache(dir)
+
+	var wg sync.WaitGroup
+
+	start := make(chan struct{})
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func(db string) {
+			defer wg.Done()
+
+			<-start
+
+			for txID := uint64(1); txID <= 5; txID++ {
+				err := fc.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: txID, TxHash: []byte(db)})
+				require.NoError(t, err)
+
+				state, err := fc.Get("uuid", db)
+				require.NoError(t, err)
+				require.Equal(t, txID, state.TxId)
+			}
+
+			// states of other servers are not serialized with the ones of uuid
+			err := fc.Set("other-uuid", db, &schema.ImmutableState{Db: db, TxId: 2, TxHash: []byte(db)})
+			require.NoError(t, err)
+		}(fmt.Sprintf("db%d", i))
+	}
+
+	close(start)
+	wg.Wait()
+
+	// states are read from disk by a new cache
+	fc = NewHistoryFileCache(dir)
+
+	for i := 0; i < 50; i++ {
+		db := fmt.Sprintf("db%d", i)
+
+		state, err := fc.Get("uuid", db)
+		require.NoError(t, err)
+		require.NotNil(t, state)
+		require.EqualValues(t, 5, state.TxId)
+		require.Equal(t, []byte(db), state.TxHash)
+
+		state, err = fc.Get("other-uuid", db)
+		require.NoError(t, err)
+		require.NotNil(t, state)
+		require.EqualValues(t, 2, state.TxId)
+	}
+}
+
+func TestHistoryFileCacheWalkReverse(t *testing.T) {
+	for _, maxStates := range []int{0, 10} {
+		fc := &historyFileCache{dir: t.TempDir(), maxStates: maxStates}
+
+		t.Run(fmt.Sprintf("an empty states dir should not be walked with maxStates=%d", maxStates), func(t *testing.T) {
+			res, err := fc.WalkReverse("uuid", "db", func(state *schema.ImmutableState) interface{} {
+				require.Fail(t, "no state should be visited")
+				return nil
+			})
+			require.NoError(t, err)
+			require.Nil(t, res)
+		})
+
+		for _, txID := range []uint64{1, 9, 10, 100} {
+			err := fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: txID, TxHash: []byte{byte(txID)}})
+			require.NoError(t, err)
+		}
+
+		t.Run(fmt.Sprintf("states should be visited from the newest one with maxStates=%d", maxStates), func(t *testing.T) {
+			if maxStates == 0 {
+				// a single state per database is kept without rotation
+				res, err := fc.WalkReverse("uuid", "db", func(state *schema.ImmutableState) interface{} {
+					return state.TxId
+				})
+				require.NoError(t, err)
+				require.Equal(t, []interface{}{uint64(100)}, res)
+				return
+			}
+
+			res, err := fc.WalkReverse("uuid", "db", func(state *schema.ImmutableState) interface{} {
+				return state.TxId
+			})
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{uint64(100), uint64(10), uint64(9), uint64(1)}, res)
+
+			res, err = fc.WalkReverse("uuid", "db", func(state *schema.ImmutableState) interface{} {
+				if state.TxId < 10 {
+					return ErrStopWalk
+				}
+				return state.TxId
+			})
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{uint64(100), uint64(10)}, res)
+		})
+	}
+}
+
+func TestHistoryFileCacheSetAll(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		fc   HistoryCache
+	}{
+		{"single file", NewHistoryFileCache(t.TempDir())},
+		{"with rotation", NewHistoryFileCacheWithRotation(t.TempDir(), 3)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			states := make(map[string]*schema.ImmutableState, 10)
+
+			for i := 0; i < 10; i++ {
+				states[fmt.Sprintf("db%d", i)] = &schema.ImmutableState{Db: fmt.Sprintf("db%d", i), TxId: uint64(i + 1), TxHash: []byte{byte(i)}}
+			}
+
+			err := c.fc.Set("uuid", "db0", &schema.ImmutableState{Db: "db0", TxId: 1, TxHash: []byte{0}})
+			require.NoError(t, err)
+
+			err = c.fc.(BatchSetter).SetAll(states, "uuid")
+			require.NoError(t, err)
+
+			for db, state := range states {
+				cachedState, err := c.fc.Get("uuid", db)
+				require.NoError(t, err)
+				require.True(t, proto.Equal(state, cachedState), "unexpected state for database %s", db)
+			}
+
+			t.Run("older states should not be stored", func(t *testing.T) {
+				err := c.fc.(BatchSetter).SetAll(map[string]*schema.ImmutableState{
+					"db1":   {Db: "db1", TxId: 100, TxHash: []byte{100}},
+					"db5":   {Db: "db5", TxId: 1, TxHash: []byte{1}},
+					"newdb": {Db: "newdb", TxId: 1, TxHash: []byte{1}},
+				}, "uuid")
+				require.ErrorIs(t, err, ErrOlderState)
+
+				cachedState, err := c.fc.Get("uuid", "db1")
+				require.NoError(t, err)
+				require.EqualValues(t, 2, cachedState.TxId)
+
+				cachedState, err = c.fc.Get("uuid", "newdb")
+				require.NoError(t, err)
+				require.Nil(t, cachedState)
+			})
+
+			t.Run("nil states should be rejected", func(t *testing.T) {
+				err := c.fc.(BatchSetter).SetAll(map[string]*schema.ImmutableState{"db1": nil}, "uuid")
+				require.ErrorIs(t, err, proto.ErrNil)
+			})
+		})
+	}
+}
+
+func TestHistoryFileCacheWalkAllLatest(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		fc   HistoryCache
+	}{
+		{"single file", NewHistoryFileCache(t.TempDir())},
+		{"with rotation", NewHistoryFileCacheWithRotation(t.TempDir(), 3)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			latestTxIDs := map[string]uint64{"db": 5, "db1": 3, "defaultdb": 10}
+
+			for db, latestTxID := range latestTxIDs {
+				for txID := uint64(1); txID <= latestTxID; txID++ {
+					err := c.fc.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: txID, TxHash: []byte{byte(txID)}})
+					require.NoError(t, err)
+				}
+			}
+
+			visited := make(map[string]uint64)
+
+			dbs, err := c.fc.(HistoryWalker).WalkAllLatest("uuid", func(db string, latest *schema.ImmutableState) bool {
+				_, ok := visited[db]
+				require.False(t, ok, "database %s visited more than once", db)
+
+				visited[db] = latest.TxId
+
+				return latest.TxId < 5
+			})
+			require.NoError(t, err)
+			require.Equal(t, latestTxIDs, visited)
+			require.Equal(t, []string{"db1"}, dbs)
+
+			dbs, err = c.fc.(HistoryWalker).WalkAllLatest("unknown-uuid", func(db string, latest *schema.ImmutableState) bool {
+				require.Fail(t, "no state should be visited")
+				return true
+			})
+			require.NoError(t, err)
+			require.Empty(t, dbs)
+		})
+	}
+}
+
+func TestHistoryFileCachePreload(t *testing.T) {
+	for _, maxStates := range []int{0, 3} {
+		t.Run(fmt.Sprintf("maxStates=%d", maxStates), func(t *testing.T) {
+			var reads int
+
+			fc := &historyFileCache{
+				dir:       t.TempDir(),
+				maxStates: maxStates,
+				readDir: func(dirname string) ([]os.FileInfo, error) {
+					reads++
+					return ioutil.ReadDir(dirname)
+				},
+				readFile: func(filename string) ([]byte, error) {
+					reads++
+					return ioutil.ReadFile(filename)
+				},
+			}
+
+			for _, db := range []string{"db1", "db2", "db3"} {
+				for txID := uint64(1); txID <= 3; txID++ {
+					err := fc.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: txID, TxHash: []byte{byte(txID)}})
+					require.NoError(t, err)
+				}
+			}
+
+			err := fc.Preload("uuid")
+			require.NoError(t, err)
+
+			reads = 0
+
+			for _, db := range []string{"db1", "db2", "db3"} {
+				state, err := fc.Get("uuid", db)
+				require.NoError(t, err)
+				require.Equal(t, db, state.Db)
+				require.EqualValues(t, 3, state.TxId)
+			}
+
+			require.Zero(t, reads)
+
+			t.Run("preloaded states should be invalidated when storing newer ones", func(t *testing.T) {
+				err := fc.Set("uuid", "db1", &schema.ImmutableState{Db: "db1", TxId: 4, TxHash: []byte{4}})
+				require.NoError(t, err)
+
+				reads = 0
+
+				state, err := fc.Get("uuid", "db1")
+				require.NoError(t, err)
+				require.EqualValues(t, 4, state.TxId)
+				require.NotZero(t, reads)
+
+				err = fc.SetAll(map[string]*schema.ImmutableState{"db2": {Db: "db2", TxId: 5, TxHash: []byte{5}}}, "uuid")
+				require.NoError(t, err)
+
+				state, err = fc.Get("uuid", "db2")
+				require.NoError(t, err)
+				require.EqualValues(t, 5, state.TxId)
+			})
+
+			t.Run("preloaded states should not be modified through returned ones", func(t *testing.T) {
+				state, err := fc.Get("uuid", "db3")
+				require.NoError(t, err)
+
+				state.TxId = 100
+
+				state, err = fc.Get("uuid", "db3")
+				require.NoError(t, err)
+				require.EqualValues(t, 3, state.TxId)
+			})
+		})
+	}
+}
+
+func TestHistoryFileCacheExportImport(t *testing.T) {
+	for _, maxStates := range []int{0, 3} {
+		t.Run(fmt.Sprintf("maxStates=%d", maxStates), func(t *testing.T) {
+			source := &historyFileCache{dir: t.TempDir(), maxStates: maxStates}
+
+			for _, db := range []string{"db1", "db2"} {
+				for txID := uint64(1); txID <= 3; txID++ {
+					err := source.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: txID, TxHash: []byte{byte(txID)}})
+					require.NoError(t, err)
+				}
+			}
+
+			blob, err := source.Export("uuid")
+			require.NoError(t, err)
+
+			target := &historyFileCache{dir: t.TempDir(), maxStates: maxStates}
+
+			err = target.Set("uuid", "db1", &schema.ImmutableState{Db: "db1", TxId: 2, TxHash: []byte{2}})
+			require.NoError(t, err)
+
+			err = target.Import("uuid", blob)
+			require.NoError(t, err)
+
+			for _, db := range []string{"db1", "db2"} {
+				state, err := target.Get("uuid", db)
+				require.NoError(t, err)
+				require.Equal(t, db, state.Db)
+				require.EqualValues(t, 3, state.TxId)
+
+				expected, err := source.Walk("uuid", db, func(s *schema.ImmutableState) interface{} { return s.TxId })
+				require.NoError(t, err)
+
+				imported, err := target.Walk("uuid", db, func(s *schema.ImmutableState) interface{} { return s.TxId })
+				require.NoError(t, err)
+				require.Equal(t, expected, imported)
+			}
+
+			t.Run("importing the same states again should not fail", func(t *testing.T) {
+				err := target.Import("uuid", blob)
+				require.NoError(t, err)
+
+				reexported, err := target.Export("uuid")
+				require.NoError(t, err)
+				require.Equal(t, blob, reexported)
+			})
+
+			t.Run("states older than the cached ones should not be imported", func(t *testing.T) {
+				err := target.Set("uuid", "db2", &schema.ImmutableState{Db: "db2", TxId: 4, TxHash: []byte{4}})
+				require.NoError(t, err)
+
+				err = target.Import("uuid", blob)
+				require.ErrorIs(t, err, ErrStateRolledBack)
+
+				state, err := target.Get("uuid", "db2")
+				require.NoError(t, err)
+				require.EqualValues(t, 4, state.TxId)
+			})
+
+			t.Run("states differing from the cached ones should not be imported", func(t *testing.T) {
+				forged, err := marshalExportedStates(map[string][]*schema.ImmutableState{
+					"db2": {{Db: "db2", TxId: 4, TxHash: []byte{5}}},
+				})
+				require.NoError(t, err)
+
+				err = target.Import("uuid", forged)
+				require.ErrorIs(t, err, ErrUnexpectedPrevState)
+
+				state, err := target.Get("uuid", "db2")
+				require.NoError(t, err)
+				require.Equal(t, []byte{4}, state.TxHash)
+			})
+
+			t.Run("invalid blobs should not be imported", func(t *testing.T) {
+				err := target.Import("uuid", []byte("not a state"))
+				require.ErrorIs(t, err, ErrInvalidExport)
+
+				err = target.Import("uuid", []byte("db1:#"))
+				require.ErrorIs(t, err, ErrInvalidExport)
+			})
+
+			t.Run("servers without states should export an empty blob", func(t *testing.T) {
+				blob, err := target.Export("unknown")
+				require.NoError(t, err)
+				require.Empty(t, blob)
+
+				err = target.Import("unknown", blob)
+				require.NoError(t, err)
+			})
+		})
+	}
+}
+
+func TestHistoryFileCacheInvalidDatabaseName(t *testing.T) {
+	for _, maxStates := range []int{0, 3} {
+		t.Run(fmt.Sprintf("maxStates=%d", maxStates), func(t *testing.T) {
+			dir := t.TempDir()
+			fc := &historyFileCache{dir: dir, maxStates: maxStates}
+
+			err := fc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: 1, TxHash: []byte{1}})
+			require.NoError(t, err)
+
+			for _, db := range []string{"db:1", "db\n1", "db\n"} {
+				state := &schema.ImmutableState{Db: db, TxId: 2, TxHash: []byte{2}}
+
+				err := fc.Set("uuid", db, state)
+				require.ErrorIs(t, err, ErrInvalidDatabaseName)
+
+				err = fc.SetAll(map[string]*schema.ImmutableState{db: state}, "uuid")
+				require.ErrorIs(t, err, ErrInvalidDatabaseName)
+
+				_, err = fc.Get("uuid", db)
+				require.ErrorIs(t, err, ErrInvalidDatabaseName)
+
+				_, err = fc.Walk("uuid", db, func(s *schema.ImmutableState) interface{} { return nil })
+				require.ErrorIs(t, err, ErrInvalidDatabaseName)
+
+				_, err = fc.WalkReverse("uuid", db, func(s *schema.ImmutableState) interface{} { return nil })
+				require.ErrorIs(t, err, ErrInvalidDatabaseName)
+			}
+
+			// nothing was written for the invalid names
+			states, err := fc.latestStates("uuid")
+			require.NoError(t, err)
+			require.Len(t, states, 1)
+			require.EqualValues(t, 1, states["db"].TxId)
+
+			t.Run("colons should be allowed by the json codec", func(t *testing.T) {
+				fc := &historyFileCache{dir: dir, maxStates: maxStates, codec: NewJSONStateCodec()}
+
+				err := fc.Set("uuid", "db:1", &schema.ImmutableState{Db: "db:1", TxId: 2, TxHash: []byte{2}})
+				require.NoError(t, err)
+
+				err = fc.Set("uuid", "db\n1", &schema.ImmutableState{Db: "db\n1", TxId: 2, TxHash: []byte{2}})
+				require.ErrorIs(t, err, ErrInvalidDatabaseName)
+			})
+
+			t.Run("path-like names should be rejected when rotating states", func(t *testing.T) {
+				for _, db := range []string{"../db", "db/1", `db\1`, ".."} {
+					err := fc.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: 2, TxHash: []byte{2}})
+					if maxStates == 0 {
+						require.NoError(t, err)
+					} else {
+						require.ErrorIs(t, err, ErrInvalidDatabaseName)
+					}
+				}
+
+				entries, err := ioutil.ReadDir(dir)
+				require.NoError(t, err)
+				require.Len(t, entries, 1)
+			})
+		})
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/golang/protobuf/proto"
+)
+
+type historyMemCache struct {
+	states     map[string]map[string][]*schema.ImmutableState
+	identities map[string]string
+	lock       sync.RWMutex
+}
+
+var (
+	_ HistoryWalker = (*historyMemCache)(nil)
+	_ BatchSetter   = (*historyMemCache)(nil)
+	_ Preloader     = (*historyMemCache)(nil)
+	_ StateExporter = (*historyMemCache)(nil)
+)
+
+// NewHistoryMemCache returns a new in-memory history cache, it keeps every state stored for each database
+// thus it's meant to be used by tests and short lived clients not requiring states to be persisted
+func NewHistoryMemCache() HistoryCache {
+	return &historyMemCache{
+		states:     map[string]map[string][]*schema.ImmutableState{},
+		identities: map[string]string{},
+	}
+}
+
+func (hmc *historyMemCache) Get(serverUUID, db string) (*schema.ImmutableState, error) {
+	hmc.lock.RLock()
+	defer hmc.lock.RUnlock()
+
+	return hmc.get(serverUUID, db), nil
+}
+
+// get returns a copy of the latest state of the database, nil if there is none
+func (hmc *historyMemCache) get(serverUUID, db string) *schema.ImmutableState {
+	states := hmc.states[serverUUID][db]
+	if len(states) == 0 {
+		return nil
+	}
+
+	return proto.Clone(states[len(states)-1]).(*schema.ImmutableState)
+}
+
+func (hmc *historyMemCache) Set(serverUUID, db string, state *schema.ImmutableState) error {
+	if state == nil {
+		return proto.ErrNil
+	}
+
+	hmc.lock.Lock()
+	defer hmc.lock.Unlock()
+
+	hmc.set(serverUUID, db, state)
+
+	return nil
+}
+
+// set stores a copy of the state keeping the states of the database in tx order
+func (hmc *historyMemCache) set(serverUUID, db string, state *schema.ImmutableState) {
+	serverStates, ok := hmc.states[serverUUID]
+	if !ok {
+		serverStates = map[string][]*schema.ImmutableState{}
+		hmc.states[serverUUID] = serverStates
+	}
+
+	states := serverStates[db]
+
+	i := sort.Search(len(states), func(i int) bool {
+		return states[i].TxId > state.TxId
+	})
+
+	states = append(states, nil)
+	copy(states[i+1:], states[i:])
+	states[i] = proto.Clone(state).(*schema.ImmutableState)
+
+	serverStates[db] = states
+}
+
+// SetAll stores the states of multiple databases, no state is stored if any of them is older than the cached one
+func (hmc *historyMemCache) SetAll(states map[string]*schema.ImmutableState, serverUUID string) error {
+	hmc.lock.Lock()
+	defer hmc.lock.Unlock()
+
+	for db, state := range states {
+		if state == nil {
+			return proto.ErrNil
+		}
+
+		dbStates := hmc.states[serverUUID][db]
+		if len(dbStates) == 0 {
+			continue
+		}
+
+		prevState := dbStates[len(dbStates)-1]
+
+		if state.TxId < prevState.TxId {
+			return fmt.Errorf("%w: state %d of database %s precedes the cached state %d", ErrStateRolledBack, state.TxId, db, prevState.TxId)
+		}
+	}
+
+	for db, state := range states {
+		hmc.set(serverUUID, db, state)
+	}
+
+	return nil
+}
+
+func (hmc *historyMemCache) Walk(serverUUID string, db string, f func(*schema.ImmutableState) interface{}) ([]interface{}, error) {
+	states := hmc.dbStates(serverUUID, db)
+	if len(states) == 0 {
+		return nil, nil
+	}
+
+	results := make([]interface{}, 0, len(states))
+
+	for _, state := range states {
+		results = append(results, f(state))
+	}
+
+	return results, nil
+}
+
+// WalkReverse visits the states of the database from the newest to the oldest one. The walk is
+// stopped as soon as f returns ErrStopWalk, which is not included in the results
+func (hmc *historyMemCache) WalkReverse(serverUUID string, db string, f func(*schema.ImmutableState) interface{}) ([]interface{}, error) {
+	states := hmc.dbStates(serverUUID, db)
+	if len(states) == 0 {
+		return nil, nil
+	}
+
+	results := make([]interface{}, 0, len(states))
+
+	for i := len(states) - 1; i >= 0; i-- {
+		res := f(states[i])
+
+		if err, ok := res.(error); ok && errors.Is(err, ErrStopWalk) {
+			break
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// WalkBatch visits the states of the database in tx order, passing them to f in batches of up to batchSize states
+func (hmc *historyMemCache) WalkBatch(serverUUID string, db string, batchSize int, f func([]*schema.ImmutableState) error) error {
+	return walkBatch(hmc.dbStates(serverUUID, db), batchSize, f)
+}
+
+// dbStates returns a copy of the states of the database in tx order, thus f is called without holding the lock
+func (hmc *historyMemCache) dbStates(serverUUID string, db string) []*schema.ImmutableState {
+	hmc.lock.RLock()
+	defer hmc.lock.RUnlock()
+
+	states := hmc.states[serverUUID][db]
+
+	cloned := make([]*schema.ImmutableState, len(states))
+	for i, state := range states {
+		cloned[i] = proto.Clone(state).(*schema.ImmutableState)
+	}
+
+	return cloned
+}
+
+func (hmc *historyMemCache) WalkAllLatest(serverUUID string, f func(db string, latest *schema.ImmutableState) bool) ([]string, error) {
+	hmc.lock.RLock()
+
+	dbs := make([]string, 0, len(hmc.states[serverUUID]))
+	latest := make(map[string]*schema.ImmutableState, len(hmc.states[serverUUID]))
+
+	for db, states := range hmc.states[serverUUID] {
+		dbs = append(dbs, db)
+		latest[db] = proto.Clone(states[len(states)-1]).(*schema.ImmutableState)
+	}
+
+	hmc.lock.RUnlock()
+
+	sort.Strings(dbs)
+
+	var matches []string
+
+	for _, db := range dbs {
+		if f(db, latest[db]) {
+			matches = append(matches, db)
+		}
+	}
+
+	return matches, nil
+}
+
+// Export encodes every state stored for the server, the states of each database are exported in tx order
+func (hmc *historyMemCache) Export(serverUUID string) ([]byte, error) {
+	hmc.lock.RLock()
+	defer hmc.lock.RUnlock()
+
+	return marshalExportedStates(hmc.states[serverUUID])
+}
+
+// Import stores the states encoded by Export but the ones already stored, no state is stored if the newest
+// imported state of any database precedes the cached one
+func (hmc *historyMemCache) Import(serverUUID string, blob []byte) error {
+	imported, err := unmarshalExportedStates(blob)
+	if err != nil {
+		return err
+	}
+
+	hmc.lock.Lock()
+	defer hmc.lock.Unlock()
+
+	latest := make(map[string]*schema.ImmutableState, len(hmc.states[serverUUID]))
+
+	for db, states := range hmc.states[serverUUID] {
+		latest[db] = states[len(states)-1]
+	}
+
+	err = checkImportedStates(imported, latest)
+	if err != nil {
+		return err
+	}
+
+	for db, states := range imported {
+		stored := make(map[uint64]struct{}, len(hmc.states[serverUUID][db]))
+		for _, state := range hmc.states[serverUUID][db] {
+			stored[state.TxId] = struct{}{}
+		}
+
+		for _, state := range states {
+			// states already stored are not duplicated, so a blob can be imported more than once
+			if _, ok := stored[state.TxId]; !ok {
+				hmc.set(serverUUID, db, state)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Preload is a no-op as states are already kept in memory
+func (hmc *historyMemCache) Preload(serverUUID string) error {
+	return nil
+}
+
+func (hmc *historyMemCache) Lock(serverUUID string) (err error) {
+	return ErrNotImplemented
+}
+
+func (hmc *historyMemCache) Unlock() (err error) {
+	return ErrNotImplemented
+}
+
+func (hmc *historyMemCache) CompareAndSet(expectedPrev, new *schema.ImmutableState, serverUUID, db string) error {
+	hmc.lock.Lock()
+	defer hmc.lock.Unlock()
+
+	return compareAndSet(
+		func() (*schema.ImmutableState, error) { return hmc.get(serverUUID, db), nil },
+		func(state *schema.ImmutableState) error { hmc.set(serverUUID, db, state); return nil },
+		expectedPrev, new, db,
+	)
+}
+
+func (hmc *historyMemCache) ServerIdentityCheck(serverIdentity, serverUUID string) error {
+	hmc.lock.Lock()
+	defer hmc.lock.Unlock()
+
+	if previousUUID, ok := hmc.identities[serverIdentity]; ok {
+		// Server with this identity was seen before, ensure it did not change
+		if previousUUID != serverUUID {
+			return ErrServerIdentityValidationFailed
+		}
+		return nil
+	}
+
+	hmc.identities[serverIdentity] = serverUUID
+	return nil
+}
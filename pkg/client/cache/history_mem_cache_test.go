@@ -0,0 +1,228 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryMemCache(t *testing.T) {
+	hmc := NewHistoryMemCache()
+
+	state, err := hmc.Get("uuid", "db")
+	require.NoError(t, err)
+	require.Nil(t, state)
+
+	res, err := hmc.Walk("uuid", "db", func(state *schema.ImmutableState) interface{} {
+		return state.TxId
+	})
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	err = hmc.Set("uuid", "db", nil)
+	require.Error(t, err)
+
+	for _, txID := range []uint64{1, 10, 9, 100} {
+		err := hmc.Set("uuid", "db", &schema.ImmutableState{Db: "db", TxId: txID, TxHash: []byte{byte(txID)}})
+		require.NoError(t, err)
+	}
+
+	err = hmc.Set("uuid", "db2", &schema.ImmutableState{Db: "db2", TxId: 5, TxHash: []byte{5}})
+	require.NoError(t, err)
+
+	t.Run("the latest state should be returned", func(t *testing.T) {
+		state, err := hmc.Get("uuid", "db")
+		require.NoError(t, err)
+		require.EqualValues(t, 100, state.TxId)
+
+		// returned states are copies
+		state.TxId = 0
+
+		state, err = hmc.Get("uuid", "db")
+		require.NoError(t, err)
+		require.EqualValues(t, 100, state.TxId)
+
+		state, err = hmc.Get("other-uuid", "db")
+		require.NoError(t, err)
+		require.Nil(t, state)
+	})
+
+	t.Run("states should be walked in tx order", func(t *testing.T) {
+		res, err := hmc.Walk("uuid", "db", func(state *schema.ImmutableState) interface{} {
+			return state.TxId
+		})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{uint64(1), uint64(9), uint64(10), uint64(100)}, res)
+
+		res, err = hmc.(HistoryWalker).WalkReverse("uuid", "db", func(state *schema.ImmutableState) interface{} {
+			if state.TxId < 10 {
+				return ErrStopWalk
+			}
+			return state.TxId
+		})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{uint64(100), uint64(10)}, res)
+	})
+
+	t.Run("no state should be stored when one of them is older", func(t *testing.T) {
+		err := hmc.(BatchSetter).SetAll(map[string]*schema.ImmutableState{
+			"db":  {Db: "db", TxId: 50},
+			"db2": {Db: "db2", TxId: 6},
+		}, "uuid")
+		require.ErrorIs(t, err, ErrOlderState)
+
+		state, err := hmc.Get("uuid", "db2")
+		require.NoError(t, err)
+		require.EqualValues(t, 5, state.TxId)
+
+		err = hmc.(BatchSetter).SetAll(map[string]*schema.ImmutableState{
+			"db2": {Db: "db2", TxId: 6},
+			"db3": {Db: "db3", TxId: 1},
+		}, "uuid")
+		require.NoError(t, err)
+	})
+
+	t.Run("the latest state of each database should be walked", func(t *testing.T) {
+		var visited []string
+
+		matches, err := hmc.(HistoryWalker).WalkAllLatest("uuid", func(db string, latest *schema.ImmutableState) bool {
+			visited = append(visited, fmt.Sprintf("%s:%d", db, latest.TxId))
+			return latest.TxId > 1
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"db:100", "db2:6", "db3:1"}, visited)
+		require.Equal(t, []string{"db", "db2"}, matches)
+
+		require.NoError(t, hmc.(Preloader).Preload("uuid"))
+	})
+
+	t.Run("server identity should be checked", func(t *testing.T) {
+		err := hmc.ServerIdentityCheck("identity", "uuid")
+		require.NoError(t, err)
+
+		err = hmc.ServerIdentityCheck("identity", "uuid")
+		require.NoError(t, err)
+
+		err = hmc.ServerIdentityCheck("identity", "other-uuid")
+		require.ErrorIs(t, err, ErrServerIdentityValidationFailed)
+
+		require.ErrorIs(t, hmc.Lock("uuid"), ErrNotImplemented)
+		require.ErrorIs(t, hmc.Unlock(), ErrNotImplemented)
+	})
+
+	t.Run("states should be exported and imported", func(t *testing.T) {
+		hmc := NewHistoryMemCache()
+
+		for txID := uint64(1); txID <= 3; txID++ {
+			err := hmc.Set("uuid", "db1", &schema.ImmutableState{Db: "db1", TxId: txID})
+			require.NoError(t, err)
+		}
+
+		blob, err := hmc.(StateExporter).Export("uuid")
+		require.NoError(t, err)
+
+		imported := NewHistoryMemCache()
+
+		for i := 0; i < 2; i++ {
+			err = imported.(StateExporter).Import("uuid", blob)
+			require.NoError(t, err)
+		}
+
+		txIDs, err := imported.Walk("uuid", "db1", func(s *schema.ImmutableState) interface{} { return s.TxId })
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{uint64(1), uint64(2), uint64(3)}, txIDs)
+
+		err = imported.Set("uuid", "db1", &schema.ImmutableState{Db: "db1", TxId: 4})
+		require.NoError(t, err)
+
+		err = imported.(StateExporter).Import("uuid", blob)
+		require.ErrorIs(t, err, ErrStateRolledBack)
+	})
+
+	t.Run("database names with colons should be exported", func(t *testing.T) {
+		hmc := NewHistoryMemCache()
+
+		err := hmc.Set("uuid", "db:1", &schema.ImmutableState{Db: "db:1", TxId: 1})
+		require.NoError(t, err)
+
+		blob, err := hmc.(StateExporter).Export("uuid")
+		require.NoError(t, err)
+
+		imported := NewHistoryMemCache()
+
+		err = imported.(StateExporter).Import("uuid", blob)
+		require.NoError(t, err)
+
+		state, err := imported.Get("uuid", "db:1")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, state.TxId)
+	})
+
+	t.Run("states exported in the legacy format should be imported", func(t *testing.T) {
+		encoded, err := NewLegacyStateCodec().Marshal("db1", &schema.ImmutableState{Db: "db1", TxId: 1})
+		require.NoError(t, err)
+
+		imported := NewHistoryMemCache()
+
+		err = imported.(StateExporter).Import("uuid", append(encoded, '\n'))
+		require.NoError(t, err)
+
+		state, err := imported.Get("uuid", "db1")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, state.TxId)
+	})
+}
+
+func TestHistoryMemCacheConcurrency(t *testing.T) {
+	hmc := NewHistoryMemCache()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(db string) {
+			defer wg.Done()
+
+			for txID := uint64(1); txID <= 100; txID++ {
+				err := hmc.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: txID})
+				require.NoError(t, err)
+
+				_, err = hmc.Get("uuid", db)
+				require.NoError(t, err)
+
+				_, err = hmc.(HistoryWalker).WalkAllLatest("uuid", func(string, *schema.ImmutableState) bool { return true })
+				require.NoError(t, err)
+			}
+		}(fmt.Sprintf("db%d", i))
+	}
+
+	wg.Wait()
+
+	for i := 0; i < 8; i++ {
+		res, err := hmc.Walk("uuid", fmt.Sprintf("db%d", i), func(state *schema.ImmutableState) interface{} {
+			return state.TxId
+		})
+		require.NoError(t, err)
+		require.Len(t, res, 100)
+	}
+}
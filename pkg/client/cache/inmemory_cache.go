@@ -39,14 +39,21 @@ func NewInMemoryCache() Cache {
 }
 
 func (imc *inMemoryCache) Get(serverUUID, db string) (*schema.ImmutableState, error) {
+	imc.lock.RLock()
+	defer imc.lock.RUnlock()
+
+	return imc.get(serverUUID, db)
+}
+
+func (imc *inMemoryCache) get(serverUUID, db string) (*schema.ImmutableState, error) {
 	serverStates, ok := imc.states[serverUUID]
 	if !ok {
-		return nil, fmt.Errorf("no roots found for server %s", serverUUID)
+		return nil, fmt.Errorf("%w: no roots found for server %s", ErrPrevStateNotFound, serverUUID)
 	}
 	state, ok := serverStates[db]
 	if !ok {
 		return nil, fmt.Errorf(
-			"no state found for server %s and database %s", serverUUID, db)
+			"%w: no state found for server %s and database %s", ErrPrevStateNotFound, serverUUID, db)
 	}
 	return state, nil
 }
@@ -54,12 +61,28 @@ func (imc *inMemoryCache) Get(serverUUID, db string) (*schema.ImmutableState, er
 func (imc *inMemoryCache) Set(serverUUID, db string, state *schema.ImmutableState) error {
 	imc.lock.Lock()
 	defer imc.lock.Unlock()
+
+	imc.set(serverUUID, db, state)
+	return nil
+}
+
+func (imc *inMemoryCache) set(serverUUID, db string, state *schema.ImmutableState) {
 	if _, ok := imc.states[serverUUID]; !ok {
 		imc.states[serverUUID] = map[string]*schema.ImmutableState{db: state}
-		return nil
+		return
 	}
 	imc.states[serverUUID][db] = state
-	return nil
+}
+
+func (imc *inMemoryCache) CompareAndSet(expectedPrev, new *schema.ImmutableState, serverUUID, db string) error {
+	imc.lock.Lock()
+	defer imc.lock.Unlock()
+
+	return compareAndSet(
+		func() (*schema.ImmutableState, error) { return imc.get(serverUUID, db) },
+		func(state *schema.ImmutableState) error { imc.set(serverUUID, db, state); return nil },
+		expectedPrev, new, db,
+	)
 }
 
 func (imc *inMemoryCache) Lock(serverUUID string) (err error) {
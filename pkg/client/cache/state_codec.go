@@ -0,0 +1,144 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// StateCodec encodes the states kept in the state files of the history file cache, one state per line
+type StateCodec interface {
+	// Marshal encodes the state of the database, the encoding must not contain newlines
+	Marshal(db string, state *schema.ImmutableState) ([]byte, error)
+	// Unmarshal decodes a state encoded by Marshal together with the name of its database. The name of the
+	// database is returned along with the error when only the state could not be decoded
+	Unmarshal(data []byte) (db string, state *schema.ImmutableState, err error)
+}
+
+// legacyStateCodec encodes states as the name of the database followed by a colon and the base64 encoded state,
+// thus database names containing colons can not be decoded
+type legacyStateCodec struct{}
+
+// NewLegacyStateCodec returns the codec of the `db:base64(state)` lines state files were originally written with
+func NewLegacyStateCodec() StateCodec {
+	return legacyStateCodec{}
+}
+
+func (legacyStateCodec) Marshal(db string, state *schema.ImmutableState) ([]byte, error) {
+	raw, err := proto.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(db + ":" + base64.StdEncoding.EncodeToString(raw)), nil
+}
+
+func (legacyStateCodec) Unmarshal(data []byte) (string, *schema.ImmutableState, error) {
+	line := strings.TrimSpace(string(data))
+
+	sep := strings.Index(line, ":")
+	if sep <= 0 {
+		return "", nil, ErrInvalidStateLine
+	}
+
+	db := line[:sep]
+
+	raw, err := base64.StdEncoding.DecodeString(line[sep+1:])
+	if err != nil || len(raw) == 0 {
+		return db, nil, ErrPrevStateNotFound
+	}
+
+	state := &schema.ImmutableState{}
+
+	err = proto.Unmarshal(raw, state)
+	if err != nil {
+		return db, nil, err
+	}
+
+	return db, state, nil
+}
+
+// jsonStateCodec encodes states as JSON objects holding the name of the database and the state
+type jsonStateCodec struct{}
+
+type jsonStateLine struct {
+	DB    string          `json:"db"`
+	State json.RawMessage `json:"state"`
+}
+
+// NewJSONStateCodec returns a codec storing the name of the database and the state as fields of a JSON object,
+// so any database name is preserved
+func NewJSONStateCodec() StateCodec {
+	return jsonStateCodec{}
+}
+
+func (jsonStateCodec) Marshal(db string, state *schema.ImmutableState) ([]byte, error) {
+	if state == nil {
+		return nil, proto.ErrNil
+	}
+
+	rawState, err := protojson.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&jsonStateLine{DB: db, State: rawState})
+}
+
+func (jsonStateCodec) Unmarshal(data []byte) (string, *schema.ImmutableState, error) {
+	var line jsonStateLine
+
+	err := json.Unmarshal(data, &line)
+	if err != nil || line.DB == "" {
+		return "", nil, ErrInvalidStateLine
+	}
+
+	if len(line.State) == 0 || string(line.State) == "null" {
+		return line.DB, nil, ErrPrevStateNotFound
+	}
+
+	state := &schema.ImmutableState{}
+
+	err = protojson.Unmarshal(line.State, state)
+	if err != nil {
+		return line.DB, nil, err
+	}
+
+	return line.DB, state, nil
+}
+
+// decodeStateLine decodes the state line with the codec, legacy lines are decoded as such so state files
+// written before switching to another codec are still readable. JSON objects are never legacy lines, thus
+// the ones which could not be decoded are not parsed again as `db:state` lines.
+func decodeStateLine(codec StateCodec, line string) (string, *schema.ImmutableState, error) {
+	db, state, err := codec.Unmarshal([]byte(line))
+	if err == nil || db != "" {
+		return db, state, err
+	}
+
+	if _, legacy := codec.(legacyStateCodec); legacy || strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return db, state, err
+	}
+
+	return legacyStateCodec{}.Unmarshal([]byte(line))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateCodecs(t *testing.T) {
+	state := &schema.ImmutableState{Db: "db", TxId: 10, TxHash: []byte{1, 2, 3}, Signature: &schema.Signature{PublicKey: []byte{4}, Signature: []byte{5}}}
+
+	for _, codec := range []StateCodec{NewLegacyStateCodec(), NewJSONStateCodec()} {
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			encoded, err := codec.Marshal("db", state)
+			require.NoError(t, err)
+			require.NotContains(t, string(encoded), "\n")
+
+			db, decoded, err := codec.Unmarshal(encoded)
+			require.NoError(t, err)
+			require.Equal(t, "db", db)
+			require.Equal(t, state.TxId, decoded.TxId)
+			require.Equal(t, state.TxHash, decoded.TxHash)
+			require.Equal(t, state.Signature.Signature, decoded.Signature.Signature)
+
+			_, err = codec.Marshal("db", nil)
+			require.Error(t, err)
+
+			_, _, err = codec.Unmarshal([]byte("invalid"))
+			require.ErrorIs(t, err, ErrInvalidStateLine)
+		})
+	}
+
+	t.Run("database names with colons should round-trip with the json codec", func(t *testing.T) {
+		encoded, err := NewJSONStateCodec().Marshal("db:with:colons", state)
+		require.NoError(t, err)
+
+		db, decoded, err := NewJSONStateCodec().Unmarshal(encoded)
+		require.NoError(t, err)
+		require.Equal(t, "db:with:colons", db)
+		require.Equal(t, state.TxId, decoded.TxId)
+	})
+
+	t.Run("legacy lines should be decoded when using another codec", func(t *testing.T) {
+		encoded, err := NewLegacyStateCodec().Marshal("db", state)
+		require.NoError(t, err)
+
+		db, decoded, err := decodeStateLine(NewJSONStateCodec(), string(encoded))
+		require.NoError(t, err)
+		require.Equal(t, "db", db)
+		require.Equal(t, state.TxId, decoded.TxId)
+	})
+
+	t.Run("json lines should not be decoded as legacy lines", func(t *testing.T) {
+		db, _, err := decodeStateLine(NewJSONStateCodec(), `{"db":"","state":{}}`)
+		require.ErrorIs(t, err, ErrInvalidStateLine)
+		require.Empty(t, db)
+	})
+
+	t.Run("the database of undecodable states should be returned", func(t *testing.T) {
+		db, _, err := NewJSONStateCodec().Unmarshal([]byte(`{"db":"db","state":{"txId":"x"}}`))
+		require.Error(t, err)
+		require.Equal(t, "db", db)
+
+		db, _, err = NewLegacyStateCodec().Unmarshal([]byte("db:"))
+		require.ErrorIs(t, err, ErrPrevStateNotFound)
+		require.Equal(t, "db", db)
+	})
+}
+
+func TestHistoryFileCacheWithJSONCodec(t *testing.T) {
+	for _, maxStates := range []int{0, 3} {
+		t.Run(fmt.Sprintf("maxStates=%d", maxStates), func(t *testing.T) {
+			dir := t.TempDir()
+
+			legacy := NewHistoryFileCacheWithCodec(dir, maxStates, NewLegacyStateCodec())
+
+			err := legacy.Set("uuid", "db1", &schema.ImmutableState{Db: "db1", TxId: 1, TxHash: []byte{1}})
+			require.NoError(t, err)
+
+			fc := NewHistoryFileCacheWithCodec(dir, maxStates, NewJSONStateCodec())
+
+			t.Run("states written in the legacy format should be read", func(t *testing.T) {
+				state, err := fc.Get("uuid", "db1")
+				require.NoError(t, err)
+				require.EqualValues(t, 1, state.TxId)
+			})
+
+			for txID := uint64(2); txID <= 3; txID++ {
+				for _, db := range []string{"db1", "db:with:colons"} {
+					err := fc.Set("uuid", db, &schema.ImmutableState{Db: db, TxId: txID, TxHash: []byte{byte(txID)}})
+					require.NoError(t, err)
+				}
+			}
+
+			for _, db := range []string{"db1", "db:with:colons"} {
+				state, err := fc.Get("uuid", db)
+				require.NoError(t, err)
+				require.Equal(t, db, state.Db)
+				require.EqualValues(t, 3, state.TxId)
+			}
+
+			latest, err := fc.(HistoryWalker).WalkAllLatest("uuid", func(db string, latest *schema.ImmutableState) bool {
+				return latest.TxId == 3
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{"db1", "db:with:colons"}, latest)
+
+			if maxStates == 0 {
+				content, err := ioutil.ReadFile(filepath.Join(dir, "uuid", ".state"))
+				require.NoError(t, err)
+
+				// states are migrated to the json codec when rewritten
+				for _, line := range strings.Split(string(content), "\n") {
+					if line != "" {
+						require.True(t, strings.HasPrefix(line, "{"))
+					}
+				}
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/sha256"
@@ -1112,6 +1113,137 @@ func (c *immuClient) verifyDualProof(
 	return nil
 }
 
+// verifyReferencedEntry verifies the entry a reference resolves to is included in the transaction it was set at,
+// the bound transaction of bound references, and such transaction is consistent with the already verified
+// transaction the reference was set at. The rename links followed from the referenced key to the key of the
+// entry are verified the same way.
+func (c *immuClient) verifyReferencedEntry(ctx context.Context, vEntry *schema.VerifiableEntry, refTxID uint64, refTxAlh [sha256.Size]byte) error {
+	proof := vEntry.ReferencedEntryProof
+	if proof == nil || proof.InclusionProof == nil || proof.DualProof == nil {
+		return store.ErrCorruptedData
+	}
+
+	entry := vEntry.Entry
+
+	if entry.ReferencedBy.AtTx > 0 && entry.Tx != entry.ReferencedBy.AtTx {
+		return store.ErrCorruptedData
+	}
+
+	key := referencedKey(entry)
+
+	for _, linkProof := range proof.RenameLinkProofs {
+		if linkProof.InclusionProof == nil || linkProof.DualProof == nil {
+			return store.ErrCorruptedData
+		}
+
+		err := c.verifyLinkedEntry(
+			ctx,
+			database.EncodeRenameLinkEntrySpec(key, linkProof.Key),
+			linkProof.Tx,
+			linkProof.InclusionProof,
+			linkProof.DualProof,
+			refTxID,
+			refTxAlh,
+		)
+		if err != nil {
+			return err
+		}
+
+		key = linkProof.Key
+	}
+
+	if !bytes.Equal(key, entry.Key) {
+		return store.ErrCorruptedData
+	}
+
+	return c.verifyLinkedEntry(
+		ctx,
+		database.EncodeEntrySpec(entry.Key, schema.KVMetadataFromProto(entry.Metadata), entry.Value),
+		entry.Tx,
+		proof.InclusionProof,
+		proof.DualProof,
+		refTxID,
+		refTxAlh,
+	)
+}
+
+// verifyLinkedEntry verifies the entry is included in the transaction txID and such transaction is consistent
+// with the already verified transaction refTxID
+func (c *immuClient) verifyLinkedEntry(
+	ctx context.Context,
+	e *store.EntrySpec,
+	txID uint64,
+	inclusionProof *schema.InclusionProof,
+	dualProofProto *schema.DualProof,
+	refTxID uint64,
+	refTxAlh [sha256.Size]byte,
+) error {
+	dualProof := schema.DualProofFromProto(dualProofProto)
+
+	var txHdr *store.TxHeader
+
+	var sourceID, targetID uint64
+	var sourceAlh, targetAlh [sha256.Size]byte
+
+	if txID <= refTxID {
+		txHdr = dualProof.SourceTxHeader
+
+		sourceID, sourceAlh = txID, txHdr.Alh()
+		targetID, targetAlh = refTxID, refTxAlh
+	} else {
+		txHdr = dualProof.TargetTxHeader
+
+		sourceID, sourceAlh = refTxID, refTxAlh
+		targetID, targetAlh = txID, txHdr.Alh()
+	}
+
+	if txHdr.ID != txID {
+		return store.ErrCorruptedData
+	}
+
+	entrySpecDigest, err := store.EntrySpecDigestFor(txHdr.Version)
+	if err != nil {
+		return err
+	}
+
+	verifies := store.VerifyInclusion(
+		schema.InclusionProofFromProto(inclusionProof),
+		entrySpecDigest(e),
+		txHdr.Eh,
+	)
+	if !verifies {
+		return store.ErrCorruptedData
+	}
+
+	return c.verifyDualProof(ctx, dualProof, sourceID, targetID, sourceAlh, targetAlh)
+}
+
+// expiredEntryDigest returns the digest of an expired entry as included in a transaction of the given version,
+// it's computed from the hash of the value as the value of expired entries is not returned
+func expiredEntryDigest(key []byte, entry *schema.Entry, txHdrVersion int) ([sha256.Size]byte, error) {
+	md := schema.KVMetadataFromProto(entry.Metadata)
+	if md == nil || !md.IsExpirable() || len(entry.ValueHash) != sha256.Size {
+		return [sha256.Size]byte{}, store.ErrCorruptedData
+	}
+
+	txEntryDigest, err := (&store.TxHeader{Version: txHdrVersion}).TxEntryDigest()
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return txEntryDigest(store.NewTxEntry(database.EncodeKey(key), md, 0, schema.DigestFromProto(entry.ValueHash), 0))
+}
+
+// referencedKey returns the key the reference of the entry points to, which differs from the key of the
+// entry when the referenced key was renamed. Servers not reporting it only resolve to the referenced key.
+func referencedKey(entry *schema.Entry) []byte {
+	if len(entry.ReferencedBy.ReferencedKey) > 0 {
+		return entry.ReferencedBy.ReferencedKey
+	}
+
+	return entry.Key
+}
+
 func (c *immuClient) verifiedGet(ctx context.Context, kReq *schema.KeyRequest) (vi *schema.Entry, err error) {
 	err = c.StateService.CacheLock()
 	if err != nil {
@@ -1129,8 +1261,9 @@ func (c *immuClient) verifiedGet(ctx context.Context, kReq *schema.KeyRequest) (
 	}
 
 	req := &schema.VerifiableGetRequest{
-		KeyRequest:   kReq,
-		ProveSinceTx: state.TxId,
+		KeyRequest:           kReq,
+		ProveSinceTx:         state.TxId,
+		ProveReferencedEntry: c.Options.VerifyReferencedEntries,
 	}
 
 	vEntry, err := c.ServiceClient.VerifiableGet(ctx, req)
@@ -1167,7 +1300,7 @@ func (c *immuClient) verifiedGet(ctx context.Context, kReq *schema.KeyRequest) (
 			vTx = ref.Tx
 		}
 
-		e = database.EncodeReference(kReq.Key, schema.KVMetadataFromProto(ref.Metadata), vEntry.Entry.Key, ref.AtTx)
+		e = database.EncodeReferenceWithAttributes(kReq.Key, schema.KVMetadataFromProto(ref.Metadata), referencedKey(vEntry.Entry), ref.AtTx, database.ReferenceAttributesFromProto(ref))
 	}
 
 	if state.TxId <= vTx {
@@ -1186,9 +1319,19 @@ func (c *immuClient) verifiedGet(ctx context.Context, kReq *schema.KeyRequest) (
 		targetAlh = schema.DigestFromProto(state.TxHash)
 	}
 
+	entryDigest := entrySpecDigest(e)
+
+	if vEntry.Entry.Expired && vEntry.Entry.ReferencedBy == nil {
+		// the value of expired entries is not returned but its hash
+		entryDigest, err = expiredEntryDigest(kReq.Key, vEntry.Entry, int(vEntry.VerifiableTx.Tx.Header.Version))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	verifies := store.VerifyInclusion(
 		inclusionProof,
-		entrySpecDigest(e),
+		entryDigest,
 		eh)
 	if !verifies {
 		return nil, store.ErrCorruptedData
@@ -1208,6 +1351,18 @@ func (c *immuClient) verifiedGet(ctx context.Context, kReq *schema.KeyRequest) (
 		}
 	}
 
+	if vEntry.Entry.ReferencedBy != nil && c.Options.VerifyReferencedEntries {
+		vTxAlh := targetAlh
+		if state.TxId > vTx {
+			vTxAlh = sourceAlh
+		}
+
+		err := c.verifyReferencedEntry(ctx, vEntry, vTx, vTxAlh)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	newState := &schema.ImmutableState{
 		Db:        c.currentDatabase(),
 		TxId:      targetID,
@@ -62,6 +62,8 @@ type Options struct {
 	HeartBeatFrequency time.Duration // Duration between two consecutive heartbeat calls to the server for session heartbeats
 
 	DisableIdentityCheck bool // Do not validate server's identity
+
+	VerifyReferencedEntries bool // Verify the entries references resolve to when doing verified reads
 }
 
 // DefaultOptions ...
@@ -240,6 +242,16 @@ func (o *Options) WithDisableIdentityCheck(disableIdentityCheck bool) *Options {
 	return o
 }
 
+// WithVerifyReferencedEntries enables or disables the verification of the entries references resolve to.
+//
+// Verified reads of a reference always verify the reference itself. When enabled, the server is also asked
+// to prove the entry the reference resolves to, and the read fails if such proof is missing or invalid.
+// Servers not supporting such proofs can't be used with this option enabled.
+func (o *Options) WithVerifyReferencedEntries(verifyReferencedEntries bool) *Options {
+	o.VerifyReferencedEntries = verifyReferencedEntries
+	return o
+}
+
 // String converts options object to a json string
 func (o *Options) String() string {
 	optionsJSON, err := json.Marshal(o)
@@ -46,7 +46,8 @@ func TestOptions(t *testing.T) {
 		WithPassword("some-password").
 		WithDatabase("some-db").
 		WithStreamChunkSize(4096).
-		WithDisableIdentityCheck(true)
+		WithDisableIdentityCheck(true).
+		WithVerifyReferencedEntries(true)
 
 	require.Equal(t, op.LogFileName, "logfilename")
 	require.Equal(t, op.PidPath, "pidpath")
@@ -69,6 +70,7 @@ func TestOptions(t *testing.T) {
 	require.Equal(t, op.Database, "some-db")
 	require.Equal(t, op.StreamChunkSize, 4096)
 	require.True(t, op.DisableIdentityCheck)
+	require.True(t, op.VerifyReferencedEntries)
 	require.Equal(t, op.Bind(), "127.0.0.1:4321")
 	require.NotEmpty(t, op.String())
 
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/logger"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/client/cache"
+	"github.com/codenotary/immudb/pkg/client/state"
+	"github.com/codenotary/immudb/pkg/database"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// dbServiceClientMock serves verifiable reads straight from a database
+type dbServiceClientMock struct {
+	schema.ImmuServiceClient
+
+	db database.DB
+
+	PostVerifiableGetF func(*schema.VerifiableGetRequest, *schema.VerifiableEntry) *schema.VerifiableEntry
+}
+
+func (m *dbServiceClientMock) CurrentState(ctx context.Context, _ *empty.Empty, opts ...grpc.CallOption) (*schema.ImmutableState, error) {
+	return m.db.CurrentState()
+}
+
+func (m *dbServiceClientMock) VerifiableGet(ctx context.Context, in *schema.VerifiableGetRequest, opts ...grpc.CallOption) (*schema.VerifiableEntry, error) {
+	vEntry, err := m.db.VerifiableGet(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.PostVerifiableGetF != nil {
+		return m.PostVerifiableGetF(in, vEntry), nil
+	}
+
+	return vEntry, nil
+}
+
+func setupDBClient(t *testing.T, opts *Options) (*immuClient, *dbServiceClientMock) {
+	log := logger.NewSimpleLogger("client_test", os.Stderr)
+
+	db, err := database.NewDB("db", nil, database.DefaultOption().WithDBRootPath(t.TempDir()), log)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	serviceClient := &dbServiceClientMock{db: db}
+
+	conn, err := grpc.Dial("passthrough:///db", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	stateService, err := state.NewStateServiceWithUUID(cache.NewFileCache(t.TempDir()), log, state.NewStateProvider(serviceClient), "uuid")
+	require.NoError(t, err)
+
+	c := NewClient().WithOptions(opts.WithDir(t.TempDir()))
+	c.ServiceClient = serviceClient
+	c.clientConn = conn
+	c.StateService = stateService
+
+	return c, serviceClient
+}
+
+func TestImmuClient_VerifiedGetRenamedReference(t *testing.T) {
+	c, serviceClient := setupDBClient(t, DefaultOptions().WithVerifyReferencedEntries(true))
+
+	ctx := context.Background()
+
+	_, err := serviceClient.db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k1"), Value: []byte("v1")}}})
+	require.NoError(t, err)
+
+	_, err = serviceClient.db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("k1")})
+	require.NoError(t, err)
+
+	_, err = serviceClient.db.RenameKey(ctx, []byte("k1"), []byte("k2"))
+	require.NoError(t, err)
+
+	_, err = serviceClient.db.RenameKey(ctx, []byte("k2"), []byte("k3"))
+	require.NoError(t, err)
+
+	entry, err := c.VerifiedGet(ctx, []byte("ref"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("k3"), entry.Key)
+	require.Equal(t, []byte("v1"), entry.Value)
+
+	t.Run("a rename link not proven to lead to the entry should be rejected", func(t *testing.T) {
+		serviceClient.PostVerifiableGetF = func(_ *schema.VerifiableGetRequest, vEntry *schema.VerifiableEntry) *schema.VerifiableEntry {
+			vEntry.ReferencedEntryProof.RenameLinkProofs = vEntry.ReferencedEntryProof.RenameLinkProofs[1:]
+			return vEntry
+		}
+		defer func() { serviceClient.PostVerifiableGetF = nil }()
+
+		_, err := c.VerifiedGet(ctx, []byte("ref"))
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+	})
+}
+
+func TestImmuClient_VerifiedGetReferenceWithoutReferencedEntryProof(t *testing.T) {
+	c, serviceClient := setupDBClient(t, DefaultOptions())
+
+	ctx := context.Background()
+
+	_, err := serviceClient.db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("val1")}}})
+	require.NoError(t, err)
+
+	_, err = serviceClient.db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref1"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	// servers not supporting referenced entry proofs ignore the request of them
+	serviceClient.PostVerifiableGetF = func(req *schema.VerifiableGetRequest, vEntry *schema.VerifiableEntry) *schema.VerifiableEntry {
+		require.False(t, req.ProveReferencedEntry)
+
+		vEntry.ReferencedEntryProof = nil
+		return vEntry
+	}
+
+	entry, err := c.VerifiedGet(ctx, []byte("ref1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("key1"), entry.Key)
+	require.Equal(t, []byte("val1"), entry.Value)
+
+	t.Run("a missing proof should be rejected when referenced entries are verified", func(t *testing.T) {
+		c.Options.VerifyReferencedEntries = true
+		defer func() { c.Options.VerifyReferencedEntries = false }()
+
+		serviceClient.PostVerifiableGetF = func(_ *schema.VerifiableGetRequest, vEntry *schema.VerifiableEntry) *schema.VerifiableEntry {
+			vEntry.ReferencedEntryProof = nil
+			return vEntry
+		}
+
+		_, err := c.VerifiedGet(ctx, []byte("ref1"))
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+	})
+}
+
+func TestImmuClient_VerifiedGetExpiredEntry(t *testing.T) {
+	c, serviceClient := setupDBClient(t, DefaultOptions())
+
+	ctx := context.Background()
+
+	_, err := serviceClient.db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("val1")}}})
+	require.NoError(t, err)
+
+	hdr, err := serviceClient.db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("ref1"),
+		ReferencedKey: []byte("key1"),
+		ExpiresAt:     time.Now().Add(-time.Second).Unix(),
+	})
+	require.NoError(t, err)
+
+	entry, err := c.VerifiedGet(ctx, []byte("ref1"))
+	require.NoError(t, err)
+	require.True(t, entry.Expired)
+	require.Equal(t, hdr.Id, entry.Tx)
+	require.Empty(t, entry.Value)
+
+	entry, err = c.VerifiedGetAt(ctx, []byte("ref1"), hdr.Id)
+	require.NoError(t, err)
+	require.True(t, entry.Expired)
+
+	t.Run("a tampered value hash should be rejected", func(t *testing.T) {
+		serviceClient.PostVerifiableGetF = func(_ *schema.VerifiableGetRequest, vEntry *schema.VerifiableEntry) *schema.VerifiableEntry {
+			vEntry.Entry.ValueHash[0]++
+			return vEntry
+		}
+		defer func() { serviceClient.PostVerifiableGetF = nil }()
+
+		_, err := c.VerifiedGet(ctx, []byte("ref1"))
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+	})
+
+	t.Run("entries without expiration should not be reported as expired", func(t *testing.T) {
+		serviceClient.PostVerifiableGetF = func(_ *schema.VerifiableGetRequest, vEntry *schema.VerifiableEntry) *schema.VerifiableEntry {
+			vEntry.Entry.Metadata = nil
+			return vEntry
+		}
+		defer func() { serviceClient.PostVerifiableGetF = nil }()
+
+		_, err := c.VerifiedGet(ctx, []byte("ref1"))
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+	})
+}
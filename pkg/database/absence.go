@@ -0,0 +1,127 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// AbsenceCheck holds the state of a key as of a transaction.
+//
+// It is not a proof of absence, which is asserted by the server as non-inclusion proofs are not available.
+// When the key has a live entry, such entry is returned along with its inclusion check. Otherwise, the
+// latest entry of the key is returned with its inclusion proof when it was deleted or has expired, and
+// the dual proof links the transaction with the latest committed one.
+type AbsenceCheck struct {
+	// AtTx is the transaction as of which the key was checked
+	AtTx uint64
+
+	// Entry is the live entry of the key, nil when the key is absent
+	Entry *schema.VerifiableEntry
+
+	// Tombstone is the latest entry of an absent key, nil when the key was never set
+	Tombstone *schema.VerifiableEntry
+
+	// DualProof links the transaction with the latest committed one, nil when the key is present
+	DualProof *schema.DualProof
+}
+
+// CheckAbsent returns the verifiable entry of the key as of the transaction, or the outcome of the
+// absence check when the key has no live entry. The latest committed transaction is used when atTx is 0.
+func (d *db) CheckAbsent(ctx context.Context, key []byte, atTx uint64) (*AbsenceCheck, error) {
+	if len(key) == 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	currTxID, _ := d.st.CommittedAlh()
+
+	if atTx > currTxID {
+		return nil, fmt.Errorf("%w: atTx must not be greater than the current transaction ID", ErrIllegalArguments)
+	}
+
+	if atTx == 0 {
+		atTx = currTxID
+	}
+
+	err := d.WaitForIndexingUpto(ctx, atTx)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &AbsenceCheck{AtTx: atTx}
+
+	storedKey := d.storedKey(key)
+
+	valRef, err := d.st.GetBetween(ctx, EncodeKey(storedKey), 1, atTx)
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	if err == nil {
+		md := valRef.KVMetadata()
+
+		if md == nil || (!md.Deleted() && !md.ExpiredAt(time.Now())) {
+			check.Entry, err = d.VerifiableGet(ctx, &schema.VerifiableGetRequest{
+				KeyRequest:   &schema.KeyRequest{Key: key, AtTx: valRef.Tx()},
+				ProveSinceTx: atTx,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return check, nil
+		}
+
+		tombstone := &schema.Entry{
+			Key:      storedKey,
+			Tx:       valRef.Tx(),
+			Metadata: schema.KVMetadataToProto(md),
+			Revision: valRef.HC(),
+			Expired:  md.ExpiredAt(time.Now()),
+		}
+
+		check.Tombstone, err = d.verifiableEntry(tombstone, valRef.Tx(), storedKey, atTx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sourceTxHdr, err := d.st.ReadTxHeader(atTx, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	targetTxHdr, err := d.st.ReadTxHeader(currTxID, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	dualProof, err := d.st.DualProof(sourceTxHdr, targetTxHdr)
+	if err != nil {
+		return nil, err
+	}
+
+	check.DualProof = schema.DualProofToProto(dualProof)
+
+	return check, nil
+}
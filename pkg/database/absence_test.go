@@ -0,0 +1,125 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAbsent(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.CheckAbsent(ctx, nil, 0)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("tag:v1"), Value: []byte("sha1")},
+		{Key: []byte("tag:v2"), Value: []byte("sha2")},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.CheckAbsent(ctx, []byte("tag:v1"), hdr.Id+1)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	requireValidInclusion := func(t *testing.T, vEntry *schema.VerifiableEntry, e *store.EntrySpec) {
+		tx := schema.TxFromProto(vEntry.VerifiableTx.Tx)
+
+		entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+		require.NoError(t, err)
+
+		verifies := store.VerifyInclusion(
+			schema.InclusionProofFromProto(vEntry.InclusionProof),
+			entrySpecDigest(e),
+			tx.Header().Eh,
+		)
+		require.True(t, verifies)
+	}
+
+	requireValidDualProof := func(t *testing.T, check *AbsenceCheck) {
+		require.NotNil(t, check.DualProof)
+
+		dualProof := schema.DualProofFromProto(check.DualProof)
+		require.Equal(t, check.AtTx, dualProof.SourceTxHeader.ID)
+
+		verifies := store.VerifyDualProof(
+			dualProof,
+			check.AtTx,
+			dualProof.TargetTxHeader.ID,
+			dualProof.SourceTxHeader.Alh(),
+			dualProof.TargetTxHeader.Alh(),
+		)
+		require.True(t, verifies)
+	}
+
+	t.Run("existing tag should be proven", func(t *testing.T) {
+		check, err := db.CheckAbsent(ctx, []byte("tag:v1"), 0)
+		require.NoError(t, err)
+		require.Equal(t, hdr.Id, check.AtTx)
+		require.Nil(t, check.Tombstone)
+		require.Nil(t, check.DualProof)
+		require.Equal(t, []byte("sha1"), check.Entry.Entry.Value)
+
+		requireValidInclusion(t, check.Entry, EncodeEntrySpec([]byte("tag:v1"), nil, []byte("sha1")))
+	})
+
+	t.Run("missing tag should be reported absent", func(t *testing.T) {
+		check, err := db.CheckAbsent(ctx, []byte("tag:v3"), 0)
+		require.NoError(t, err)
+		require.Nil(t, check.Entry)
+		require.Nil(t, check.Tombstone)
+
+		requireValidDualProof(t, check)
+	})
+
+	delHdr, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("tag:v2")}})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("tag:v3"), Value: []byte("sha3")}}})
+	require.NoError(t, err)
+
+	t.Run("deleted tag should be reported absent along with its tombstone", func(t *testing.T) {
+		check, err := db.CheckAbsent(ctx, []byte("tag:v2"), 0)
+		require.NoError(t, err)
+		require.Nil(t, check.Entry)
+		require.Equal(t, delHdr.Id, check.Tombstone.Entry.Tx)
+		require.True(t, check.Tombstone.Entry.Metadata.Deleted)
+
+		md := schema.KVMetadataFromProto(check.Tombstone.Entry.Metadata)
+		requireValidInclusion(t, check.Tombstone, &store.EntrySpec{Key: EncodeKey([]byte("tag:v2")), Metadata: md})
+
+		requireValidDualProof(t, check)
+	})
+
+	t.Run("absence should be checked as of the transaction", func(t *testing.T) {
+		check, err := db.CheckAbsent(ctx, []byte("tag:v3"), delHdr.Id)
+		require.NoError(t, err)
+		require.Nil(t, check.Entry)
+
+		requireValidDualProof(t, check)
+
+		check, err = db.CheckAbsent(ctx, []byte("tag:v2"), hdr.Id)
+		require.NoError(t, err)
+		require.Equal(t, []byte("sha2"), check.Entry.Entry.Value)
+	})
+}
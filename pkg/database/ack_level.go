@@ -0,0 +1,175 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// AckLevel determines when write operations return to the caller in a primary database.
+// Replicas only acknowledge transactions when synchronous replication is enabled,
+// as they don't report their state to the primary otherwise.
+type AckLevel int
+
+const (
+	// AckLevelLocal returns as soon as the transaction is committed in the primary
+	AckLevelLocal AckLevel = iota
+	// AckLevelQuorum waits until a majority of the known replicas acknowledged the transaction
+	AckLevelQuorum
+	// AckLevelAll waits until every known replica acknowledged the transaction
+	AckLevelAll
+)
+
+const DefaultReplicationAckTimeout = 10 * time.Second
+
+var ErrReplicationTimeout = errors.New("timeout waiting for replication acknowledgment")
+var ErrNoKnownReplicas = errors.New("no known replicas to acknowledge the transaction")
+
+// ReplicationTimeoutError is returned when the transaction is committed in the primary
+// but not enough replicas acknowledged it before the timeout
+type ReplicationTimeoutError struct {
+	TxID         uint64
+	Acks         int
+	RequiredAcks int
+}
+
+func (e *ReplicationTimeoutError) Error() string {
+	return fmt.Sprintf("%v: tx %d acknowledged by %d replicas, %d required", ErrReplicationTimeout, e.TxID, e.Acks, e.RequiredAcks)
+}
+
+func (e *ReplicationTimeoutError) Is(target error) bool {
+	return target == ErrReplicationTimeout
+}
+
+// replicationAcks keeps the latest transaction acknowledged by each replica
+type replicationAcks struct {
+	mutex sync.Mutex
+
+	acked map[string]uint64
+
+	// closed and replaced each time a replica acknowledges a transaction
+	changed chan struct{}
+}
+
+func newReplicationAcks() *replicationAcks {
+	return &replicationAcks{
+		acked:   make(map[string]uint64),
+		changed: make(chan struct{}),
+	}
+}
+
+func (r *replicationAcks) ack(replicaUUID string, txID uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ackedTxID, ok := r.acked[replicaUUID]
+	if ok && txID <= ackedTxID {
+		return
+	}
+
+	r.acked[replicaUUID] = txID
+
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// status returns the number of replicas which acknowledged the transaction and the number of required ones,
+// which is zero when no replica is known
+func (r *replicationAcks) status(level AckLevel, txID uint64) (acks, requiredAcks int, changed <-chan struct{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, ackedTxID := range r.acked {
+		if ackedTxID >= txID {
+			acks++
+		}
+	}
+
+	if len(r.acked) == 0 {
+		return 0, 0, r.changed
+	}
+
+	switch level {
+	case AckLevelQuorum:
+		requiredAcks = len(r.acked)/2 + 1
+	case AckLevelAll:
+		requiredAcks = len(r.acked)
+	}
+
+	return acks, requiredAcks, r.changed
+}
+
+// waitForAcks blocks until the transaction is acknowledged as required by the ack level. ErrNoKnownReplicas
+// is returned right away when no replica is known, as the transaction could not be acknowledged otherwise.
+func (r *replicationAcks) waitForAcks(ctx context.Context, level AckLevel, txID uint64, timeout time.Duration) error {
+	if level == AckLevelLocal {
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		acks, requiredAcks, changed := r.status(level, txID)
+		if requiredAcks == 0 {
+			return fmt.Errorf("%w: tx %d", ErrNoKnownReplicas, txID)
+		}
+
+		if acks >= requiredAcks {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return &ReplicationTimeoutError{TxID: txID, Acks: acks, RequiredAcks: requiredAcks}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ackLevelFor returns the ack level requested by a write, the one configured for the database by default
+func (d *db) ackLevelFor(level schema.AckLevel) (AckLevel, error) {
+	switch level {
+	case schema.AckLevel_ACK_DEFAULT:
+		return d.options.ackLevel, nil
+	case schema.AckLevel_ACK_LOCAL:
+		return AckLevelLocal, nil
+	case schema.AckLevel_ACK_QUORUM:
+		return AckLevelQuorum, nil
+	case schema.AckLevel_ACK_ALL:
+		return AckLevelAll, nil
+	}
+
+	return AckLevelLocal, fmt.Errorf("%w: invalid ack level %d", ErrIllegalArguments, level)
+}
+
+func (d *db) waitForReplicationAcks(ctx context.Context, level schema.AckLevel, txID uint64) error {
+	ackLevel, err := d.ackLevelFor(level)
+	if err != nil {
+		return err
+	}
+
+	return d.replicationAcks.waitForAcks(ctx, ackLevel, txID, d.options.replicationAckTimeout)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+// mockReplicator acknowledges the latest committed transaction on behalf of the given replicas
+type mockReplicator struct {
+	db *db
+}
+
+func (r *mockReplicator) replicate(replicaUUIDs ...string) {
+	txID, _ := r.db.st.CommittedAlh()
+
+	for _, uuid := range replicaUUIDs {
+		r.db.replicationAcks.ack(uuid, txID)
+	}
+}
+
+func TestAckLevel(t *testing.T) {
+	ctx := context.Background()
+
+	set := func(db *db, key string) <-chan error {
+		done := make(chan error, 1)
+
+		lastTxID, _ := db.st.CommittedAlh()
+
+		go func() {
+			_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(key), Value: []byte("value")}}})
+			done <- err
+		}()
+
+		// wait until the transaction is locally committed
+		require.Eventually(t, func() bool {
+			txID, _ := db.st.CommittedAlh()
+			return txID > lastTxID
+		}, time.Second, time.Millisecond)
+
+		return done
+	}
+
+	requireBlocked := func(t *testing.T, done <-chan error) {
+		select {
+		case err := <-done:
+			require.FailNow(t, "operation should be waiting for replication", "err: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	requireDone := func(t *testing.T, done <-chan error) {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			require.FailNow(t, "operation should not be waiting for replication")
+		}
+	}
+
+	t.Run("local ack level should not wait for replicas", func(t *testing.T) {
+		db := makeDb(t)
+		require.Equal(t, AckLevelLocal, db.options.ackLevel)
+
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}}})
+		require.NoError(t, err)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("key")})
+		require.NoError(t, err)
+	})
+
+	t.Run("quorum ack level should wait for a majority of replicas", func(t *testing.T) {
+		db := makeDbWith(t, "db", DefaultOption().WithDBRootPath(t.TempDir()).WithAckLevel(AckLevelQuorum))
+
+		replicator := &mockReplicator{db: db}
+		replicator.replicate("replica1", "replica2", "replica3")
+
+		done := set(db, "key")
+		requireBlocked(t, done)
+
+		replicator.replicate("replica1")
+		requireBlocked(t, done)
+
+		replicator.replicate("replica2")
+		requireDone(t, done)
+	})
+
+	t.Run("all ack level should wait for every replica", func(t *testing.T) {
+		db := makeDbWith(t, "db", DefaultOption().WithDBRootPath(t.TempDir()).WithAckLevel(AckLevelAll))
+
+		replicator := &mockReplicator{db: db}
+		replicator.replicate("replica1", "replica2")
+
+		done := set(db, "key")
+		requireBlocked(t, done)
+
+		replicator.replicate("replica1")
+		requireBlocked(t, done)
+
+		replicator.replicate("replica2")
+		requireDone(t, done)
+	})
+
+	t.Run("set reference should wait for replicas as well", func(t *testing.T) {
+		db := makeDbWith(t, "db", DefaultOption().WithDBRootPath(t.TempDir()).WithAckLevel(AckLevelAll))
+
+		replicator := &mockReplicator{db: db}
+		replicator.replicate("replica1")
+
+		done := set(db, "key")
+		replicator.replicate("replica1")
+		requireDone(t, done)
+
+		lastTxID, _ := db.st.CommittedAlh()
+
+		refDone := make(chan error, 1)
+
+		go func() {
+			_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("key")})
+			refDone <- err
+		}()
+
+		require.Eventually(t, func() bool {
+			txID, _ := db.st.CommittedAlh()
+			return txID > lastTxID
+		}, time.Second, time.Millisecond)
+
+		requireBlocked(t, refDone)
+
+		replicator.replicate("replica1")
+		requireDone(t, refDone)
+	})
+
+	t.Run("operations should fail right away when no replica is known", func(t *testing.T) {
+		db := makeDbWith(t, "db", DefaultOption().WithDBRootPath(t.TempDir()).WithAckLevel(AckLevelQuorum))
+
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}}})
+		require.ErrorIs(t, err, ErrNoKnownReplicas)
+
+		// the transaction is committed locally regardless of the error
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("key")})
+		require.NoError(t, err)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("key")})
+		require.ErrorIs(t, err, ErrNoKnownReplicas)
+	})
+
+	t.Run("ack level of the request should override the one of the database", func(t *testing.T) {
+		db := makeDb(t)
+
+		replicator := &mockReplicator{db: db}
+		replicator.replicate("replica1")
+
+		_, err := db.Set(ctx, &schema.SetRequest{
+			KVs:      []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}},
+			AckLevel: schema.AckLevel(100),
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("key"), AckLevel: schema.AckLevel(100)})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		lastTxID, _ := db.st.CommittedAlh()
+
+		done := make(chan error, 1)
+
+		go func() {
+			_, err := db.Set(ctx, &schema.SetRequest{
+				KVs:      []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}},
+				AckLevel: schema.AckLevel_ACK_ALL,
+			})
+			done <- err
+		}()
+
+		require.Eventually(t, func() bool {
+			txID, _ := db.st.CommittedAlh()
+			return txID > lastTxID
+		}, time.Second, time.Millisecond)
+
+		requireBlocked(t, done)
+
+		replicator.replicate("replica1")
+		requireDone(t, done)
+
+		dbAll := makeDbWith(t, "db", DefaultOption().WithDBRootPath(t.TempDir()).WithAckLevel(AckLevelAll))
+
+		_, err = dbAll.Set(ctx, &schema.SetRequest{
+			KVs:      []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}},
+			AckLevel: schema.AckLevel_ACK_LOCAL,
+		})
+		require.NoError(t, err)
+
+		_, err = dbAll.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("ref"),
+			ReferencedKey: []byte("key"),
+			AckLevel:      schema.AckLevel_ACK_LOCAL,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("operations should fail when replicas don't acknowledge on time", func(t *testing.T) {
+		db := makeDbWith(t, "db", DefaultOption().
+			WithDBRootPath(t.TempDir()).
+			WithAckLevel(AckLevelQuorum).
+			WithReplicationAckTimeout(10*time.Millisecond),
+		)
+
+		replicator := &mockReplicator{db: db}
+		replicator.replicate("replica1")
+
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}}})
+		require.ErrorIs(t, err, ErrReplicationTimeout)
+
+		var timeoutErr *ReplicationTimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		require.Equal(t, 0, timeoutErr.Acks)
+		require.Equal(t, 1, timeoutErr.RequiredAcks)
+
+		// the transaction is committed locally regardless of the timeout
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("key")})
+		require.NoError(t, err)
+		require.Equal(t, timeoutErr.TxID, entry.Tx)
+	})
+}
@@ -37,6 +37,10 @@ func (d *db) ExecAll(ctx context.Context, req *schema.ExecAllRequest) (*schema.T
 		return nil, err
 	}
 
+	if d.keyObfuscationEnabled() {
+		return nil, ErrKeyObfuscationUnsupported
+	}
+
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
@@ -89,6 +93,40 @@ func (d *db) ExecAll(ctx context.Context, req *schema.ExecAllRequest) (*schema.T
 					return nil, nil, store.ErrIllegalArguments
 				}
 
+				if x.Ref.CreateTargetIfMissing {
+					// targets can be set by a key-value operation of the same transaction
+					return nil, nil, fmt.Errorf("%w: target creation is not supported within ExecAll", store.ErrIllegalArguments)
+				}
+
+				if len(x.Ref.ExpectedValueHash) > 0 {
+					// the referenced value may be set by a key-value operation of the same transaction
+					return nil, nil, fmt.Errorf("%w: expected value hashes are not supported within ExecAll", store.ErrIllegalArguments)
+				}
+
+				if len(x.Ref.Group) > 0 || x.Ref.Priority != 0 {
+					// group membership entries are not written by ExecAll
+					return nil, nil, fmt.Errorf("%w: reference groups are not supported within ExecAll", store.ErrIllegalArguments)
+				}
+
+				if d.isReservedKey(x.Ref.ReferencedKey) {
+					return nil, nil, ErrReservedKeyReference
+				}
+
+				err := d.validateReferenceTransform(x.Ref.Transform)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				err = validateReferenceLabel(x.Ref.Label)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				err = validateReferenceCreatedBy(x.Ref.CreatedBy)
+				if err != nil {
+					return nil, nil, err
+				}
+
 				if req.NoWait && (x.Ref.AtTx != 0 || !x.Ref.BoundRef) {
 					return nil, nil, fmt.Errorf(
 						"%w: can only set references to keys added within same transaction, please use bound references with AtTx set to 0",
@@ -124,19 +162,24 @@ func (d *db) ExecAll(ctx context.Context, req *schema.ExecAllRequest) (*schema.T
 				}
 
 				// reference arguments are converted in regular key value items and then atomically inserted
+				attrs := referenceAttributesFromRequest(x.Ref)
+				md := referenceMetadataFromRequest(x.Ref)
+
 				if x.Ref.BoundRef && x.Ref.AtTx == 0 {
-					e = EncodeReference(
+					e = EncodeReferenceWithAttributes(
 						x.Ref.Key,
-						nil,
+						md,
 						x.Ref.ReferencedKey,
 						txID,
+						attrs,
 					)
 				} else {
-					e = EncodeReference(
+					e = EncodeReferenceWithAttributes(
 						x.Ref.Key,
-						nil,
+						md,
 						x.Ref.ReferencedKey,
 						x.Ref.AtTx,
+						attrs,
 					)
 				}
 
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// Partial updates read the current value of a key and commit the updated one within the same
+// transaction. Conflicting updates are detected when committing, in which case
+// store.ErrTxReadConflict is returned and the update can be retried.
+//
+// Missing keys, including deleted and expired ones, are created as if the current value was empty.
+// The updated value is written without metadata, thus previous expiration or non-indexable settings
+// are not carried over.
+
+var (
+	ErrPartialUpdateOnReference = fmt.Errorf("%w: partial updates are not supported on references", store.ErrIllegalArguments)
+	ErrInvalidJSONValue         = fmt.Errorf("%w: value is not valid JSON", store.ErrIllegalArguments)
+)
+
+// Append commits the current value of the key followed by the provided suffix
+func (d *db) Append(ctx context.Context, key, suffix []byte, preconditions []*schema.Precondition) (*schema.TxHeader, error) {
+	return d.partialUpdate(ctx, key, len(suffix), preconditions, func(value []byte) ([]byte, error) {
+		updated := make([]byte, len(value)+len(suffix))
+		copy(updated, value)
+		copy(updated[len(value):], suffix)
+
+		return updated, nil
+	})
+}
+
+// MergeJSON applies the JSON merge patch (RFC 7386) to the current value of the key,
+// which must be valid JSON unless the key is missing
+func (d *db) MergeJSON(ctx context.Context, key, patch []byte, preconditions []*schema.Precondition) (*schema.TxHeader, error) {
+	var p interface{}
+
+	err := json.Unmarshal(patch, &p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid patch: %v", ErrIllegalArguments, err)
+	}
+
+	return d.partialUpdate(ctx, key, len(patch), preconditions, func(value []byte) ([]byte, error) {
+		var target interface{}
+
+		if len(value) > 0 {
+			err := json.Unmarshal(value, &target)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidJSONValue, err)
+			}
+		}
+
+		return json.Marshal(mergePatch(target, p))
+	})
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{}, len(patchObj))
+	}
+
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+
+		targetObj[k] = mergePatch(targetObj[k], v)
+	}
+
+	return targetObj
+}
+
+func (d *db) partialUpdate(
+	ctx context.Context,
+	key []byte,
+	size int,
+	preconditions []*schema.Precondition,
+	update func(value []byte) ([]byte, error),
+) (*schema.TxHeader, error) {
+	if len(key) == 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	hdr, err := d.lockedPartialUpdate(ctx, key, size, preconditions, update)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.waitForReplicationAcks(ctx, schema.AckLevel_ACK_DEFAULT, hdr.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+func (d *db) lockedPartialUpdate(
+	ctx context.Context,
+	key []byte,
+	size int,
+	preconditions []*schema.Precondition,
+	update func(value []byte) ([]byte, error),
+) (*schema.TxHeader, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	err := d.writeLimiter.acquire(1, len(key)+size)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := d.newTx(ctx, store.DefaultTxOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Cancel()
+
+	storedKey := d.storedKey(key)
+
+	var value []byte
+
+	valRef, err := tx.Get(ctx, EncodeKey(storedKey))
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		encVal, err := valRef.Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(encVal) == 0 || encVal[0] != PlainValuePrefix {
+			return nil, ErrPartialUpdateOnReference
+		}
+
+		value = encVal[1:]
+	}
+
+	updated, err := update(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.keyObfuscationEnabled() {
+		_, err = d.setObfuscatedKey(tx, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	e := EncodeEntrySpec(storedKey, nil, updated)
+
+	err = tx.Set(e.Key, e.Metadata, e.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range preconditions {
+		c, err := PreconditionFromProto(preconditions[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if d.keyObfuscationEnabled() {
+			c = d.obfuscatePrecondition(c)
+		}
+
+		err = tx.AddPrecondition(c)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
+		}
+	}
+
+	hdr, err := tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.TxHeaderToProto(hdr), nil
+}
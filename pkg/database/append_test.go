@@ -0,0 +1,106 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppend(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("log"), Value: []byte("line1\n")}}})
+	require.NoError(t, err)
+
+	hdr, err := db.Append(ctx, []byte("log"), []byte("line2\n"), nil)
+	require.NoError(t, err)
+
+	entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("log")})
+	require.NoError(t, err)
+	require.Equal(t, []byte("line1\nline2\n"), entry.Value)
+	require.Equal(t, hdr.Id, entry.Tx)
+
+	t.Run("appending to a missing key should create it", func(t *testing.T) {
+		_, err := db.Append(ctx, []byte("new-log"), []byte("line1\n"), nil)
+		require.NoError(t, err)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("new-log")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("line1\n"), entry.Value)
+	})
+
+	t.Run("preconditions should be checked", func(t *testing.T) {
+		_, err := db.Append(ctx, []byte("log"), []byte("line3\n"), []*schema.Precondition{
+			schema.PreconditionKeyNotModifiedAfterTX([]byte("log"), hdr.Id-1),
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+	})
+
+	t.Run("references should not be updated", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("log-ref"), ReferencedKey: []byte("log")})
+		require.NoError(t, err)
+
+		_, err = db.Append(ctx, []byte("log-ref"), []byte("line3\n"), nil)
+		require.ErrorIs(t, err, ErrPartialUpdateOnReference)
+	})
+
+	_, err = db.Append(ctx, nil, []byte("line3\n"), nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+}
+
+func TestMergeJSON(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("user"), Value: []byte(`{"name":"alice","address":{"city":"rome","zip":"00100"},"tags":["a"]}`)},
+		{Key: []byte("plain"), Value: []byte("not json")},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.MergeJSON(ctx, []byte("user"), []byte(`{"address":{"city":"milan","zip":null},"tags":["b"],"age":30}`), nil)
+	require.NoError(t, err)
+
+	entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("user")})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"alice","address":{"city":"milan"},"tags":["b"],"age":30}`, string(entry.Value))
+
+	t.Run("merging into a missing key should create it", func(t *testing.T) {
+		_, err := db.MergeJSON(ctx, []byte("new-user"), []byte(`{"name":"bob","age":null}`), nil)
+		require.NoError(t, err)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("new-user")})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name":"bob"}`, string(entry.Value))
+	})
+
+	t.Run("invalid values and patches should be rejected", func(t *testing.T) {
+		_, err := db.MergeJSON(ctx, []byte("plain"), []byte(`{"name":"bob"}`), nil)
+		require.ErrorIs(t, err, ErrInvalidJSONValue)
+
+		_, err = db.MergeJSON(ctx, []byte("user"), []byte(`{"name":`), nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
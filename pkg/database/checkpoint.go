@@ -0,0 +1,59 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// Checkpoint syncs the committed transactions and flushes the index up to them, so the returned state
+// doesn't depend on any pending write. Transactions committed while checkpointing may be included in
+// the flushed index but not in the returned state. Nothing is flushed when no transaction was committed
+// since the previous checkpoint.
+func (d *db) Checkpoint(ctx context.Context) (*schema.ImmutableState, error) {
+	d.checkpointMutex.Lock()
+	defer d.checkpointMutex.Unlock()
+
+	txID, alh := d.st.CommittedAlh()
+
+	if txID > d.lastCheckpointTxID {
+		err := d.st.Sync()
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.st.WaitForIndexingUpto(ctx, txID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.st.FlushIndexes(0, true)
+		if err != nil {
+			return nil, err
+		}
+
+		d.lastCheckpointTxID = txID
+	}
+
+	return &schema.ImmutableState{
+		Db:     d.name,
+		TxId:   txID,
+		TxHash: alh[:],
+	}, nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+var ErrCheckpointKeyNotSet = fmt.Errorf("%w: no checkpoint public key configured", ErrIllegalState)
+
+// CheckpointReader is a read-only handle pinned to the transaction of a signed checkpoint.
+// Entries committed after the checkpoint are not visible through it.
+type CheckpointReader struct {
+	db    *db
+	state *schema.ImmutableState
+	index *checkpointIndex
+}
+
+// OpenCheckpointReader verifies the signature of the provided state against the configured
+// checkpoint public key and returns a reader pinned to the transaction of the checkpoint
+func (d *db) OpenCheckpointReader(signedState *schema.ImmutableState) (*CheckpointReader, error) {
+	if d.options.checkpointPublicKey == nil {
+		return nil, ErrCheckpointKeyNotSet
+	}
+
+	if signedState == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	err := signedState.CheckSignature(d.options.checkpointPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCheckpoint, err)
+	}
+
+	if signedState.Db != d.name {
+		return nil, fmt.Errorf("%w: checkpoint belongs to database '%s'", ErrInvalidCheckpoint, signedState.Db)
+	}
+
+	lastTxID, _ := d.st.CommittedAlh()
+	if signedState.TxId == 0 || signedState.TxId > lastTxID {
+		return nil, fmt.Errorf("%w: checkpoint transaction %d is not committed", ErrInvalidCheckpoint, signedState.TxId)
+	}
+
+	hdr, err := d.st.ReadTxHeader(signedState.TxId, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	alh := hdr.Alh()
+	if !bytes.Equal(signedState.TxHash, alh[:]) {
+		return nil, fmt.Errorf("%w: checkpoint hash does not match transaction %d", ErrInvalidCheckpoint, signedState.TxId)
+	}
+
+	return &CheckpointReader{
+		db:    d,
+		state: signedState,
+		index: &checkpointIndex{st: d.st, txID: signedState.TxId},
+	}, nil
+}
+
+// TxID returns the transaction the reader is pinned to
+func (r *CheckpointReader) TxID() uint64 {
+	return r.state.TxId
+}
+
+// State returns the signed state the reader was opened with
+func (r *CheckpointReader) State() *schema.ImmutableState {
+	return r.state
+}
+
+// Get returns the value of the key as of the checkpoint, references are resolved as of the checkpoint as well
+func (r *CheckpointReader) Get(ctx context.Context, key []byte) (*schema.Entry, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: empty key", ErrIllegalArguments)
+	}
+
+	err := r.db.WaitForIndexingUpto(ctx, r.state.TxId)
+	if err != nil {
+		return nil, err
+	}
+
+	encKey := EncodeKey(r.db.storedKey(key))
+
+	entry, err := r.db.get(ctx, encKey, r.index, false)
+	if err == nil {
+		entry, err = r.db.effectiveReference(ctx, encKey, entry, r.index)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.completeEntry(key, entry, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// checkpointIndex restricts key lookups to entries committed up to the checkpoint transaction
+type checkpointIndex struct {
+	st   *store.ImmuStore
+	txID uint64
+}
+
+func (idx *checkpointIndex) Get(ctx context.Context, key []byte) (store.ValueRef, error) {
+	return idx.GetWithFilters(ctx, key, store.IgnoreExpired, store.IgnoreDeleted)
+}
+
+func (idx *checkpointIndex) GetBetween(ctx context.Context, key []byte, initialTxID, finalTxID uint64) (store.ValueRef, error) {
+	if initialTxID > idx.txID {
+		return nil, store.ErrKeyNotFound
+	}
+
+	if finalTxID > idx.txID {
+		finalTxID = idx.txID
+	}
+
+	return idx.st.GetBetween(ctx, key, initialTxID, finalTxID)
+}
+
+func (idx *checkpointIndex) GetWithFilters(ctx context.Context, key []byte, filters ...store.FilterFn) (store.ValueRef, error) {
+	valRef, err := idx.st.GetBetween(ctx, key, 1, idx.txID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	for _, filter := range filters {
+		err = filter(valRef, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return valRef, nil
+}
+
+func (idx *checkpointIndex) GetWithPrefix(ctx context.Context, prefix []byte, neq []byte) (key []byte, valRef store.ValueRef, err error) {
+	return idx.GetWithPrefixAndFilters(ctx, prefix, neq)
+}
+
+func (idx *checkpointIndex) GetWithPrefixAndFilters(ctx context.Context, prefix []byte, neq []byte, filters ...store.FilterFn) (key []byte, valRef store.ValueRef, err error) {
+	return nil, nil, fmt.Errorf("%w: prefix lookups are not supported at a checkpoint", ErrIllegalState)
+}
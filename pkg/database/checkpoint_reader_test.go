@@ -0,0 +1,193 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/signer"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func signState(t *testing.T, sig signer.Signer, state *schema.ImmutableState) {
+	signature, publicKey, err := sig.Sign(state.ToBytes())
+	require.NoError(t, err)
+
+	state.Signature = &schema.Signature{
+		Signature: signature,
+		PublicKey: publicKey,
+	}
+}
+
+func TestOpenCheckpointReader(t *testing.T) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	sig := signer.NewSignerFromPKey(rand.Reader, pk)
+
+	t.Run("opening a checkpoint reader should fail without a public key", func(t *testing.T) {
+		db := makeDb(t)
+
+		state, err := db.CurrentState()
+		require.NoError(t, err)
+
+		_, err = db.OpenCheckpointReader(state)
+		require.ErrorIs(t, err, ErrCheckpointKeyNotSet)
+	})
+
+	opts := DefaultOption().
+		WithDBRootPath(t.TempDir()).
+		WithCheckpointPublicKey(&pk.PublicKey)
+
+	db := makeDbWith(t, "db", opts)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("key1"), Value: []byte("value1")},
+		{Key: []byte("key2"), Value: []byte("value2")},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte("ref1"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	checkpoint, err := db.CurrentState()
+	require.NoError(t, err)
+
+	checkpoint.Db = db.GetName()
+	signState(t, sig, checkpoint)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("key1"), Value: []byte("value1_v2")},
+		{Key: []byte("key3"), Value: []byte("value3")},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.Delete(context.Background(), &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("key2")}})
+	require.NoError(t, err)
+
+	t.Run("reads should be pinned to the checkpoint", func(t *testing.T) {
+		reader, err := db.OpenCheckpointReader(checkpoint)
+		require.NoError(t, err)
+		require.Equal(t, checkpoint.TxId, reader.TxID())
+
+		entry, err := reader.Get(context.Background(), []byte("key1"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value1"), entry.Value)
+		require.LessOrEqual(t, entry.Tx, checkpoint.TxId)
+
+		entry, err = reader.Get(context.Background(), []byte("key2"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value2"), entry.Value)
+
+		entry, err = reader.Get(context.Background(), []byte("ref1"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value1"), entry.Value)
+		require.NotNil(t, entry.ReferencedBy)
+
+		_, err = reader.Get(context.Background(), []byte("key3"))
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		_, err = reader.Get(context.Background(), nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("tampered checkpoints should be rejected", func(t *testing.T) {
+		_, err := db.OpenCheckpointReader(nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		tampered := proto.Clone(checkpoint).(*schema.ImmutableState)
+		tampered.TxId++
+
+		_, err = db.OpenCheckpointReader(tampered)
+		require.ErrorIs(t, err, ErrInvalidCheckpoint)
+
+		tampered = proto.Clone(checkpoint).(*schema.ImmutableState)
+		tampered.TxHash = make([]byte, len(checkpoint.TxHash))
+
+		_, err = db.OpenCheckpointReader(tampered)
+		require.ErrorIs(t, err, ErrInvalidCheckpoint)
+
+		unsigned := proto.Clone(checkpoint).(*schema.ImmutableState)
+		unsigned.Signature = nil
+
+		_, err = db.OpenCheckpointReader(unsigned)
+		require.ErrorIs(t, err, ErrInvalidCheckpoint)
+	})
+
+	t.Run("checkpoints signed by another key should be rejected", func(t *testing.T) {
+		otherPk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		state := proto.Clone(checkpoint).(*schema.ImmutableState)
+		signState(t, signer.NewSignerFromPKey(rand.Reader, otherPk), state)
+
+		_, err = db.OpenCheckpointReader(state)
+		require.ErrorIs(t, err, ErrInvalidCheckpoint)
+	})
+
+	t.Run("validly signed states not matching the database should be rejected", func(t *testing.T) {
+		state := proto.Clone(checkpoint).(*schema.ImmutableState)
+		state.TxHash = make([]byte, len(checkpoint.TxHash))
+		signState(t, sig, state)
+
+		_, err = db.OpenCheckpointReader(state)
+		require.ErrorIs(t, err, ErrInvalidCheckpoint)
+
+		state = proto.Clone(checkpoint).(*schema.ImmutableState)
+		state.Db = "otherdb"
+		signState(t, sig, state)
+
+		_, err = db.OpenCheckpointReader(state)
+		require.ErrorIs(t, err, ErrInvalidCheckpoint)
+	})
+}
+
+func TestCheckpointReaderWithKeyObfuscation(t *testing.T) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	opts := DefaultOption().
+		WithDBRootPath(t.TempDir()).
+		WithCheckpointPublicKey(&pk.PublicKey).
+		WithKeyObfuscationSecret([]byte("a-secret-of-enough-length"))
+
+	db := makeDbWith(t, "db", opts)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	checkpoint, err := db.CurrentState()
+	require.NoError(t, err)
+
+	checkpoint.Db = db.GetName()
+	signState(t, signer.NewSignerFromPKey(rand.Reader, pk), checkpoint)
+
+	reader, err := db.OpenCheckpointReader(checkpoint)
+	require.NoError(t, err)
+
+	entry, err := reader.Get(context.Background(), []byte("key1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("key1"), entry.Key)
+	require.Equal(t, []byte("value1"), entry.Value)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	t.Run("an empty database should be checkpointed", func(t *testing.T) {
+		state, err := db.Checkpoint(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "db", state.Db)
+		require.Zero(t, state.TxId)
+	})
+
+	t.Run("checkpoints should be safe under concurrent writes", func(t *testing.T) {
+		var wg sync.WaitGroup
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				for j := 0; j < 10; j++ {
+					_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+						{Key: []byte(fmt.Sprintf("key%d-%d", i, j)), Value: []byte("value")},
+					}})
+					require.NoError(t, err)
+				}
+			}(i)
+		}
+
+		for i := 0; i < 5; i++ {
+			state, err := db.Checkpoint(ctx)
+			require.NoError(t, err)
+
+			if state.TxId > 0 {
+				hdr, err := db.st.ReadTxHeader(state.TxId, false, false)
+				require.NoError(t, err)
+
+				alh := hdr.Alh()
+				require.Equal(t, alh[:], state.TxHash)
+			}
+		}
+
+		wg.Wait()
+	})
+
+	t.Run("the checkpoint should match the last committed tx", func(t *testing.T) {
+		hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("last"), Value: []byte("value")}}})
+		require.NoError(t, err)
+
+		state, err := db.Checkpoint(ctx)
+		require.NoError(t, err)
+		require.Equal(t, hdr.Id, state.TxId)
+		require.Equal(t, db.lastCheckpointTxID, state.TxId)
+
+		currState, err := db.CurrentState()
+		require.NoError(t, err)
+		require.Equal(t, currState.TxId, state.TxId)
+		require.Equal(t, currState.TxHash, state.TxHash)
+
+		// the index includes the checkpointed tx
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("last"), NoWait: true})
+		require.NoError(t, err)
+		require.Equal(t, hdr.Id, entry.Tx)
+
+		// nothing is flushed again when no tx was committed since the previous checkpoint
+		state2, err := db.Checkpoint(ctx)
+		require.NoError(t, err)
+		require.Equal(t, state, state2)
+	})
+}
@@ -22,10 +22,12 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/codenotary/immudb/embedded/document"
@@ -34,6 +36,7 @@ import (
 
 	"github.com/codenotary/immudb/embedded/logger"
 	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/signer"
 )
 
 const (
@@ -51,6 +54,7 @@ var (
 	ErrNotReplica                 = errors.New("database is NOT a replica")
 	ErrReplicaDivergedFromPrimary = errors.New("replica diverged from primary")
 	ErrInvalidRevision            = errors.New("invalid key revision number")
+	ErrIndexNotUpToDate           = errors.New("index is not up to date")
 )
 
 type DB interface {
@@ -68,10 +72,13 @@ type DB interface {
 	SetSyncReplication(enabled bool)
 
 	MaxResultSize() int
+	TxLimits() (maxKeyLen, maxValueLen, maxTxEntries int)
 
 	// State
 	Health() (waitingCount int, lastReleaseAt time.Time)
+	Metrics() Metrics
 	CurrentState() (*schema.ImmutableState, error)
+	OpenCheckpointReader(signedState *schema.ImmutableState) (*CheckpointReader, error)
 
 	Size() (uint64, error)
 
@@ -82,17 +89,52 @@ type DB interface {
 	VerifiableSet(ctx context.Context, req *schema.VerifiableSetRequest) (*schema.VerifiableTx, error)
 
 	Get(ctx context.Context, req *schema.KeyRequest) (*schema.Entry, error)
+	GetAt(ctx context.Context, key []byte, atTx uint64) (*schema.Entry, error)
 	VerifiableGet(ctx context.Context, req *schema.VerifiableGetRequest) (*schema.VerifiableEntry, error)
 	GetAll(ctx context.Context, req *schema.KeyListRequest) (*schema.Entries, error)
+	GetEntryMetadata(ctx context.Context, req *schema.KeyRequest) (*EntryMetadata, error)
+	GetReference(ctx context.Context, req *schema.KeyRequest) (*schema.Reference, error)
+	ReferenceHistory(ctx context.Context, req *schema.HistoryRequest) ([]*schema.Reference, error)
+	VerifiableGetAll(ctx context.Context, req *schema.VerifiableGetAllRequest) (*schema.VerifiableEntries, error)
 
 	Delete(ctx context.Context, req *schema.DeleteKeysRequest) (*schema.TxHeader, error)
 
 	SetReference(ctx context.Context, req *schema.ReferenceRequest) (*schema.TxHeader, error)
+	SetReferenceAndResolve(ctx context.Context, req *schema.ReferenceRequest) (*schema.SetReferenceResponse, error)
+	SetReferences(ctx context.Context, req *schema.SetReferencesRequest) (*schema.TxHeader, error)
+	ResolveGroup(ctx context.Context, group []byte) (*schema.Entry, error)
 	VerifiableSetReference(ctx context.Context, req *schema.VerifiableReferenceRequest) (*schema.VerifiableTx, error)
+	VerifiableSetReferences(ctx context.Context, req *schema.VerifiableSetReferencesRequest) (*schema.VerifiableTx, error)
+	RefreshReferences(ctx context.Context, prefix []byte) (refreshed int, txIDs []uint64, err error)
+	ResolveReferenceProvenance(ctx context.Context, key []byte) ([]*ReferenceHop, error)
+	CanonicalKey(ctx context.Context, key []byte) ([]byte, error)
+	VerifiableSetWithReference(ctx context.Context, kv *schema.KeyValue, req *schema.ReferenceRequest, proveSinceTx uint64) (*schema.VerifiableTx, error)
+	CheckUnique(ctx context.Context, prefix, key []byte, proveSinceTx uint64) (*UniquenessCheck, error)
+	CheckAbsent(ctx context.Context, key []byte, atTx uint64) (*AbsenceCheck, error)
+
+	OpenSnapshot(ctx context.Context, txID uint64) (*Snapshot, error)
+	OpenSnapshots() int
+
+	RenameKey(ctx context.Context, oldKey, newKey []byte) (*schema.TxHeader, error)
+	RenameReference(ctx context.Context, oldKey, newKey []byte, allowOverwrite bool) (*schema.TxHeader, error)
+
+	VerifyIndex(ctx context.Context) error
+	SelfVerify(ctx context.Context, sampleSize int) error
+
+	Append(ctx context.Context, key, suffix []byte, preconditions []*schema.Precondition) (*schema.TxHeader, error)
+	MergeJSON(ctx context.Context, key, patch []byte, preconditions []*schema.Precondition) (*schema.TxHeader, error)
+
+	ExportBundle(ctx context.Context, fromTx, toTx uint64, w io.Writer, s signer.Signer) (*BundleManifest, error)
+	RepairReferences(ctx context.Context, dryRun bool) ([]*BrokenReference, error)
+	ListReferences(ctx context.Context, req *ListReferencesRequest) ([]*ListedReference, error)
+	ScanReferences(ctx context.Context, req *schema.ScanRequest) ([]*schema.Reference, error)
 
 	Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries, error)
+	ScanByTx(ctx context.Context, req *schema.ScanRequest, send func(*schema.TxGroup) error) error
 
 	History(ctx context.Context, req *schema.HistoryRequest) (*schema.Entries, error)
+	GetVersions(ctx context.Context, key []byte, k int, sinceTx uint64) ([]*schema.Entry, error)
+	VerifiableHistory(ctx context.Context, req *schema.VerifiableHistoryRequest, send func(*schema.VerifiableHistoryResponse) error) error
 
 	ExecAll(ctx context.Context, operations *schema.ExecAllRequest) (*schema.TxHeader, error)
 
@@ -133,9 +175,11 @@ type DB interface {
 
 	VerifiableTxByID(ctx context.Context, req *schema.VerifiableTxRequest) (*schema.VerifiableTx, error)
 	TxScan(ctx context.Context, req *schema.TxScanRequest) (*schema.TxList, error)
+	RecentTxs(ctx context.Context, n int) ([]*TxSummary, error)
 
 	// Maintenance
 	FlushIndex(req *schema.FlushIndexRequest) error
+	Checkpoint(ctx context.Context) (*schema.ImmutableState, error)
 	CompactIndex() error
 
 	IsClosed() bool
@@ -166,10 +210,26 @@ type db struct {
 
 	maxResultSize int
 
+	writeLimiter *writeRateLimiter
+
+	snapshotSlots *snapshotSlots
+
+	keyObfuscator *keyObfuscator
+
 	txPool store.TxPool
 
 	replicaStates      map[string]*replicaState
 	replicaStatesMutex sync.Mutex
+
+	replicationAcks *replicationAcks
+
+	checkpointMutex    sync.Mutex
+	lastCheckpointTxID uint64
+
+	referenceMetrics *referenceMetrics
+
+	// resolutionHook is invoked on each resolution step of Get, only meant to be set by tests
+	resolutionHook func(key []byte)
 }
 
 // OpenDB Opens an existing Database from disk
@@ -187,12 +247,17 @@ func OpenDB(dbName string, multidbHandler sql.MultiDBHandler, opts *Options, log
 	}
 
 	dbi := &db{
-		Logger:        log,
-		options:       opts,
-		name:          dbName,
-		replicaStates: replicaStates,
-		maxResultSize: opts.maxResultSize,
-		mutex:         &instrumentedRWMutex{},
+		Logger:          log,
+		options:         opts,
+		name:            dbName,
+		replicaStates:   replicaStates,
+		replicationAcks: newReplicationAcks(),
+		maxResultSize:   opts.maxResultSize,
+		writeLimiter:    newWriteRateLimiter(opts.maxWriteOpsPerSecond, opts.maxWriteBytesPerSecond),
+		snapshotSlots:   newSnapshotSlots(dbName, opts.maxOpenSnapshots),
+		mutex:           &instrumentedRWMutex{},
+
+		referenceMetrics: &referenceMetrics{},
 	}
 
 	dbDir := dbi.Path()
@@ -211,7 +276,15 @@ func OpenDB(dbName string, multidbHandler sql.MultiDBHandler, opts *Options, log
 		return nil, logErr(dbi.Logger, "unable to open database: %s", err)
 	}
 
-	for _, prefix := range []byte{SetKeyPrefix, SortedSetKeyPrefix} {
+	if len(opts.keyObfuscationSecret) > 0 {
+		dbi.keyObfuscator, err = newKeyObfuscator(opts.keyObfuscationSecret)
+		if err != nil {
+			dbi.st.Close()
+			return nil, logErr(dbi.Logger, "unable to open database: %s", err)
+		}
+	}
+
+	for _, prefix := range dbi.indexedPrefixes() {
 		err := dbi.st.InitIndexing(&store.IndexSpec{
 			SourcePrefix: []byte{prefix},
 			TargetPrefix: []byte{prefix},
@@ -236,7 +309,12 @@ func OpenDB(dbName string, multidbHandler sql.MultiDBHandler, opts *Options, log
 	}
 	dbi.Logger.Infof("sql-engine ready for database '%s' {replica = %v}", dbName, opts.replica)
 
-	dbi.documentEngine, err = document.NewEngine(dbi.st, document.DefaultOptions().WithPrefix([]byte{DocumentPrefix}))
+	dbi.documentEngine, err = document.NewEngine(dbi.st, document.DefaultOptions().
+		WithPrefix([]byte{DocumentPrefix}).
+		WithValueCodecs(opts.documentValueCodecs).
+		WithFieldEncryptionKey(opts.documentFieldEncryptionKey).
+		WithDeletedCollectionRetention(opts.documentDeletedCollectionRetention),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -248,6 +326,14 @@ func OpenDB(dbName string, multidbHandler sql.MultiDBHandler, opts *Options, log
 	}
 	dbi.txPool = txPool
 
+	if opts.verifyIndexOnOpen {
+		err = dbi.VerifyIndex(context.Background())
+		if err != nil {
+			dbi.st.Close()
+			return nil, logErr(dbi.Logger, "unable to open database: %s", err)
+		}
+	}
+
 	if opts.replica {
 		dbi.Logger.Infof("database '%s' {replica = %v} successfully opened", dbName, opts.replica)
 		return dbi, nil
@@ -302,12 +388,17 @@ func NewDB(dbName string, multidbHandler sql.MultiDBHandler, opts *Options, log
 	}
 
 	dbi := &db{
-		Logger:        log,
-		options:       opts,
-		name:          dbName,
-		replicaStates: replicaStates,
-		maxResultSize: opts.maxResultSize,
-		mutex:         &instrumentedRWMutex{},
+		Logger:          log,
+		options:         opts,
+		name:            dbName,
+		replicaStates:   replicaStates,
+		replicationAcks: newReplicationAcks(),
+		maxResultSize:   opts.maxResultSize,
+		writeLimiter:    newWriteRateLimiter(opts.maxWriteOpsPerSecond, opts.maxWriteBytesPerSecond),
+		snapshotSlots:   newSnapshotSlots(dbName, opts.maxOpenSnapshots),
+		mutex:           &instrumentedRWMutex{},
+
+		referenceMetrics: &referenceMetrics{},
 	}
 
 	dbDir := filepath.Join(opts.GetDBRootPath(), dbName)
@@ -331,7 +422,15 @@ func NewDB(dbName string, multidbHandler sql.MultiDBHandler, opts *Options, log
 		return nil, logErr(dbi.Logger, "unable to open database: %s", err)
 	}
 
-	for _, prefix := range []byte{SetKeyPrefix, SortedSetKeyPrefix} {
+	if len(opts.keyObfuscationSecret) > 0 {
+		dbi.keyObfuscator, err = newKeyObfuscator(opts.keyObfuscationSecret)
+		if err != nil {
+			dbi.st.Close()
+			return nil, logErr(dbi.Logger, "unable to open database: %s", err)
+		}
+	}
+
+	for _, prefix := range dbi.indexedPrefixes() {
 		err := dbi.st.InitIndexing(&store.IndexSpec{
 			SourcePrefix: []byte{prefix},
 			TargetPrefix: []byte{prefix},
@@ -362,7 +461,12 @@ func NewDB(dbName string, multidbHandler sql.MultiDBHandler, opts *Options, log
 	}
 	dbi.Logger.Infof("sql-engine ready for database '%s' {replica = %v}", dbName, opts.replica)
 
-	dbi.documentEngine, err = document.NewEngine(dbi.st, document.DefaultOptions().WithPrefix([]byte{DocumentPrefix}))
+	dbi.documentEngine, err = document.NewEngine(dbi.st, document.DefaultOptions().
+		WithPrefix([]byte{DocumentPrefix}).
+		WithValueCodecs(opts.documentValueCodecs).
+		WithFieldEncryptionKey(opts.documentFieldEncryptionKey).
+		WithDeletedCollectionRetention(opts.documentDeletedCollectionRetention),
+	)
 	if err != nil {
 		return nil, logErr(dbi.Logger, "Unable to open database: %s", err)
 	}
@@ -377,6 +481,22 @@ func (d *db) MaxResultSize() int {
 	return d.maxResultSize
 }
 
+// TxLimits returns the limits transactions of the database are subject to, so batches can be split up front.
+// Lengths exclude the one-byte prefix keys and values are stored with, and maxTxEntries is the number of keys a single
+// transaction can set, each key taking up an additional entry for its mapping when key obfuscation is enabled.
+// Values are read from the store, thus reflecting the settings the database was created with.
+func (d *db) TxLimits() (maxKeyLen, maxValueLen, maxTxEntries int) {
+	maxKeyLen = d.st.MaxKeyLen() - 1
+	maxValueLen = d.st.MaxValueLen() - 1
+	maxTxEntries = d.st.MaxTxEntries()
+
+	if d.keyObfuscationEnabled() {
+		maxTxEntries /= 2
+	}
+
+	return maxKeyLen, maxValueLen, maxTxEntries
+}
+
 func (d *db) FlushIndex(req *schema.FlushIndexRequest) error {
 	if req == nil {
 		return store.ErrIllegalArguments
@@ -391,7 +511,36 @@ func (d *db) CompactIndex() error {
 }
 
 // Set ...
-func (d *db) Set(ctx context.Context, req *schema.SetRequest) (*schema.TxHeader, error) {
+func (d *db) Set(ctx context.Context, req *schema.SetRequest) (hdr *schema.TxHeader, err error) {
+	if d.options.slowOperationThreshold > 0 {
+		defer func(start time.Time) {
+			var keyLen int
+			for _, kv := range req.GetKVs() {
+				keyLen += len(kv.Key)
+			}
+			d.observeSlowOperation(SetOperation, keyLen, start, hdr.GetId())
+		}(time.Now())
+	}
+
+	_, err = d.ackLevelFor(req.GetAckLevel())
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err = d.lockedSet(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.waitForReplicationAcks(ctx, req.AckLevel, hdr.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+func (d *db) lockedSet(ctx context.Context, req *schema.SetRequest) (*schema.TxHeader, error) {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
 
@@ -399,6 +548,18 @@ func (d *db) Set(ctx context.Context, req *schema.SetRequest) (*schema.TxHeader,
 		return nil, ErrIsReplica
 	}
 
+	if req != nil {
+		var size int
+		for _, kv := range req.KVs {
+			size += len(kv.Key) + len(kv.Value)
+		}
+
+		err := d.writeLimiter.acquire(1, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return d.set(ctx, req)
 }
 
@@ -427,8 +588,17 @@ func (d *db) set(ctx context.Context, req *schema.SetRequest) (*schema.TxHeader,
 		}
 		keys[kid] = struct{}{}
 
+		key := kv.Key
+
+		if d.keyObfuscationEnabled() {
+			key, err = d.setObfuscatedKey(tx, kv.Key)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		e := EncodeEntrySpec(
-			kv.Key,
+			key,
 			schema.KVMetadataFromProto(kv.Metadata),
 			kv.Value,
 		)
@@ -445,6 +615,10 @@ func (d *db) set(ctx context.Context, req *schema.SetRequest) (*schema.TxHeader,
 			return nil, err
 		}
 
+		if d.keyObfuscationEnabled() {
+			c = d.obfuscatePrecondition(c)
+		}
+
 		err = tx.AddPrecondition(c)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
@@ -534,37 +708,181 @@ func checkKeyRequest(req *schema.KeyRequest) error {
 }
 
 // Get ...
-func (d *db) Get(ctx context.Context, req *schema.KeyRequest) (*schema.Entry, error) {
-	err := checkKeyRequest(req)
+func (d *db) Get(ctx context.Context, req *schema.KeyRequest) (entry *schema.Entry, err error) {
+	if d.options.slowOperationThreshold > 0 {
+		defer func(start time.Time) {
+			d.observeSlowOperation(GetOperation, len(req.GetKey()), start, entry.GetTx())
+		}(time.Now())
+	}
+
+	err = checkKeyRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.TimeoutMs > 0 {
+		timeout := time.Duration(req.TimeoutMs) * time.Millisecond
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		defer func() {
+			if errors.Is(err, context.DeadlineExceeded) {
+				entry = nil
+				err = &GetTimeoutError{Timeout: timeout}
+			}
+		}()
+	}
+
+	err = d.waitForKeyRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := EncodeKey(d.storedKey(req.Key))
+
+	if req.AtRevision != 0 {
+		entry, err = d.getAtRevision(ctx, key, req.AtRevision, true)
+	} else {
+		entry, err = d.getAtTx(ctx, key, req.AtTx, 0, d.st, 0, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AtTx == 0 && req.AtRevision == 0 {
+		// the latest version of a reference is only resolved once effective
+		entry, err = d.effectiveReference(ctx, key, entry, d.st)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.FailIfStaleReference {
+		err = d.checkReferenceIsNotStale(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = d.completeEntry(req.Key, entry, !req.SkipTransform)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Decode != schema.ValueType_RAW && !entry.Expired {
+		entry.DecodedValue, err = decodeValue(entry.Value, req.Decode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.ReturnDigest {
+		err = d.setEntryDigest(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.TimeoutMs > 0 {
+		// the value may have been read after the deadline
+		err = ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// GetAt returns the value of the key as it existed at the given transaction, i.e. the latest value set at or
+// before it. ErrKeyNotFound is returned when the key had no value as of such transaction. References are
+// resolved as of the transaction as well, bound references included.
+func (d *db) GetAt(ctx context.Context, key []byte, atTx uint64) (*schema.Entry, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: empty key", ErrIllegalArguments)
+	}
+
+	currTxID, _ := d.st.CommittedAlh()
+	if atTx == 0 || atTx > currTxID {
+		return nil, fmt.Errorf(
+			"%w: atTx must be greater than zero and not greater than the current transaction ID",
+			ErrIllegalArguments,
+		)
+	}
+
+	err := d.WaitForIndexingUpto(ctx, atTx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &checkpointIndex{st: d.st, txID: atTx}
+
+	encKey := EncodeKey(d.storedKey(key))
+
+	entry, err := d.get(ctx, encKey, index, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// as of the transaction, references not yet effective are not resolved either
+	entry, err = d.effectiveReference(ctx, encKey, entry, index)
 	if err != nil {
 		return nil, err
 	}
 
+	err = d.completeEntry(key, entry, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// completeEntry does what every read does with the entry read for the key: the key is returned as requested
+// when keys are obfuscated and, if requested, the transform of the reference the entry was resolved through
+// is applied to the value
+func (d *db) completeEntry(key []byte, entry *schema.Entry, transform bool) (err error) {
+	if d.keyObfuscationEnabled() {
+		entry.Key = key
+	}
+
+	if transform && entry.ReferencedBy != nil && entry.ReferencedBy.Transform != "" {
+		entry.Value, err = d.applyReferenceTransform(entry.ReferencedBy.Transform, entry.Value)
+	}
+
+	return err
+}
+
+// waitForKeyRequest ensures the index includes the transaction the key request must be served since.
+// When SinceTx is zero the request is served from the currently indexed state without waiting. Such state
+// may not include the latest committed transactions, as replicated transactions, transactions committed
+// with NoWait and data indexed asynchronously, e.g. by additional indexes, may not be indexed yet.
+// Otherwise the index is waited to include SinceTx, unless NoWait is set, in which case ErrIndexNotUpToDate
+// is returned right away if it does not include it yet. Requests at a given transaction never wait.
+func (d *db) waitForKeyRequest(ctx context.Context, req *schema.KeyRequest) error {
 	currTxID, _ := d.st.CommittedAlh()
 	if req.SinceTx > currTxID {
-		return nil, fmt.Errorf(
+		return fmt.Errorf(
 			"%w: SinceTx must not be greater than the current transaction ID",
 			ErrIllegalArguments,
 		)
 	}
 
-	if !req.NoWait && req.AtTx == 0 {
-		waitUntilTx := req.SinceTx
-		if waitUntilTx == 0 {
-			waitUntilTx = currTxID
-		}
+	if req.SinceTx == 0 || req.AtTx > 0 {
+		return nil
+	}
 
-		err := d.WaitForIndexingUpto(ctx, waitUntilTx)
-		if err != nil {
-			return nil, err
+	if req.NoWait {
+		if !d.st.IsIndexedUpto(req.SinceTx) {
+			return fmt.Errorf("%w: transaction %d is not indexed yet", ErrIndexNotUpToDate, req.SinceTx)
 		}
-	}
 
-	if req.AtRevision != 0 {
-		return d.getAtRevision(ctx, EncodeKey(req.Key), req.AtRevision, true)
+		return nil
 	}
 
-	return d.getAtTx(ctx, EncodeKey(req.Key), req.AtTx, 0, d.st, 0, true)
+	return d.WaitForIndexingUpto(ctx, req.SinceTx)
 }
 
 func (d *db) get(ctx context.Context, key []byte, index store.KeyIndex, skipIntegrityCheck bool) (*schema.Entry, error) {
@@ -580,6 +898,14 @@ func (d *db) getAtTx(
 	revision uint64,
 	skipIntegrityCheck bool,
 ) (entry *schema.Entry, err error) {
+	if d.resolutionHook != nil {
+		d.resolutionHook(key)
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return nil, err
+	}
 
 	var txID uint64
 	var val []byte
@@ -595,7 +921,7 @@ func (d *db) getAtTx(
 		revision = valRef.HC()
 		md = valRef.KVMetadata()
 
-		val, err = valRef.Resolve()
+		val, err = valRef.ResolveContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -626,6 +952,17 @@ func (d *db) readMetadataAndValue(key []byte, atTx uint64, skipIntegrityCheck bo
 }
 
 func (d *db) getAtRevision(ctx context.Context, key []byte, atRevision int64, skipIntegrityCheck bool) (entry *schema.Entry, err error) {
+	valRef, revision, err := d.valRefAtRevision(key, atRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.getAtTx(ctx, key, valRef.Tx(), 0, d.st, revision, skipIntegrityCheck)
+}
+
+// valRefAtRevision returns the value reference of the key at the given revision, negative revisions
+// are relative to the latest one
+func (d *db) valRefAtRevision(key []byte, atRevision int64) (store.ValueRef, uint64, error) {
 	var offset uint64
 	var desc bool
 
@@ -639,22 +976,17 @@ func (d *db) getAtRevision(ctx context.Context, key []byte, atRevision int64, sk
 
 	valRefs, hCount, err := d.st.History(key, offset, desc, 1)
 	if errors.Is(err, store.ErrNoMoreEntries) || errors.Is(err, store.ErrOffsetOutOfRange) {
-		return nil, ErrInvalidRevision
+		return nil, 0, ErrInvalidRevision
 	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if atRevision < 0 {
 		atRevision = int64(hCount) + atRevision
 	}
 
-	entry, err = d.getAtTx(ctx, key, valRefs[0].Tx(), 0, d.st, uint64(atRevision), skipIntegrityCheck)
-	if err != nil {
-		return nil, err
-	}
-
-	return entry, err
+	return valRefs[0], uint64(atRevision), nil
 }
 
 func (d *db) resolveValue(
@@ -677,21 +1009,29 @@ func (d *db) resolveValue(
 	}
 
 	// Reference lookup
-	if val[0] == ReferenceValuePrefix {
-		if len(val) < 1+8 {
-			return nil, fmt.Errorf("%w: internal value consistency error - invalid reference", store.ErrCorruptedData)
+	if isReferenceValue(val) {
+		refKey, atTx, attrs, err := unwrapReferenceValue(val)
+		if err != nil {
+			return nil, err
 		}
 
 		if resolved == MaxKeyResolutionLimit {
 			return nil, ErrKeyResolutionLimitReached
 		}
 
-		atTx := binary.BigEndian.Uint64(TrimPrefix(val))
-		refKey := make([]byte, len(val)-1-8)
-		copy(refKey, val[1+8:])
+		if resolved == 0 {
+			atomic.AddUint64(&d.referenceMetrics.resolutions, 1)
+		}
+		atomic.AddUint64(&d.referenceMetrics.hops, 1)
 
 		if index != nil {
 			entry, err = d.getAtTx(ctx, refKey, atTx, resolved+1, index, 0, skipIntegrityCheck)
+			if errors.Is(err, store.ErrKeyNotFound) && atTx == 0 {
+				entry, err = d.getRenamed(ctx, refKey, resolved+1, index, skipIntegrityCheck)
+			}
+			if errors.Is(err, store.ErrKeyNotFound) && resolved == 0 {
+				atomic.AddUint64(&d.referenceMetrics.notFound, 1)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -702,13 +1042,9 @@ func (d *db) resolveValue(
 			}
 		}
 
-		entry.ReferencedBy = &schema.Reference{
-			Tx:       txID,
-			Key:      TrimPrefix(key),
-			Metadata: schema.KVMetadataToProto(md),
-			AtTx:     atTx,
-			Revision: revision,
-		}
+		entry.Kind = schema.EntryKind_REFERENCE
+
+		entry.ReferencedBy = referenceDescriptor(key, txID, md, refKey, atTx, revision, attrs)
 
 		return entry, nil
 	}
@@ -719,6 +1055,7 @@ func (d *db) resolveValue(
 		Metadata: schema.KVMetadataToProto(md),
 		Value:    TrimPrefix(val),
 		Revision: revision,
+		Kind:     schema.EntryKind_DIRECT,
 	}, nil
 }
 
@@ -811,42 +1148,175 @@ func (d *db) VerifiableGet(ctx context.Context, req *schema.VerifiableGetRequest
 	}
 
 	e, err := d.Get(ctx, req.KeyRequest)
+	if errors.Is(err, store.ErrExpiredEntry) && req.KeyRequest.AtRevision == 0 {
+		// expired values can not be read but the inclusion of the entry can still be proven
+		e, err = d.expiredEntryAt(ctx, req.KeyRequest.Key, req.KeyRequest.AtTx)
+		if err == nil && req.KeyRequest.ReturnDigest {
+			err = d.setEntryDigest(e)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var vTxID uint64
-	var vKey []byte
+	vTxID, vKey := d.verifiableKey(e)
 
-	if e.ReferencedBy == nil {
-		vTxID = e.Tx
-		vKey = e.Key
-	} else {
-		vTxID = e.ReferencedBy.Tx
-		vKey = e.ReferencedBy.Key
+	vEntry, err := d.verifiableEntry(e, vTxID, vKey, req.ProveSinceTx)
+	if err != nil {
+		return nil, err
 	}
 
-	// key-value inclusion proof
+	if req.ProveReferencedEntry && e.ReferencedBy != nil {
+		vEntry.ReferencedEntryProof, err = d.referencedEntryProof(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return vEntry, nil
+}
+
+// referencedEntryProof proves the inclusion of the entry a reference resolves to in the transaction it was set at,
+// and the consistency of such transaction with the one the reference was set at, so the entry is proven to be
+// the one the proven reference points at
+func (d *db) referencedEntryProof(ctx context.Context, e *schema.Entry) (*schema.ReferencedEntryProof, error) {
 	tx, err := d.allocTx()
 	if err != nil {
 		return nil, err
 	}
 	defer d.releaseTx(tx)
 
-	err = d.st.ReadTx(vTxID, false, tx)
+	err = d.st.ReadTx(e.Tx, false, tx)
 	if err != nil {
 		return nil, err
 	}
 
-	var rootTxHdr *store.TxHeader
+	inclusionProof, err := tx.Proof(EncodeKey(d.storedKey(e.Key)))
+	if err != nil {
+		return nil, err
+	}
 
-	if req.ProveSinceTx == 0 {
-		rootTxHdr = tx.Header()
-	} else {
-		rootTxHdr, err = d.st.ReadTxHeader(req.ProveSinceTx, false, false)
+	refTxHdr, err := d.st.ReadTxHeader(e.ReferencedBy.Tx, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceTxHdr, targetTxHdr := tx.Header(), refTxHdr
+	if sourceTxHdr.ID > targetTxHdr.ID {
+		// unbound references may resolve to entries set after the reference
+		sourceTxHdr, targetTxHdr = targetTxHdr, sourceTxHdr
+	}
+
+	dualProof, err := d.st.DualProof(sourceTxHdr, targetTxHdr)
+	if err != nil {
+		return nil, err
+	}
+
+	// the referenced key may have been renamed after the reference was set
+	renameLinkProofs, err := d.renameLinkProofs(ctx, e.ReferencedBy.ReferencedKey, d.storedKey(e.Key), refTxHdr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema.ReferencedEntryProof{
+		InclusionProof:   schema.InclusionProofToProto(inclusionProof),
+		DualProof:        schema.DualProofToProto(dualProof),
+		RenameLinkProofs: renameLinkProofs,
+	}, nil
+}
+
+// expiredEntryAt returns the entry of the key set at the given transaction, or the latest one when atTx is 0,
+// flagged as expired. The value is not returned but its hash, so the inclusion of the entry can be verified.
+// ErrExpiredEntry is returned if the entry is not expired.
+func (d *db) expiredEntryAt(ctx context.Context, key []byte, atTx uint64) (*schema.Entry, error) {
+	storedKey := EncodeKey(d.storedKey(key))
+
+	if atTx == 0 {
+		valRef, err := d.st.GetWithFilters(ctx, storedKey)
 		if err != nil {
 			return nil, err
 		}
+
+		atTx = valRef.Tx()
+	}
+
+	txEntry, _, err := d.st.ReadTxEntry(atTx, storedKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	md := txEntry.Metadata()
+	if md == nil || !md.ExpiredAt(d.options.storeOpts.TimeFunc()) {
+		return nil, store.ErrExpiredEntry
+	}
+
+	hVal := txEntry.HVal()
+
+	return &schema.Entry{
+		Tx:        atTx,
+		Key:       key,
+		Metadata:  schema.KVMetadataToProto(md),
+		Expired:   true,
+		ValueHash: hVal[:],
+	}, nil
+}
+
+// setEntryDigest sets the digest of the entry as included in the transaction it is proven against,
+// i.e. the leaf digest expected by store.VerifyInclusion, and the version of such transaction header
+func (d *db) setEntryDigest(e *schema.Entry) error {
+	txID, key := e.Tx, d.storedKey(e.Key)
+	if e.ReferencedBy != nil {
+		txID, key = e.ReferencedBy.Tx, e.ReferencedBy.Key
+	}
+
+	txEntry, hdr, err := d.st.ReadTxEntry(txID, EncodeKey(key), false)
+	if err != nil {
+		return err
+	}
+
+	txEntryDigest, err := hdr.TxEntryDigest()
+	if err != nil {
+		return err
+	}
+
+	digest, err := txEntryDigest(txEntry)
+	if err != nil {
+		return err
+	}
+
+	e.Digest = digest[:]
+	e.DigestVersion = int32(hdr.Version)
+
+	return nil
+}
+
+// verifiableKey returns the transaction and the key to be proven for the entry
+func (d *db) verifiableKey(e *schema.Entry) (uint64, []byte) {
+	if e.ReferencedBy != nil {
+		return e.ReferencedBy.Tx, e.ReferencedBy.Key
+	}
+
+	if d.keyObfuscationEnabled() {
+		// the stored key is the one included in the transaction
+		e.Key = d.storedKey(e.Key)
+	}
+
+	return e.Tx, e.Key
+}
+
+// verifiableEntry proves the inclusion of the key in the transaction and the consistency of such
+// transaction with the one the proof is requested since
+func (d *db) verifiableEntry(e *schema.Entry, vTxID uint64, vKey []byte, proveSinceTx uint64) (*schema.VerifiableEntry, error) {
+	// key-value inclusion proof
+	tx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(tx)
+
+	err = d.st.ReadTx(vTxID, false, tx)
+	if err != nil {
+		return nil, err
 	}
 
 	inclusionProof, err := tx.Proof(EncodeKey(vKey))
@@ -854,9 +1324,36 @@ func (d *db) VerifiableGet(ctx context.Context, req *schema.VerifiableGetRequest
 		return nil, err
 	}
 
+	verifiableTx, err := d.verifiableTx(tx, proveSinceTx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema.VerifiableEntry{
+		Entry:          e,
+		VerifiableTx:   verifiableTx,
+		InclusionProof: schema.InclusionProofToProto(inclusionProof),
+	}, nil
+}
+
+// verifiableTx proves the consistency of the transaction with the one the proof is requested since
+func (d *db) verifiableTx(tx *store.Tx, proveSinceTx uint64) (*schema.VerifiableTx, error) {
+	var rootTxHdr *store.TxHeader
+
+	if proveSinceTx == 0 {
+		rootTxHdr = tx.Header()
+	} else {
+		var err error
+
+		rootTxHdr, err = d.st.ReadTxHeader(proveSinceTx, false, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var sourceTxHdr, targetTxHdr *store.TxHeader
 
-	if req.ProveSinceTx <= vTxID {
+	if proveSinceTx <= tx.Header().ID {
 		sourceTxHdr = rootTxHdr
 		targetTxHdr = tx.Header()
 	} else {
@@ -869,15 +1366,9 @@ func (d *db) VerifiableGet(ctx context.Context, req *schema.VerifiableGetRequest
 		return nil, err
 	}
 
-	verifiableTx := &schema.VerifiableTx{
+	return &schema.VerifiableTx{
 		Tx:        schema.TxToProto(tx),
 		DualProof: schema.DualProofToProto(dualProof),
-	}
-
-	return &schema.VerifiableEntry{
-		Entry:          e,
-		VerifiableTx:   verifiableTx,
-		InclusionProof: schema.InclusionProofToProto(inclusionProof),
 	}, nil
 }
 
@@ -920,7 +1411,7 @@ func (d *db) Delete(ctx context.Context, req *schema.DeleteKeysRequest) (*schema
 
 		md.AsDeleted(true)
 
-		e := EncodeEntrySpec(k, md, nil)
+		e := EncodeEntrySpec(d.storedKey(k), md, nil)
 
 		err = tx.Delete(ctx, e.Key)
 		if err != nil {
@@ -941,8 +1432,14 @@ func (d *db) Delete(ctx context.Context, req *schema.DeleteKeysRequest) (*schema
 	return schema.TxHeaderToProto(hdr), nil
 }
 
-// GetAll ...
+// GetAll returns the entries of the keys found, plain keys and references are all read from the same snapshot
+// thus references are resolved consistently with the rest of the keys. As with Get, references are only resolved
+// once effective and their transform, if any, is applied.
 func (d *db) GetAll(ctx context.Context, req *schema.KeyListRequest) (*schema.Entries, error) {
+	if req == nil {
+		return nil, ErrIllegalArguments
+	}
+
 	snap, err := d.snapshotSince(ctx, []byte{SetKeyPrefix}, req.SinceTx)
 	if err != nil {
 		return nil, err
@@ -952,14 +1449,25 @@ func (d *db) GetAll(ctx context.Context, req *schema.KeyListRequest) (*schema.En
 	list := &schema.Entries{}
 
 	for _, key := range req.Keys {
-		e, err := d.get(ctx, EncodeKey(key), snap, true)
-		if err == nil || errors.Is(err, store.ErrKeyNotFound) {
-			if e != nil {
-				list.Entries = append(list.Entries, e)
-			}
-		} else {
+		encKey := EncodeKey(d.storedKey(key))
+
+		e, err := d.get(ctx, encKey, snap, true)
+		if err == nil {
+			e, err = d.effectiveReference(ctx, encKey, e, snap)
+		}
+		if errors.Is(err, store.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
 			return nil, err
 		}
+
+		err = d.completeEntry(key, e, true)
+		if err != nil {
+			return nil, err
+		}
+
+		list.Entries = append(list.Entries, e)
 	}
 
 	return list, nil
@@ -1356,6 +1864,8 @@ func (d *db) ExportTxByID(ctx context.Context, req *schema.ExportTxRequest) (txb
 		if err != nil {
 			return nil, mayCommitUpToTxID, mayCommitUpToAlh, err
 		}
+
+		d.replicationAcks.ack(req.ReplicaState.UUID, req.ReplicaState.PrecommittedTxID)
 	}
 
 	// it might be the case primary will commit some txs (even there could be inmem-precommitted txs)
@@ -1600,7 +2110,7 @@ func (d *db) History(ctx context.Context, req *schema.HistoryRequest) (*schema.E
 		limit = d.maxResultSize
 	}
 
-	key := EncodeKey(req.Key)
+	key := EncodeKey(d.storedKey(req.Key))
 
 	valRefs, _, err := d.st.History(key, req.Offset, req.Desc, limit)
 	if err != nil && err != store.ErrOffsetOutOfRange {
@@ -1612,7 +2122,7 @@ func (d *db) History(ctx context.Context, req *schema.HistoryRequest) (*schema.E
 	}
 
 	for i, valRef := range valRefs {
-		val, err := valRef.Resolve()
+		val, err := valRef.ResolveContext(ctx)
 		if err != nil && err != store.ErrExpiredEntry {
 			return nil, err
 		}
@@ -1632,6 +2142,234 @@ func (d *db) History(ctx context.Context, req *schema.HistoryRequest) (*schema.E
 	return list, nil
 }
 
+// GetVersions returns up to k of the most recent revisions of a key, newest first. References are resolved
+// and the revisions of the referenced key are returned, up to the bound transaction for bound references.
+func (d *db) GetVersions(ctx context.Context, key []byte, k int, sinceTx uint64) ([]*schema.Entry, error) {
+	if len(key) == 0 || k <= 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	if k > d.maxResultSize {
+		return nil, fmt.Errorf("%w: the specified limit (%d) is larger than the maximum allowed one (%d)",
+			ErrResultSizeLimitExceeded, k, d.maxResultSize)
+	}
+
+	currTxID, _ := d.st.CommittedAlh()
+
+	if sinceTx > currTxID {
+		return nil, ErrIllegalArguments
+	}
+
+	waitUntilTx := sinceTx
+	if waitUntilTx == 0 {
+		waitUntilTx = currTxID
+	}
+
+	err := d.WaitForIndexingUpto(ctx, waitUntilTx)
+	if err != nil {
+		return nil, err
+	}
+
+	storedKey := EncodeKey(d.storedKey(key))
+
+	entry, err := d.get(ctx, storedKey, d.st, true)
+	if err == nil {
+		// references are only followed once effective
+		entry, err = d.effectiveReference(ctx, storedKey, entry, d.st)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var boundTx uint64
+
+	if entry.ReferencedBy != nil {
+		key = entry.Key
+		storedKey = EncodeKey(entry.Key)
+		boundTx = entry.ReferencedBy.AtTx
+	}
+
+	versions := make([]*schema.Entry, 0, k)
+
+	for offset := uint64(0); len(versions) < k; {
+		valRefs, _, err := d.st.History(storedKey, offset, true, k)
+		if errors.Is(err, store.ErrOffsetOutOfRange) || errors.Is(err, store.ErrNoMoreEntries) {
+			// the whole history was already read
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, valRef := range valRefs {
+			if len(versions) == k {
+				break
+			}
+
+			if boundTx > 0 && valRef.Tx() > boundTx {
+				continue
+			}
+
+			val, err := valRef.Resolve()
+			if err != nil && err != store.ErrExpiredEntry {
+				return nil, err
+			}
+			if len(val) > 0 {
+				val = TrimPrefix(val)
+			}
+
+			versions = append(versions, &schema.Entry{
+				Tx:       valRef.Tx(),
+				Key:      key,
+				Metadata: schema.KVMetadataToProto(valRef.KVMetadata()),
+				Value:    val,
+				Expired:  errors.Is(err, store.ErrExpiredEntry),
+				Revision: valRef.HC(),
+			})
+		}
+
+		if len(valRefs) < k {
+			break
+		}
+
+		offset += uint64(len(valRefs))
+	}
+
+	return versions, nil
+}
+
+// VerifiableHistory sends the revisions of a key committed up to the current transaction, which is
+// used as the target all revisions are proven against. The first message holds the proof between
+// ProveSinceTx and the target transaction, each of the following ones a single revision with its proofs.
+// Revisions are read in pages, thus memory usage does not depend on the size of the history.
+func (d *db) VerifiableHistory(ctx context.Context, req *schema.VerifiableHistoryRequest, send func(*schema.VerifiableHistoryResponse) error) error {
+	if req == nil || len(req.Key) == 0 || send == nil {
+		return ErrIllegalArguments
+	}
+
+	targetTxID, _ := d.st.CommittedAlh()
+	if targetTxID < req.ProveSinceTx {
+		return ErrIllegalState
+	}
+
+	err := d.WaitForIndexingUpto(ctx, targetTxID)
+	if err != nil {
+		return err
+	}
+
+	targetTxHdr, err := d.st.ReadTxHeader(targetTxID, false, false)
+	if err != nil {
+		return err
+	}
+
+	sourceTxHdr := targetTxHdr
+
+	if req.ProveSinceTx > 0 {
+		sourceTxHdr, err = d.st.ReadTxHeader(req.ProveSinceTx, false, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	dualProof, err := d.st.DualProof(sourceTxHdr, targetTxHdr)
+	if err != nil {
+		return err
+	}
+
+	err = send(&schema.VerifiableHistoryResponse{DualProof: schema.DualProofToProto(dualProof)})
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.allocTx()
+	if err != nil {
+		return err
+	}
+	defer d.releaseTx(tx)
+
+	// verifiable entries hold the stored key, as it's the one included in the transaction
+	storedKey := d.storedKey(req.Key)
+	key := EncodeKey(storedKey)
+
+	var offset uint64
+	if req.FromRevision > 0 {
+		offset = req.FromRevision - 1
+	}
+
+	var sent uint64
+
+	for {
+		pageSize := d.maxResultSize
+		if req.Limit > 0 && req.Limit-sent < uint64(pageSize) {
+			pageSize = int(req.Limit - sent)
+		}
+
+		valRefs, _, err := d.st.History(key, offset, false, pageSize)
+		if errors.Is(err, store.ErrOffsetOutOfRange) || errors.Is(err, store.ErrNoMoreEntries) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, valRef := range valRefs {
+			if valRef.Tx() > targetTxID {
+				// revisions committed after the target transaction are not included
+				return nil
+			}
+
+			val, err := valRef.Resolve()
+			if err != nil && !errors.Is(err, store.ErrExpiredEntry) {
+				return err
+			}
+			if len(val) > 0 {
+				val = TrimPrefix(val)
+			}
+
+			expired := errors.Is(err, store.ErrExpiredEntry)
+
+			err = d.st.ReadTx(valRef.Tx(), false, tx)
+			if err != nil {
+				return err
+			}
+
+			inclusionProof, err := tx.Proof(key)
+			if err != nil {
+				return err
+			}
+
+			entryDualProof, err := d.st.DualProof(tx.Header(), targetTxHdr)
+			if err != nil {
+				return err
+			}
+
+			err = send(&schema.VerifiableHistoryResponse{
+				Entry: &schema.Entry{
+					Tx:       valRef.Tx(),
+					Key:      storedKey,
+					Metadata: schema.KVMetadataToProto(valRef.KVMetadata()),
+					Value:    val,
+					Expired:  expired,
+					Revision: valRef.HC(),
+				},
+				InclusionProof: schema.InclusionProofToProto(inclusionProof),
+				EntryDualProof: schema.DualProofToProto(entryDualProof),
+			})
+			if err != nil {
+				return err
+			}
+
+			sent++
+		}
+
+		if len(valRefs) < pageSize || (req.Limit > 0 && sent == req.Limit) {
+			return nil
+		}
+
+		offset += uint64(len(valRefs))
+	}
+}
+
 func (d *db) IsClosed() bool {
 	d.closingMutex.Lock()
 	defer d.closingMutex.Unlock()
@@ -348,6 +348,242 @@ func TestDbSetGet(t *testing.T) {
 	require.ErrorIs(t, err, ErrIllegalArguments)
 }
 
+func TestGetReturningDigest(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	txhdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref1"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("key1")})
+	require.NoError(t, err)
+	require.Nil(t, entry.Digest)
+
+	entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("key1"), ReturnDigest: true})
+	require.NoError(t, err)
+	require.Equal(t, int32(txhdr.Version), entry.DigestVersion)
+
+	entrySpecDigest, err := store.EntrySpecDigestFor(int(entry.DigestVersion))
+	require.NoError(t, err)
+
+	digest := entrySpecDigest(EncodeEntrySpec(entry.Key, schema.KVMetadataFromProto(entry.Metadata), entry.Value))
+	require.Equal(t, digest[:], entry.Digest)
+
+	for _, key := range []string{"key1", "ref1"} {
+		vitem, err := db.VerifiableGet(ctx, &schema.VerifiableGetRequest{
+			KeyRequest: &schema.KeyRequest{Key: []byte(key), ReturnDigest: true},
+		})
+		require.NoError(t, err)
+		require.Len(t, vitem.Entry.Digest, sha256.Size)
+
+		var entryDigest [sha256.Size]byte
+		copy(entryDigest[:], vitem.Entry.Digest)
+
+		verifies := store.VerifyInclusion(
+			schema.InclusionProofFromProto(vitem.InclusionProof),
+			entryDigest,
+			schema.DigestFromProto(vitem.VerifiableTx.DualProof.TargetTxHeader.EH),
+		)
+		require.True(t, verifies)
+	}
+}
+
+func TestGetAt(t *testing.T) {
+	db := makeDb(t)
+
+	_, err := db.GetAt(context.Background(), nil, 1)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	hdr1, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k1"), Value: []byte("v1")}}})
+	require.NoError(t, err)
+
+	hdr2, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k2"), Value: []byte("v1")}}})
+	require.NoError(t, err)
+
+	hdr3, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k1"), Value: []byte("v2")}}})
+	require.NoError(t, err)
+
+	refHdr, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte("ref"),
+		ReferencedKey: []byte("k1"),
+	})
+	require.NoError(t, err)
+
+	boundRefHdr, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte("boundRef"),
+		ReferencedKey: []byte("k1"),
+		AtTx:          hdr1.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	hdr6, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k1"), Value: []byte("v3")}}})
+	require.NoError(t, err)
+
+	delHdr, err := db.Delete(context.Background(), &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("k1")}})
+	require.NoError(t, err)
+
+	_, err = db.GetAt(context.Background(), []byte("k1"), 0)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	_, err = db.GetAt(context.Background(), []byte("k1"), delHdr.Id+1)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	_, err = db.GetAt(context.Background(), []byte("k2"), hdr1.Id)
+	require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	entry, err := db.GetAt(context.Background(), []byte("k1"), hdr2.Id)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), entry.Value)
+	require.Equal(t, hdr1.Id, entry.Tx)
+
+	entry, err = db.GetAt(context.Background(), []byte("k1"), hdr3.Id)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), entry.Value)
+	require.Equal(t, hdr3.Id, entry.Tx)
+
+	_, err = db.GetAt(context.Background(), []byte("k1"), delHdr.Id)
+	require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	_, err = db.GetAt(context.Background(), []byte("ref"), hdr3.Id)
+	require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	entry, err = db.GetAt(context.Background(), []byte("ref"), boundRefHdr.Id)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), entry.Value)
+	require.Equal(t, refHdr.Id, entry.ReferencedBy.Tx)
+
+	entry, err = db.GetAt(context.Background(), []byte("ref"), hdr6.Id)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v3"), entry.Value)
+
+	entry, err = db.GetAt(context.Background(), []byte("boundRef"), hdr6.Id)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), entry.Value)
+	require.Equal(t, hdr1.Id, entry.Tx)
+}
+
+func TestTxLimits(t *testing.T) {
+	opts := DefaultOption().WithDBRootPath(t.TempDir())
+	opts.WithStoreOptions(opts.storeOpts.WithMaxTxEntries(4).WithMaxKeyLen(16).WithMaxValueLen(32))
+
+	db := makeDbWith(t, "db", opts)
+
+	maxKeyLen, maxValueLen, maxTxEntries := db.TxLimits()
+	require.Equal(t, 15, maxKeyLen)
+	require.Equal(t, 31, maxValueLen)
+	require.Equal(t, 4, maxTxEntries)
+
+	kvs := make([]*schema.KeyValue, maxTxEntries)
+	for i := range kvs {
+		kvs[i] = &schema.KeyValue{
+			Key:   []byte(fmt.Sprintf("%0*d", maxKeyLen, i)),
+			Value: make([]byte, maxValueLen),
+		}
+	}
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: kvs})
+	require.NoError(t, err)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: append(kvs, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")})})
+	require.ErrorIs(t, err, store.ErrMaxTxEntriesLimitExceeded)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: make([]byte, maxKeyLen+1), Value: []byte("value")}}})
+	require.ErrorIs(t, err, store.ErrMaxKeyLenExceeded)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: make([]byte, maxValueLen+1)}}})
+	require.ErrorIs(t, err, store.ErrMaxValueLenExceeded)
+
+	t.Run("with key obfuscation", func(t *testing.T) {
+		opts := DefaultOption().WithDBRootPath(t.TempDir()).WithKeyObfuscationSecret([]byte("0123456789abcdef"))
+		opts.WithStoreOptions(opts.storeOpts.WithMaxTxEntries(4))
+
+		db := makeDbWith(t, "db", opts)
+
+		_, _, maxTxEntries := db.TxLimits()
+		require.Equal(t, 2, maxTxEntries)
+
+		kvs := make([]*schema.KeyValue, maxTxEntries)
+		for i := range kvs {
+			kvs[i] = &schema.KeyValue{Key: []byte(fmt.Sprintf("key%d", i)), Value: []byte("value")}
+		}
+
+		_, err := db.Set(context.Background(), &schema.SetRequest{KVs: kvs})
+		require.NoError(t, err)
+
+		_, err = db.Set(context.Background(), &schema.SetRequest{KVs: append(kvs, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")})})
+		require.ErrorIs(t, err, store.ErrMaxTxEntriesLimitExceeded)
+	})
+}
+
+func TestGetSinceTxWhileIndexing(t *testing.T) {
+	db := makeDb(t)
+
+	hdr1, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	// an additional index whose mapping blocks keeps indexing behind the committed transactions
+	release := make(chan struct{})
+
+	laggingPrefix := WrapWithPrefix([]byte("lagging"), SetKeyPrefix)
+
+	err = db.st.InitIndexing(&store.IndexSpec{
+		SourcePrefix: laggingPrefix,
+		TargetPrefix: laggingPrefix,
+		SourceEntryMapper: func(key, value []byte) ([]byte, error) {
+			<-release
+			return key, nil
+		},
+	})
+	require.NoError(t, err)
+
+	hdr2, err := db.Set(context.Background(), &schema.SetRequest{
+		KVs: []*schema.KeyValue{
+			{Key: []byte("lagging_key"), Value: []byte("value")},
+			{Key: []byte("key1"), Value: []byte("value2")},
+		},
+		NoWait: true,
+	})
+	require.NoError(t, err)
+	require.False(t, db.st.IsIndexedUpto(hdr2.Id))
+
+	t.Run("reading with SinceTx set to zero should not wait for indexing", func(t *testing.T) {
+		_, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte("key1")})
+		require.NoError(t, err)
+
+		// the currently indexed state does not include the transactions not yet indexed asynchronously
+		_, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte("lagging_key")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("reading with NoWait should fail fast when the index is behind SinceTx", func(t *testing.T) {
+		_, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte("key1"), SinceTx: hdr2.Id, NoWait: true})
+		require.ErrorIs(t, err, ErrIndexNotUpToDate)
+
+		_, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte("key1"), SinceTx: hdr1.Id, NoWait: true})
+		require.NoError(t, err)
+	})
+
+	t.Run("reading without NoWait should wait for the index to include SinceTx", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("key1"), SinceTx: hdr2.Id})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte("key1"), SinceTx: hdr2.Id})
+		require.NoError(t, err)
+		require.Equal(t, []byte("value2"), entry.Value)
+		require.Equal(t, hdr2.Id, entry.Tx)
+	})
+}
+
 func TestDelete(t *testing.T) {
 	db := makeDb(t)
 
@@ -438,6 +674,29 @@ func TestCurrentState(t *testing.T) {
 	}
 }
 
+func TestCommitTimestamp(t *testing.T) {
+	db := makeDb(t)
+
+	start := time.Now().Truncate(time.Second)
+
+	var prevTs time.Time
+
+	for i := 0; i < 3; i++ {
+		hdr, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}}})
+		require.NoError(t, err)
+		require.NotZero(t, hdr.Ts)
+		require.False(t, hdr.Time().Before(prevTs))
+
+		prevTs = hdr.Time()
+	}
+
+	hdr, err := db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("key")})
+	require.NoError(t, err)
+	require.False(t, hdr.Time().Before(prevTs))
+	require.False(t, hdr.Time().Before(start))
+	require.False(t, hdr.Time().After(time.Now()))
+}
+
 func TestSafeSetGet(t *testing.T) {
 	db := makeDb(t)
 
@@ -549,6 +808,93 @@ func TestSetGetAll(t *testing.T) {
 	}
 }
 
+func TestGetAllWithReferences(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("key1"), Value: []byte("v1")},
+		{Key: []byte("key2"), Value: []byte("a")},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("pinned"), ReferencedKey: []byte("key1"), AtTx: hdr.Id, BoundRef: true})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("latest"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("scheduled"),
+		ReferencedKey: []byte("key2"),
+		EffectiveFrom: time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("v2")}}})
+	require.NoError(t, err)
+
+	t.Run("invalid requests should fail", func(t *testing.T) {
+		_, err := db.GetAll(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("references should be resolved along with plain keys", func(t *testing.T) {
+		entries, err := db.GetAll(ctx, &schema.KeyListRequest{
+			Keys: [][]byte{[]byte("key1"), []byte("pinned"), []byte("latest"), []byte("scheduled"), []byte("missing"), []byte("key2")},
+		})
+		require.NoError(t, err)
+		require.Len(t, entries.Entries, 4)
+
+		require.Equal(t, []byte("v2"), entries.Entries[0].Value)
+		require.Nil(t, entries.Entries[0].ReferencedBy)
+
+		require.Equal(t, []byte("v1"), entries.Entries[1].Value)
+		require.Equal(t, []byte("pinned"), entries.Entries[1].ReferencedBy.Key)
+		require.Equal(t, hdr.Id, entries.Entries[1].Tx)
+
+		require.Equal(t, []byte("v2"), entries.Entries[2].Value)
+		require.Equal(t, []byte("latest"), entries.Entries[2].ReferencedBy.Key)
+
+		require.Equal(t, []byte("a"), entries.Entries[3].Value)
+		require.Nil(t, entries.Entries[3].ReferencedBy)
+	})
+
+	t.Run("plain keys and references should be read from the same snapshot", func(t *testing.T) {
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		done := make(chan struct{})
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte(fmt.Sprintf("v%d", i))}}})
+				require.NoError(t, err)
+			}
+		}()
+
+		for i := 0; i < 100; i++ {
+			entries, err := db.GetAll(ctx, &schema.KeyListRequest{Keys: [][]byte{[]byte("key1"), []byte("latest")}})
+			require.NoError(t, err)
+			require.Len(t, entries.Entries, 2)
+			require.Equal(t, entries.Entries[0].Value, entries.Entries[1].Value)
+			require.Equal(t, entries.Entries[0].Tx, entries.Entries[1].Tx)
+		}
+
+		close(done)
+		wg.Wait()
+	})
+}
+
 func TestTxByID(t *testing.T) {
 	db := makeDb(t)
 
@@ -1072,6 +1418,205 @@ func TestHistory(t *testing.T) {
 	require.Empty(t, inc.Entries)
 }
 
+func TestGetVersions(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	var txIDs []uint64
+
+	for i := 1; i <= 5; i++ {
+		hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte(fmt.Sprintf("value%d", i))}}})
+		require.NoError(t, err)
+
+		txIDs = append(txIDs, hdr.Id)
+	}
+
+	requireVersions := func(t *testing.T, versions []*schema.Entry, newest int) {
+		for i, e := range versions {
+			rev := newest - i
+
+			require.Equal(t, []byte("key"), e.Key)
+			require.Equal(t, []byte(fmt.Sprintf("value%d", rev)), e.Value)
+			require.Equal(t, txIDs[rev-1], e.Tx)
+			require.EqualValues(t, rev, e.Revision)
+		}
+	}
+
+	t.Run("invalid arguments should be rejected", func(t *testing.T) {
+		_, err := db.GetVersions(ctx, nil, 1, 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.GetVersions(ctx, []byte("key"), 0, 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.GetVersions(ctx, []byte("key"), db.MaxResultSize()+1, 0)
+		require.ErrorIs(t, err, ErrResultSizeLimitExceeded)
+
+		_, err = db.GetVersions(ctx, []byte("key"), 1, txIDs[4]+1)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.GetVersions(ctx, []byte("missing"), 1, 0)
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("the newest revisions should be returned in descending order", func(t *testing.T) {
+		versions, err := db.GetVersions(ctx, []byte("key"), 3, 0)
+		require.NoError(t, err)
+		require.Len(t, versions, 3)
+		requireVersions(t, versions, 5)
+	})
+
+	t.Run("all revisions should be returned when there are less than requested", func(t *testing.T) {
+		versions, err := db.GetVersions(ctx, []byte("key"), 10, 0)
+		require.NoError(t, err)
+		require.Len(t, versions, 5)
+		requireVersions(t, versions, 5)
+	})
+
+	t.Run("references should be resolved to the revisions of the referenced key", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("tag"), ReferencedKey: []byte("key")})
+		require.NoError(t, err)
+
+		versions, err := db.GetVersions(ctx, []byte("tag"), 2, 0)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		requireVersions(t, versions, 5)
+	})
+
+	t.Run("bound references should not return newer revisions", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("boundTag"), ReferencedKey: []byte("key"), AtTx: txIDs[2], BoundRef: true})
+		require.NoError(t, err)
+
+		versions, err := db.GetVersions(ctx, []byte("boundTag"), 2, 0)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		requireVersions(t, versions, 3)
+	})
+
+	t.Run("bound references should be resolved when the history ends at a page boundary", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("firstTag"), ReferencedKey: []byte("key"), AtTx: txIDs[0], BoundRef: true})
+		require.NoError(t, err)
+
+		versions, err := db.GetVersions(ctx, []byte("firstTag"), 5, 0)
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+		requireVersions(t, versions, 1)
+	})
+}
+
+
+func TestVerifiableHistory(t *testing.T) {
+	db := makeDb(t)
+
+	db.maxResultSize = 3
+
+	revisions := 10
+
+	for i := 1; i <= revisions; i++ {
+		_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{
+			{Key: []byte("key1"), Value: []byte(fmt.Sprintf("value%d", i))},
+			{Key: []byte(fmt.Sprintf("other%d", i)), Value: []byte("other")},
+		}})
+		require.NoError(t, err)
+	}
+
+	trustedState, err := db.CurrentState()
+	require.NoError(t, err)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key2"), Value: []byte("value")}}})
+	require.NoError(t, err)
+
+	err = db.VerifiableHistory(context.Background(), nil, nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	err = db.VerifiableHistory(context.Background(), &schema.VerifiableHistoryRequest{Key: []byte("key1"), ProveSinceTx: math.MaxUint64},
+		func(res *schema.VerifiableHistoryResponse) error { return nil })
+	require.ErrorIs(t, err, ErrIllegalState)
+
+	// streamHistory verifies each message as it's received and returns the verified revisions
+	streamHistory := func(t *testing.T, req *schema.VerifiableHistoryRequest) []*schema.Entry {
+		var targetID uint64
+		var targetAlh [sha256.Size]byte
+		var entries []*schema.Entry
+
+		err := db.VerifiableHistory(context.Background(), req, func(res *schema.VerifiableHistoryResponse) error {
+			if targetID == 0 {
+				require.NotNil(t, res.DualProof)
+				require.Nil(t, res.Entry)
+
+				dualProof := schema.DualProofFromProto(res.DualProof)
+
+				targetID = dualProof.TargetTxHeader.ID
+				targetAlh = dualProof.TargetTxHeader.Alh()
+
+				var trustedAlh [sha256.Size]byte
+				copy(trustedAlh[:], trustedState.TxHash)
+
+				require.True(t, store.VerifyDualProof(dualProof, trustedState.TxId, targetID, trustedAlh, targetAlh))
+
+				return nil
+			}
+
+			require.Nil(t, res.DualProof)
+			require.NotNil(t, res.Entry)
+
+			dualProof := schema.DualProofFromProto(res.EntryDualProof)
+			txHdr := dualProof.SourceTxHeader
+			require.Equal(t, res.Entry.Tx, txHdr.ID)
+
+			entrySpec := EncodeEntrySpec(res.Entry.Key, schema.KVMetadataFromProto(res.Entry.Metadata), res.Entry.Value)
+
+			entrySpecDigest, err := store.EntrySpecDigestFor(txHdr.Version)
+			require.NoError(t, err)
+
+			inclusionProof := schema.InclusionProofFromProto(res.InclusionProof)
+			require.True(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(entrySpec), txHdr.Eh))
+
+			require.True(t, store.VerifyDualProof(dualProof, txHdr.ID, targetID, txHdr.Alh(), targetAlh))
+
+			entries = append(entries, res.Entry)
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.NotZero(t, targetID)
+
+		return entries
+	}
+
+	t.Run("all revisions should be streamed and verified", func(t *testing.T) {
+		entries := streamHistory(t, &schema.VerifiableHistoryRequest{Key: []byte("key1"), ProveSinceTx: trustedState.TxId})
+		require.Len(t, entries, revisions)
+
+		for i, entry := range entries {
+			require.Equal(t, uint64(i+1), entry.Revision)
+			require.Equal(t, []byte(fmt.Sprintf("value%d", i+1)), entry.Value)
+		}
+	})
+
+	t.Run("streaming should be resumed from a revision", func(t *testing.T) {
+		entries := streamHistory(t, &schema.VerifiableHistoryRequest{
+			Key:          []byte("key1"),
+			FromRevision: 5,
+			Limit:        4,
+			ProveSinceTx: trustedState.TxId,
+		})
+		require.Len(t, entries, 4)
+
+		for i, entry := range entries {
+			require.Equal(t, uint64(i+5), entry.Revision)
+			require.Equal(t, []byte(fmt.Sprintf("value%d", i+5)), entry.Value)
+		}
+
+		entries = streamHistory(t, &schema.VerifiableHistoryRequest{
+			Key:          []byte("key1"),
+			FromRevision: uint64(revisions + 1),
+			ProveSinceTx: trustedState.TxId,
+		})
+		require.Empty(t, entries)
+	})
+}
 func TestPreconditionedSet(t *testing.T) {
 	db := makeDb(t)
 
@@ -17,8 +17,10 @@ limitations under the License.
 package database
 
 import (
+	"crypto/ecdsa"
 	"time"
 
+	"github.com/codenotary/immudb/embedded/document"
 	"github.com/codenotary/immudb/embedded/store"
 )
 
@@ -38,9 +40,51 @@ type Options struct {
 	syncReplication bool
 	syncAcks        int // only if !replica
 
+	// replication acknowledgment required before write operations return
+	ackLevel              AckLevel
+	replicationAckTimeout time.Duration
+
 	readTxPoolSize int
 	maxResultSize  int
 
+	// write rate limits, no limit is enforced when set to zero
+	maxWriteOpsPerSecond   int
+	maxWriteBytesPerSecond int
+
+	// maximum number of concurrently open snapshots, no limit is enforced when set to zero
+	maxOpenSnapshots int
+
+	// operations taking longer than the threshold are reported, disabled when set to zero
+	slowOperationThreshold time.Duration
+	slowOperationHook      SlowOperationHook
+
+	// public key used to verify the signature of checkpoints
+	checkpointPublicKey *ecdsa.PublicKey
+
+	// codecs applied to document field values
+	documentValueCodecs *document.ValueCodecRegistry
+
+	// key used to encrypt the document fields marked as encrypted
+	documentFieldEncryptionKey []byte
+
+	// period soft-deleted collections can be restored within
+	documentDeletedCollectionRetention time.Duration
+
+	// whitelisted transforms references may be resolved through
+	referenceTransforms map[string]ReferenceTransform
+
+	// maximum number of rename links followed when resolving references, links are not followed when set to zero
+	renameFollowDepth int
+
+	// when set, opening the database fails if the index is not consistent with the transaction log
+	verifyIndexOnOpen bool
+
+	// secret used to obfuscate keys, keys are stored in plain when not set
+	keyObfuscationSecret []byte
+
+	// prefixes of the keys holding internal records of the database, they can not be referenced
+	reservedKeyPrefixes [][]byte
+
 	// TruncationFrequency determines how frequently to truncate data from the database.
 	TruncationFrequency time.Duration
 
@@ -51,11 +95,15 @@ type Options struct {
 // DefaultOption Initialise Db Optionts to default values
 func DefaultOption() *Options {
 	return &Options{
-		dbRootPath:          DefaultDbRootPath,
-		storeOpts:           store.DefaultOptions(),
-		maxResultSize:       MaxKeyScanLimit,
-		readTxPoolSize:      DefaultReadTxPoolSize,
-		TruncationFrequency: DefaultTruncationFrequency,
+		dbRootPath:            DefaultDbRootPath,
+		storeOpts:             store.DefaultOptions(),
+		maxResultSize:         MaxKeyScanLimit,
+		readTxPoolSize:        DefaultReadTxPoolSize,
+		TruncationFrequency:   DefaultTruncationFrequency,
+		replicationAckTimeout: DefaultReplicationAckTimeout,
+		renameFollowDepth:     DefaultRenameFollowDepth,
+
+		documentDeletedCollectionRetention: document.DefaultDeletedCollectionRetention,
 	}
 }
 
@@ -97,6 +145,19 @@ func (o *Options) WithSyncAcks(syncAcks int) *Options {
 	return o
 }
 
+// WithAckLevel sets the replication acknowledgment Set and SetReference wait for before returning
+// when the request does not specify one
+func (o *Options) WithAckLevel(ackLevel AckLevel) *Options {
+	o.ackLevel = ackLevel
+	return o
+}
+
+// WithReplicationAckTimeout sets how long to wait for replication acknowledgments
+func (o *Options) WithReplicationAckTimeout(timeout time.Duration) *Options {
+	o.replicationAckTimeout = timeout
+	return o
+}
+
 func (o *Options) WithReadTxPoolSize(txPoolSize int) *Options {
 	o.readTxPoolSize = txPoolSize
 	return o
@@ -120,3 +181,96 @@ func (o *Options) WithMaxResultSize(maxResultSize int) *Options {
 	o.maxResultSize = maxResultSize
 	return o
 }
+
+// WithMaxWriteOpsPerSecond sets the maximum number of write operations per second, zero means no limit
+func (o *Options) WithMaxWriteOpsPerSecond(maxWriteOpsPerSecond int) *Options {
+	o.maxWriteOpsPerSecond = maxWriteOpsPerSecond
+	return o
+}
+
+// WithMaxWriteBytesPerSecond sets the maximum number of written bytes per second, zero means no limit
+func (o *Options) WithMaxWriteBytesPerSecond(maxWriteBytesPerSecond int) *Options {
+	o.maxWriteBytesPerSecond = maxWriteBytesPerSecond
+	return o
+}
+
+// WithMaxOpenSnapshots sets the maximum number of concurrently open snapshots, zero means no limit
+func (o *Options) WithMaxOpenSnapshots(maxOpenSnapshots int) *Options {
+	o.maxOpenSnapshots = maxOpenSnapshots
+	return o
+}
+
+// WithRenameFollowDepth sets the maximum number of rename links followed when resolving references, zero disables following
+func (o *Options) WithRenameFollowDepth(depth int) *Options {
+	o.renameFollowDepth = depth
+	return o
+}
+
+// WithVerifyIndexOnOpen sets whether the index is verified against the transaction log when opening the database
+func (o *Options) WithVerifyIndexOnOpen(verify bool) *Options {
+	o.verifyIndexOnOpen = verify
+	return o
+}
+
+// WithSlowOperationThreshold sets the duration above which Get, Scan and Set operations are reported, zero disables reporting
+func (o *Options) WithSlowOperationThreshold(threshold time.Duration) *Options {
+	o.slowOperationThreshold = threshold
+	return o
+}
+
+// WithSlowOperationHook sets the hook receiving slow operation records, slow operations are logged when not set
+func (o *Options) WithSlowOperationHook(hook SlowOperationHook) *Options {
+	o.slowOperationHook = hook
+	return o
+}
+
+// WithReferenceTransform registers a transform references may be resolved through
+func (o *Options) WithReferenceTransform(name string, transform ReferenceTransform) *Options {
+	if o.referenceTransforms == nil {
+		o.referenceTransforms = make(map[string]ReferenceTransform)
+	}
+	o.referenceTransforms[name] = transform
+	return o
+}
+
+// WithKeyObfuscationSecret enables key obfuscation, keys are stored as keyed hashes derived from the secret
+func (o *Options) WithKeyObfuscationSecret(secret []byte) *Options {
+	o.keyObfuscationSecret = secret
+	return o
+}
+
+// WithReservedKeyPrefixes sets the prefixes of the keys holding internal records of the database
+// e.g. the users of the system database. References to keys starting with any of them are rejected.
+func (o *Options) WithReservedKeyPrefixes(prefixes ...[]byte) *Options {
+	o.reservedKeyPrefixes = prefixes
+	return o
+}
+
+// GetReservedKeyPrefixes returns the prefixes of the keys that can not be referenced
+func (o *Options) GetReservedKeyPrefixes() [][]byte {
+	return o.reservedKeyPrefixes
+}
+
+// WithCheckpointPublicKey sets the public key used to verify signed checkpoints before reading at them
+func (o *Options) WithCheckpointPublicKey(publicKey *ecdsa.PublicKey) *Options {
+	o.checkpointPublicKey = publicKey
+	return o
+}
+
+// WithDocumentValueCodecs sets the registry of codecs applied to document field values on insert and read
+func (o *Options) WithDocumentValueCodecs(valueCodecs *document.ValueCodecRegistry) *Options {
+	o.documentValueCodecs = valueCodecs
+	return o
+}
+
+// WithDocumentFieldEncryptionKey sets the AES key used to encrypt the document fields marked as encrypted
+func (o *Options) WithDocumentFieldEncryptionKey(key []byte) *Options {
+	o.documentFieldEncryptionKey = key
+	return o
+}
+
+// WithDocumentDeletedCollectionRetention sets the period soft-deleted collections can be restored within
+func (o *Options) WithDocumentDeletedCollectionRetention(retention time.Duration) *Options {
+	o.documentDeletedCollectionRetention = retention
+	return o
+}
@@ -39,12 +39,16 @@ func TestDefaultOptions(t *testing.T) {
 		WithStoreOptions(storeOpts).
 		WithReadTxPoolSize(789).
 		WithSyncReplication(true).
-		WithTruncationFrequency(1 * time.Hour)
+		WithTruncationFrequency(1 * time.Hour).
+		WithMaxWriteOpsPerSecond(100).
+		WithMaxWriteBytesPerSecond(1024)
 
 	require.Equal(t, op.GetDBRootPath(), rootpath)
 	require.Equal(t, op.GetTxPoolSize(), 789)
 	require.True(t, op.syncReplication)
 	require.Equal(t, op.TruncationFrequency, 1*time.Hour)
+	require.Equal(t, 100, op.maxWriteOpsPerSecond)
+	require.Equal(t, 1024, op.maxWriteBytesPerSecond)
 
 	require.Equal(t, storeOpts, op.storeOpts)
 }
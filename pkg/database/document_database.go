@@ -17,14 +17,20 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/codenotary/immudb/embedded/document"
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/api/protomodel"
 	"github.com/codenotary/immudb/pkg/api/schema"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+var ErrDeleteAllNotConfirmed = fmt.Errorf("%w: confirmation is required to delete all documents of a collection", ErrIllegalArguments)
+
 // DocumentDatabase is the interface for document database
 type DocumentDatabase interface {
 	// GetCollection returns the collection schema
@@ -37,6 +43,10 @@ type DocumentDatabase interface {
 	UpdateCollection(ctx context.Context, username string, req *protomodel.UpdateCollectionRequest) (*protomodel.UpdateCollectionResponse, error)
 	// DeleteCollection deletes a collection
 	DeleteCollection(ctx context.Context, username string, req *protomodel.DeleteCollectionRequest) (*protomodel.DeleteCollectionResponse, error)
+	// RestoreCollection restores a soft-deleted collection
+	RestoreCollection(ctx context.Context, username string, req *protomodel.RestoreCollectionRequest) (*protomodel.RestoreCollectionResponse, error)
+	// PurgeCollections drops the soft-deleted collections whose retention period expired
+	PurgeCollections(ctx context.Context, username string, req *protomodel.PurgeCollectionsRequest) (*protomodel.PurgeCollectionsResponse, error)
 	// AddField adds a new field in a collection
 	AddField(ctx context.Context, username string, req *protomodel.AddFieldRequest) (*protomodel.AddFieldResponse, error)
 	// RemoveField removes a field from a collection
@@ -47,18 +57,34 @@ type DocumentDatabase interface {
 	DeleteIndex(ctx context.Context, username string, req *protomodel.DeleteIndexRequest) (*protomodel.DeleteIndexResponse, error)
 	// InsertDocuments creates new documents
 	InsertDocuments(ctx context.Context, username string, req *protomodel.InsertDocumentsRequest) (*protomodel.InsertDocumentsResponse, error)
+	// UploadDocument inserts a document received in chunks, the value of large fields being received in parts
+	UploadDocument(ctx context.Context, username string, recv func() (*protomodel.UploadDocumentChunk, error)) (*protomodel.UploadDocumentResponse, error)
 	// ReplaceDocuments replaces documents matching the query
 	ReplaceDocuments(ctx context.Context, username string, req *protomodel.ReplaceDocumentsRequest) (*protomodel.ReplaceDocumentsResponse, error)
+	// UpdateDocument replaces the fields of a document only if its latest version was written by the expected transaction
+	UpdateDocument(ctx context.Context, username string, req *protomodel.UpdateDocumentRequest) (*protomodel.UpdateDocumentResponse, error)
 	// AuditDocument returns the document audit history
 	AuditDocument(ctx context.Context, req *protomodel.AuditDocumentRequest) (*protomodel.AuditDocumentResponse, error)
 	// SearchDocuments returns the documents matching the query
 	SearchDocuments(ctx context.Context, query *protomodel.Query, offset int64) (document.DocumentReader, error)
+	// SearchDocumentsWithHighlights returns a document reader for the given query, each document read includes the
+	// field comparisons it satisfies
+	SearchDocumentsWithHighlights(ctx context.Context, query *protomodel.Query, offset int64) (document.DocumentReader, error)
+	// SearchDocumentsPage returns a page of the documents matching the query, starting after the position encoded
+	// in the page token, along with the token of the next page
+	SearchDocumentsPage(ctx context.Context, query *protomodel.Query, pageToken string, pageSize int, highlight bool) ([]*protomodel.DocumentAtRevision, string, error)
+	// ExplainSearchDocuments returns the plan used to search the documents matching the query
+	ExplainSearchDocuments(ctx context.Context, query *protomodel.Query) (*protomodel.QueryPlan, error)
 	// CountDocuments returns the number of documents matching the query
 	CountDocuments(ctx context.Context, req *protomodel.CountDocumentsRequest) (*protomodel.CountDocumentsResponse, error)
 	// DeleteDocuments deletes documents maching the query
 	DeleteDocuments(ctx context.Context, username string, req *protomodel.DeleteDocumentsRequest) (*protomodel.DeleteDocumentsResponse, error)
+	// DeleteDocument deletes the document with the given id, its previous revisions can still be audited
+	DeleteDocument(ctx context.Context, username string, req *protomodel.DeleteDocumentRequest) (*protomodel.DeleteDocumentResponse, error)
 	// ProofDocument returns the proofs for a document
 	ProofDocument(ctx context.Context, req *protomodel.ProofDocumentRequest) (*protomodel.ProofDocumentResponse, error)
+	// WatchCollection streams the changes made to the documents of a collection as they are committed
+	WatchCollection(ctx context.Context, req *protomodel.CollectionWatchRequest, send func(*protomodel.CollectionWatchResponse) error) error
 }
 
 // CreateCollection creates a new collection
@@ -74,7 +100,20 @@ func (d *db) CreateCollection(ctx context.Context, username string, req *protomo
 		return nil, ErrIllegalArguments
 	}
 
-	err := d.documentEngine.CreateCollection(ctx, username, req.Name, req.DocumentIdFieldName, req.Fields, req.Indexes)
+	createCollection := d.documentEngine.CreateCollection
+	if req.Strict {
+		createCollection = d.documentEngine.CreateStrictCollection
+	}
+
+	err := createCollection(
+		ctx,
+		username,
+		req.Name,
+		req.DocumentIdFieldName,
+		req.Fields,
+		req.Indexes,
+		document.WithDefaultOrderBy(req.DefaultOrderBy),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +178,12 @@ func (d *db) DeleteCollection(ctx context.Context, username string, req *protomo
 		return nil, ErrIllegalArguments
 	}
 
-	err := d.documentEngine.DeleteCollection(ctx, username, req.Name)
+	deleteCollection := d.documentEngine.DeleteCollection
+	if req.Soft {
+		deleteCollection = d.documentEngine.SoftDeleteCollection
+	}
+
+	err := deleteCollection(ctx, username, req.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +191,48 @@ func (d *db) DeleteCollection(ctx context.Context, username string, req *protomo
 	return &protomodel.DeleteCollectionResponse{}, nil
 }
 
+// RestoreCollection restores a soft-deleted collection
+func (d *db) RestoreCollection(ctx context.Context, username string, req *protomodel.RestoreCollectionRequest) (*protomodel.RestoreCollectionResponse, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	if req == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	err := d.documentEngine.RestoreCollection(ctx, username, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protomodel.RestoreCollectionResponse{}, nil
+}
+
+// PurgeCollections drops the soft-deleted collections whose retention period expired
+func (d *db) PurgeCollections(ctx context.Context, username string, req *protomodel.PurgeCollectionsRequest) (*protomodel.PurgeCollectionsResponse, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	if req == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	purged, err := d.documentEngine.PurgeCollections(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protomodel.PurgeCollectionsResponse{Collections: purged}, nil
+}
+
 // AddField adds a new field in a collection
 func (d *db) AddField(ctx context.Context, username string, req *protomodel.AddFieldRequest) (*protomodel.AddFieldResponse, error) {
 	d.mutex.RLock()
@@ -244,7 +330,12 @@ func (d *db) InsertDocuments(ctx context.Context, username string, req *protomod
 		return nil, ErrIllegalArguments
 	}
 
-	txID, docIDs, err := d.documentEngine.InsertDocuments(ctx, username, req.CollectionName, req.Documents)
+	preconditions, err := d.kvPreconditionsFromProto(req.KvPreconditions)
+	if err != nil {
+		return nil, err
+	}
+
+	txID, docIDs, err := d.documentEngine.InsertDocuments(ctx, username, req.CollectionName, req.Documents, preconditions...)
 	if err != nil {
 		return nil, err
 	}
@@ -260,6 +351,100 @@ func (d *db) InsertDocuments(ctx context.Context, username string, req *protomod
 	}, nil
 }
 
+// UploadDocument inserts a document received in chunks until recv returns io.EOF. The first chunk holds
+// the collection name and the fields of the document not being uploaded in parts. The value of each field
+// uploaded in parts is stored content-addressed, the inserted document referring to it. Chunks are received
+// without holding the database lock, which is only taken to insert the document along with the values.
+// The upload fails as soon as the received values exceed the limits of the database.
+func (d *db) UploadDocument(ctx context.Context, username string, recv func() (*protomodel.UploadDocumentChunk, error)) (*protomodel.UploadDocumentResponse, error) {
+	if d.IsReplica() {
+		return nil, ErrIsReplica
+	}
+
+	if recv == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	first, err := recv()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: no document received", ErrIllegalArguments)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc := first.Document
+
+	err = d.writeLimiter.acquire(1, proto.Size(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := d.documentEngine.NewBlobUpload(ctx, first.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	for chunk := first; ; {
+		if chunk != first && (chunk.CollectionName != "" || chunk.Document != nil) {
+			return nil, fmt.Errorf("%w: collection and document must only be set on the first chunk", ErrIllegalArguments)
+		}
+
+		if chunk.Field != "" {
+			err = d.writeLimiter.acquire(0, len(chunk.Data))
+			if err != nil {
+				return nil, err
+			}
+
+			w, err := upload.Writer(chunk.Field)
+			if err != nil {
+				return nil, err
+			}
+
+			err = w.Write(chunk.Data)
+			if err != nil {
+				return nil, err
+			}
+		} else if len(chunk.Data) > 0 {
+			return nil, fmt.Errorf("%w: the field data is part of must be specified", ErrIllegalArguments)
+		}
+
+		chunk, err = recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d.insertUploadedDocument(ctx, username, doc, upload)
+}
+
+func (d *db) insertUploadedDocument(
+	ctx context.Context,
+	username string,
+	doc *structpb.Struct,
+	upload *document.BlobUpload,
+) (*protomodel.UploadDocumentResponse, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	txID, docID, err := d.documentEngine.InsertDocumentWithBlobs(ctx, username, doc, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protomodel.UploadDocumentResponse{
+		TransactionId: txID,
+		DocumentId:    docID.EncodeToHexString(),
+	}, nil
+}
+
 // ReplaceDocuments replaces documents matching the query
 func (d *db) ReplaceDocuments(ctx context.Context, username string, req *protomodel.ReplaceDocumentsRequest) (*protomodel.ReplaceDocumentsResponse, error) {
 	d.mutex.RLock()
@@ -273,7 +458,18 @@ func (d *db) ReplaceDocuments(ctx context.Context, username string, req *protomo
 		return nil, ErrIllegalArguments
 	}
 
-	revisions, err := d.documentEngine.ReplaceDocuments(ctx, username, req.Query, req.Document)
+	preconditions, err := d.kvPreconditionsFromProto(req.KvPreconditions)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []*protomodel.DocumentAtRevision
+
+	if req.IfRevMatch > 0 {
+		revisions, err = d.documentEngine.ReplaceDocumentIfRevMatch(ctx, username, req.Query, req.Document, req.IfRevMatch, preconditions...)
+	} else {
+		revisions, err = d.documentEngine.ReplaceDocuments(ctx, username, req.Query, req.Document, preconditions...)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -283,6 +479,57 @@ func (d *db) ReplaceDocuments(ctx context.Context, username string, req *protomo
 	}, nil
 }
 
+// UpdateDocument replaces the fields of the document with the given id only if its latest version was written by
+// the expected transaction, so concurrent updates are not lost
+func (d *db) UpdateDocument(ctx context.Context, username string, req *protomodel.UpdateDocumentRequest) (*protomodel.UpdateDocumentResponse, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	if req == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	docID, err := document.NewDocumentIDFromHexEncodedString(req.DocumentId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid document id", err)
+	}
+
+	revision, err := d.documentEngine.UpdateDocumentIfTxMatch(ctx, username, req.CollectionName, docID, req.Document, req.ExpectedTransactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protomodel.UpdateDocumentResponse{
+		TransactionId: revision.TransactionId,
+		Revision:      revision.Revision,
+	}, nil
+}
+
+// kvPreconditionsFromProto returns the preconditions on key-value entries a document write is subject to,
+// they are checked by the store when the transaction holding the documents is committed
+func (d *db) kvPreconditionsFromProto(pcs []*schema.Precondition) ([]store.Precondition, error) {
+	preconditions := make([]store.Precondition, len(pcs))
+
+	for i, pc := range pcs {
+		c, err := PreconditionFromProto(pc)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.keyObfuscationEnabled() {
+			c = d.obfuscatePrecondition(c)
+		}
+
+		preconditions[i] = c
+	}
+
+	return preconditions, nil
+}
+
 func (d *db) AuditDocument(ctx context.Context, req *protomodel.AuditDocumentRequest) (*protomodel.AuditDocumentResponse, error) {
 	if req == nil {
 		return nil, ErrIllegalArguments
@@ -321,6 +568,23 @@ func (d *db) SearchDocuments(ctx context.Context, query *protomodel.Query, offse
 	return d.documentEngine.GetDocuments(ctx, query, offset)
 }
 
+// SearchDocumentsWithHighlights returns a document reader for the given query, each document read includes the
+// field comparisons it satisfies
+func (d *db) SearchDocumentsWithHighlights(ctx context.Context, query *protomodel.Query, offset int64) (document.DocumentReader, error) {
+	return d.documentEngine.GetDocumentsWithHighlights(ctx, query, offset)
+}
+
+// SearchDocumentsPage returns a page of the documents matching the query, starting after the position encoded
+// in the page token, along with the token of the next page, which is empty when there are no more documents
+func (d *db) SearchDocumentsPage(ctx context.Context, query *protomodel.Query, pageToken string, pageSize int, highlight bool) ([]*protomodel.DocumentAtRevision, string, error) {
+	return d.documentEngine.GetDocumentsPage(ctx, query, pageToken, pageSize, highlight)
+}
+
+// ExplainSearchDocuments returns the plan used to search the documents matching the query
+func (d *db) ExplainSearchDocuments(ctx context.Context, query *protomodel.Query) (*protomodel.QueryPlan, error) {
+	return d.documentEngine.ExplainQuery(ctx, query)
+}
+
 // CountDocuments returns the number of documents matching the query
 func (d *db) CountDocuments(ctx context.Context, req *protomodel.CountDocumentsRequest) (*protomodel.CountDocumentsResponse, error) {
 	if req == nil {
@@ -346,11 +610,47 @@ func (d *db) DeleteDocuments(ctx context.Context, username string, req *protomod
 		return nil, ErrIllegalArguments
 	}
 
-	err := d.documentEngine.DeleteDocuments(ctx, username, req.Query)
+	if len(req.Query.GetExpressions()) == 0 && !req.Confirm {
+		return nil, ErrDeleteAllNotConfirmed
+	}
+
+	deleted, err := d.documentEngine.DeleteDocuments(ctx, username, req.Query)
 	if err != nil {
 		return nil, err
 	}
-	return &protomodel.DeleteDocumentsResponse{}, nil
+
+	return &protomodel.DeleteDocumentsResponse{
+		DeletedCount: uint64(deleted),
+	}, nil
+}
+
+// DeleteDocument deletes the document with the given id, which is no longer returned by searches
+// but can be revived by inserting it again
+func (d *db) DeleteDocument(ctx context.Context, username string, req *protomodel.DeleteDocumentRequest) (*protomodel.DeleteDocumentResponse, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	if req == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	docID, err := document.NewDocumentIDFromHexEncodedString(req.DocumentId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid document id", err)
+	}
+
+	txID, err := d.documentEngine.DeleteDocument(ctx, username, req.CollectionName, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protomodel.DeleteDocumentResponse{
+		TransactionId: txID,
+	}, nil
 }
 
 // ProofDocument returns the proofs for a documenta
@@ -415,3 +715,64 @@ func (d *db) ProofDocument(ctx context.Context, req *protomodel.ProofDocumentReq
 		},
 	}, nil
 }
+
+// WatchCollection streams the changes made to the documents of the collection by the transactions committed
+// after the one specified in the request, changes are sent in commit order as soon as they are indexed.
+// Streaming stops when the context is done or the changes can not be sent. Each change holds the id of
+// its transaction, thus streaming may be resumed from the last fully received transaction. Changes are
+// delivered at least once, duplicates can be identified by their transaction and document ids.
+func (d *db) WatchCollection(ctx context.Context, req *protomodel.CollectionWatchRequest, send func(*protomodel.CollectionWatchResponse) error) error {
+	if req == nil || send == nil {
+		return ErrIllegalArguments
+	}
+
+	_, err := d.documentEngine.GetCollection(ctx, req.CollectionName)
+	if err != nil {
+		return err
+	}
+
+	currTxID, _ := d.st.CommittedAlh()
+
+	if req.SinceTransactionId > currTxID {
+		return fmt.Errorf("%w: sinceTransactionId must not be greater than the current transaction ID", ErrIllegalArguments)
+	}
+
+	sinceTx := req.SinceTransactionId
+	if sinceTx == 0 {
+		sinceTx = currTxID
+	}
+
+	for txID := sinceTx + 1; ; txID++ {
+		err := d.WaitForIndexingUpto(ctx, txID)
+		if err != nil {
+			return err
+		}
+
+		changes, err := d.collectionChanges(ctx, req.CollectionName, txID)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			err = send(change)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *db) collectionChanges(ctx context.Context, collectionName string, txID uint64) ([]*protomodel.CollectionWatchResponse, error) {
+	tx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(tx)
+
+	err = d.st.ReadTx(txID, false, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.documentEngine.CollectionChanges(ctx, collectionName, tx)
+}
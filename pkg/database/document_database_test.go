@@ -18,7 +18,10 @@ package database
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -72,6 +75,12 @@ func TestDocumentDB_InvalidParameters(t *testing.T) {
 	_, err = db.DeleteCollection(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, ErrIllegalArguments)
 
+	_, err = db.RestoreCollection(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	_, err = db.PurgeCollections(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
 	_, err = db.CreateIndex(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, ErrIllegalArguments)
 
@@ -81,6 +90,9 @@ func TestDocumentDB_InvalidParameters(t *testing.T) {
 	_, err = db.InsertDocuments(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, ErrIllegalArguments)
 
+	_, err = db.UploadDocument(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
 	_, err = db.ReplaceDocuments(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, ErrIllegalArguments)
 
@@ -93,6 +105,12 @@ func TestDocumentDB_InvalidParameters(t *testing.T) {
 	_, err = db.DeleteDocuments(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, ErrIllegalArguments)
 
+	_, err = db.DeleteDocument(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	_, err = db.UpdateDocument(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
 	_, err = db.ProofDocument(context.Background(), nil)
 	require.ErrorIs(t, err, ErrIllegalArguments)
 }
@@ -111,6 +129,12 @@ func TestDocumentDB_WritesOnReplica(t *testing.T) {
 	_, err = db.DeleteCollection(context.Background(), "admin", &protomodel.DeleteCollectionRequest{})
 	require.ErrorIs(t, err, ErrIsReplica)
 
+	_, err = db.RestoreCollection(context.Background(), "admin", &protomodel.RestoreCollectionRequest{})
+	require.ErrorIs(t, err, ErrIsReplica)
+
+	_, err = db.PurgeCollections(context.Background(), "admin", &protomodel.PurgeCollectionsRequest{})
+	require.ErrorIs(t, err, ErrIsReplica)
+
 	_, err = db.AddField(context.Background(), "admin", &protomodel.AddFieldRequest{})
 	require.ErrorIs(t, err, ErrIsReplica)
 
@@ -126,11 +150,20 @@ func TestDocumentDB_WritesOnReplica(t *testing.T) {
 	_, err = db.InsertDocuments(context.Background(), "admin", &protomodel.InsertDocumentsRequest{})
 	require.ErrorIs(t, err, ErrIsReplica)
 
+	_, err = db.UploadDocument(context.Background(), "admin", func() (*protomodel.UploadDocumentChunk, error) { return nil, io.EOF })
+	require.ErrorIs(t, err, ErrIsReplica)
+
 	_, err = db.ReplaceDocuments(context.Background(), "admin", &protomodel.ReplaceDocumentsRequest{})
 	require.ErrorIs(t, err, ErrIsReplica)
 
 	_, err = db.DeleteDocuments(context.Background(), "admin", &protomodel.DeleteDocumentsRequest{})
 	require.ErrorIs(t, err, ErrIsReplica)
+
+	_, err = db.DeleteDocument(context.Background(), "admin", &protomodel.DeleteDocumentRequest{})
+	require.ErrorIs(t, err, ErrIsReplica)
+
+	_, err = db.UpdateDocument(context.Background(), "admin", &protomodel.UpdateDocumentRequest{})
+	require.ErrorIs(t, err, ErrIsReplica)
 }
 
 func TestDocumentDB_WithCollections(t *testing.T) {
@@ -313,12 +346,21 @@ func TestDocumentDB_WithCollections(t *testing.T) {
 				CollectionName: defaultCollectionName,
 			},
 		})
+		require.ErrorIs(t, err, ErrDeleteAllNotConfirmed)
+
+		_, err = db.DeleteDocuments(context.Background(), "admin", &protomodel.DeleteDocumentsRequest{
+			Query: &protomodel.Query{
+				CollectionName: defaultCollectionName,
+			},
+			Confirm: true,
+		})
 		require.NoError(t, err)
 
 		_, err = db.DeleteDocuments(context.Background(), "admin", &protomodel.DeleteDocumentsRequest{
 			Query: &protomodel.Query{
 				CollectionName: "1invalidCollectionName",
 			},
+			Confirm: true,
 		})
 		require.ErrorIs(t, err, ErrIllegalArguments)
 	})
@@ -546,6 +588,20 @@ func TestDocumentDB_WithDocuments(t *testing.T) {
 		require.EqualValues(t, 1, countResp.Count)
 	})
 
+	t.Run("should fail when replacing document with a stale revision", func(t *testing.T) {
+		_, err := db.ReplaceDocuments(context.Background(), "admin", &protomodel.ReplaceDocumentsRequest{
+			Query: &protomodel.Query{CollectionName: collectionName},
+			Document: &structpb.Struct{
+				Fields: map[string]*structpb.Value{
+					"_id":     structpb.NewStringValue(docID),
+					"pincode": structpb.NewNumberValue(456),
+				},
+			},
+			IfRevMatch: 1,
+		})
+		require.ErrorIs(t, err, document.ErrRevisionConflict)
+	})
+
 	t.Run("should pass when auditing document without requesting payloads", func(t *testing.T) {
 		resp, err := db.AuditDocument(context.Background(), &protomodel.AuditDocumentRequest{
 			CollectionName: collectionName,
@@ -631,6 +687,287 @@ func TestDocumentDB_WithDocuments(t *testing.T) {
 	})
 }
 
+func TestDocumentDB_WithKVPreconditions(t *testing.T) {
+	db := makeDocumentDb(t)
+
+	ctx := context.Background()
+
+	collectionName := "mycollection"
+	_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{
+		Name: collectionName,
+		Fields: []*protomodel.Field{
+			{Name: "tenant", Type: protomodel.FieldType_STRING},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("tenants/acme"), Value: []byte("config")}}})
+	require.NoError(t, err)
+
+	countDocuments := func(t *testing.T) int64 {
+		res, err := db.CountDocuments(ctx, &protomodel.CountDocumentsRequest{
+			Query: &protomodel.Query{CollectionName: collectionName},
+		})
+		require.NoError(t, err)
+		return res.Count
+	}
+
+	t.Run("documents should be inserted when the required key exists", func(t *testing.T) {
+		res, err := db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+			CollectionName: collectionName,
+			Documents: []*structpb.Struct{
+				{Fields: map[string]*structpb.Value{"tenant": structpb.NewStringValue("acme")}},
+			},
+			KvPreconditions: []*schema.Precondition{schema.PreconditionKeyMustExist([]byte("tenants/acme"))},
+		})
+		require.NoError(t, err)
+		require.Len(t, res.DocumentIds, 1)
+		require.EqualValues(t, 1, countDocuments(t))
+	})
+
+	t.Run("documents should not be inserted when the required key is missing", func(t *testing.T) {
+		_, err := db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+			CollectionName: collectionName,
+			Documents: []*structpb.Struct{
+				{Fields: map[string]*structpb.Value{"tenant": structpb.NewStringValue("unknown")}},
+			},
+			KvPreconditions: []*schema.Precondition{schema.PreconditionKeyMustExist([]byte("tenants/unknown"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+		require.EqualValues(t, 1, countDocuments(t))
+	})
+
+	t.Run("documents should not be replaced when the required key is missing", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{Field: "tenant", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue("acme")},
+					},
+				},
+			},
+		}
+
+		_, err := db.ReplaceDocuments(ctx, "admin", &protomodel.ReplaceDocumentsRequest{
+			Query:           query,
+			Document:        &structpb.Struct{Fields: map[string]*structpb.Value{"tenant": structpb.NewStringValue("acme")}},
+			KvPreconditions: []*schema.Precondition{schema.PreconditionKeyMustExist([]byte("tenants/unknown"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+
+		res, err := db.ReplaceDocuments(ctx, "admin", &protomodel.ReplaceDocumentsRequest{
+			Query:           query,
+			Document:        &structpb.Struct{Fields: map[string]*structpb.Value{"tenant": structpb.NewStringValue("acme")}},
+			KvPreconditions: []*schema.Precondition{schema.PreconditionKeyMustExist([]byte("tenants/acme"))},
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Revisions, 1)
+		require.EqualValues(t, 2, res.Revisions[0].Revision)
+	})
+
+	t.Run("invalid preconditions should be rejected", func(t *testing.T) {
+		_, err := db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+			CollectionName: collectionName,
+			Documents: []*structpb.Struct{
+				{Fields: map[string]*structpb.Value{"tenant": structpb.NewStringValue("acme")}},
+			},
+			KvPreconditions: []*schema.Precondition{schema.PreconditionKeyMustExist(nil)},
+		})
+		require.ErrorIs(t, err, store.ErrInvalidPrecondition)
+	})
+}
+
+func TestDocumentDB_WithStrictCollection(t *testing.T) {
+	db := makeDocumentDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{
+		Name:   "strictcollection",
+		Fields: []*protomodel.Field{{Name: "name", Type: protomodel.FieldType_STRING}},
+		Strict: true,
+	})
+	require.NoError(t, err)
+
+	res, err := db.GetCollection(ctx, &protomodel.GetCollectionRequest{Name: "strictcollection"})
+	require.NoError(t, err)
+	require.True(t, res.Collection.Strict)
+
+	_, err = db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+		CollectionName: "strictcollection",
+		Documents: []*structpb.Struct{
+			{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("alice")}},
+			{Fields: map[string]*structpb.Value{"surname": structpb.NewStringValue("smith")}},
+		},
+	})
+	require.ErrorIs(t, err, document.ErrUndeclaredField)
+	require.Contains(t, err.Error(), "'surname'")
+
+	_, err = db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+		CollectionName: "strictcollection",
+		Documents: []*structpb.Struct{
+			{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("alice")}},
+			{Fields: map[string]*structpb.Value{}},
+		},
+	})
+	require.NoError(t, err)
+}
+
+func TestDocumentDB_WithSoftDeletedCollection(t *testing.T) {
+	db := makeDocumentDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{
+		Name:   "mycollection",
+		Fields: []*protomodel.Field{{Name: "name", Type: protomodel.FieldType_STRING}},
+	})
+	require.NoError(t, err)
+
+	_, err = db.DeleteCollection(ctx, "admin", &protomodel.DeleteCollectionRequest{Name: "mycollection", Soft: true})
+	require.NoError(t, err)
+
+	collections, err := db.GetCollections(ctx, &protomodel.GetCollectionsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, collections.Collections)
+
+	purged, err := db.PurgeCollections(ctx, "admin", &protomodel.PurgeCollectionsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, purged.Collections)
+
+	_, err = db.RestoreCollection(ctx, "admin", &protomodel.RestoreCollectionRequest{Name: "mycollection"})
+	require.NoError(t, err)
+
+	_, err = db.GetCollection(ctx, &protomodel.GetCollectionRequest{Name: "mycollection"})
+	require.NoError(t, err)
+}
+
+func TestDocumentDB_DeleteDocument(t *testing.T) {
+	db := makeDocumentDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{
+		Name:   "mycollection",
+		Fields: []*protomodel.Field{{Name: "name", Type: protomodel.FieldType_STRING}},
+	})
+	require.NoError(t, err)
+
+	insertRes, err := db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+		CollectionName: "mycollection",
+		Documents: []*structpb.Struct{
+			{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("alice")}},
+		},
+	})
+	require.NoError(t, err)
+
+	docID := insertRes.DocumentIds[0]
+
+	_, err = db.DeleteDocument(ctx, "admin", &protomodel.DeleteDocumentRequest{CollectionName: "mycollection"})
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	_, err = db.DeleteDocument(ctx, "admin", &protomodel.DeleteDocumentRequest{
+		CollectionName: "mycollection",
+		DocumentId:     document.NewDocumentIDFromTimestamp(time.Now(), 1).EncodeToHexString(),
+	})
+	require.ErrorIs(t, err, document.ErrDocumentNotFound)
+
+	deleteRes, err := db.DeleteDocument(ctx, "admin", &protomodel.DeleteDocumentRequest{
+		CollectionName: "mycollection",
+		DocumentId:     docID,
+	})
+	require.NoError(t, err)
+	require.Greater(t, deleteRes.TransactionId, insertRes.TransactionId)
+
+	countRes, err := db.CountDocuments(ctx, &protomodel.CountDocumentsRequest{
+		Query: &protomodel.Query{CollectionName: "mycollection"},
+	})
+	require.NoError(t, err)
+	require.Zero(t, countRes.Count)
+
+	revivedRes, err := db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+		CollectionName: "mycollection",
+		Documents: []*structpb.Struct{
+			{Fields: map[string]*structpb.Value{
+				"_id":  structpb.NewStringValue(docID),
+				"name": structpb.NewStringValue("alice smith"),
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	err = db.WaitForIndexingUpto(ctx, revivedRes.TransactionId)
+	require.NoError(t, err)
+
+	auditRes, err := db.AuditDocument(ctx, &protomodel.AuditDocumentRequest{
+		CollectionName: "mycollection",
+		DocumentId:     docID,
+		Page:           1,
+		PageSize:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, auditRes.Revisions, 3)
+	require.Equal(t, "alice", auditRes.Revisions[0].Document.Fields["name"].GetStringValue())
+	require.True(t, auditRes.Revisions[1].Metadata.Deleted)
+	require.Equal(t, "alice smith", auditRes.Revisions[2].Document.Fields["name"].GetStringValue())
+}
+
+func TestDocumentDB_UpdateDocument(t *testing.T) {
+	db := makeDocumentDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{
+		Name:   "mycollection",
+		Fields: []*protomodel.Field{{Name: "name", Type: protomodel.FieldType_STRING}},
+	})
+	require.NoError(t, err)
+
+	insertRes, err := db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+		CollectionName: "mycollection",
+		Documents: []*structpb.Struct{
+			{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("alice")}},
+		},
+	})
+	require.NoError(t, err)
+
+	docID := insertRes.DocumentIds[0]
+
+	updateRes, err := db.UpdateDocument(ctx, "admin", &protomodel.UpdateDocumentRequest{
+		CollectionName:        "mycollection",
+		DocumentId:            docID,
+		Document:              &structpb.Struct{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("alice smith")}},
+		ExpectedTransactionId: insertRes.TransactionId,
+	})
+	require.NoError(t, err)
+	require.Greater(t, updateRes.TransactionId, insertRes.TransactionId)
+	require.EqualValues(t, 2, updateRes.Revision)
+
+	_, err = db.UpdateDocument(ctx, "admin", &protomodel.UpdateDocumentRequest{
+		CollectionName:        "mycollection",
+		DocumentId:            docID,
+		Document:              &structpb.Struct{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("bob")}},
+		ExpectedTransactionId: insertRes.TransactionId,
+	})
+	require.ErrorIs(t, err, document.ErrTxConflict)
+
+	_, err = db.UpdateDocument(ctx, "admin", &protomodel.UpdateDocumentRequest{
+		CollectionName:        "mycollection",
+		Document:              &structpb.Struct{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("bob")}},
+		ExpectedTransactionId: updateRes.TransactionId,
+	})
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	searchRes, err := db.SearchDocuments(ctx, &protomodel.Query{CollectionName: "mycollection"}, 0)
+	require.NoError(t, err)
+	defer searchRes.Close()
+
+	revisions, err := searchRes.ReadN(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "alice smith", revisions[0].Document.Fields["name"].GetStringValue())
+}
+
 func TestDocumentDB_AuditDocuments_CornerCases(t *testing.T) {
 	db := makeDocumentDb(t)
 
@@ -711,3 +1048,309 @@ func TestDocumentDB_WithSerializedJsonDocument(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestDocumentDB_WatchCollection(t *testing.T) {
+	db := makeDocumentDb(t)
+
+	ctx := context.Background()
+
+	for _, collectionName := range []string{"users", "others"} {
+		_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{
+			Name:   collectionName,
+			Fields: []*protomodel.Field{{Name: "name", Type: protomodel.FieldType_STRING}},
+		})
+		require.NoError(t, err)
+	}
+
+	err := db.WatchCollection(ctx, &protomodel.CollectionWatchRequest{CollectionName: "missing"}, func(*protomodel.CollectionWatchResponse) error { return nil })
+	require.ErrorIs(t, err, document.ErrCollectionDoesNotExist)
+
+	state, err := db.CurrentState()
+	require.NoError(t, err)
+
+	err = db.WatchCollection(ctx, &protomodel.CollectionWatchRequest{CollectionName: "users", SinceTransactionId: state.TxId + 1}, func(*protomodel.CollectionWatchResponse) error { return nil })
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	watch := func(sinceTx uint64) (<-chan *protomodel.CollectionWatchResponse, context.CancelFunc, <-chan error) {
+		watchCtx, cancel := context.WithCancel(ctx)
+
+		changes := make(chan *protomodel.CollectionWatchResponse, 10)
+		done := make(chan error, 1)
+
+		go func() {
+			done <- db.WatchCollection(watchCtx, &protomodel.CollectionWatchRequest{
+				CollectionName:     "users",
+				SinceTransactionId: sinceTx,
+			}, func(change *protomodel.CollectionWatchResponse) error {
+				changes <- change
+				return nil
+			})
+		}()
+
+		return changes, cancel, done
+	}
+
+	changes, cancel, done := watch(state.TxId)
+
+	insertRes, err := db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+		CollectionName: "users",
+		Documents: []*structpb.Struct{
+			{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("alice")}},
+		},
+	})
+	require.NoError(t, err)
+
+	docID := insertRes.DocumentIds[0]
+
+	_, err = db.InsertDocuments(ctx, "admin", &protomodel.InsertDocumentsRequest{
+		CollectionName: "others",
+		Documents: []*structpb.Struct{
+			{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("bob")}},
+		},
+	})
+	require.NoError(t, err)
+
+	query := &protomodel.Query{
+		CollectionName: "users",
+		Expressions: []*protomodel.QueryExpression{
+			{
+				FieldComparisons: []*protomodel.FieldComparison{
+					{Field: "_id", Operator: protomodel.ComparisonOperator_EQ, Value: structpb.NewStringValue(docID)},
+				},
+			},
+		},
+	}
+
+	replaceRes, err := db.ReplaceDocuments(ctx, "admin", &protomodel.ReplaceDocumentsRequest{
+		Query:    query,
+		Document: &structpb.Struct{Fields: map[string]*structpb.Value{"name": structpb.NewStringValue("alice smith")}},
+	})
+	require.NoError(t, err)
+
+	_, err = db.DeleteDocuments(ctx, "admin", &protomodel.DeleteDocumentsRequest{Query: query})
+	require.NoError(t, err)
+
+	next := func(t *testing.T, changes <-chan *protomodel.CollectionWatchResponse) *protomodel.CollectionWatchResponse {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "change not received")
+			return nil
+		}
+	}
+
+	t.Run("changes should be received in commit order", func(t *testing.T) {
+		change := next(t, changes)
+		require.Equal(t, protomodel.ChangeOperation_INSERT, change.Operation)
+		require.Equal(t, docID, change.DocumentId)
+		require.Equal(t, insertRes.TransactionId, change.TransactionId)
+		require.EqualValues(t, 1, change.Revision)
+		require.Equal(t, "alice", change.Document.Fields["name"].GetStringValue())
+
+		change = next(t, changes)
+		require.Equal(t, protomodel.ChangeOperation_UPDATE, change.Operation)
+		require.Equal(t, docID, change.DocumentId)
+		require.Equal(t, replaceRes.Revisions[0].TransactionId, change.TransactionId)
+		require.EqualValues(t, 2, change.Revision)
+		require.Equal(t, "alice smith", change.Document.Fields["name"].GetStringValue())
+
+		change = next(t, changes)
+		require.Equal(t, protomodel.ChangeOperation_DELETE, change.Operation)
+		require.Equal(t, docID, change.DocumentId)
+		require.Greater(t, change.TransactionId, replaceRes.Revisions[0].TransactionId)
+		require.Nil(t, change.Document)
+
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+		require.Empty(t, changes)
+	})
+
+	t.Run("watching should be resumable from a transaction", func(t *testing.T) {
+		changes, cancel, done := watch(insertRes.TransactionId)
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		change := next(t, changes)
+		require.Equal(t, protomodel.ChangeOperation_UPDATE, change.Operation)
+
+		change = next(t, changes)
+		require.Equal(t, protomodel.ChangeOperation_DELETE, change.Operation)
+	})
+}
+
+func TestDocumentDB_UploadDocument(t *testing.T) {
+	db := makeDocumentDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{
+		Name:   "files",
+		Fields: []*protomodel.Field{{Name: "name", Type: protomodel.FieldType_STRING}},
+	})
+	require.NoError(t, err)
+
+	var sb strings.Builder
+	for i := 0; sb.Len() < 2<<20; i++ {
+		fmt.Fprintf(&sb, "%08d: the quick brown fox jumps over the lazy dog\n", i)
+	}
+	content := sb.String()
+
+	const partSize = 100_000
+
+	chunks := []*protomodel.UploadDocumentChunk{{
+		CollectionName: "files",
+		Document: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"name": structpb.NewStringValue("fox.txt"),
+		}},
+	}}
+
+	for i := 0; i < len(content); i += partSize {
+		end := i + partSize
+		if end > len(content) {
+			end = len(content)
+		}
+
+		chunks = append(chunks, &protomodel.UploadDocumentChunk{Field: "content", Data: []byte(content[i:end])})
+	}
+
+	recvFrom := func(chunks []*protomodel.UploadDocumentChunk) func() (*protomodel.UploadDocumentChunk, error) {
+		return func() (*protomodel.UploadDocumentChunk, error) {
+			if len(chunks) == 0 {
+				return nil, io.EOF
+			}
+
+			chunk := chunks[0]
+			chunks = chunks[1:]
+
+			return chunk, nil
+		}
+	}
+
+	res, err := db.UploadDocument(ctx, "admin", recvFrom(chunks))
+	require.NoError(t, err)
+	require.NotZero(t, res.TransactionId)
+
+	docReader, err := db.SearchDocuments(ctx, &protomodel.Query{CollectionName: "files"}, 0)
+	require.NoError(t, err)
+	defer docReader.Close()
+
+	rev, err := docReader.Read(ctx)
+	require.NoError(t, err)
+	require.Equal(t, res.DocumentId, rev.Document.Fields["_id"].GetStringValue())
+	require.Equal(t, "fox.txt", rev.Document.Fields["name"].GetStringValue())
+	require.Equal(t, content, rev.Document.Fields["content"].GetStringValue())
+
+	t.Run("the document revision should be verifiable", func(t *testing.T) {
+		proofRes, err := db.ProofDocument(ctx, &protomodel.ProofDocumentRequest{
+			CollectionName: "files",
+			DocumentId:     res.DocumentId,
+			TransactionId:  res.TransactionId,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, proofRes)
+	})
+
+	t.Run("invalid uploads should fail", func(t *testing.T) {
+		_, err := db.UploadDocument(ctx, "admin", recvFrom(nil))
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.UploadDocument(ctx, "admin", recvFrom([]*protomodel.UploadDocumentChunk{
+			{CollectionName: "files"},
+			{CollectionName: "files"},
+		}))
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.UploadDocument(ctx, "admin", recvFrom([]*protomodel.UploadDocumentChunk{
+			{CollectionName: "files", Data: []byte("data")},
+		}))
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.UploadDocument(ctx, "admin", recvFrom([]*protomodel.UploadDocumentChunk{
+			{CollectionName: "missing"},
+		}))
+		require.ErrorIs(t, err, document.ErrCollectionDoesNotExist)
+	})
+
+	t.Run("the database should not be locked while chunks are received", func(t *testing.T) {
+		recv := recvFrom([]*protomodel.UploadDocumentChunk{
+			{CollectionName: "files"},
+			{Field: "content", Data: []byte("first part")},
+		})
+
+		// exclusive writes must not wait for the upload to complete
+		_, err := db.UploadDocument(ctx, "admin", func() (*protomodel.UploadDocumentChunk, error) {
+			_, err := db.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{
+				{Operation: &schema.Op_Kv{Kv: &schema.KeyValue{Key: []byte("key"), Value: []byte("value")}}},
+			}})
+			require.NoError(t, err)
+
+			return recv()
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestDocumentDB_UploadDocumentLimits(t *testing.T) {
+	ctx := context.Background()
+
+	// chunks of distinct content, each one filling up a whole stored chunk
+	uploadFrom := func(maxChunks, chunkSize int) (recv func() (*protomodel.UploadDocumentChunk, error), received *int) {
+		received = new(int)
+
+		return func() (*protomodel.UploadDocumentChunk, error) {
+			*received++
+
+			switch {
+			case *received == 1:
+				return &protomodel.UploadDocumentChunk{CollectionName: "files"}, nil
+			case *received <= maxChunks+1:
+				data := strings.Repeat(fmt.Sprintf("%c", 'a'+*received%26), chunkSize-8) + fmt.Sprintf("%08d", *received)
+				return &protomodel.UploadDocumentChunk{Field: "content", Data: []byte(data)}, nil
+			}
+
+			return nil, io.EOF
+		}, received
+	}
+
+	t.Run("uploads should fail as soon as values exceed the transaction limit", func(t *testing.T) {
+		opts := DefaultOption().WithDBRootPath(t.TempDir())
+		opts.WithStoreOptions(opts.storeOpts.WithMaxTxEntries(32))
+
+		db := makeDbWith(t, "db", opts)
+
+		_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{Name: "files"})
+		require.NoError(t, err)
+
+		_, maxValueLen, maxTxEntries := db.TxLimits()
+
+		recv, received := uploadFrom(10*maxTxEntries, maxValueLen)
+
+		_, err = db.UploadDocument(ctx, "admin", recv)
+		require.ErrorIs(t, err, store.ErrMaxTxEntriesLimitExceeded)
+		require.Less(t, *received, 2*maxTxEntries)
+	})
+
+	t.Run("uploads should fail as soon as values exceed the write rate limit", func(t *testing.T) {
+		opts := DefaultOption().
+			WithDBRootPath(t.TempDir()).
+			WithMaxWriteBytesPerSecond(1000)
+
+		db := makeDbWith(t, "db", opts)
+
+		_, err := db.CreateCollection(ctx, "admin", &protomodel.CreateCollectionRequest{Name: "files"})
+		require.NoError(t, err)
+
+		now := time.Now()
+		db.writeLimiter.now = func() time.Time { return now }
+		db.writeLimiter.lastRefill = now
+
+		recv, received := uploadFrom(10, 600)
+
+		_, err = db.UploadDocument(ctx, "admin", recv)
+		require.ErrorIs(t, err, ErrRateLimited)
+		require.Equal(t, 3, *received)
+	})
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// EntryMetadata is the metadata of a version of a key, the value is not included. Unlike Get, the latest
+// version is returned even if it denotes a deletion or it's expired, and references are not resolved.
+type EntryMetadata struct {
+	Key      []byte
+	Tx       uint64
+	Revision uint64
+
+	Deleted      bool
+	NonIndexable bool
+	Expired      bool
+	// ExpiresAt is the expiration time of the entry, zero when the entry does not expire
+	ExpiresAt time.Time
+
+	// ValueLen is the length of the value, zero for references
+	ValueLen int
+
+	// ReferencedKey and Reference describe the reference when the key is one, the reference holds
+	// the creator and the rest of the reference attributes
+	ReferencedKey []byte
+	Reference     *schema.Reference
+}
+
+// GetEntryMetadata returns the metadata of the key at the version selected by the request
+func (d *db) GetEntryMetadata(ctx context.Context, req *schema.KeyRequest) (*EntryMetadata, error) {
+	err := checkKeyRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.waitForKeyRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := EncodeKey(d.storedKey(req.Key))
+
+	// deleted and expired entries are included
+	txID, revision, md, val, err := d.readKeyVersion(ctx, key, req)
+	if err != nil {
+		return nil, err
+	}
+
+	emd := &EntryMetadata{
+		Key:      req.Key,
+		Tx:       txID,
+		Revision: revision,
+	}
+
+	if md != nil {
+		emd.Deleted = md.Deleted()
+		emd.NonIndexable = md.NonIndexable()
+		emd.Expired = md.ExpiredAt(time.Now())
+
+		if md.IsExpirable() {
+			emd.ExpiresAt, _ = md.ExpirationTime()
+		}
+	}
+
+	if emd.Deleted {
+		// deletions are stored without value
+		return emd, nil
+	}
+
+	if len(val) < 1 {
+		return nil, fmt.Errorf("%w: internal value consistency error - missing value prefix", store.ErrCorruptedData)
+	}
+
+	if isReferenceValue(val) {
+		refKey, atTx, attrs, err := unwrapReferenceValue(val)
+		if err != nil {
+			return nil, err
+		}
+
+		emd.ReferencedKey = TrimPrefix(refKey)
+		emd.Reference = referenceDescriptor(key, txID, md, refKey, atTx, revision, attrs)
+	} else {
+		emd.ValueLen = len(val) - 1
+	}
+
+	return emd, nil
+}
+
+// readKeyVersion reads the version of the key selected by the request, the latest one being selected with the
+// given filters. The value is returned as stored, thus references are not resolved.
+func (d *db) readKeyVersion(
+	ctx context.Context,
+	key []byte,
+	req *schema.KeyRequest,
+	filters ...store.FilterFn,
+) (txID, revision uint64, md *store.KVMetadata, val []byte, err error) {
+	switch {
+	case req.AtRevision != 0:
+		var valRef store.ValueRef
+
+		valRef, revision, err = d.valRefAtRevision(key, req.AtRevision)
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+
+		txID = valRef.Tx()
+
+		md, val, err = d.readMetadataAndValue(key, txID, true)
+	case req.AtTx != 0:
+		txID = req.AtTx
+
+		md, val, err = d.readMetadataAndValue(key, txID, true)
+	default:
+		var valRef store.ValueRef
+
+		valRef, err = d.st.GetWithFilters(ctx, key, filters...)
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+
+		txID = valRef.Tx()
+		revision = valRef.HC()
+		md = valRef.KVMetadata()
+
+		val, err = valRef.Resolve()
+	}
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	return txID, revision, md, val, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEntryMetadata(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	hdr1, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{
+		Key:      []byte("key1"),
+		Value:    []byte("value1"),
+		Metadata: &schema.KVMetadata{Expiration: &schema.Expiration{ExpiresAt: expiresAt.Unix()}},
+	}}})
+	require.NoError(t, err)
+
+	hdr2, err := db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("ref1"),
+		ReferencedKey: []byte("key1"),
+		CreatedBy:     "alice",
+		CreatedAt:     1700000000,
+	})
+	require.NoError(t, err)
+
+	hdr3, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{
+		Key:      []byte("key2"),
+		Value:    []byte("value2"),
+		Metadata: &schema.KVMetadata{NonIndexable: true},
+	}}})
+	require.NoError(t, err)
+
+	t.Run("invalid requests should be rejected", func(t *testing.T) {
+		_, err := db.GetEntryMetadata(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.GetEntryMetadata(ctx, &schema.KeyRequest{Key: []byte("missing")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("the metadata of a value entry should be returned", func(t *testing.T) {
+		md, err := db.GetEntryMetadata(ctx, &schema.KeyRequest{Key: []byte("key1")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("key1"), md.Key)
+		require.Equal(t, hdr1.Id, md.Tx)
+		require.EqualValues(t, 1, md.Revision)
+		require.False(t, md.Deleted)
+		require.False(t, md.NonIndexable)
+		require.False(t, md.Expired)
+		require.Equal(t, expiresAt.Unix(), md.ExpiresAt.Unix())
+		require.Equal(t, len("value1"), md.ValueLen)
+		require.Nil(t, md.ReferencedKey)
+		require.Nil(t, md.Reference)
+	})
+
+	t.Run("the descriptor of a reference should be returned without resolving it", func(t *testing.T) {
+		md, err := db.GetEntryMetadata(ctx, &schema.KeyRequest{Key: []byte("ref1")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("ref1"), md.Key)
+		require.Equal(t, hdr2.Id, md.Tx)
+		require.EqualValues(t, 1, md.Revision)
+		require.Zero(t, md.ValueLen)
+		require.True(t, md.ExpiresAt.IsZero())
+		require.Equal(t, []byte("key1"), md.ReferencedKey)
+		require.NotNil(t, md.Reference)
+		require.Equal(t, []byte("ref1"), md.Reference.Key)
+		require.Equal(t, hdr2.Id, md.Reference.Tx)
+		require.Equal(t, "alice", md.Reference.CreatedBy)
+		require.EqualValues(t, 1700000000, md.Reference.CreatedAt)
+	})
+
+	t.Run("the metadata of a non-indexable entry should be returned at its tx", func(t *testing.T) {
+		md, err := db.GetEntryMetadata(ctx, &schema.KeyRequest{Key: []byte("key2"), AtTx: hdr3.Id})
+		require.NoError(t, err)
+		require.Equal(t, hdr3.Id, md.Tx)
+		require.True(t, md.NonIndexable)
+		require.Equal(t, len("value2"), md.ValueLen)
+	})
+
+	hdr4, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("key1")}})
+	require.NoError(t, err)
+
+	t.Run("the metadata of a deleted entry should be returned", func(t *testing.T) {
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("key1")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		md, err := db.GetEntryMetadata(ctx, &schema.KeyRequest{Key: []byte("key1")})
+		require.NoError(t, err)
+		require.Equal(t, hdr4.Id, md.Tx)
+		require.EqualValues(t, 2, md.Revision)
+		require.True(t, md.Deleted)
+
+		md, err = db.GetEntryMetadata(ctx, &schema.KeyRequest{Key: []byte("key1"), AtRevision: -1})
+		require.NoError(t, err)
+		require.Equal(t, hdr1.Id, md.Tx)
+		require.EqualValues(t, 1, md.Revision)
+		require.False(t, md.Deleted)
+	})
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/signer"
+	"google.golang.org/protobuf/proto"
+)
+
+// An export bundle is a self-contained sequence of records, each one made of a 1-byte record type,
+// a 4-byte big-endian length and the payload:
+//
+//   - a transaction record for each transaction in the exported range, holding the transaction in
+//     protobuf format including the raw values of its entries (the values of expired entries are omitted)
+//   - the manifest record, holding the JSON encoded manifest of the bundle
+//   - the signature record, holding the signature of the manifest record payload
+//
+// Bundles can be verified without contacting the server, only the public key of the signer is required.
+
+const (
+	bundleTxRecord        = byte(1)
+	bundleManifestRecord  = byte(2)
+	bundleSignatureRecord = byte(3)
+
+	maxBundleRecordLen = 64 << 20
+)
+
+var ErrInvalidBundle = errors.New("invalid export bundle")
+
+// BundleManifest describes the content of an export bundle
+type BundleManifest struct {
+	Database string `json:"database"`
+
+	// FromTx and ToTx delimit the range of exported transactions, both included
+	FromTx uint64 `json:"fromTx"`
+	ToTx   uint64 `json:"toTx"`
+
+	// PrevAlh is the accumulative linear hash of the transaction preceding the first exported one
+	PrevAlh []byte `json:"prevAlh"`
+
+	// Alh is the accumulative linear hash of the last exported transaction
+	Alh []byte `json:"alh"`
+
+	// Digest is the sha256 digest of the transaction records
+	Digest []byte `json:"digest"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExportBundle writes the transactions in the range [fromTx, toTx] followed by the manifest of the bundle signed
+// with the provided signer. The manifest is returned once the bundle was fully written.
+func (d *db) ExportBundle(ctx context.Context, fromTx, toTx uint64, w io.Writer, s signer.Signer) (*BundleManifest, error) {
+	if fromTx == 0 || toTx < fromTx || w == nil || s == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	lastTxID, _ := d.st.CommittedAlh()
+	if toTx > lastTxID {
+		return nil, fmt.Errorf("%w: toTx must not be greater than the current transaction ID", ErrIllegalArguments)
+	}
+
+	tx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(tx)
+
+	bw := bufio.NewWriter(w)
+	digest := sha256.New()
+
+	manifest := &BundleManifest{
+		Database:  d.name,
+		FromTx:    fromTx,
+		ToTx:      toTx,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	for txID := fromTx; txID <= toTx; txID++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		err = d.st.ReadTx(txID, false, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		if txID == fromTx {
+			manifest.PrevAlh = tx.Header().PrevAlh[:]
+		}
+
+		if txID == toTx {
+			alh := tx.Header().Alh()
+			manifest.Alh = alh[:]
+		}
+
+		stx, err := d.rawTx(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := proto.Marshal(stx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeBundleRecord(io.MultiWriter(bw, digest), bundleTxRecord, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest.Digest = digest.Sum(nil)
+
+	encManifest, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, _, err := s.Sign(encManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeBundleRecord(bw, bundleManifestRecord, encManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeBundleRecord(bw, bundleSignatureRecord, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// rawTx returns the transaction including the raw values of all its entries
+func (d *db) rawTx(tx *store.Tx) (*schema.Tx, error) {
+	stx := schema.TxToProto(tx)
+
+	for i, e := range tx.Entries() {
+		v, err := d.st.ReadValue(e)
+		if errors.Is(err, store.ErrExpiredEntry) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		stx.Entries[i].Value = v
+	}
+
+	return stx, nil
+}
+
+func writeBundleRecord(w io.Writer, recordType byte, payload []byte) error {
+	var hdr [5]byte
+	hdr[0] = recordType
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(payload)))
+
+	_, err := w.Write(hdr[:])
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(payload)
+	return err
+}
+
+func readBundleRecord(r io.Reader) (recordType byte, payload []byte, err error) {
+	var hdr [5]byte
+
+	_, err = io.ReadFull(r, hdr[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	payloadLen := binary.BigEndian.Uint32(hdr[1:])
+	if payloadLen > maxBundleRecordLen {
+		return 0, nil, fmt.Errorf("%w: record too large", ErrInvalidBundle)
+	}
+
+	payload = make([]byte, payloadLen)
+
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return hdr[0], payload, nil
+}
+
+// VerifyBundle checks the bundle was signed by the owner of the public key and that it holds the complete
+// and untampered sequence of transactions described by its manifest, which is returned on success.
+func VerifyBundle(r io.Reader, publicKey *ecdsa.PublicKey) (*BundleManifest, error) {
+	if r == nil || publicKey == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	br := bufio.NewReader(r)
+	digest := sha256.New()
+
+	var txs []*schema.Tx
+
+	var encManifest []byte
+
+	for encManifest == nil {
+		recordType, payload, err := readBundleRecord(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+
+		switch recordType {
+		case bundleTxRecord:
+			stx := &schema.Tx{}
+
+			err = proto.Unmarshal(payload, stx)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+			}
+
+			txs = append(txs, stx)
+
+			err = writeBundleRecord(digest, recordType, payload)
+			if err != nil {
+				return nil, err
+			}
+		case bundleManifestRecord:
+			encManifest = payload
+		default:
+			return nil, fmt.Errorf("%w: unexpected record type %d", ErrInvalidBundle, recordType)
+		}
+	}
+
+	recordType, signature, err := readBundleRecord(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if recordType != bundleSignatureRecord {
+		return nil, fmt.Errorf("%w: signature not found", ErrInvalidBundle)
+	}
+
+	err = signer.Verify(encManifest, signature, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	var manifest BundleManifest
+
+	err = json.Unmarshal(encManifest, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	if !bytes.Equal(manifest.Digest, digest.Sum(nil)) {
+		return nil, fmt.Errorf("%w: digest mismatch", ErrInvalidBundle)
+	}
+
+	if uint64(len(txs)) != manifest.ToTx-manifest.FromTx+1 {
+		return nil, fmt.Errorf("%w: missing transactions", ErrInvalidBundle)
+	}
+
+	err = verifyBundleTxs(txs, &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// verifyBundleTxs checks each transaction is consistent with its entries and linked to the previous one
+func verifyBundleTxs(txs []*schema.Tx, manifest *BundleManifest) error {
+	prevAlh := schema.DigestFromProto(manifest.PrevAlh)
+
+	for i, stx := range txs {
+		if stx.Header == nil || stx.Header.Id != manifest.FromTx+uint64(i) {
+			return fmt.Errorf("%w: unexpected transaction", ErrInvalidBundle)
+		}
+
+		if int(stx.Header.Nentries) != len(stx.Entries) {
+			return fmt.Errorf("%w: invalid number of entries in transaction %d", ErrInvalidBundle, stx.Header.Id)
+		}
+
+		for _, e := range stx.Entries {
+			if len(e.Value) > 0 && sha256.Sum256(e.Value) != schema.DigestFromProto(e.HValue) {
+				return fmt.Errorf("%w: value mismatch in transaction %d", ErrInvalidBundle, stx.Header.Id)
+			}
+		}
+
+		// the entries digest is recalculated from the entries of the transaction
+		tx := schema.TxFromProto(stx)
+
+		if tx.Header().Eh != schema.DigestFromProto(stx.Header.EH) {
+			return fmt.Errorf("%w: entries mismatch in transaction %d", ErrInvalidBundle, stx.Header.Id)
+		}
+
+		if tx.Header().PrevAlh != prevAlh {
+			return fmt.Errorf("%w: transaction %d is not linked to the previous one", ErrInvalidBundle, stx.Header.Id)
+		}
+
+		prevAlh = tx.Header().Alh()
+	}
+
+	if prevAlh != schema.DigestFromProto(manifest.Alh) {
+		return fmt.Errorf("%w: alh mismatch", ErrInvalidBundle)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/signer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportBundle(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+			{Key: []byte{'k', byte(i)}, Value: []byte{'v', byte(i)}},
+		}})
+		require.NoError(t, err)
+	}
+
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	s := signer.NewSignerFromPKey(rand.Reader, pk)
+
+	var buf bytes.Buffer
+
+	manifest, err := db.ExportBundle(ctx, 2, 4, &buf, s)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), manifest.FromTx)
+	require.Equal(t, uint64(4), manifest.ToTx)
+
+	hdr, err := db.TxByID(ctx, &schema.TxRequest{Tx: 4})
+	require.NoError(t, err)
+
+	alh := schema.TxHeaderFromProto(hdr.Header).Alh()
+	require.Equal(t, alh[:], manifest.Alh)
+
+	t.Run("a valid bundle should be verified", func(t *testing.T) {
+		verified, err := VerifyBundle(bytes.NewReader(buf.Bytes()), &pk.PublicKey)
+		require.NoError(t, err)
+		require.Equal(t, manifest.Alh, verified.Alh)
+		require.Equal(t, manifest.PrevAlh, verified.PrevAlh)
+		require.Equal(t, manifest.Digest, verified.Digest)
+	})
+
+	t.Run("a tampered bundle should fail verification", func(t *testing.T) {
+		tampered := append([]byte(nil), buf.Bytes()...)
+
+		i := bytes.Index(tampered, []byte{'v', 3})
+		require.Greater(t, i, 0)
+		tampered[i+1] = 9
+
+		_, err := VerifyBundle(bytes.NewReader(tampered), &pk.PublicKey)
+		require.ErrorIs(t, err, ErrInvalidBundle)
+
+		_, err = VerifyBundle(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), &pk.PublicKey)
+		require.ErrorIs(t, err, ErrInvalidBundle)
+	})
+
+	t.Run("a bundle signed with another key should fail verification", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		_, err = VerifyBundle(bytes.NewReader(buf.Bytes()), &otherKey.PublicKey)
+		require.ErrorIs(t, err, ErrInvalidBundle)
+	})
+
+	t.Run("invalid ranges should be rejected", func(t *testing.T) {
+		_, err := db.ExportBundle(ctx, 0, 1, &buf, s)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ExportBundle(ctx, 3, 2, &buf, s)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ExportBundle(ctx, 1, 100, &buf, s)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrGetTimeout = errors.New("get timeout exceeded")
+
+// GetTimeoutError is returned when the resolution of a Get request does not complete within its timeout
+type GetTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *GetTimeoutError) Error() string {
+	return fmt.Sprintf("%s, resolution did not complete within %s", ErrGetTimeout.Error(), e.Timeout)
+}
+
+func (e *GetTimeoutError) Is(target error) bool {
+	return target == ErrGetTimeout
+}
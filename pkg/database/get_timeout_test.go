@@ -0,0 +1,65 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTimeout(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("key")})
+	require.NoError(t, err)
+
+	t.Run("a fast resolution should succeed", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ref"), TimeoutMs: 1000})
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), entry.Value)
+	})
+
+	t.Run("a slow reference follow should time out", func(t *testing.T) {
+		db.resolutionHook = func(key []byte) {
+			if bytes.Equal(key, EncodeKey([]byte("key"))) {
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+		defer func() { db.resolutionHook = nil }()
+
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ref"), TimeoutMs: 10})
+		require.ErrorIs(t, err, ErrGetTimeout)
+
+		var timeoutErr *GetTimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		require.Equal(t, 10*time.Millisecond, timeoutErr.Timeout)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ref")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), entry.Value)
+	})
+}
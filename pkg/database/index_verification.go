@@ -0,0 +1,141 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/codenotary/immudb/embedded/store"
+)
+
+var ErrInconsistentIndex = errors.New("index is not consistent with the transaction log")
+
+// IndexInconsistencyError describes the first key whose index entry does not match the transaction log
+type IndexInconsistencyError struct {
+	Key []byte
+	// ExpectedTx is the latest transaction the key was written at
+	ExpectedTx uint64
+	// IndexedTx is the transaction of the index entry, zero when the key is not indexed
+	IndexedTx uint64
+}
+
+func (e *IndexInconsistencyError) Error() string {
+	return fmt.Sprintf("%s, key '%s' was written at tx %d but its index entry refers to tx %d",
+		ErrInconsistentIndex.Error(), e.Key, e.ExpectedTx, e.IndexedTx)
+}
+
+func (e *IndexInconsistencyError) Is(target error) bool {
+	return target == ErrInconsistentIndex
+}
+
+type expectedIndexEntry struct {
+	tx   uint64
+	hVal [sha256.Size]byte
+}
+
+// indexedPrefixes returns the prefixes of the key spaces indexed by the database itself,
+// SQL and document engines maintain their own indexes
+func (d *db) indexedPrefixes() []byte {
+	prefixes := []byte{SetKeyPrefix, SortedSetKeyPrefix, RenameLinkPrefix, ReferenceGroupPrefix}
+
+	if d.keyObfuscationEnabled() {
+		prefixes = append(prefixes, ObfuscatedKeyMappingPrefix)
+	}
+
+	return prefixes
+}
+
+// VerifyIndex replays the committed transactions and checks the index entry of every indexed key refers to the
+// transaction and value digest the key was last written with. The first inconsistency in key order is returned.
+// The latest state of all the keys is kept in memory while verifying, thus it's meant to be run as a maintenance
+// operation e.g. after a crash, and it requires the whole transaction log to be available.
+func (d *db) VerifyIndex(ctx context.Context) error {
+	return d.verifyIndex(ctx, d.st)
+}
+
+func (d *db) verifyIndex(ctx context.Context, index store.KeyIndex) error {
+	lastTxID, _ := d.st.CommittedAlh()
+
+	err := d.st.WaitForIndexingUpto(ctx, lastTxID)
+	if err != nil {
+		return err
+	}
+
+	indexed := make(map[byte]struct{})
+	for _, prefix := range d.indexedPrefixes() {
+		indexed[prefix] = struct{}{}
+	}
+
+	tx, err := d.allocTx()
+	if err != nil {
+		return err
+	}
+	defer d.releaseTx(tx)
+
+	expected := make(map[string]expectedIndexEntry)
+
+	for txID := uint64(1); txID <= lastTxID; txID++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := d.st.ReadTx(txID, false, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range tx.Entries() {
+			if _, ok := indexed[e.Key()[0]]; !ok {
+				continue
+			}
+
+			if e.Metadata() != nil && e.Metadata().NonIndexable() {
+				continue
+			}
+
+			expected[string(e.Key())] = expectedIndexEntry{tx: txID, hVal: e.HVal()}
+		}
+	}
+
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		exp := expected[k]
+
+		valRef, err := index.GetWithFilters(ctx, []byte(k))
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return &IndexInconsistencyError{Key: []byte(k), ExpectedTx: exp.tx}
+		}
+		if err != nil {
+			return err
+		}
+
+		if valRef.Tx() != exp.tx || valRef.HVal() != exp.hVal {
+			return &IndexInconsistencyError{Key: []byte(k), ExpectedTx: exp.tx, IndexedTx: valRef.Tx()}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/logger"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptedIndex reports a stale transaction for one of the keys
+type corruptedIndex struct {
+	store.KeyIndex
+	key []byte
+}
+
+type staleValueRef struct {
+	store.ValueRef
+}
+
+func (v *staleValueRef) Tx() uint64 {
+	return v.ValueRef.Tx() - 1
+}
+
+func (idx *corruptedIndex) GetWithFilters(ctx context.Context, key []byte, filters ...store.FilterFn) (store.ValueRef, error) {
+	valRef, err := idx.KeyIndex.GetWithFilters(ctx, key, filters...)
+	if err != nil || !bytes.Equal(key, idx.key) {
+		return valRef, err
+	}
+	return &staleValueRef{ValueRef: valRef}, nil
+}
+
+func TestVerifyIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	d, err := NewDB("db", nil, DefaultOption().WithDBRootPath(dir), logger.NewSimpleLogger("immudb ", os.Stderr))
+	require.NoError(t, err)
+
+	db := d.(*db)
+
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+			{Key: []byte("key1"), Value: []byte{byte(i)}},
+			{Key: []byte{'k', byte(i)}, Value: []byte{byte(i)}},
+		}})
+		require.NoError(t, err)
+	}
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	_, err = db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{{'k', 0}}})
+	require.NoError(t, err)
+
+	t.Run("a consistent index should be verified", func(t *testing.T) {
+		err := db.VerifyIndex(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("a corrupted index entry should be detected", func(t *testing.T) {
+		err := db.verifyIndex(ctx, &corruptedIndex{KeyIndex: db.st, key: EncodeKey([]byte("key1"))})
+		require.ErrorIs(t, err, ErrInconsistentIndex)
+
+		var inconsistencyErr *IndexInconsistencyError
+		require.ErrorAs(t, err, &inconsistencyErr)
+		require.Equal(t, EncodeKey([]byte("key1")), inconsistencyErr.Key)
+		require.Equal(t, uint64(3), inconsistencyErr.ExpectedTx)
+		require.Equal(t, uint64(2), inconsistencyErr.IndexedTx)
+	})
+
+	t.Run("the index should be verified when opening the database in strict mode", func(t *testing.T) {
+		err := db.Close()
+		require.NoError(t, err)
+
+		reopened, err := OpenDB("db", nil, DefaultOption().WithDBRootPath(dir).WithVerifyIndexOnOpen(true), logger.NewSimpleLogger("immudb ", os.Stderr))
+		require.NoError(t, err)
+
+		err = reopened.Close()
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/codenotary/immudb/embedded/store"
+)
+
+// Key obfuscation stores the keyed hash of each key instead of the key itself. The key is kept
+// encrypted in a side mapping, thus keys can be returned when scanning but key bytes are never
+// stored in plain. The following tradeoffs apply when key obfuscation is enabled:
+//
+//   - exact-match operations (Set, Get, Delete, History and their verifiable variants) are supported
+//   - prefix and range scans are not available, full scans return entries in hash order
+//   - references, sorted sets and ExecAll are not supported
+//   - verifiable entries hold the stored (hashed) key, as it's the one included in the transaction
+//   - the secret is required to read the database, it can not be changed once data was written
+
+const (
+	minKeyObfuscationSecretLen = 16
+
+	keyObfuscationHashLabel       = "immudb-key-obfuscation-hash"
+	keyObfuscationEncryptionLabel = "immudb-key-obfuscation-encryption"
+)
+
+var (
+	ErrKeyObfuscationUnsupported = fmt.Errorf("%w: operation not supported when key obfuscation is enabled", store.ErrIllegalArguments)
+	ErrCorruptedKeyMapping       = fmt.Errorf("%w: corrupted obfuscated key mapping", store.ErrCorruptedData)
+)
+
+type keyObfuscator struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+func newKeyObfuscator(secret []byte) (*keyObfuscator, error) {
+	if len(secret) < minKeyObfuscationSecretLen {
+		return nil, fmt.Errorf("%w: key obfuscation secret must be at least %d bytes long", ErrIllegalArguments, minKeyObfuscationSecretLen)
+	}
+
+	block, err := aes.NewCipher(deriveKey(secret, keyObfuscationEncryptionLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyObfuscator{
+		hashKey: deriveKey(secret, keyObfuscationHashLabel),
+		aead:    aead,
+	}, nil
+}
+
+func deriveKey(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// obfuscate returns the key stored in place of the provided one
+func (o *keyObfuscator) obfuscate(key []byte) []byte {
+	mac := hmac.New(sha256.New, o.hashKey)
+	mac.Write(key)
+	return mac.Sum(nil)
+}
+
+// seal encrypts the key, the obfuscated key is authenticated so a mapping can not be moved to another key
+func (o *keyObfuscator) seal(obfuscatedKey, key []byte) ([]byte, error) {
+	nonce := make([]byte, o.aead.NonceSize(), o.aead.NonceSize()+len(key)+o.aead.Overhead())
+
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.aead.Seal(nonce, nonce, key, obfuscatedKey), nil
+}
+
+func (o *keyObfuscator) open(obfuscatedKey, sealedKey []byte) ([]byte, error) {
+	if len(sealedKey) < o.aead.NonceSize() {
+		return nil, ErrCorruptedKeyMapping
+	}
+
+	key, err := o.aead.Open(nil, sealedKey[:o.aead.NonceSize()], sealedKey[o.aead.NonceSize():], obfuscatedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptedKeyMapping, err)
+	}
+
+	return key, nil
+}
+
+func (d *db) keyObfuscationEnabled() bool {
+	return d.keyObfuscator != nil
+}
+
+// storedKey returns the key under which the provided one is stored
+func (d *db) storedKey(key []byte) []byte {
+	if d.keyObfuscator == nil {
+		return key
+	}
+	return d.keyObfuscator.obfuscate(key)
+}
+
+func encodeObfuscatedKeyMapping(obfuscatedKey []byte) []byte {
+	return WrapWithPrefix(obfuscatedKey, ObfuscatedKeyMappingPrefix)
+}
+
+// setObfuscatedKey adds the obfuscated key and the entry of the side mapping to the transaction
+func (d *db) setObfuscatedKey(tx *store.OngoingTx, key []byte) ([]byte, error) {
+	obfuscatedKey := d.keyObfuscator.obfuscate(key)
+
+	sealedKey, err := d.keyObfuscator.seal(obfuscatedKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Set(encodeObfuscatedKeyMapping(obfuscatedKey), nil, sealedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return obfuscatedKey, nil
+}
+
+// obfuscatePrecondition rewrites the key of the precondition with the obfuscated one
+func (d *db) obfuscatePrecondition(c store.Precondition) store.Precondition {
+	switch c := c.(type) {
+	case *store.PreconditionKeyMustExist:
+		return &store.PreconditionKeyMustExist{Key: EncodeKey(d.storedKey(TrimPrefix(c.Key)))}
+	case *store.PreconditionKeyMustNotExist:
+		return &store.PreconditionKeyMustNotExist{Key: EncodeKey(d.storedKey(TrimPrefix(c.Key)))}
+	case *store.PreconditionKeyNotModifiedAfterTx:
+		return &store.PreconditionKeyNotModifiedAfterTx{Key: EncodeKey(d.storedKey(TrimPrefix(c.Key))), TxID: c.TxID}
+	case *PreconditionKeyMustBeReference:
+		return &PreconditionKeyMustBeReference{Key: EncodeKey(d.storedKey(TrimPrefix(c.Key)))}
+	case *PreconditionKeyMustNotBeReference:
+		return &PreconditionKeyMustNotBeReference{Key: EncodeKey(d.storedKey(TrimPrefix(c.Key)))}
+	case *PreconditionKeyMustHaveValue:
+		return &PreconditionKeyMustHaveValue{Key: EncodeKey(d.storedKey(TrimPrefix(c.Key))), Value: c.Value}
+	}
+	return c
+}
+
+// keyMappingReader resolves obfuscated keys from the side mapping as of the given transaction
+type keyMappingReader struct {
+	obfuscator *keyObfuscator
+	snap       *store.Snapshot
+}
+
+func (d *db) newKeyMappingReader(ctx context.Context, txID uint64) (*keyMappingReader, error) {
+	snap, err := d.st.SnapshotMustIncludeTxID(ctx, []byte{ObfuscatedKeyMappingPrefix}, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyMappingReader{
+		obfuscator: d.keyObfuscator,
+		snap:       snap,
+	}, nil
+}
+
+func (r *keyMappingReader) key(ctx context.Context, obfuscatedKey []byte) ([]byte, error) {
+	valRef, err := r.snap.Get(ctx, encodeObfuscatedKeyMapping(obfuscatedKey))
+	if err != nil {
+		return nil, err
+	}
+
+	sealedKey, err := valRef.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.obfuscator.open(obfuscatedKey, sealedKey)
+}
+
+func (r *keyMappingReader) Close() error {
+	return r.snap.Close()
+}
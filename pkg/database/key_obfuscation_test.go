@@ -0,0 +1,122 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyObfuscation(t *testing.T) {
+	secret := []byte("a-secret-of-enough-length")
+
+	options := DefaultOption().WithDBRootPath(t.TempDir()).WithKeyObfuscationSecret(secret)
+	db := makeDbWith(t, "db", options)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("user:alice@example.com"), Value: []byte("alice")},
+		{Key: []byte("user:bob@example.com"), Value: []byte("bob")},
+	}})
+	require.NoError(t, err)
+
+	t.Run("exact-match get should round-trip obfuscated keys", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("user:alice@example.com")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("user:alice@example.com"), entry.Key)
+		require.Equal(t, []byte("alice"), entry.Value)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("user:carol@example.com")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("keys should not be stored in plain", func(t *testing.T) {
+		_, err := db.st.Get(ctx, EncodeKey([]byte("user:alice@example.com")))
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		_, err = db.st.Get(ctx, EncodeKey(db.storedKey([]byte("user:alice@example.com"))))
+		require.NoError(t, err)
+	})
+
+	t.Run("full scan should return the original keys", func(t *testing.T) {
+		entries, err := db.Scan(ctx, &schema.ScanRequest{})
+		require.NoError(t, err)
+		require.Len(t, entries.Entries, 2)
+
+		keys := []string{string(entries.Entries[0].Key), string(entries.Entries[1].Key)}
+		require.ElementsMatch(t, []string{"user:alice@example.com", "user:bob@example.com"}, keys)
+	})
+
+	t.Run("prefix scans and references should not be supported", func(t *testing.T) {
+		_, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("user:")})
+		require.ErrorIs(t, err, ErrKeyObfuscationUnsupported)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("user:bob@example.com")})
+		require.ErrorIs(t, err, ErrKeyObfuscationUnsupported)
+	})
+
+	t.Run("preconditions and deletions should use obfuscated keys", func(t *testing.T) {
+		_, err := db.Set(ctx, &schema.SetRequest{
+			KVs:           []*schema.KeyValue{{Key: []byte("user:alice@example.com"), Value: []byte("alice2")}},
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustNotExist([]byte("user:alice@example.com"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+
+		_, err = db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("user:bob@example.com")}})
+		require.NoError(t, err)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("user:bob@example.com")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("verifiable get should prove the stored key", func(t *testing.T) {
+		ventry, err := db.VerifiableGet(ctx, &schema.VerifiableGetRequest{
+			KeyRequest: &schema.KeyRequest{Key: []byte("user:alice@example.com")},
+		})
+		require.NoError(t, err)
+		require.Equal(t, db.storedKey([]byte("user:alice@example.com")), ventry.Entry.Key)
+	})
+
+	t.Run("get all should read obfuscated keys", func(t *testing.T) {
+		entries, err := db.GetAll(ctx, &schema.KeyListRequest{Keys: [][]byte{
+			[]byte("user:alice@example.com"),
+			[]byte("user:carol@example.com"),
+		}})
+		require.NoError(t, err)
+		require.Len(t, entries.Entries, 1)
+		require.Equal(t, []byte("user:alice@example.com"), entries.Entries[0].Key)
+		require.Equal(t, []byte("alice"), entries.Entries[0].Value)
+
+		ventries, err := db.VerifiableGetAll(ctx, &schema.VerifiableGetAllRequest{
+			KeyListRequest: &schema.KeyListRequest{Keys: [][]byte{[]byte("user:alice@example.com")}},
+		})
+		require.NoError(t, err)
+		require.Len(t, ventries.Txs, 1)
+		require.Len(t, ventries.Txs[0].Entries, 1)
+		require.Equal(t, db.storedKey([]byte("user:alice@example.com")), ventries.Txs[0].Entries[0].Key)
+	})
+
+	t.Run("a short secret should be rejected", func(t *testing.T) {
+		_, err := newKeyObfuscator([]byte("short"))
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
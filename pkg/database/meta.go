@@ -18,6 +18,7 @@ package database
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math"
 
 	"github.com/codenotary/immudb/embedded/store"
@@ -28,13 +29,164 @@ const (
 	SortedSetKeyPrefix
 	SQLPrefix
 	DocumentPrefix
+	ObfuscatedKeyMappingPrefix
+	RenameLinkPrefix
+	ReferenceGroupPrefix
 )
 
 const (
 	PlainValuePrefix = iota
 	ReferenceValuePrefix
+	AttributedReferenceValuePrefix
 )
 
+const (
+	refTransformAttrCode byte = iota
+	refCreatedByAttrCode
+	refCreatedAtAttrCode
+	refEffectiveFromAttrCode
+	refGroupAttrCode
+	refPriorityAttrCode
+	refLabelAttrCode
+)
+
+const (
+	refKeyLenSize  = 2
+	refAttrLenSize = 2
+)
+
+// ReferenceAttributes holds optional reference settings. They are stored as part of the
+// reference value, thus covered by the entry digest.
+type ReferenceAttributes struct {
+	// Transform is the name of the server-side transform applied to the referenced value
+	Transform string
+	// CreatedBy is the identity of the creator of the reference
+	CreatedBy string
+	// CreatedAt is the creation time of the reference in unix seconds
+	CreatedAt int64
+	// EffectiveFrom is the time in unix seconds from which the reference is resolved
+	EffectiveFrom int64
+	// Group is the name of the group the reference belongs to
+	Group []byte
+	// Priority is the priority of the reference within its group
+	Priority uint32
+	// Label is a human-readable label of the reference, not used for resolution
+	Label string
+}
+
+func (attrs *ReferenceAttributes) isEmpty() bool {
+	return attrs == nil ||
+		(attrs.Transform == "" && attrs.CreatedBy == "" && attrs.CreatedAt == 0 && attrs.EffectiveFrom == 0 &&
+			len(attrs.Group) == 0 && attrs.Priority == 0 && attrs.Label == "")
+}
+
+func (attrs *ReferenceAttributes) serialize() []byte {
+	var b []byte
+
+	if attrs.Transform != "" {
+		b = appendReferenceAttribute(b, refTransformAttrCode, []byte(attrs.Transform))
+	}
+
+	if attrs.CreatedBy != "" {
+		b = appendReferenceAttribute(b, refCreatedByAttrCode, []byte(attrs.CreatedBy))
+	}
+
+	if attrs.CreatedAt != 0 {
+		b = appendReferenceAttribute(b, refCreatedAtAttrCode, encodeReferenceTimeAttribute(attrs.CreatedAt))
+	}
+
+	if attrs.EffectiveFrom != 0 {
+		b = appendReferenceAttribute(b, refEffectiveFromAttrCode, encodeReferenceTimeAttribute(attrs.EffectiveFrom))
+	}
+
+	if len(attrs.Group) > 0 {
+		b = appendReferenceAttribute(b, refGroupAttrCode, attrs.Group)
+	}
+
+	if attrs.Priority != 0 {
+		var priority [4]byte
+		binary.BigEndian.PutUint32(priority[:], attrs.Priority)
+
+		b = appendReferenceAttribute(b, refPriorityAttrCode, priority[:])
+	}
+
+	if attrs.Label != "" {
+		b = appendReferenceAttribute(b, refLabelAttrCode, []byte(attrs.Label))
+	}
+
+	return b
+}
+
+func appendReferenceAttribute(b []byte, code byte, attr []byte) []byte {
+	var hdr [1 + refAttrLenSize]byte
+
+	hdr[0] = code
+	binary.BigEndian.PutUint16(hdr[1:], uint16(len(attr)))
+
+	b = append(b, hdr[:]...)
+	return append(b, attr...)
+}
+
+func (attrs *ReferenceAttributes) deserialize(b []byte) error {
+	for len(b) > 0 {
+		if len(b) < 1+refAttrLenSize {
+			return fmt.Errorf("%w: internal value consistency error - invalid reference attribute", store.ErrCorruptedData)
+		}
+
+		var err error
+
+		code := b[0]
+		attrLen := int(binary.BigEndian.Uint16(b[1:]))
+		b = b[1+refAttrLenSize:]
+
+		if len(b) < attrLen {
+			return fmt.Errorf("%w: internal value consistency error - invalid reference attribute", store.ErrCorruptedData)
+		}
+
+		switch code {
+		case refTransformAttrCode:
+			attrs.Transform = string(b[:attrLen])
+		case refCreatedByAttrCode:
+			attrs.CreatedBy = string(b[:attrLen])
+		case refCreatedAtAttrCode:
+			attrs.CreatedAt, err = decodeReferenceTimeAttribute(b[:attrLen])
+		case refEffectiveFromAttrCode:
+			attrs.EffectiveFrom, err = decodeReferenceTimeAttribute(b[:attrLen])
+		case refGroupAttrCode:
+			attrs.Group = append([]byte(nil), b[:attrLen]...)
+		case refPriorityAttrCode:
+			if attrLen != 4 {
+				return fmt.Errorf("%w: internal value consistency error - invalid reference attribute", store.ErrCorruptedData)
+			}
+			attrs.Priority = binary.BigEndian.Uint32(b[:attrLen])
+		case refLabelAttrCode:
+			attrs.Label = string(b[:attrLen])
+		default:
+			return fmt.Errorf("%w: internal value consistency error - unknown reference attribute", store.ErrCorruptedData)
+		}
+		if err != nil {
+			return err
+		}
+
+		b = b[attrLen:]
+	}
+
+	return nil
+}
+
+func encodeReferenceTimeAttribute(ts int64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(ts))
+	return b[:]
+}
+
+func decodeReferenceTimeAttribute(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("%w: internal value consistency error - invalid reference attribute", store.ErrCorruptedData)
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
 // WrapWithPrefix ...
 func WrapWithPrefix(b []byte, prefix byte) []byte {
 	wb := make([]byte, 1+len(b))
@@ -78,6 +230,38 @@ func EncodeReference(
 	}
 }
 
+// EncodeReferenceWithAttributes encodes a reference holding the specified attributes,
+// the resulting entry is the same as with EncodeReference when no attribute is set
+func EncodeReferenceWithAttributes(
+	key []byte,
+	md *store.KVMetadata,
+	referencedKey []byte,
+	atTx uint64,
+	attrs *ReferenceAttributes,
+) *store.EntrySpec {
+	return &store.EntrySpec{
+		Key:      WrapWithPrefix(key, SetKeyPrefix),
+		Metadata: md,
+		Value:    WrapReferenceValueWithAttributesAt(WrapWithPrefix(referencedKey, SetKeyPrefix), atTx, attrs),
+	}
+}
+
+// DecodeReference is the inverse of EncodeReference, it returns the referenced key and the transaction the
+// reference is bound to from the value of a reference entry, being atTx zero when the reference is not bound.
+// The value of references encoded with attributes is decoded as well, attributes are ignored.
+func DecodeReference(encoded []byte) (referencedKey []byte, atTx uint64, err error) {
+	key, atTx, _, err := unwrapReferenceValue(encoded)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(key) == 0 || key[0] != SetKeyPrefix {
+		return nil, 0, fmt.Errorf("%w: internal value consistency error - invalid referenced key", store.ErrCorruptedData)
+	}
+
+	return TrimPrefix(key), atTx, nil
+}
+
 func WrapReferenceValueAt(key []byte, atTx uint64) []byte {
 	refVal := make([]byte, 1+8+len(key))
 
@@ -88,6 +272,69 @@ func WrapReferenceValueAt(key []byte, atTx uint64) []byte {
 	return refVal
 }
 
+func WrapReferenceValueWithAttributesAt(key []byte, atTx uint64, attrs *ReferenceAttributes) []byte {
+	if attrs.isEmpty() {
+		return WrapReferenceValueAt(key, atTx)
+	}
+
+	encAttrs := attrs.serialize()
+
+	refVal := make([]byte, 1+8+refKeyLenSize+len(key)+len(encAttrs))
+
+	refVal[0] = AttributedReferenceValuePrefix
+	binary.BigEndian.PutUint64(refVal[1:], atTx)
+	binary.BigEndian.PutUint16(refVal[1+8:], uint16(len(key)))
+	copy(refVal[1+8+refKeyLenSize:], key)
+	copy(refVal[1+8+refKeyLenSize+len(key):], encAttrs)
+
+	return refVal
+}
+
+// minReferenceValueLen is the length of the shortest reference value, made of the prefix, the transaction
+// the reference is bound to and a prefixed key of a single byte
+const minReferenceValueLen = 1 + 8 + 2
+
+func isReferenceValue(val []byte) bool {
+	return len(val) > 0 && (val[0] == ReferenceValuePrefix || val[0] == AttributedReferenceValuePrefix)
+}
+
+// unwrapReferenceValue returns the (prefixed) referenced key, the transaction the reference
+// is bound to and the reference attributes
+func unwrapReferenceValue(val []byte) (key []byte, atTx uint64, attrs *ReferenceAttributes, err error) {
+	if !isReferenceValue(val) || len(val) < 1+8 {
+		return nil, 0, nil, fmt.Errorf("%w: internal value consistency error - invalid reference", store.ErrCorruptedData)
+	}
+
+	atTx = binary.BigEndian.Uint64(val[1:])
+	attrs = &ReferenceAttributes{}
+
+	if val[0] == ReferenceValuePrefix {
+		key = make([]byte, len(val)-1-8)
+		copy(key, val[1+8:])
+
+		return key, atTx, attrs, nil
+	}
+
+	if len(val) < 1+8+refKeyLenSize {
+		return nil, 0, nil, fmt.Errorf("%w: internal value consistency error - invalid reference", store.ErrCorruptedData)
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(val[1+8:]))
+	if len(val) < 1+8+refKeyLenSize+keyLen {
+		return nil, 0, nil, fmt.Errorf("%w: internal value consistency error - invalid reference", store.ErrCorruptedData)
+	}
+
+	key = make([]byte, keyLen)
+	copy(key, val[1+8+refKeyLenSize:])
+
+	err = attrs.deserialize(val[1+8+refKeyLenSize+keyLen:])
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	return key, atTx, attrs, nil
+}
+
 func EncodeZAdd(set []byte, score float64, key []byte, atTx uint64) *store.EntrySpec {
 	return &store.EntrySpec{
 		Key:   WrapZAddReferenceAt(set, score, key, atTx),
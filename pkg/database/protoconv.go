@@ -56,7 +56,56 @@ func PreconditionFromProto(c *schema.Precondition) (store.Precondition, error) {
 			Key:  EncodeKey(key),
 			TxID: c.KeyNotModifiedAfterTX.GetTxID(),
 		}, nil
+
+	case *schema.Precondition_KeyMustBeReference:
+		key := c.KeyMustBeReference.GetKey()
+		if len(key) == 0 {
+			return nil, store.ErrInvalidPreconditionNullKey
+		}
+
+		return &PreconditionKeyMustBeReference{
+			Key: EncodeKey(key),
+		}, nil
+
+	case *schema.Precondition_KeyMustNotBeReference:
+		key := c.KeyMustNotBeReference.GetKey()
+		if len(key) == 0 {
+			return nil, store.ErrInvalidPreconditionNullKey
+		}
+
+		return &PreconditionKeyMustNotBeReference{
+			Key: EncodeKey(key),
+		}, nil
+
+	case *schema.Precondition_KeyMustHaveValue:
+		key := c.KeyMustHaveValue.GetKey()
+		if len(key) == 0 {
+			return nil, store.ErrInvalidPreconditionNullKey
+		}
+
+		return &PreconditionKeyMustHaveValue{
+			Key:   EncodeKey(key),
+			Value: c.KeyMustHaveValue.GetValue(),
+		}, nil
 	}
 
 	return nil, store.ErrInvalidPreconditionNull
 }
+
+// ReferenceAttributesFromProto returns the attributes of the reference entry,
+// required to compute the digest of the reference entry
+func ReferenceAttributesFromProto(ref *schema.Reference) *ReferenceAttributes {
+	if ref == nil {
+		return nil
+	}
+
+	return &ReferenceAttributes{
+		Transform:     ref.Transform,
+		CreatedBy:     ref.CreatedBy,
+		CreatedAt:     ref.CreatedAt,
+		EffectiveFrom: ref.EffectiveFrom,
+		Group:         ref.Group,
+		Priority:      ref.Priority,
+		Label:         ref.Label,
+	}
+}
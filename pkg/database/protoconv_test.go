@@ -78,4 +78,15 @@ func TestPreconditionFromProto(t *testing.T) {
 		require.IsType(t, &store.PreconditionKeyNotModifiedAfterTx{}, c)
 	})
 
+	t.Run("KeyMustHaveValue", func(t *testing.T) {
+		_, err := PreconditionFromProto(schema.PreconditionKeyMustHaveValue(nil, []byte{1}))
+		require.ErrorIs(t, err, store.ErrInvalidPrecondition)
+		require.ErrorIs(t, err, store.ErrInvalidPreconditionNullKey)
+
+		c, err := PreconditionFromProto(schema.PreconditionKeyMustHaveValue([]byte{1}, []byte{2}))
+		require.NoError(t, err)
+		require.IsType(t, &PreconditionKeyMustHaveValue{}, c)
+		require.Equal(t, []byte{2}, c.(*PreconditionKeyMustHaveValue).Value)
+	})
+
 }
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("write rate limit exceeded")
+
+// RateLimitedError is returned when a write exceeds the rate limits of the database,
+// RetryAfter hints how long to wait before the write may be accepted
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s, retry after %v", ErrRateLimited.Error(), e.RetryAfter)
+}
+
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// writeRateLimiter is a token bucket limiting both the number of write operations
+// and the amount of written bytes per second. Up to one second of traffic may be
+// consumed in a single burst.
+type writeRateLimiter struct {
+	mutex sync.Mutex
+
+	opsPerSecond   float64
+	bytesPerSecond float64
+
+	availableOps   float64
+	availableBytes float64
+
+	lastRefill time.Time
+
+	now func() time.Time
+}
+
+// newWriteRateLimiter returns nil when no limit is set, so no limit is enforced
+func newWriteRateLimiter(opsPerSecond, bytesPerSecond int) *writeRateLimiter {
+	if opsPerSecond <= 0 && bytesPerSecond <= 0 {
+		return nil
+	}
+
+	l := &writeRateLimiter{
+		opsPerSecond:   float64(opsPerSecond),
+		bytesPerSecond: float64(bytesPerSecond),
+		now:            time.Now,
+	}
+
+	l.availableOps = l.opsPerSecond
+	l.availableBytes = l.bytesPerSecond
+	l.lastRefill = l.now()
+
+	return l
+}
+
+// acquire consumes the tokens required by a write of the specified size,
+// nothing is consumed if the write needs to be throttled
+func (l *writeRateLimiter) acquire(ops, bytes int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	elapsed := now.Sub(l.lastRefill).Seconds()
+	l.lastRefill = now
+
+	l.availableOps = refill(l.availableOps, l.opsPerSecond, elapsed)
+	l.availableBytes = refill(l.availableBytes, l.bytesPerSecond, elapsed)
+
+	opsWait := waitFor(l.availableOps, l.opsPerSecond, float64(ops))
+	bytesWait := waitFor(l.availableBytes, l.bytesPerSecond, float64(bytes))
+
+	retryAfter := opsWait
+	if bytesWait > retryAfter {
+		retryAfter = bytesWait
+	}
+
+	if retryAfter > 0 {
+		return &RateLimitedError{RetryAfter: retryAfter}
+	}
+
+	if l.opsPerSecond > 0 {
+		l.availableOps -= float64(ops)
+	}
+
+	if l.bytesPerSecond > 0 {
+		l.availableBytes -= float64(bytes)
+	}
+
+	return nil
+}
+
+func refill(available, perSecond, elapsed float64) float64 {
+	return math.Min(perSecond, available+perSecond*elapsed)
+}
+
+func waitFor(available, perSecond, required float64) time.Duration {
+	if perSecond <= 0 {
+		return 0
+	}
+
+	// writes larger than the burst size are accepted once the bucket is full
+	required = math.Min(required, perSecond)
+
+	if available >= required {
+		return 0
+	}
+
+	return time.Duration(math.Ceil((required - available) / perSecond * float64(time.Second)))
+}
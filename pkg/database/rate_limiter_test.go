@@ -0,0 +1,117 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRateLimiterDisabled(t *testing.T) {
+	l := newWriteRateLimiter(0, 0)
+	require.Nil(t, l)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, l.acquire(1, 1024))
+	}
+}
+
+func TestWriteRateLimiter(t *testing.T) {
+	now := time.Now()
+
+	l := newWriteRateLimiter(2, 100)
+	l.now = func() time.Time { return now }
+	l.lastRefill = now
+
+	t.Run("normal traffic should pass", func(t *testing.T) {
+		require.NoError(t, l.acquire(1, 10))
+		require.NoError(t, l.acquire(1, 10))
+	})
+
+	t.Run("bursts above the ops limit should be throttled", func(t *testing.T) {
+		err := l.acquire(1, 10)
+		require.ErrorIs(t, err, ErrRateLimited)
+
+		var rateLimitedErr *RateLimitedError
+		require.True(t, errors.As(err, &rateLimitedErr))
+		require.Equal(t, 500*time.Millisecond, rateLimitedErr.RetryAfter)
+	})
+
+	t.Run("traffic should pass once retry-after elapsed", func(t *testing.T) {
+		now = now.Add(500 * time.Millisecond)
+		require.NoError(t, l.acquire(1, 10))
+	})
+
+	t.Run("bursts above the bytes limit should be throttled", func(t *testing.T) {
+		now = now.Add(time.Second)
+
+		require.NoError(t, l.acquire(1, 90))
+
+		err := l.acquire(1, 20)
+		require.ErrorIs(t, err, ErrRateLimited)
+
+		var rateLimitedErr *RateLimitedError
+		require.True(t, errors.As(err, &rateLimitedErr))
+		require.Equal(t, 100*time.Millisecond, rateLimitedErr.RetryAfter)
+	})
+
+	t.Run("writes larger than the burst should pass once the bucket is full", func(t *testing.T) {
+		now = now.Add(time.Second)
+
+		require.NoError(t, l.acquire(1, 1000))
+
+		err := l.acquire(1, 1)
+		require.ErrorIs(t, err, ErrRateLimited)
+	})
+}
+
+func TestWriteRateLimitedDatabase(t *testing.T) {
+	opts := DefaultOption().
+		WithDBRootPath(t.TempDir()).
+		WithMaxWriteOpsPerSecond(2)
+
+	db := makeDbWith(t, "db", opts)
+
+	now := time.Now()
+	db.writeLimiter.now = func() time.Time { return now }
+	db.writeLimiter.lastRefill = now
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte("ref1"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key2"), Value: []byte("value2")}}})
+	require.ErrorIs(t, err, ErrRateLimited)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte("ref2"), ReferencedKey: []byte("key1")})
+	require.ErrorIs(t, err, ErrRateLimited)
+
+	now = now.Add(time.Second)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key2"), Value: []byte("value2")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte("ref2"), ReferencedKey: []byte("key2")})
+	require.NoError(t, err)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// TxEntryKind is the kind of operation an entry of a transaction was written by
+type TxEntryKind string
+
+const (
+	TxEntryKindSet       TxEntryKind = "set"
+	TxEntryKindReference TxEntryKind = "reference"
+	TxEntryKindDelete    TxEntryKind = "delete"
+	TxEntryKindZAdd      TxEntryKind = "zadd"
+	TxEntryKindSQL       TxEntryKind = "sql"
+	TxEntryKindDocument  TxEntryKind = "document"
+)
+
+// TxSummary describes a committed transaction and the entries it touched
+type TxSummary struct {
+	Header  *schema.TxHeader
+	Entries []*TxEntrySummary
+}
+
+// TxEntrySummary describes an entry of a transaction
+type TxEntrySummary struct {
+	// Key is the key of key-value entries and the set of sorted set entries,
+	// the raw key without the prefix of the engine otherwise
+	Key  []byte
+	Kind TxEntryKind
+}
+
+// RecentTxs returns the summaries of the last n committed transactions, newest first.
+// Only the requested transactions are read, n is capped by the maximum result size.
+func (d *db) RecentTxs(ctx context.Context, n int) ([]*TxSummary, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: the number of transactions must be positive", ErrIllegalArguments)
+	}
+
+	if n > d.maxResultSize {
+		return nil, fmt.Errorf("%w: the specified number of transactions (%d) is larger than the maximum allowed one (%d)",
+			ErrResultSizeLimitExceeded, n, d.maxResultSize)
+	}
+
+	tx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(tx)
+
+	lastTxID, _ := d.st.CommittedAlh()
+
+	var summaries []*TxSummary
+
+	for txID := lastTxID; txID > 0 && len(summaries) < n; txID-- {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.st.ReadTx(txID, false, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		summary, err := d.summarizeTx(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+func (d *db) summarizeTx(tx *store.Tx) (*TxSummary, error) {
+	summary := &TxSummary{
+		Header: schema.TxHeaderToProto(tx.Header()),
+	}
+
+	for _, e := range tx.Entries() {
+		if len(e.Key()) == 0 {
+			continue
+		}
+
+		var entry *TxEntrySummary
+
+		switch e.Key()[0] {
+		case SetKeyPrefix:
+			kind, err := d.kvEntryKind(e)
+			if err != nil {
+				return nil, err
+			}
+
+			entry = &TxEntrySummary{Key: TrimPrefix(e.Key()), Kind: kind}
+		case SortedSetKeyPrefix:
+			// zKey = [1+setLenLen+set+scoreLen+keyLenLen+1+key+txIDLen]
+			zKey := e.Key()
+
+			setLen := int(binary.BigEndian.Uint64(zKey[1:]))
+
+			entry = &TxEntrySummary{Key: zKey[1+setLenLen : 1+setLenLen+setLen], Kind: TxEntryKindZAdd}
+		case SQLPrefix:
+			entry = &TxEntrySummary{Key: TrimPrefix(e.Key()), Kind: TxEntryKindSQL}
+		case DocumentPrefix:
+			entry = &TxEntrySummary{Key: TrimPrefix(e.Key()), Kind: TxEntryKindDocument}
+		default:
+			// internal entries are not reported
+			continue
+		}
+
+		summary.Entries = append(summary.Entries, entry)
+	}
+
+	return summary, nil
+}
+
+func (d *db) kvEntryKind(e *store.TxEntry) (TxEntryKind, error) {
+	if e.Metadata() != nil && e.Metadata().Deleted() {
+		return TxEntryKindDelete, nil
+	}
+
+	v, err := d.st.ReadValue(e)
+	if errors.Is(err, store.ErrExpiredEntry) {
+		return TxEntryKindSet, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if isReferenceValue(v) {
+		return TxEntryKindReference, nil
+	}
+
+	return TxEntryKindSet, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentTxs(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	setHdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("key1"), Value: []byte("value1")},
+		{Key: []byte("key2"), Value: []byte("value2")},
+	}})
+	require.NoError(t, err)
+
+	refHdr, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref1"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	zaddHdr, err := db.ZAdd(ctx, &schema.ZAddRequest{Set: []byte("set1"), Score: 1, Key: []byte("key2")})
+	require.NoError(t, err)
+
+	delHdr, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("key2")}})
+	require.NoError(t, err)
+
+	t.Run("invalid number of transactions should fail", func(t *testing.T) {
+		_, err := db.RecentTxs(ctx, 0)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.RecentTxs(ctx, db.MaxResultSize()+1)
+		require.ErrorIs(t, err, ErrResultSizeLimitExceeded)
+	})
+
+	t.Run("recent transactions should be returned newest first", func(t *testing.T) {
+		txs, err := db.RecentTxs(ctx, 4)
+		require.NoError(t, err)
+		require.Len(t, txs, 4)
+
+		require.Equal(t, delHdr.Id, txs[0].Header.Id)
+		require.Equal(t, []*TxEntrySummary{{Key: []byte("key2"), Kind: TxEntryKindDelete}}, txs[0].Entries)
+
+		require.Equal(t, zaddHdr.Id, txs[1].Header.Id)
+		require.Equal(t, []*TxEntrySummary{{Key: []byte("set1"), Kind: TxEntryKindZAdd}}, txs[1].Entries)
+
+		require.Equal(t, refHdr.Id, txs[2].Header.Id)
+		require.Equal(t, []*TxEntrySummary{{Key: []byte("ref1"), Kind: TxEntryKindReference}}, txs[2].Entries)
+
+		require.Equal(t, setHdr.Id, txs[3].Header.Id)
+		require.Equal(t, []*TxEntrySummary{
+			{Key: []byte("key1"), Kind: TxEntryKindSet},
+			{Key: []byte("key2"), Kind: TxEntryKindSet},
+		}, txs[3].Entries)
+	})
+
+	t.Run("feed should stop at the first transaction", func(t *testing.T) {
+		txs, err := db.RecentTxs(ctx, 100)
+		require.NoError(t, err)
+		require.Len(t, txs, int(delHdr.Id))
+		require.EqualValues(t, 1, txs[len(txs)-1].Header.Id)
+	})
+}
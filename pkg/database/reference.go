@@ -17,26 +17,104 @@ limitations under the License.
 package database
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/api/schema"
 )
 
+// MaxReferenceLabelLen is the maximum length of the label of a reference
+const MaxReferenceLabelLen = 256
+
+// MaxReferenceCreatedByLen is the maximum length of the creator identity of a reference
+const MaxReferenceCreatedByLen = 256
+
 var ErrReferencedKeyCannotBeAReference = errors.New("referenced key cannot be a reference")
 var ErrFinalKeyCannotBeConvertedIntoReference = errors.New("final key cannot be converted into a reference")
+var ErrReservedKeyReference = fmt.Errorf("%w: referenced key has a reserved prefix", store.ErrIllegalArguments)
 var ErrNoWaitOperationMustBeSelfContained = fmt.Errorf("no wait operation must be self-contained: %w", store.ErrIllegalArguments)
+var ErrStaleReference = errors.New("stale reference")
+var ErrReferencedValueMismatch = errors.New("referenced value does not match the expected hash")
+
+// StaleReferenceError is returned when a bound reference is read but its referenced key has a newer version,
+// LatestTx is the transaction of the latest version of the referenced key
+type StaleReferenceError struct {
+	Key      []byte
+	AtTx     uint64
+	LatestTx uint64
+}
+
+func (e *StaleReferenceError) Error() string {
+	return fmt.Sprintf("%s: reference '%s' is bound to tx %d but the referenced key was updated at tx %d",
+		ErrStaleReference.Error(), e.Key, e.AtTx, e.LatestTx)
+}
+
+func (e *StaleReferenceError) Is(target error) bool {
+	return target == ErrStaleReference
+}
 
 // Reference ...
 func (d *db) SetReference(ctx context.Context, req *schema.ReferenceRequest) (*schema.TxHeader, error) {
-	if req == nil || len(req.Key) == 0 || len(req.ReferencedKey) == 0 {
-		return nil, store.ErrIllegalArguments
+	_, err := d.ackLevelFor(req.GetAckLevel())
+	if err != nil {
+		return nil, err
 	}
 
-	if (req.AtTx == 0 && req.BoundRef) || (req.AtTx > 0 && !req.BoundRef) {
-		return nil, store.ErrIllegalArguments
+	hdr, err := d.setReference(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.waitForReplicationAcks(ctx, req.AckLevel, hdr.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+// SetReferenceAndResolve sets the reference as SetReference does and returns the entry it resolves to as of the
+// transaction it was set by, saving a subsequent read. As the reference is set anyway, no entry is returned when
+// the reference does not resolve (e.g. it is not yet effective) or it could not be read.
+func (d *db) SetReferenceAndResolve(ctx context.Context, req *schema.ReferenceRequest) (*schema.SetReferenceResponse, error) {
+	hdr, err := d.SetReference(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &schema.SetReferenceResponse{Header: hdr}
+
+	res.ResolvedValue, err = d.GetAt(ctx, req.Key, hdr.Id)
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		d.Logger.Warningf("could not resolve reference '%s' set at tx %d: %v", req.Key, hdr.Id, err)
+	}
+
+	return res, nil
+}
+
+// setReference sets the reference of the request, if target is not nil the referenced key is set to it
+// within the same transaction
+func (d *db) setReference(ctx context.Context, req *schema.ReferenceRequest, target *schema.KeyValue) (*schema.TxHeader, error) {
+	err := d.validateReferenceRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	size := len(req.Key) + len(req.ReferencedKey) + len(req.DefaultTargetValue)
+
+	if target != nil {
+		err = validateReferenceTarget(req, target)
+		if err != nil {
+			return nil, err
+		}
+
+		size += len(target.Value)
 	}
 
 	d.mutex.Lock()
@@ -46,57 +124,152 @@ func (d *db) SetReference(ctx context.Context, req *schema.ReferenceRequest) (*s
 		return nil, ErrIsReplica
 	}
 
+	err = d.writeLimiter.acquire(1, size)
+	if err != nil {
+		return nil, err
+	}
+
 	lastTxID, _ := d.st.CommittedAlh()
-	err := d.st.WaitForIndexingUpto(ctx, lastTxID)
+	err = d.st.WaitForIndexingUpto(ctx, lastTxID)
 	if err != nil {
 		return nil, err
 	}
 
-	// check key does not exists or it's already a reference
-	entry, err := d.getAtTx(ctx, EncodeKey(req.Key), req.AtTx, 0, d.st, 0, true)
-	if err != nil && err != store.ErrKeyNotFound {
+	var check *referenceCheck
+
+	if target == nil {
+		check, err = d.checkReference(ctx, req)
+	} else {
+		// the referenced key is overwritten, only the reference key needs to be checked
+		err = d.checkReferenceKey(ctx, req)
+		check = &referenceCheck{target: target}
+	}
+	if err != nil {
 		return nil, err
 	}
-	if entry != nil && entry.ReferencedBy == nil {
-		return nil, ErrFinalKeyCannotBeConvertedIntoReference
+
+	tx, err := d.st.NewWriteOnlyTx(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Cancel()
 
-	// check referenced key exists and it's not a reference
-	refEntry, err := d.getAtTx(ctx, EncodeKey(req.ReferencedKey), req.AtTx, 0, d.st, 0, true)
+	err = addReference(tx, req, check)
 	if err != nil {
 		return nil, err
 	}
-	if refEntry.ReferencedBy != nil {
-		return nil, ErrReferencedKeyCannotBeAReference
+
+	var hdr *store.TxHeader
+
+	if req.NoWait {
+		hdr, err = tx.AsyncCommit(ctx)
+	} else {
+		hdr, err = tx.Commit(ctx)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	tx, err := d.st.NewWriteOnlyTx(ctx)
+	return schema.TxHeaderToProto(hdr), err
+}
+
+// SetReferences atomically sets all the references of the request within a single transaction. Every reference
+// is validated as SetReference does before committing, thus no reference is set if any of them is not valid.
+// The noWait setting of the request applies to the whole batch, preconditions of each reference are all checked.
+func (d *db) SetReferences(ctx context.Context, req *schema.SetReferencesRequest) (*schema.TxHeader, error) {
+	hdr, err := d.setReferences(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Cancel()
 
-	e := EncodeReference(
-		req.Key,
-		nil,
-		req.ReferencedKey,
-		req.AtTx,
-	)
+	err = d.waitForReplicationAcks(ctx, schema.AckLevel_ACK_DEFAULT, hdr.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+func (d *db) setReferences(ctx context.Context, req *schema.SetReferencesRequest) (*schema.TxHeader, error) {
+	if req == nil || len(req.References) == 0 {
+		return nil, store.ErrIllegalArguments
+	}
+
+	var size int
+
+	keys := make(map[string]struct{}, len(req.References))
+
+	for _, ref := range req.References {
+		err := d.validateReferenceRequest(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		_, ok := keys[string(ref.Key)]
+		if ok {
+			return nil, schema.ErrDuplicatedKeysNotSupported
+		}
+		keys[string(ref.Key)] = struct{}{}
+
+		size += len(ref.Key) + len(ref.ReferencedKey) + len(ref.DefaultTargetValue)
+	}
+
+	for _, ref := range req.References {
+		// references set within the same batch can not be referenced
+		_, ok := keys[string(ref.ReferencedKey)]
+		if ok {
+			return nil, ErrReferencedKeyCannotBeAReference
+		}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
 
-	err = tx.Set(e.Key, e.Metadata, e.Value)
+	err := d.writeLimiter.acquire(1, size)
 	if err != nil {
 		return nil, err
 	}
 
-	for i := range req.Preconditions {
-		c, err := PreconditionFromProto(req.Preconditions[i])
+	lastTxID, _ := d.st.CommittedAlh()
+	err = d.st.WaitForIndexingUpto(ctx, lastTxID)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]*referenceCheck, len(req.References))
+	targets := make(map[string]struct{})
+
+	for i, ref := range req.References {
+		checks[i], err = d.checkReference(ctx, ref)
 		if err != nil {
 			return nil, err
 		}
 
-		err = tx.AddPrecondition(c)
+		if !checks[i].createTarget {
+			continue
+		}
+
+		_, ok := targets[string(ref.ReferencedKey)]
+		if ok {
+			return nil, schema.ErrDuplicatedKeysNotSupported
+		}
+		targets[string(ref.ReferencedKey)] = struct{}{}
+	}
+
+	tx, err := d.st.NewWriteOnlyTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Cancel()
+
+	for i, ref := range req.References {
+		err = addReference(tx, ref, checks[i])
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
+			return nil, err
 		}
 	}
 
@@ -111,55 +284,1244 @@ func (d *db) SetReference(ctx context.Context, req *schema.ReferenceRequest) (*s
 		return nil, err
 	}
 
-	return schema.TxHeaderToProto(hdr), err
+	return schema.TxHeaderToProto(hdr), nil
 }
 
-// SafeReference ...
-func (d *db) VerifiableSetReference(ctx context.Context, req *schema.VerifiableReferenceRequest) (*schema.VerifiableTx, error) {
-	if req == nil {
-		return nil, store.ErrIllegalArguments
+// validateReferenceRequest checks the arguments of the reference request
+func (d *db) validateReferenceRequest(req *schema.ReferenceRequest) error {
+	if req == nil || len(req.Key) == 0 || len(req.ReferencedKey) == 0 {
+		return store.ErrIllegalArguments
 	}
 
-	lastTxID, _ := d.st.CommittedAlh()
-	if lastTxID < req.ProveSinceTx {
-		return nil, store.ErrIllegalArguments
+	if (req.AtTx == 0 && req.BoundRef) || (req.AtTx > 0 && !req.BoundRef) {
+		return store.ErrIllegalArguments
 	}
 
-	// Preallocate tx buffers
-	lastTx, err := d.allocTx()
+	if req.CreateTargetIfMissing && req.BoundRef {
+		return fmt.Errorf("%w: target creation is not supported for bound references", store.ErrIllegalArguments)
+	}
+
+	if len(req.ExpectedValueHash) > 0 && len(req.ExpectedValueHash) != sha256.Size {
+		return fmt.Errorf("%w: expected value hash must be %d bytes long", store.ErrIllegalArguments, sha256.Size)
+	}
+
+	if d.isReservedKey(req.ReferencedKey) {
+		return ErrReservedKeyReference
+	}
+
+	if d.keyObfuscationEnabled() {
+		return ErrKeyObfuscationUnsupported
+	}
+
+	err := d.validateReferenceGroup(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer d.releaseTx(lastTx)
 
-	txMetatadata, err := d.SetReference(ctx, req.ReferenceRequest)
+	err = validateReferenceLabel(req.Label)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	err = d.st.ReadTx(uint64(txMetatadata.Id), false, lastTx)
+	err = validateReferenceCreatedBy(req.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	return d.validateReferenceTransform(req.Transform)
+}
+
+// isReservedKey returns true if the key starts with one of the reserved prefixes of the database
+func (d *db) isReservedKey(key []byte) bool {
+	for _, prefix := range d.options.reservedKeyPrefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateReferenceLabel(label string) error {
+	if len(label) > MaxReferenceLabelLen {
+		return fmt.Errorf("%w: reference label exceeds the maximum length (%d)", ErrIllegalArguments, MaxReferenceLabelLen)
+	}
+	return nil
+}
+
+func validateReferenceCreatedBy(createdBy string) error {
+	if len(createdBy) > MaxReferenceCreatedByLen {
+		return fmt.Errorf("%w: reference creator exceeds the maximum length (%d)", ErrIllegalArguments, MaxReferenceCreatedByLen)
+	}
+	return nil
+}
+
+// referenceCheck holds what the reference is written with after checking the current state of the keys
+type referenceCheck struct {
+	// target is set when the referenced key is set to it along with the reference
+	target *schema.KeyValue
+	// createTarget is set when the referenced key must be created along with the reference
+	createTarget bool
+	// valueTx is the transaction of the referenced value the expected hash was checked against, the referenced
+	// key must not be modified after it for the check to hold at commit time. Zero when there is no such value.
+	valueTx uint64
+}
+
+// checkReference checks the reference can be set given the current state of the keys
+func (d *db) checkReference(ctx context.Context, req *schema.ReferenceRequest) (*referenceCheck, error) {
+	err := d.checkReferenceKey(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	var prevTxHdr *store.TxHeader
+	check := &referenceCheck{}
 
-	if req.ProveSinceTx == 0 {
-		prevTxHdr = lastTx.Header()
-	} else {
-		prevTxHdr, err = d.st.ReadTxHeader(req.ProveSinceTx, false, false)
+	// check referenced key exists and it's not a reference
+	refEntry, err := d.getAtTx(ctx, EncodeKey(req.ReferencedKey), req.AtTx, 0, d.st, 0, true)
+	check.createTarget = errors.Is(err, store.ErrKeyNotFound) && req.CreateTargetIfMissing
+	if err != nil && !check.createTarget {
+		return nil, err
+	}
+	if refEntry != nil && refEntry.ReferencedBy != nil {
+		return nil, ErrReferencedKeyCannotBeAReference
+	}
+
+	if len(req.ExpectedValueHash) > 0 {
+		value := req.DefaultTargetValue
+		if !check.createTarget {
+			value = refEntry.Value
+		}
+
+		valueHash := sha256.Sum256(value)
+		if !bytes.Equal(valueHash[:], req.ExpectedValueHash) {
+			return nil, ErrReferencedValueMismatch
+		}
+
+		if !check.createTarget && req.AtTx == 0 {
+			// the value at a given transaction can not change but the latest one can
+			check.valueTx = refEntry.Tx
+		}
+	}
+
+	return check, nil
+}
+
+// checkReferenceKey checks the reference key does not exist or it's already a reference
+func (d *db) checkReferenceKey(ctx context.Context, req *schema.ReferenceRequest) error {
+	entry, err := d.getAtTx(ctx, EncodeKey(req.Key), req.AtTx, 0, d.st, 0, true)
+	if err != nil && err != store.ErrKeyNotFound {
+		return err
+	}
+	if entry != nil && entry.ReferencedBy == nil {
+		return ErrFinalKeyCannotBeConvertedIntoReference
+	}
+	return nil
+}
+
+// validateReferenceTarget checks the reference can be set along with the entry it references
+func validateReferenceTarget(req *schema.ReferenceRequest, target *schema.KeyValue) error {
+	if !bytes.Equal(req.ReferencedKey, target.Key) || bytes.Equal(req.Key, target.Key) {
+		return store.ErrIllegalArguments
+	}
+
+	if req.BoundRef || req.CreateTargetIfMissing || len(req.ExpectedValueHash) > 0 {
+		return fmt.Errorf(
+			"%w: bound references, target creation and expected value hashes are not supported when setting the referenced key",
+			store.ErrIllegalArguments)
+	}
+
+	return nil
+}
+
+// addReference adds the reference entry, the referenced key when it has to be created and
+// the preconditions of the reference to the transaction
+func addReference(tx *store.OngoingTx, req *schema.ReferenceRequest, check *referenceCheck) error {
+	if check.valueTx > 0 {
+		err := tx.AddPrecondition(&store.PreconditionKeyNotModifiedAfterTx{
+			Key:  EncodeKey(req.ReferencedKey),
+			TxID: check.valueTx,
+		})
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
 		}
 	}
 
-	dualProof, err := d.st.DualProof(prevTxHdr, lastTx.Header())
+	if check.target != nil {
+		target := EncodeEntrySpec(check.target.Key, schema.KVMetadataFromProto(check.target.Metadata), check.target.Value)
+
+		err := tx.Set(target.Key, target.Metadata, target.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if check.createTarget {
+		target := EncodeEntrySpec(req.ReferencedKey, nil, req.DefaultTargetValue)
+
+		err := tx.Set(target.Key, target.Metadata, target.Value)
+		if err != nil {
+			return err
+		}
+
+		// the target is not overwritten if it's concurrently created
+		err = tx.AddPrecondition(&store.PreconditionKeyMustNotExist{Key: target.Key})
+		if err != nil {
+			return fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
+		}
+	}
+
+	e := EncodeReferenceWithAttributes(
+		req.Key,
+		referenceMetadataFromRequest(req),
+		req.ReferencedKey,
+		req.AtTx,
+		referenceAttributesFromRequest(req),
+	)
+
+	err := tx.Set(e.Key, e.Metadata, e.Value)
+	if err != nil {
+		return err
+	}
+
+	err = addReferenceGroupMember(tx, req)
+	if err != nil {
+		return err
+	}
+
+	for i := range req.Preconditions {
+		c, err := PreconditionFromProto(req.Preconditions[i])
+		if err != nil {
+			return err
+		}
+
+		err = tx.AddPrecondition(c)
+		if err != nil {
+			return fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
+		}
+	}
+
+	return nil
+}
+
+// GetReference returns the reference stored under the key at the version selected by the request, without
+// resolving it. ErrKeyNotFound is returned if the key is not a reference.
+func (d *db) GetReference(ctx context.Context, req *schema.KeyRequest) (*schema.Reference, error) {
+	err := checkKeyRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
-	return &schema.VerifiableTx{
-		Tx:        schema.TxToProto(lastTx),
-		DualProof: schema.DualProofToProto(dualProof),
-	}, nil
+	err = d.waitForKeyRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := EncodeKey(d.storedKey(req.Key))
+
+	txID, revision, md, val, err := d.readKeyVersion(ctx, key, req, store.IgnoreExpired, store.IgnoreDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	if md != nil && md.Deleted() {
+		return nil, store.ErrKeyNotFound
+	}
+
+	if !isReferenceValue(val) {
+		return nil, fmt.Errorf("%w: key '%s' is not a reference", store.ErrKeyNotFound, req.Key)
+	}
+
+	refKey, atTx, attrs, err := unwrapReferenceValue(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return referenceDescriptor(key, txID, md, refKey, atTx, revision, attrs), nil
+}
+
+// ReferenceHistory returns the versions of a reference key as in History, each of them decoded as a reference
+// so what the key pointed at over time is described. Deleted and expired versions are returned without the
+// referenced key, the key is not found if any other version is not a reference.
+func (d *db) ReferenceHistory(ctx context.Context, req *schema.HistoryRequest) ([]*schema.Reference, error) {
+	if req == nil || len(req.Key) == 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	if int(req.Limit) > d.maxResultSize {
+		return nil, fmt.Errorf("%w: the specified limit (%d) is larger than the maximum allowed one (%d)",
+			ErrResultSizeLimitExceeded, req.Limit, d.maxResultSize)
+	}
+
+	currTxID, _ := d.st.CommittedAlh()
+
+	if req.SinceTx > currTxID {
+		return nil, ErrIllegalArguments
+	}
+
+	waitUntilTx := req.SinceTx
+	if waitUntilTx == 0 {
+		waitUntilTx = currTxID
+	}
+
+	err := d.WaitForIndexingUpto(ctx, waitUntilTx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = d.maxResultSize
+	}
+
+	key := EncodeKey(d.storedKey(req.Key))
+
+	valRefs, _, err := d.st.History(key, req.Offset, req.Desc, limit)
+	if err != nil && err != store.ErrOffsetOutOfRange {
+		return nil, err
+	}
+
+	refs := make([]*schema.Reference, len(valRefs))
+
+	for i, valRef := range valRefs {
+		md := valRef.KVMetadata()
+
+		val, err := valRef.Resolve()
+		if errors.Is(err, store.ErrExpiredEntry) || (md != nil && md.Deleted()) {
+			refs[i] = &schema.Reference{
+				Tx:       valRef.Tx(),
+				Key:      req.Key,
+				Metadata: schema.KVMetadataToProto(md),
+				Revision: valRef.HC(),
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !isReferenceValue(val) {
+			return nil, fmt.Errorf("%w: key '%s' is not a reference at transaction %d", store.ErrKeyNotFound, req.Key, valRef.Tx())
+		}
+
+		refKey, atTx, attrs, err := unwrapReferenceValue(val)
+		if err != nil {
+			return nil, err
+		}
+
+		refs[i] = referenceDescriptor(key, valRef.Tx(), md, refKey, atTx, valRef.HC(), attrs)
+		refs[i].Key = req.Key
+	}
+
+	return refs, nil
+}
+
+// referenceDescriptor describes the reference stored under the key at the given transaction
+func referenceDescriptor(key []byte, txID uint64, md *store.KVMetadata, refKey []byte, atTx, revision uint64, attrs *ReferenceAttributes) *schema.Reference {
+	return &schema.Reference{
+		Tx:            txID,
+		Key:           TrimPrefix(key),
+		ReferencedKey: TrimPrefix(refKey),
+		Metadata:      schema.KVMetadataToProto(md),
+		AtTx:          atTx,
+		Revision:      revision,
+		Transform:     attrs.Transform,
+		CreatedBy:     attrs.CreatedBy,
+		CreatedAt:     attrs.CreatedAt,
+		EffectiveFrom: attrs.EffectiveFrom,
+		Group:         attrs.Group,
+		Priority:      attrs.Priority,
+		Label:         attrs.Label,
+		BoundRef:      atTx > 0,
+	}
+}
+
+// referenceMetadataFromRequest returns the metadata of the reference entry, nil when the reference does not expire
+func referenceMetadataFromRequest(req *schema.ReferenceRequest) *store.KVMetadata {
+	if req.ExpiresAt == 0 {
+		return nil
+	}
+
+	md := store.NewKVMetadata()
+	md.ExpiresAt(time.Unix(req.ExpiresAt, 0))
+
+	return md
+}
+
+// referenceAttributesFromRequest returns the attributes the reference is stored with, the creation time
+// defaults to the time the reference is written when the creator is specified
+func referenceAttributesFromRequest(req *schema.ReferenceRequest) *ReferenceAttributes {
+	attrs := &ReferenceAttributes{
+		Transform:     req.Transform,
+		CreatedBy:     req.CreatedBy,
+		CreatedAt:     req.CreatedAt,
+		EffectiveFrom: req.EffectiveFrom,
+		Group:         req.Group,
+		Priority:      req.Priority,
+		Label:         req.Label,
+	}
+
+	if attrs.CreatedBy != "" && attrs.CreatedAt == 0 {
+		attrs.CreatedAt = time.Now().Unix()
+	}
+
+	return attrs
+}
+
+// effectiveReference returns the latest version of the key whose reference, if any, is already effective.
+// Previous versions are resolved using the given index while the reference is not effective, the key is not
+// found when there is none.
+func (d *db) effectiveReference(ctx context.Context, key []byte, entry *schema.Entry, index store.KeyIndex) (*schema.Entry, error) {
+	now := d.options.storeOpts.TimeFunc().Unix()
+
+	for entry.ReferencedBy != nil && entry.ReferencedBy.EffectiveFrom > now {
+		if entry.ReferencedBy.Tx <= 1 {
+			return nil, store.ErrKeyNotFound
+		}
+
+		valRef, err := index.GetBetween(ctx, key, 1, entry.ReferencedBy.Tx-1)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err = d.getAtTx(ctx, key, valRef.Tx(), 0, index, valRef.HC(), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// effectiveValueRef returns the latest version of the key, as read from the given index, whose reference, if any,
+// is already effective, along with its value. It's the value reference counterpart of effectiveReference, for
+// reads describing references instead of resolving them. The key is not found when there is no such version.
+func (d *db) effectiveValueRef(ctx context.Context, key []byte, valRef store.ValueRef, index store.KeyIndex) (store.ValueRef, []byte, error) {
+	now := d.options.storeOpts.TimeFunc().Unix()
+
+	for {
+		val, err := valRef.Resolve()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !isReferenceValue(val) {
+			return valRef, val, nil
+		}
+
+		_, _, attrs, err := unwrapReferenceValue(val)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if attrs.EffectiveFrom <= now {
+			return valRef, val, nil
+		}
+
+		if valRef.Tx() <= 1 {
+			return nil, nil, store.ErrKeyNotFound
+		}
+
+		valRef, err = index.GetBetween(ctx, key, 1, valRef.Tx()-1)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// SafeReference ...
+func (d *db) VerifiableSetReference(ctx context.Context, req *schema.VerifiableReferenceRequest) (*schema.VerifiableTx, error) {
+	if req == nil {
+		return nil, store.ErrIllegalArguments
+	}
+
+	lastTxID, _ := d.st.CommittedAlh()
+	if lastTxID < req.ProveSinceTx {
+		return nil, store.ErrIllegalArguments
+	}
+
+	// Preallocate tx buffers
+	lastTx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(lastTx)
+
+	txMetatadata, err := d.SetReference(ctx, req.ReferenceRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.st.ReadTx(uint64(txMetatadata.Id), false, lastTx)
+	if err != nil {
+		return nil, err
+	}
+
+	var prevTxHdr *store.TxHeader
+
+	if req.ProveSinceTx == 0 {
+		prevTxHdr = lastTx.Header()
+	} else {
+		prevTxHdr, err = d.st.ReadTxHeader(req.ProveSinceTx, false, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dualProof, err := d.st.DualProof(prevTxHdr, lastTx.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema.VerifiableTx{
+		Tx:        schema.TxToProto(lastTx),
+		DualProof: schema.DualProofToProto(dualProof),
+	}, nil
+}
+
+// VerifiableSetReferences sets the references as SetReferences does and returns the transaction holding all of them,
+// along with the dual proof since ProveSinceTx. The inclusion of each reference entry can be proven against the
+// single transaction.
+func (d *db) VerifiableSetReferences(ctx context.Context, req *schema.VerifiableSetReferencesRequest) (*schema.VerifiableTx, error) {
+	if req == nil {
+		return nil, store.ErrIllegalArguments
+	}
+
+	lastTxID, _ := d.st.CommittedAlh()
+	if lastTxID < req.ProveSinceTx {
+		return nil, store.ErrIllegalArguments
+	}
+
+	// Preallocate tx buffers
+	lastTx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(lastTx)
+
+	hdr, err := d.SetReferences(ctx, req.SetReferencesRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.st.ReadTx(hdr.Id, false, lastTx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.verifiableTx(lastTx, req.ProveSinceTx)
+}
+
+// VerifiableSetWithReference sets the key-value and the reference of the request to it within the same transaction,
+// thus the reference is created along with its target and a single proof covers both entries. The referenced key
+// of the request must be the key of the key-value and the reference can not be bound.
+func (d *db) VerifiableSetWithReference(ctx context.Context, kv *schema.KeyValue, req *schema.ReferenceRequest, proveSinceTx uint64) (*schema.VerifiableTx, error) {
+	if kv == nil || len(kv.Key) == 0 {
+		return nil, store.ErrIllegalArguments
+	}
+
+	_, err := d.ackLevelFor(req.GetAckLevel())
+	if err != nil {
+		return nil, err
+	}
+
+	lastTxID, _ := d.st.CommittedAlh()
+	if lastTxID < proveSinceTx {
+		return nil, store.ErrIllegalArguments
+	}
+
+	// Preallocate tx buffers
+	lastTx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(lastTx)
+
+	hdr, err := d.setReference(ctx, req, kv)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.waitForReplicationAcks(ctx, req.AckLevel, hdr.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.st.ReadTx(hdr.Id, false, lastTx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.verifiableTx(lastTx, proveSinceTx)
+}
+
+type referenceRefresh struct {
+	key           []byte
+	md            *store.KVMetadata
+	referencedKey []byte
+	latestTx      uint64
+	attrs         *ReferenceAttributes
+	refTx         uint64
+}
+
+// RefreshReferences re-binds all the bound references under the given prefix to the latest
+// transaction of their referenced keys. References whose referenced key was deleted or expired
+// are left untouched. Rewrites are committed in transactions of at most MaxTxEntries entries,
+// the number of refreshed references and the committed transactions are returned.
+func (d *db) RefreshReferences(ctx context.Context, prefix []byte) (refreshed int, txIDs []uint64, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.isReplica() {
+		return 0, nil, ErrIsReplica
+	}
+
+	refreshes, err := d.staleBoundReferences(ctx, prefix)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	batchSize := d.st.MaxTxEntries()
+
+	for len(refreshes) > 0 {
+		n := batchSize
+		if n > len(refreshes) {
+			n = len(refreshes)
+		}
+
+		hdr, err := d.commitReferenceRefreshes(ctx, refreshes[:n])
+		if err != nil {
+			return refreshed, txIDs, err
+		}
+
+		refreshed += n
+		txIDs = append(txIDs, hdr.ID)
+
+		refreshes = refreshes[n:]
+	}
+
+	return refreshed, txIDs, nil
+}
+
+func (d *db) staleBoundReferences(ctx context.Context, prefix []byte) ([]*referenceRefresh, error) {
+	snap, err := d.snapshotSince(ctx, []byte{SetKeyPrefix}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Close()
+
+	r, err := snap.NewKeyReader(store.KeyReaderSpec{
+		Prefix:  EncodeKey(prefix),
+		Filters: []store.FilterFn{store.IgnoreExpired, store.IgnoreDeleted},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var refreshes []*referenceRefresh
+
+	for {
+		key, valRef, err := r.Read(ctx)
+		if errors.Is(err, store.ErrNoMoreEntries) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := valRef.Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isReferenceValue(val) {
+			continue
+		}
+
+		referencedKey, atTx, attrs, err := unwrapReferenceValue(val)
+		if err != nil {
+			return nil, err
+		}
+
+		if atTx == 0 {
+			// non-bound references always resolve to the latest value
+			continue
+		}
+
+		latest, err := snap.Get(ctx, referencedKey)
+		if errors.Is(err, store.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if latest.Tx() == atTx {
+			continue
+		}
+
+		refreshes = append(refreshes, &referenceRefresh{
+			key:           key,
+			md:            valRef.KVMetadata(),
+			referencedKey: referencedKey,
+			latestTx:      latest.Tx(),
+			attrs:         attrs,
+			refTx:         valRef.Tx(),
+		})
+	}
+
+	return refreshes, nil
+}
+
+func (d *db) commitReferenceRefreshes(ctx context.Context, refreshes []*referenceRefresh) (*store.TxHeader, error) {
+	tx, err := d.st.NewWriteOnlyTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Cancel()
+
+	for _, ref := range refreshes {
+		value := WrapReferenceValueWithAttributesAt(ref.referencedKey, ref.latestTx, ref.attrs)
+
+		err = tx.Set(ref.key, ref.md, value)
+		if err != nil {
+			return nil, err
+		}
+
+		// references updated concurrently are not overwritten
+		err = tx.AddPrecondition(&store.PreconditionKeyNotModifiedAfterTx{Key: ref.key, TxID: ref.refTx})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// BrokenReference describes a reference whose referenced key can no longer be resolved
+type BrokenReference struct {
+	// Key is the key of the reference
+	Key []byte
+	// ReferencedKey is the key the reference points to
+	ReferencedKey []byte
+	// AtTx is the transaction the reference is bound to, zero for non-bound references
+	AtTx uint64
+	// Tx is the transaction the reference was set at
+	Tx uint64
+	// RemovedAtTx is the transaction the reference was tombstoned at, zero when not removed
+	RemovedAtTx uint64
+}
+
+// RepairReferences looks for references whose referenced key was deleted, expired or is no longer
+// present at the transaction the reference is bound to. Unless dryRun is set, broken references are
+// tombstoned in transactions of at most MaxTxEntries entries, previous values remaining available in
+// the history of each reference. References updated concurrently are not removed, and as each
+// transaction is committed atomically an interrupted repair can simply be run again.
+func (d *db) RepairReferences(ctx context.Context, dryRun bool) ([]*BrokenReference, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !dryRun && d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	broken, err := d.brokenReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return broken, nil
+	}
+
+	batchSize := d.st.MaxTxEntries()
+
+	for i := 0; i < len(broken); i += batchSize {
+		n := batchSize
+		if i+n > len(broken) {
+			n = len(broken) - i
+		}
+
+		hdr, err := d.commitReferenceRemovals(ctx, broken[i:i+n])
+		if err != nil {
+			return broken[:i], err
+		}
+
+		for _, ref := range broken[i : i+n] {
+			ref.RemovedAtTx = hdr.ID
+		}
+	}
+
+	return broken, nil
+}
+
+func (d *db) brokenReferences(ctx context.Context) ([]*BrokenReference, error) {
+	snap, err := d.snapshotSince(ctx, []byte{SetKeyPrefix}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Close()
+
+	r, err := snap.NewKeyReader(store.KeyReaderSpec{
+		Prefix:  []byte{SetKeyPrefix},
+		Filters: []store.FilterFn{store.IgnoreExpired, store.IgnoreDeleted},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var broken []*BrokenReference
+
+	for {
+		key, valRef, err := r.Read(ctx)
+		if errors.Is(err, store.ErrNoMoreEntries) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := valRef.Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isReferenceValue(val) {
+			continue
+		}
+
+		referencedKey, atTx, _, err := unwrapReferenceValue(val)
+		if err != nil {
+			return nil, err
+		}
+
+		if atTx == 0 {
+			_, err = snap.Get(ctx, referencedKey)
+		} else {
+			var md *store.KVMetadata
+
+			md, _, err = d.readMetadataAndValue(referencedKey, atTx, false)
+			if err == nil && md != nil && md.Deleted() {
+				err = store.ErrKeyNotFound
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, store.ErrKeyNotFound) {
+			return nil, err
+		}
+
+		broken = append(broken, &BrokenReference{
+			Key:           TrimPrefix(key),
+			ReferencedKey: TrimPrefix(referencedKey),
+			AtTx:          atTx,
+			Tx:            valRef.Tx(),
+		})
+	}
+
+	return broken, nil
+}
+
+func (d *db) commitReferenceRemovals(ctx context.Context, refs []*BrokenReference) (*store.TxHeader, error) {
+	tx, err := d.st.NewWriteOnlyTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Cancel()
+
+	for _, ref := range refs {
+		key := EncodeKey(ref.Key)
+
+		md := store.NewKVMetadata()
+		md.AsDeleted(true)
+
+		err = tx.Set(key, md, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.AddPrecondition(&store.PreconditionKeyNotModifiedAfterTx{Key: key, TxID: ref.Tx})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", store.ErrInvalidPrecondition, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReferenceHop is a link in the resolution of a reference
+type ReferenceHop struct {
+	// Key is the key resolved at this hop
+	Key []byte
+	// Tx is the transaction the entry resolved at this hop was committed in
+	Tx uint64
+	// Timestamp is the timestamp of the transaction
+	Timestamp int64
+	// Reference describes the reference followed from this hop, nil for the final entry
+	Reference *schema.Reference
+	// Bound tells whether the next hop is pinned to Reference.AtTx or resolved to its latest value
+	Bound bool
+}
+
+// CanonicalKey returns the key holding the value the provided key resolves to, following references up to the
+// resolution limit. The key is returned unchanged when it's not a reference.
+func (d *db) CanonicalKey(ctx context.Context, key []byte) ([]byte, error) {
+	hops, err := d.ResolveReferenceProvenance(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return hops[len(hops)-1].Key, nil
+}
+
+// checkReferenceIsNotStale fails when the referenced key of a bound reference has a version newer than the bound one,
+// deletions and expirations are considered as newer versions
+func (d *db) checkReferenceIsNotStale(ctx context.Context, entry *schema.Entry) error {
+	if entry.ReferencedBy == nil || entry.ReferencedBy.AtTx == 0 {
+		return nil
+	}
+
+	latest, err := d.st.GetWithFilters(ctx, EncodeKey(entry.Key))
+	if err != nil {
+		return err
+	}
+
+	if latest.Tx() > entry.ReferencedBy.AtTx {
+		atomic.AddUint64(&d.referenceMetrics.staleHits, 1)
+
+		return &StaleReferenceError{
+			Key:      entry.ReferencedBy.Key,
+			AtTx:     entry.ReferencedBy.AtTx,
+			LatestTx: latest.Tx(),
+		}
+	}
+
+	return nil
+}
+
+// ResolveReferenceProvenance returns the hops followed to resolve the key, starting with
+// the key itself and ending with the entry holding the resolved value
+func (d *db) ResolveReferenceProvenance(ctx context.Context, key []byte) ([]*ReferenceHop, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: empty key", ErrIllegalArguments)
+	}
+
+	lastTxID, _ := d.st.CommittedAlh()
+	err := d.st.WaitForIndexingUpto(ctx, lastTxID)
+	if err != nil {
+		return nil, err
+	}
+
+	var hops []*ReferenceHop
+
+	encKey := EncodeKey(key)
+	var atTx uint64
+
+	for resolved := 0; ; resolved++ {
+		var txID, revision uint64
+		var md *store.KVMetadata
+		var val []byte
+
+		if atTx == 0 {
+			valRef, err := d.st.Get(ctx, encKey)
+			if err != nil {
+				return nil, err
+			}
+
+			// the latest version of a reference is only followed once effective
+			valRef, val, err = d.effectiveValueRef(ctx, encKey, valRef, d.st)
+			if err != nil {
+				return nil, err
+			}
+
+			txID = valRef.Tx()
+			revision = valRef.HC()
+			md = valRef.KVMetadata()
+		} else {
+			txID = atTx
+
+			md, val, err = d.readMetadataAndValue(encKey, atTx, false)
+			if err != nil {
+				return nil, err
+			}
+
+			if md != nil && md.Deleted() {
+				return nil, store.ErrKeyNotFound
+			}
+		}
+
+		hdr, err := d.st.ReadTxHeader(txID, false, false)
+		if err != nil {
+			return nil, err
+		}
+
+		hop := &ReferenceHop{
+			Key:       TrimPrefix(encKey),
+			Tx:        txID,
+			Timestamp: hdr.Ts,
+		}
+
+		hops = append(hops, hop)
+
+		if !isReferenceValue(val) {
+			return hops, nil
+		}
+
+		if resolved == MaxKeyResolutionLimit {
+			return nil, ErrKeyResolutionLimitReached
+		}
+
+		refKey, refAtTx, attrs, err := unwrapReferenceValue(val)
+		if err != nil {
+			return nil, err
+		}
+
+		hop.Reference = referenceDescriptor(encKey, txID, md, refKey, refAtTx, revision, attrs)
+		hop.Bound = refAtTx > 0
+
+		encKey = refKey
+		atTx = refAtTx
+	}
+}
+
+// ListReferencesRequest selects the references to be listed
+type ListReferencesRequest struct {
+	// Prefix filters references by their own key
+	Prefix []byte
+	// TargetPrefix filters references by the key they point to
+	TargetPrefix []byte
+	// SeekKey is the key of the last reference of the previous page, listing starts right after it
+	SeekKey []byte
+	// MaxAtTx restricts the listing to bound references whose AtTx is not greater than it, no restriction when not set
+	MaxAtTx uint64
+	// Limit is the maximum number of references to be returned, MaxResultSize is used when not set
+	Limit int
+}
+
+// ListedReference describes a reference and the key it points to
+type ListedReference struct {
+	// Key is the key of the reference
+	Key []byte
+	// ReferencedKey is the key the reference points to
+	ReferencedKey []byte
+	// AtTx is the transaction the reference is bound to, zero for non-bound references
+	AtTx uint64
+	// Tx is the transaction the reference was set at
+	Tx uint64
+	// Transform is the transform applied when resolving the reference
+	Transform string
+	// Label is the human-readable label the reference was set with
+	Label string
+	// Stale is set for bound references whose referenced key has a version newer than the bound one,
+	// deletions and expirations are considered as newer versions
+	Stale bool
+}
+
+// ListReferences returns the references in key order. References are matched against TargetPrefix by
+// the referenced key they were set with, as keys pointed to by a reference never change. Thus bound
+// references are listed even if their referenced key was deleted or updated after the bound transaction,
+// RepairReferences may be used to find the ones that can no longer be resolved.
+// Setting MaxAtTx lists the bound references older than the given transaction, i.e. candidates to be refreshed.
+func (d *db) ListReferences(ctx context.Context, req *ListReferencesRequest) ([]*ListedReference, error) {
+	if req == nil || req.Limit < 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	if req.Limit > d.maxResultSize {
+		return nil, fmt.Errorf("%w: the specified limit (%d) is larger than the maximum allowed one (%d)",
+			ErrResultSizeLimitExceeded, req.Limit, d.maxResultSize)
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = d.maxResultSize
+	}
+
+	snap, err := d.snapshotSince(ctx, []byte{SetKeyPrefix}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Close()
+
+	var seekKey []byte
+	if len(req.SeekKey) > 0 {
+		seekKey = EncodeKey(req.SeekKey)
+	}
+
+	r, err := snap.NewKeyReader(store.KeyReaderSpec{
+		SeekKey: seekKey,
+		Prefix:  EncodeKey(req.Prefix),
+		Filters: []store.FilterFn{store.IgnoreExpired, store.IgnoreDeleted},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	encTargetPrefix := EncodeKey(req.TargetPrefix)
+
+	var refs []*ListedReference
+
+	err = d.readReferences(ctx, r, snap, limit, func(key []byte, valRef store.ValueRef, referencedKey []byte, atTx uint64, attrs *ReferenceAttributes) (bool, error) {
+		if !bytes.HasPrefix(referencedKey, encTargetPrefix) {
+			return false, nil
+		}
+
+		if req.MaxAtTx > 0 && (atTx == 0 || atTx > req.MaxAtTx) {
+			return false, nil
+		}
+
+		stale := false
+
+		if atTx > 0 {
+			latest, err := snap.GetWithFilters(ctx, referencedKey)
+			if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+				return false, err
+			}
+
+			stale = err == nil && latest.Tx() > atTx
+		}
+
+		refs = append(refs, &ListedReference{
+			Key:           TrimPrefix(key),
+			ReferencedKey: TrimPrefix(referencedKey),
+			AtTx:          atTx,
+			Tx:            valRef.Tx(),
+			Transform:     attrs.Transform,
+			Label:         attrs.Label,
+			Stale:         stale,
+		})
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// readReferences reads the references of the key reader in its order, as they are resolved, i.e. once effective.
+// Keys which are not references are skipped, without reading their values when they are too short to be one.
+// f is called with each reference until it accepted limit of them.
+func (d *db) readReferences(
+	ctx context.Context,
+	r store.KeyReader,
+	snap *store.Snapshot,
+	limit int,
+	f func(key []byte, valRef store.ValueRef, referencedKey []byte, atTx uint64, attrs *ReferenceAttributes) (bool, error),
+) error {
+	for accepted := 0; accepted < limit; {
+		key, valRef, err := r.Read(ctx)
+		if errors.Is(err, store.ErrNoMoreEntries) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if valRef.Len() < minReferenceValueLen {
+			continue
+		}
+
+		valRef, val, err := d.effectiveValueRef(ctx, key, valRef, snap)
+		if errors.Is(err, store.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !isReferenceValue(val) {
+			continue
+		}
+
+		referencedKey, atTx, attrs, err := unwrapReferenceValue(val)
+		if err != nil {
+			return err
+		}
+
+		ok, err := f(key, valRef, referencedKey, atTx, attrs)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			accepted++
+		}
+	}
+
+	return nil
+}
+
+// ScanReferences scans keys as Scan does but returns only the ones holding references, each of them decoded
+// as a reference instead of being resolved, so the key it points to and the transaction it is bound to are
+// described. As in ListReferences, references are returned once effective. Limit applies to the returned
+// references, entries which are not references are skipped. Offset, key patterns, grouping and the
+// inclusion of deleted or expired entries are not supported.
+func (d *db) ScanReferences(ctx context.Context, req *schema.ScanRequest) ([]*schema.Reference, error) {
+	if req == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	if req.Offset > 0 || req.KeyPattern != "" || req.GroupByTx || req.GroupByPrefixDepth > 0 || req.IncludeDeleted || req.IncludeExpired {
+		return nil, fmt.Errorf("%w: only prefix, seek and end keys, order, limit and sinceTx are supported when scanning references", ErrIllegalArguments)
+	}
+
+	if req.Limit > uint64(d.maxResultSize) {
+		return nil, fmt.Errorf("%w: the specified limit (%d) is larger than the maximum allowed one (%d)",
+			ErrResultSizeLimitExceeded, req.Limit, d.maxResultSize)
+	}
+
+	if d.keyObfuscationEnabled() {
+		return nil, ErrKeyObfuscationUnsupported
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = d.maxResultSize
+	}
+
+	var seekKey []byte
+	if len(req.SeekKey) > 0 {
+		seekKey = EncodeKey(req.SeekKey)
+	}
+
+	var endKey []byte
+	if len(req.EndKey) > 0 {
+		endKey = EncodeKey(req.EndKey)
+	}
+
+	snap, err := d.snapshotSince(ctx, []byte{SetKeyPrefix}, req.SinceTx)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Close()
+
+	r, err := snap.NewKeyReader(store.KeyReaderSpec{
+		SeekKey:       seekKey,
+		EndKey:        endKey,
+		Prefix:        EncodeKey(req.Prefix),
+		DescOrder:     req.Desc,
+		Filters:       []store.FilterFn{store.IgnoreExpired, store.IgnoreDeleted},
+		InclusiveSeek: req.InclusiveSeek,
+		InclusiveEnd:  req.InclusiveEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var refs []*schema.Reference
+
+	err = d.readReferences(ctx, r, snap, limit, func(key []byte, valRef store.ValueRef, referencedKey []byte, atTx uint64, attrs *ReferenceAttributes) (bool, error) {
+		refs = append(refs, referenceDescriptor(key, valRef.Tx(), valRef.KVMetadata(), referencedKey, atTx, valRef.HC(), attrs))
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
 }
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// References set with a group are listed under the group by a membership entry written within the same
+// transaction as the reference. The group and the priority are stored as attributes of the reference,
+// membership entries are only used to find the references of the group and are never resolved on their own.
+//
+// ResolveGroup resolves the live reference with the highest priority. A member is live when its key is
+// still a reference of the group and the referenced key is found. Ties are broken in favour of the member
+// with the lowest reference key, thus resolution is deterministic regardless of the order members were added.
+
+var ErrNoLiveGroupReference = fmt.Errorf("%w: no live reference in the group", store.ErrKeyNotFound)
+
+const refGroupLenSize = 2
+
+func encodeReferenceGroupPrefix(group []byte) []byte {
+	b := make([]byte, 1+refGroupLenSize+len(group))
+	b[0] = ReferenceGroupPrefix
+	binary.BigEndian.PutUint16(b[1:], uint16(len(group)))
+	copy(b[1+refGroupLenSize:], group)
+	return b
+}
+
+func encodeReferenceGroupMember(group, key []byte) []byte {
+	return append(encodeReferenceGroupPrefix(group), key...)
+}
+
+func (d *db) validateReferenceGroup(req *schema.ReferenceRequest) error {
+	if len(req.Group) == 0 {
+		if req.Priority != 0 {
+			return fmt.Errorf("%w: priority can only be set for references in a group", store.ErrIllegalArguments)
+		}
+		return nil
+	}
+
+	if len(encodeReferenceGroupMember(req.Group, req.Key)) > d.st.MaxKeyLen() {
+		return fmt.Errorf("%w: reference group name is too long", store.ErrIllegalArguments)
+	}
+
+	return nil
+}
+
+// addReferenceGroupMember adds the membership entry of the reference to the transaction
+func addReferenceGroupMember(tx *store.OngoingTx, req *schema.ReferenceRequest) error {
+	if len(req.Group) == 0 {
+		return nil
+	}
+
+	var priority [4]byte
+	binary.BigEndian.PutUint32(priority[:], req.Priority)
+
+	return tx.Set(encodeReferenceGroupMember(req.Group, req.Key), nil, priority[:])
+}
+
+// ResolveGroup returns the entry resolved by the live reference of the group with the highest priority
+func (d *db) ResolveGroup(ctx context.Context, group []byte) (*schema.Entry, error) {
+	if len(group) == 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	members, err := d.referenceGroupMembers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved *schema.Entry
+
+	for _, key := range members {
+		entry, err := d.Get(ctx, &schema.KeyRequest{Key: key})
+		if errors.Is(err, store.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// the key may have been set again out of the group
+		if entry.ReferencedBy == nil || !bytes.Equal(entry.ReferencedBy.Group, group) {
+			continue
+		}
+
+		// members are visited in key order, thus the lowest key wins ties
+		if resolved == nil || entry.ReferencedBy.Priority > resolved.ReferencedBy.Priority {
+			resolved = entry
+		}
+	}
+
+	if resolved == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrNoLiveGroupReference, group)
+	}
+
+	return resolved, nil
+}
+
+// referenceGroupMembers returns the keys of the references ever added to the group in key order
+func (d *db) referenceGroupMembers(ctx context.Context, group []byte) ([][]byte, error) {
+	snap, err := d.snapshotSince(ctx, []byte{ReferenceGroupPrefix}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Close()
+
+	prefix := encodeReferenceGroupPrefix(group)
+
+	r, err := snap.NewKeyReader(store.KeyReaderSpec{Prefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var members [][]byte
+
+	for {
+		key, _, err := r.Read(ctx)
+		if errors.Is(err, store.ErrNoMoreEntries) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, append([]byte(nil), key[len(prefix):]...))
+	}
+
+	return members, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveReferenceGroup(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("primary"), Value: []byte("primary-value")},
+		{Key: []byte("secondary"), Value: []byte("secondary-value")},
+		{Key: []byte("tertiary"), Value: []byte("tertiary-value")},
+	}})
+	require.NoError(t, err)
+
+	t.Run("invalid group arguments should be rejected", func(t *testing.T) {
+		_, err := db.ResolveGroup(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("failover/a"),
+			ReferencedKey: []byte("primary"),
+			Priority:      1,
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{{
+			Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{
+				Key:           []byte("failover/a"),
+				ReferencedKey: []byte("primary"),
+				Group:         []byte("svc"),
+			}},
+		}}})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ResolveGroup(ctx, []byte("svc"))
+		require.ErrorIs(t, err, ErrNoLiveGroupReference)
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	// tertiary shares the priority of secondary but its reference key sorts after it
+	_, err = db.SetReferences(ctx, &schema.SetReferencesRequest{References: []*schema.ReferenceRequest{
+		{Key: []byte("failover/c"), ReferencedKey: []byte("tertiary"), Group: []byte("svc"), Priority: 5},
+		{Key: []byte("failover/b"), ReferencedKey: []byte("secondary"), Group: []byte("svc"), Priority: 5},
+		{Key: []byte("failover/a"), ReferencedKey: []byte("primary"), Group: []byte("svc"), Priority: 10},
+		{Key: []byte("other/a"), ReferencedKey: []byte("tertiary"), Group: []byte("other"), Priority: 100},
+	}})
+	require.NoError(t, err)
+
+	resolveGroup := func(t *testing.T) *schema.Entry {
+		entry, err := db.ResolveGroup(ctx, []byte("svc"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("svc"), entry.ReferencedBy.Group)
+		return entry
+	}
+
+	t.Run("the highest priority reference should be resolved", func(t *testing.T) {
+		entry := resolveGroup(t)
+		require.Equal(t, []byte("primary-value"), entry.Value)
+		require.Equal(t, []byte("failover/a"), entry.ReferencedBy.Key)
+		require.EqualValues(t, 10, entry.ReferencedBy.Priority)
+	})
+
+	t.Run("resolution should fall to the next priority when the target is deleted", func(t *testing.T) {
+		_, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("primary")}})
+		require.NoError(t, err)
+
+		entry := resolveGroup(t)
+		require.Equal(t, []byte("secondary-value"), entry.Value)
+		require.Equal(t, []byte("failover/b"), entry.ReferencedBy.Key)
+	})
+
+	t.Run("resolution should skip references set again out of the group", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("failover/b"), ReferencedKey: []byte("secondary")})
+		require.NoError(t, err)
+
+		entry := resolveGroup(t)
+		require.Equal(t, []byte("tertiary-value"), entry.Value)
+		require.Equal(t, []byte("failover/c"), entry.ReferencedBy.Key)
+		require.EqualValues(t, 5, entry.ReferencedBy.Priority)
+	})
+
+	t.Run("no reference should be resolved when all targets are dead", func(t *testing.T) {
+		_, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("tertiary")}})
+		require.NoError(t, err)
+
+		_, err = db.ResolveGroup(ctx, []byte("svc"))
+		require.ErrorIs(t, err, ErrNoLiveGroupReference)
+	})
+}
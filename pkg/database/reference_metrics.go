@@ -0,0 +1,55 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import "sync/atomic"
+
+// Metrics holds the counters collected by the database since it was opened
+type Metrics struct {
+	// ReferenceResolutions is the number of reads resolving a reference
+	ReferenceResolutions uint64
+	// ReferenceHops is the total number of references followed while resolving them,
+	// chained references accounting for one hop each
+	ReferenceHops uint64
+	// ReferencesNotFound is the number of resolutions failing because the referenced key was not found
+	ReferencesNotFound uint64
+	// StaleReferenceHits is the number of bound references found to be stale when read
+	StaleReferenceHits uint64
+}
+
+// referenceMetrics is updated atomically, so counting does not require any locking.
+// It is allocated on its own so its fields are 64-bit aligned as required by atomic operations
+type referenceMetrics struct {
+	resolutions uint64
+	hops        uint64
+	notFound    uint64
+	staleHits   uint64
+}
+
+func (m *referenceMetrics) snapshot() Metrics {
+	return Metrics{
+		ReferenceResolutions: atomic.LoadUint64(&m.resolutions),
+		ReferenceHops:        atomic.LoadUint64(&m.hops),
+		ReferencesNotFound:   atomic.LoadUint64(&m.notFound),
+		StaleReferenceHits:   atomic.LoadUint64(&m.staleHits),
+	}
+}
+
+// Metrics returns the current value of the counters of the database
+func (d *db) Metrics() Metrics {
+	return d.referenceMetrics.snapshot()
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/codenotary/immudb/embedded/store"
+)
+
+// PreconditionKeyMustBeReference is only met when the key exists and it's a reference
+type PreconditionKeyMustBeReference struct {
+	Key []byte
+}
+
+func (cs *PreconditionKeyMustBeReference) String() string { return "KeyMustBeReference" }
+
+func (cs *PreconditionKeyMustBeReference) Validate(st *store.ImmuStore) error {
+	return validatePreconditionKey(st, cs.Key)
+}
+
+func (cs *PreconditionKeyMustBeReference) Check(ctx context.Context, idx store.KeyIndex) (bool, error) {
+	isRef, err := keyIsReference(ctx, idx, cs.Key)
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return isRef, nil
+}
+
+// PreconditionKeyMustNotBeReference is only met when the key does not exist or it's not a reference
+type PreconditionKeyMustNotBeReference struct {
+	Key []byte
+}
+
+func (cs *PreconditionKeyMustNotBeReference) String() string { return "KeyMustNotBeReference" }
+
+func (cs *PreconditionKeyMustNotBeReference) Validate(st *store.ImmuStore) error {
+	return validatePreconditionKey(st, cs.Key)
+}
+
+func (cs *PreconditionKeyMustNotBeReference) Check(ctx context.Context, idx store.KeyIndex) (bool, error) {
+	isRef, err := keyIsReference(ctx, idx, cs.Key)
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !isRef, nil
+}
+
+// PreconditionKeyMustHaveValue is only met when the key exists, it's not a reference and it holds the given value.
+// As any other precondition, it's checked within the transaction being committed.
+type PreconditionKeyMustHaveValue struct {
+	Key   []byte
+	Value []byte
+}
+
+func (cs *PreconditionKeyMustHaveValue) String() string { return "KeyMustHaveValue" }
+
+func (cs *PreconditionKeyMustHaveValue) Validate(st *store.ImmuStore) error {
+	return validatePreconditionKey(st, cs.Key)
+}
+
+func (cs *PreconditionKeyMustHaveValue) Check(ctx context.Context, idx store.KeyIndex) (bool, error) {
+	valRef, err := idx.Get(ctx, cs.Key)
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	val, err := valRef.Resolve()
+	if err != nil {
+		return false, err
+	}
+
+	if len(val) == 0 || val[0] != PlainValuePrefix {
+		return false, nil
+	}
+
+	return bytes.Equal(TrimPrefix(val), cs.Value), nil
+}
+
+func validatePreconditionKey(st *store.ImmuStore, key []byte) error {
+	if len(key) == 0 {
+		return store.ErrInvalidPreconditionNullKey
+	}
+
+	if len(key) > st.MaxKeyLen() {
+		return store.ErrInvalidPreconditionMaxKeyLenExceeded
+	}
+
+	return nil
+}
+
+func keyIsReference(ctx context.Context, idx store.KeyIndex, key []byte) (bool, error) {
+	valRef, err := idx.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	val, err := valRef.Resolve()
+	if err != nil {
+		return false, err
+	}
+
+	return isReferenceValue(val), nil
+}
@@ -19,10 +19,14 @@ package database
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/api/schema"
@@ -105,6 +109,205 @@ func TestStoreReference(t *testing.T) {
 	require.True(t, verifies)
 }
 
+func TestStoreReferencedBy(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`firstKey`), Value: []byte(`firstValue`)}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: []byte(`firstKey`)})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte(`myBoundTag`),
+		ReferencedKey: []byte(`firstKey`),
+		AtTx:          hdr.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte(`firstKey`)})
+	require.NoError(t, err)
+	require.Nil(t, entry.ReferencedBy)
+
+	entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte(`myTag`)})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`firstKey`), entry.Key)
+	require.Equal(t, []byte(`myTag`), entry.ReferencedBy.Key)
+	require.Zero(t, entry.ReferencedBy.AtTx)
+	require.False(t, entry.ReferencedBy.BoundRef)
+
+	entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte(`myBoundTag`)})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`firstKey`), entry.Key)
+	require.Equal(t, []byte(`myBoundTag`), entry.ReferencedBy.Key)
+	require.Equal(t, hdr.Id, entry.ReferencedBy.AtTx)
+	require.True(t, entry.ReferencedBy.BoundRef)
+}
+
+func TestStoreGetReference(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`firstKey`), Value: []byte(`firstValue`)}}})
+	require.NoError(t, err)
+
+	refHdr, err := db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte(`myTag`),
+		ReferencedKey: []byte(`firstKey`),
+		AtTx:          hdr.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: []byte(`firstKey`)})
+	require.NoError(t, err)
+
+	t.Run("invalid requests should fail", func(t *testing.T) {
+		_, err := db.GetReference(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("the latest reference should be returned without being resolved", func(t *testing.T) {
+		ref, err := db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`myTag`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`myTag`), ref.Key)
+		require.Equal(t, []byte(`firstKey`), ref.ReferencedKey)
+		require.Zero(t, ref.AtTx)
+		require.False(t, ref.BoundRef)
+		require.EqualValues(t, 2, ref.Revision)
+	})
+
+	t.Run("a previous version of the reference should be returned", func(t *testing.T) {
+		ref, err := db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`myTag`), AtTx: refHdr.Id})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`firstKey`), ref.ReferencedKey)
+		require.Equal(t, hdr.Id, ref.AtTx)
+		require.True(t, ref.BoundRef)
+		require.Equal(t, refHdr.Id, ref.Tx)
+
+		ref, err = db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`myTag`), AtRevision: 1})
+		require.NoError(t, err)
+		require.Equal(t, refHdr.Id, ref.Tx)
+		require.True(t, ref.BoundRef)
+	})
+
+	t.Run("keys not being references should not be found", func(t *testing.T) {
+		_, err := db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`firstKey`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		_, err = db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`unknown`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("deleted references should not be found", func(t *testing.T) {
+		_, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte(`myTag`)}})
+		require.NoError(t, err)
+
+		_, err = db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`myTag`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+}
+
+func TestStoreReferenceHistory(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte(`v1`), Value: []byte(`value1`)},
+		{Key: []byte(`v2`), Value: []byte(`value2`)},
+		{Key: []byte(`v3`), Value: []byte(`value3`)},
+	}})
+	require.NoError(t, err)
+
+	refHdr1, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte(`release`), ReferencedKey: []byte(`v1`)})
+	require.NoError(t, err)
+
+	refHdr2, err := db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte(`release`),
+		ReferencedKey: []byte(`v2`),
+		AtTx:          hdr.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	refHdr3, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte(`release`), ReferencedKey: []byte(`v3`)})
+	require.NoError(t, err)
+
+	t.Run("invalid requests should fail", func(t *testing.T) {
+		_, err := db.ReferenceHistory(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ReferenceHistory(ctx, &schema.HistoryRequest{})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ReferenceHistory(ctx, &schema.HistoryRequest{Key: []byte(`release`), Limit: int32(db.MaxResultSize() + 1)})
+		require.ErrorIs(t, err, ErrResultSizeLimitExceeded)
+	})
+
+	t.Run("every version of the reference should be decoded", func(t *testing.T) {
+		refs, err := db.ReferenceHistory(ctx, &schema.HistoryRequest{Key: []byte(`release`)})
+		require.NoError(t, err)
+		require.Len(t, refs, 3)
+
+		require.Equal(t, []byte(`v1`), refs[0].ReferencedKey)
+		require.Equal(t, refHdr1.Id, refs[0].Tx)
+		require.Zero(t, refs[0].AtTx)
+		require.False(t, refs[0].BoundRef)
+
+		require.Equal(t, []byte(`v2`), refs[1].ReferencedKey)
+		require.Equal(t, refHdr2.Id, refs[1].Tx)
+		require.Equal(t, hdr.Id, refs[1].AtTx)
+		require.True(t, refs[1].BoundRef)
+
+		require.Equal(t, []byte(`v3`), refs[2].ReferencedKey)
+		require.Equal(t, refHdr3.Id, refs[2].Tx)
+
+		for i, ref := range refs {
+			require.Equal(t, []byte(`release`), ref.Key)
+			require.EqualValues(t, i+1, ref.Revision)
+		}
+	})
+
+	t.Run("versions should be paginated in the requested order", func(t *testing.T) {
+		refs, err := db.ReferenceHistory(ctx, &schema.HistoryRequest{Key: []byte(`release`), Desc: true, Offset: 1, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+		require.Equal(t, []byte(`v2`), refs[0].ReferencedKey)
+
+		refs, err = db.ReferenceHistory(ctx, &schema.HistoryRequest{Key: []byte(`release`), Offset: 4})
+		require.NoError(t, err)
+		require.Empty(t, refs)
+	})
+
+	t.Run("keys not being references should not be found", func(t *testing.T) {
+		_, err := db.ReferenceHistory(ctx, &schema.HistoryRequest{Key: []byte(`v1`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		_, err = db.ReferenceHistory(ctx, &schema.HistoryRequest{Key: []byte(`unknown`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("deleted versions should not have a referenced key", func(t *testing.T) {
+		delHdr, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte(`release`)}})
+		require.NoError(t, err)
+
+		refs, err := db.ReferenceHistory(ctx, &schema.HistoryRequest{Key: []byte(`release`), Desc: true, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, refs, 2)
+
+		require.Equal(t, delHdr.Id, refs[0].Tx)
+		require.True(t, refs[0].Metadata.Deleted)
+		require.Nil(t, refs[0].ReferencedKey)
+
+		require.Equal(t, []byte(`v3`), refs[1].ReferencedKey)
+	})
+}
+
 func TestStore_GetReferenceWithIndexResolution(t *testing.T) {
 	db := makeDb(t)
 
@@ -140,6 +343,69 @@ func TestStoreInvalidReferenceToReference(t *testing.T) {
 	require.ErrorIs(t, err, ErrReferencedKeyCannotBeAReference)
 }
 
+func TestStoreReferenceToReservedKey(t *testing.T) {
+	options := DefaultOption().WithDBRootPath(t.TempDir()).WithReservedKeyPrefixes([]byte{1}, []byte(`internal.`))
+	db := makeDbWith(t, "db", options)
+
+	for _, reservedKey := range [][]byte{{1, 'k'}, []byte(`internal.key1`)} {
+		_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: reservedKey, Value: []byte(`value`)}}})
+		require.NoError(t, err)
+
+		_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: reservedKey})
+		require.ErrorIs(t, err, ErrReservedKeyReference)
+		require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+		_, err = db.ExecAll(context.Background(), &schema.ExecAllRequest{
+			Operations: []*schema.Op{{
+				Operation: &schema.Op_Ref{
+					Ref: &schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: reservedKey},
+				},
+			}},
+		})
+		require.ErrorIs(t, err, ErrReservedKeyReference)
+
+		_, err = db.VerifiableSetWithReference(context.Background(),
+			&schema.KeyValue{Key: reservedKey, Value: []byte(`value`)},
+			&schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: reservedKey},
+			0,
+		)
+		require.ErrorIs(t, err, ErrReservedKeyReference)
+	}
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`normalKey`), Value: []byte(`value`)}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: []byte(`normalKey`)})
+	require.NoError(t, err)
+
+	entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`myTag`)})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`normalKey`), entry.Key)
+	require.Equal(t, []byte(`value`), entry.Value)
+
+	t.Run("keys resembling internal key spaces should be referenced", func(t *testing.T) {
+		db := makeDb(t)
+
+		// user keys are always stored under SetKeyPrefix, so keys resembling the internal
+		// SQL or document key spaces can be referenced like any other key
+		for _, key := range [][]byte{
+			append([]byte{SQLPrefix}, []byte(`CTL.key1`)...),
+			append([]byte{DocumentPrefix}, []byte(`R.key1`)...),
+			{1, 'k'},
+		} {
+			_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: key, Value: []byte(`value`)}}})
+			require.NoError(t, err)
+
+			_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: key})
+			require.NoError(t, err)
+
+			entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`myTag`)})
+			require.NoError(t, err)
+			require.Equal(t, key, entry.Key)
+		}
+	})
+}
+
 func TestStoreReferenceAsyncCommit(t *testing.T) {
 	db := makeDb(t)
 
@@ -380,6 +646,151 @@ func TestStoreVerifiableReference(t *testing.T) {
 	require.Equal(t, []byte(`firstValue`), firstItemRet.Value, "Should have referenced item value")
 }
 
+func TestStoreVerifiableGetReferencedEntry(t *testing.T) {
+	db := makeDb(t)
+
+	hdr1, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`firstKey`), Value: []byte(`firstValue`)}}})
+	require.NoError(t, err)
+
+	_, err = db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`firstKey`), Value: []byte(`secondValue`)}}})
+	require.NoError(t, err)
+
+	refHdr, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte(`myTag`),
+		ReferencedKey: []byte(`firstKey`),
+		AtTx:          hdr1.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	vEntry, err := db.VerifiableGet(context.Background(), &schema.VerifiableGetRequest{
+		KeyRequest:   &schema.KeyRequest{Key: []byte(`myTag`), SinceTx: refHdr.Id},
+		ProveSinceTx: refHdr.Id,
+	})
+	require.NoError(t, err)
+	require.Nil(t, vEntry.ReferencedEntryProof)
+
+	vEntry, err = db.VerifiableGet(context.Background(), &schema.VerifiableGetRequest{
+		KeyRequest:           &schema.KeyRequest{Key: []byte(`myTag`), SinceTx: refHdr.Id},
+		ProveSinceTx:         refHdr.Id,
+		ProveReferencedEntry: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`firstValue`), vEntry.Entry.Value)
+	require.Equal(t, hdr1.Id, vEntry.Entry.Tx)
+	require.NotNil(t, vEntry.ReferencedEntryProof)
+
+	dualProof := schema.DualProofFromProto(vEntry.ReferencedEntryProof.DualProof)
+	require.Equal(t, hdr1.Id, dualProof.SourceTxHeader.ID)
+	require.Equal(t, refHdr.Id, dualProof.TargetTxHeader.ID)
+
+	entrySpecDigest, err := store.EntrySpecDigestFor(dualProof.SourceTxHeader.Version)
+	require.NoError(t, err)
+
+	e := EncodeEntrySpec(vEntry.Entry.Key, schema.KVMetadataFromProto(vEntry.Entry.Metadata), vEntry.Entry.Value)
+
+	verifies := store.VerifyInclusion(
+		schema.InclusionProofFromProto(vEntry.ReferencedEntryProof.InclusionProof),
+		entrySpecDigest(e),
+		dualProof.SourceTxHeader.Eh,
+	)
+	require.True(t, verifies)
+
+	verifies = store.VerifyDualProof(
+		dualProof,
+		hdr1.Id,
+		refHdr.Id,
+		schema.TxHeaderFromProto(hdr1).Alh(),
+		schema.TxHeaderFromProto(refHdr).Alh(),
+	)
+	require.True(t, verifies)
+
+	e = EncodeEntrySpec(vEntry.Entry.Key, schema.KVMetadataFromProto(vEntry.Entry.Metadata), []byte(`secondValue`))
+
+	verifies = store.VerifyInclusion(
+		schema.InclusionProofFromProto(vEntry.ReferencedEntryProof.InclusionProof),
+		entrySpecDigest(e),
+		dualProof.SourceTxHeader.Eh,
+	)
+	require.False(t, verifies)
+}
+
+func TestStoreVerifiableReferences(t *testing.T) {
+	db := makeDb(t)
+
+	_, err := db.VerifiableSetReferences(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	req := &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte(`firstKey`), Value: []byte(`firstValue`)},
+		{Key: []byte(`secondKey`), Value: []byte(`secondValue`)},
+	}}
+	txhdr, err := db.Set(context.Background(), req)
+	require.NoError(t, err)
+
+	_, err = db.VerifiableSetReferences(context.Background(), &schema.VerifiableSetReferencesRequest{
+		SetReferencesRequest: nil,
+		ProveSinceTx:         txhdr.Id,
+	})
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	refsReq := &schema.SetReferencesRequest{
+		References: []*schema.ReferenceRequest{
+			{Key: []byte(`myTag1`), ReferencedKey: []byte(`firstKey`)},
+			{Key: []byte(`myTag2`), ReferencedKey: []byte(`secondKey`)},
+			{Key: []byte(`myTag3`), ReferencedKey: []byte(`firstKey`), AtTx: txhdr.Id, BoundRef: true},
+		},
+	}
+
+	_, err = db.VerifiableSetReferences(context.Background(), &schema.VerifiableSetReferencesRequest{
+		SetReferencesRequest: refsReq,
+		ProveSinceTx:         txhdr.Id + 1,
+	})
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	vtx, err := db.VerifiableSetReferences(context.Background(), &schema.VerifiableSetReferencesRequest{
+		SetReferencesRequest: refsReq,
+		ProveSinceTx:         txhdr.Id,
+	})
+	require.NoError(t, err)
+	require.Len(t, vtx.Tx.Entries, 3)
+
+	dualProof := schema.DualProofFromProto(vtx.DualProof)
+
+	verifies := store.VerifyDualProof(
+		dualProof,
+		txhdr.Id,
+		vtx.Tx.Header.Id,
+		schema.TxHeaderFromProto(txhdr).Alh(),
+		dualProof.TargetTxHeader.Alh(),
+	)
+	require.True(t, verifies)
+
+	tx := schema.TxFromProto(vtx.Tx)
+
+	entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+	require.NoError(t, err)
+
+	for _, ref := range refsReq.References {
+		inclusionProof, err := tx.Proof(EncodeKey(ref.Key))
+		require.NoError(t, err)
+
+		e := EncodeReference(ref.Key, nil, ref.ReferencedKey, ref.AtTx)
+		require.True(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+
+		e = EncodeReference(ref.Key, nil, []byte(`otherKey`), ref.AtTx)
+		require.False(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+	}
+
+	for i, expected := range []string{`firstValue`, `secondValue`, `firstValue`} {
+		keyReq := &schema.KeyRequest{Key: refsReq.References[i].Key, SinceTx: vtx.Tx.Header.Id}
+
+		itemRet, err := db.Get(context.Background(), keyReq)
+		require.NoError(t, err)
+		require.Equal(t, []byte(expected), itemRet.Value, "Should have referenced item value")
+	}
+}
+
 func TestStoreReferenceWithPreconditions(t *testing.T) {
 	db := makeDb(t)
 
@@ -433,3 +844,1887 @@ func TestStoreReferenceWithPreconditions(t *testing.T) {
 	})
 	require.ErrorIs(t, err, store.ErrInvalidPrecondition)
 }
+
+func TestStoreReferenceWithTransform(t *testing.T) {
+	opts := DefaultOption().
+		WithDBRootPath(t.TempDir()).
+		WithReferenceTransform("customerName", ExtractJSONField("name"))
+
+	db := makeDbWith(t, "db", opts)
+
+	rawValue := []byte(`{"name":"Alice","age":30}`)
+
+	hdr, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`customer1`), Value: rawValue}}})
+	require.NoError(t, err)
+
+	t.Run("unknown transforms should be rejected", func(t *testing.T) {
+		_, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+			Key:           []byte(`customer1Name`),
+			ReferencedKey: []byte(`customer1`),
+			Transform:     "unknown",
+		})
+		require.ErrorIs(t, err, ErrUnknownReferenceTransform)
+
+		_, err = db.ExecAll(context.Background(), &schema.ExecAllRequest{
+			Operations: []*schema.Op{{
+				Operation: &schema.Op_Ref{
+					Ref: &schema.ReferenceRequest{Key: []byte(`customer1Name`), ReferencedKey: []byte(`customer1`), Transform: "unknown"},
+				},
+			}},
+		})
+		require.ErrorIs(t, err, ErrUnknownReferenceTransform)
+	})
+
+	vtx, err := db.VerifiableSetReference(context.Background(), &schema.VerifiableReferenceRequest{
+		ReferenceRequest: &schema.ReferenceRequest{
+			Key:           []byte(`customer1Name`),
+			ReferencedKey: []byte(`customer1`),
+			AtTx:          hdr.Id,
+			BoundRef:      true,
+			Transform:     "customerName",
+		},
+		ProveSinceTx: hdr.Id,
+	})
+	require.NoError(t, err)
+
+	t.Run("get should return the transformed value", func(t *testing.T) {
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`customer1Name`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`customer1`), entry.Key)
+		require.Equal(t, []byte(`"Alice"`), entry.Value)
+		require.Equal(t, "customerName", entry.ReferencedBy.Transform)
+	})
+
+	t.Run("raw value should still be retrievable", func(t *testing.T) {
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`customer1Name`), SkipTransform: true})
+		require.NoError(t, err)
+		require.Equal(t, rawValue, entry.Value)
+
+		entry, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`customer1`)})
+		require.NoError(t, err)
+		require.Equal(t, rawValue, entry.Value)
+	})
+
+	t.Run("transform should be covered by the entry digest", func(t *testing.T) {
+		tx := schema.TxFromProto(vtx.Tx)
+
+		entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+		require.NoError(t, err)
+
+		inclusionProof, err := tx.Proof(EncodeKey([]byte(`customer1Name`)))
+		require.NoError(t, err)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`customer1Name`)})
+		require.NoError(t, err)
+
+		e := EncodeReferenceWithAttributes(
+			entry.ReferencedBy.Key,
+			nil,
+			entry.Key,
+			entry.ReferencedBy.AtTx,
+			ReferenceAttributesFromProto(entry.ReferencedBy),
+		)
+		require.True(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+
+		e = EncodeReference(entry.ReferencedBy.Key, nil, entry.Key, entry.ReferencedBy.AtTx)
+		require.False(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+	})
+
+	t.Run("failing transforms should return an error", func(t *testing.T) {
+		_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`customer1`), Value: []byte(`{"age":31}`)}}})
+		require.NoError(t, err)
+
+		_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{
+			Key:           []byte(`customer1LatestName`),
+			ReferencedKey: []byte(`customer1`),
+			Transform:     "customerName",
+		})
+		require.NoError(t, err)
+
+		_, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`customer1LatestName`)})
+		require.ErrorIs(t, err, ErrReferenceTransformFailed)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`customer1Name`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`"Alice"`), entry.Value)
+	})
+}
+
+func TestReferenceValueWithAttributes(t *testing.T) {
+	key := WrapWithPrefix([]byte(`key1`), SetKeyPrefix)
+
+	t.Run("references without attributes should keep the legacy encoding", func(t *testing.T) {
+		val := WrapReferenceValueWithAttributesAt(key, 10, &ReferenceAttributes{})
+		require.Equal(t, WrapReferenceValueAt(key, 10), val)
+
+		refKey, atTx, attrs, err := unwrapReferenceValue(val)
+		require.NoError(t, err)
+		require.Equal(t, key, refKey)
+		require.EqualValues(t, 10, atTx)
+		require.True(t, attrs.isEmpty())
+	})
+
+	t.Run("references with attributes should round-trip", func(t *testing.T) {
+		val := WrapReferenceValueWithAttributesAt(key, 10, &ReferenceAttributes{Transform: "transform1"})
+		require.EqualValues(t, AttributedReferenceValuePrefix, val[0])
+
+		refKey, atTx, attrs, err := unwrapReferenceValue(val)
+		require.NoError(t, err)
+		require.Equal(t, key, refKey)
+		require.EqualValues(t, 10, atTx)
+		require.Equal(t, "transform1", attrs.Transform)
+
+		_, _, _, err = unwrapReferenceValue(val[:len(val)-1])
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+
+		_, _, _, err = unwrapReferenceValue(append(val, 0xff, 0, 0))
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+	})
+}
+
+func TestDecodeReference(t *testing.T) {
+	t.Run("unbound references should round-trip", func(t *testing.T) {
+		entry := EncodeReference([]byte(`ref1`), nil, []byte(`key1`), 0)
+
+		referencedKey, atTx, err := DecodeReference(entry.Value)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`key1`), referencedKey)
+		require.Zero(t, atTx)
+	})
+
+	t.Run("bound references should round-trip", func(t *testing.T) {
+		entry := EncodeReference([]byte(`ref1`), nil, []byte(`key1`), 10)
+
+		referencedKey, atTx, err := DecodeReference(entry.Value)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`key1`), referencedKey)
+		require.EqualValues(t, 10, atTx)
+	})
+
+	t.Run("references with attributes should be decoded", func(t *testing.T) {
+		entry := EncodeReferenceWithAttributes([]byte(`ref1`), nil, []byte(`key1`), 10, &ReferenceAttributes{Label: "label1"})
+
+		referencedKey, atTx, err := DecodeReference(entry.Value)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`key1`), referencedKey)
+		require.EqualValues(t, 10, atTx)
+	})
+
+	t.Run("values other than references should not be decoded", func(t *testing.T) {
+		_, _, err := DecodeReference(nil)
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+
+		_, _, err = DecodeReference(EncodeEntrySpec([]byte(`key1`), nil, []byte(`value1`)).Value)
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+
+		_, _, err = DecodeReference(WrapReferenceValueAt([]byte(`key1`), 10))
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+
+		_, _, err = DecodeReference(WrapReferenceValueAt(nil, 10))
+		require.ErrorIs(t, err, store.ErrCorruptedData)
+	})
+}
+
+func TestStoreSetReferences(t *testing.T) {
+	db := makeDb(t)
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte(`key1`), Value: []byte(`value1`)},
+		{Key: []byte(`key2`), Value: []byte(`value2`)},
+	}})
+	require.NoError(t, err)
+
+	hdr, err := db.SetReferences(context.Background(), &schema.SetReferencesRequest{
+		References: []*schema.ReferenceRequest{
+			{Key: []byte(`ref1`), ReferencedKey: []byte(`key1`)},
+			{Key: []byte(`ref2`), ReferencedKey: []byte(`key2`)},
+			{Key: []byte(`ref3`), ReferencedKey: []byte(`key1`)},
+		},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, hdr.Nentries)
+
+	for ref, value := range map[string]string{`ref1`: `value1`, `ref2`: `value2`, `ref3`: `value1`} {
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(ref)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(value), entry.Value)
+		require.Equal(t, hdr.Id, entry.ReferencedBy.Tx)
+	}
+
+	t.Run("a single invalid reference should roll back the whole batch", func(t *testing.T) {
+		for _, c := range []struct {
+			ref *schema.ReferenceRequest
+			err error
+		}{
+			{&schema.ReferenceRequest{ReferencedKey: []byte(`key1`)}, ErrIllegalArguments},
+			{&schema.ReferenceRequest{Key: []byte(`ref5`), ReferencedKey: []byte(`ref1`)}, ErrReferencedKeyCannotBeAReference},
+			{&schema.ReferenceRequest{Key: []byte(`ref5`), ReferencedKey: []byte(`ref4`)}, ErrReferencedKeyCannotBeAReference},
+			{&schema.ReferenceRequest{Key: []byte(`key2`), ReferencedKey: []byte(`key1`)}, ErrFinalKeyCannotBeConvertedIntoReference},
+			{&schema.ReferenceRequest{Key: []byte(`ref4`), ReferencedKey: []byte(`key2`)}, schema.ErrDuplicatedKeysNotSupported},
+		} {
+			_, err := db.SetReferences(context.Background(), &schema.SetReferencesRequest{
+				References: []*schema.ReferenceRequest{
+					{Key: []byte(`ref4`), ReferencedKey: []byte(`key1`)},
+					c.ref,
+				},
+			})
+			require.ErrorIs(t, err, c.err)
+
+			_, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref4`)})
+			require.ErrorIs(t, err, store.ErrKeyNotFound)
+		}
+	})
+
+	t.Run("empty batches should be rejected", func(t *testing.T) {
+		_, err := db.SetReferences(context.Background(), &schema.SetReferencesRequest{})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.SetReferences(context.Background(), nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
+func TestStoreReferenceCreatorMetadata(t *testing.T) {
+	db := makeDb(t)
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key1`), Value: []byte(`value1`)}}})
+	require.NoError(t, err)
+
+	vtx, err := db.VerifiableSetReference(context.Background(), &schema.VerifiableReferenceRequest{
+		ReferenceRequest: &schema.ReferenceRequest{
+			Key:           []byte(`ref1`),
+			ReferencedKey: []byte(`key1`),
+			CreatedBy:     "alice",
+			CreatedAt:     1700000000,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("creator metadata should round-trip", func(t *testing.T) {
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref1`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`value1`), entry.Value)
+		require.Equal(t, "alice", entry.ReferencedBy.CreatedBy)
+		require.EqualValues(t, 1700000000, entry.ReferencedBy.CreatedAt)
+	})
+
+	t.Run("creator metadata should be covered by the entry digest", func(t *testing.T) {
+		tx := schema.TxFromProto(vtx.Tx)
+
+		entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+		require.NoError(t, err)
+
+		inclusionProof, err := tx.Proof(EncodeKey([]byte(`ref1`)))
+		require.NoError(t, err)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref1`)})
+		require.NoError(t, err)
+
+		attrs := ReferenceAttributesFromProto(entry.ReferencedBy)
+
+		e := EncodeReferenceWithAttributes(entry.ReferencedBy.Key, nil, entry.Key, entry.ReferencedBy.AtTx, attrs)
+		require.True(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+
+		attrs.CreatedBy = "mallory"
+
+		e = EncodeReferenceWithAttributes(entry.ReferencedBy.Key, nil, entry.Key, entry.ReferencedBy.AtTx, attrs)
+		require.False(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+	})
+
+	t.Run("creation time should default to the write time", func(t *testing.T) {
+		hdr, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+			Key:           []byte(`ref2`),
+			ReferencedKey: []byte(`key1`),
+			CreatedBy:     "bob",
+		})
+		require.NoError(t, err)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref2`)})
+		require.NoError(t, err)
+		require.Equal(t, "bob", entry.ReferencedBy.CreatedBy)
+		require.InDelta(t, hdr.Ts, entry.ReferencedBy.CreatedAt, 1)
+	})
+
+	t.Run("references without creator metadata should not record it", func(t *testing.T) {
+		_, err := db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte(`ref3`), ReferencedKey: []byte(`key1`)})
+		require.NoError(t, err)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref3`)})
+		require.NoError(t, err)
+		require.Empty(t, entry.ReferencedBy.CreatedBy)
+		require.Zero(t, entry.ReferencedBy.CreatedAt)
+	})
+
+	t.Run("creators exceeding the maximum length should be rejected", func(t *testing.T) {
+		_, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+			Key:           []byte(`ref4`),
+			ReferencedKey: []byte(`key1`),
+			CreatedBy:     strings.Repeat("a", MaxReferenceCreatedByLen+1),
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ExecAll(context.Background(), &schema.ExecAllRequest{
+			Operations: []*schema.Op{{
+				Operation: &schema.Op_Ref{
+					Ref: &schema.ReferenceRequest{
+						Key:           []byte(`ref4`),
+						ReferencedKey: []byte(`key1`),
+						CreatedBy:     strings.Repeat("a", MaxReferenceCreatedByLen+1),
+					},
+				},
+			}},
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
+func TestStoreReferenceLabel(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key1`), Value: []byte(`value1`)}}})
+	require.NoError(t, err)
+
+	vtx, err := db.VerifiableSetReference(ctx, &schema.VerifiableReferenceRequest{
+		ReferenceRequest: &schema.ReferenceRequest{
+			Key:           []byte(`ref1`),
+			ReferencedKey: []byte(`key1`),
+			Label:         "primary account",
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("labels exceeding the maximum length should be rejected", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte(`ref2`),
+			ReferencedKey: []byte(`key1`),
+			Label:         strings.Repeat("a", MaxReferenceLabelLen+1),
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ExecAll(ctx, &schema.ExecAllRequest{
+			Operations: []*schema.Op{{
+				Operation: &schema.Op_Ref{
+					Ref: &schema.ReferenceRequest{
+						Key:           []byte(`ref2`),
+						ReferencedKey: []byte(`key1`),
+						Label:         strings.Repeat("a", MaxReferenceLabelLen+1),
+					},
+				},
+			}},
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("the label should round-trip without affecting resolution", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte(`ref1`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`value1`), entry.Value)
+		require.Equal(t, "primary account", entry.ReferencedBy.Label)
+
+		ref, err := db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`ref1`)})
+		require.NoError(t, err)
+		require.Equal(t, "primary account", ref.Label)
+	})
+
+	t.Run("listed references should include the label", func(t *testing.T) {
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{})
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+		require.Equal(t, []byte(`ref1`), refs[0].Key)
+		require.Equal(t, "primary account", refs[0].Label)
+	})
+
+	t.Run("the label should be covered by the entry digest", func(t *testing.T) {
+		tx := schema.TxFromProto(vtx.Tx)
+
+		entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+		require.NoError(t, err)
+
+		inclusionProof, err := tx.Proof(EncodeKey([]byte(`ref1`)))
+		require.NoError(t, err)
+
+		ref, err := db.GetReference(ctx, &schema.KeyRequest{Key: []byte(`ref1`)})
+		require.NoError(t, err)
+
+		attrs := ReferenceAttributesFromProto(ref)
+
+		e := EncodeReferenceWithAttributes(ref.Key, nil, ref.ReferencedKey, ref.AtTx, attrs)
+		require.True(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+
+		attrs.Label = "secondary account"
+
+		e = EncodeReferenceWithAttributes(ref.Key, nil, ref.ReferencedKey, ref.AtTx, attrs)
+		require.False(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+	})
+}
+
+func TestStoreRefreshReferences(t *testing.T) {
+	opts := DefaultOption().WithDBRootPath(t.TempDir())
+	opts.WithStoreOptions(opts.storeOpts.WithMaxTxEntries(2))
+
+	db := makeDbWith(t, "db", opts)
+
+	targets := []string{"target1", "target2", "target3"}
+	targetTxs := make(map[string]uint64)
+
+	for _, target := range targets {
+		hdr, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(target), Value: []byte(target + "_v1")}}})
+		require.NoError(t, err)
+
+		targetTxs[target] = hdr.Id
+	}
+
+	for _, target := range targets {
+		_, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+			Key:           []byte("tags/" + target),
+			ReferencedKey: []byte(target),
+			AtTx:          targetTxs[target],
+			BoundRef:      true,
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte("tags/latest"),
+		ReferencedKey: []byte("target1"),
+	})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte("other/target1"),
+		ReferencedKey: []byte("target1"),
+		AtTx:          targetTxs["target1"],
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	t.Run("up-to-date references should not be rewritten", func(t *testing.T) {
+		refreshed, txIDs, err := db.RefreshReferences(context.Background(), []byte("tags/"))
+		require.NoError(t, err)
+		require.Zero(t, refreshed)
+		require.Empty(t, txIDs)
+	})
+
+	for _, target := range targets {
+		_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(target), Value: []byte(target + "_v2")}}})
+		require.NoError(t, err)
+	}
+
+	t.Run("stale bound references should be refreshed in bounded transactions", func(t *testing.T) {
+		refreshed, txIDs, err := db.RefreshReferences(context.Background(), []byte("tags/"))
+		require.NoError(t, err)
+		require.Equal(t, len(targets), refreshed)
+		require.Len(t, txIDs, 2)
+
+		for _, target := range targets {
+			entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte("tags/" + target)})
+			require.NoError(t, err)
+			require.Equal(t, []byte(target+"_v2"), entry.Value)
+			require.NotNil(t, entry.ReferencedBy)
+			require.Equal(t, entry.Tx, entry.ReferencedBy.AtTx)
+			require.Contains(t, txIDs, entry.ReferencedBy.Tx)
+		}
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte("other/target1")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("target1_v1"), entry.Value)
+	})
+
+	t.Run("references to deleted keys should be skipped", func(t *testing.T) {
+		_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("target1"), Value: []byte("target1_v3")}}})
+		require.NoError(t, err)
+
+		_, err = db.Delete(context.Background(), &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("target1")}})
+		require.NoError(t, err)
+
+		refreshed, txIDs, err := db.RefreshReferences(context.Background(), []byte("tags/"))
+		require.NoError(t, err)
+		require.Zero(t, refreshed)
+		require.Empty(t, txIDs)
+	})
+}
+
+func TestStoreResolveReferenceProvenance(t *testing.T) {
+	db := makeDb(t)
+
+	hdr1, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`firstKey`), Value: []byte(`firstValue`)}}})
+	require.NoError(t, err)
+
+	hdr2, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`firstKey`), Value: []byte(`secondValue`)}}})
+	require.NoError(t, err)
+
+	t.Run("provenance of a plain key should hold a single hop", func(t *testing.T) {
+		hops, err := db.ResolveReferenceProvenance(context.Background(), []byte(`firstKey`))
+		require.NoError(t, err)
+		require.Len(t, hops, 1)
+		require.Equal(t, []byte(`firstKey`), hops[0].Key)
+		require.Equal(t, hdr2.Id, hops[0].Tx)
+		require.Equal(t, hdr2.Ts, hops[0].Timestamp)
+		require.Nil(t, hops[0].Reference)
+	})
+
+	t.Run("provenance of a reference should include the referenced key", func(t *testing.T) {
+		refHdr, err := db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte(`myTag`), ReferencedKey: []byte(`firstKey`)})
+		require.NoError(t, err)
+
+		hops, err := db.ResolveReferenceProvenance(context.Background(), []byte(`myTag`))
+		require.NoError(t, err)
+		require.Len(t, hops, 2)
+
+		require.Equal(t, []byte(`myTag`), hops[0].Key)
+		require.Equal(t, refHdr.Id, hops[0].Tx)
+		require.Equal(t, refHdr.Ts, hops[0].Timestamp)
+		require.NotNil(t, hops[0].Reference)
+		require.Equal(t, []byte(`myTag`), hops[0].Reference.Key)
+		require.Zero(t, hops[0].Reference.AtTx)
+		require.False(t, hops[0].Bound)
+
+		require.Equal(t, []byte(`firstKey`), hops[1].Key)
+		require.Equal(t, hdr2.Id, hops[1].Tx)
+		require.Nil(t, hops[1].Reference)
+	})
+
+	t.Run("provenance of a bound reference should include the bound revision", func(t *testing.T) {
+		refHdr, err := db.SetReference(context.Background(), &schema.ReferenceRequest{
+			Key:           []byte(`myBoundTag`),
+			ReferencedKey: []byte(`firstKey`),
+			AtTx:          hdr1.Id,
+			BoundRef:      true,
+		})
+		require.NoError(t, err)
+
+		hops, err := db.ResolveReferenceProvenance(context.Background(), []byte(`myBoundTag`))
+		require.NoError(t, err)
+		require.Len(t, hops, 2)
+
+		require.Equal(t, refHdr.Id, hops[0].Tx)
+		require.True(t, hops[0].Bound)
+		require.Equal(t, hdr1.Id, hops[0].Reference.AtTx)
+
+		require.Equal(t, []byte(`firstKey`), hops[1].Key)
+		require.Equal(t, hdr1.Id, hops[1].Tx)
+		require.Equal(t, hdr1.Ts, hops[1].Timestamp)
+	})
+
+	t.Run("provenance should not exceed the resolution limit", func(t *testing.T) {
+		tx, err := db.st.NewWriteOnlyTx(context.Background())
+		require.NoError(t, err)
+
+		e := EncodeReference([]byte(`myChainedTag`), nil, []byte(`myTag`), 0)
+		err = tx.Set(e.Key, e.Metadata, e.Value)
+		require.NoError(t, err)
+
+		_, err = tx.Commit(context.Background())
+		require.NoError(t, err)
+
+		_, err = db.ResolveReferenceProvenance(context.Background(), []byte(`myChainedTag`))
+		require.ErrorIs(t, err, ErrKeyResolutionLimitReached)
+	})
+
+	t.Run("provenance of missing keys should fail", func(t *testing.T) {
+		_, err := db.ResolveReferenceProvenance(context.Background(), []byte(`missingKey`))
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		_, err = db.ResolveReferenceProvenance(context.Background(), nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
+func TestStoreRepairReferences(t *testing.T) {
+	opts := DefaultOption().WithDBRootPath(t.TempDir())
+	opts.WithStoreOptions(opts.storeOpts.WithMaxTxEntries(2))
+
+	db := makeDbWith(t, "db", opts)
+
+	targetTxs := make(map[string]uint64)
+
+	for _, target := range []string{"target1", "target2"} {
+		hdr, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(target), Value: []byte(target)}}})
+		require.NoError(t, err)
+
+		targetTxs[target] = hdr.Id
+	}
+
+	for _, ref := range []string{"ref1", "ref2", "ref3"} {
+		_, err := db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte(ref), ReferencedKey: []byte("target1")})
+		require.NoError(t, err)
+	}
+
+	_, err := db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte("ref4"), ReferencedKey: []byte("target2")})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte("bound1"),
+		ReferencedKey: []byte("target1"),
+		AtTx:          targetTxs["target1"],
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	t.Run("no broken references should be reported", func(t *testing.T) {
+		broken, err := db.RepairReferences(context.Background(), false)
+		require.NoError(t, err)
+		require.Empty(t, broken)
+	})
+
+	_, err = db.Delete(context.Background(), &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("target1")}})
+	require.NoError(t, err)
+
+	t.Run("dry run should report broken references without changes", func(t *testing.T) {
+		state, err := db.CurrentState()
+		require.NoError(t, err)
+
+		broken, err := db.RepairReferences(context.Background(), true)
+		require.NoError(t, err)
+		require.Len(t, broken, 3)
+
+		for i, ref := range broken {
+			require.Equal(t, []byte(fmt.Sprintf("ref%d", i+1)), ref.Key)
+			require.Equal(t, []byte("target1"), ref.ReferencedKey)
+			require.Zero(t, ref.AtTx)
+			require.NotZero(t, ref.Tx)
+			require.Zero(t, ref.RemovedAtTx)
+		}
+
+		stateAfter, err := db.CurrentState()
+		require.NoError(t, err)
+		require.Equal(t, state.TxId, stateAfter.TxId)
+	})
+
+	t.Run("broken references should be tombstoned", func(t *testing.T) {
+		broken, err := db.RepairReferences(context.Background(), false)
+		require.NoError(t, err)
+		require.Len(t, broken, 3)
+
+		for _, ref := range broken {
+			require.NotZero(t, ref.RemovedAtTx)
+
+			_, err = db.Get(context.Background(), &schema.KeyRequest{Key: ref.Key})
+			require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+			// previous values are kept in the history of the reference
+			valRefs, _, err := db.st.History(EncodeKey(ref.Key), 0, false, 10)
+			require.NoError(t, err)
+			require.Len(t, valRefs, 2)
+			require.Equal(t, ref.Tx, valRefs[0].Tx())
+			require.True(t, valRefs[1].KVMetadata().Deleted())
+		}
+
+		require.Equal(t, broken[0].RemovedAtTx, broken[1].RemovedAtTx)
+		require.NotEqual(t, broken[0].RemovedAtTx, broken[2].RemovedAtTx)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte("ref4")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("target2"), entry.Value)
+
+		entry, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte("bound1")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("target1"), entry.Value)
+
+		broken, err = db.RepairReferences(context.Background(), true)
+		require.NoError(t, err)
+		require.Empty(t, broken)
+	})
+}
+
+func TestStoreEntryKind(t *testing.T) {
+	db := makeDb(t)
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{Key: []byte("ref1"), ReferencedKey: []byte("key1")})
+	require.NoError(t, err)
+
+	t.Run("get should report the kind of the entry", func(t *testing.T) {
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte("key1")})
+		require.NoError(t, err)
+		require.Equal(t, schema.EntryKind_DIRECT, entry.Kind)
+		require.Nil(t, entry.ReferencedBy)
+
+		entry, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte("ref1")})
+		require.NoError(t, err)
+		require.Equal(t, schema.EntryKind_REFERENCE, entry.Kind)
+		require.Equal(t, []byte("key1"), entry.Key)
+		require.Equal(t, []byte("ref1"), entry.ReferencedBy.Key)
+	})
+
+	t.Run("scan should report the kind of each entry", func(t *testing.T) {
+		entries, err := db.Scan(context.Background(), &schema.ScanRequest{})
+		require.NoError(t, err)
+		require.Len(t, entries.Entries, 2)
+
+		require.Equal(t, schema.EntryKind_DIRECT, entries.Entries[0].Kind)
+		require.Equal(t, []byte("key1"), entries.Entries[0].Key)
+
+		require.Equal(t, schema.EntryKind_REFERENCE, entries.Entries[1].Kind)
+		require.Equal(t, []byte("key1"), entries.Entries[1].Key)
+		require.Equal(t, []byte("ref1"), entries.Entries[1].ReferencedBy.Key)
+	})
+}
+
+func TestStoreListReferences(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	var ordersTx uint64
+
+	for _, key := range []string{"orders/1", "orders/2", "users/1"} {
+		hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(key), Value: []byte(key)}}})
+		require.NoError(t, err)
+
+		if key == "orders/1" {
+			ordersTx = hdr.Id
+		}
+	}
+
+	for ref, target := range map[string]string{
+		"tag/a": "orders/1",
+		"tag/b": "users/1",
+		"tag/c": "orders/2",
+		"tag/d": "orders/2",
+	} {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte(ref), ReferencedKey: []byte(target)})
+		require.NoError(t, err)
+	}
+
+	_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("tag/e"),
+		ReferencedKey: []byte("orders/1"),
+		AtTx:          ordersTx,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	keysOf := func(refs []*ListedReference) []string {
+		keys := make([]string, len(refs))
+		for i, ref := range refs {
+			keys[i] = string(ref.Key)
+		}
+		return keys
+	}
+
+	t.Run("invalid requests should fail", func(t *testing.T) {
+		_, err := db.ListReferences(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ListReferences(ctx, &ListReferencesRequest{Limit: db.MaxResultSize() + 1})
+		require.ErrorIs(t, err, ErrResultSizeLimitExceeded)
+	})
+
+	t.Run("references should be listed by target prefix", func(t *testing.T) {
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{TargetPrefix: []byte("orders/")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"tag/a", "tag/c", "tag/d", "tag/e"}, keysOf(refs))
+		require.Equal(t, []byte("orders/1"), refs[3].ReferencedKey)
+		require.Equal(t, ordersTx, refs[3].AtTx)
+	})
+
+	t.Run("references should be paginated", func(t *testing.T) {
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{TargetPrefix: []byte("orders/"), Limit: 3})
+		require.NoError(t, err)
+		require.Equal(t, []string{"tag/a", "tag/c", "tag/d"}, keysOf(refs))
+
+		refs, err = db.ListReferences(ctx, &ListReferencesRequest{TargetPrefix: []byte("orders/"), SeekKey: refs[2].Key, Limit: 3})
+		require.NoError(t, err)
+		require.Equal(t, []string{"tag/e"}, keysOf(refs))
+	})
+
+	t.Run("bound references should be listed by the key they were set with", func(t *testing.T) {
+		_, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("orders/1")}})
+		require.NoError(t, err)
+
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{Prefix: []byte("tag/e"), TargetPrefix: []byte("orders/")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"tag/e"}, keysOf(refs))
+	})
+}
+
+func TestStoreListReferencesMaxAtTx(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	txs := make([]uint64, 4)
+
+	for i := range txs {
+		hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte{byte(i)}}}})
+		require.NoError(t, err)
+
+		txs[i] = hdr.Id
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte(fmt.Sprintf("ref/%d", i)),
+			ReferencedKey: []byte("key"),
+			AtTx:          hdr.Id,
+			BoundRef:      true,
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref/latest"), ReferencedKey: []byte("key")})
+	require.NoError(t, err)
+
+	keysOf := func(refs []*ListedReference) []string {
+		keys := make([]string, len(refs))
+		for i, ref := range refs {
+			keys[i] = string(ref.Key)
+		}
+		return keys
+	}
+
+	t.Run("all references should be listed without threshold", func(t *testing.T) {
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ref/0", "ref/1", "ref/2", "ref/3", "ref/latest"}, keysOf(refs))
+
+		for _, ref := range refs[:3] {
+			require.True(t, ref.Stale)
+		}
+		require.False(t, refs[3].Stale)
+		require.False(t, refs[4].Stale)
+	})
+
+	t.Run("only bound references up to the threshold should be listed", func(t *testing.T) {
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{MaxAtTx: txs[1]})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ref/0", "ref/1"}, keysOf(refs))
+		require.Equal(t, txs[0], refs[0].AtTx)
+		require.Equal(t, txs[1], refs[1].AtTx)
+
+		refs, err = db.ListReferences(ctx, &ListReferencesRequest{MaxAtTx: txs[0]})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ref/0"}, keysOf(refs))
+	})
+
+	t.Run("threshold listing should be paginated", func(t *testing.T) {
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{MaxAtTx: txs[3], Limit: 3})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ref/0", "ref/1", "ref/2"}, keysOf(refs))
+
+		refs, err = db.ListReferences(ctx, &ListReferencesRequest{MaxAtTx: txs[3], SeekKey: refs[2].Key, Limit: 3})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ref/3"}, keysOf(refs))
+		require.False(t, refs[0].Stale)
+	})
+
+	t.Run("deleting the referenced key should make bound references stale", func(t *testing.T) {
+		_, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("key")}})
+		require.NoError(t, err)
+
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{MaxAtTx: txs[3], SeekKey: []byte("ref/2")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ref/3"}, keysOf(refs))
+		require.True(t, refs[0].Stale)
+	})
+}
+
+func TestStoreScanReferences(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("user:123:name"), Value: []byte("john")},
+		{Key: []byte("posts/1"), Value: []byte("post1")},
+		{Key: []byte("posts/2"), Value: []byte("post2")},
+	}})
+	require.NoError(t, err)
+
+	for tag, target := range map[string]string{
+		"user:123:tag:a": "posts/1",
+		"user:123:tag:c": "posts/2",
+		"user:456:tag:a": "posts/2",
+	} {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte(tag), ReferencedKey: []byte(target)})
+		require.NoError(t, err)
+	}
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("user:123:tag:b"),
+		ReferencedKey: []byte("posts/1"),
+		AtTx:          hdr.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("user:123:tag:z"), Value: []byte("plain")}}})
+	require.NoError(t, err)
+
+	keysOf := func(refs []*schema.Reference) []string {
+		keys := make([]string, len(refs))
+		for i, ref := range refs {
+			keys[i] = string(ref.Key)
+		}
+		return keys
+	}
+
+	t.Run("invalid requests should fail", func(t *testing.T) {
+		_, err := db.ScanReferences(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ScanReferences(ctx, &schema.ScanRequest{Offset: 1})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.ScanReferences(ctx, &schema.ScanRequest{Limit: uint64(db.MaxResultSize() + 1)})
+		require.ErrorIs(t, err, ErrResultSizeLimitExceeded)
+	})
+
+	t.Run("only references under the prefix should be returned", func(t *testing.T) {
+		refs, err := db.ScanReferences(ctx, &schema.ScanRequest{Prefix: []byte("user:123:")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user:123:tag:a", "user:123:tag:b", "user:123:tag:c"}, keysOf(refs))
+
+		require.Equal(t, []byte("posts/1"), refs[0].ReferencedKey)
+		require.Zero(t, refs[0].AtTx)
+		require.False(t, refs[0].BoundRef)
+
+		require.Equal(t, []byte("posts/1"), refs[1].ReferencedKey)
+		require.Equal(t, hdr.Id, refs[1].AtTx)
+		require.True(t, refs[1].BoundRef)
+
+		require.Equal(t, []byte("posts/2"), refs[2].ReferencedKey)
+	})
+
+	t.Run("references should be scanned in descending order", func(t *testing.T) {
+		refs, err := db.ScanReferences(ctx, &schema.ScanRequest{Prefix: []byte("user:123:"), Desc: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user:123:tag:c", "user:123:tag:b", "user:123:tag:a"}, keysOf(refs))
+	})
+
+	t.Run("references should be paginated", func(t *testing.T) {
+		refs, err := db.ScanReferences(ctx, &schema.ScanRequest{Prefix: []byte("user:123:"), Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user:123:tag:a", "user:123:tag:b"}, keysOf(refs))
+
+		refs, err = db.ScanReferences(ctx, &schema.ScanRequest{Prefix: []byte("user:123:"), SeekKey: refs[1].Key, Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user:123:tag:c"}, keysOf(refs))
+
+		refs, err = db.ScanReferences(ctx, &schema.ScanRequest{Prefix: []byte("user:123:"), SeekKey: []byte("user:123:tag:b"), Desc: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user:123:tag:a"}, keysOf(refs))
+	})
+
+	t.Run("deleted references should not be returned", func(t *testing.T) {
+		_, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("user:123:tag:a")}})
+		require.NoError(t, err)
+
+		refs, err := db.ScanReferences(ctx, &schema.ScanRequest{Prefix: []byte("user:123:")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user:123:tag:b", "user:123:tag:c"}, keysOf(refs))
+	})
+}
+
+func TestStoreReferenceCreateTargetIfMissing(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	t.Run("target creation should not be supported for bound references", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:                   []byte("ref"),
+			ReferencedKey:         []byte("target"),
+			AtTx:                  1,
+			BoundRef:              true,
+			CreateTargetIfMissing: true,
+		})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("missing target should not be created by default", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("target")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("missing target should be created along with the reference", func(t *testing.T) {
+		hdr, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:                   []byte("ref"),
+			ReferencedKey:         []byte("target"),
+			CreateTargetIfMissing: true,
+			DefaultTargetValue:    []byte("default"),
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, hdr.Nentries)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("target")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("default"), entry.Value)
+		require.Equal(t, hdr.Id, entry.Tx)
+
+		entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("ref")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("default"), entry.Value)
+		require.Equal(t, []byte("ref"), entry.ReferencedBy.Key)
+	})
+
+	t.Run("existing target should not be overwritten", func(t *testing.T) {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("target"), Value: []byte("value")}}})
+		require.NoError(t, err)
+
+		hdr, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:                   []byte("ref2"),
+			ReferencedKey:         []byte("target"),
+			CreateTargetIfMissing: true,
+			DefaultTargetValue:    []byte("default"),
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 1, hdr.Nentries)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ref2")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), entry.Value)
+	})
+}
+
+func TestStoreGetFailIfStaleReference(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("target"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("bound"),
+		ReferencedKey: []byte("target"),
+		AtTx:          hdr.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("unbound"), ReferencedKey: []byte("target")})
+	require.NoError(t, err)
+
+	t.Run("fresh bound reference should return the value", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("bound"), FailIfStaleReference: true})
+		require.NoError(t, err)
+		require.Equal(t, []byte("value1"), entry.Value)
+	})
+
+	updateHdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("target"), Value: []byte("value2")}}})
+	require.NoError(t, err)
+
+	t.Run("stale bound reference should return the bound value by default", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("bound")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("value1"), entry.Value)
+	})
+
+	t.Run("stale bound reference should fail when requested", func(t *testing.T) {
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("bound"), FailIfStaleReference: true})
+		require.ErrorIs(t, err, ErrStaleReference)
+
+		var staleErr *StaleReferenceError
+		require.ErrorAs(t, err, &staleErr)
+		require.Equal(t, []byte("bound"), staleErr.Key)
+		require.Equal(t, hdr.Id, staleErr.AtTx)
+		require.Equal(t, updateHdr.Id, staleErr.LatestTx)
+	})
+
+	t.Run("unbound reference should never be stale", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("unbound"), FailIfStaleReference: true})
+		require.NoError(t, err)
+		require.Equal(t, []byte("value2"), entry.Value)
+	})
+
+	t.Run("deletion of the referenced key should make the reference stale", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("bound"),
+			ReferencedKey: []byte("target"),
+			AtTx:          updateHdr.Id,
+			BoundRef:      true,
+		})
+		require.NoError(t, err)
+
+		_, err = db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("target")}})
+		require.NoError(t, err)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("bound"), FailIfStaleReference: true})
+		require.ErrorIs(t, err, ErrStaleReference)
+	})
+}
+
+func TestStoreCanonicalKey(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key"), Value: []byte("value")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("tag"), ReferencedKey: []byte("key")})
+	require.NoError(t, err)
+
+	t.Run("direct key should be returned unchanged", func(t *testing.T) {
+		key, err := db.CanonicalKey(ctx, []byte("key"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("key"), key)
+	})
+
+	t.Run("reference should be resolved to its referenced key", func(t *testing.T) {
+		key, err := db.CanonicalKey(ctx, []byte("tag"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("key"), key)
+	})
+
+	t.Run("chains should not exceed the resolution limit", func(t *testing.T) {
+		tx, err := db.st.NewWriteOnlyTx(ctx)
+		require.NoError(t, err)
+
+		e := EncodeReference([]byte("chainedTag"), nil, []byte("tag"), 0)
+		err = tx.Set(e.Key, e.Metadata, e.Value)
+		require.NoError(t, err)
+
+		_, err = tx.Commit(ctx)
+		require.NoError(t, err)
+
+		_, err = db.CanonicalKey(ctx, []byte("chainedTag"))
+		require.ErrorIs(t, err, ErrKeyResolutionLimitReached)
+	})
+
+	t.Run("missing keys should fail", func(t *testing.T) {
+		_, err := db.CanonicalKey(ctx, []byte("missing"))
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+}
+
+func TestStoreVerifiableSetWithReference(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.VerifiableSetWithReference(ctx, nil, &schema.ReferenceRequest{Key: []byte("tag"), ReferencedKey: []byte("key")}, 0)
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	_, err = db.VerifiableSetWithReference(ctx, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")}, &schema.ReferenceRequest{Key: []byte("key"), ReferencedKey: []byte("key")}, 0)
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	txhdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("plainKey"), Value: []byte("plainValue")}}})
+	require.NoError(t, err)
+
+	_, err = db.VerifiableSetWithReference(ctx, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")}, &schema.ReferenceRequest{Key: []byte("plainKey"), ReferencedKey: []byte("key")}, 0)
+	require.ErrorIs(t, err, ErrFinalKeyCannotBeConvertedIntoReference)
+
+	_, err = db.VerifiableSetWithReference(ctx, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")}, &schema.ReferenceRequest{Key: []byte("tag"), ReferencedKey: []byte("key")}, txhdr.Id+1)
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	_, err = db.VerifiableSetWithReference(ctx, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")}, &schema.ReferenceRequest{Key: []byte("tag"), ReferencedKey: []byte("other")}, 0)
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	_, err = db.VerifiableSetWithReference(ctx, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")}, &schema.ReferenceRequest{Key: []byte("tag"), ReferencedKey: []byte("key"), AtTx: txhdr.Id, BoundRef: true}, 0)
+	require.ErrorIs(t, err, store.ErrIllegalArguments)
+
+	vtx, err := db.VerifiableSetWithReference(ctx, &schema.KeyValue{Key: []byte("key"), Value: []byte("value")}, &schema.ReferenceRequest{
+		Key:           []byte("tag"),
+		ReferencedKey: []byte("key"),
+		Label:         "latest",
+		Group:         []byte("tags"),
+	}, txhdr.Id)
+	require.NoError(t, err)
+	// the key-value, the reference and its group membership
+	require.Len(t, vtx.Tx.Entries, 3)
+
+	dualProof := schema.DualProofFromProto(vtx.DualProof)
+
+	verifies := store.VerifyDualProof(
+		dualProof,
+		txhdr.Id,
+		vtx.Tx.Header.Id,
+		schema.TxHeaderFromProto(txhdr).Alh(),
+		dualProof.TargetTxHeader.Alh(),
+	)
+	require.True(t, verifies)
+
+	tx := schema.TxFromProto(vtx.Tx)
+
+	entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+	require.NoError(t, err)
+
+	for _, e := range []*store.EntrySpec{
+		EncodeEntrySpec([]byte("key"), nil, []byte("value")),
+		EncodeReferenceWithAttributes([]byte("tag"), nil, []byte("key"), 0, &ReferenceAttributes{Label: "latest", Group: []byte("tags")}),
+	} {
+		inclusionProof, err := tx.Proof(e.Key)
+		require.NoError(t, err)
+		require.True(t, store.VerifyInclusion(inclusionProof, entrySpecDigest(e), tx.Header().Eh))
+	}
+
+	entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("tag"), SinceTx: vtx.Tx.Header.Id})
+	require.NoError(t, err)
+	require.Equal(t, []byte("key"), entry.Key)
+	require.Equal(t, []byte("value"), entry.Value)
+	require.Equal(t, vtx.Tx.Header.Id, entry.Tx)
+	require.Equal(t, vtx.Tx.Header.Id, entry.ReferencedBy.Tx)
+	require.Equal(t, "latest", entry.ReferencedBy.Label)
+
+	entry, err = db.ResolveGroup(ctx, []byte("tags"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), entry.Value)
+}
+
+func TestStoreReferenceWithReferencePreconditions(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("key1"), Value: []byte("value1")},
+		{Key: []byte("key2"), Value: []byte("value2")},
+	}})
+	require.NoError(t, err)
+
+	t.Run("a reference should not be repointed when the key is not a reference yet", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key1"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustBeReference([]byte("tag"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("tag")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("a reference should be created when the key is not a reference", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key1"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustNotBeReference([]byte("tag"))},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("a reference should not be created twice", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key2"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustNotBeReference([]byte("tag"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("tag")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("key1"), entry.Key)
+	})
+
+	t.Run("an existing reference should be repointed", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key2"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustBeReference([]byte("tag"))},
+		})
+		require.NoError(t, err)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("tag")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("key2"), entry.Key)
+	})
+
+	t.Run("plain keys should not be considered references", func(t *testing.T) {
+		_, err := db.Set(ctx, &schema.SetRequest{
+			KVs:           []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1b")}},
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustBeReference([]byte("key1"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+
+		_, err = db.Set(ctx, &schema.SetRequest{
+			KVs:           []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1b")}},
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustNotBeReference([]byte("key1"))},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid preconditions should be rejected", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key1"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustBeReference(nil)},
+		})
+		require.ErrorIs(t, err, store.ErrInvalidPrecondition)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key1"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustNotBeReference(make([]byte, db.st.MaxKeyLen()+1))},
+		})
+		require.ErrorIs(t, err, store.ErrInvalidPrecondition)
+	})
+}
+
+func TestStoreReferenceWithValuePrecondition(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	t.Run("the reference should be set when the referenced key holds the expected value", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key1"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustHaveValue([]byte("key1"), []byte("value1"))},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("the reference should not be set when the referenced key holds a different value", func(t *testing.T) {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value2")}}})
+		require.NoError(t, err)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag"),
+			ReferencedKey: []byte("key1"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustHaveValue([]byte("key1"), []byte("value1"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:           []byte("tag2"),
+			ReferencedKey: []byte("key1"),
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustHaveValue([]byte("missing"), []byte("value1"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+	})
+
+	t.Run("references should not be taken as holding the value they resolve to", func(t *testing.T) {
+		_, err := db.Set(ctx, &schema.SetRequest{
+			KVs:           []*schema.KeyValue{{Key: []byte("key2"), Value: []byte("value")}},
+			Preconditions: []*schema.Precondition{schema.PreconditionKeyMustHaveValue([]byte("tag"), []byte("value2"))},
+		})
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+	})
+
+	t.Run("the precondition should be checked within the transaction being committed", func(t *testing.T) {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("counter"), Value: []byte("0")}}})
+		require.NoError(t, err)
+
+		var succeeded int32
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 10; j++ {
+					entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("counter")})
+					require.NoError(t, err)
+
+					n, err := strconv.Atoi(string(entry.Value))
+					require.NoError(t, err)
+
+					_, err = db.Set(ctx, &schema.SetRequest{
+						KVs:           []*schema.KeyValue{{Key: []byte("counter"), Value: []byte(strconv.Itoa(n + 1))}},
+						Preconditions: []*schema.Precondition{schema.PreconditionKeyMustHaveValue([]byte("counter"), entry.Value)},
+					})
+					if errors.Is(err, store.ErrPreconditionFailed) {
+						continue
+					}
+					require.NoError(t, err)
+
+					atomic.AddInt32(&succeeded, 1)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("counter")})
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(int(succeeded)), string(entry.Value))
+	})
+}
+
+func TestStoreReferenceExpiration(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key1`), Value: []byte(`value1`)}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte(`live`),
+		ReferencedKey: []byte(`key1`),
+		ExpiresAt:     time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+
+	hdr, err := db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte(`expired`),
+		ReferencedKey: []byte(`key1`),
+		ExpiresAt:     time.Now().Add(-time.Second).Unix(),
+	})
+	require.NoError(t, err)
+
+	t.Run("a reference should be resolved until it expires", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte(`live`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`value1`), entry.Value)
+		require.NotNil(t, entry.ReferencedBy.Metadata.Expiration)
+	})
+
+	t.Run("an expired reference should not be found", func(t *testing.T) {
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte(`expired`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	requireExpiredEntryProof := func(t *testing.T, vEntry *schema.VerifiableEntry) {
+		require.True(t, vEntry.Entry.Expired)
+		require.Equal(t, hdr.Id, vEntry.Entry.Tx)
+		require.Empty(t, vEntry.Entry.Value)
+		require.NotNil(t, vEntry.Entry.Metadata.Expiration)
+
+		txHdr := schema.TxHeaderFromProto(vEntry.VerifiableTx.Tx.Header)
+
+		entryDigest, err := txHdr.TxEntryDigest()
+		require.NoError(t, err)
+
+		// the digest is computed from what is returned, as the value of the entry is not
+		txEntry := store.NewTxEntry(
+			EncodeKey([]byte(`expired`)),
+			schema.KVMetadataFromProto(vEntry.Entry.Metadata),
+			0,
+			schema.DigestFromProto(vEntry.Entry.ValueHash),
+			0,
+		)
+
+		digest, err := entryDigest(txEntry)
+		require.NoError(t, err)
+
+		verifies := store.VerifyInclusion(schema.InclusionProofFromProto(vEntry.InclusionProof), digest, txHdr.Eh)
+		require.True(t, verifies)
+	}
+
+	t.Run("the inclusion of an expired reference should be provable", func(t *testing.T) {
+		vEntry, err := db.VerifiableGet(ctx, &schema.VerifiableGetRequest{
+			KeyRequest: &schema.KeyRequest{Key: []byte(`expired`), AtTx: hdr.Id},
+		})
+		require.NoError(t, err)
+		requireExpiredEntryProof(t, vEntry)
+	})
+
+	t.Run("the inclusion of the latest expired reference should be provable", func(t *testing.T) {
+		vEntry, err := db.VerifiableGet(ctx, &schema.VerifiableGetRequest{
+			KeyRequest: &schema.KeyRequest{Key: []byte(`expired`)},
+		})
+		require.NoError(t, err)
+		requireExpiredEntryProof(t, vEntry)
+	})
+
+	t.Run("a reference should not be proven as expired before it expires", func(t *testing.T) {
+		_, err := db.expiredEntryAt(ctx, []byte(`live`), hdr.Id-1)
+		require.ErrorIs(t, err, store.ErrExpiredEntry)
+	})
+
+	t.Run("expiring references should be set within ExecAll", func(t *testing.T) {
+		_, err := db.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{
+			{Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{
+				Key:           []byte(`expired-op`),
+				ReferencedKey: []byte(`key1`),
+				ExpiresAt:     time.Now().Add(-time.Second).Unix(),
+			}}},
+		}})
+		require.NoError(t, err)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte(`expired-op`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+}
+
+func TestStoreReferenceEffectiveFrom(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	options := DefaultOption().WithDBRootPath(t.TempDir()).WithStoreOptions(store.DefaultOptions().WithTimeFunc(clock))
+	db := makeDbWith(t, "db", options)
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte(`key1`), Value: []byte(`value1`)},
+		{Key: []byte(`key2`), Value: []byte(`value2`)},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte(`pending`),
+		ReferencedKey: []byte(`key1`),
+		EffectiveFrom: now.Unix() + 60,
+	})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte(`ref`),
+		ReferencedKey: []byte(`key1`),
+	})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(context.Background(), &schema.ReferenceRequest{
+		Key:           []byte(`ref`),
+		ReferencedKey: []byte(`key2`),
+		EffectiveFrom: now.Unix() + 60,
+	})
+	require.NoError(t, err)
+
+	t.Run("a reference should not be resolved before it is effective", func(t *testing.T) {
+		_, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`pending`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`key1`), entry.Key)
+		require.Equal(t, []byte(`value1`), entry.Value)
+		require.Zero(t, entry.ReferencedBy.EffectiveFrom)
+	})
+
+	t.Run("a reference should be resolved at a given tx regardless of its effective time", func(t *testing.T) {
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref`), AtRevision: 2})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`key2`), entry.Key)
+	})
+
+	t.Run("other reads should not resolve a reference before it is effective", func(t *testing.T) {
+		ctx := context.Background()
+
+		lastTxID, _ := db.st.CommittedAlh()
+
+		_, err := db.GetAt(ctx, []byte(`pending`), lastTxID)
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		entry, err := db.GetAt(ctx, []byte(`ref`), lastTxID)
+		require.NoError(t, err)
+		require.Equal(t, []byte(`key1`), entry.Key)
+
+		entries, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte(`pe`)})
+		require.NoError(t, err)
+		require.Empty(t, entries.Entries)
+
+		entries, err = db.Scan(ctx, &schema.ScanRequest{Prefix: []byte(`ref`)})
+		require.NoError(t, err)
+		require.Len(t, entries.Entries, 1)
+		require.Equal(t, []byte(`key1`), entries.Entries[0].Key)
+
+		versions, err := db.GetVersions(ctx, []byte(`ref`), 1, 0)
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+		require.Equal(t, []byte(`key1`), versions[0].Key)
+
+		refs, err := db.ListReferences(ctx, &ListReferencesRequest{})
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+		require.Equal(t, []byte(`ref`), refs[0].Key)
+		require.Equal(t, []byte(`key1`), refs[0].ReferencedKey)
+
+		scanned, err := db.ScanReferences(ctx, &schema.ScanRequest{})
+		require.NoError(t, err)
+		require.Len(t, scanned, 1)
+		require.Equal(t, []byte(`ref`), scanned[0].Key)
+		require.Equal(t, []byte(`key1`), scanned[0].ReferencedKey)
+
+		hops, err := db.ResolveReferenceProvenance(ctx, []byte(`ref`))
+		require.NoError(t, err)
+		require.Len(t, hops, 2)
+		require.Equal(t, []byte(`key1`), hops[1].Key)
+
+		_, err = db.ResolveReferenceProvenance(ctx, []byte(`pending`))
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	now = now.Add(time.Minute)
+
+	t.Run("a reference should be resolved once effective", func(t *testing.T) {
+		entry, err := db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`pending`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`value1`), entry.Value)
+
+		entry, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte(`ref`)})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`key2`), entry.Key)
+		require.Equal(t, []byte(`value2`), entry.Value)
+		require.Equal(t, now.Unix(), entry.ReferencedBy.EffectiveFrom)
+	})
+}
+
+func TestStoreReferenceAndResolve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	options := DefaultOption().WithDBRootPath(t.TempDir()).WithStoreOptions(store.DefaultOptions().WithTimeFunc(clock))
+	db := makeDbWith(t, "db", options)
+
+	ctx := context.Background()
+
+	hdr1, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key`), Value: []byte(`value1`)}}})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key`), Value: []byte(`value2`)}}})
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		name          string
+		req           *schema.ReferenceRequest
+		expectedValue []byte
+	}{
+		{
+			name:          "latest",
+			req:           &schema.ReferenceRequest{Key: []byte(`latest`), ReferencedKey: []byte(`key`)},
+			expectedValue: []byte(`value2`),
+		},
+		{
+			name:          "bound",
+			req:           &schema.ReferenceRequest{Key: []byte(`bound`), ReferencedKey: []byte(`key`), AtTx: hdr1.Id, BoundRef: true},
+			expectedValue: []byte(`value1`),
+		},
+		{
+			name:          "no wait",
+			req:           &schema.ReferenceRequest{Key: []byte(`nowait`), ReferencedKey: []byte(`key`), NoWait: true},
+			expectedValue: []byte(`value2`),
+		},
+	} {
+		t.Run("the resolved value of a "+c.name+" reference should be returned", func(t *testing.T) {
+			res, err := db.SetReferenceAndResolve(ctx, c.req)
+			require.NoError(t, err)
+			require.NotNil(t, res.ResolvedValue)
+			require.Equal(t, c.expectedValue, res.ResolvedValue.Value)
+			require.Equal(t, res.Header.Id, res.ResolvedValue.ReferencedBy.Tx)
+
+			entry, err := db.Get(ctx, &schema.KeyRequest{Key: c.req.Key})
+			require.NoError(t, err)
+			require.Equal(t, entry.Key, res.ResolvedValue.Key)
+			require.Equal(t, entry.Value, res.ResolvedValue.Value)
+			require.Equal(t, entry.Tx, res.ResolvedValue.Tx)
+			require.Equal(t, entry.Revision, res.ResolvedValue.Revision)
+		})
+	}
+
+	t.Run("the value should be resolved as of the transaction the reference was set by", func(t *testing.T) {
+		res, err := db.SetReferenceAndResolve(ctx, &schema.ReferenceRequest{Key: []byte(`latest`), ReferencedKey: []byte(`key`)})
+		require.NoError(t, err)
+
+		_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key`), Value: []byte(`value3`)}}})
+		require.NoError(t, err)
+
+		entry, err := db.GetAt(ctx, []byte(`latest`), res.Header.Id)
+		require.NoError(t, err)
+		require.Equal(t, entry.Value, res.ResolvedValue.Value)
+		require.Equal(t, []byte(`value2`), res.ResolvedValue.Value)
+	})
+
+	t.Run("no value should be returned for a reference which is not yet effective", func(t *testing.T) {
+		res, err := db.SetReferenceAndResolve(ctx, &schema.ReferenceRequest{
+			Key:           []byte(`pending`),
+			ReferencedKey: []byte(`key`),
+			EffectiveFrom: now.Unix() + 60,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res.Header)
+		require.Nil(t, res.ResolvedValue)
+	})
+}
+
+func TestStoreReferenceExpectedValueHash(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr1, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key`), Value: []byte(`value1`)}}})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key`), Value: []byte(`value2`)}}})
+	require.NoError(t, err)
+
+	value1Hash := sha256.Sum256([]byte(`value1`))
+	value2Hash := sha256.Sum256([]byte(`value2`))
+
+	t.Run("an expected value hash of invalid length should be rejected", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:               []byte(`ref`),
+			ReferencedKey:     []byte(`key`),
+			ExpectedValueHash: value1Hash[:16],
+		})
+		require.ErrorIs(t, err, store.ErrIllegalArguments)
+	})
+
+	t.Run("a bound reference should be set when the value at the bound transaction matches", func(t *testing.T) {
+		hdr, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:               []byte(`bound`),
+			ReferencedKey:     []byte(`key`),
+			AtTx:              hdr1.Id,
+			BoundRef:          true,
+			ExpectedValueHash: value1Hash[:],
+		})
+		require.NoError(t, err)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte(`bound`), SinceTx: hdr.Id})
+		require.NoError(t, err)
+		require.Equal(t, []byte(`value1`), entry.Value)
+	})
+
+	t.Run("a bound reference should not be set when the value at the bound transaction does not match", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:               []byte(`bound2`),
+			ReferencedKey:     []byte(`key`),
+			AtTx:              hdr1.Id,
+			BoundRef:          true,
+			ExpectedValueHash: value2Hash[:],
+		})
+		require.ErrorIs(t, err, ErrReferencedValueMismatch)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte(`bound2`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("an unbound reference should be checked against the latest value", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:               []byte(`latest`),
+			ReferencedKey:     []byte(`key`),
+			ExpectedValueHash: value1Hash[:],
+		})
+		require.ErrorIs(t, err, ErrReferencedValueMismatch)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:               []byte(`latest`),
+			ReferencedKey:     []byte(`key`),
+			ExpectedValueHash: value2Hash[:],
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("a created target should be checked against the default value", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:                   []byte(`created`),
+			ReferencedKey:         []byte(`missing`),
+			CreateTargetIfMissing: true,
+			DefaultTargetValue:    []byte(`value2`),
+			ExpectedValueHash:     value1Hash[:],
+		})
+		require.ErrorIs(t, err, ErrReferencedValueMismatch)
+
+		_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+			Key:                   []byte(`created`),
+			ReferencedKey:         []byte(`missing`),
+			CreateTargetIfMissing: true,
+			DefaultTargetValue:    []byte(`value1`),
+			ExpectedValueHash:     value1Hash[:],
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("every reference of a batch should be checked", func(t *testing.T) {
+		_, err := db.SetReferences(ctx, &schema.SetReferencesRequest{References: []*schema.ReferenceRequest{
+			{Key: []byte(`batch1`), ReferencedKey: []byte(`key`), ExpectedValueHash: value2Hash[:]},
+			{Key: []byte(`batch2`), ReferencedKey: []byte(`key`), AtTx: hdr1.Id, BoundRef: true, ExpectedValueHash: value2Hash[:]},
+		}})
+		require.ErrorIs(t, err, ErrReferencedValueMismatch)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte(`batch1`)})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+	})
+
+	t.Run("an unbound reference should not be set if the value changes before it is committed", func(t *testing.T) {
+		req := &schema.ReferenceRequest{Key: []byte(`racing`), ReferencedKey: []byte(`key`), ExpectedValueHash: value2Hash[:]}
+
+		check, err := db.checkReference(ctx, req)
+		require.NoError(t, err)
+		require.NotZero(t, check.valueTx)
+
+		_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(`key`), Value: []byte(`value3`)}}})
+		require.NoError(t, err)
+
+		tx, err := db.st.NewWriteOnlyTx(ctx)
+		require.NoError(t, err)
+		defer tx.Cancel()
+
+		err = addReference(tx, req, check)
+		require.NoError(t, err)
+
+		_, err = tx.Commit(ctx)
+		require.ErrorIs(t, err, store.ErrPreconditionFailed)
+	})
+
+	t.Run("expected value hashes should be rejected within ExecAll", func(t *testing.T) {
+		_, err := db.ExecAll(ctx, &schema.ExecAllRequest{Operations: []*schema.Op{
+			{Operation: &schema.Op_Ref{Ref: &schema.ReferenceRequest{
+				Key:               []byte(`op`),
+				ReferencedKey:     []byte(`key`),
+				ExpectedValueHash: value2Hash[:],
+			}}},
+		}})
+		require.ErrorIs(t, err, store.ErrIllegalArguments)
+	})
+}
+
+func TestReferenceMetrics(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("target"), Value: []byte("value1")},
+		{Key: []byte("removed"), Value: []byte("value")},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("target")})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("dangling"), ReferencedKey: []byte("removed")})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("bound"),
+		ReferencedKey: []byte("target"),
+		AtTx:          hdr.Id,
+		BoundRef:      true,
+	})
+	require.NoError(t, err)
+
+	_, err = db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("removed")}})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("target"), Value: []byte("value2")}}})
+	require.NoError(t, err)
+
+	before := db.Metrics()
+
+	_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("target")})
+	require.NoError(t, err)
+
+	require.Equal(t, before, db.Metrics())
+
+	const getCount = 10
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < getCount; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ref")})
+			require.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("dangling")})
+	require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("bound"), FailIfStaleReference: true})
+	require.ErrorIs(t, err, ErrStaleReference)
+
+	after := db.Metrics()
+
+	require.Equal(t, uint64(getCount+2), after.ReferenceResolutions-before.ReferenceResolutions)
+	require.Equal(t, uint64(getCount+2), after.ReferenceHops-before.ReferenceHops)
+	require.Equal(t, uint64(1), after.ReferencesNotFound-before.ReferencesNotFound)
+	require.Equal(t, uint64(1), after.StaleReferenceHits-before.StaleReferenceHits)
+}
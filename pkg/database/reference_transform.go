@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/store"
+)
+
+const MaxReferenceTransformNameLen = 256
+
+var ErrUnknownReferenceTransform = fmt.Errorf("%w: unknown reference transform", store.ErrIllegalArguments)
+var ErrReferenceTransformFailed = errors.New("reference transform failed")
+
+// ReferenceTransform derives the value returned when a reference is resolved from the referenced value.
+// Transforms must be pure i.e. the same input must always produce the same output.
+type ReferenceTransform func(value []byte) ([]byte, error)
+
+// ExtractJSONField returns a transform extracting a top-level field from a JSON object,
+// the field value is returned in its JSON encoding
+func ExtractJSONField(field string) ReferenceTransform {
+	return func(value []byte) ([]byte, error) {
+		var obj map[string]json.RawMessage
+
+		err := json.Unmarshal(value, &obj)
+		if err != nil {
+			return nil, err
+		}
+
+		fieldValue, ok := obj[field]
+		if !ok {
+			return nil, fmt.Errorf("field '%s' not found", field)
+		}
+
+		return fieldValue, nil
+	}
+}
+
+func (d *db) validateReferenceTransform(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	if len(name) > MaxReferenceTransformNameLen {
+		return fmt.Errorf("%w: transform name exceeds the maximum length (%d)", ErrIllegalArguments, MaxReferenceTransformNameLen)
+	}
+
+	_, ok := d.options.referenceTransforms[name]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownReferenceTransform, name)
+	}
+
+	return nil
+}
+
+func (d *db) applyReferenceTransform(name string, value []byte) ([]byte, error) {
+	transform, ok := d.options.referenceTransforms[name]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownReferenceTransform, name)
+	}
+
+	transformed, err := transform(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReferenceTransformFailed, err)
+	}
+
+	if len(transformed) > d.st.MaxValueLen() {
+		return nil, fmt.Errorf("%w: transformed value exceeds the maximum value length", ErrReferenceTransformFailed)
+	}
+
+	return transformed, nil
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// Renaming a key deletes it and sets the new key with the same value and metadata, both within the
+// same transaction that records a rename link from the old key to the new one. The history of both
+// keys is preserved and the link is a regular entry, thus covered by the proofs of the transaction.
+//
+// Non-bound references to a key that was renamed resolve to the key the rename links lead to, up to the
+// configured follow depth. Links are only followed when the referenced key is not found, a key set again
+// after being renamed is resolved as usual. Bound references keep resolving the value at the bound transaction.
+
+const DefaultRenameFollowDepth = 8
+
+func encodeRenameLink(encKey []byte) []byte {
+	return WrapWithPrefix(TrimPrefix(encKey), RenameLinkPrefix)
+}
+
+// EncodeRenameLinkEntrySpec returns the entry recorded when key is renamed to newKey
+func EncodeRenameLinkEntrySpec(key, newKey []byte) *store.EntrySpec {
+	return &store.EntrySpec{
+		Key:   WrapWithPrefix(key, RenameLinkPrefix),
+		Value: EncodeKey(newKey),
+	}
+}
+
+// RenameKey moves the value of oldKey to newKey, which must not exist, and records a rename link
+func (d *db) RenameKey(ctx context.Context, oldKey, newKey []byte) (*schema.TxHeader, error) {
+	if len(oldKey) == 0 || len(newKey) == 0 || bytes.Equal(oldKey, newKey) {
+		return nil, ErrIllegalArguments
+	}
+
+	hdr, err := d.lockedRenameKey(ctx, oldKey, newKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.waitForReplicationAcks(ctx, schema.AckLevel_ACK_DEFAULT, hdr.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+func (d *db) lockedRenameKey(ctx context.Context, oldKey, newKey []byte) (*schema.TxHeader, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	err := d.writeLimiter.acquire(1, len(oldKey)+len(newKey))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := d.newTx(ctx, store.DefaultTxOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Cancel()
+
+	encOldKey := EncodeKey(d.storedKey(oldKey))
+	encNewKey := EncodeKey(d.storedKey(newKey))
+
+	valRef, err := tx.Get(ctx, encOldKey)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Get(ctx, encNewKey)
+	if err == nil {
+		return nil, fmt.Errorf("%w: key '%s' already exists", store.ErrKeyAlreadyExists, newKey)
+	}
+	if !errors.Is(err, store.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	val, err := valRef.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.keyObfuscationEnabled() {
+		_, err = d.setObfuscatedKey(tx, newKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Set(encNewKey, valRef.KVMetadata(), val)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Delete(ctx, encOldKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Set(encodeRenameLink(encOldKey), nil, encNewKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err := tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.TxHeaderToProto(hdr), nil
+}
+
+// RenameReference atomically moves the reference stored under oldKey to newKey. The reference is set under
+// newKey with the same referenced key, bound transaction, attributes and metadata and oldKey is deleted,
+// both within the same transaction. newKey must not exist unless allowOverwrite is set, in which case it can
+// only be replaced when it's a reference itself. No rename link is recorded as references can not be referenced.
+func (d *db) RenameReference(ctx context.Context, oldKey, newKey []byte, allowOverwrite bool) (*schema.TxHeader, error) {
+	if len(oldKey) == 0 || len(newKey) == 0 || bytes.Equal(oldKey, newKey) {
+		return nil, ErrIllegalArguments
+	}
+
+	hdr, err := d.lockedRenameReference(ctx, oldKey, newKey, allowOverwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.waitForReplicationAcks(ctx, schema.AckLevel_ACK_DEFAULT, hdr.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+func (d *db) lockedRenameReference(ctx context.Context, oldKey, newKey []byte, allowOverwrite bool) (*schema.TxHeader, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.isReplica() {
+		return nil, ErrIsReplica
+	}
+
+	if d.keyObfuscationEnabled() {
+		return nil, ErrKeyObfuscationUnsupported
+	}
+
+	err := d.writeLimiter.acquire(1, len(oldKey)+len(newKey))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := d.newTx(ctx, store.DefaultTxOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Cancel()
+
+	encOldKey := EncodeKey(oldKey)
+	encNewKey := EncodeKey(newKey)
+
+	valRef, err := tx.Get(ctx, encOldKey)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := valRef.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isReferenceValue(val) {
+		return nil, fmt.Errorf("%w: key '%s' is not a reference", ErrIllegalArguments, oldKey)
+	}
+
+	_, _, attrs, err := unwrapReferenceValue(val)
+	if err != nil {
+		return nil, err
+	}
+
+	newValRef, err := tx.Get(ctx, encNewKey)
+	if err == nil {
+		if !allowOverwrite {
+			return nil, fmt.Errorf("%w: key '%s' already exists", store.ErrKeyAlreadyExists, newKey)
+		}
+
+		newVal, err := newValRef.Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isReferenceValue(newVal) {
+			return nil, ErrFinalKeyCannotBeConvertedIntoReference
+		}
+	} else if !errors.Is(err, store.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	err = tx.Set(encNewKey, valRef.KVMetadata(), val)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Delete(ctx, encOldKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addReferenceGroupMember(tx, &schema.ReferenceRequest{Key: newKey, Group: attrs.Group, Priority: attrs.Priority})
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err := tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.TxHeaderToProto(hdr), nil
+}
+
+// getRenamed follows the rename links of a key that was not found
+func (d *db) getRenamed(ctx context.Context, encKey []byte, resolved int, index store.KeyIndex, skipIntegrityCheck bool) (*schema.Entry, error) {
+	for depth := 0; depth < d.options.renameFollowDepth; depth++ {
+		valRef, err := index.Get(ctx, encodeRenameLink(encKey))
+		if err != nil {
+			return nil, err
+		}
+
+		encKey, err = valRef.Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := d.getAtTx(ctx, encKey, 0, resolved, index, 0, skipIntegrityCheck)
+		if !errors.Is(err, store.ErrKeyNotFound) {
+			return entry, err
+		}
+	}
+
+	return nil, store.ErrKeyNotFound
+}
+
+// renameLinkProofs proves the rename links followed from the referenced key to the key of the entry the reference
+// resolves to, each one linked with the transaction the reference was set at
+func (d *db) renameLinkProofs(ctx context.Context, refKey, key []byte, refTxHdr *store.TxHeader) ([]*schema.RenameLinkProof, error) {
+	tx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(tx)
+
+	var proofs []*schema.RenameLinkProof
+
+	for depth := 0; depth < d.options.renameFollowDepth && !bytes.Equal(refKey, key); depth++ {
+		linkKey := encodeRenameLink(EncodeKey(refKey))
+
+		valRef, err := d.st.Get(ctx, linkKey)
+		if err != nil {
+			return nil, err
+		}
+
+		encNewKey, err := valRef.Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		err = d.st.ReadTx(valRef.Tx(), false, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		inclusionProof, err := tx.Proof(linkKey)
+		if err != nil {
+			return nil, err
+		}
+
+		sourceTxHdr, targetTxHdr := tx.Header(), refTxHdr
+		if sourceTxHdr.ID > targetTxHdr.ID {
+			sourceTxHdr, targetTxHdr = targetTxHdr, sourceTxHdr
+		}
+
+		dualProof, err := d.st.DualProof(sourceTxHdr, targetTxHdr)
+		if err != nil {
+			return nil, err
+		}
+
+		refKey = TrimPrefix(encNewKey)
+
+		proofs = append(proofs, &schema.RenameLinkProof{
+			Tx:             valRef.Tx(),
+			Key:            refKey,
+			InclusionProof: schema.InclusionProofToProto(inclusionProof),
+			DualProof:      schema.DualProofToProto(dualProof),
+		})
+	}
+
+	if !bytes.Equal(refKey, key) {
+		return nil, fmt.Errorf("%w: rename links do not lead to '%s'", ErrIllegalState, key)
+	}
+
+	return proofs, nil
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameKey(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("config-v1"), Value: []byte("v1")}}})
+	require.NoError(t, err)
+
+	refHdr, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("latest"), ReferencedKey: []byte("config-v1")})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("pinned"), ReferencedKey: []byte("config-v1"), AtTx: refHdr.Id - 1, BoundRef: true})
+	require.NoError(t, err)
+
+	hdr, err := db.RenameKey(ctx, []byte("config-v1"), []byte("config-v2"))
+	require.NoError(t, err)
+
+	t.Run("the old key should be deleted and its history preserved", func(t *testing.T) {
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("config-v1")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("config-v1"), AtTx: refHdr.Id - 1})
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), entry.Value)
+
+		entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("config-v2")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), entry.Value)
+		require.Equal(t, hdr.Id, entry.Tx)
+	})
+
+	t.Run("references should follow the rename", func(t *testing.T) {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("config-v2"), Value: []byte("v2")}}})
+		require.NoError(t, err)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("latest")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("config-v2"), entry.Key)
+		require.Equal(t, []byte("v2"), entry.Value)
+		require.Equal(t, []byte("latest"), entry.ReferencedBy.Key)
+
+		entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("pinned")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("config-v1"), entry.Key)
+		require.Equal(t, []byte("v1"), entry.Value)
+	})
+
+	t.Run("chained renames should be followed", func(t *testing.T) {
+		_, err := db.RenameKey(ctx, []byte("config-v2"), []byte("config-v3"))
+		require.NoError(t, err)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("latest")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("config-v3"), entry.Key)
+		require.Equal(t, []byte("v2"), entry.Value)
+
+		vEntry, err := db.VerifiableGet(ctx, &schema.VerifiableGetRequest{KeyRequest: &schema.KeyRequest{Key: []byte("latest")}})
+		require.NoError(t, err)
+		require.Equal(t, []byte("config-v3"), vEntry.Entry.Key)
+		require.Equal(t, []byte("config-v1"), vEntry.Entry.ReferencedBy.ReferencedKey)
+
+		vEntry, err = db.VerifiableGet(ctx, &schema.VerifiableGetRequest{
+			KeyRequest:           &schema.KeyRequest{Key: []byte("latest")},
+			ProveReferencedEntry: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, vEntry.ReferencedEntryProof.RenameLinkProofs, 2)
+		require.Equal(t, hdr.Id, vEntry.ReferencedEntryProof.RenameLinkProofs[0].Tx)
+		require.Equal(t, []byte("config-v2"), vEntry.ReferencedEntryProof.RenameLinkProofs[0].Key)
+		require.Equal(t, []byte("config-v3"), vEntry.ReferencedEntryProof.RenameLinkProofs[1].Key)
+	})
+
+	t.Run("invalid renames should be rejected", func(t *testing.T) {
+		_, err := db.RenameKey(ctx, []byte("missing"), []byte("other"))
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("other"), Value: []byte("other")}}})
+		require.NoError(t, err)
+
+		_, err = db.RenameKey(ctx, []byte("config-v3"), []byte("other"))
+		require.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+		_, err = db.RenameKey(ctx, []byte("other"), []byte("other"))
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
+func TestRenameKeyFollowDepth(t *testing.T) {
+	options := DefaultOption().WithDBRootPath(t.TempDir()).WithRenameFollowDepth(1)
+	db := makeDbWith(t, "db", options)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k1"), Value: []byte("v")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("k1")})
+	require.NoError(t, err)
+
+	_, err = db.RenameKey(ctx, []byte("k1"), []byte("k2"))
+	require.NoError(t, err)
+
+	entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ref")})
+	require.NoError(t, err)
+	require.Equal(t, []byte("k2"), entry.Key)
+
+	_, err = db.RenameKey(ctx, []byte("k2"), []byte("k3"))
+	require.NoError(t, err)
+
+	_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("ref")})
+	require.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+func TestRenameKeyAtTx(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k1"), Value: []byte("v1")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("ref"), ReferencedKey: []byte("k1")})
+	require.NoError(t, err)
+
+	hdr, err := db.RenameKey(ctx, []byte("k1"), []byte("k2"))
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k1"), Value: []byte("v3")}}})
+	require.NoError(t, err)
+
+	_, err = db.RenameKey(ctx, []byte("k1"), []byte("k3"))
+	require.NoError(t, err)
+
+	entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ref")})
+	require.NoError(t, err)
+	require.Equal(t, []byte("k3"), entry.Key)
+
+	// the rename link is read as of the transaction, not the one recorded afterwards
+	entry, err = db.GetAt(ctx, []byte("ref"), hdr.Id)
+	require.NoError(t, err)
+	require.Equal(t, []byte("k2"), entry.Key)
+	require.Equal(t, []byte("v1"), entry.Value)
+}
+
+func TestRenameReference(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("config"), Value: []byte("v1")}}})
+	require.NoError(t, err)
+
+	_, err = db.SetReference(ctx, &schema.ReferenceRequest{
+		Key:           []byte("stable"),
+		ReferencedKey: []byte("config"),
+		AtTx:          hdr.Id,
+		BoundRef:      true,
+		Label:         "stable config",
+	})
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("config"), Value: []byte("v2")}}})
+	require.NoError(t, err)
+
+	renameHdr, err := db.RenameReference(ctx, []byte("stable"), []byte("production"), false)
+	require.NoError(t, err)
+
+	t.Run("the reference should be moved with the same configuration", func(t *testing.T) {
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("stable")})
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("production")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), entry.Value)
+
+		ref, err := db.GetReference(ctx, &schema.KeyRequest{Key: []byte("production")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("config"), ref.ReferencedKey)
+		require.Equal(t, hdr.Id, ref.AtTx)
+		require.Equal(t, "stable config", ref.Label)
+		require.Equal(t, renameHdr.Id, ref.Tx)
+	})
+
+	t.Run("the rename should be applied within a single transaction", func(t *testing.T) {
+		tx, err := db.TxByID(ctx, &schema.TxRequest{Tx: renameHdr.Id})
+		require.NoError(t, err)
+		require.Len(t, tx.Entries, 2)
+
+		// the old key is deleted by the same transaction that sets the new one
+		oldHistory, err := db.History(ctx, &schema.HistoryRequest{Key: []byte("stable")})
+		require.NoError(t, err)
+		require.Len(t, oldHistory.Entries, 2)
+		require.Equal(t, renameHdr.Id, oldHistory.Entries[1].Tx)
+		require.True(t, oldHistory.Entries[1].Metadata.Deleted)
+
+		newHistory, err := db.History(ctx, &schema.HistoryRequest{Key: []byte("production")})
+		require.NoError(t, err)
+		require.Len(t, newHistory.Entries, 1)
+		require.Equal(t, renameHdr.Id, newHistory.Entries[0].Tx)
+	})
+
+	t.Run("existing keys should only be overwritten when allowed", func(t *testing.T) {
+		_, err := db.SetReference(ctx, &schema.ReferenceRequest{Key: []byte("latest"), ReferencedKey: []byte("config")})
+		require.NoError(t, err)
+
+		_, err = db.RenameReference(ctx, []byte("latest"), []byte("production"), false)
+		require.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+		_, err = db.RenameReference(ctx, []byte("latest"), []byte("config"), true)
+		require.ErrorIs(t, err, ErrFinalKeyCannotBeConvertedIntoReference)
+
+		_, err = db.RenameReference(ctx, []byte("latest"), []byte("production"), true)
+		require.NoError(t, err)
+
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("production")})
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), entry.Value)
+	})
+
+	t.Run("invalid renames should be rejected", func(t *testing.T) {
+		_, err := db.RenameReference(ctx, []byte("missing"), []byte("other"), false)
+		require.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		_, err = db.RenameReference(ctx, []byte("config"), []byte("other"), false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.RenameReference(ctx, []byte("production"), []byte("production"), false)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
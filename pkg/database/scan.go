@@ -17,20 +17,177 @@ limitations under the License.
 package database
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/api/schema"
+	"google.golang.org/protobuf/proto"
 )
 
+const (
+	// MaxKeyPatternLen is the maximum length of the key pattern of a scan
+	MaxKeyPatternLen = 256
+	// MaxKeyPatternWildcards is the maximum number of '*' wildcards of the key pattern of a scan,
+	// as the cost of matching a key grows with them
+	MaxKeyPatternWildcards = 8
+)
+
+var ErrInvalidKeyPattern = fmt.Errorf("%w: invalid key pattern", ErrIllegalArguments)
+
 // Scan ...
-func (d *db) Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries, error) {
+func (d *db) Scan(ctx context.Context, req *schema.ScanRequest) (entries *schema.Entries, err error) {
+	var snapTxID uint64
+
+	if d.options.slowOperationThreshold > 0 {
+		defer func(start time.Time) {
+			d.observeSlowOperation(ScanOperation, len(req.GetPrefix()), start, snapTxID)
+		}(time.Now())
+	}
+
+	return d.scan(ctx, req, &snapTxID)
+}
+
+// ScanByTx scans as Scan does with GroupByTx set, each group is sent as soon as its entries are resolved.
+// Only the ids of the transactions of the scanned entries are kept in memory while scanning, the entries
+// of each group are then read from its transaction. The database is not locked while groups are sent.
+func (d *db) ScanByTx(ctx context.Context, req *schema.ScanRequest, send func(*schema.TxGroup) error) error {
+	if req == nil || send == nil {
+		return ErrIllegalArguments
+	}
+
+	req = proto.Clone(req).(*schema.ScanRequest)
+	req.GroupByTx = true
+
+	d.mutex.RLock()
+
+	s, err := d.newScan(ctx, req)
+	if err != nil {
+		d.mutex.RUnlock()
+		return err
+	}
+	defer s.close()
+
+	groups, err := d.newTxGroupReader(ctx, s)
+
+	d.mutex.RUnlock()
+
+	if err != nil {
+		return err
+	}
+	defer groups.close()
+
+	for {
+		d.mutex.RLock()
+		group, err := groups.read(ctx)
+		d.mutex.RUnlock()
+
+		if errors.Is(err, store.ErrNoMoreEntries) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = send(group)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (d *db) scan(ctx context.Context, req *schema.ScanRequest, snapTxID *uint64) (entries *schema.Entries, err error) {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
 
+	s, err := d.newScan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+
+	*snapTxID = s.snap.Ts()
+
+	if req.GroupByPrefixDepth > 0 {
+		return scanGroups(ctx, s.r, req.Prefix, int(req.GroupByPrefixDepth), s.limit)
+	}
+
+	entries = &schema.Entries{}
+
+	if req.GroupByTx {
+		groups, err := d.newTxGroupReader(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		defer groups.close()
+
+		for {
+			group, err := groups.read(ctx)
+			if errors.Is(err, store.ErrNoMoreEntries) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			entries.TxGroups = append(entries.TxGroups, group)
+		}
+
+		return entries, nil
+	}
+
+	for l := 1; l <= s.limit; l++ {
+		key, valRef, err := s.r.Read(ctx)
+		if errors.Is(err, store.ErrNoMoreEntries) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := d.scannedEntry(ctx, key, valRef, s.snap, s.keyMapping, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if e != nil {
+			entries.Entries = append(entries.Entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+// scanState holds what the entries of a scan are read from, it must be closed once the scan is done
+type scanState struct {
+	req        *schema.ScanRequest
+	limit      int
+	filters    []store.FilterFn
+	snap       *store.Snapshot
+	r          store.KeyReader
+	keyMapping *keyMappingReader
+}
+
+func (s *scanState) close() {
+	if s.keyMapping != nil {
+		s.keyMapping.Close()
+	}
+	s.r.Close()
+	s.snap.Close()
+}
+
+// newScan validates the scan request and opens the snapshot and the key reader the entries are read from
+func (d *db) newScan(ctx context.Context, req *schema.ScanRequest) (*scanState, error) {
 	currTxID, _ := d.st.CommittedAlh()
 
 	if req == nil || req.SinceTx > currTxID {
@@ -42,6 +199,25 @@ func (d *db) Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries
 			ErrResultSizeLimitExceeded, req.Limit, d.maxResultSize)
 	}
 
+	err := validateKeyPattern(req.KeyPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GroupByPrefixDepth > 0 && req.GroupByTx {
+		return nil, fmt.Errorf("%w: grouping by prefix and by transaction can not be combined", ErrIllegalArguments)
+	}
+
+	if req.GroupByPrefixDepth > 0 && (req.Desc || req.Offset > 0) {
+		// group digests are computed in ascending key order over the whole group
+		return nil, fmt.Errorf("%w: grouping by prefix is not supported along with descending order or offset", ErrIllegalArguments)
+	}
+
+	if d.keyObfuscationEnabled() && (len(req.Prefix) > 0 || len(req.SeekKey) > 0 || len(req.EndKey) > 0 || req.KeyPattern != "" || req.GroupByPrefixDepth > 0) {
+		// obfuscated keys do not preserve the ordering of the original ones
+		return nil, ErrKeyObfuscationUnsupported
+	}
+
 	limit := int(req.Limit)
 	if req.Limit == 0 {
 		limit = d.maxResultSize
@@ -57,11 +233,24 @@ func (d *db) Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries
 		endKey = EncodeKey(req.EndKey)
 	}
 
+	filters := make([]store.FilterFn, 0, 2)
+	if !req.IncludeExpired {
+		filters = append(filters, store.IgnoreExpired)
+	}
+	if !req.IncludeDeleted {
+		filters = append(filters, store.IgnoreDeleted)
+	}
+
 	snap, err := d.snapshotSince(ctx, []byte{SetKeyPrefix}, req.SinceTx)
 	if err != nil {
 		return nil, err
 	}
-	defer snap.Close()
+
+	offset := req.Offset
+	if req.KeyPattern != "" {
+		// the offset is applied to matching keys
+		offset = 0
+	}
 
 	r, err := snap.NewKeyReader(
 		store.KeyReaderSpec{
@@ -69,19 +258,242 @@ func (d *db) Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries
 			EndKey:        endKey,
 			Prefix:        EncodeKey(req.Prefix),
 			DescOrder:     req.Desc,
-			Filters:       []store.FilterFn{store.IgnoreExpired, store.IgnoreDeleted},
+			Filters:       filters,
 			InclusiveSeek: req.InclusiveSeek,
 			InclusiveEnd:  req.InclusiveEnd,
-			Offset:        req.Offset,
+			Offset:        offset,
 		})
 	if err != nil {
+		snap.Close()
 		return nil, err
 	}
-	defer r.Close()
 
+	if req.KeyPattern != "" {
+		r = &keyPatternReader{KeyReader: r, pattern: req.KeyPattern, offset: req.Offset}
+	}
+
+	s := &scanState{
+		req:     req,
+		limit:   limit,
+		filters: filters,
+		snap:    snap,
+		r:       r,
+	}
+
+	if d.keyObfuscationEnabled() {
+		s.keyMapping, err = d.newKeyMappingReader(ctx, snap.Ts())
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// scannedEntry resolves the entry read by a scan, nil is returned for entries to be skipped
+func (d *db) scannedEntry(
+	ctx context.Context,
+	key []byte,
+	valRef store.ValueRef,
+	snap *store.Snapshot,
+	keyMapping *keyMappingReader,
+	req *schema.ScanRequest,
+) (*schema.Entry, error) {
+	var e *schema.Entry
+	var err error
+
+	md := valRef.KVMetadata()
+
+	if md != nil && ((req.IncludeDeleted && md.Deleted()) || (req.IncludeExpired && md.ExpiredAt(time.Now()))) {
+		e, err = entryWithStatus(key, valRef)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		e, err = d.getAtTx(ctx, key, valRef.Tx(), 0, snap, valRef.HC(), true)
+		if err == nil {
+			// references are only resolved once effective
+			e, err = d.effectiveReference(ctx, key, e, snap)
+		}
+		if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, io.EOF) {
+			return nil, nil // ignore deleted or truncated ones (referenced key may have been deleted or truncated)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if keyMapping != nil {
+		e.Key, err = keyMapping.key(ctx, e.Key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+type scannedKey struct {
+	key    []byte
+	valRef store.ValueRef
+}
+
+// txGroupReader reads the scanned entries grouped by the transaction that last wrote their keys, in ascending
+// transaction order. The keys are read once to collect the ids of the transactions and the bounds of the
+// scanned keys, the entries of each group are then read from its transaction, thus keys and values are never
+// kept in memory but the ones of the group being read.
+type txGroupReader struct {
+	d *db
+	s *scanState
+
+	txIDs []uint64
+
+	prefix []byte
+	// minKey and maxKey are the lowest and highest scanned keys
+	minKey []byte
+	maxKey []byte
+
+	tx *store.Tx
+}
+
+func (d *db) newTxGroupReader(ctx context.Context, s *scanState) (*txGroupReader, error) {
+	g := &txGroupReader{
+		d:      d,
+		s:      s,
+		prefix: EncodeKey(s.req.Prefix),
+	}
+
+	txIDs := make(map[uint64]struct{})
+
+	for l := 1; l <= s.limit; l++ {
+		key, valRef, err := s.r.Read(ctx)
+		if errors.Is(err, store.ErrNoMoreEntries) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if g.minKey == nil || bytes.Compare(key, g.minKey) < 0 {
+			g.minKey = key
+		}
+		if g.maxKey == nil || bytes.Compare(key, g.maxKey) > 0 {
+			g.maxKey = key
+		}
+
+		txIDs[valRef.Tx()] = struct{}{}
+	}
+
+	g.txIDs = make([]uint64, 0, len(txIDs))
+	for txID := range txIDs {
+		g.txIDs = append(g.txIDs, txID)
+	}
+	sort.Slice(g.txIDs, func(i, j int) bool { return g.txIDs[i] < g.txIDs[j] })
+
+	if len(g.txIDs) > 0 {
+		tx, err := d.allocTx()
+		if err != nil {
+			return nil, err
+		}
+		g.tx = tx
+	}
+
+	return g, nil
+}
+
+// read returns the next group, groups without entries to be returned are skipped
+func (g *txGroupReader) read(ctx context.Context) (*schema.TxGroup, error) {
+	for len(g.txIDs) > 0 {
+		txID := g.txIDs[0]
+		g.txIDs = g.txIDs[1:]
+
+		err := g.d.st.ReadTx(txID, false, g.tx)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []scannedKey
+
+		for _, e := range g.tx.Entries() {
+			if !g.scanned(e.Key()) {
+				continue
+			}
+
+			valRef, err := g.s.snap.GetWithFilters(ctx, e.Key(), g.s.filters...)
+			if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, store.ErrExpiredEntry) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if valRef.Tx() != txID {
+				// the key was written again by a later transaction
+				continue
+			}
+
+			keys = append(keys, scannedKey{key: e.Key(), valRef: valRef})
+		}
+
+		// keys of the same transaction are returned in scan order
+		sort.Slice(keys, func(i, j int) bool {
+			return (bytes.Compare(keys[i].key, keys[j].key) < 0) != g.s.req.Desc
+		})
+
+		group := &schema.TxGroup{Tx: txID}
+
+		for _, k := range keys {
+			e, err := g.d.scannedEntry(ctx, k.key, k.valRef, g.s.snap, g.s.keyMapping, g.s.req)
+			if err != nil {
+				return nil, err
+			}
+
+			if e != nil {
+				group.Entries = append(group.Entries, e)
+			}
+		}
+
+		if len(group.Entries) > 0 {
+			return group, nil
+		}
+	}
+
+	return nil, store.ErrNoMoreEntries
+}
+
+// scanned returns true if the key was read by the scan, as scanned keys are read in key order any key
+// between the lowest and the highest scanned keys which is selected by the scan was read
+func (g *txGroupReader) scanned(key []byte) bool {
+	if !bytes.HasPrefix(key, g.prefix) || bytes.Compare(key, g.minKey) < 0 || bytes.Compare(key, g.maxKey) > 0 {
+		return false
+	}
+
+	if g.s.req.KeyPattern == "" {
+		return true
+	}
+
+	// the pattern was validated, thus no error is returned
+	match, _ := path.Match(g.s.req.KeyPattern, string(TrimPrefix(key)))
+	return match
+}
+
+func (g *txGroupReader) close() {
+	if g.tx != nil {
+		g.d.releaseTx(g.tx)
+	}
+}
+
+// scanGroups groups the entries by their sub-prefix below the prefix. The sub-prefix of a key spans up to depth
+// '/' delimited levels after the prefix, including the trailing delimiter. The digest of a group is computed
+// over the keys and stored values of its entries, thus it only changes when any of them is changed.
+func scanGroups(ctx context.Context, r store.KeyReader, prefix []byte, depth int, limit int) (*schema.Entries, error) {
 	entries := &schema.Entries{}
 
-	for l := 1; l <= limit; l++ {
+	var group *schema.PrefixGroup
+	var groupHash hash.Hash
+
+	for {
 		key, valRef, err := r.Read(ctx)
 		if errors.Is(err, store.ErrNoMoreEntries) {
 			break
@@ -90,16 +502,136 @@ func (d *db) Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries
 			return nil, err
 		}
 
-		e, err := d.getAtTx(ctx, key, valRef.Tx(), 0, snap, valRef.HC(), true)
-		if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, io.EOF) {
-			continue // ignore deleted or truncated ones (referenced key may have been deleted or truncated)
+		key = TrimPrefix(key)
+
+		subPrefix := keySubPrefix(key, len(prefix), depth)
+
+		if group == nil || !bytes.Equal(group.Prefix, subPrefix) {
+			if group != nil {
+				group.Digest = groupHash.Sum(nil)
+			}
+
+			if len(entries.Groups) == limit {
+				group = nil
+				break
+			}
+
+			group = &schema.PrefixGroup{Prefix: subPrefix}
+			groupHash = sha256.New()
+
+			entries.Groups = append(entries.Groups, group)
 		}
-		if err != nil {
+
+		val, err := valRef.ResolveContext(ctx)
+		if err != nil && !errors.Is(err, store.ErrExpiredEntry) {
 			return nil, err
 		}
 
-		entries.Entries = append(entries.Entries, e)
+		var keyLen [4]byte
+		binary.BigEndian.PutUint32(keyLen[:], uint32(len(key)))
+
+		valHash := sha256.Sum256(val)
+
+		groupHash.Write(keyLen[:])
+		groupHash.Write(key)
+		groupHash.Write(valHash[:])
+
+		group.Count++
+	}
+
+	if group != nil {
+		group.Digest = groupHash.Sum(nil)
 	}
 
 	return entries, nil
 }
+
+// keySubPrefix returns the key up to the end of the depth-th '/' delimited level after the first n bytes
+func keySubPrefix(key []byte, n, depth int) []byte {
+	for i := n; i < len(key); i++ {
+		if key[i] != '/' {
+			continue
+		}
+
+		depth--
+		if depth == 0 {
+			return key[:i+1]
+		}
+	}
+
+	return key
+}
+
+// entryWithStatus builds the entry of a deleted or expired key without resolving it,
+// status is conveyed by the entry metadata and the expired flag
+func entryWithStatus(key []byte, valRef store.ValueRef) (*schema.Entry, error) {
+	val, err := valRef.Resolve()
+	if err != nil && !errors.Is(err, store.ErrExpiredEntry) {
+		return nil, err
+	}
+	if len(val) > 0 {
+		val = TrimPrefix(val)
+	}
+
+	return &schema.Entry{
+		Tx:       valRef.Tx(),
+		Key:      TrimPrefix(key),
+		Metadata: schema.KVMetadataToProto(valRef.KVMetadata()),
+		Value:    val,
+		Expired:  errors.Is(err, store.ErrExpiredEntry),
+		Revision: valRef.HC(),
+	}, nil
+}
+
+func validateKeyPattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+
+	if len(pattern) > MaxKeyPatternLen {
+		return fmt.Errorf("%w: pattern length exceeds the maximum allowed (%d)", ErrInvalidKeyPattern, MaxKeyPatternLen)
+	}
+
+	if strings.Count(pattern, "*") > MaxKeyPatternWildcards {
+		return fmt.Errorf("%w: number of wildcards exceeds the maximum allowed (%d)", ErrInvalidKeyPattern, MaxKeyPatternWildcards)
+	}
+
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidKeyPattern, err)
+	}
+
+	return nil
+}
+
+// keyPatternReader skips the keys not matching the pattern. Keys are read within the bounds of the
+// underlying reader, thus candidates are still selected by prefix, seek and end keys.
+type keyPatternReader struct {
+	store.KeyReader
+
+	pattern string
+	offset  uint64
+	skipped uint64
+}
+
+func (r *keyPatternReader) Read(ctx context.Context) ([]byte, store.ValueRef, error) {
+	for {
+		key, valRef, err := r.KeyReader.Read(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the pattern was validated, thus no error is returned
+		match, _ := path.Match(r.pattern, string(TrimPrefix(key)))
+		if !match {
+			continue
+		}
+
+		if r.skipped < r.offset {
+			r.skipped++
+			continue
+		}
+
+		return key, valRef, nil
+	}
+}
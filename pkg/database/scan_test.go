@@ -19,7 +19,9 @@ package database
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/api/schema"
@@ -362,3 +364,351 @@ func TestStoreScanWithTruncation(t *testing.T) {
 		require.Equal(t, list.Entries[4].Key, []byte(`prefix:suffix5`))
 	})
 }
+
+func TestStoreScanIncludeExpiredAndDeleted(t *testing.T) {
+	db := makeDb(t)
+
+	_, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte(`prefix:active`), Value: []byte(`item1`)},
+		{Key: []byte(`prefix:deleted`), Value: []byte(`item2`)},
+		{
+			Key:   []byte(`prefix:expired`),
+			Value: []byte(`item3`),
+			Metadata: &schema.KVMetadata{
+				Expiration: &schema.Expiration{
+					ExpiresAt: time.Now().Unix() - 1,
+				},
+			},
+		},
+	}})
+	require.NoError(t, err)
+
+	_, err = db.Delete(context.Background(), &schema.DeleteKeysRequest{Keys: [][]byte{[]byte(`prefix:deleted`)}})
+	require.NoError(t, err)
+
+	t.Run("deleted and expired entries should be excluded by default", func(t *testing.T) {
+		list, err := db.Scan(context.Background(), &schema.ScanRequest{Prefix: []byte(`prefix:`)})
+		require.NoError(t, err)
+		require.Len(t, list.Entries, 1)
+		require.Equal(t, []byte(`prefix:active`), list.Entries[0].Key)
+	})
+
+	t.Run("deleted entries should be included when requested", func(t *testing.T) {
+		list, err := db.Scan(context.Background(), &schema.ScanRequest{
+			Prefix:         []byte(`prefix:`),
+			IncludeDeleted: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, list.Entries, 2)
+		require.Equal(t, []byte(`prefix:active`), list.Entries[0].Key)
+		require.Equal(t, []byte(`prefix:deleted`), list.Entries[1].Key)
+		require.True(t, list.Entries[1].Metadata.Deleted)
+		require.Nil(t, list.Entries[1].Value)
+		require.EqualValues(t, 2, list.Entries[1].Revision)
+	})
+
+	t.Run("expired entries should be included when requested", func(t *testing.T) {
+		list, err := db.Scan(context.Background(), &schema.ScanRequest{
+			Prefix:         []byte(`prefix:`),
+			IncludeExpired: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, list.Entries, 2)
+		require.Equal(t, []byte(`prefix:active`), list.Entries[0].Key)
+		require.Equal(t, []byte(`prefix:expired`), list.Entries[1].Key)
+		require.True(t, list.Entries[1].Expired)
+		require.Nil(t, list.Entries[1].Value)
+	})
+
+	t.Run("deleted and expired entries should be included when requested", func(t *testing.T) {
+		list, err := db.Scan(context.Background(), &schema.ScanRequest{
+			Prefix:         []byte(`prefix:`),
+			IncludeExpired: true,
+			IncludeDeleted: true,
+			Desc:           true,
+		})
+		require.NoError(t, err)
+		require.Len(t, list.Entries, 3)
+		require.Equal(t, []byte(`prefix:expired`), list.Entries[0].Key)
+		require.True(t, list.Entries[0].Expired)
+		require.Equal(t, []byte(`prefix:deleted`), list.Entries[1].Key)
+		require.True(t, list.Entries[1].Metadata.Deleted)
+		require.False(t, list.Entries[1].Expired)
+		require.Equal(t, []byte(`prefix:active`), list.Entries[2].Key)
+		require.Equal(t, []byte(`item1`), list.Entries[2].Value)
+	})
+}
+
+func TestStoreScanKeyPattern(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	for _, key := range []string{
+		"user/alice/profile",
+		"user/alice/session",
+		"user/bob/session",
+		"user/bob/tokens/session",
+		"user/carol/session",
+		"users/dave/session",
+	} {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(key), Value: []byte(key)}}})
+		require.NoError(t, err)
+	}
+
+	keysOf := func(entries *schema.Entries) []string {
+		keys := make([]string, len(entries.Entries))
+		for i, e := range entries.Entries {
+			keys[i] = string(e.Key)
+		}
+		return keys
+	}
+
+	t.Run("invalid patterns should be rejected", func(t *testing.T) {
+		_, err := db.Scan(ctx, &schema.ScanRequest{KeyPattern: "user/[a"})
+		require.ErrorIs(t, err, ErrInvalidKeyPattern)
+
+		_, err = db.Scan(ctx, &schema.ScanRequest{KeyPattern: strings.Repeat("*/", MaxKeyPatternWildcards+1)})
+		require.ErrorIs(t, err, ErrInvalidKeyPattern)
+
+		_, err = db.Scan(ctx, &schema.ScanRequest{KeyPattern: strings.Repeat("a", MaxKeyPatternLen+1)})
+		require.ErrorIs(t, err, ErrInvalidKeyPattern)
+	})
+
+	t.Run("only keys matching the pattern should be returned", func(t *testing.T) {
+		entries, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("user/"), KeyPattern: "user/*/session"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user/alice/session", "user/bob/session", "user/carol/session"}, keysOf(entries))
+	})
+
+	t.Run("offset and limit should apply to matching keys", func(t *testing.T) {
+		entries, err := db.Scan(ctx, &schema.ScanRequest{KeyPattern: "user/*/session", Offset: 1, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user/bob/session"}, keysOf(entries))
+
+		entries, err = db.Scan(ctx, &schema.ScanRequest{KeyPattern: "user/*/session", SeekKey: []byte("user/alice/session"), Desc: true})
+		require.NoError(t, err)
+		require.Empty(t, entries.Entries)
+
+		entries, err = db.Scan(ctx, &schema.ScanRequest{KeyPattern: "user/*/session", SeekKey: []byte("user/alice/session")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user/bob/session", "user/carol/session"}, keysOf(entries))
+	})
+}
+
+func TestStoreScanGroupByPrefixDepth(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	set := func(key, value string) {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte(key), Value: []byte(value)}}})
+		require.NoError(t, err)
+	}
+
+	set("other/a/x", "1")
+	set("tree/a/x", "1")
+	set("tree/a/y", "2")
+	set("tree/b/x", "3")
+	set("tree/b/z/w", "4")
+	set("tree/c", "5")
+
+	groupsOf := func(t *testing.T, depth uint32) map[string]*schema.PrefixGroup {
+		entries, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("tree/"), GroupByPrefixDepth: depth})
+		require.NoError(t, err)
+		require.Empty(t, entries.Entries)
+
+		groups := make(map[string]*schema.PrefixGroup, len(entries.Groups))
+		for _, g := range entries.Groups {
+			require.Len(t, g.Digest, 32)
+			groups[string(g.Prefix)] = g
+		}
+		return groups
+	}
+
+	t.Run("entries should be grouped by their immediate sub-prefix", func(t *testing.T) {
+		groups := groupsOf(t, 1)
+		require.Len(t, groups, 3)
+		require.EqualValues(t, 2, groups["tree/a/"].Count)
+		require.EqualValues(t, 2, groups["tree/b/"].Count)
+		require.EqualValues(t, 1, groups["tree/c"].Count)
+	})
+
+	t.Run("entries should be grouped up to the given depth", func(t *testing.T) {
+		groups := groupsOf(t, 2)
+		require.Len(t, groups, 5)
+		require.EqualValues(t, 1, groups["tree/a/x"].Count)
+		require.EqualValues(t, 1, groups["tree/b/z/"].Count)
+	})
+
+	t.Run("digests should only change along with their members", func(t *testing.T) {
+		before := groupsOf(t, 1)
+
+		set("tree/b/x", "33")
+
+		after := groupsOf(t, 1)
+		require.Equal(t, before["tree/a/"].Digest, after["tree/a/"].Digest)
+		require.NotEqual(t, before["tree/b/"].Digest, after["tree/b/"].Digest)
+		require.Equal(t, before["tree/c"].Digest, after["tree/c"].Digest)
+
+		set("tree/a/z", "6")
+		set("tree/b/x", "33")
+
+		latest := groupsOf(t, 1)
+		require.NotEqual(t, after["tree/a/"].Digest, latest["tree/a/"].Digest)
+		require.EqualValues(t, 3, latest["tree/a/"].Count)
+		require.Equal(t, after["tree/b/"].Digest, latest["tree/b/"].Digest)
+	})
+
+	t.Run("limit should apply to the number of groups", func(t *testing.T) {
+		entries, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("tree/"), GroupByPrefixDepth: 1, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, entries.Groups, 2)
+		require.Equal(t, []byte("tree/a/"), entries.Groups[0].Prefix)
+		require.Equal(t, []byte("tree/b/"), entries.Groups[1].Prefix)
+		require.Equal(t, groupsOf(t, 1)["tree/b/"].Digest, entries.Groups[1].Digest)
+	})
+
+	t.Run("descending order and offset should not be supported", func(t *testing.T) {
+		_, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("tree/"), GroupByPrefixDepth: 1, Desc: true})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("tree/"), GroupByPrefixDepth: 1, Offset: 1})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
+
+func TestStoreScanGroupByTx(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	set := func(kvs ...string) uint64 {
+		req := &schema.SetRequest{}
+		for i := 0; i < len(kvs); i += 2 {
+			req.KVs = append(req.KVs, &schema.KeyValue{Key: []byte(kvs[i]), Value: []byte(kvs[i+1])})
+		}
+
+		hdr, err := db.Set(ctx, req)
+		require.NoError(t, err)
+
+		return hdr.Id
+	}
+
+	tx1 := set("k/a", "1", "k/d", "1", "k/f", "1")
+	tx2 := set("k/b", "2", "k/e", "2")
+	tx3 := set("k/c", "3", "k/a", "3")
+	set("other", "4")
+
+	groupKeys := func(groups []*schema.TxGroup) map[uint64][]string {
+		res := make(map[uint64][]string, len(groups))
+		for _, g := range groups {
+			for _, e := range g.Entries {
+				res[g.Tx] = append(res[g.Tx], string(e.Key))
+			}
+		}
+		return res
+	}
+
+	t.Run("entries should be grouped by the tx that last wrote each key", func(t *testing.T) {
+		entries, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("k/"), GroupByTx: true})
+		require.NoError(t, err)
+		require.Empty(t, entries.Entries)
+		require.Len(t, entries.TxGroups, 3)
+
+		require.Equal(t, []uint64{tx1, tx2, tx3}, []uint64{entries.TxGroups[0].Tx, entries.TxGroups[1].Tx, entries.TxGroups[2].Tx})
+
+		require.Equal(t, map[uint64][]string{
+			tx1: {"k/d", "k/f"},
+			tx2: {"k/b", "k/e"},
+			tx3: {"k/a", "k/c"},
+		}, groupKeys(entries.TxGroups))
+
+		require.Equal(t, []byte("3"), entries.TxGroups[2].Entries[0].Value)
+	})
+
+	t.Run("the limit should apply to the number of entries", func(t *testing.T) {
+		entries, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("k/"), GroupByTx: true, Limit: 3})
+		require.NoError(t, err)
+
+		require.Equal(t, map[uint64][]string{
+			tx2: {"k/b"},
+			tx3: {"k/a", "k/c"},
+		}, groupKeys(entries.TxGroups))
+	})
+
+	t.Run("groups should be streamed one by one", func(t *testing.T) {
+		var groups []*schema.TxGroup
+
+		err := db.ScanByTx(ctx, &schema.ScanRequest{Prefix: []byte("k/")}, func(g *schema.TxGroup) error {
+			groups = append(groups, g)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, groups, 3)
+
+		errSend := fmt.Errorf("send error")
+
+		err = db.ScanByTx(ctx, &schema.ScanRequest{Prefix: []byte("k/")}, func(g *schema.TxGroup) error {
+			return errSend
+		})
+		require.ErrorIs(t, err, errSend)
+	})
+
+	t.Run("the database should not be locked while sending groups", func(t *testing.T) {
+		var groups []*schema.TxGroup
+
+		err := db.ScanByTx(ctx, &schema.ScanRequest{Prefix: []byte("k/")}, func(g *schema.TxGroup) error {
+			groups = append(groups, g)
+
+			// keys written while streaming are not included, as groups are read from the same snapshot
+			_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("k/e"), Value: []byte("5")}}})
+			return err
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, map[uint64][]string{
+			tx1: {"k/d", "k/f"},
+			tx2: {"k/b", "k/e"},
+			tx3: {"k/a", "k/c"},
+		}, groupKeys(groups))
+	})
+
+	t.Run("entries of each group should be in scan order and selected as in the scan", func(t *testing.T) {
+		// k/e was written again while streaming
+		e, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("k/e")})
+		require.NoError(t, err)
+
+		entries, err := db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("k/"), GroupByTx: true, Desc: true})
+		require.NoError(t, err)
+
+		require.Equal(t, map[uint64][]string{
+			tx1:  {"k/f", "k/d"},
+			tx2:  {"k/b"},
+			tx3:  {"k/c", "k/a"},
+			e.Tx: {"k/e"},
+		}, groupKeys(entries.TxGroups))
+
+		entries, err = db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("k/"), GroupByTx: true, Desc: true, Offset: 1, Limit: 3})
+		require.NoError(t, err)
+
+		require.Equal(t, map[uint64][]string{
+			tx1:  {"k/d"},
+			tx3:  {"k/c"},
+			e.Tx: {"k/e"},
+		}, groupKeys(entries.TxGroups))
+
+		entries, err = db.Scan(ctx, &schema.ScanRequest{Prefix: []byte("k/"), GroupByTx: true, KeyPattern: "k/[a-c]"})
+		require.NoError(t, err)
+
+		require.Equal(t, map[uint64][]string{
+			tx2: {"k/b"},
+			tx3: {"k/a", "k/c"},
+		}, groupKeys(entries.TxGroups))
+	})
+
+	t.Run("grouping by tx and by prefix should not be combined", func(t *testing.T) {
+		_, err := db.Scan(ctx, &schema.ScanRequest{GroupByTx: true, GroupByPrefixDepth: 1})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/codenotary/immudb/embedded/store"
+)
+
+var ErrSelfVerificationFailed = errors.New("self verification failed")
+
+// SelfVerificationError describes the first check failed while verifying the database
+type SelfVerificationError struct {
+	// TxID is the transaction the failure was found at
+	TxID uint64
+	// Key is the key of the sampled entry, empty when the chain of transactions could not be verified
+	Key    []byte
+	Reason string
+}
+
+func (e *SelfVerificationError) Error() string {
+	if len(e.Key) == 0 {
+		return fmt.Sprintf("%s at tx %d: %s", ErrSelfVerificationFailed.Error(), e.TxID, e.Reason)
+	}
+
+	return fmt.Sprintf("%s at tx %d, key '%s': %s", ErrSelfVerificationFailed.Error(), e.TxID, e.Key, e.Reason)
+}
+
+func (e *SelfVerificationError) Is(target error) bool {
+	return target == ErrSelfVerificationFailed
+}
+
+// txLogReader reads the transactions and values being verified
+type txLogReader interface {
+	ReadTx(txID uint64, skipIntegrityCheck bool, tx *store.Tx) error
+	ReadValue(entry *store.TxEntry) ([]byte, error)
+}
+
+// SelfVerify checks the current root of the database is consistent with the first transaction, thus the
+// whole chain of transactions is linked, then samples sampleSize random entries and verifies their values
+// and their inclusion against the current root. The first failure found is returned.
+func (d *db) SelfVerify(ctx context.Context, sampleSize int) error {
+	return d.selfVerify(ctx, sampleSize, d.st)
+}
+
+func (d *db) selfVerify(ctx context.Context, sampleSize int, reader txLogReader) error {
+	if sampleSize < 0 {
+		return ErrIllegalArguments
+	}
+
+	lastTxID, lastAlh := d.st.CommittedAlh()
+	if lastTxID == 0 {
+		return nil
+	}
+
+	lastTxHdr, err := d.st.ReadTxHeader(lastTxID, false, false)
+	if err != nil {
+		return err
+	}
+
+	if lastTxHdr.Alh() != lastAlh {
+		return &SelfVerificationError{TxID: lastTxID, Reason: "transaction header does not match the current root"}
+	}
+
+	firstTxHdr, err := d.st.ReadTxHeader(1, false, false)
+	if err != nil {
+		return err
+	}
+
+	err = d.verifyTxAgainstRoot(firstTxHdr, lastTxHdr)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.allocTx()
+	if err != nil {
+		return err
+	}
+	defer d.releaseTx(tx)
+
+	for i := 0; i < sampleSize; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		txID := 1 + uint64(rand.Int63n(int64(lastTxID)))
+
+		err := reader.ReadTx(txID, false, tx)
+		if errors.Is(err, store.ErrCorruptedData) {
+			return &SelfVerificationError{TxID: txID, Reason: err.Error()}
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(tx.Entries()) == 0 {
+			continue
+		}
+
+		e := tx.Entries()[rand.Intn(len(tx.Entries()))]
+
+		err = d.verifySampledEntry(tx, e, reader)
+		if err != nil {
+			return err
+		}
+
+		err = d.verifyTxAgainstRoot(tx.Header(), lastTxHdr)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifySampledEntry checks the value of the entry matches its digest and the entry is included in the transaction
+func (d *db) verifySampledEntry(tx *store.Tx, e *store.TxEntry, reader txLogReader) error {
+	failure := func(reason string) error {
+		return &SelfVerificationError{TxID: tx.Header().ID, Key: e.Key(), Reason: reason}
+	}
+
+	val, err := reader.ReadValue(e)
+	if errors.Is(err, store.ErrCorruptedData) {
+		return failure(err.Error())
+	}
+	if err != nil && !errors.Is(err, store.ErrExpiredEntry) {
+		return err
+	}
+
+	if err == nil && e.VLen() > 0 && sha256.Sum256(val) != e.HVal() {
+		return failure("value does not match its digest")
+	}
+
+	entryDigest, err := tx.Header().TxEntryDigest()
+	if err != nil {
+		return err
+	}
+
+	digest, err := entryDigest(e)
+	if err != nil {
+		return err
+	}
+
+	proof, err := tx.Proof(e.Key())
+	if err != nil {
+		return err
+	}
+
+	if !store.VerifyInclusion(proof, digest, tx.Header().Eh) {
+		return failure("entry is not included in the transaction")
+	}
+
+	return nil
+}
+
+// verifyTxAgainstRoot checks the transaction is consistent with the transaction of the current root
+func (d *db) verifyTxAgainstRoot(txHdr, rootTxHdr *store.TxHeader) error {
+	proof, err := d.st.DualProof(txHdr, rootTxHdr)
+	if err != nil {
+		return err
+	}
+
+	if !store.VerifyDualProof(proof, txHdr.ID, rootTxHdr.ID, txHdr.Alh(), rootTxHdr.Alh()) {
+		return &SelfVerificationError{TxID: txHdr.ID, Reason: "transaction is not consistent with the current root"}
+	}
+
+	return nil
+}
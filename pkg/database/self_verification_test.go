@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptedTxLog tampers with the values of one of the keys
+type corruptedTxLog struct {
+	txLogReader
+	key []byte
+}
+
+func (l *corruptedTxLog) ReadValue(entry *store.TxEntry) ([]byte, error) {
+	val, err := l.txLogReader.ReadValue(entry)
+	if err != nil || !bytes.Equal(entry.Key(), l.key) {
+		return val, err
+	}
+
+	tampered := make([]byte, len(val))
+	copy(tampered, val)
+	tampered[len(tampered)-1] ^= 0xFF
+
+	return tampered, nil
+}
+
+func TestSelfVerify(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	t.Run("an empty database should be verified", func(t *testing.T) {
+		err := db.SelfVerify(ctx, 10)
+		require.NoError(t, err)
+	})
+
+	for i := 0; i < 5; i++ {
+		_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+			{Key: []byte("key1"), Value: []byte{byte(i)}},
+		}})
+		require.NoError(t, err)
+	}
+
+	t.Run("invalid sample size should fail", func(t *testing.T) {
+		err := db.SelfVerify(ctx, -1)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+
+	t.Run("a healthy database should be verified", func(t *testing.T) {
+		err := db.SelfVerify(ctx, 0)
+		require.NoError(t, err)
+
+		err = db.SelfVerify(ctx, 10)
+		require.NoError(t, err)
+	})
+
+	t.Run("a corrupted entry should be detected", func(t *testing.T) {
+		err := db.selfVerify(ctx, 1, &corruptedTxLog{txLogReader: db.st, key: EncodeKey([]byte("key1"))})
+		require.ErrorIs(t, err, ErrSelfVerificationFailed)
+
+		var verificationErr *SelfVerificationError
+		require.ErrorAs(t, err, &verificationErr)
+		require.Equal(t, EncodeKey([]byte("key1")), verificationErr.Key)
+		require.NotZero(t, verificationErr.TxID)
+	})
+}
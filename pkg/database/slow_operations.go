@@ -0,0 +1,64 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"time"
+)
+
+const (
+	GetOperation  = "get"
+	ScanOperation = "scan"
+	SetOperation  = "set"
+)
+
+// SlowOperation is the record emitted when an operation exceeds the slow operation threshold
+type SlowOperation struct {
+	Database  string
+	Operation string
+	// KeyLen holds the length of the key or the scanned prefix, for multi-key writes it holds the sum of key lengths
+	KeyLen   int
+	Duration time.Duration
+	// TxID holds the transaction the operation read from or committed, zero if the operation failed
+	TxID uint64
+}
+
+// SlowOperationHook is invoked with each operation exceeding the slow operation threshold
+type SlowOperationHook func(op SlowOperation)
+
+func (d *db) observeSlowOperation(operation string, keyLen int, start time.Time, txID uint64) {
+	elapsed := time.Since(start)
+	if elapsed < d.options.slowOperationThreshold {
+		return
+	}
+
+	op := SlowOperation{
+		Database:  d.name,
+		Operation: operation,
+		KeyLen:    keyLen,
+		Duration:  elapsed,
+		TxID:      txID,
+	}
+
+	if d.options.slowOperationHook != nil {
+		d.options.slowOperationHook(op)
+		return
+	}
+
+	d.Logger.Warningf("slow operation on database '%s' {op=%s, key_len=%d, duration=%v, tx=%d}",
+		op.Database, op.Operation, op.KeyLen, op.Duration, op.TxID)
+}
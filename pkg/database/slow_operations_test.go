@@ -0,0 +1,88 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowOperationHook(t *testing.T) {
+	var ops []SlowOperation
+
+	opts := DefaultOption().
+		WithDBRootPath(t.TempDir()).
+		WithSlowOperationThreshold(time.Hour).
+		WithSlowOperationHook(func(op SlowOperation) {
+			ops = append(ops, op)
+		})
+
+	db := makeDbWith(t, "db", opts)
+
+	hdr, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	_, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte("key1")})
+	require.NoError(t, err)
+
+	t.Run("fast operations should not be reported", func(t *testing.T) {
+		require.Empty(t, ops)
+	})
+
+	// every operation is slow under such a threshold
+	opts.WithSlowOperationThreshold(time.Nanosecond)
+
+	t.Run("slow operations should be reported", func(t *testing.T) {
+		hdr2, err := db.Set(context.Background(), &schema.SetRequest{KVs: []*schema.KeyValue{
+			{Key: []byte("key2"), Value: []byte("value2")},
+			{Key: []byte("key33"), Value: []byte("value3")},
+		}})
+		require.NoError(t, err)
+
+		_, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte("key1")})
+		require.NoError(t, err)
+
+		_, err = db.Scan(context.Background(), &schema.ScanRequest{Prefix: []byte("ke")})
+		require.NoError(t, err)
+
+		_, err = db.Get(context.Background(), &schema.KeyRequest{Key: []byte("missing")})
+		require.Error(t, err)
+
+		require.Len(t, ops, 4)
+
+		require.Equal(t, "db", ops[0].Database)
+		require.Equal(t, SetOperation, ops[0].Operation)
+		require.Equal(t, 9, ops[0].KeyLen)
+		require.Equal(t, hdr2.Id, ops[0].TxID)
+		require.Greater(t, ops[0].Duration, time.Duration(0))
+
+		require.Equal(t, GetOperation, ops[1].Operation)
+		require.Equal(t, 4, ops[1].KeyLen)
+		require.Equal(t, hdr.Id, ops[1].TxID)
+
+		require.Equal(t, ScanOperation, ops[2].Operation)
+		require.Equal(t, 2, ops[2].KeyLen)
+		require.Equal(t, hdr2.Id, ops[2].TxID)
+
+		require.Equal(t, GetOperation, ops[3].Operation)
+		require.Zero(t, ops[3].TxID)
+	})
+}
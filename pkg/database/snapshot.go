@@ -0,0 +1,151 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var ErrTooManySnapshots = errors.New("too many open snapshots")
+
+var metricsOpenSnapshots = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "immudb_open_snapshots",
+	Help: "Number of currently open read-only snapshots",
+}, []string{
+	"db",
+})
+
+// snapshotSlots keeps track of the open snapshots of a database, so clients
+// leaking snapshots can not pin the index and value log indefinitely
+type snapshotSlots struct {
+	mutex sync.Mutex
+
+	max  int
+	open int
+
+	gauge prometheus.Gauge
+}
+
+func newSnapshotSlots(dbName string, max int) *snapshotSlots {
+	return &snapshotSlots{
+		max:   max,
+		gauge: metricsOpenSnapshots.WithLabelValues(dbName),
+	}
+}
+
+func (s *snapshotSlots) acquire() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.max > 0 && s.open >= s.max {
+		return fmt.Errorf("%w: the maximum number of open snapshots (%d) has been reached", ErrTooManySnapshots, s.max)
+	}
+
+	s.open++
+	s.gauge.Set(float64(s.open))
+
+	return nil
+}
+
+func (s *snapshotSlots) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.open--
+	s.gauge.Set(float64(s.open))
+}
+
+func (s *snapshotSlots) count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.open
+}
+
+// Snapshot is a read-only view of the database as of a transaction.
+// Snapshots hold resources until they are closed, thus Close must be called once no longer needed.
+type Snapshot struct {
+	db   *db
+	snap *store.Snapshot
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// OpenSnapshot opens a read-only snapshot including at least the specified transaction,
+// the latest committed transaction is used when txID is 0.
+// ErrTooManySnapshots is returned when the maximum number of open snapshots has been reached.
+func (d *db) OpenSnapshot(ctx context.Context, txID uint64) (*Snapshot, error) {
+	err := d.snapshotSlots.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := d.snapshotSince(ctx, []byte{SetKeyPrefix}, txID)
+	if err != nil {
+		d.snapshotSlots.release()
+		return nil, err
+	}
+
+	return &Snapshot{db: d, snap: snap}, nil
+}
+
+// OpenSnapshots returns the number of currently open snapshots
+func (d *db) OpenSnapshots() int {
+	return d.snapshotSlots.count()
+}
+
+// TxID returns the transaction the snapshot is at
+func (s *Snapshot) TxID() uint64 {
+	return s.snap.Ts()
+}
+
+// Get returns the entry of the key as of the snapshot
+func (s *Snapshot) Get(ctx context.Context, key []byte) (*schema.Entry, error) {
+	if len(key) == 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	entry, err := s.db.getAtTx(ctx, EncodeKey(s.db.storedKey(key)), 0, 0, s.snap, 0, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.db.keyObfuscationEnabled() {
+		entry.Key = key
+	}
+
+	return entry, nil
+}
+
+// Close releases the snapshot, it's safe to call it more than once
+func (s *Snapshot) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeErr = s.snap.Close()
+		s.db.snapshotSlots.release()
+	})
+
+	return s.closeErr
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxOpenSnapshots(t *testing.T) {
+	options := DefaultOption().WithDBRootPath(t.TempDir()).WithMaxOpenSnapshots(2)
+	db := makeDbWith(t, "db", options)
+
+	ctx := context.Background()
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}})
+	require.NoError(t, err)
+
+	snap1, err := db.OpenSnapshot(ctx, 0)
+	require.NoError(t, err)
+
+	_, err = db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{{Key: []byte("key1"), Value: []byte("value2")}}})
+	require.NoError(t, err)
+
+	snap2, err := db.OpenSnapshot(ctx, 0)
+	require.NoError(t, err)
+	defer snap2.Close()
+
+	require.Equal(t, 2, db.OpenSnapshots())
+	require.Equal(t, 2.0, testutil.ToFloat64(metricsOpenSnapshots.WithLabelValues("db")))
+
+	t.Run("snapshots should be isolated", func(t *testing.T) {
+		require.Equal(t, hdr.Id, snap1.TxID())
+
+		entry, err := snap1.Get(ctx, []byte("key1"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value1"), entry.Value)
+
+		entry, err = snap2.Get(ctx, []byte("key1"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value2"), entry.Value)
+	})
+
+	t.Run("opening more snapshots than the limit should fail", func(t *testing.T) {
+		_, err := db.OpenSnapshot(ctx, 0)
+		require.ErrorIs(t, err, ErrTooManySnapshots)
+		require.Equal(t, 2, db.OpenSnapshots())
+	})
+
+	t.Run("closing a snapshot should free a slot", func(t *testing.T) {
+		err := snap1.Close()
+		require.NoError(t, err)
+
+		// closing again should not free another slot
+		err = snap1.Close()
+		require.NoError(t, err)
+
+		require.Equal(t, 1, db.OpenSnapshots())
+		require.Equal(t, 1.0, testutil.ToFloat64(metricsOpenSnapshots.WithLabelValues("db")))
+
+		snap3, err := db.OpenSnapshot(ctx, 0)
+		require.NoError(t, err)
+		defer snap3.Close()
+
+		_, err = db.OpenSnapshot(ctx, 0)
+		require.ErrorIs(t, err, ErrTooManySnapshots)
+	})
+}
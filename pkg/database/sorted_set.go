@@ -46,6 +46,10 @@ func (d *db) ZAdd(ctx context.Context, req *schema.ZAddRequest) (*schema.TxHeade
 		return nil, store.ErrIllegalArguments
 	}
 
+	if d.keyObfuscationEnabled() {
+		return nil, ErrKeyObfuscationUnsupported
+	}
+
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// UniquenessCheck holds the outcome of checking a key within a namespace as of the target transaction.
+//
+// It is not a proof of uniqueness: absence of the key is asserted by the server, as non-inclusion
+// proofs are not available. Only the entry, when present, comes with its inclusion proof, and the dual
+// proof links the transaction the check was requested since with the target one.
+type UniquenessCheck struct {
+	// TargetTxID is the transaction as of which the key was checked
+	TargetTxID uint64
+
+	// DualProof links the transaction the check was requested since with the target one
+	DualProof *schema.DualProof
+
+	// Entry is the live entry of the key, nil when there is none
+	Entry *schema.VerifiableEntry
+}
+
+// CheckUnique looks the key up within the namespace, i.e. the exact key made of the namespace followed
+// by the provided one, and returns its live entry, if any, as of the latest committed transaction.
+// Keys sharing the same prefix, such as "alice" and "alice2", are distinct keys and do not conflict.
+func (d *db) CheckUnique(ctx context.Context, prefix, key []byte, proveSinceTx uint64) (*UniquenessCheck, error) {
+	if len(key) == 0 {
+		return nil, ErrIllegalArguments
+	}
+
+	targetTxID, _ := d.st.CommittedAlh()
+	if targetTxID < proveSinceTx {
+		return nil, ErrIllegalState
+	}
+
+	check := &UniquenessCheck{TargetTxID: targetTxID}
+
+	entry, err := d.VerifiableGet(ctx, &schema.VerifiableGetRequest{
+		KeyRequest:   &schema.KeyRequest{Key: append(append([]byte{}, prefix...), key...), SinceTx: targetTxID},
+		ProveSinceTx: targetTxID,
+	})
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	if err == nil {
+		check.Entry = entry
+
+		if entry.VerifiableTx.Tx.Header.Id > targetTxID {
+			// the key was concurrently updated
+			targetTxID = entry.VerifiableTx.Tx.Header.Id
+			check.TargetTxID = targetTxID
+		}
+	}
+	targetTxHdr, err := d.st.ReadTxHeader(targetTxID, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceTxHdr := targetTxHdr
+
+	if proveSinceTx > 0 {
+		sourceTxHdr, err = d.st.ReadTxHeader(proveSinceTx, false, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dualProof, err := d.st.DualProof(sourceTxHdr, targetTxHdr)
+	if err != nil {
+		return nil, err
+	}
+
+	check.DualProof = schema.DualProofToProto(dualProof)
+
+	return check, nil
+}
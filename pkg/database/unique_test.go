@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckUnique(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	_, err := db.CheckUnique(ctx, []byte("customers:"), nil, 0)
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	hdr, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("customers:alice"), Value: []byte("ID1")},
+		{Key: []byte("customers:alice2"), Value: []byte("ID2")},
+		{Key: []byte("customers:bob"), Value: []byte("ID3")},
+	}})
+	require.NoError(t, err)
+
+	sourceTxHdr := schema.TxHeaderFromProto(hdr)
+
+	_, err = db.CheckUnique(ctx, []byte("customers:"), []byte("alice"), hdr.Id+1)
+	require.ErrorIs(t, err, ErrIllegalState)
+
+	requireValidDualProof := func(t *testing.T, check *UniquenessCheck) {
+		dualProof := schema.DualProofFromProto(check.DualProof)
+		require.Equal(t, check.TargetTxID, dualProof.TargetTxHeader.ID)
+
+		verifies := store.VerifyDualProof(
+			dualProof,
+			sourceTxHdr.ID,
+			check.TargetTxID,
+			sourceTxHdr.Alh(),
+			dualProof.TargetTxHeader.Alh(),
+		)
+		require.True(t, verifies)
+	}
+
+	t.Run("existing key should be returned with its proof", func(t *testing.T) {
+		check, err := db.CheckUnique(ctx, []byte("customers:"), []byte("alice"), hdr.Id)
+		require.NoError(t, err)
+		require.NotNil(t, check.Entry)
+		require.Equal(t, []byte("customers:alice"), check.Entry.Entry.Key)
+		require.Equal(t, []byte("ID1"), check.Entry.Entry.Value)
+
+		requireValidDualProof(t, check)
+
+		tx := schema.TxFromProto(check.Entry.VerifiableTx.Tx)
+
+		entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+		require.NoError(t, err)
+
+		e := EncodeEntrySpec(check.Entry.Entry.Key, nil, check.Entry.Entry.Value)
+
+		verifies := store.VerifyInclusion(
+			schema.InclusionProofFromProto(check.Entry.InclusionProof),
+			entrySpecDigest(e),
+			tx.Header().Eh,
+		)
+		require.True(t, verifies)
+
+		// the transaction of the entry is consistent with the target one
+		entryDualProof := schema.DualProofFromProto(check.Entry.VerifiableTx.DualProof)
+		targetDualProof := schema.DualProofFromProto(check.DualProof)
+
+		verifies = store.VerifyDualProof(
+			entryDualProof,
+			tx.Header().ID,
+			check.TargetTxID,
+			tx.Header().Alh(),
+			targetDualProof.TargetTxHeader.Alh(),
+		)
+		require.True(t, verifies)
+	})
+
+	t.Run("absent key should not be matched by keys sharing its prefix", func(t *testing.T) {
+		check, err := db.CheckUnique(ctx, []byte("customers:"), []byte("ali"), hdr.Id)
+		require.NoError(t, err)
+		require.Nil(t, check.Entry)
+
+		requireValidDualProof(t, check)
+
+		check, err = db.CheckUnique(ctx, []byte("customers:"), []byte("carol"), hdr.Id)
+		require.NoError(t, err)
+		require.Nil(t, check.Entry)
+
+		requireValidDualProof(t, check)
+	})
+
+	t.Run("deleted entries should not be considered", func(t *testing.T) {
+		_, err := db.Delete(ctx, &schema.DeleteKeysRequest{Keys: [][]byte{[]byte("customers:bob")}})
+		require.NoError(t, err)
+
+		check, err := db.CheckUnique(ctx, []byte("customers:"), []byte("bob"), hdr.Id)
+		require.NoError(t, err)
+		require.Nil(t, check.Entry)
+
+		requireValidDualProof(t, check)
+	})
+}
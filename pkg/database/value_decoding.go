@@ -0,0 +1,71 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+var ErrValueTypeMismatch = errors.New("value does not match the requested type")
+
+// ValueTypeMismatchError is returned when the stored value can not be decoded as the requested type
+type ValueTypeMismatchError struct {
+	Type     schema.ValueType
+	ValueLen int
+}
+
+func (e *ValueTypeMismatchError) Error() string {
+	return fmt.Sprintf("%s, a value of %d bytes can not be decoded as %s", ErrValueTypeMismatch.Error(), e.ValueLen, e.Type)
+}
+
+func (e *ValueTypeMismatchError) Is(target error) bool {
+	return target == ErrValueTypeMismatch
+}
+
+// decodeValue decodes the value as the specified type, numeric types are expected to be encoded
+// as 8-byte big-endian values and booleans as a single byte
+func decodeValue(value []byte, valueType schema.ValueType) (*schema.SQLValue, error) {
+	switch valueType {
+	case schema.ValueType_INT64:
+		if len(value) != 8 {
+			return nil, &ValueTypeMismatchError{Type: valueType, ValueLen: len(value)}
+		}
+		return &schema.SQLValue{Value: &schema.SQLValue_N{N: int64(binary.BigEndian.Uint64(value))}}, nil
+	case schema.ValueType_FLOAT64:
+		if len(value) != 8 {
+			return nil, &ValueTypeMismatchError{Type: valueType, ValueLen: len(value)}
+		}
+		return &schema.SQLValue{Value: &schema.SQLValue_F{F: math.Float64frombits(binary.BigEndian.Uint64(value))}}, nil
+	case schema.ValueType_BOOL:
+		if len(value) != 1 || value[0] > 1 {
+			return nil, &ValueTypeMismatchError{Type: valueType, ValueLen: len(value)}
+		}
+		return &schema.SQLValue{Value: &schema.SQLValue_B{B: value[0] == 1}}, nil
+	case schema.ValueType_TIMESTAMP:
+		if len(value) != 8 {
+			return nil, &ValueTypeMismatchError{Type: valueType, ValueLen: len(value)}
+		}
+		return &schema.SQLValue{Value: &schema.SQLValue_Ts{Ts: int64(binary.BigEndian.Uint64(value))}}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported value type '%s'", ErrIllegalArguments, valueType)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDecodedValue(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	intValue := make([]byte, 8)
+	binary.BigEndian.PutUint64(intValue, uint64(math.MaxInt64-1))
+
+	negIntValue := make([]byte, 8)
+	binary.BigEndian.PutUint64(negIntValue, uint64(0xFFFFFFFFFFFFFFFF)) // -1
+
+	floatValue := make([]byte, 8)
+	binary.BigEndian.PutUint64(floatValue, math.Float64bits(3.14))
+
+	now := time.Now().UnixMicro()
+	tsValue := make([]byte, 8)
+	binary.BigEndian.PutUint64(tsValue, uint64(now))
+
+	_, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("counter"), Value: intValue},
+		{Key: []byte("balance"), Value: negIntValue},
+		{Key: []byte("ratio"), Value: floatValue},
+		{Key: []byte("enabled"), Value: []byte{1}},
+		{Key: []byte("updatedAt"), Value: tsValue},
+		{Key: []byte("name"), Value: []byte("alice")},
+	}})
+	require.NoError(t, err)
+
+	t.Run("raw values should not be decoded", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("counter")})
+		require.NoError(t, err)
+		require.Equal(t, intValue, entry.Value)
+		require.Nil(t, entry.DecodedValue)
+	})
+
+	t.Run("int64 values should be decoded", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("counter"), Decode: schema.ValueType_INT64})
+		require.NoError(t, err)
+		require.Equal(t, intValue, entry.Value)
+		require.EqualValues(t, math.MaxInt64-1, entry.DecodedValue.GetN())
+
+		entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("balance"), Decode: schema.ValueType_INT64})
+		require.NoError(t, err)
+		require.EqualValues(t, -1, entry.DecodedValue.GetN())
+	})
+
+	t.Run("float64 values should be decoded", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("ratio"), Decode: schema.ValueType_FLOAT64})
+		require.NoError(t, err)
+		require.Equal(t, 3.14, entry.DecodedValue.GetF())
+	})
+
+	t.Run("bool and timestamp values should be decoded", func(t *testing.T) {
+		entry, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("enabled"), Decode: schema.ValueType_BOOL})
+		require.NoError(t, err)
+		require.True(t, entry.DecodedValue.GetB())
+
+		entry, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("updatedAt"), Decode: schema.ValueType_TIMESTAMP})
+		require.NoError(t, err)
+		require.Equal(t, now, entry.DecodedValue.GetTs())
+	})
+
+	t.Run("mismatching values should fail with a typed error", func(t *testing.T) {
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("name"), Decode: schema.ValueType_INT64})
+		require.ErrorIs(t, err, ErrValueTypeMismatch)
+
+		var mismatchErr *ValueTypeMismatchError
+		require.ErrorAs(t, err, &mismatchErr)
+		require.Equal(t, schema.ValueType_INT64, mismatchErr.Type)
+		require.Equal(t, 5, mismatchErr.ValueLen)
+
+		_, err = db.Get(ctx, &schema.KeyRequest{Key: []byte("counter"), Decode: schema.ValueType_BOOL})
+		require.ErrorIs(t, err, ErrValueTypeMismatch)
+	})
+
+	t.Run("unknown types should be rejected", func(t *testing.T) {
+		_, err := db.Get(ctx, &schema.KeyRequest{Key: []byte("counter"), Decode: schema.ValueType(100)})
+		require.ErrorIs(t, err, ErrIllegalArguments)
+	})
+}
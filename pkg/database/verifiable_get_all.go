@@ -0,0 +1,98 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+
+	"github.com/codenotary/immudb/pkg/api/schema"
+)
+
+// VerifiableGetAll reads the keys and proves them grouped by the transaction they were written in,
+// so a single dual proof is provided for all the keys sharing a transaction.
+// Keys not found are skipped as done by GetAll.
+func (d *db) VerifiableGetAll(ctx context.Context, req *schema.VerifiableGetAllRequest) (*schema.VerifiableEntries, error) {
+	if req == nil || req.KeyListRequest == nil {
+		return nil, ErrIllegalArguments
+	}
+
+	lastTxID, _ := d.st.CommittedAlh()
+	if lastTxID < req.ProveSinceTx {
+		return nil, ErrIllegalState
+	}
+
+	entries, err := d.GetAll(ctx, req.KeyListRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var txIDs []uint64
+	groups := make(map[uint64][]*schema.Entry)
+	keys := make(map[uint64][][]byte)
+
+	for _, e := range entries.Entries {
+		vTxID, vKey := d.verifiableKey(e)
+
+		if _, ok := groups[vTxID]; !ok {
+			txIDs = append(txIDs, vTxID)
+		}
+
+		groups[vTxID] = append(groups[vTxID], e)
+		keys[vTxID] = append(keys[vTxID], vKey)
+	}
+
+	tx, err := d.allocTx()
+	if err != nil {
+		return nil, err
+	}
+	defer d.releaseTx(tx)
+
+	ventries := &schema.VerifiableEntries{
+		Txs: make([]*schema.VerifiableTxEntries, len(txIDs)),
+	}
+
+	for i, txID := range txIDs {
+		err = d.st.ReadTx(txID, false, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		verifiableTx, err := d.verifiableTx(tx, req.ProveSinceTx)
+		if err != nil {
+			return nil, err
+		}
+
+		inclusionProofs := make([]*schema.InclusionProof, len(keys[txID]))
+
+		for j, key := range keys[txID] {
+			inclusionProof, err := tx.Proof(EncodeKey(key))
+			if err != nil {
+				return nil, err
+			}
+
+			inclusionProofs[j] = schema.InclusionProofToProto(inclusionProof)
+		}
+
+		ventries.Txs[i] = &schema.VerifiableTxEntries{
+			VerifiableTx:    verifiableTx,
+			Entries:         groups[txID],
+			InclusionProofs: inclusionProofs,
+		}
+	}
+
+	return ventries, nil
+}
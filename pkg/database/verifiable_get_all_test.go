@@ -0,0 +1,120 @@
+/*
+Copyright 2024 Codenotary Inc. All rights reserved.
+
+SPDX-License-Identifier: BUSL-1.1
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://mariadb.com/bsl11/
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreVerifiableGetAll(t *testing.T) {
+	db := makeDb(t)
+
+	ctx := context.Background()
+
+	hdr1, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("key1"), Value: []byte("value1")},
+		{Key: []byte("key2"), Value: []byte("value2")},
+		{Key: []byte("key3"), Value: []byte("value3")},
+	}})
+	require.NoError(t, err)
+
+	hdr2, err := db.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("key4"), Value: []byte("value4")},
+	}})
+	require.NoError(t, err)
+
+	t.Run("invalid requests should be rejected", func(t *testing.T) {
+		_, err := db.VerifiableGetAll(ctx, nil)
+		require.ErrorIs(t, err, ErrIllegalArguments)
+
+		_, err = db.VerifiableGetAll(ctx, &schema.VerifiableGetAllRequest{
+			KeyListRequest: &schema.KeyListRequest{Keys: [][]byte{[]byte("key1")}},
+			ProveSinceTx:   hdr2.Id + 1,
+		})
+		require.ErrorIs(t, err, ErrIllegalState)
+	})
+
+	for _, proveSinceTx := range []uint64{0, hdr1.Id, hdr2.Id} {
+		ventries, err := db.VerifiableGetAll(ctx, &schema.VerifiableGetAllRequest{
+			KeyListRequest: &schema.KeyListRequest{
+				Keys: [][]byte{[]byte("key1"), []byte("key4"), []byte("key3"), []byte("missing"), []byte("key2")},
+			},
+			ProveSinceTx: proveSinceTx,
+		})
+		require.NoError(t, err)
+
+		// a single group per distinct transaction, in order of first appearance
+		require.Len(t, ventries.Txs, 2)
+		require.Equal(t, hdr1.Id, ventries.Txs[0].VerifiableTx.Tx.Header.Id)
+		require.Equal(t, hdr2.Id, ventries.Txs[1].VerifiableTx.Tx.Header.Id)
+
+		require.Len(t, ventries.Txs[0].Entries, 3)
+		require.Equal(t, []byte("key1"), ventries.Txs[0].Entries[0].Key)
+		require.Equal(t, []byte("key3"), ventries.Txs[0].Entries[1].Key)
+		require.Equal(t, []byte("key2"), ventries.Txs[0].Entries[2].Key)
+
+		require.Len(t, ventries.Txs[1].Entries, 1)
+		require.Equal(t, []byte("key4"), ventries.Txs[1].Entries[0].Key)
+
+		var sourceAlh [32]byte
+		if proveSinceTx > 0 {
+			sourceHdr, err := db.st.ReadTxHeader(proveSinceTx, false, false)
+			require.NoError(t, err)
+			sourceAlh = sourceHdr.Alh()
+		}
+
+		for _, group := range ventries.Txs {
+			require.Len(t, group.InclusionProofs, len(group.Entries))
+
+			tx := schema.TxFromProto(group.VerifiableTx.Tx)
+
+			entrySpecDigest, err := store.EntrySpecDigestFor(tx.Header().Version)
+			require.NoError(t, err)
+
+			for i, e := range group.Entries {
+				verifies := store.VerifyInclusion(
+					schema.InclusionProofFromProto(group.InclusionProofs[i]),
+					entrySpecDigest(EncodeEntrySpec(e.Key, schema.KVMetadataFromProto(e.Metadata), e.Value)),
+					tx.Header().Eh,
+				)
+				require.True(t, verifies)
+			}
+
+			if proveSinceTx == 0 {
+				continue
+			}
+
+			dualProof := schema.DualProofFromProto(group.VerifiableTx.DualProof)
+
+			sourceID, targetID := proveSinceTx, tx.Header().ID
+			sourceTxAlh, targetTxAlh := sourceAlh, tx.Header().Alh()
+
+			if proveSinceTx > tx.Header().ID {
+				sourceID, targetID = targetID, sourceID
+				sourceTxAlh, targetTxAlh = targetTxAlh, sourceTxAlh
+			}
+
+			verifies := store.VerifyDualProof(dualProof, sourceID, targetID, sourceTxAlh, targetTxAlh)
+			require.True(t, verifies)
+		}
+	}
+}
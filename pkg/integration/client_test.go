@@ -1469,6 +1469,62 @@ func TestImmuClient_VerifiedGetAt(t *testing.T) {
 	require.ErrorIs(t, err, store.ErrCorruptedData)
 }
 
+func TestImmuClient_VerifiedGetReferencedEntryTampering(t *testing.T) {
+	bs, _, ctx := setupTestServerAndClient(t)
+
+	client, err := bs.NewAuthenticatedClient(ic.
+		DefaultOptions().
+		WithDir(t.TempDir()).
+		WithVerifyReferencedEntries(true),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.CloseSession(context.Background()) })
+
+	txHdr1, err := client.VerifiedSet(ctx, []byte(`key1`), []byte(`val1`))
+	require.NoError(t, err)
+
+	_, err = client.VerifiedSet(ctx, []byte(`key1`), []byte(`val2`))
+	require.NoError(t, err)
+
+	_, err = client.VerifiedSetReferenceAt(ctx, []byte(`ref1`), []byte(`key1`), txHdr1.Id)
+	require.NoError(t, err)
+
+	entry, err := client.VerifiedGet(ctx, []byte(`ref1`))
+	require.NoError(t, err)
+	require.Equal(t, []byte(`key1`), entry.Key)
+	require.Equal(t, []byte(`val1`), entry.Value)
+
+	bs.Server.PostVerifiableGetFn = func(ctx context.Context,
+		req *schema.VerifiableGetRequest, res *schema.VerifiableEntry, err error) (*schema.VerifiableEntry, error) {
+
+		if err != nil {
+			return res, err
+		}
+
+		res.Entry.Value = []byte(`val2`)
+
+		return res, nil
+	}
+
+	_, err = client.VerifiedGet(ctx, []byte(`ref1`))
+	require.ErrorIs(t, err, store.ErrCorruptedData)
+
+	bs.Server.PostVerifiableGetFn = func(ctx context.Context,
+		req *schema.VerifiableGetRequest, res *schema.VerifiableEntry, err error) (*schema.VerifiableEntry, error) {
+
+		if err != nil {
+			return res, err
+		}
+
+		res.ReferencedEntryProof = nil
+
+		return res, nil
+	}
+
+	_, err = client.VerifiedGet(ctx, []byte(`ref1`))
+	require.ErrorIs(t, err, store.ErrCorruptedData)
+}
+
 func TestImmuClient_VerifiedGetSince(t *testing.T) {
 	_, client, ctx := setupTestServerAndClient(t)
 
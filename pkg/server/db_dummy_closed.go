@@ -19,6 +19,7 @@ package server
 import (
 	"context"
 	"crypto/sha256"
+	"io"
 	"path/filepath"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/codenotary/immudb/pkg/api/protomodel"
 	"github.com/codenotary/immudb/pkg/api/schema"
 	"github.com/codenotary/immudb/pkg/database"
+	"github.com/codenotary/immudb/pkg/signer"
 )
 
 // work-around until a DBManager is in-place, taking care of all db-related stuff
@@ -62,10 +64,19 @@ func (db *closedDB) IsSyncReplicationEnabled() bool {
 func (db *closedDB) SetSyncReplication(enabled bool) {
 }
 
+func (db *closedDB) Metrics() database.Metrics {
+	return database.Metrics{}
+}
+
 func (db *closedDB) MaxResultSize() int {
 	return 1000
 }
 
+func (db *closedDB) TxLimits() (maxKeyLen, maxValueLen, maxTxEntries int) {
+	storeOpts := db.opts.GetStoreOptions()
+	return storeOpts.MaxKeyLen - 1, storeOpts.MaxValueLen - 1, storeOpts.MaxTxEntries
+}
+
 func (db *closedDB) UseTimeFunc(timeFunc store.TimeFunc) error {
 	return store.ErrAlreadyClosed
 }
@@ -78,6 +89,10 @@ func (db *closedDB) CurrentState() (*schema.ImmutableState, error) {
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) OpenCheckpointReader(signedState *schema.ImmutableState) (*database.CheckpointReader, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (db *closedDB) Size() (uint64, error) {
 	return 0, store.ErrAlreadyClosed
 }
@@ -98,6 +113,10 @@ func (db *closedDB) Get(ctx context.Context, req *schema.KeyRequest) (*schema.En
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) GetAt(ctx context.Context, key []byte, atTx uint64) (*schema.Entry, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (db *closedDB) VerifiableGet(ctx context.Context, req *schema.VerifiableGetRequest) (*schema.VerifiableEntry, error) {
 	return nil, store.ErrAlreadyClosed
 }
@@ -106,22 +125,138 @@ func (db *closedDB) GetAll(ctx context.Context, req *schema.KeyListRequest) (*sc
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) GetEntryMetadata(ctx context.Context, req *schema.KeyRequest) (*database.EntryMetadata, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) GetReference(ctx context.Context, req *schema.KeyRequest) (*schema.Reference, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) ReferenceHistory(ctx context.Context, req *schema.HistoryRequest) ([]*schema.Reference, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) VerifiableGetAll(ctx context.Context, req *schema.VerifiableGetAllRequest) (*schema.VerifiableEntries, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (db *closedDB) Delete(ctx context.Context, req *schema.DeleteKeysRequest) (*schema.TxHeader, error) {
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) Append(ctx context.Context, key, suffix []byte, preconditions []*schema.Precondition) (*schema.TxHeader, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) MergeJSON(ctx context.Context, key, patch []byte, preconditions []*schema.Precondition) (*schema.TxHeader, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) RenameKey(ctx context.Context, oldKey, newKey []byte) (*schema.TxHeader, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) RenameReference(ctx context.Context, oldKey, newKey []byte, allowOverwrite bool) (*schema.TxHeader, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) VerifyIndex(ctx context.Context) error {
+	return store.ErrAlreadyClosed
+}
+
+func (db *closedDB) SelfVerify(ctx context.Context, sampleSize int) error {
+	return store.ErrAlreadyClosed
+}
+
 func (db *closedDB) SetReference(ctx context.Context, req *schema.ReferenceRequest) (*schema.TxHeader, error) {
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) SetReferenceAndResolve(ctx context.Context, req *schema.ReferenceRequest) (*schema.SetReferenceResponse, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) ResolveGroup(ctx context.Context, group []byte) (*schema.Entry, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) SetReferences(ctx context.Context, req *schema.SetReferencesRequest) (*schema.TxHeader, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (db *closedDB) VerifiableSetReference(ctx context.Context, req *schema.VerifiableReferenceRequest) (*schema.VerifiableTx, error) {
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) VerifiableSetReferences(ctx context.Context, req *schema.VerifiableSetReferencesRequest) (*schema.VerifiableTx, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) RefreshReferences(ctx context.Context, prefix []byte) (int, []uint64, error) {
+	return 0, nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) ResolveReferenceProvenance(ctx context.Context, key []byte) ([]*database.ReferenceHop, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) VerifiableSetWithReference(ctx context.Context, kv *schema.KeyValue, req *schema.ReferenceRequest, proveSinceTx uint64) (*schema.VerifiableTx, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) CheckUnique(ctx context.Context, prefix, key []byte, proveSinceTx uint64) (*database.UniquenessCheck, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) CheckAbsent(ctx context.Context, key []byte, atTx uint64) (*database.AbsenceCheck, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) OpenSnapshot(ctx context.Context, txID uint64) (*database.Snapshot, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) OpenSnapshots() int {
+	return 0
+}
+
+func (db *closedDB) ExportBundle(ctx context.Context, fromTx, toTx uint64, w io.Writer, s signer.Signer) (*database.BundleManifest, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) CanonicalKey(ctx context.Context, key []byte) ([]byte, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) GetVersions(ctx context.Context, key []byte, k int, sinceTx uint64) ([]*schema.Entry, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) VerifiableHistory(ctx context.Context, req *schema.VerifiableHistoryRequest, send func(*schema.VerifiableHistoryResponse) error) error {
+	return store.ErrAlreadyClosed
+}
+
+func (db *closedDB) RepairReferences(ctx context.Context, dryRun bool) ([]*database.BrokenReference, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) ListReferences(ctx context.Context, req *database.ListReferencesRequest) ([]*database.ListedReference, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (db *closedDB) ScanReferences(ctx context.Context, req *schema.ScanRequest) ([]*schema.Reference, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (db *closedDB) Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries, error) {
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) ScanByTx(ctx context.Context, req *schema.ScanRequest, send func(*schema.TxGroup) error) error {
+	return store.ErrAlreadyClosed
+}
+
 func (db *closedDB) History(ctx context.Context, req *schema.HistoryRequest) (*schema.Entries, error) {
 	return nil, store.ErrAlreadyClosed
 }
@@ -226,6 +361,10 @@ func (db *closedDB) VerifiableTxByID(ctx context.Context, req *schema.Verifiable
 	return nil, store.ErrAlreadyClosed
 }
 
+func (db *closedDB) RecentTxs(ctx context.Context, n int) ([]*database.TxSummary, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (db *closedDB) TxScan(ctx context.Context, req *schema.TxScanRequest) (*schema.TxList, error) {
 	return nil, store.ErrAlreadyClosed
 }
@@ -234,6 +373,10 @@ func (db *closedDB) FlushIndex(req *schema.FlushIndexRequest) error {
 	return store.ErrAlreadyClosed
 }
 
+func (db *closedDB) Checkpoint(ctx context.Context) (*schema.ImmutableState, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (db *closedDB) CompactIndex() error {
 	return store.ErrAlreadyClosed
 }
@@ -272,6 +415,14 @@ func (d *closedDB) DeleteCollection(ctx context.Context, username string, req *p
 	return nil, store.ErrAlreadyClosed
 }
 
+func (d *closedDB) RestoreCollection(ctx context.Context, username string, req *protomodel.RestoreCollectionRequest) (*protomodel.RestoreCollectionResponse, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (d *closedDB) PurgeCollections(ctx context.Context, username string, req *protomodel.PurgeCollectionsRequest) (*protomodel.PurgeCollectionsResponse, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (d *closedDB) AddField(ctx context.Context, username string, req *protomodel.AddFieldRequest) (*protomodel.AddFieldResponse, error) {
 	return nil, store.ErrAlreadyClosed
 }
@@ -292,10 +443,18 @@ func (d *closedDB) InsertDocuments(ctx context.Context, username string, req *pr
 	return nil, store.ErrAlreadyClosed
 }
 
+func (d *closedDB) UploadDocument(ctx context.Context, username string, recv func() (*protomodel.UploadDocumentChunk, error)) (*protomodel.UploadDocumentResponse, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (d *closedDB) ReplaceDocuments(ctx context.Context, username string, req *protomodel.ReplaceDocumentsRequest) (*protomodel.ReplaceDocumentsResponse, error) {
 	return nil, store.ErrAlreadyClosed
 }
 
+func (d *closedDB) UpdateDocument(ctx context.Context, username string, req *protomodel.UpdateDocumentRequest) (*protomodel.UpdateDocumentResponse, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (d *closedDB) AuditDocument(ctx context.Context, req *protomodel.AuditDocumentRequest) (*protomodel.AuditDocumentResponse, error) {
 	return nil, store.ErrAlreadyClosed
 }
@@ -304,6 +463,18 @@ func (d *closedDB) SearchDocuments(ctx context.Context, query *protomodel.Query,
 	return nil, store.ErrAlreadyClosed
 }
 
+func (d *closedDB) SearchDocumentsWithHighlights(ctx context.Context, query *protomodel.Query, offset int64) (document.DocumentReader, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
+func (d *closedDB) SearchDocumentsPage(ctx context.Context, query *protomodel.Query, pageToken string, pageSize int, highlight bool) ([]*protomodel.DocumentAtRevision, string, error) {
+	return nil, "", store.ErrAlreadyClosed
+}
+
+func (d *closedDB) ExplainSearchDocuments(ctx context.Context, query *protomodel.Query) (*protomodel.QueryPlan, error) {
+	return nil, store.ErrAlreadyClosed
+}
+
 func (d *closedDB) CountDocuments(ctx context.Context, req *protomodel.CountDocumentsRequest) (*protomodel.CountDocumentsResponse, error) {
 	return nil, store.ErrAlreadyClosed
 }
@@ -312,6 +483,14 @@ func (d *closedDB) ProofDocument(ctx context.Context, req *protomodel.ProofDocum
 	return nil, store.ErrAlreadyClosed
 }
 
+func (d *closedDB) WatchCollection(ctx context.Context, req *protomodel.CollectionWatchRequest, send func(*protomodel.CollectionWatchResponse) error) error {
+	return store.ErrAlreadyClosed
+}
+
 func (d *closedDB) DeleteDocuments(ctx context.Context, username string, req *protomodel.DeleteDocumentsRequest) (*protomodel.DeleteDocumentsResponse, error) {
 	return nil, store.ErrAlreadyClosed
 }
+
+func (d *closedDB) DeleteDocument(ctx context.Context, username string, req *protomodel.DeleteDocumentRequest) (*protomodel.DeleteDocumentResponse, error) {
+	return nil, store.ErrAlreadyClosed
+}
@@ -40,6 +40,13 @@ func TestDummyClosedDatabase(t *testing.T) {
 
 	require.Equal(t, 1000, cdb.MaxResultSize())
 
+	maxKeyLen, maxValueLen, maxTxEntries := cdb.TxLimits()
+	require.Equal(t, cdb.opts.GetStoreOptions().MaxKeyLen-1, maxKeyLen)
+	require.Equal(t, cdb.opts.GetStoreOptions().MaxValueLen-1, maxValueLen)
+	require.Equal(t, cdb.opts.GetStoreOptions().MaxTxEntries, maxTxEntries)
+
+	require.Equal(t, database.Metrics{}, cdb.Metrics())
+
 	err := cdb.UseTimeFunc(nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
@@ -61,27 +68,81 @@ func TestDummyClosedDatabase(t *testing.T) {
 	_, err = cdb.Get(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.GetAt(context.Background(), nil, 0)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.VerifiableGet(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
 	_, err = cdb.GetAll(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.GetEntryMetadata(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.GetReference(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.ReferenceHistory(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.VerifiableGetAll(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.Delete(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.Append(context.Background(), nil, nil, nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.MergeJSON(context.Background(), nil, nil, nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.RenameKey(context.Background(), nil, nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.RenameReference(context.Background(), nil, nil, false)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	err = cdb.VerifyIndex(context.Background())
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	err = cdb.SelfVerify(context.Background(), 1)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.SetReference(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.SetReferences(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.ResolveGroup(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.VerifiableSetReference(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.VerifiableSetReferences(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.Scan(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.ScanReferences(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	err = cdb.ScanByTx(context.Background(), nil, nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.History(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.GetVersions(context.Background(), nil, 1, 0)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	err = cdb.VerifiableHistory(context.Background(), nil, nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.ExecAll(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
@@ -164,9 +225,32 @@ func TestDummyClosedDatabase(t *testing.T) {
 	_, err = cdb.TxScan(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.RecentTxs(context.Background(), 1)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.VerifiableSetWithReference(context.Background(), nil, nil, 0)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.CheckUnique(context.Background(), nil, nil, 0)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.CheckAbsent(context.Background(), nil, 0)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.OpenSnapshot(context.Background(), 0)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	require.Zero(t, cdb.OpenSnapshots())
+
+	_, err = cdb.ExportBundle(context.Background(), 1, 1, nil, nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	err = cdb.FlushIndex(nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.Checkpoint(context.Background())
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	err = cdb.CompactIndex()
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 	require.True(t, cdb.IsClosed())
@@ -189,6 +273,12 @@ func TestDummyClosedDatabase(t *testing.T) {
 	_, err = cdb.DeleteCollection(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.RestoreCollection(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.PurgeCollections(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.AddField(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
@@ -204,24 +294,45 @@ func TestDummyClosedDatabase(t *testing.T) {
 	_, err = cdb.InsertDocuments(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.UploadDocument(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.ReplaceDocuments(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.UpdateDocument(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.AuditDocument(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
 	_, err = cdb.SearchDocuments(context.Background(), nil, 0)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.SearchDocumentsWithHighlights(context.Background(), nil, 0)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, _, err = cdb.SearchDocumentsPage(context.Background(), nil, "", 0, false)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
+	_, err = cdb.ExplainSearchDocuments(context.Background(), nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.CountDocuments(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
 	_, err = cdb.ProofDocument(context.Background(), nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	err = cdb.WatchCollection(context.Background(), nil, nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	_, err = cdb.DeleteDocuments(context.Background(), "admin", nil)
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 
+	_, err = cdb.DeleteDocument(context.Background(), "admin", nil)
+	require.ErrorIs(t, err, store.ErrAlreadyClosed)
+
 	err = cdb.Close()
 	require.ErrorIs(t, err, store.ErrAlreadyClosed)
 }
@@ -161,6 +161,36 @@ func (s *ImmuServer) VerifiableGet(ctx context.Context, req *schema.VerifiableGe
 	return vEntry, nil
 }
 
+// VerifiableHistory streams the revisions of a key together with their proofs
+func (s *ImmuServer) VerifiableHistory(req *schema.VerifiableHistoryRequest, srv schema.ImmuService_VerifiableHistoryServer) error {
+	db, err := s.getDBFromCtx(srv.Context(), "VerifiableHistory")
+	if err != nil {
+		return err
+	}
+
+	return db.VerifiableHistory(srv.Context(), req, func(res *schema.VerifiableHistoryResponse) error {
+		if s.StateSigner != nil && res.DualProof != nil {
+			hdr := schema.TxHeaderFromProto(res.DualProof.TargetTxHeader)
+			alh := hdr.Alh()
+
+			newState := &schema.ImmutableState{
+				Db:     db.GetName(),
+				TxId:   hdr.ID,
+				TxHash: alh[:],
+			}
+
+			err := s.StateSigner.Sign(newState)
+			if err != nil {
+				return err
+			}
+
+			res.Signature = newState.Signature
+		}
+
+		return srv.Send(res)
+	})
+}
+
 // Scan ...
 func (s *ImmuServer) Scan(ctx context.Context, req *schema.ScanRequest) (*schema.Entries, error) {
 	db, err := s.getDBFromCtx(ctx, "Scan")
@@ -171,6 +201,16 @@ func (s *ImmuServer) Scan(ctx context.Context, req *schema.ScanRequest) (*schema
 	return db.Scan(ctx, req)
 }
 
+// ScanByTx streams the scanned entries grouped by the transaction that last wrote each key
+func (s *ImmuServer) ScanByTx(req *schema.ScanRequest, srv schema.ImmuService_ScanByTxServer) error {
+	db, err := s.getDBFromCtx(srv.Context(), "ScanByTx")
+	if err != nil {
+		return err
+	}
+
+	return db.ScanByTx(srv.Context(), req, srv.Send)
+}
+
 // Count ...
 func (s *ImmuServer) Count(ctx context.Context, req *schema.KeyPrefix) (*schema.EntryCount, error) {
 	db, err := s.getDBFromCtx(ctx, "Scan")
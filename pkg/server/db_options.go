@@ -18,9 +18,12 @@ package server
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/codenotary/immudb/embedded/ahtree"
@@ -229,7 +232,14 @@ func (s *ImmuServer) defaultAHTOptions() *ahtOptions {
 }
 
 func (s *ImmuServer) databaseOptionsFrom(opts *dbOptions) *database.Options {
-	return database.DefaultOption().
+	dbOpts := database.DefaultOption()
+
+	if opts.Database == s.Options.GetSystemAdminDBName() {
+		// users and database settings are stored as regular keys of the system database
+		dbOpts.WithReservedKeyPrefixes(SystemDBReservedKeyPrefixes...)
+	}
+
+	return dbOpts.
 		WithDBRootPath(s.Options.Dir).
 		WithStoreOptions(s.storeOptionsForDB(opts.Database, s.remoteStorage, opts.storeOptions())).
 		AsReplica(opts.Replica).
@@ -238,7 +248,29 @@ func (s *ImmuServer) databaseOptionsFrom(opts *dbOptions) *database.Options {
 		WithReadTxPoolSize(opts.ReadTxPoolSize).
 		WithRetentionPeriod(time.Millisecond * time.Duration(opts.RetentionPeriod)).
 		WithTruncationFrequency(time.Millisecond * time.Duration(opts.TruncationFrequency)).
-		WithMaxResultSize(s.Options.MaxResultSize)
+		WithMaxResultSize(s.Options.MaxResultSize).
+		WithDocumentFieldEncryptionKey(s.documentFieldEncryptionKey).
+		WithDocumentValueCodecs(s.Options.documentValueCodecs)
+}
+
+// readDocumentFieldEncryptionKey reads the hex-encoded document field encryption key from the file
+func readDocumentFieldEncryptionKey(keyFile string) ([]byte, error) {
+	hexKey, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(hexKey)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: the key must be hex-encoded", ErrIllegalArguments)
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: the key must be 16, 24 or 32 bytes long", ErrIllegalArguments)
 }
 
 func (opts *dbOptions) storeOptions() *store.Options {
@@ -17,6 +17,8 @@ limitations under the License.
 package server
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/codenotary/immudb/pkg/replication"
@@ -37,6 +39,49 @@ func TestDefaultOptions(t *testing.T) {
 	require.ErrorIs(t, opts.Validate(), ErrIllegalArguments)
 }
 
+func TestSystemDBReservedKeyPrefixes(t *testing.T) {
+	dir := t.TempDir()
+
+	s, closer := testServer(DefaultOptions().WithDir(dir))
+	defer closer()
+
+	sysDBOpts := s.databaseOptionsFrom(s.defaultDBOptions(s.Options.GetSystemAdminDBName(), ""))
+	require.Equal(t, SystemDBReservedKeyPrefixes, sysDBOpts.GetReservedKeyPrefixes())
+
+	dbOpts := s.databaseOptionsFrom(s.defaultDBOptions("db1", "user"))
+	require.Empty(t, dbOpts.GetReservedKeyPrefixes())
+}
+
+func TestReadDocumentFieldEncryptionKey(t *testing.T) {
+	dir := t.TempDir()
+
+	writeKeyFile := func(name, content string) string {
+		keyFile := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(keyFile, []byte(content), 0600))
+		return keyFile
+	}
+
+	_, err := readDocumentFieldEncryptionKey(filepath.Join(dir, "missing.key"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = readDocumentFieldEncryptionKey(writeKeyFile("invalid.key", "not hex"))
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	_, err = readDocumentFieldEncryptionKey(writeKeyFile("short.key", "00112233"))
+	require.ErrorIs(t, err, ErrIllegalArguments)
+
+	key, err := readDocumentFieldEncryptionKey(writeKeyFile("valid.key", "000102030405060708090a0b0c0d0e0f\n"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, key)
+
+	s, closer := testServer(DefaultOptions().
+		WithDir(filepath.Join(dir, "data")).
+		WithFieldEncryptionKeyFile(filepath.Join(dir, "short.key")))
+	defer closer()
+
+	require.Error(t, s.Initialize())
+}
+
 func TestReplicaOptions(t *testing.T) {
 	dir := t.TempDir()
 
@@ -89,6 +89,34 @@ func (s *ImmuServer) DeleteCollection(ctx context.Context, req *protomodel.Delet
 	return db.DeleteCollection(ctx, user.Username, req)
 }
 
+func (s *ImmuServer) RestoreCollection(ctx context.Context, req *protomodel.RestoreCollectionRequest) (*protomodel.RestoreCollectionResponse, error) {
+	db, err := s.getDBFromCtx(ctx, "RestoreCollection")
+	if err != nil {
+		return nil, err
+	}
+
+	_, user, err := s.getLoggedInUserdataFromCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get loggedin user data")
+	}
+
+	return db.RestoreCollection(ctx, user.Username, req)
+}
+
+func (s *ImmuServer) PurgeCollections(ctx context.Context, req *protomodel.PurgeCollectionsRequest) (*protomodel.PurgeCollectionsResponse, error) {
+	db, err := s.getDBFromCtx(ctx, "PurgeCollections")
+	if err != nil {
+		return nil, err
+	}
+
+	_, user, err := s.getLoggedInUserdataFromCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get loggedin user data")
+	}
+
+	return db.PurgeCollections(ctx, user.Username, req)
+}
+
 func (s *ImmuServer) AddField(ctx context.Context, req *protomodel.AddFieldRequest) (*protomodel.AddFieldResponse, error) {
 	db, err := s.getDBFromCtx(ctx, "AddField")
 	if err != nil {
@@ -159,6 +187,25 @@ func (s *ImmuServer) InsertDocuments(ctx context.Context, req *protomodel.Insert
 	return db.InsertDocuments(ctx, user.Username, req)
 }
 
+func (s *ImmuServer) UploadDocument(srv protomodel.DocumentService_UploadDocumentServer) error {
+	db, err := s.getDBFromCtx(srv.Context(), "UploadDocument")
+	if err != nil {
+		return err
+	}
+
+	_, user, err := s.getLoggedInUserdataFromCtx(srv.Context())
+	if err != nil {
+		return fmt.Errorf("could not get loggedin user data")
+	}
+
+	res, err := db.UploadDocument(srv.Context(), user.Username, srv.Recv)
+	if err != nil {
+		return err
+	}
+
+	return srv.SendAndClose(res)
+}
+
 func (s *ImmuServer) ReplaceDocuments(ctx context.Context, req *protomodel.ReplaceDocumentsRequest) (*protomodel.ReplaceDocumentsResponse, error) {
 	db, err := s.getDBFromCtx(ctx, "ReplaceDocuments")
 	if err != nil {
@@ -173,6 +220,20 @@ func (s *ImmuServer) ReplaceDocuments(ctx context.Context, req *protomodel.Repla
 	return db.ReplaceDocuments(ctx, user.Username, req)
 }
 
+func (s *ImmuServer) UpdateDocument(ctx context.Context, req *protomodel.UpdateDocumentRequest) (*protomodel.UpdateDocumentResponse, error) {
+	db, err := s.getDBFromCtx(ctx, "UpdateDocument")
+	if err != nil {
+		return nil, err
+	}
+
+	_, user, err := s.getLoggedInUserdataFromCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get loggedin user data")
+	}
+
+	return db.UpdateDocument(ctx, user.Username, req)
+}
+
 func (s *ImmuServer) AuditDocument(ctx context.Context, req *protomodel.AuditDocumentRequest) (*protomodel.AuditDocumentResponse, error) {
 	db, err := s.getDBFromCtx(ctx, "AuditDocument")
 	if err != nil {
@@ -196,7 +257,14 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 		return nil, fmt.Errorf("%w: query or searchId must be specified, not both", ErrIllegalArguments)
 	}
 
-	if req.Page < 1 || req.PageSize < 1 {
+	if req.PageToken != "" && req.Page > 0 {
+		return nil, fmt.Errorf("%w: page token or page number must be specified, not both", ErrIllegalArguments)
+	}
+
+	// documents are paginated by token when no page number is specified
+	paginateByToken := req.Page == 0 && req.SearchId == ""
+
+	if (!paginateByToken && req.Page < 1) || req.PageSize < 1 {
 		return nil, fmt.Errorf("%w: invalid page or page size", ErrIllegalArguments)
 	}
 
@@ -205,6 +273,10 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 			database.ErrResultSizeLimitExceeded, req.PageSize, db.MaxResultSize())
 	}
 
+	if paginateByToken {
+		return s.searchDocumentsByToken(ctx, db, req)
+	}
+
 	// get the session from the context
 	sessionID, err := sessions.GetSessionIDFromContext(ctx)
 	if err != nil {
@@ -233,7 +305,7 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 
 		// paginated reader already exists, resume reading from the correct offset based
 		// on pagination parameters, do validation on the pagination parameters
-		if req.Page != pgreader.LastPageNumber+1 || req.PageSize != pgreader.LastPageSize {
+		if req.Page != pgreader.LastPageNumber+1 || req.PageSize != pgreader.LastPageSize || req.Highlight != pgreader.Highlight {
 			if pgreader.Reader != nil {
 				err := pgreader.Reader.Close()
 				if err != nil {
@@ -250,7 +322,13 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 		// create a new reader and add it to the session
 		offset := int64((req.Page - 1) * req.PageSize)
 
-		docReader, err := db.SearchDocuments(ctx, query, offset)
+		var docReader document.DocumentReader
+
+		if req.Highlight {
+			docReader, err = db.SearchDocumentsWithHighlights(ctx, query, offset)
+		} else {
+			docReader, err = db.SearchDocuments(ctx, query, offset)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -259,6 +337,7 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 		pgreader = &sessions.PaginatedDocumentReader{
 			Reader:         docReader,
 			Query:          query,
+			Highlight:      req.Highlight,
 			LastPageNumber: req.Page,
 			LastPageSize:   req.PageSize,
 		}
@@ -266,6 +345,35 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 		sess.SetPaginatedDocumentReader(searchID, pgreader)
 	}
 
+	var plan *protomodel.QueryPlan
+
+	if req.Explain {
+		// the plan does not depend on the page being read, thus it's computed once per search
+		if pgreader.Plan == nil {
+			pgreader.Plan, err = db.ExplainSearchDocuments(ctx, pgreader.Query)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		plan = pgreader.Plan
+	}
+
+	var totalCount int64
+
+	if req.CountMatches {
+		// counted within the transaction of the paginated reader when supported, so the count is consistent
+		// with the pages read
+		if counter, ok := pgreader.Reader.(document.DocumentCounter); ok {
+			totalCount, err = counter.Count(ctx)
+		} else {
+			totalCount, err = countMatchingDocuments(ctx, db, pgreader.Query)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// read the next page of data from the paginated reader
 	docs, err := pgreader.Reader.ReadN(ctx, int(req.PageSize))
 	if err != nil && !errors.Is(err, document.ErrNoMoreDocuments) {
@@ -280,7 +388,9 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 		}
 
 		return &protomodel.SearchDocumentsResponse{
-			Revisions: docs,
+			Revisions:  docs,
+			Plan:       plan,
+			TotalCount: totalCount,
 		}, nil
 	}
 
@@ -288,11 +398,58 @@ func (s *ImmuServer) SearchDocuments(ctx context.Context, req *protomodel.Search
 	sess.UpdatePaginatedDocumentReader(searchID, req.Page, req.PageSize)
 
 	return &protomodel.SearchDocumentsResponse{
-		SearchId:  searchID,
-		Revisions: docs,
+		SearchId:   searchID,
+		Revisions:  docs,
+		Plan:       plan,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// searchDocumentsByToken returns the page of documents following the position encoded in the page token,
+// no state is kept in the session as the token of the next page is enough to resume the search
+func (s *ImmuServer) searchDocumentsByToken(ctx context.Context, db database.DB, req *protomodel.SearchDocumentsRequest) (*protomodel.SearchDocumentsResponse, error) {
+	revisions, nextPageToken, err := db.SearchDocumentsPage(ctx, req.Query, req.PageToken, int(req.PageSize), req.Highlight)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *protomodel.QueryPlan
+
+	if req.Explain {
+		plan, err = db.ExplainSearchDocuments(ctx, req.Query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var totalCount int64
+
+	if req.CountMatches {
+		totalCount, err = countMatchingDocuments(ctx, db, req.Query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &protomodel.SearchDocumentsResponse{
+		Revisions:     revisions,
+		Plan:          plan,
+		NextPageToken: nextPageToken,
+		TotalCount:    totalCount,
 	}, nil
 }
 
+// countMatchingDocuments returns the number of documents matching the query across all pages.
+// As each page read by token is read on its own snapshot, the count is not bound to the snapshot of any page
+func countMatchingDocuments(ctx context.Context, db database.DB, query *protomodel.Query) (int64, error) {
+	res, err := db.CountDocuments(ctx, &protomodel.CountDocumentsRequest{Query: query})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.Count, nil
+}
+
 func (s *ImmuServer) CountDocuments(ctx context.Context, req *protomodel.CountDocumentsRequest) (*protomodel.CountDocumentsResponse, error) {
 	db, err := s.getDBFromCtx(ctx, "CountDocuments")
 	if err != nil {
@@ -316,6 +473,20 @@ func (s *ImmuServer) DeleteDocuments(ctx context.Context, req *protomodel.Delete
 	return db.DeleteDocuments(ctx, user.Username, req)
 }
 
+func (s *ImmuServer) DeleteDocument(ctx context.Context, req *protomodel.DeleteDocumentRequest) (*protomodel.DeleteDocumentResponse, error) {
+	db, err := s.getDBFromCtx(ctx, "DeleteDocument")
+	if err != nil {
+		return nil, err
+	}
+
+	_, user, err := s.getLoggedInUserdataFromCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get loggedin user data")
+	}
+
+	return db.DeleteDocument(ctx, user.Username, req)
+}
+
 func (s *ImmuServer) ProofDocument(ctx context.Context, req *protomodel.ProofDocumentRequest) (*protomodel.ProofDocumentResponse, error) {
 	db, err := s.getDBFromCtx(ctx, "ProofDocument")
 	if err != nil {
@@ -347,3 +518,12 @@ func (s *ImmuServer) ProofDocument(ctx context.Context, req *protomodel.ProofDoc
 
 	return res, nil
 }
+
+func (s *ImmuServer) CollectionWatch(req *protomodel.CollectionWatchRequest, srv protomodel.DocumentService_CollectionWatchServer) error {
+	db, err := s.getDBFromCtx(srv.Context(), "CollectionWatch")
+	if err != nil {
+		return err
+	}
+
+	return db.WatchCollection(srv.Context(), req, srv.Send)
+}
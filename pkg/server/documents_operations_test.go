@@ -19,13 +19,16 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/codenotary/immudb/pkg/api/protomodel"
 	"github.com/codenotary/immudb/pkg/auth"
+	"github.com/codenotary/immudb/pkg/database"
 	"github.com/codenotary/immudb/pkg/server/sessions"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -60,6 +63,12 @@ func TestV2Authentication(t *testing.T) {
 	_, err = s.DeleteCollection(ctx, &protomodel.DeleteCollectionRequest{})
 	require.ErrorIs(t, err, ErrNotLoggedIn)
 
+	_, err = s.RestoreCollection(ctx, &protomodel.RestoreCollectionRequest{})
+	require.ErrorIs(t, err, ErrNotLoggedIn)
+
+	_, err = s.PurgeCollections(ctx, &protomodel.PurgeCollectionsRequest{})
+	require.ErrorIs(t, err, ErrNotLoggedIn)
+
 	_, err = s.AddField(ctx, &protomodel.AddFieldRequest{})
 	require.ErrorIs(t, err, ErrNotLoggedIn)
 
@@ -75,6 +84,9 @@ func TestV2Authentication(t *testing.T) {
 	_, err = s.InsertDocuments(ctx, &protomodel.InsertDocumentsRequest{})
 	require.ErrorIs(t, err, ErrNotLoggedIn)
 
+	err = s.UploadDocument(&DocumentService_UploadDocumentServerMock{ctx: ctx})
+	require.ErrorIs(t, err, ErrNotLoggedIn)
+
 	_, err = s.ReplaceDocuments(ctx, &protomodel.ReplaceDocumentsRequest{})
 	require.ErrorIs(t, err, ErrNotLoggedIn)
 
@@ -90,6 +102,12 @@ func TestV2Authentication(t *testing.T) {
 	_, err = s.DeleteDocuments(ctx, &protomodel.DeleteDocumentsRequest{})
 	require.ErrorIs(t, err, ErrNotLoggedIn)
 
+	_, err = s.DeleteDocument(ctx, &protomodel.DeleteDocumentRequest{})
+	require.ErrorIs(t, err, ErrNotLoggedIn)
+
+	_, err = s.UpdateDocument(ctx, &protomodel.UpdateDocumentRequest{})
+	require.ErrorIs(t, err, ErrNotLoggedIn)
+
 	_, err = s.ProofDocument(ctx, &protomodel.ProofDocumentRequest{})
 	require.ErrorIs(t, err, ErrNotLoggedIn)
 
@@ -128,6 +146,9 @@ func TestV2Authentication(t *testing.T) {
 	_, err = s.InsertDocuments(ctx, &protomodel.InsertDocumentsRequest{})
 	require.NotErrorIs(t, err, ErrNotLoggedIn)
 
+	err = s.UploadDocument(&DocumentService_UploadDocumentServerMock{ctx: ctx})
+	require.NotErrorIs(t, err, ErrNotLoggedIn)
+
 	_, err = s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{})
 	require.NotErrorIs(t, err, ErrNotLoggedIn)
 
@@ -137,6 +158,12 @@ func TestV2Authentication(t *testing.T) {
 	_, err = s.DeleteCollection(ctx, &protomodel.DeleteCollectionRequest{})
 	require.NotErrorIs(t, err, ErrNotLoggedIn)
 
+	_, err = s.RestoreCollection(ctx, &protomodel.RestoreCollectionRequest{})
+	require.NotErrorIs(t, err, ErrNotLoggedIn)
+
+	_, err = s.PurgeCollections(ctx, &protomodel.PurgeCollectionsRequest{})
+	require.NotErrorIs(t, err, ErrNotLoggedIn)
+
 	_, err = s.GetCollections(ctx, &protomodel.GetCollectionsRequest{})
 	require.NotErrorIs(t, err, ErrNotLoggedIn)
 
@@ -144,6 +171,33 @@ func TestV2Authentication(t *testing.T) {
 	require.NotErrorIs(t, err, ErrNotLoggedIn)
 }
 
+type DocumentService_UploadDocumentServerMock struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks []*protomodel.UploadDocumentChunk
+	res    *protomodel.UploadDocumentResponse
+}
+
+func (s *DocumentService_UploadDocumentServerMock) Recv() (*protomodel.UploadDocumentChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+
+	return chunk, nil
+}
+
+func (s *DocumentService_UploadDocumentServerMock) SendAndClose(res *protomodel.UploadDocumentResponse) error {
+	s.res = res
+	return nil
+}
+
+func (s *DocumentService_UploadDocumentServerMock) Context() context.Context {
+	return s.ctx
+}
+
 func TestPaginationOnReader(t *testing.T) {
 	dir := t.TempDir()
 
@@ -601,6 +655,7 @@ func TestPaginatedReader_NoMoreDocsFound(t *testing.T) {
 		require.NoError(t, err)
 		require.Len(t, resp.Revisions, 10)
 		require.Len(t, resp.SearchId, 0)
+		require.Nil(t, resp.Plan)
 
 		// ensure there is only one reader in the session for the request and it is being reused
 		// get the session from the context
@@ -622,11 +677,209 @@ func TestPaginatedReader_NoMoreDocsFound(t *testing.T) {
 
 	})
 
+	t.Run("search with explain should return the query plan alongside results", func(t *testing.T) {
+		resp, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+			Query: &protomodel.Query{
+				CollectionName: collectionName,
+				Expressions: []*protomodel.QueryExpression{
+					{
+						FieldComparisons: []*protomodel.FieldComparison{
+							{
+								Field:    "pincode",
+								Operator: protomodel.ComparisonOperator_LE,
+								Value:    structpb.NewNumberValue(3),
+							},
+						},
+					},
+				},
+				OrderBy: []*protomodel.OrderByClause{{Field: "pincode"}},
+			},
+			Page:     1,
+			PageSize: 10,
+			Explain:  true,
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Revisions, 3)
+		require.NotNil(t, resp.Plan)
+		require.False(t, resp.Plan.FullScan)
+		require.Equal(t, []string{"pincode"}, resp.Plan.IndexFields)
+		require.EqualValues(t, 3, resp.Plan.DocumentsExamined)
+		require.EqualValues(t, 3, resp.Plan.DocumentsMatched)
+	})
+
+	t.Run("paginated search with explain should compute the query plan once", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: collectionName,
+			OrderBy:        []*protomodel.OrderByClause{{Field: "pincode"}},
+		}
+
+		resp, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+			Query:    query,
+			Page:     1,
+			PageSize: 2,
+			Explain:  true,
+			KeepOpen: true,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resp.Plan)
+		require.NotEmpty(t, resp.SearchId)
+
+		nextResp, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+			SearchId: resp.SearchId,
+			Page:     2,
+			PageSize: 2,
+			Explain:  true,
+		})
+		require.NoError(t, err)
+		require.Same(t, resp.Plan, nextResp.Plan)
+	})
+
+	t.Run("search with highlight should return the matching field of each result", func(t *testing.T) {
+		resp, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+			Query: &protomodel.Query{
+				CollectionName: collectionName,
+				Expressions: []*protomodel.QueryExpression{
+					{
+						FieldComparisons: []*protomodel.FieldComparison{
+							{
+								Field:    "country",
+								Operator: protomodel.ComparisonOperator_LIKE,
+								Value:    structpb.NewStringValue("^country-1"),
+							},
+						},
+					},
+				},
+				OrderBy: []*protomodel.OrderByClause{{Field: "idx"}},
+			},
+			Page:      1,
+			PageSize:  10,
+			Highlight: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Revisions, 2)
+
+		for _, rev := range resp.Revisions {
+			require.Len(t, rev.Matches, 1)
+			require.Equal(t, "country", rev.Matches[0].Field)
+			require.Equal(t, &protomodel.SubstringMatch{Start: 0, End: 9}, rev.Matches[0].Substring)
+		}
+	})
+
+	t.Run("search counting matches should return the total count alongside the page", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: collectionName,
+			Expressions: []*protomodel.QueryExpression{
+				{
+					FieldComparisons: []*protomodel.FieldComparison{
+						{
+							Field:    "pincode",
+							Operator: protomodel.ComparisonOperator_LE,
+							Value:    structpb.NewNumberValue(7),
+						},
+					},
+				},
+			},
+		}
+
+		resp, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+			Query:        query,
+			Page:         1,
+			PageSize:     3,
+			CountMatches: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Revisions, 3)
+		require.EqualValues(t, 7, resp.TotalCount)
+
+		resp, err = s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+			Query:        query,
+			PageSize:     3,
+			CountMatches: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Revisions, 3)
+		require.NotEmpty(t, resp.NextPageToken)
+		require.EqualValues(t, 7, resp.TotalCount)
+
+		resp, err = s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+			Query:    query,
+			Page:     1,
+			PageSize: 3,
+		})
+		require.NoError(t, err)
+		require.Zero(t, resp.TotalCount)
+	})
+
+	t.Run("search by page token should return every document once", func(t *testing.T) {
+		query := &protomodel.Query{
+			CollectionName: collectionName,
+			OrderBy:        []*protomodel.OrderByClause{{Field: "idx", Desc: true}},
+		}
+
+		var results []*protomodel.DocumentAtRevision
+		var pageToken string
+
+		for {
+			resp, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+				Query:     query,
+				PageToken: pageToken,
+				PageSize:  4,
+			})
+			require.NoError(t, err)
+			require.Empty(t, resp.SearchId)
+
+			results = append(results, resp.Revisions...)
+
+			if resp.NextPageToken == "" {
+				break
+			}
+
+			require.Len(t, resp.Revisions, 4)
+			pageToken = resp.NextPageToken
+		}
+
+		require.Len(t, results, 10)
+
+		for i, rev := range results {
+			require.Equal(t, float64(10-i), rev.Document.Fields["idx"].GetNumberValue())
+		}
+
+		sessionID, err := sessions.GetSessionIDFromContext(ctx)
+		require.NoError(t, err)
+
+		sess, err := s.SessManager.GetSession(sessionID)
+		require.NoError(t, err)
+		require.Equal(t, 0, sess.GetDocumentReadersCount())
+
+		t.Run("search with both page token and page number should fail", func(t *testing.T) {
+			_, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+				Query:     query,
+				PageToken: pageToken,
+				Page:      2,
+				PageSize:  4,
+			})
+			require.ErrorIs(t, err, ErrIllegalArguments)
+		})
+
+		t.Run("search with a page token of another query should fail", func(t *testing.T) {
+			_, err := s.SearchDocuments(ctx, &protomodel.SearchDocumentsRequest{
+				Query: &protomodel.Query{
+					CollectionName: collectionName,
+					OrderBy:        []*protomodel.OrderByClause{{Field: "idx"}},
+				},
+				PageToken: pageToken,
+				PageSize:  4,
+			})
+			require.ErrorIs(t, err, database.ErrIllegalArguments)
+		})
+	})
+
 	t.Run("document deletion should succeed", func(t *testing.T) {
 		_, err = s.DeleteDocuments(ctx, &protomodel.DeleteDocumentsRequest{
 			Query: &protomodel.Query{
 				CollectionName: collectionName,
 			},
+			Confirm: true,
 		})
 		require.NoError(t, err)
 	})
@@ -17,6 +17,7 @@ limitations under the License.
 package server
 
 import (
+	"github.com/codenotary/immudb/embedded/document"
 	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/auth"
 	"github.com/codenotary/immudb/pkg/database"
@@ -71,6 +72,21 @@ func mapServerError(err error) error {
 	if goerrors.Is(err, store.ErrPreconditionFailed) {
 		return errors.New(err.Error()).WithCode(errors.CodIntegrityConstraintViolation)
 	}
+	if goerrors.Is(err, document.ErrUndeclaredField) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if goerrors.Is(err, document.ErrDocumentNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if goerrors.Is(err, document.ErrRevisionConflict) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	if goerrors.Is(err, document.ErrTxConflict) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	if goerrors.Is(err, document.ErrFieldEncryptionKeyNotSet) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
 	return err
 }
 
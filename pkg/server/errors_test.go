@@ -21,9 +21,12 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/codenotary/immudb/embedded/document"
 	"github.com/codenotary/immudb/embedded/store"
 	immuerrors "github.com/codenotary/immudb/pkg/errors"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestMapServerError(t *testing.T) {
@@ -39,4 +42,19 @@ func TestMapServerError(t *testing.T) {
 
 	err = mapServerError(fmt.Errorf("%w: test", store.ErrPreconditionFailed))
 	require.Equal(t, immuerrors.CodIntegrityConstraintViolation, err.(immuerrors.Error).Code())
+
+	err = mapServerError(fmt.Errorf("%w: test", document.ErrUndeclaredField))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = mapServerError(fmt.Errorf("%w: test", document.ErrDocumentNotFound))
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	err = mapServerError(&document.RevisionConflictError{DocumentID: "doc", CurrentRevision: 2})
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	err = mapServerError(&document.TxConflictError{DocumentID: "doc", CurrentTxID: 2})
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	err = mapServerError(fmt.Errorf("%w: test", document.ErrFieldEncryptionKeyNotSet))
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
 }
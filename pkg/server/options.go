@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/codenotary/immudb/embedded/document"
 	"github.com/codenotary/immudb/embedded/logger"
 	"github.com/codenotary/immudb/pkg/database"
 	"github.com/codenotary/immudb/pkg/replication"
@@ -64,6 +65,8 @@ type Options struct {
 	usingCustomListener         bool
 	maintenance                 bool
 	SigningKey                  string
+	FieldEncryptionKeyFile      string
+	documentValueCodecs         *document.ValueCodecRegistry
 	synced                      bool
 	RemoteStorageOptions        *RemoteStorageOptions
 	StreamChunkSize             int
@@ -331,6 +334,9 @@ func (o *Options) String() string {
 	if o.SigningKey != "" {
 		opts = append(opts, rightPad("Signing key", o.SigningKey))
 	}
+	if o.FieldEncryptionKeyFile != "" {
+		opts = append(opts, rightPad("Field encryption key", o.FieldEncryptionKeyFile))
+	}
 	if o.RemoteStorageOptions.S3Storage {
 		opts = append(opts, "S3 storage")
 		if o.RemoteStorageOptions.S3RoleEnabled {
@@ -443,6 +449,19 @@ func (o *Options) WithSigningKey(signingKey string) *Options {
 	return o
 }
 
+// WithFieldEncryptionKeyFile sets the path of the file holding the hex-encoded key used to encrypt
+// the document fields marked as encrypted
+func (o *Options) WithFieldEncryptionKeyFile(keyFile string) *Options {
+	o.FieldEncryptionKeyFile = keyFile
+	return o
+}
+
+// WithDocumentValueCodecs sets the registry of codecs applied to document field values of every database
+func (o *Options) WithDocumentValueCodecs(valueCodecs *document.ValueCodecRegistry) *Options {
+	o.documentValueCodecs = valueCodecs
+	return o
+}
+
 // WithStreamChunkSize set the chunk size
 func (o *Options) WithStreamChunkSize(streamChunkSize int) *Options {
 	o.StreamChunkSize = streamChunkSize
@@ -69,6 +69,10 @@ const (
 	KeyPrefixDBSettings
 )
 
+// SystemDBReservedKeyPrefixes holds the prefixes of the keys of the system database holding users and
+// database settings, references to them are rejected
+var SystemDBReservedKeyPrefixes = [][]byte{{KeyPrefixUser}, {KeyPrefixDBSettings}}
+
 var startedAt time.Time
 
 var immudbTextLogo = " _                               _ _     \n" +
@@ -129,6 +133,13 @@ func (s *ImmuServer) Initialize() error {
 		return logErr(s.Logger, "unable to initialize remote storage: %v", err)
 	}
 
+	if s.Options.FieldEncryptionKeyFile != "" {
+		s.documentFieldEncryptionKey, err = readDocumentFieldEncryptionKey(s.Options.FieldEncryptionKeyFile)
+		if err != nil {
+			return logErr(s.Logger, "unable to read the document field encryption key: %v", err)
+		}
+	}
+
 	if err = s.loadSystemDatabase(dataDir, s.remoteStorage, adminPassword, s.Options.ForceAdminPassword); err != nil {
 		return logErr(s.Logger, "unable to load system database: %v", err)
 	}
@@ -1153,6 +1153,35 @@ func testServerScan(ctx context.Context, s *ImmuServer, t *testing.T) {
 	}
 }
 
+type scanByTxServerMock struct {
+	mockServerStream
+	groups []*schema.TxGroup
+}
+
+func (m *scanByTxServerMock) Send(group *schema.TxGroup) error {
+	m.groups = append(m.groups, group)
+	return nil
+}
+
+func testServerScanByTx(ctx context.Context, s *ImmuServer, t *testing.T) {
+	hdr, err := s.Set(ctx, &schema.SetRequest{KVs: []*schema.KeyValue{
+		{Key: []byte("scanByTx/a"), Value: []byte("1")},
+		{Key: []byte("scanByTx/b"), Value: []byte("1")},
+	}})
+	require.NoError(t, err)
+
+	srv := &scanByTxServerMock{mockServerStream: mockServerStream{ctx: ctx}}
+
+	err = s.ScanByTx(&schema.ScanRequest{Prefix: []byte("scanByTx/"), SinceTx: hdr.Id}, srv)
+	require.NoError(t, err)
+	require.Len(t, srv.groups, 1)
+	require.Equal(t, hdr.Id, srv.groups[0].Tx)
+	require.Len(t, srv.groups[0].Entries, 2)
+
+	err = s.ScanByTx(&schema.ScanRequest{Prefix: []byte("scanByTx/")}, &scanByTxServerMock{mockServerStream: mockServerStream{ctx: context.Background()}})
+	require.ErrorIs(t, err, ErrNotLoggedIn)
+}
+
 func testServerScanError(ctx context.Context, s *ImmuServer, t *testing.T) {
 	_, err := s.Scan(context.Background(), &schema.ScanRequest{
 		SeekKey: nil,
@@ -1307,6 +1336,7 @@ func TestServerDbOperations(t *testing.T) {
 	testServerZAddError(ctx, s, t)
 	testServerScan(ctx, s, t)
 	testServerScanError(ctx, s, t)
+	testServerScanByTx(ctx, s, t)
 	testServerTxScan(ctx, s, t)
 	testServerSafeReference(ctx, s, t)
 	testServerSafeReferenceError(ctx, s, t)
@@ -27,7 +27,8 @@ import (
 type ServerMock struct {
 	Srv *server.ImmuServer
 
-	PreVerifiableGetFn func(context.Context, *schema.VerifiableGetRequest)
+	PreVerifiableGetFn  func(context.Context, *schema.VerifiableGetRequest)
+	PostVerifiableGetFn func(context.Context, *schema.VerifiableGetRequest, *schema.VerifiableEntry, error) (*schema.VerifiableEntry, error)
 
 	PreVerifiableSetFn  func(context.Context, *schema.VerifiableSetRequest)
 	PostSetFn           func(context.Context, *schema.SetRequest, *schema.TxHeader, error) (*schema.TxHeader, error)
@@ -179,7 +180,12 @@ func (s *ServerMock) VerifiableGet(ctx context.Context, req *schema.VerifiableGe
 		s.PreVerifiableGetFn(ctx, req)
 	}
 
-	return s.Srv.VerifiableGet(ctx, req)
+	if s.PostVerifiableGetFn == nil {
+		return s.Srv.VerifiableGet(ctx, req)
+	}
+
+	rsp, err := s.Srv.VerifiableGet(ctx, req)
+	return s.PostVerifiableGetFn(ctx, req, rsp, err)
 }
 
 func (s *ServerMock) GetAll(ctx context.Context, req *schema.KeyListRequest) (*schema.Entries, error) {
@@ -227,6 +233,14 @@ func (s *ServerMock) History(ctx context.Context, req *schema.HistoryRequest) (*
 	return s.Srv.History(ctx, req)
 }
 
+func (s *ServerMock) ScanByTx(req *schema.ScanRequest, scanServer schema.ImmuService_ScanByTxServer) error {
+	return s.Srv.ScanByTx(req, scanServer)
+}
+
+func (s *ServerMock) VerifiableHistory(req *schema.VerifiableHistoryRequest, historyServer schema.ImmuService_VerifiableHistoryServer) error {
+	return s.Srv.VerifiableHistory(req, historyServer)
+}
+
 func (s *ServerMock) ServerInfo(ctx context.Context, req *schema.ServerInfoRequest) (*schema.ServerInfoResponse, error) {
 	return s.Srv.ServerInfo(ctx, req)
 }
@@ -45,8 +45,11 @@ var (
 type PaginatedDocumentReader struct {
 	Reader         document.DocumentReader // reader to read from
 	Query          *protomodel.Query
+	Highlight      bool   // whether documents read include the field comparisons they satisfy
 	LastPageNumber uint32 // last read page number
 	LastPageSize   uint32 // number of items per page
+
+	Plan *protomodel.QueryPlan // plan of the query, computed once when first explained
 }
 
 type Session struct {
@@ -85,6 +85,9 @@ type ImmuServer struct {
 
 	remoteStorage remotestorage.Storage
 
+	// key used to encrypt the document fields marked as encrypted, read from the configured key file
+	documentFieldEncryptionKey []byte
+
 	SessManager sessions.Manager
 }
 